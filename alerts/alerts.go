@@ -0,0 +1,90 @@
+package alerts
+
+import (
+    "fmt"
+
+    "gobank/i18n"
+    "gobank/types"
+)
+
+// Movement is one side of a posted money movement, seen from Account,
+// whose Balance already includes it. Amount is in the account's currency.
+type Movement struct {
+    Account *types.Account
+    Amount int64
+    Outgoing bool
+    Counterparty int64
+    CounterpartyCurrency string
+}
+
+type Triggered struct {
+    Rule *types.AlertRule
+    Description string
+}
+
+func Validate(req *types.CreateAlertRuleRequest) error {
+    switch req.Kind {
+    case types.AlertBalanceBelow, types.AlertTransactionAbove:
+        if req.Threshold <= 0 {
+            return fmt.Errorf("threshold must be positive")
+        }
+    case types.AlertForeignCurrency:
+    default:
+        return fmt.Errorf("unknown alert kind %q", req.Kind)
+    }
+
+    return nil
+}
+
+// Evaluate returns the rules m matches. A balance rule only fires when the
+// movement takes the balance from at or above the threshold to below it, so
+// an account that stays low isn't alerted on every payment.
+func Evaluate(rules []*types.AlertRule, m Movement) []Triggered {
+    triggered := []Triggered{}
+
+    for _, rule := range rules {
+        switch rule.Kind {
+        case types.AlertBalanceBelow:
+            before := m.Account.Balance
+            if m.Outgoing {
+                before += m.Amount
+            } else {
+                before -= m.Amount
+            }
+            if before >= rule.Threshold && m.Account.Balance < rule.Threshold {
+                triggered = append(triggered, Triggered{
+                    Rule: rule,
+                    Description: fmt.Sprintf("your balance is %s, below your alert of %s", money(m, m.Account.Balance), money(m, rule.Threshold)),
+                })
+            }
+        case types.AlertTransactionAbove:
+            if m.Amount > rule.Threshold {
+                triggered = append(triggered, Triggered{
+                    Rule: rule,
+                    Description: fmt.Sprintf("%s of %s, above your alert of %s", direction(m), money(m, m.Amount), money(m, rule.Threshold)),
+                })
+            }
+        case types.AlertForeignCurrency:
+            if m.CounterpartyCurrency != "" && m.CounterpartyCurrency != m.Account.Currency {
+                triggered = append(triggered, Triggered{
+                    Rule: rule,
+                    Description: fmt.Sprintf("%s of %s with a %s account", direction(m), money(m, m.Amount), m.CounterpartyCurrency),
+                })
+            }
+        }
+    }
+
+    return triggered
+}
+
+func direction(m Movement) string {
+    if m.Outgoing {
+        return fmt.Sprintf("a payment to account %d", m.Counterparty)
+    }
+    return fmt.Sprintf("a payment from account %d", m.Counterparty)
+}
+
+// money writes an amount in the account's currency and locale.
+func money(m Movement, amount int64) string {
+    return i18n.FormatMoney(m.Account.Locale, amount, m.Account.Currency)
+}
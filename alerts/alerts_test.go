@@ -0,0 +1,28 @@
+package alerts
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/types"
+)
+
+func TestEvaluate(t *testing.T) {
+    rules := []*types.AlertRule{
+        {ID: 1, Kind: types.AlertBalanceBelow, Threshold: 100},
+        {ID: 2, Kind: types.AlertTransactionAbove, Threshold: 500},
+        {ID: 3, Kind: types.AlertForeignCurrency},
+    }
+    acc := &types.Account{Number: 1, Balance: 50, Currency: "USD"}
+
+    // 200 -> 50 crosses the balance threshold
+    got := Evaluate(rules, Movement{Account: acc, Amount: 150, Outgoing: true, Counterparty: 2, CounterpartyCurrency: "USD"})
+    assert.Len(t, got, 1)
+    assert.Equal(t, 1, got[0].Rule.ID)
+
+    // already below, large and foreign
+    got = Evaluate(rules, Movement{Account: acc, Amount: 600, Outgoing: false, Counterparty: 2, CounterpartyCurrency: "EUR"})
+    assert.Len(t, got, 2)
+    assert.Equal(t, 2, got[0].Rule.ID)
+    assert.Equal(t, 3, got[1].Rule.ID)
+}
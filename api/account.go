@@ -3,18 +3,28 @@ package api
 import (
     "net/http"
     "fmt"
+    "gobank/auth"
+    "gobank/i18n"
     "gobank/types"
-    "encoding/json"
+    "maps"
     "strconv"
+    "time"
+    "gobank/snapshot"
+    "gobank/notify"
+    "gobank/numbering"
+    "net/mail"
 )
 
+const (
+    maxNicknameLength = 64
+    maxMetadataKeys = 20
+    maxMetadataKeyLength = 40
+    maxMetadataValueLength = 500
+)
 
 
-func (s *APIServer) handleAccount(w http.ResponseWriter, r *http.Request) error {
-    if r.Method == "GET" {
-        return s.handleGetAccount(w, r)
-    }
 
+func (s *APIServer) handleAccount(w http.ResponseWriter, r *http.Request) error {
     if r.Method == "POST" {
         return s.handleCreateAccount(w, r)
     }
@@ -27,15 +37,27 @@ func (s *APIServer) handleAccountWithID(w http.ResponseWriter, r *http.Request)
     if r.Method == "GET" {
         return s.handleGetAccountByID(w, r)
     }
+    if r.Method == "PATCH" {
+        return s.handleUpdateAccount(w, r)
+    }
     if r.Method == "DELETE" {
+        if !isPrimaryOwner(r) {
+            return fmt.Errorf("only the account holder can delete the account")
+        }
         return s.handleDeleteAccount(w, r)
     }
 
     return fmt.Errorf("method not allowed %s", r.Method)
 }
 
-func (s *APIServer) handleGetAccount(w http.ResponseWriter, r *http.Request) error {
-    accounts, err := s.store.GetAccounts()
+// handleAdminAccounts lists every account. The list has each holder's
+// contact details, so only operators get to see it.
+func (s *APIServer) handleAdminAccounts(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    accounts, err := s.store.GetAccounts(r.Context())
     if err != nil {
         return err
     }
@@ -52,7 +74,7 @@ func (s *APIServer) handleGetAccountByID(w http.ResponseWriter, r *http.Request)
             return err
         }
 
-        account, err := s.store.GetAccountByID(id)
+        account, err := s.store.GetAccountByID(r.Context(), id)
 
         if err != nil {
             return err
@@ -63,20 +85,95 @@ func (s *APIServer) handleGetAccountByID(w http.ResponseWriter, r *http.Request)
 
 func (s *APIServer) handleCreateAccount(w http.ResponseWriter, r *http.Request) error {
     createAccountReq := new(types.CreateAccountRequest)
-    if err := json.NewDecoder(r.Body).Decode(createAccountReq); err != nil {
+    if err := s.decodeJSON(w, r, createAccountReq); err != nil {
         return err
     }
 
+    if createAccountReq.Email != "" {
+        if _, err := mail.ParseAddress(createAccountReq.Email); err != nil {
+            return fmt.Errorf("invalid email address")
+        }
+    }
+    if createAccountReq.Currency != "" && !s.cfg.FXRates.Supports(createAccountReq.Currency) {
+        return fmt.Errorf("unsupported currency %s", createAccountReq.Currency)
+    }
+    if createAccountReq.Locale != "" && !i18n.Supported(createAccountReq.Locale) {
+        return fmt.Errorf("unsupported locale %s", createAccountReq.Locale)
+    }
+
     account, err := types.NewAccount(createAccountReq.FirstName, createAccountReq.LastName, createAccountReq.Password)
 
     if err != nil {
         return err
     }
+    account.Email = createAccountReq.Email
+    account.Locale = createAccountReq.Locale
+    if account.Locale == "" {
+        account.Locale = i18n.Match(r.Header.Get("Accept-Language"))
+    }
+    if createAccountReq.Currency != "" {
+        account.Currency = createAccountReq.Currency
+    }
+
+    if err := numbering.New(s.store).Create(r.Context(), account); err != nil {
+        return err
+    }
+
+    s.notifier.Publish(notify.Event{Type: notify.AccountCreated, Account: account})
+
 
-    if err := s.store.CreateAccount(account); err != nil {
+    return WriteJSON(w, http.StatusOK, account)
+}
+
+// handleUpdateAccount sets the nickname, locale and metadata. Metadata keys are
+// merged into what is stored, and a null value removes a key.
+func (s *APIServer) handleUpdateAccount(w http.ResponseWriter, r *http.Request) error {
+    req := new(types.UpdateAccountRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
         return err
     }
 
+    account := auth.AccountFromContext(r.Context())
+    if req.Nickname != nil {
+        if len(*req.Nickname) > maxNicknameLength {
+            return fmt.Errorf("nickname must be at most %d characters", maxNicknameLength)
+        }
+        account.Nickname = *req.Nickname
+    }
+    if req.Locale != nil {
+        if !i18n.Supported(*req.Locale) {
+            return fmt.Errorf("unsupported locale %s", *req.Locale)
+        }
+        account.Locale = *req.Locale
+    }
+
+    if len(req.Metadata) > 0 {
+        metadata := maps.Clone(account.Metadata)
+        if metadata == nil {
+            metadata = map[string]string{}
+        }
+        for key, value := range req.Metadata {
+            if key == "" || len(key) > maxMetadataKeyLength {
+                return fmt.Errorf("metadata keys must be between 1 and %d characters", maxMetadataKeyLength)
+            }
+            if value == nil {
+                delete(metadata, key)
+                continue
+            }
+            if len(*value) > maxMetadataValueLength {
+                return fmt.Errorf("metadata value of %s must be at most %d characters", key, maxMetadataValueLength)
+            }
+            metadata[key] = *value
+        }
+        if len(metadata) > maxMetadataKeys {
+            return fmt.Errorf("metadata can have at most %d keys", maxMetadataKeys)
+        }
+        account.Metadata = metadata
+    }
+
+    if err := s.store.UpdateAccount(r.Context(), account); err != nil {
+        return err
+    }
 
     return WriteJSON(w, http.StatusOK, account)
 }
@@ -88,7 +185,7 @@ func (s *APIServer) handleDeleteAccount(w http.ResponseWriter, r *http.Request)
         return err
     }
 
-    if err := s.store.DeleteAccount(id); err != nil {
+    if err := s.store.DeleteAccount(r.Context(), id); err != nil {
         return err
     }
 
@@ -98,8 +195,68 @@ func (s *APIServer) handleDeleteAccount(w http.ResponseWriter, r *http.Request)
 
 
 
+func (s *APIServer) handleAccountTransactions(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := getID(r)
+    if err != nil {
+        return err
+    }
+
+    account, err := s.store.GetAccountByID(r.Context(), id)
+    if err != nil {
+        return err
+    }
+
+    txs, err := s.store.GetTransactionsByAccount(r.Context(), account.Number)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, txs)
+}
+
+func (s *APIServer) handleAccountBalance(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    resp := types.BalanceResponse{
+        AccountNumber: account.Number,
+        Balance: account.Balance,
+        Currency: account.Currency,
+    }
+
+    if at := r.URL.Query().Get("at"); at != "" {
+        day, err := time.Parse("2006-01-02", at)
+        if err != nil {
+            return fmt.Errorf("at must be a date like 2024-06-01")
+        }
+
+        balance, err := snapshot.BalanceAt(r.Context(), s.store, account.Number, day)
+        if err != nil {
+            return err
+        }
+        resp.Balance = balance
+        resp.At = &day
+
+        return WriteJSON(w, http.StatusOK, resp)
+    }
+
+    balances, err := s.store.GetBalances(r.Context(), account.Number, time.Now().UTC())
+    if err != nil {
+        return err
+    }
+    resp.Balances = balances
+
+    return WriteJSON(w, http.StatusOK, resp)
+}
+
 func getID(r *http.Request) (int, error) {
-	idStr := r.URL.Path[len("/account/"):]
+    idStr := r.PathValue("id")
     id, err := strconv.Atoi(idStr)
     if err != nil {
         return id, fmt.Errorf("This id is not a valid integer")
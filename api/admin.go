@@ -0,0 +1,63 @@
+package api
+
+import (
+    "crypto/subtle"
+    "fmt"
+    "net/http"
+
+    "gobank/i18n"
+    "gobank/reconcile"
+)
+
+// withAdminAuth lets a request through only when its x-admin-token header
+// matches the configured admin token.
+func withAdminAuth(adminToken string) Middleware {
+    return func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+            token := r.Header.Get("x-admin-token")
+            if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+                writeMessage(w, r, http.StatusForbidden, i18n.PermissionDenied)
+                return
+            }
+
+            handlerFunc(w, r)
+        }
+    }
+}
+
+func (s *APIServer) handleReconciliation(w http.ResponseWriter, r *http.Request) error {
+    if r.Method == "GET" {
+        discrepancies, err := s.store.GetDiscrepancies(r.Context())
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, discrepancies)
+    }
+
+    if r.Method == "POST" {
+        report, err := reconcile.New(s.store).RunOnce(r.Context())
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, report)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// handleJobs shows the last run of every scheduled job, whichever instance
+// ran it.
+func (s *APIServer) handleJobs(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    statuses, err := s.store.GetJobStatuses(r.Context())
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, statuses)
+}
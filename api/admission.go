@@ -0,0 +1,131 @@
+package api
+
+import (
+    "net/http"
+    "time"
+
+    "gobank/i18n"
+    "gobank/metrics"
+)
+
+// Requests are admitted by priority when the server is busy. High priority
+// requests move money or log in, low priority ones list history or run
+// reports and everything else is normal.
+type priority int
+
+const (
+    priorityLow priority = iota
+    priorityNormal
+    priorityHigh
+)
+
+func (p priority) String() string {
+    switch p {
+    case priorityLow:
+        return "low"
+    case priorityHigh:
+        return "high"
+    }
+    return "normal"
+}
+
+var (
+    requestsInFlight = metrics.NewGauge("gobank_requests_in_flight", "Requests being handled, by priority.", "priority")
+    admissionWaitSeconds = metrics.NewCounter("gobank_admission_wait_seconds_total", "Time requests spent waiting for a slot, by priority.", "priority")
+    requestsShedTotal = metrics.NewCounter("gobank_requests_shed_total", "Requests turned away with a 503 because the server was busy, by priority.", "priority")
+)
+
+// admission limits how many requests are handled at once. All requests
+// share the first limit-reserved slots and only high priority ones may take
+// the reserved rest, so a flood of reads can't starve transfers and logins.
+type admission struct {
+    // all holds a token for every request being handled, shared one for
+    // every request below high priority
+    all chan struct{}
+    shared chan struct{}
+    maxWait time.Duration
+}
+
+func newAdmission(limit, reserved int, maxWait time.Duration) *admission {
+    return &admission{
+        all: make(chan struct{}, limit),
+        shared: make(chan struct{}, max(limit-reserved, 0)),
+        maxWait: maxWait,
+    }
+}
+
+// acquire takes a slot for a request of priority p and returns the func
+// that gives it back. Low priority requests only get a free slot, the
+// others wait up to maxWait or until the request is cancelled.
+func (a *admission) acquire(r *http.Request, p priority) (func(), bool) {
+    start := time.Now()
+    defer func() {
+        admissionWaitSeconds.Add(time.Since(start).Seconds(), p.String())
+    }()
+
+    var timeout <-chan time.Time
+    if p != priorityLow {
+        t := time.NewTimer(a.maxWait)
+        defer t.Stop()
+        timeout = t.C
+    }
+    take := func(slots chan struct{}) bool {
+        select {
+        case slots <- struct{}{}:
+            return true
+        default:
+        }
+        if timeout == nil {
+            return false
+        }
+        select {
+        case slots <- struct{}{}:
+            return true
+        case <-timeout:
+        case <-r.Context().Done():
+        }
+        return false
+    }
+
+    if p != priorityHigh {
+        if !take(a.shared) {
+            return nil, false
+        }
+        if !take(a.all) {
+            <-a.shared
+            return nil, false
+        }
+        return func() { <-a.all; <-a.shared }, true
+    }
+
+    if !take(a.all) {
+        return nil, false
+    }
+    return func() { <-a.all }, true
+}
+
+// withAdmission answers requests that get no slot with a 503 and a hint to
+// come back in a second. A nil admission lets every request through.
+func withAdmission(a *admission, p priority) Middleware {
+    return func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        if a == nil {
+            return handlerFunc
+        }
+
+        return func(w http.ResponseWriter, r *http.Request) {
+            release, ok := a.acquire(r, p)
+            if !ok {
+                requestsShedTotal.Inc(p.String())
+                w.Header().Set("Retry-After", "1")
+                writeMessage(w, r, http.StatusServiceUnavailable, i18n.Overloaded)
+                return
+            }
+            defer release()
+
+            requestsInFlight.Add(1, p.String())
+            defer requestsInFlight.Add(-1, p.String())
+
+            handlerFunc(w, r)
+        }
+    }
+}
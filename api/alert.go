@@ -0,0 +1,115 @@
+package api
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/alerts"
+    "gobank/notify"
+    "gobank/types"
+)
+
+func (s *APIServer) handleAlerts(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        rules, err := s.store.GetAlertRules(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, rules)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.CreateAlertRuleRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+        if err := alerts.Validate(req); err != nil {
+            return err
+        }
+
+        rule := &types.AlertRule{
+            AccountNumber: account.Number,
+            Kind: req.Kind,
+            Threshold: req.Threshold,
+            CreatedAt: time.Now().UTC(),
+        }
+        if err := s.store.CreateAlertRule(r.Context(), rule); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, rule)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *APIServer) handleDeleteAlert(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "DELETE" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    ruleID, err := strconv.Atoi(r.PathValue("ruleID"))
+    if err != nil {
+        return fmt.Errorf("invalid rule id given %s", r.PathValue("ruleID"))
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    if err := s.store.DeleteAlertRule(r.Context(), account.Number, ruleID); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, map[string]int{"deleted": ruleID})
+}
+
+// checkAlerts evaluates both sides of a posted transfer against their
+// alert rules. The transfer has already happened, so failures are only
+// logged.
+func (s *APIServer) checkAlerts(ctx context.Context, tx *types.Transaction, to *types.Account, converted int64) {
+    from, err := s.store.GetAccountByNumber(ctx, tx.FromAccount)
+    if err != nil {
+        s.logger.Printf("alerts: loading account %d: %v", tx.FromAccount, err)
+        return
+    }
+    to, err = s.store.GetAccountByNumber(ctx, to.Number)
+    if err != nil {
+        s.logger.Printf("alerts: loading account %d: %v", tx.ToAccount, err)
+        return
+    }
+
+    s.publishAlerts(ctx, alerts.Movement{
+        Account: from,
+        Amount: tx.Amount,
+        Outgoing: true,
+        Counterparty: to.Number,
+        CounterpartyCurrency: to.Currency,
+    })
+    s.publishAlerts(ctx, alerts.Movement{
+        Account: to,
+        Amount: converted,
+        Counterparty: from.Number,
+        CounterpartyCurrency: from.Currency,
+    })
+}
+
+func (s *APIServer) publishAlerts(ctx context.Context, m alerts.Movement) {
+    rules, err := s.store.GetAlertRules(ctx, m.Account.Number)
+    if err != nil {
+        s.logger.Printf("alerts: loading rules for %d: %v", m.Account.Number, err)
+        return
+    }
+
+    for _, t := range alerts.Evaluate(rules, m) {
+        s.notifier.Publish(notify.Event{
+            Type: notify.AlertTriggered,
+            Account: m.Account,
+            Data: map[string]any{"description": t.Description, "rule": t.Rule.ID},
+        })
+    }
+}
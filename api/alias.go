@@ -0,0 +1,255 @@
+package api
+
+import (
+    "context"
+    "crypto/subtle"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/mail"
+    "strings"
+    "time"
+
+    "gobank/auth"
+    "gobank/claims"
+    "gobank/i18n"
+    "gobank/notify"
+    "gobank/storage"
+    "gobank/types"
+)
+
+const aliasCodeTTL = 24 * time.Hour
+
+// normalizeAlias returns the canonical form of an email or E.164 phone
+// alias and its kind.
+func normalizeAlias(alias string) (string, string, error) {
+    alias = strings.TrimSpace(alias)
+    if strings.HasPrefix(alias, "+") {
+        if !e164.MatchString(alias) {
+            return "", "", fmt.Errorf("phone must be in E.164 format, e.g. +14155550100")
+        }
+        return alias, types.AliasPhone, nil
+    }
+
+    addr, err := mail.ParseAddress(alias)
+    if err != nil || addr.Address != alias {
+        return "", "", fmt.Errorf("alias must be an email address or an E.164 phone number")
+    }
+
+    return strings.ToLower(alias), types.AliasEmail, nil
+}
+
+// handleAliases lists and registers aliases. A phone alias must be the
+// account's verified phone and is registered straight away; an email alias
+// is confirmed with a code sent to the address first.
+func (s *APIServer) handleAliases(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        aliases, err := s.store.GetAliasesByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, aliases)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.AliasRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+
+        alias, kind, err := normalizeAlias(req.Alias)
+        if err != nil {
+            return err
+        }
+
+        if kind == types.AliasPhone {
+            if !account.PhoneVerified || account.Phone != alias {
+                return fmt.Errorf("verify the phone number on the account before adding it as an alias")
+            }
+            return s.registerAlias(w, r, account, alias, kind)
+        }
+
+        if existing, err := s.store.GetAlias(r.Context(), alias); err == nil {
+            return fmt.Errorf("%s: %w", existing.Alias, storage.ErrAliasTaken)
+        }
+
+        code, err := newPhoneCode()
+        if err != nil {
+            return err
+        }
+        v := &types.AliasVerification{
+            AccountNumber: account.Number,
+            Alias: alias,
+            CodeHash: hashPhoneCode(code),
+            ExpiresAt: time.Now().UTC().Add(aliasCodeTTL),
+        }
+        if err := s.store.SaveAliasVerification(r.Context(), v); err != nil {
+            return err
+        }
+
+        s.notifier.Publish(notify.Event{
+            Type: notify.AliasVerification,
+            Account: account,
+            Email: alias,
+            Data: map[string]any{"code": code, "alias": alias},
+        })
+
+        return WriteJSON(w, http.StatusAccepted, map[string]string{"status": "verification code sent"})
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *APIServer) handleVerifyAlias(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.VerifyAliasRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    alias, kind, err := normalizeAlias(req.Alias)
+    if err != nil {
+        return err
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    v, err := s.store.GetAliasVerification(r.Context(), account.Number, alias)
+    if err != nil {
+        return fmt.Errorf("no verification in progress for %s", alias)
+    }
+
+    if time.Now().After(v.ExpiresAt) || v.Attempts >= phoneCodeMaxAttempts {
+        if err := s.store.DeleteAliasVerification(r.Context(), account.Number, alias); err != nil {
+            return err
+        }
+        return fmt.Errorf("verification code expired, request a new one")
+    }
+
+    if subtle.ConstantTimeCompare([]byte(hashPhoneCode(req.Code)), []byte(v.CodeHash)) != 1 {
+        v.Attempts++
+        if err := s.store.SaveAliasVerification(r.Context(), v); err != nil {
+            return err
+        }
+        return fmt.Errorf("invalid verification code")
+    }
+
+    if err := s.store.DeleteAliasVerification(r.Context(), account.Number, alias); err != nil {
+        return err
+    }
+
+    return s.registerAlias(w, r, account, alias, kind)
+}
+
+// registerAlias saves a verified alias and pays out any money that was sent
+// to it before it had an account.
+func (s *APIServer) registerAlias(w http.ResponseWriter, r *http.Request, account *types.Account, alias, kind string) error {
+    a := &types.Alias{
+        Alias: alias,
+        Kind: kind,
+        AccountNumber: account.Number,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.store.CreateAlias(r.Context(), a); err != nil {
+        return err
+    }
+
+    n, err := claims.Pay(r.Context(), s.store, s.cfg.FXRates, alias, account)
+    if err != nil {
+        // the alias is saved, the claims are retried the next time it is
+        // registered or returned to their senders when they expire
+        s.logger.Printf("paying claims for %s: %v", alias, err)
+    }
+    if n > 0 {
+        s.logger.Printf("paid %d alias claims for %s into account %d", n, alias, account.Number)
+    }
+
+    return WriteJSON(w, http.StatusOK, a)
+}
+
+func (s *APIServer) handleDeleteAlias(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "DELETE" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    alias, _, err := normalizeAlias(r.PathValue("alias"))
+    if err != nil {
+        return err
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    if err := s.store.DeleteAlias(r.Context(), account.Number, alias); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, map[string]string{"deleted": alias})
+}
+
+// accountForAlias returns the account an alias is registered to, or
+// storage.ErrNotFound when nobody has registered it.
+func (s *APIServer) accountForAlias(ctx context.Context, alias string) (*types.Account, error) {
+    a, err := s.store.GetAlias(ctx, alias)
+    if err != nil {
+        return nil, err
+    }
+
+    account, err := s.store.GetAccountByNumber(ctx, a.AccountNumber)
+    if errors.Is(err, storage.ErrNotFound) {
+        return nil, fmt.Errorf("alias %s belongs to a closed account", alias)
+    }
+
+    return account, err
+}
+
+// transferToUnclaimedAlias holds the money in suspense until someone
+// registers the alias and invites them to open an account. A blocked
+// transfer is turned down rather than queued for review, as there is no
+// account yet an approval could pay.
+func (s *APIServer) transferToUnclaimedAlias(w http.ResponseWriter, r *http.Request, plan *transferPlan) error {
+    from, alias, kind := plan.from, plan.alias, plan.aliasKind
+    tx, amount := plan.tx, plan.tx.Amount
+    if plan.decision.Action == types.FraudBlock {
+        return writeMessage(w, r, http.StatusForbidden, i18n.TransferBlocked)
+    }
+
+    claim := &types.AliasClaim{
+        Alias: alias,
+        ExpiresAt: tx.CreatedAt.Add(s.cfg.AliasClaimTTL),
+    }
+    err := s.store.CreateAliasClaim(r.Context(), claim, tx, plan.entries, s.cfg.VelocityLimits)
+    if errors.Is(err, storage.ErrIdempotencyKeyUsed) {
+        return s.replayTransfer(w, r, tx.IdempotencyKey, &types.TransferRequest{ToAlias: alias, Amount: amount})
+    }
+    if err != nil {
+        return err
+    }
+    s.recordTransferUsage(r.Context(), from.Number, amount)
+    if plan.decision.Action == types.FraudReview {
+        s.recordFraudCase(r.Context(), plan.decision, from.Number, types.SuspenseAccountNumber, amount, nil, tx)
+    }
+
+    invite := notify.Event{
+        Type: notify.AliasInvite,
+        // the recipient has no account yet, so write in the sender's locale
+        Account: &types.Account{Locale: from.Locale},
+        Data: map[string]any{
+            "sender": from.FirstName + " " + from.LastName,
+            "amount": amount,
+            "currency": from.Currency,
+            "alias": alias,
+            "expiresAt": claim.ExpiresAt,
+        },
+    }
+    if kind == types.AliasPhone {
+        invite.Phone = alias
+    } else {
+        invite.Email = alias
+    }
+    s.notifier.Publish(invite)
+
+    return WriteJSON(w, http.StatusAccepted, tx)
+}
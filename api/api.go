@@ -4,127 +4,504 @@ import (
     "log"
     "encoding/json"
     "net/http"
-    "fmt"
+    "net"
+    "slices"
+    "strings"
     "time"
-    jwt "github.com/golang-jwt/jwt/v4"
+    "context"
+    "errors"
+    "gobank/auth"
+    "gobank/chaos"
+    "gobank/clearing"
+    "gobank/config"
+    "gobank/documents"
+    "gobank/i18n"
+    "gobank/metrics"
+    "gobank/notify"
+    "gobank/slo"
     "gobank/storage"
     "gobank/types"
+    "gobank/webhook"
 )
 
 type APIServer struct {
     listenAddr string
     store storage.Storage
+    cfg config.Config
+    notifier *notify.Notifier
+    reports *reportCache
+    middleware []Middleware
+    blobs documents.BlobStore
+    scanner documents.Scanner
+    clearing clearing.Network
+    // webhooks is nil when no outbound webhook is configured
+    webhooks *webhook.Client
+    logger *log.Logger
+    tokenTTL time.Duration
+    // prefix is put in front of every route
+    prefix string
+    // listener is used by Run instead of listening on listenAddr
+    listener net.Listener
+    // admission is nil when admission control is off
+    admission *admission
+    maintenance *maintenance
+    transferSLO *slo.Tracker
+    loginSLO *slo.Tracker
+    // chaos is nil unless faults are injected for resilience testing
+    chaos *chaos.Injector
 }
 
-func NewApiServer(listenAddr string, store storage.Storage) *APIServer {
-    return &APIServer {
-        listenAddr: listenAddr,
+type Option func(*APIServer)
+
+// WithLogger sends the request log and everything else the server logs to
+// logger instead of the standard logger.
+func WithLogger(logger *log.Logger) Option {
+    return func(s *APIServer) {
+        s.logger = logger
+    }
+}
+
+// WithTokenTTL overrides how long login tokens are valid, see
+// config.Config.TokenTTL.
+func WithTokenTTL(ttl time.Duration) Option {
+    return func(s *APIServer) {
+        s.tokenTTL = ttl
+    }
+}
+
+// WithRouterPrefix serves every route under prefix, e.g. /bank/account/1
+// for /bank, so Handler can be mounted next to other routes of a program
+// that embeds the server.
+func WithRouterPrefix(prefix string) Option {
+    return func(s *APIServer) {
+        s.prefix = strings.TrimRight(prefix, "/")
+    }
+}
+
+// WithMiddleware adds middleware like Use does.
+func WithMiddleware(mw ...Middleware) Option {
+    return func(s *APIServer) {
+        s.Use(mw...)
+    }
+}
+
+// WithChaos drops outbound webhooks as i decides. Storage faults are
+// injected by wrapping the store with chaos.Wrap.
+func WithChaos(i *chaos.Injector) Option {
+    return func(s *APIServer) {
+        s.chaos = i
+    }
+}
+
+// WithListener makes Run accept connections on l instead of listening on
+// the configured address.
+func WithListener(l net.Listener) Option {
+    return func(s *APIServer) {
+        s.listener = l
+    }
+}
+
+func NewApiServer(cfg config.Config, store storage.Storage, notifier *notify.Notifier, opts ...Option) *APIServer {
+    var webhooks *webhook.Client
+    if cfg.OutboundWebhookURL != "" {
+        webhooks = webhook.NewClient(cfg.OutboundWebhookURL, []byte(cfg.OutboundWebhookSecret))
+    }
+
+    s := &APIServer {
+        listenAddr: cfg.ListenAddr,
         store: store,
+        cfg: cfg,
+        notifier: notifier,
+        reports: newReportCache(cfg.ReportRefreshInterval),
+        blobs: documents.NewDiskStore(cfg.DocumentDir),
+        scanner: documents.NopScanner{},
+        clearing: clearing.NewSandbox(cfg.ClearingSandboxDelay),
+        webhooks: webhooks,
+        logger: log.Default(),
+        tokenTTL: cfg.TokenTTL,
+        maintenance: new(maintenance),
+        transferSLO: slo.New("transfer", cfg.TransferSLO),
+        loginSLO: slo.New("login", cfg.LoginSLO),
     }
+    s.maintenance.set(cfg.Maintenance)
+    if cfg.MaxInFlight > 0 {
+        s.admission = newAdmission(cfg.MaxInFlight, cfg.ReservedInFlight, cfg.AdmissionMaxWait)
+    }
+    for _, opt := range opts {
+        opt(s)
+    }
+
+    return s
 }
 
 func (s *APIServer) Run() error {
-    router := http.NewServeMux()
+    l := s.listener
+    if l == nil {
+        lc := net.ListenConfig{KeepAlive: s.cfg.TCPKeepAlive}
+        var err error
+        if l, err = lc.Listen(context.Background(), "tcp", s.listenAddr); err != nil {
+            return err
+        }
+    }
 
-    router.HandleFunc("/login", makeHTTPHandleFunc(s.handleLogin))
-    router.HandleFunc("/account", makeHTTPHandleFunc(s.handleAccount))
-    router.HandleFunc("/account/", withJWTAuth(makeHTTPHandleFunc(s.handleAccountWithID), s.store))
-    router.HandleFunc("/transfer", makeHTTPHandleFunc(s.handleTransfer))
+    s.logger.Println("json API server running on: ", l.Addr())
 
-    log.Println("json API server running on port: ", s.listenAddr)
+    return s.Serve(l)
+}
 
+// Serve accepts connections on l, which lets callers pick the listener
+// (e.g. a random port in tests) instead of listenAddr.
+func (s *APIServer) Serve(l net.Listener) error {
     server := &http.Server{
-		Addr:         s.listenAddr,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: s.cfg.ReadHeaderTimeout,
+		ReadTimeout:       s.cfg.ReadTimeout,
+		WriteTimeout:      s.cfg.WriteTimeout,
+		IdleTimeout:       s.cfg.IdleTimeout,
+		MaxHeaderBytes:    s.cfg.MaxHeaderBytes,
+		HTTP2:             &http.HTTP2Config{MaxConcurrentStreams: s.cfg.MaxConcurrentStreams},
+		ErrorLog:          s.logger,
 	}
+	server.SetKeepAlivesEnabled(s.cfg.KeepAlives)
 
-	return server.ListenAndServe()
-}
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(s.cfg.H2C)
+	server.Protocols = protocols
 
+	return server.Serve(l)
+}
 
+// Handler returns every route of the API behind its middleware, for
+// programs that mount gobank in their own http.Server instead of calling
+// Run. The server's timeouts and protocols are then up to them.
+func (s *APIServer) Handler() http.Handler {
+    router := http.NewServeMux()
+    base := []Middleware{withRequestID, withLogging(s.logger)}
+    if s.cfg.Compression {
+        base = append(base, withCompression(s.cfg.CompressMinBytes))
+    }
+    base = append(base, s.withMaintenance)
+    root := routes{mux: router, prefix: s.prefix, middleware: slices.Concat(base, s.middleware)}
 
-func (s *APIServer) handleTransfer(w http.ResponseWriter, r *http.Request) error {
-    if r.Method == "POST" {
-        transferReq := new(types.TransferRequest)
-        if err := json.NewDecoder(r.Body).Decode(transferReq); err != nil {
-            return err
-        }
-        defer r.Body.Close()
+    // time spent waiting for admission doesn't count against the timeout
+    read := root.group(withAdmission(s.admission, priorityNormal), withTimeout(s.cfg.ReadRequestTimeout))
+    money := root.group(withAdmission(s.admission, priorityHigh), withTimeout(s.cfg.MoneyRequestTimeout))
+    // requests turned away for load count against the SLOs as well
+    transfers := root.group(withSLO(s.transferSLO), withAdmission(s.admission, priorityHigh), withTimeout(s.cfg.MoneyRequestTimeout))
+    login := root.group(withSLO(s.loginSLO), withAdmission(s.admission, priorityHigh), withTimeout(s.cfg.ReadRequestTimeout))
+    bulk := root.group(withAdmission(s.admission, priorityLow), withTimeout(s.cfg.ReadRequestTimeout))
 
-        return WriteJSON(w, http.StatusOK, transferReq)
+    // every authenticated request counts against its account's quota
+    authenticated := func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        return withJWTAuth(s.store, s.logger)(s.withQuota(handlerFunc))
     }
-    return fmt.Errorf("method %s  not supported, you should use POST instead", r.Method)
+
+    public := read
+    account := read.group(authenticated)
+    holder := account.group(withPrimaryOwner)
+    // changing who can use the account takes a session that passed step-up
+    access := holder.group(withStepUp)
+    admin := read.group(withAdminAuth(s.cfg.AdminToken))
+    // external callers authenticate themselves in the handler
+    external := money
+    moneyMovement := money.group(authenticated, withStepUp, withActiveAccount, s.withSignature)
+    adminMoney := money.group(withAdminAuth(s.cfg.AdminToken))
+
+    login.handle("/login", makeHTTPHandleFunc(s.handleLogin))
+    login.handle("/login/activate", makeHTTPHandleFunc(s.handleActivateAccount))
+    login.handle("/login/step-up", makeHTTPHandleFunc(s.handleStepUp), authenticated)
+    public.handle("/account", makeHTTPHandleFunc(s.handleAccount))
+    public.handle("/onboarding", makeHTTPHandleFunc(s.handleOnboarding))
+    holder.handle("/invitations", makeHTTPHandleFunc(s.handleInvitations), s.withSharingChecks)
+    account.handle("/account/{id}", makeHTTPHandleFunc(s.handleAccountWithID))
+    account.handle("/account/{id}/alerts", makeHTTPHandleFunc(s.handleAlerts))
+    account.handle("/account/{id}/alerts/{ruleID}", makeHTTPHandleFunc(s.handleDeleteAlert))
+    holder.handle("/account/{id}/aliases", makeHTTPHandleFunc(s.handleAliases))
+    holder.handle("/account/{id}/aliases/verify", makeHTTPHandleFunc(s.handleVerifyAlias))
+    holder.handle("/account/{id}/aliases/{alias}", makeHTTPHandleFunc(s.handleDeleteAlias))
+    bulk.handle("/account/{id}/audit", makeHTTPHandleFunc(s.handleAuditLog), authenticated)
+    account.handle("/account/{id}/balance", makeHTTPHandleFunc(s.handleAccountBalance))
+    account.handle("/account/{id}/loans", makeHTTPHandleFunc(s.handleLoans))
+    account.handle("/account/{id}/loans/{loanID}", makeHTTPHandleFunc(s.handleLoan))
+    account.handle("/account/{id}/pots", makeHTTPHandleFunc(s.handlePots))
+    account.handle("/account/{id}/pots/{potID}", makeHTTPHandleFunc(s.handlePot))
+    moneyMovement.handle("/account/{id}/pots/{potID}/{action}", makeHTTPHandleFunc(s.handlePotAction))
+    holder.handle("/account/{id}/notifications", makeHTTPHandleFunc(s.handleNotificationPreferences))
+    access.handle("/account/{id}/owners", makeHTTPHandleFunc(s.handleOwners))
+    access.handle("/account/{id}/owners/{ownerNumber}", makeHTTPHandleFunc(s.handleDeleteOwner))
+    access.handle("/account/{id}/grants", makeHTTPHandleFunc(s.handleGrants))
+    access.handle("/account/{id}/grants/{grantID}", makeHTTPHandleFunc(s.handleRevokeGrant))
+    access.handle("/account/{id}/invitations", makeHTTPHandleFunc(s.handleOwnerInvitations))
+    access.handle("/account/{id}/invitations/{invitationID}/{action}", makeHTTPHandleFunc(s.handleOwnerInvitationAction))
+    // closing moves the balance, but a dormant account can still be closed
+    money.handle("/account/{id}/close", makeHTTPHandleFunc(s.handleCloseAccount), authenticated, withPrimaryOwner, withStepUp, s.withSignature)
+    holder.handle("/account/{id}/reactivate", makeHTTPHandleFunc(s.handleReactivate))
+    holder.handle("/account/{id}/reactivate/verify", makeHTTPHandleFunc(s.handleVerifyReactivate))
+    holder.handle("/account/{id}/phone", makeHTTPHandleFunc(s.handlePhone))
+    holder.handle("/account/{id}/phone/verify", makeHTTPHandleFunc(s.handleVerifyPhone))
+    account.handle("/account/{id}/cards", makeHTTPHandleFunc(s.handleCards))
+    account.handle("/account/{id}/cards/{cardID}", makeHTTPHandleFunc(s.handleCard))
+    account.handle("/account/{id}/cards/{cardID}/{action}", makeHTTPHandleFunc(s.handleCardAction))
+    account.handle("/account/{id}/cards/{cardID}/pin", makeHTTPHandleFunc(s.handleCardPIN))
+    account.handle("/account/{id}/cards/{cardID}/pin/verify", makeHTTPHandleFunc(s.handleVerifyCardPIN))
+    holder.handle("/account/{id}/devices", makeHTTPHandleFunc(s.handleDevices))
+    holder.handle("/account/{id}/devices/{deviceID}", makeHTTPHandleFunc(s.handleDeleteDevice))
+    access.handle("/account/{id}/signing-keys", makeHTTPHandleFunc(s.handleSigningKeys))
+    access.handle("/account/{id}/signing-keys/{keyID}", makeHTTPHandleFunc(s.handleDeleteSigningKey), s.withSignature)
+    holder.handle("/account/{id}/documents", makeHTTPHandleFunc(s.handleDocuments))
+    holder.handle("/account/{id}/documents/{documentID}", makeHTTPHandleFunc(s.handleDocument))
+    public.handle("/documents/{documentID}/download", makeHTTPHandleFunc(s.handleDownloadDocument))
+    holder.handle("/account/{id}/disputes", makeHTTPHandleFunc(s.handleDisputes))
+    account.handle("/account/{id}/disputes/{disputeID}", makeHTTPHandleFunc(s.handleDispute))
+    account.handle("/account/{id}/holds", makeHTTPHandleFunc(s.handleHolds))
+    external.handle("/cards/authorize", makeHTTPHandleFunc(s.handleAuthorize))
+    external.handle("/cards/holds/{holdID}/{action}", makeHTTPHandleFunc(s.handleHoldAction))
+    account.handle("/account/{id}/qr", makeHTTPHandleFunc(s.handleQR))
+    account.handle("/qr/decode", makeHTTPHandleFunc(s.handleDecodeQR))
+    account.handle("/account/{id}/requests", makeHTTPHandleFunc(s.handlePaymentRequests))
+    moneyMovement.handle("/account/{id}/requests/{requestID}/{action}", makeHTTPHandleFunc(s.handlePaymentRequestAction))
+    bulk.handle("/account/{id}/transactions", makeHTTPHandleFunc(s.handleAccountTransactions), authenticated)
+    holder.handle("/account/{id}/usage", makeHTTPHandleFunc(s.handleUsage))
+    transfers.handle("/transfer", makeHTTPHandleFunc(s.handleTransfer), authenticated, withStepUp, withActiveAccount, s.withSignature)
+    // previews move nothing, so they don't need a step-up or signature
+    account.handle("/transfer/preview", makeHTTPHandleFunc(s.handleTransferPreview), withActiveAccount)
+    // the scope has to be set before withJWTAuth checks grants
+    transfers.handle("/account/{id}/transfer", makeHTTPHandleFunc(s.handleTransfer), withScope(types.ScopeTransfer), authenticated, withStepUp, withActiveAccount, s.withSignature)
+    transfers.handle("/account/{id}/transfer/external", makeHTTPHandleFunc(s.handleExternalTransfer), withScope(types.ScopeTransfer), authenticated, withStepUp, withActiveAccount, s.withSignature)
+    account.handle("/account/{id}/external-transfers", makeHTTPHandleFunc(s.handleExternalTransfers))
+    account.handle("/account/{id}/external-transfers/{transferID}", makeHTTPHandleFunc(s.handleExternalTransferByID))
+    external.handle("/webhooks/inbound/{provider}", makeHTTPHandleFunc(s.handleInboundWebhook))
+    adminMoney.handle("/transactions/{transactionID}/reverse", makeHTTPHandleFunc(s.handleReverseTransaction))
+    admin.handle("/admin/accounts", makeHTTPHandleFunc(s.handleAdminAccounts))
+    adminMoney.handle("/admin/accounts/import", makeHTTPHandleFunc(s.handleImportAccounts))
+    bulk.handle("/admin/reconciliation", makeHTTPHandleFunc(s.handleReconciliation), withAdminAuth(s.cfg.AdminToken))
+    admin.handle("/admin/cards/{cardID}/unblock", makeHTTPHandleFunc(s.handleUnblockCard))
+    admin.handle("/admin/loans", makeHTTPHandleFunc(s.handleAdminLoans))
+    adminMoney.handle("/admin/loans/{loanID}/{action}", makeHTTPHandleFunc(s.handleAdminLoanAction))
+    admin.handle("/admin/products/rates", makeHTTPHandleFunc(s.handleProductRates))
+    admin.handle("/admin/products/rates/{rateID}", makeHTTPHandleFunc(s.handleProductRate))
+    admin.handle("/admin/fraud/cases", makeHTTPHandleFunc(s.handleFraudCases))
+    adminMoney.handle("/admin/fraud/cases/{caseID}/{action}", makeHTTPHandleFunc(s.handleFraudCaseAction))
+    bulk.handle("/admin/reports/flows", makeHTTPHandleFunc(s.handleFlowReport), withAdminAuth(s.cfg.AdminToken))
+    bulk.handle("/admin/reports/largest-transfers", makeHTTPHandleFunc(s.handleLargestTransfersReport), withAdminAuth(s.cfg.AdminToken))
+    bulk.handle("/admin/usage", makeHTTPHandleFunc(s.handleAdminUsage), withAdminAuth(s.cfg.AdminToken))
+    admin.handle("/admin/disputes", makeHTTPHandleFunc(s.handleAdminDisputes))
+    adminMoney.handle("/admin/disputes/{disputeID}/{action}", makeHTTPHandleFunc(s.handleDisputeAction))
+    admin.handle("/admin/documents/{documentID}", makeHTTPHandleFunc(s.handleAdminDocument))
+    admin.handle("/admin/jobs", makeHTTPHandleFunc(s.handleJobs))
+    admin.handle("/admin/dead-letters", makeHTTPHandleFunc(s.handleDeadLetters))
+    admin.handle("/admin/dead-letters/{letterID}", makeHTTPHandleFunc(s.handleDeadLetter))
+    adminMoney.handle("/admin/dead-letters/{letterID}/replay", makeHTTPHandleFunc(s.handleReplayDeadLetter))
+    admin.handle("/admin/invitations", makeHTTPHandleFunc(s.handleAdminInvitations))
+    admin.handle("/admin/maintenance", makeHTTPHandleFunc(s.handleMaintenance))
+    admin.handle("/admin/slo", makeHTTPHandleFunc(s.handleSLO))
+    root.handle("/metrics", metrics.Handler().ServeHTTP)
+    root.handle("/healthz", makeHTTPHandleFunc(s.handleHealthz))
+
+    return router
 }
 
 
+
+// decodeJSON decodes the request body into v, refusing bodies larger than
+// the configured MaxBodyBytes.
+func (s *APIServer) decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+    body := http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes)
+    defer body.Close()
+
+    return json.NewDecoder(body).Decode(v)
+}
+
 func WriteJSON(w http.ResponseWriter, status int, v any) error {
     w.Header().Add("Content-Type", "application/json")
     w.WriteHeader(status)
     return json.NewEncoder(w).Encode(v)
 }
 
-func withJWTAuth(handlerFunc http.HandlerFunc, s storage.Storage) http.HandlerFunc {
-    return func(w http.ResponseWriter, r *http.Request) {
-        log.Println("calling JWT auth middleware")
+// withTimeout gives the request a context deadline of d. Storage calls made
+// with r.Context() are cancelled once it passes and the handler error is
+// turned into a 504 by makeHTTPHandleFunc.
+func withTimeout(d time.Duration) Middleware {
+    return func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+            ctx, cancel := context.WithTimeout(r.Context(), d)
+            defer cancel()
 
-        userID, err := getID(r)
-        if err != nil {
-            WriteJSON(w, http.StatusForbidden, ApiError{Error: "permission denied"})
-            return
+            handlerFunc(w, r.WithContext(ctx))
         }
+    }
+}
 
-        tokenString := r.Header.Get("x-jwt-token")
-        token, err := validateJWT(tokenString)
-        if err != nil {
-            WriteJSON(w, http.StatusForbidden, ApiError{Error: "permission denied"})
-            return
-        }
-        if !token.Valid {
-            WriteJSON(w, http.StatusForbidden, ApiError{Error: "permission denied"})
-            return
-        }
+// withJWTAuth only lets requests with a valid token through. On routes with
+// an {id} the token must belong to that account, one of its other owners
+// or someone it granted access to; see delegatedAccess. The account is
+// available to the handler through auth.AccountFromContext and the token
+// holder through auth.ActorFromContext.
+func withJWTAuth(s storage.Storage, logger *log.Logger) Middleware {
+    return func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+            claims, err := auth.ParseToken(jwtSecret(), r.Header.Get("x-jwt-token"))
+            if err != nil {
+                writeMessage(w, r, http.StatusForbidden, i18n.PermissionDenied)
+                return
+            }
+            number := claims.AccountNumber
 
-        if err != nil {
-            WriteJSON(w, http.StatusForbidden, ApiError{Error: "permission denied"})
-            return
-        }
+            var account *types.Account
+            if r.PathValue("id") != "" {
+                userID, err := getID(r)
+                if err != nil {
+                    writeMessage(w, r, http.StatusForbidden, i18n.PermissionDenied)
+                    return
+                }
+                account, err = s.GetAccountByID(r.Context(), userID)
+            } else {
+                account, err = s.GetAccountByNumber(r.Context(), number)
+            }
+            if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, storage.ErrUnavailable) {
+                writeError(w, r, err)
+                return
+            }
+            if err != nil {
+                WriteJSON(w, http.StatusBadRequest, ApiError{Error: "This account does not exist"})
+                return
+            }
 
-        account, err := s.GetAccountByID(userID)
-        if err != nil {
-            WriteJSON(w, http.StatusBadRequest, ApiError{Error: "This account does not exist"})
-            return
-        }
+            var grant *types.Grant
+            if account.Number != number {
+                grant, err = delegatedAccess(r, s, account, number)
+                if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, storage.ErrUnavailable) {
+                    writeError(w, r, err)
+                    return
+                }
+                if err != nil {
+                    writeMessage(w, r, http.StatusForbidden, i18n.PermissionDenied)
+                    return
+                }
+            }
 
-        claims := token.Claims.(jwt.MapClaims)
-        if account.Number != int64(claims["accountNumber"].(float64)) {
-            WriteJSON(w, http.StatusForbidden, ApiError{Error: "permission denied"})
-            return
+            ctx := auth.WithIdentity(r.Context(), &auth.Identity{Claims: claims, Account: account, Grant: grant})
+            audited(handlerFunc, s, logger, w, r.WithContext(ctx))
         }
-
-        fmt.Println(claims["accountNumber"])
-
-        handlerFunc(w, r)
     }
 }
 
 
 
+
 type apiFunc func(http.ResponseWriter, *http.Request) error
 
 type ApiError struct {
     Error string `json:"error"`
+    Code string `json:"code,omitempty"`
 }
 
 func makeHTTPHandleFunc(f apiFunc) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if err := f(w, r); err != nil {
             // handle the error
-            WriteJSON(w, http.StatusBadRequest, ApiError{Error: err.Error()})
+            writeError(w, r, err)
         }
     }
 }
 
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+    status := errorStatus(err)
+    if status == http.StatusServiceUnavailable {
+        w.Header().Set("Retry-After", "10")
+    }
+
+    if code := errorCode(err); code != "" {
+        writeMessage(w, r, status, code)
+        return
+    }
+
+    WriteJSON(w, status, ApiError{Error: err.Error()})
+}
+
+// writeMessage answers with the catalog message for code, in the language
+// the request asks for.
+func writeMessage(w http.ResponseWriter, r *http.Request, status int, code string) error {
+    return WriteJSON(w, status, ApiError{Error: i18n.Message(requestLocale(r), code), Code: code})
+}
+
+// requestLocale picks the locale from Accept-Language, then from the
+// account the request is for.
+func requestLocale(r *http.Request) string {
+    if v := r.Header.Get("Accept-Language"); v != "" {
+        return i18n.Match(v)
+    }
+    if account := auth.AccountFromContext(r.Context()); account != nil && account.Locale != "" {
+        return account.Locale
+    }
+
+    return i18n.DefaultLocale
+}
+
+// errorCode returns the catalog code for errors users are expected to
+// see, or "" to pass the error text through.
+func errorCode(err error) string {
+    switch {
+    case errors.Is(err, storage.ErrUnavailable):
+        return i18n.StorageUnavailable
+    case errors.Is(err, context.DeadlineExceeded):
+        return i18n.Timeout
+    case errors.Is(err, storage.ErrInsufficientFunds):
+        return i18n.InsufficientFunds
+    case errors.Is(err, storage.ErrVelocityExceeded):
+        return i18n.LimitExceeded
+    case errors.Is(err, storage.ErrQuotaExceeded):
+        return i18n.QuotaExceeded
+    }
+
+    return ""
+}
+
+func errorStatus(err error) int {
+    if errors.Is(err, storage.ErrUnavailable) {
+        return http.StatusServiceUnavailable
+    }
+
+    if errors.Is(err, storage.ErrVelocityExceeded) || errors.Is(err, storage.ErrQuotaExceeded) {
+        return http.StatusTooManyRequests
+    }
+
+    if errors.Is(err, storage.ErrInsufficientFunds) ||
+        errors.Is(err, storage.ErrIdempotencyKeyUsed) ||
+        errors.Is(err, documents.ErrInfected) {
+        return http.StatusUnprocessableEntity
+    }
+
+    if errors.Is(err, storage.ErrNotPending) ||
+        errors.Is(err, storage.ErrAliasTaken) ||
+        errors.Is(err, storage.ErrRequestClosed) ||
+        errors.Is(err, storage.ErrLoanState) ||
+        errors.Is(err, storage.ErrRateInEffect) ||
+        errors.Is(err, storage.ErrCaseClosed) ||
+        errors.Is(err, storage.ErrHoldClosed) ||
+        errors.Is(err, storage.ErrAlreadyReplayed) ||
+        errors.Is(err, storage.ErrAlreadyDisputed) ||
+        errors.Is(err, storage.ErrDisputeClosed) ||
+        errors.Is(err, storage.ErrAccountClosed) ||
+        errors.Is(err, storage.ErrClosureBlocked) ||
+        errors.Is(err, storage.ErrAlreadyReversed) ||
+        errors.Is(err, storage.ErrInvitationClosed) {
+        return http.StatusConflict
+    }
+
+    if errors.Is(err, context.DeadlineExceeded) {
+        return http.StatusGatewayTimeout
+    }
+
+    var maxBytesErr *http.MaxBytesError
+    if errors.As(err, &maxBytesErr) {
+        return http.StatusRequestEntityTooLarge
+    }
+
+    return http.StatusBadRequest
+}
+
 
 
@@ -0,0 +1,2279 @@
+package api_test
+
+import (
+    "bytes"
+    "compress/gzip"
+    "compress/zlib"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/api"
+    "gobank/api/apitest"
+    "gobank/config"
+    "gobank/importer"
+    "gobank/notify"
+    "gobank/signing"
+    "gobank/slo"
+    "gobank/storage/storagetest"
+    "gobank/types"
+    "gobank/webhook"
+)
+
+func TestGetAccountByIDRequiresToken(t *testing.T) {
+    srv := apitest.NewServer(t)
+    acc := srv.CreateAccount(t, "a", "b", "pw")
+
+    resp := srv.Do(t, "GET", fmt.Sprintf("/account/%d", acc.ID), "", nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    token := srv.Login(t, acc.Number, "pw")
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d", acc.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    got := new(types.Account)
+    assert.Nil(t, json.NewDecoder(resp.Body).Decode(got))
+    assert.Equal(t, acc.Number, got.Number)
+}
+
+func TestOnlyAdminsListAccounts(t *testing.T) {
+    srv := apitest.NewServer(t)
+    acc := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, acc.Number, "pw")
+
+    resp := srv.Do(t, "GET", "/account", "", nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = srv.Do(t, "GET", "/admin/accounts", token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "GET", "/admin/accounts", nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    accounts := []*types.Account{}
+    json.NewDecoder(resp.Body).Decode(&accounts)
+    assert.Len(t, accounts, 1)
+}
+
+func TestGetAccountsStorageError(t *testing.T) {
+    srv := apitest.NewServer(t)
+    srv.Store.FailOn("GetAccounts", errors.New("db is down"))
+
+    resp := srv.DoAdmin(t, "GET", "/admin/accounts", nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestCreateAccountBodyTooLarge(t *testing.T) {
+    srv := apitest.NewServer(t)
+
+    body := map[string]string{"firstName": strings.Repeat("a", 2<<20)}
+    resp := srv.Do(t, "POST", "/account", "", body)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+func TestSlowStorageTimesOut(t *testing.T) {
+    srv := apitest.NewServer(t)
+    srv.Store.SetLatency(10 * time.Second)
+
+    start := time.Now()
+    resp := srv.DoAdmin(t, "GET", "/admin/accounts", nil)
+    defer resp.Body.Close()
+
+    assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+    assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestTransferPostsLedgerEntries(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 400})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 700})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    ctx := context.Background()
+    got, _ := srv.Store.GetAccountByNumber(ctx, bob.Number)
+    assert.Equal(t, int64(400), got.Balance)
+
+    balance, _ := srv.Store.GetLedgerBalance(ctx, alice.Number)
+    assert.Equal(t, int64(600), balance)
+}
+
+func TestTransferIdempotencyKeyPostsOnce(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    send := func(key string, amount int64) (*http.Response, *types.Transaction) {
+        body, _ := json.Marshal(types.TransferRequest{ToAccount: bob.Number, Amount: amount})
+        req, _ := http.NewRequest("POST", srv.URL+"/transfer", bytes.NewReader(body))
+        req.Header.Set("x-jwt-token", token)
+        req.Header.Set("Idempotency-Key", key)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+        tx := new(types.Transaction)
+        json.NewDecoder(resp.Body).Decode(tx)
+        return resp, tx
+    }
+
+    resp, first := send("k1", 400)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Empty(t, resp.Header.Get("Idempotent-Replayed"))
+
+    // the response was lost, the client sends it again
+    resp, again := send("k1", 400)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+    assert.Equal(t, first.ID, again.ID)
+
+    // a key belongs to one transfer
+    resp, _ = send("k1", 500)
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    resp, other := send("k2", 400)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.NotEqual(t, first.ID, other.ID)
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), bob.Number)
+    assert.Equal(t, int64(800), got.Balance)
+}
+
+func TestTransferConvertsCurrency(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    bob.Currency = "EUR"
+    ctx := context.Background()
+    if err := srv.Store.UpdateAccount(ctx, bob); err != nil {
+        t.Fatal(err)
+    }
+    srv.Fund(t, alice.Number, 1000)
+
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/alerts", alice.ID), token, types.CreateAlertRuleRequest{Kind: types.AlertForeignCurrency})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 108})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    got, _ := srv.Store.GetAccountByNumber(ctx, bob.Number)
+    assert.Equal(t, int64(100), got.Balance)
+
+    fx, _ := srv.Store.GetLedgerBalance(ctx, types.FXAccountNumber)
+    assert.Equal(t, int64(8), fx)
+}
+
+func TestTransferPreview(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    bob.Currency = "EUR"
+    ctx := context.Background()
+    if err := srv.Store.UpdateAccount(ctx, bob); err != nil {
+        t.Fatal(err)
+    }
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/transfer/preview", token, types.TransferRequest{ToAccount: bob.Number, Amount: 108})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    preview := new(types.TransferPreview)
+    json.NewDecoder(resp.Body).Decode(preview)
+    assert.Equal(t, types.PreviewCompleted, preview.Outcome)
+    assert.Equal(t, int64(100), preview.ConvertedAmount)
+    assert.Equal(t, "EUR", preview.ToCurrency)
+    assert.Equal(t, int64(0), preview.Fee)
+    assert.NotEmpty(t, preview.Entries)
+
+    // nothing was posted
+    got, _ := srv.Store.GetAccountByNumber(ctx, alice.Number)
+    assert.Equal(t, int64(1000), got.Balance)
+    txs, _ := srv.Store.GetTransactionsByAccount(ctx, alice.Number)
+    assert.Len(t, txs, 1)
+
+    resp = srv.Do(t, "POST", "/transfer/preview", token, types.TransferRequest{ToAccount: bob.Number, Amount: 2000})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer/preview", token, types.TransferRequest{ToAlias: "carol@example.com", Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    preview = new(types.TransferPreview)
+    json.NewDecoder(resp.Body).Decode(preview)
+    assert.Equal(t, types.PreviewPending, preview.Outcome)
+
+    // a large first payment would be held, without opening a case
+    srv.Fund(t, alice.Number, 1000000)
+    resp = srv.Do(t, "POST", "/transfer/preview", token, types.TransferRequest{ToAccount: bob.Number, Amount: 300000})
+    defer resp.Body.Close()
+    preview = new(types.TransferPreview)
+    json.NewDecoder(resp.Body).Decode(preview)
+    assert.Equal(t, types.PreviewBlocked, preview.Outcome)
+    cases, _ := srv.Store.GetFraudCasesByStatus(ctx, types.FraudCasePending)
+    assert.Empty(t, cases)
+}
+
+func TestTransferPreviewShowsTheFee(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    ctx := context.Background()
+    rate := &types.ProductRate{Key: types.ProductTransferFee, Value: 25, EffectiveFrom: time.Now().UTC().Add(-time.Hour), CreatedAt: time.Now().UTC()}
+    if err := srv.Store.CreateProductRate(ctx, rate); err != nil {
+        t.Fatal(err)
+    }
+
+    resp := srv.Do(t, "POST", "/transfer/preview", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    preview := new(types.TransferPreview)
+    json.NewDecoder(resp.Body).Decode(preview)
+    assert.Equal(t, int64(25), preview.Fee)
+
+    // the fee counts towards the balance the transfer needs
+    resp = srv.Do(t, "POST", "/transfer/preview", token, types.TransferRequest{ToAccount: bob.Number, Amount: 990})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    got, _ := srv.Store.GetAccountByNumber(ctx, alice.Number)
+    assert.Equal(t, int64(1000-100-25), got.Balance)
+    fees, _ := srv.Store.GetLedgerBalance(ctx, types.FeeAccountNumber)
+    assert.Equal(t, int64(25), fees)
+}
+
+func TestInboundWebhookSettlesPendingTransfer(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    srv.Fund(t, alice.Number, 1000)
+
+    ctx := context.Background()
+    pending := &types.Transaction{
+        Kind: types.TransactionTransfer,
+        Status: types.StatusPending,
+        FromAccount: alice.Number,
+        ToAccount: types.SettlementAccountNumber,
+        Amount: 300,
+        Provider: apitest.WebhookProvider,
+        Reference: "ref-1",
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := srv.Store.PostTransaction(ctx, pending, types.NewEntries(alice.Number, types.SettlementAccountNumber, 300)); err != nil {
+        t.Fatal(err)
+    }
+
+    send := func(secret string) *http.Response {
+        body := []byte(`{"reference":"ref-1","status":"failed","reason":"account closed"}`)
+        req, _ := http.NewRequest("POST", srv.URL+"/webhooks/inbound/"+apitest.WebhookProvider, bytes.NewReader(body))
+        req.Header.Set(webhook.SignatureHeader, webhook.Sign([]byte(secret), time.Now(), body))
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        return resp
+    }
+
+    assert.Equal(t, http.StatusUnauthorized, send("wrong").StatusCode)
+    assert.Equal(t, http.StatusOK, send(apitest.WebhookSecret).StatusCode)
+    // redelivery must not return the money twice
+    assert.Equal(t, http.StatusOK, send(apitest.WebhookSecret).StatusCode)
+
+    got, _ := srv.Store.GetAccountByNumber(ctx, alice.Number)
+    assert.Equal(t, int64(1000), got.Balance)
+
+    tx, _ := srv.Store.GetTransactionByReference(ctx, apitest.WebhookProvider, "ref-1")
+    assert.Equal(t, types.StatusFailed, tx.Status)
+}
+
+func TestTransferToAliasIsClaimedOnRegistration(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+
+    ctx := context.Background()
+    bob.Phone = "+14155550123"
+    bob.PhoneVerified = true
+    if err := srv.Store.UpdateAccount(ctx, bob); err != nil {
+        t.Fatal(err)
+    }
+
+    aliceToken := srv.Login(t, alice.Number, "pw")
+    resp := srv.Do(t, "POST", "/transfer", aliceToken, types.TransferRequest{ToAlias: bob.Phone, Amount: 250})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+    bobToken := srv.Login(t, bob.Number, "pw")
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/aliases", bob.ID), bobToken, types.AliasRequest{Alias: bob.Phone})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    got, _ := srv.Store.GetAccountByNumber(ctx, bob.Number)
+    assert.Equal(t, int64(250), got.Balance)
+
+    resp = srv.Do(t, "POST", "/transfer", aliceToken, types.TransferRequest{ToAlias: bob.Phone, Amount: 50})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    got, _ = srv.Store.GetAccountByNumber(ctx, bob.Number)
+    assert.Equal(t, int64(300), got.Balance)
+}
+
+func TestPaymentRequestAccept(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, bob.Number, 1000)
+
+    aliceToken := srv.Login(t, alice.Number, "pw")
+    bobToken := srv.Login(t, bob.Number, "pw")
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/requests", alice.ID), aliceToken, types.CreatePaymentRequestRequest{PayerAccount: bob.Number, Amount: 200, Memo: "dinner"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    pr := new(types.PaymentRequest)
+    json.NewDecoder(resp.Body).Decode(pr)
+
+    // only the payer can accept
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/requests/%d/accept", alice.ID, pr.ID), aliceToken, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/requests/%d/accept", bob.ID, pr.ID), bobToken, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/requests/%d/accept", bob.ID, pr.ID), bobToken, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    ctx := context.Background()
+    got, _ := srv.Store.GetAccountByNumber(ctx, alice.Number)
+    assert.Equal(t, int64(200), got.Balance)
+
+    stored, _ := srv.Store.GetPaymentRequest(ctx, pr.ID)
+    assert.Equal(t, types.RequestAccepted, stored.Status)
+    assert.NotZero(t, stored.TransactionID)
+}
+
+func TestPaymentRequestIsCheckedLikeATransfer(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, bob.Number, 1000000)
+    bobToken := srv.Login(t, bob.Number, "pw")
+    ctx := context.Background()
+
+    request := func(amount int64) *types.PaymentRequest {
+        pr := &types.PaymentRequest{RequesterAccount: alice.Number, PayerAccount: bob.Number, Amount: amount, Status: types.RequestPending, ExpiresAt: time.Now().Add(time.Hour)}
+        srv.Store.CreatePaymentRequest(ctx, pr)
+        return pr
+    }
+    accept := func(pr *types.PaymentRequest) int {
+        resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/requests/%d/accept", bob.ID, pr.ID), bobToken, nil)
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+
+    // a large first payment to alice is blocked
+    large := request(300000)
+    assert.Equal(t, http.StatusForbidden, accept(large))
+    stored, _ := srv.Store.GetPaymentRequest(ctx, large.ID)
+    assert.Equal(t, types.RequestPending, stored.Status)
+
+    // closing cancels alice's requests, this one slipped in after
+    if err := srv.Store.CloseAccount(ctx, alice.Number, nil, nil, nil, time.Now()); err != nil {
+        t.Fatal(err)
+    }
+    closed := request(100)
+    assert.Equal(t, http.StatusConflict, accept(closed))
+    got, _ := srv.Store.GetAccountByNumber(ctx, bob.Number)
+    assert.Equal(t, int64(1000000), got.Balance)
+}
+
+func TestQRPayloadRoundTrip(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "smith", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+
+    aliceToken := srv.Login(t, alice.Number, "pw")
+    resp := srv.Do(t, "GET", fmt.Sprintf("/account/%d/qr?amount=300&memo=lunch", alice.ID), aliceToken, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    code := new(types.QRCode)
+    json.NewDecoder(resp.Body).Decode(code)
+
+    bobToken := srv.Login(t, bob.Number, "pw")
+    resp = srv.Do(t, "POST", "/qr/decode", bobToken, types.DecodeQRRequest{Payload: code.Payload})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    decoded := new(types.DecodedQR)
+    json.NewDecoder(resp.Body).Decode(decoded)
+    assert.Equal(t, alice.Number, decoded.Account)
+    assert.Equal(t, int64(300), decoded.Amount)
+    assert.Equal(t, "lunch", decoded.Memo)
+    assert.Equal(t, "alice s.", decoded.RecipientName)
+
+    resp = srv.Do(t, "POST", "/qr/decode", bobToken, types.DecodeQRRequest{Payload: code.Payload + "x"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestCardAuthorizationPlacesHolds(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/cards", alice.ID), token, types.IssueCardRequest{DailyLimit: 800})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    card := new(types.IssuedCard)
+    json.NewDecoder(resp.Body).Decode(card)
+    assert.Len(t, card.PAN, 16)
+
+    authorize := func(amount int64, cvv string) *types.AuthorizationResponse {
+        body, _ := json.Marshal(types.AuthorizationRequest{
+            PAN: card.PAN,
+            CVV: cvv,
+            ExpiryMonth: card.ExpiryMonth,
+            ExpiryYear: card.ExpiryYear,
+            Amount: amount,
+            Merchant: "coffee shop",
+        })
+        req, _ := http.NewRequest("POST", srv.URL+"/cards/authorize", bytes.NewReader(body))
+        req.Header.Set(webhook.SignatureHeader, webhook.Sign([]byte(apitest.CardNetworkSecret), time.Now(), body))
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+        assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+        out := new(types.AuthorizationResponse)
+        json.NewDecoder(resp.Body).Decode(out)
+        return out
+    }
+
+    assert.True(t, authorize(500, card.CVV).Approved)
+    assert.Equal(t, "invalid_cvv", authorize(10, "000"+card.CVV).DeclineReason)
+    assert.Equal(t, "limit_exceeded", authorize(400, card.CVV).DeclineReason)
+
+    resp = srv.Do(t, "PUT", fmt.Sprintf("/account/%d/cards/%d", alice.ID, card.ID), token, types.CardLimitRequest{})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    // 500 of the 1000 is held already
+    assert.Equal(t, "insufficient_funds", authorize(600, card.CVV).DeclineReason)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/cards/%d/freeze", alice.ID, card.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Equal(t, "card_frozen", authorize(10, card.CVV).DeclineReason)
+
+    holds, _ := srv.Store.GetActiveHolds(context.Background(), alice.Number, time.Now())
+    if !assert.Len(t, holds, 1) {
+        return
+    }
+
+    // held money can't be transferred either
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 600})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    holdAction := func(id int, action string, body []byte) int {
+        req, _ := http.NewRequest("POST", fmt.Sprintf("%s/cards/holds/%d/%s", srv.URL, id, action), bytes.NewReader(body))
+        req.Header.Set(webhook.SignatureHeader, webhook.Sign([]byte(apitest.CardNetworkSecret), time.Now(), body))
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+
+    // the merchant settles for less than it authorized
+    capture, _ := json.Marshal(types.CaptureRequest{Amount: 450})
+    assert.Equal(t, http.StatusOK, holdAction(holds[0].ID, "capture", capture))
+    assert.Equal(t, http.StatusConflict, holdAction(holds[0].ID, "release", nil))
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), alice.Number)
+    assert.Equal(t, int64(550), got.Balance)
+    holds, _ = srv.Store.GetActiveHolds(context.Background(), alice.Number, time.Now())
+    assert.Empty(t, holds)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/cards/%d/unfreeze", alice.ID, card.ID), token, nil)
+    defer resp.Body.Close()
+    released := authorize(100, card.CVV)
+    assert.True(t, released.Approved)
+    assert.Equal(t, http.StatusOK, holdAction(released.HoldID, "release", nil))
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 550})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCardPINBlocksAfterThreeFailures(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/cards", alice.ID), token, types.IssueCardRequest{})
+    defer resp.Body.Close()
+    card := new(types.IssuedCard)
+    json.NewDecoder(resp.Body).Decode(card)
+
+    pinPath := fmt.Sprintf("/account/%d/cards/%d/pin", alice.ID, card.ID)
+    resp = srv.Do(t, "PUT", pinPath, token, types.PINRequest{PIN: "1234"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // changing takes the current pin
+    resp = srv.Do(t, "PUT", pinPath, token, types.PINRequest{PIN: "4321"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    verify := func(pin string) types.VerifyPINResponse {
+        resp := srv.Do(t, "POST", pinPath+"/verify", token, types.VerifyPINRequest{PIN: pin})
+        defer resp.Body.Close()
+        out := types.VerifyPINResponse{}
+        json.NewDecoder(resp.Body).Decode(&out)
+        return out
+    }
+
+    // a correct pin clears the failure from the change above
+    assert.True(t, verify("1234").Valid)
+    assert.Equal(t, 2, verify("0000").AttemptsLeft)
+    assert.Equal(t, 1, verify("0000").AttemptsLeft)
+    assert.Equal(t, 0, verify("0000").AttemptsLeft)
+
+    got, _ := srv.Store.GetCard(context.Background(), card.ID)
+    assert.Equal(t, types.CardBlocked, got.Status)
+    assert.False(t, verify("1234").Valid)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/cards/%d/unblock", card.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.True(t, verify("1234").Valid)
+}
+
+func TestLoanApprovalDisburses(t *testing.T) {
+    srv := apitest.NewServer(t)
+    acc := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, acc.Number, "pw")
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/loans", acc.ID), token, types.LoanApplication{Amount: 120000, TermMonths: 12})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    loan := new(types.Loan)
+    json.NewDecoder(resp.Body).Decode(loan)
+    assert.Equal(t, types.LoanApplied, loan.Status)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/loans/%d/approve", loan.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/loans/%d/approve", loan.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/loans/%d", acc.ID, loan.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    details := new(types.LoanDetails)
+    json.NewDecoder(resp.Body).Decode(details)
+    assert.Equal(t, types.LoanActive, details.Status)
+    assert.Equal(t, int64(120000), details.Outstanding)
+    assert.Len(t, details.Schedule, 12)
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), acc.Number)
+    assert.Equal(t, int64(120000), got.Balance)
+}
+
+func TestProductRateAppliesToNewLoans(t *testing.T) {
+    srv := apitest.NewServer(t)
+    acc := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, acc.Number, "pw")
+
+    resp := srv.DoAdmin(t, "POST", "/admin/products/rates", types.ProductRateRequest{Key: types.ProductLoanRateBPS, Value: 900})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    current := new(types.ProductRate)
+    json.NewDecoder(resp.Body).Decode(current)
+
+    future := time.Now().Add(24 * time.Hour)
+    resp = srv.DoAdmin(t, "POST", "/admin/products/rates", types.ProductRateRequest{Key: types.ProductLoanRateBPS, Value: 1500, EffectiveFrom: &future})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    scheduled := new(types.ProductRate)
+    json.NewDecoder(resp.Body).Decode(scheduled)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/loans", acc.ID), token, types.LoanApplication{Amount: 120000, TermMonths: 12})
+    defer resp.Body.Close()
+    loan := new(types.Loan)
+    json.NewDecoder(resp.Body).Decode(loan)
+    assert.Equal(t, 900, loan.RateBPS)
+
+    resp = srv.DoAdmin(t, "DELETE", fmt.Sprintf("/admin/products/rates/%d", current.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "DELETE", fmt.Sprintf("/admin/products/rates/%d", scheduled.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "POST", "/admin/products/rates", types.ProductRateRequest{Key: "savings_rate", Value: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    // rates can't be backdated, on creation or later
+    past := time.Now().Add(-time.Hour)
+    resp = srv.DoAdmin(t, "POST", "/admin/products/rates", types.ProductRateRequest{Key: types.ProductLoanRateBPS, Value: 1500, EffectiveFrom: &past})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "POST", "/admin/products/rates", types.ProductRateRequest{Key: types.ProductLoanRateBPS, Value: 1500, EffectiveFrom: &future})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    json.NewDecoder(resp.Body).Decode(scheduled)
+
+    resp = srv.DoAdmin(t, "PUT", fmt.Sprintf("/admin/products/rates/%d", scheduled.ID), types.ProductRateRequest{Value: 1500, EffectiveFrom: &past})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestBlockedTransferWaitsForReview(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    // a large first payment to bob is held
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 300000})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+    c := new(types.FraudCase)
+    json.NewDecoder(resp.Body).Decode(c)
+    assert.Equal(t, types.FraudBlock, c.Decision)
+    assert.Equal(t, types.FraudCasePending, c.Status)
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), bob.Number)
+    assert.Equal(t, int64(0), got.Balance)
+
+    resp = srv.DoAdmin(t, "GET", "/admin/fraud/cases", nil)
+    defer resp.Body.Close()
+    cases := []*types.FraudCase{}
+    json.NewDecoder(resp.Body).Decode(&cases)
+    assert.Len(t, cases, 1)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/fraud/cases/%d/approve", c.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/fraud/cases/%d/reject", c.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    got, _ = srv.Store.GetAccountByNumber(context.Background(), bob.Number)
+    assert.Equal(t, int64(300000), got.Balance)
+
+    // bob is no longer a new payee
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 300000})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // an unclaimed alias always is, and there is nobody to approve it for
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAlias: "mallory@example.com", Amount: 300000})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+    apiErr := new(api.ApiError)
+    json.NewDecoder(resp.Body).Decode(apiErr)
+    assert.Equal(t, "transfer_blocked", apiErr.Code)
+}
+
+func TestSplitTransfersCountTowardFraudThresholds(t *testing.T) {
+    srv := apitest.NewServer(t, func(cfg *config.Config) {
+        cfg.Fraud.AmountWindow = 24 * time.Hour
+    })
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    for i := 0; i < 4; i++ {
+        resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 150000})
+        resp.Body.Close()
+        assert.Equal(t, http.StatusOK, resp.StatusCode)
+    }
+
+    // only the transfer that took the day's total over the threshold is
+    // reviewed
+    cases, _ := srv.Store.GetFraudCasesByStatus(context.Background(), types.FraudCasePosted)
+    assert.Len(t, cases, 1)
+    assert.Equal(t, types.FraudReview, cases[0].Decision)
+}
+
+func TestTransferVelocityLimit(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 10000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    limit := config.Default().VelocityLimits[0].Count
+    for i := 0; i < limit; i++ {
+        resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 10})
+        resp.Body.Close()
+        assert.Equal(t, http.StatusOK, resp.StatusCode)
+    }
+
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 10})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+    // unclaimed aliases count as well
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAlias: "mallory@example.com", Amount: 10})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), bob.Number)
+    assert.Equal(t, int64(10*limit), got.Balance)
+    got, _ = srv.Store.GetAccountByNumber(context.Background(), alice.Number)
+    assert.Equal(t, int64(10000-10*limit), got.Balance)
+}
+
+func TestNewDeviceRequiresStepUp(t *testing.T) {
+    srv := apitest.NewServer(t, func(cfg *config.Config) { cfg.StepUpOnAnomaly = true })
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+
+    login := func(device string) *types.LoginResponse {
+        body, _ := json.Marshal(types.LoginRequest{Number: alice.Number, Password: "pw"})
+        req, _ := http.NewRequest("POST", srv.URL+"/login", bytes.NewReader(body))
+        req.Header.Set("X-Device-ID", device)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+        login := new(types.LoginResponse)
+        json.NewDecoder(resp.Body).Decode(login)
+        return login
+    }
+
+    // the first device is trusted
+    first := login("phone")
+    assert.False(t, first.StepUpRequired)
+    assert.False(t, login("phone").StepUpRequired)
+
+    second := login("laptop")
+    assert.True(t, second.StepUpRequired)
+
+    resp := srv.Do(t, "POST", "/transfer", second.Token, types.TransferRequest{ToAccount: bob.Number, Amount: 10})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+    apiErr := new(api.ApiError)
+    json.NewDecoder(resp.Body).Decode(apiErr)
+    assert.Equal(t, "step_up_required", apiErr.Code)
+
+    resp = srv.Do(t, "POST", "/login/step-up", second.Token, types.StepUpRequest{Code: "not-the-code"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    // nor can it change who has access to the account
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/grants", alice.ID), second.Token, types.CreateGrantRequest{})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+    resp = srv.Do(t, "POST", "/invitations", second.Token, types.CreateInvitationRequest{Email: "carol@example.com", Permission: types.PermissionFull})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+    resp = srv.Do(t, "POST", "/invitations", second.Token, types.CreateInvitationRequest{Email: "carol@example.com"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // the laptop isn't known until the code is confirmed
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/devices", alice.ID), first.Token, nil)
+    defer resp.Body.Close()
+    devices := []*types.Device{}
+    json.NewDecoder(resp.Body).Decode(&devices)
+    assert.Len(t, devices, 1)
+    assert.True(t, login("laptop").StepUpRequired)
+
+    confirmed := srv.StepUp(t, second.Token)
+    resp = srv.Do(t, "POST", "/transfer", confirmed, types.TransferRequest{ToAccount: bob.Number, Amount: 10})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.False(t, login("laptop").StepUpRequired)
+
+    resp = srv.Do(t, "POST", "/transfer", first.Token, types.TransferRequest{ToAccount: bob.Number, Amount: 10})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestFlowReportIsCached(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    srv.Fund(t, bob.Number, 500)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 200})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "GET", "/admin/reports/flows", nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age=")
+    report := new(types.FlowReport)
+    json.NewDecoder(resp.Body).Decode(report)
+    assert.Equal(t, int64(1500), report.Deposits)
+    assert.Equal(t, int64(1500), report.Net)
+    assert.Equal(t, 2, report.ActiveAccounts)
+    assert.Len(t, report.Days, 1)
+
+    // served from cache until the refresh interval passes
+    srv.Fund(t, alice.Number, 1000)
+    resp = srv.DoAdmin(t, "GET", "/admin/reports/flows", nil)
+    defer resp.Body.Close()
+    cached := new(types.FlowReport)
+    json.NewDecoder(resp.Body).Decode(cached)
+    assert.Equal(t, int64(1500), cached.Deposits)
+
+    resp = srv.DoAdmin(t, "GET", "/admin/reports/largest-transfers?limit=5", nil)
+    defer resp.Body.Close()
+    largest := new(types.LargestTransfersReport)
+    json.NewDecoder(resp.Body).Decode(largest)
+    assert.Len(t, largest.Transfers, 1)
+    assert.Equal(t, int64(200), largest.Transfers[0].Amount)
+
+    resp = srv.DoAdmin(t, "GET", "/admin/reports/flows?from=2024-06-02&to=2024-06-01", nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPotMoneyIsSetAside(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/pots", alice.ID), token, types.PotRequest{Name: "holiday", Target: 2000, RoundUp: true})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    pot := new(types.PotResponse)
+    json.NewDecoder(resp.Body).Decode(pot)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/pots/%d/deposit", alice.ID, pot.ID), token, types.PotMoveRequest{Amount: 600})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // only 400 is left to spend
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 500})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 250})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // 250 rounds up to 300, saving 50
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/pots/%d", alice.ID, pot.ID), token, nil)
+    defer resp.Body.Close()
+    json.NewDecoder(resp.Body).Decode(pot)
+    assert.Equal(t, int64(650), pot.Balance)
+    assert.Equal(t, 32, pot.Progress)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/pots/%d/withdraw", alice.ID, pot.ID), token, types.PotMoveRequest{Amount: 700})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestJointAccountOwners(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    carol := srv.CreateAccount(t, "carol", "c", "pw")
+    srv.Fund(t, alice.Number, 1000)
+
+    aliceToken := srv.Login(t, alice.Number, "pw")
+    bobToken := srv.Login(t, bob.Number, "pw")
+    carolToken := srv.Login(t, carol.Number, "pw")
+
+    invite := func(owner *types.Account, token, permission string) {
+        resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/owners", alice.ID), aliceToken, types.InviteOwnerRequest{OwnerNumber: owner.Number, Permission: permission})
+        defer resp.Body.Close()
+        assert.Equal(t, http.StatusOK, resp.StatusCode)
+        inv := new(types.OwnerInvitation)
+        json.NewDecoder(resp.Body).Decode(inv)
+
+        resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/invitations/%d/accept", owner.ID, inv.ID), token, nil)
+        defer resp.Body.Close()
+        assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+        resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/invitations/%d/accept", owner.ID, inv.ID), token, nil)
+        defer resp.Body.Close()
+        assert.Equal(t, http.StatusConflict, resp.StatusCode)
+    }
+    invite(bob, bobToken, types.PermissionFull)
+    invite(carol, carolToken, types.PermissionView)
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/transfer", alice.ID), bobToken, types.TransferRequest{ToAccount: carol.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // view only owners can read but not move money
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance", alice.ID), carolToken, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/transfer", alice.ID), carolToken, types.TransferRequest{ToAccount: carol.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    // only the holder manages owners
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/owners", alice.ID), bobToken, types.InviteOwnerRequest{OwnerNumber: carol.Number, Permission: types.PermissionFull})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    entries, _ := srv.Store.GetAuditLog(context.Background(), alice.Number)
+    var transfers []*types.AuditEntry
+    for _, e := range entries {
+        if e.Path == fmt.Sprintf("/account/%d/transfer", alice.ID) {
+            transfers = append(transfers, e)
+        }
+    }
+    assert.Len(t, transfers, 1)
+    assert.Equal(t, bob.Number, transfers[0].ActorNumber)
+    assert.Equal(t, http.StatusOK, transfers[0].Status)
+}
+
+func TestGrantLimitsDelegatedAccess(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+
+    aliceToken := srv.Login(t, alice.Number, "pw")
+    bobToken := srv.Login(t, bob.Number, "pw")
+
+    // no grant yet
+    resp := srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance", alice.ID), bobToken, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/grants", alice.ID), aliceToken, types.CreateGrantRequest{
+        GranteeNumber: bob.Number,
+        Scopes: []string{types.ScopeRead, types.ScopeTransfer},
+        TransferLimit: 200,
+    })
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    grant := new(types.Grant)
+    json.NewDecoder(resp.Body).Decode(grant)
+
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance", alice.ID), bobToken, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    transfer := func(amount int64) int {
+        resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/transfer", alice.ID), bobToken, types.TransferRequest{ToAccount: bob.Number, Amount: amount})
+        defer resp.Body.Close()
+        return resp.StatusCode
+    }
+    assert.Equal(t, http.StatusOK, transfer(200))
+    assert.Equal(t, http.StatusBadRequest, transfer(201))
+
+    // the grant doesn't cover other changes
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/pots", alice.ID), bobToken, types.PotRequest{Name: "x", Target: 10})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    resp = srv.Do(t, "DELETE", fmt.Sprintf("/account/%d/grants/%d", alice.ID, grant.ID), aliceToken, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    assert.Equal(t, http.StatusForbidden, transfer(100))
+}
+
+func TestPatchAccountMetadata(t *testing.T) {
+    srv := apitest.NewServer(t)
+    acc := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, acc.Number, "pw")
+
+    patch := func(body string) (*types.Account, int) {
+        resp := srv.Do(t, "PATCH", fmt.Sprintf("/account/%d", acc.ID), token, json.RawMessage(body))
+        defer resp.Body.Close()
+        got := new(types.Account)
+        json.NewDecoder(resp.Body).Decode(got)
+        return got, resp.StatusCode
+    }
+
+    got, status := patch(`{"nickname": "bills", "metadata": {"crm_id": "C-42", "cost_center": "ops"}}`)
+    assert.Equal(t, http.StatusOK, status)
+    assert.Equal(t, "bills", got.Nickname)
+
+    got, status = patch(`{"metadata": {"cost_center": null, "region": "eu"}}`)
+    assert.Equal(t, http.StatusOK, status)
+    assert.Equal(t, "bills", got.Nickname)
+    assert.Equal(t, map[string]string{"crm_id": "C-42", "region": "eu"}, got.Metadata)
+
+    _, status = patch(fmt.Sprintf(`{"metadata": {"note": %q}}`, strings.Repeat("x", 501)))
+    assert.Equal(t, http.StatusBadRequest, status)
+
+    resp := srv.Do(t, "GET", fmt.Sprintf("/account/%d", acc.ID), token, nil)
+    defer resp.Body.Close()
+    got = new(types.Account)
+    json.NewDecoder(resp.Body).Decode(got)
+    assert.Equal(t, "bills", got.Nickname)
+    assert.Equal(t, "C-42", got.Metadata["crm_id"])
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+    srv := apitest.NewServer(t)
+    d := &types.DeadLetter{
+        Event: "large_withdrawal",
+        Channel: "email",
+        AccountNumber: 42,
+        To: "ada@example.com",
+        Subject: "Large withdrawal from account 42",
+        Body: "hello",
+        Reason: "smtp: try again later",
+        Attempts: 5,
+        Status: types.DeadLetterPending,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := srv.Store.CreateDeadLetter(context.Background(), d); err != nil {
+        t.Fatal(err)
+    }
+
+    resp := srv.DoAdmin(t, "GET", "/admin/dead-letters", nil)
+    defer resp.Body.Close()
+    letters := []*types.DeadLetter{}
+    json.NewDecoder(resp.Body).Decode(&letters)
+    assert.Len(t, letters, 1)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/dead-letters/%d/replay", d.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    got := new(types.DeadLetter)
+    json.NewDecoder(resp.Body).Decode(got)
+    assert.Equal(t, types.DeadLetterReplayed, got.Status)
+    assert.NotNil(t, got.ReplayedAt)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/dead-letters/%d/replay", d.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestSignedTransferRejectsReplay(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/signing-keys", alice.ID), token, nil)
+    defer resp.Body.Close()
+    key := new(types.CreatedSigningKey)
+    json.NewDecoder(resp.Body).Decode(key)
+    if !assert.NotEmpty(t, key.Secret) {
+        return
+    }
+
+    // a token alone no longer moves money
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+    body := []byte(fmt.Sprintf(`{"toAccount":%d,"amount":100}`, bob.Number))
+    header := signing.Sign(key.ID, []byte(key.Secret), time.Now(), signing.NewNonce(), "POST", "/transfer", body)
+    send := func(header string) int {
+        req, _ := http.NewRequest("POST", srv.URL+"/transfer", bytes.NewReader(body))
+        req.Header.Set("x-jwt-token", token)
+        req.Header.Set(signing.Header, header)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+
+    assert.Equal(t, http.StatusOK, send(header))
+    assert.Equal(t, http.StatusUnauthorized, send(header))
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), bob.Number)
+    assert.Equal(t, int64(100), got.Balance)
+
+    // nor turns signing off
+    resp = srv.Do(t, "DELETE", fmt.Sprintf("/account/%d/signing-keys/%s", alice.ID, key.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+    // nor adds a key of its own
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/signing-keys", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+    keys, _ := srv.Store.GetSigningKeysByAccount(context.Background(), alice.Number)
+    assert.Len(t, keys, 1)
+}
+
+func TestUseRunsMiddlewareOnEveryRoute(t *testing.T) {
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { l.Close() })
+
+    server := api.NewApiServer(config.Default(), storagetest.New(), notify.New(notify.NewConsoleSender(io.Discard), 1))
+    server.Use(func(next http.HandlerFunc) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+            w.Header().Set("X-Embedded", "yes")
+            next(w, r)
+        }
+    })
+    go server.Serve(l)
+
+    for _, path := range []string{"/metrics", "/account/1", "/admin/jobs"} {
+        req, _ := http.NewRequest("GET", "http://"+l.Addr().String()+path, nil)
+        req.Header.Set("X-Request-ID", "req-1")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        resp.Body.Close()
+
+        assert.Equal(t, "yes", resp.Header.Get("X-Embedded"), path)
+        assert.Equal(t, "req-1", resp.Header.Get("X-Request-ID"), path)
+    }
+}
+
+func TestHandlerMountsUnderPrefix(t *testing.T) {
+    if os.Getenv("JWT_SECRET") == "" {
+        t.Setenv("JWT_SECRET", "apitest-secret")
+    }
+
+    store := storagetest.New()
+    acc, _ := types.NewAccount("alice", "a", "pw")
+    store.CreateAccount(context.Background(), acc)
+
+    var logs bytes.Buffer
+    server := api.NewApiServer(config.Default(), store, notify.New(notify.NewConsoleSender(io.Discard), 1),
+        api.WithRouterPrefix("/bank/"),
+        api.WithLogger(log.New(&logs, "", 0)),
+        api.WithTokenTTL(time.Hour),
+    )
+    mux := http.NewServeMux()
+    mux.Handle("/bank/", server.Handler())
+    mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusTeapot)
+    })
+    outer := httptest.NewServer(mux)
+    defer outer.Close()
+
+    body, _ := json.Marshal(types.LoginRequest{Number: acc.Number, Password: "pw"})
+    resp, err := http.Post(outer.URL+"/bank/login", "application/json", bytes.NewReader(body))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    login := new(types.LoginResponse)
+    json.NewDecoder(resp.Body).Decode(login)
+
+    req, _ := http.NewRequest("GET", fmt.Sprintf("%s/bank/account/%d", outer.URL, acc.ID), nil)
+    req.Header.Set("x-jwt-token", login.Token)
+    resp, err = http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // the routes only exist under the prefix
+    resp, err = http.Get(outer.URL + "/login")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+    assert.Contains(t, logs.String(), "POST /bank/login 200")
+}
+
+func TestH2C(t *testing.T) {
+    srv := apitest.NewServer(t, func(cfg *config.Config) {
+        cfg.H2C = true
+    })
+
+    protocols := new(http.Protocols)
+    protocols.SetUnencryptedHTTP2(true)
+    client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+    resp, err := client.Get(srv.URL + "/metrics")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Equal(t, 2, resp.ProtoMajor)
+}
+
+func TestErrorsAreLocalized(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    token := srv.Login(t, alice.Number, "pw")
+
+    send := func(path, token string, body any) (int, api.ApiError) {
+        buf := new(bytes.Buffer)
+        json.NewEncoder(buf).Encode(body)
+        req, _ := http.NewRequest("POST", srv.URL+path, buf)
+        req.Header.Set("x-jwt-token", token)
+        req.Header.Set("Accept-Language", "de-AT,de;q=0.9")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer resp.Body.Close()
+
+        apiErr := api.ApiError{}
+        json.NewDecoder(resp.Body).Decode(&apiErr)
+        return resp.StatusCode, apiErr
+    }
+
+    status, apiErr := send("/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    assert.Equal(t, http.StatusUnprocessableEntity, status)
+    assert.Equal(t, "insufficient_funds", apiErr.Code)
+    assert.Equal(t, "Nicht genügend Guthaben", apiErr.Error)
+
+    // unknown numbers and wrong passwords get the same answer
+    for _, req := range []types.LoginRequest{{Number: alice.Number, Password: "nope"}, {Number: 999999, Password: "pw"}} {
+        status, apiErr = send("/login", "", req)
+        assert.Equal(t, http.StatusForbidden, status)
+        assert.Equal(t, "invalid_credentials", apiErr.Code)
+        assert.Equal(t, "Kontonummer oder Passwort ist falsch", apiErr.Error)
+    }
+}
+
+func TestLargeResponsesAreCompressed(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, alice.Number, "pw")
+
+    get := func(accept string) *http.Response {
+        req, _ := http.NewRequest("GET", fmt.Sprintf("%s/account/%d/transactions", srv.URL, alice.ID), nil)
+        req.Header.Set("x-jwt-token", token)
+        req.Header.Set("Accept-Encoding", accept)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return resp
+    }
+
+    resp := get("deflate;q=0.5, gzip")
+    resp.Body.Close()
+    assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+
+    for i := 0; i < 50; i++ {
+        srv.Fund(t, alice.Number, 100)
+    }
+
+    resp = get("deflate;q=0.5, gzip")
+    defer resp.Body.Close()
+    assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+    assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+    zr, err := gzip.NewReader(resp.Body)
+    if !assert.NoError(t, err) {
+        return
+    }
+    txs := []*types.Transaction{}
+    assert.NoError(t, json.NewDecoder(zr).Decode(&txs))
+    assert.Len(t, txs, 50)
+
+    resp = get("deflate")
+    defer resp.Body.Close()
+    assert.Equal(t, "deflate", resp.Header.Get("Content-Encoding"))
+
+    dr, err := zlib.NewReader(resp.Body)
+    if !assert.NoError(t, err) {
+        return
+    }
+    txs = []*types.Transaction{}
+    assert.NoError(t, json.NewDecoder(dr).Decode(&txs))
+    assert.Len(t, txs, 50)
+}
+
+func TestImportAccountsAndActivate(t *testing.T) {
+    srv := apitest.NewServer(t)
+
+    body := "firstName,lastName,number,balance\nalice,a,1000001,5000\n,b,1000002,0\n"
+    req, _ := http.NewRequest("POST", srv.URL+"/admin/accounts/import", strings.NewReader(body))
+    req.Header.Set("x-admin-token", apitest.AdminToken)
+    req.Header.Set("Content-Type", "text/csv")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    res := new(importer.Result)
+    assert.NoError(t, json.NewDecoder(resp.Body).Decode(res))
+    if !assert.Len(t, res.Imported, 1) {
+        return
+    }
+    assert.Equal(t, 2, res.Errors[0].Row)
+
+    activate := func(token string) int {
+        resp := srv.Do(t, "POST", "/login/activate", "", types.ActivateAccountRequest{Number: 1000001, Token: token, Password: "pw"})
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+    assert.Equal(t, http.StatusForbidden, activate("wrong"))
+    assert.Equal(t, http.StatusOK, activate(res.Imported[0].ActivationToken))
+    assert.Equal(t, http.StatusForbidden, activate(res.Imported[0].ActivationToken))
+
+    srv.Login(t, 1000001, "pw")
+}
+
+func TestDormantAccountsCantSendUntilReactivated(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    since := time.Now().UTC()
+    if err := srv.Store.SetDormancy(context.Background(), alice.Number, nil, &since); err != nil {
+        t.Fatal(err)
+    }
+
+    transfer := func() *http.Response {
+        return srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 10})
+    }
+    resp := transfer()
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+    apiErr := new(api.ApiError)
+    json.NewDecoder(resp.Body).Decode(apiErr)
+    assert.Equal(t, "account_dormant", apiErr.Code)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/reactivate", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+    started := map[string]string{}
+    json.NewDecoder(resp.Body).Decode(&started)
+
+    // the code only went out by email, so swap in one we know
+    sum := sha256.Sum256([]byte("123456"))
+    srv.Store.SaveStepUpChallenge(context.Background(), &types.StepUpChallenge{
+        ID: started["challengeId"],
+        AccountNumber: alice.Number,
+        CodeHash: hex.EncodeToString(sum[:]),
+        ExpiresAt: time.Now().Add(time.Minute),
+    })
+
+    path := fmt.Sprintf("/account/%d/reactivate/verify", alice.ID)
+    resp = srv.Do(t, "POST", path, token, types.ReactivateRequest{ChallengeID: started["challengeId"], Code: "123456"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    reactivated := new(types.Account)
+    json.NewDecoder(resp.Body).Decode(reactivated)
+    assert.Nil(t, reactivated.DormantSince)
+
+    resp = transfer()
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestDisputeHoldsAndChargesBack(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    carol := srv.CreateAccount(t, "carol", "c", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    aliceToken := srv.Login(t, alice.Number, "pw")
+    bobToken := srv.Login(t, bob.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/transfer", aliceToken, types.TransferRequest{ToAccount: bob.Number, Amount: 400})
+    defer resp.Body.Close()
+    tx := new(types.Transaction)
+    json.NewDecoder(resp.Body).Decode(tx)
+
+    path := fmt.Sprintf("/account/%d/disputes", alice.ID)
+    open := types.CreateDisputeRequest{TransactionID: tx.ID, Reason: types.DisputeUnauthorized}
+    resp = srv.Do(t, "POST", path, aliceToken, open)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusCreated, resp.StatusCode)
+    d := new(types.Dispute)
+    json.NewDecoder(resp.Body).Decode(d)
+    assert.Equal(t, int64(400), d.HeldAmount)
+
+    // bob can't move the held money while the dispute is open
+    resp = srv.Do(t, "POST", "/transfer", bobToken, types.TransferRequest{ToAccount: carol.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/disputes/%d/accept", d.ID), types.ResolveDisputeRequest{Resolution: "not authorised by the holder"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    ctx := context.Background()
+    balance, _ := srv.Store.GetLedgerBalance(ctx, alice.Number)
+    assert.Equal(t, int64(1000), balance)
+    balance, _ = srv.Store.GetLedgerBalance(ctx, bob.Number)
+    assert.Equal(t, int64(0), balance)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/disputes/%d/deny", d.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", path, aliceToken, open)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestUploadAndDownloadDocument(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    png := []byte("\x89PNG\r\n\x1a\nreceipt")
+    upload := func(purpose, contentType string) *http.Response {
+        path := fmt.Sprintf("%s/account/%d/documents?purpose=%s&filename=receipt.png", srv.URL, alice.ID, purpose)
+        req, _ := http.NewRequest("POST", path, bytes.NewReader(png))
+        req.Header.Set("x-jwt-token", token)
+        req.Header.Set("Content-Type", contentType)
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return resp
+    }
+
+    resp := upload(types.DocumentDisputeEvidence, "application/pdf")
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = upload(types.DocumentKYC, "image/png")
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusCreated, resp.StatusCode)
+    kyc := new(types.Document)
+    json.NewDecoder(resp.Body).Decode(kyc)
+
+    resp = upload(types.DocumentDisputeEvidence, "image/png")
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusCreated, resp.StatusCode)
+    evidence := new(types.Document)
+    json.NewDecoder(resp.Body).Decode(evidence)
+    assert.Equal(t, int64(len(png)), evidence.Size)
+
+    resp, err := http.Get(srv.URL + evidence.DownloadURL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+    got, _ := io.ReadAll(resp.Body)
+    assert.Equal(t, png, got)
+
+    resp, err = http.Get(srv.URL + strings.Replace(evidence.DownloadURL, "signature=", "signature=0", 1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    defer resp.Body.Close()
+    tx := new(types.Transaction)
+    json.NewDecoder(resp.Body).Decode(tx)
+
+    // only evidence can back a dispute, not identity documents
+    path := fmt.Sprintf("/account/%d/disputes", alice.ID)
+    req := types.CreateDisputeRequest{TransactionID: tx.ID, Reason: types.DisputeNotReceived, Documents: []int{kyc.ID}}
+    resp = srv.Do(t, "POST", path, token, req)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    req.Documents = []int{evidence.ID}
+    resp = srv.Do(t, "POST", path, token, req)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusCreated, resp.StatusCode)
+    d := new(types.Dispute)
+    json.NewDecoder(resp.Body).Decode(d)
+    assert.Equal(t, []int{evidence.ID}, d.Documents)
+}
+
+func TestCloseAccountSweepsBalance(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    aliceToken := srv.Login(t, alice.Number, "pw")
+    bobToken := srv.Login(t, bob.Number, "pw")
+    ctx := context.Background()
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/pots", alice.ID), aliceToken, types.PotRequest{Name: "holiday", Target: 2000})
+    defer resp.Body.Close()
+    pot := new(types.PotResponse)
+    json.NewDecoder(resp.Body).Decode(pot)
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/pots/%d/deposit", alice.ID, pot.ID), aliceToken, types.PotMoveRequest{Amount: 300})
+    defer resp.Body.Close()
+
+    pr := &types.PaymentRequest{RequesterAccount: bob.Number, PayerAccount: alice.Number, Amount: 50, Status: types.RequestPending, ExpiresAt: time.Now().Add(time.Hour)}
+    srv.Store.CreatePaymentRequest(ctx, pr)
+
+    path := fmt.Sprintf("/account/%d/close", alice.ID)
+    resp = srv.Do(t, "POST", path, aliceToken, types.CloseAccountRequest{})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", path, aliceToken, types.CloseAccountRequest{ToAccount: bob.Number})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    closed := new(types.Account)
+    json.NewDecoder(resp.Body).Decode(closed)
+    assert.NotNil(t, closed.ClosedAt)
+
+    // the pot money went along with the rest
+    balance, _ := srv.Store.GetLedgerBalance(ctx, alice.Number)
+    assert.Equal(t, int64(0), balance)
+    balance, _ = srv.Store.GetLedgerBalance(ctx, bob.Number)
+    assert.Equal(t, int64(1000), balance)
+    got, _ := srv.Store.GetPaymentRequest(ctx, pr.ID)
+    assert.Equal(t, types.RequestCancelled, got.Status)
+
+    resp = srv.Do(t, "POST", path, aliceToken, types.CloseAccountRequest{ToAccount: bob.Number})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", aliceToken, types.TransferRequest{ToAccount: bob.Number, Amount: 1})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", bobToken, types.TransferRequest{ToAccount: alice.Number, Amount: 1})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestCloseAccountPaysOutFirst(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+    ctx := context.Background()
+    path := fmt.Sprintf("/account/%d/close", alice.ID)
+
+    payout := &types.Payout{RoutingNumber: "021000021", ToAccount: "12345678", Name: "alice"}
+    resp := srv.Do(t, "POST", path, token, types.CloseAccountRequest{Payout: payout})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+    et := new(types.ExternalTransfer)
+    json.NewDecoder(resp.Body).Decode(et)
+    assert.Equal(t, int64(1000), et.Amount)
+    assert.Equal(t, types.StatusPending, et.Status)
+
+    // the account stays open until the payout settles
+    got, _ := srv.Store.GetAccountByNumber(ctx, alice.Number)
+    assert.Nil(t, got.ClosedAt)
+    resp = srv.Do(t, "POST", path, token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    if err := srv.Store.SettleTransaction(ctx, et.ID, types.StatusCompleted, nil, nil); err != nil {
+        t.Fatal(err)
+    }
+    resp = srv.Do(t, "POST", path, token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCloseAccountSweepIsChecked(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 10000)
+    token := srv.Login(t, alice.Number, "pw")
+    path := fmt.Sprintf("/account/%d/close", alice.ID)
+
+    // a claim that expires is refunded to the account
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAlias: "carol@example.com", Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+    resp = srv.Do(t, "POST", path, token, types.CloseAccountRequest{ToAccount: bob.Number})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    claims, _ := srv.Store.GetPendingAliasClaims(context.Background(), "carol@example.com")
+    if !assert.Len(t, claims, 1) {
+        return
+    }
+    ret := &types.Transaction{Kind: types.TransactionTransfer, FromAccount: types.SuspenseAccountNumber, ToAccount: alice.Number, Amount: 100, CreatedAt: time.Now()}
+    srv.Store.SettleTransaction(context.Background(), claims[0].TransactionID, types.StatusFailed, ret, types.NewEntries(types.SuspenseAccountNumber, alice.Number, 100))
+
+    // the sweep counts against the velocity limits like any transfer
+    limit := config.Default().VelocityLimits[0].Count
+    for i := 0; i < limit; i++ {
+        resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 10})
+        resp.Body.Close()
+    }
+    resp = srv.Do(t, "POST", path, token, types.CloseAccountRequest{ToAccount: bob.Number})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), alice.Number)
+    assert.Nil(t, got.ClosedAt)
+}
+
+func TestCloseAccountWithLoanIsBlocked(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, alice.Number, "pw")
+
+    srv.Store.CreateLoan(context.Background(), &types.Loan{AccountNumber: alice.Number, Principal: 1000, TermMonths: 12, Status: types.LoanApplied})
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/close", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), alice.Number)
+    assert.Nil(t, got.ClosedAt)
+}
+
+func TestPreferences(t *testing.T) {
+    srv := apitest.NewServer(t)
+    acc := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, acc.Number, "pw")
+
+    put := func(body string) (*types.Preferences, int) {
+        resp := srv.Do(t, "PUT", fmt.Sprintf("/account/%d/notifications", acc.ID), token, json.RawMessage(body))
+        defer resp.Body.Close()
+        got := new(types.Preferences)
+        json.NewDecoder(resp.Body).Decode(got)
+        return got, resp.StatusCode
+    }
+
+    got, status := put(`{"statementDelivery": "online", "marketingOptIn": true, "locale": "de-DE", "events": {"transfer_confirmation": {"email": false}}}`)
+    assert.Equal(t, http.StatusOK, status)
+    assert.True(t, got.Email)
+    assert.Equal(t, types.StatementOnline, got.StatementDelivery)
+    assert.Equal(t, types.Channels{}, got.Events["transfer_confirmation"])
+
+    _, status = put(`{"events": {"new_device_login": {"email": false, "sms": false}}}`)
+    assert.Equal(t, http.StatusBadRequest, status)
+    _, status = put(`{"events": {"no_such_event": {"email": true}}}`)
+    assert.Equal(t, http.StatusBadRequest, status)
+    _, status = put(`{"events": {"transfer_confirmation": {"sms": true}}}`)
+    assert.Equal(t, http.StatusBadRequest, status)
+    _, status = put(`{"statementDelivery": "fax"}`)
+    assert.Equal(t, http.StatusBadRequest, status)
+
+    stored, _ := srv.Store.GetNotificationPreferences(context.Background(), acc.Number)
+    assert.True(t, stored.MarketingOptIn)
+    assert.Equal(t, types.StatementOnline, stored.StatementDelivery)
+    account, _ := srv.Store.GetAccountByNumber(context.Background(), acc.Number)
+    assert.Equal(t, "de-DE", account.Locale)
+
+    // fields left out keep their value, the locale as well
+    got, status = put(`{"email": false}`)
+    assert.Equal(t, http.StatusOK, status)
+    assert.Equal(t, "de-DE", got.Locale)
+    stored, _ = srv.Store.GetNotificationPreferences(context.Background(), acc.Number)
+    assert.False(t, stored.Email)
+    assert.True(t, stored.MarketingOptIn)
+
+    // nothing is saved when the store fails
+    srv.Store.FailOn("SavePreferences", errors.New("connection reset"))
+    _, status = put(`{"locale": "en-US", "marketingOptIn": false}`)
+    assert.NotEqual(t, http.StatusOK, status)
+    account, _ = srv.Store.GetAccountByNumber(context.Background(), acc.Number)
+    assert.Equal(t, "de-DE", account.Locale)
+}
+
+func TestExternalTransfer(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+    path := fmt.Sprintf("/account/%d/transfer/external", alice.ID)
+
+    resp := srv.Do(t, "POST", path, token, types.ExternalTransferRequest{RoutingNumber: "021000022", ToAccount: "12345678", Name: "carol", Amount: 300})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", path, token, types.ExternalTransferRequest{RoutingNumber: "021000021", ToAccount: "12345678", Name: "carol", Amount: 300})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+    et := new(types.ExternalTransfer)
+    json.NewDecoder(resp.Body).Decode(et)
+    assert.Equal(t, types.StatusPending, et.Status)
+    assert.Equal(t, "sandbox", et.Network)
+    assert.NotEmpty(t, et.Reference)
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), alice.Number)
+    assert.Equal(t, int64(700), got.Balance)
+
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/external-transfers/%d", alice.ID, et.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    // a pending transfer could still come back to the account
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/close", alice.ID), token, types.CloseAccountRequest{ToAccount: bob.Number})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestBlockedExternalTransferWaitsForReview(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    srv.Fund(t, alice.Number, 1000000)
+    token := srv.Login(t, alice.Number, "pw")
+    path := fmt.Sprintf("/account/%d/transfer/external", alice.ID)
+    req := types.ExternalTransferRequest{RoutingNumber: "021000021", ToAccount: "12345678", Name: "carol", Amount: 300000}
+
+    resp := srv.Do(t, "POST", path, token, req)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+    c := new(types.FraudCase)
+    json.NewDecoder(resp.Body).Decode(c)
+    assert.Equal(t, types.FraudCasePending, c.Status)
+    if !assert.NotNil(t, c.External) {
+        return
+    }
+    assert.Equal(t, "12345678", c.External.ToAccount)
+
+    got, _ := srv.Store.GetAccountByNumber(context.Background(), alice.Number)
+    assert.Equal(t, int64(1000000), got.Balance)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/fraud/cases/%d/approve", c.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    transfers, _ := srv.Store.GetExternalTransfersByAccount(context.Background(), alice.Number)
+    if assert.Len(t, transfers, 1) {
+        assert.Equal(t, types.StatusPending, transfers[0].Status)
+        assert.Equal(t, int64(300000), transfers[0].Amount)
+    }
+
+    // carol is no longer a new payee
+    resp = srv.Do(t, "POST", path, token, req)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+    et := new(types.ExternalTransfer)
+    json.NewDecoder(resp.Body).Decode(et)
+    assert.Equal(t, types.StatusPending, et.Status)
+}
+
+func TestAccountBalance(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", fmt.Sprintf("/account/%d/pots", alice.ID), token, types.PotRequest{Name: "holiday", Target: 2000})
+    defer resp.Body.Close()
+    pot := new(types.PotResponse)
+    json.NewDecoder(resp.Body).Decode(pot)
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/pots/%d/deposit", alice.ID, pot.ID), token, types.PotMoveRequest{Amount: 200})
+    defer resp.Body.Close()
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/transfer/external", alice.ID), token, types.ExternalTransferRequest{RoutingNumber: "021000021", ToAccount: "12345678", Name: "carol", Amount: 300})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    got := new(types.BalanceResponse)
+    json.NewDecoder(resp.Body).Decode(got)
+    assert.Equal(t, int64(700), got.Balance)
+    assert.Equal(t, "USD", got.Currency)
+    assert.NotNil(t, got.Balances)
+    // the pending transfer hasn't left yet, but can't be spent either
+    assert.Equal(t, int64(1000), got.Booked)
+    assert.Equal(t, int64(300), got.Pending)
+    assert.Equal(t, int64(500), got.Available)
+}
+
+func TestArchivedTransactionsStayReadable(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    token := srv.Login(t, alice.Number, "pw")
+
+    ctx := context.Background()
+    cutoff := time.Now().UTC().AddDate(0, -config.Default().ArchiveAfterMonths, 0)
+    // mid-morning, so everything below falls on the same day
+    old := cutoff.AddDate(0, -1, 0).Truncate(24 * time.Hour).Add(9 * time.Hour)
+    post := func(tx *types.Transaction, entries []*types.LedgerEntry) {
+        if err := srv.Store.PostTransaction(ctx, tx, entries); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    opening := &types.Transaction{Kind: types.TransactionOpening, FromAccount: types.SettlementAccountNumber, ToAccount: alice.Number, Amount: 1000, CreatedAt: old}
+    post(opening, types.NewEntries(types.SettlementAccountNumber, alice.Number, 1000))
+    transfer := &types.Transaction{Kind: types.TransactionTransfer, FromAccount: alice.Number, ToAccount: bob.Number, Amount: 200, CreatedAt: old.Add(time.Hour)}
+    post(transfer, types.NewEntries(alice.Number, bob.Number, 200))
+
+    // an external transfer the clearing network never settled
+    external := &types.Transaction{Kind: types.TransactionTransfer, Status: types.StatusPending, FromAccount: alice.Number, ToAccount: types.SettlementAccountNumber, Amount: 100, Provider: apitest.WebhookProvider, Reference: "ref-old", CreatedAt: old}
+    post(external, types.NewEntries(alice.Number, types.SettlementAccountNumber, 100))
+
+    // a paid payment request and a claimed alias transfer still point at
+    // their transactions
+    pr := &types.PaymentRequest{RequesterAccount: bob.Number, PayerAccount: alice.Number, Amount: 50, Status: types.RequestPending, ExpiresAt: old.AddDate(0, 0, 7), CreatedAt: old}
+    if err := srv.Store.CreatePaymentRequest(ctx, pr); err != nil {
+        t.Fatal(err)
+    }
+    paid := &types.Transaction{Kind: types.TransactionTransfer, FromAccount: alice.Number, ToAccount: bob.Number, Amount: 50, CreatedAt: old}
+    if err := srv.Store.AcceptPaymentRequest(ctx, pr.ID, paid, types.NewEntries(alice.Number, bob.Number, 50), nil); err != nil {
+        t.Fatal(err)
+    }
+    claimed := &types.Transaction{Kind: types.TransactionTransfer, Status: types.StatusPending, FromAccount: alice.Number, ToAccount: types.SuspenseAccountNumber, Amount: 30, CreatedAt: old}
+    claim := &types.AliasClaim{Alias: "bob@example.com", ExpiresAt: old.AddDate(0, 0, 7)}
+    if err := srv.Store.CreateAliasClaim(ctx, claim, claimed, types.NewEntries(alice.Number, types.SuspenseAccountNumber, 30), nil); err != nil {
+        t.Fatal(err)
+    }
+    payout := &types.Transaction{Kind: types.TransactionTransfer, FromAccount: types.SuspenseAccountNumber, ToAccount: bob.Number, Amount: 30, CreatedAt: old.Add(2 * time.Hour)}
+    if err := srv.Store.SettleTransaction(ctx, claimed.ID, types.StatusCompleted, payout, types.NewEntries(types.SuspenseAccountNumber, bob.Number, 30)); err != nil {
+        t.Fatal(err)
+    }
+
+    read := func() ([]*types.Transaction, *types.BalanceResponse) {
+        resp := srv.Do(t, "GET", fmt.Sprintf("/account/%d/transactions", alice.ID), token, nil)
+        defer resp.Body.Close()
+        assert.Equal(t, http.StatusOK, resp.StatusCode)
+        txs := []*types.Transaction{}
+        json.NewDecoder(resp.Body).Decode(&txs)
+
+        resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance?at=%s", alice.ID, old.Format("2006-01-02")), token, nil)
+        defer resp.Body.Close()
+        assert.Equal(t, http.StatusOK, resp.StatusCode)
+        balance := new(types.BalanceResponse)
+        json.NewDecoder(resp.Body).Decode(balance)
+
+        return txs, balance
+    }
+    txs, balance := read()
+    assert.Len(t, txs, 5)
+    assert.Equal(t, int64(620), balance.Balance)
+
+    n, err := srv.Store.ArchiveTransactionsBefore(ctx, cutoff)
+    assert.Nil(t, err)
+    assert.Equal(t, 3, n)
+    for _, tx := range []*types.Transaction{opening, transfer, payout} {
+        assert.True(t, srv.Store.Archived(tx.ID), tx.ID)
+    }
+    for _, tx := range []*types.Transaction{external, paid, claimed} {
+        assert.False(t, srv.Store.Archived(tx.ID), tx.ID)
+    }
+
+    archivedTxs, archivedBalance := read()
+    assert.Equal(t, txs, archivedTxs)
+    assert.Equal(t, balance, archivedBalance)
+}
+
+func TestReverseTransaction(t *testing.T) {
+    events := make(chan webhook.Event, 1)
+    hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        if webhook.Verify([]byte("outbound-secret"), r.Header.Get(webhook.SignatureHeader), body, time.Now(), time.Minute) != nil {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        var e webhook.Event
+        json.Unmarshal(body, &e)
+        events <- e
+    }))
+    defer hook.Close()
+
+    srv := apitest.NewServer(t, func(cfg *config.Config) {
+        cfg.OutboundWebhookURL = hook.URL
+        cfg.OutboundWebhookSecret = "outbound-secret"
+    })
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 400})
+    defer resp.Body.Close()
+    tx := new(types.Transaction)
+    json.NewDecoder(resp.Body).Decode(tx)
+
+    path := fmt.Sprintf("/transactions/%d/reverse", tx.ID)
+    resp = srv.Do(t, "POST", path, token, types.ReverseTransactionRequest{Reason: "sent twice"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "POST", path, types.ReverseTransactionRequest{})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "POST", path, types.ReverseTransactionRequest{Reason: "sent twice"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    reversal := new(types.Reversal)
+    json.NewDecoder(resp.Body).Decode(reversal)
+    assert.Equal(t, tx.ID, reversal.TransactionID)
+    assert.NotZero(t, reversal.ReversalID)
+
+    balance, _ := srv.Store.GetLedgerBalance(context.Background(), alice.Number)
+    assert.Equal(t, int64(1000), balance)
+    balance, _ = srv.Store.GetLedgerBalance(context.Background(), bob.Number)
+    assert.Equal(t, int64(0), balance)
+
+    resp = srv.DoAdmin(t, "POST", path, types.ReverseTransactionRequest{Reason: "again"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", fmt.Sprintf("/account/%d/disputes", alice.ID), token, types.CreateDisputeRequest{TransactionID: tx.ID, Reason: types.DisputeDuplicate})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+    select {
+    case e := <-events:
+        assert.Equal(t, "transaction.reversed", e.Type)
+    case <-time.After(5 * time.Second):
+        t.Fatal("no webhook event")
+    }
+}
+
+func TestFailedWebhookIsDeadLettered(t *testing.T) {
+    up := make(chan bool, 1)
+    up <- false
+    events := make(chan webhook.Event, 1)
+    hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ok := <-up
+        up <- ok
+        if !ok {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        var e webhook.Event
+        json.NewDecoder(r.Body).Decode(&e)
+        events <- e
+    }))
+    defer hook.Close()
+
+    srv := apitest.NewServer(t, func(cfg *config.Config) {
+        cfg.OutboundWebhookURL = hook.URL
+        cfg.OutboundWebhookAttempts = 3
+        cfg.OutboundWebhookBackoff = time.Millisecond
+    })
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 400})
+    defer resp.Body.Close()
+    tx := new(types.Transaction)
+    json.NewDecoder(resp.Body).Decode(tx)
+
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/transactions/%d/reverse", tx.ID), types.ReverseTransactionRequest{Reason: "sent twice"})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    var letters []*types.DeadLetter
+    for deadline := time.Now().Add(5 * time.Second); len(letters) == 0; {
+        if time.Now().After(deadline) {
+            t.Fatal("no dead letter")
+        }
+        time.Sleep(10 * time.Millisecond)
+        letters, _ = srv.Store.GetDeadLettersByStatus(context.Background(), types.DeadLetterPending)
+    }
+    d := letters[0]
+    assert.Equal(t, "transaction.reversed", d.Event)
+    assert.Equal(t, "webhook", d.Channel)
+    assert.Equal(t, 3, d.Attempts)
+    assert.Equal(t, "503 Service Unavailable", d.Reason)
+
+    <-up
+    up <- true
+    resp = srv.DoAdmin(t, "POST", fmt.Sprintf("/admin/dead-letters/%d/replay", d.ID), nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    select {
+    case e := <-events:
+        assert.Equal(t, "transaction.reversed", e.Type)
+    case <-time.After(5 * time.Second):
+        t.Fatal("no webhook event")
+    }
+}
+
+func TestAdmissionShedsLowPriorityFirst(t *testing.T) {
+    srv := apitest.NewServer(t, func(cfg *config.Config) {
+        cfg.MaxInFlight = 2
+        cfg.ReservedInFlight = 1
+        cfg.AdmissionMaxWait = 50 * time.Millisecond
+    })
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, alice.Number, "pw")
+    srv.Store.SetLatency(300 * time.Millisecond)
+
+    // a slow read takes the only slot outside the reserve
+    done := make(chan int)
+    go func() {
+        resp := srv.Do(t, "GET", fmt.Sprintf("/account/%d", alice.ID), token, nil)
+        resp.Body.Close()
+        done <- resp.StatusCode
+    }()
+    time.Sleep(100 * time.Millisecond)
+
+    resp := srv.Do(t, "GET", fmt.Sprintf("/account/%d/transactions", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+    assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+    apiErr := new(api.ApiError)
+    json.NewDecoder(resp.Body).Decode(apiErr)
+    assert.Equal(t, "overloaded", apiErr.Code)
+
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+    // logins get the reserved slot
+    srv.Login(t, alice.Number, "pw")
+
+    assert.Equal(t, http.StatusOK, <-done)
+}
+
+func TestMaintenanceModeServesOnlyReads(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.DoAdmin(t, "PUT", "/admin/maintenance", types.Maintenance{Enabled: true})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+    apiErr := new(api.ApiError)
+    json.NewDecoder(resp.Body).Decode(apiErr)
+    assert.Equal(t, "maintenance", apiErr.Code)
+
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer/preview", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.Do(t, "GET", "/healthz", "", nil)
+    defer resp.Body.Close()
+    health := new(types.HealthResponse)
+    json.NewDecoder(resp.Body).Decode(health)
+    assert.Equal(t, "ok", health.Status)
+    assert.True(t, health.Maintenance.Enabled)
+    assert.NotNil(t, health.Maintenance.Since)
+
+    resp = srv.DoAdmin(t, "PUT", "/admin/maintenance", types.Maintenance{Enabled: false})
+    defer resp.Body.Close()
+
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestUsageQuotas(t *testing.T) {
+    srv := apitest.NewServer(t, func(cfg *config.Config) {
+        cfg.Quotas = types.Quotas{RequestsPerDay: 6, TransferVolumePerMonth: 500}
+    })
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 400})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 200})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+    apiErr := new(api.ApiError)
+    json.NewDecoder(resp.Body).Decode(apiErr)
+    assert.Equal(t, "quota_exceeded", apiErr.Code)
+
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/usage", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    report := new(types.UsageReport)
+    json.NewDecoder(resp.Body).Decode(report)
+    assert.Equal(t, int64(3), report.Total.Requests)
+    assert.Equal(t, int64(1), report.Total.Transfers)
+    assert.Equal(t, int64(400), report.Total.TransferVolume)
+    assert.Equal(t, int64(500), report.Quotas.TransferVolumePerMonth)
+
+    resp = srv.DoAdmin(t, "GET", "/admin/usage", nil)
+    defer resp.Body.Close()
+    totals := []*types.Usage{}
+    json.NewDecoder(resp.Body).Decode(&totals)
+    assert.Len(t, totals, 1)
+    assert.Equal(t, alice.Number, totals[0].AccountNumber)
+
+    for i := 0; i < 3; i++ {
+        resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance", alice.ID), token, nil)
+        defer resp.Body.Close()
+        assert.Equal(t, http.StatusOK, resp.StatusCode)
+    }
+    resp = srv.Do(t, "GET", fmt.Sprintf("/account/%d/balance", alice.ID), token, nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+    assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+}
+
+func TestSLOStatus(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    bob := srv.CreateAccount(t, "bob", "b", "pw")
+    srv.Fund(t, alice.Number, 1000)
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 100})
+    defer resp.Body.Close()
+    // turned down, but served
+    resp = srv.Do(t, "POST", "/transfer", token, types.TransferRequest{ToAccount: bob.Number, Amount: 5000})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "GET", "/admin/slo", nil)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    statuses := []slo.Status{}
+    json.NewDecoder(resp.Body).Decode(&statuses)
+    assert.Len(t, statuses, 2)
+    assert.Equal(t, "transfer", statuses[0].Name)
+    assert.Equal(t, slo.StateOK, statuses[0].State)
+    assert.Equal(t, int64(2), statuses[0].Objectives[0].Requests)
+    assert.Equal(t, 1.0, statuses[0].Objectives[0].Ratio)
+    assert.Equal(t, "login", statuses[1].Name)
+    assert.Equal(t, int64(1), statuses[1].Objectives[0].Requests)
+}
+
+func TestAccountInvitations(t *testing.T) {
+    srv := apitest.NewServer(t)
+    alice := srv.CreateAccount(t, "alice", "a", "pw")
+    token := srv.Login(t, alice.Number, "pw")
+
+    resp := srv.Do(t, "POST", "/invitations", token, types.CreateInvitationRequest{Email: "carol@example.com", Permission: types.PermissionView})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    resp = srv.DoAdmin(t, "POST", "/admin/invitations", types.CreateInvitationRequest{Email: "dave@example.com", Permission: types.PermissionFull})
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp = srv.Do(t, "GET", "/invitations", token, nil)
+    defer resp.Body.Close()
+    invitations := []*types.AccountInvitation{}
+    json.NewDecoder(resp.Body).Decode(&invitations)
+    assert.Len(t, invitations, 1)
+    assert.Equal(t, types.InvitationPending, invitations[0].Status)
+
+    // the token only goes out by email
+    srv.Store.CreateAccountInvitation(context.Background(), &types.AccountInvitation{
+        Email: "erin@example.com",
+        InvitedBy: alice.Number,
+        Permission: types.PermissionView,
+        Status: types.InvitationPending,
+        TokenHash: importer.HashToken("invitation-token"),
+        ExpiresAt: time.Now().Add(time.Hour),
+    })
+    onboard := types.OnboardRequest{Token: "invitation-token", FirstName: "erin", LastName: "e", Password: "pw"}
+    resp = srv.Do(t, "POST", "/onboarding", "", onboard)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    erin := new(types.Account)
+    json.NewDecoder(resp.Body).Decode(erin)
+    assert.Equal(t, "erin@example.com", erin.Email)
+
+    owners, _ := srv.Store.GetAccountOwners(context.Background(), alice.Number)
+    assert.Len(t, owners, 1)
+    assert.Equal(t, erin.Number, owners[0].OwnerNumber)
+    assert.Equal(t, types.PermissionView, owners[0].Permission)
+
+    resp = srv.Do(t, "POST", "/onboarding", "", onboard)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+    // a closed account can't gain owners
+    srv.Store.CreateAccountInvitation(context.Background(), &types.AccountInvitation{
+        Email: "frank@example.com",
+        InvitedBy: alice.Number,
+        Permission: types.PermissionFull,
+        Status: types.InvitationPending,
+        TokenHash: importer.HashToken("closed-token"),
+        ExpiresAt: time.Now().Add(time.Hour),
+    })
+    if err := srv.Store.CloseAccount(context.Background(), alice.Number, nil, nil, nil, time.Now()); err != nil {
+        t.Fatal(err)
+    }
+    onboard.Token = "closed-token"
+    resp = srv.Do(t, "POST", "/onboarding", "", onboard)
+    defer resp.Body.Close()
+    assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
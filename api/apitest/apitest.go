@@ -0,0 +1,219 @@
+package apitest
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "io"
+    "net"
+    "net/http"
+    "os"
+    "testing"
+    "time"
+
+    "gobank/api"
+    "gobank/auth"
+    "gobank/config"
+    "gobank/fx"
+    "gobank/notify"
+    "gobank/numbering"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+// WebhookProvider and WebhookSecret are the inbound webhook provider the
+// test server accepts and the secret its callbacks must be signed with.
+const (
+    WebhookProvider = "sandbox"
+    WebhookSecret = "apitest-webhook-secret"
+    // CardNetworkSecret signs card authorization requests.
+    CardNetworkSecret = "apitest-card-network-secret"
+    AdminToken = "apitest-admin-token"
+)
+
+// Server is an APIServer listening on a random local port and backed by an
+// in-memory storagetest.Store.
+type Server struct {
+    URL string
+    Store *storagetest.Store
+}
+
+// NewServer starts the API server for the duration of the test. JWT_SECRET
+// is set to a test value when it is not already set, and EUR is quoted at
+// 1.08 USD. Each configure func can change the config before the server
+// starts.
+func NewServer(t testing.TB, configure ...func(*config.Config)) *Server {
+    t.Helper()
+
+    if os.Getenv("JWT_SECRET") == "" {
+        t.Setenv("JWT_SECRET", "apitest-secret")
+    }
+
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    store := storagetest.New()
+    cfg := config.Default()
+    cfg.ListenAddr = l.Addr().String()
+    cfg.FXRates["EUR"] = 108 * fx.RateScale / 100
+    cfg.WebhookSecrets[WebhookProvider] = WebhookSecret
+    cfg.QRSecret = "apitest-qr-secret"
+    cfg.AdminToken = AdminToken
+    cfg.WebhookSecrets["card_network"] = CardNetworkSecret
+    cfg.EncryptionKey = bytes.Repeat([]byte{7}, 32)
+    cfg.DocumentSecret = "apitest-document-secret"
+    cfg.DocumentDir = t.TempDir()
+    for _, f := range configure {
+        f(&cfg)
+    }
+    notifier := notify.New(
+        notify.NewConsoleSender(io.Discard),
+        1,
+        notify.WithSMS(notify.NewConsoleSMSSender(io.Discard)),
+        notify.WithPreferences(store),
+        notify.WithDeadLetters(store),
+    )
+    server := api.NewApiServer(cfg, store, notifier)
+    go server.Serve(l)
+    t.Cleanup(func() { l.Close() })
+
+    return &Server{
+        URL: "http://" + l.Addr().String(),
+        Store: store,
+    }
+}
+
+// CreateAccount stores a new account directly, bypassing the API.
+func (s *Server) CreateAccount(t testing.TB, firstName, lastName, password string) *types.Account {
+    t.Helper()
+
+    acc, err := types.NewAccount(firstName, lastName, password)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := numbering.New(s.Store).Create(context.Background(), acc); err != nil {
+        t.Fatal(err)
+    }
+
+    return acc
+}
+
+// Login returns a JWT for the account through the /login endpoint.
+func (s *Server) Login(t testing.TB, number int64, password string) string {
+    t.Helper()
+
+    resp := s.Do(t, "POST", "/login", "", types.LoginRequest{Number: number, Password: password})
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("login failed with status %d", resp.StatusCode)
+    }
+
+    loginResp := new(types.LoginResponse)
+    if err := json.NewDecoder(resp.Body).Decode(loginResp); err != nil {
+        t.Fatal(err)
+    }
+
+    return loginResp.Token
+}
+
+// StepUp confirms the step-up challenge of token's session, whose code
+// only goes out in a notification, and returns the token it gets back.
+func (s *Server) StepUp(t testing.TB, token string) string {
+    t.Helper()
+
+    claims, err := auth.ParseToken([]byte(os.Getenv("JWT_SECRET")), token)
+    if err != nil {
+        t.Fatal(err)
+    }
+    c, err := s.Store.GetStepUpChallenge(context.Background(), claims.StepUp)
+    if err != nil {
+        t.Fatal(err)
+    }
+    sum := sha256.Sum256([]byte("000000"))
+    c.CodeHash = hex.EncodeToString(sum[:])
+    if err := s.Store.SaveStepUpChallenge(context.Background(), c); err != nil {
+        t.Fatal(err)
+    }
+
+    resp := s.Do(t, "POST", "/login/step-up", token, types.StepUpRequest{Code: "000000"})
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("step-up failed with status %d", resp.StatusCode)
+    }
+
+    loginResp := new(types.LoginResponse)
+    if err := json.NewDecoder(resp.Body).Decode(loginResp); err != nil {
+        t.Fatal(err)
+    }
+
+    return loginResp.Token
+}
+
+// Do sends body encoded as JSON (when not nil) with the token in the
+// x-jwt-token header (when not empty).
+func (s *Server) Do(t testing.TB, method, path, token string, body any) *http.Response {
+    t.Helper()
+
+    header := http.Header{}
+    if token != "" {
+        header.Set("x-jwt-token", token)
+    }
+
+    return s.do(t, method, path, header, body)
+}
+
+// DoAdmin sends a request authenticated with the admin token.
+func (s *Server) DoAdmin(t testing.TB, method, path string, body any) *http.Response {
+    t.Helper()
+
+    header := http.Header{}
+    header.Set("x-admin-token", AdminToken)
+
+    return s.do(t, method, path, header, body)
+}
+
+func (s *Server) do(t testing.TB, method, path string, header http.Header, body any) *http.Response {
+    t.Helper()
+
+    buf := new(bytes.Buffer)
+    if body != nil {
+        if err := json.NewEncoder(buf).Encode(body); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    req, err := http.NewRequest(method, s.URL+path, buf)
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header = header
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return resp
+}
+
+// Fund credits the account with amount from the settlement account.
+func (s *Server) Fund(t testing.TB, number int64, amount int64) {
+    t.Helper()
+
+    tx := &types.Transaction{
+        Kind: types.TransactionOpening,
+        FromAccount: types.SettlementAccountNumber,
+        ToAccount: number,
+        Amount: amount,
+        CreatedAt: time.Now().UTC(),
+    }
+    entries := types.NewEntries(types.SettlementAccountNumber, number, amount)
+    if err := s.Store.PostTransaction(context.Background(), tx, entries); err != nil {
+        t.Fatal(err)
+    }
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "time"
+
+    "gobank/auth"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+    r.status = status
+    r.ResponseWriter.WriteHeader(status)
+}
+
+// audited runs handlerFunc and records every request that can change the
+// account in its audit log, with the owner whose token made it. Reads are
+// not recorded.
+func audited(handlerFunc http.HandlerFunc, s storage.Storage, logger *log.Logger, w http.ResponseWriter, r *http.Request) {
+    if r.Method == "GET" || r.Method == "HEAD" {
+        handlerFunc(w, r)
+        return
+    }
+
+    rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+    handlerFunc(rec, r)
+
+    entry := &types.AuditEntry{
+        AccountNumber: auth.AccountFromContext(r.Context()).Number,
+        ActorNumber: auth.ActorFromContext(r.Context()),
+        Method: r.Method,
+        Path: r.URL.Path,
+        Status: rec.status,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.RecordAudit(r.Context(), entry); err != nil {
+        logger.Println("recording audit entry failed:", err)
+    }
+}
+
+func (s *APIServer) handleAuditLog(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    entries, err := s.store.GetAuditLog(r.Context(), auth.AccountFromContext(r.Context()).Number)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, entries)
+}
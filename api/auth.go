@@ -1,12 +1,13 @@
 package api
 
 import (
+    "errors"
     "net/http"
-    "encoding/json"
+    "gobank/auth"
+    "gobank/i18n"
+    "gobank/storage"
     "gobank/types"
     "os"
-    jwt "github.com/golang-jwt/jwt/v4"
-    "fmt"
 )
 
 
@@ -16,20 +17,30 @@ func (s *APIServer) handleLogin(w http.ResponseWriter, r *http.Request) error {
     }
 
     req := new(types.LoginRequest)
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+    if err := s.decodeJSON(w, r, req); err != nil {
         return err
     }
 
-    acc, err := s.store.GetAccountByNumber(int64(req.Number))
+    // an unknown number looks the same as a wrong password
+    acc, err := s.store.GetAccountByNumber(r.Context(), int64(req.Number))
+    if errors.Is(err, storage.ErrNotFound) {
+        return writeMessage(w, r, http.StatusForbidden, i18n.InvalidCredentials)
+    }
     if err != nil {
         return err
     }
 
     if !acc.ValidatePassword(req.Password) {
-        return WriteJSON(w, http.StatusForbidden, "Either number or password is incorect")
+        return writeMessage(w, r, http.StatusForbidden, i18n.InvalidCredentials)
+    }
+
+    claims := auth.Claims{AccountID: acc.ID, AccountNumber: acc.Number, IP: clientIP(r), Country: s.clientCountry(r)}
+    claims.StepUp, err = s.checkDevice(r, acc, claims)
+    if err != nil {
+        return err
     }
 
-    token, err := createJWT(acc)
+    token, err := auth.NewToken(jwtSecret(), claims, s.tokenTTL)
     if err != nil {
         return err
     }
@@ -37,6 +48,7 @@ func (s *APIServer) handleLogin(w http.ResponseWriter, r *http.Request) error {
     resp := types.LoginResponse{
         Token: token,
         Number: acc.Number,
+        StepUpRequired: claims.StepUp != "",
     }
 
     return WriteJSON(w, http.StatusOK, resp)
@@ -44,26 +56,7 @@ func (s *APIServer) handleLogin(w http.ResponseWriter, r *http.Request) error {
 
 
 
-func validateJWT(token string) (*jwt.Token, error) {
-    secret := os.Getenv("JWT_SECRET")
-    return jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-            return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-        }
-
-        return []byte(secret), nil
-    })
-}
-
-func createJWT(account *types.Account) (string, error) {
-    claims := &jwt.MapClaims{
-        "expiresAt": 15000, 
-        "accountNumber": account.Number,
-    }
-
-    secret := os.Getenv("JWT_SECRET")
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-    return token.SignedString([]byte(secret))
+// jwtSecret is the key login tokens are signed with.
+func jwtSecret() []byte {
+    return []byte(os.Getenv("JWT_SECRET"))
 }
-
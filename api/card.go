@@ -0,0 +1,376 @@
+package api
+
+import (
+    "crypto/subtle"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/cards"
+    "gobank/metrics"
+    "gobank/storage"
+    "gobank/types"
+    "gobank/vault"
+)
+
+const (
+    // cardNetworkProvider is the webhook provider whose secret signs card
+    // authorization requests.
+    cardNetworkProvider = "card_network"
+    holdTTL = 7 * 24 * time.Hour
+)
+
+var authorizationsTotal = metrics.NewCounter("gobank_card_authorizations_total", "Card authorizations by result.", "result")
+
+func (s *APIServer) handleCards(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        cards, err := s.store.GetCardsByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, cards)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.IssueCardRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+        if req.DailyLimit < 0 {
+            return fmt.Errorf("dailyLimit can't be negative")
+        }
+
+        issued, err := s.issueCard(account, req.DailyLimit)
+        if err != nil {
+            return err
+        }
+        if err := s.store.CreateCard(r.Context(), issued.Card); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, issued)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// issueCard generates a card for account with its details encrypted for
+// storage. The returned IssuedCard is the only place they appear in clear.
+func (s *APIServer) issueCard(account *types.Account, dailyLimit int64) (*types.IssuedCard, error) {
+    key := s.cfg.EncryptionKey
+    if len(key) == 0 {
+        return nil, fmt.Errorf("cards are not enabled")
+    }
+
+    pan, err := cards.NewPAN()
+    if err != nil {
+        return nil, err
+    }
+    cvv, err := cards.NewCVV()
+    if err != nil {
+        return nil, err
+    }
+    now := time.Now().UTC()
+    month, year := cards.Expiry(now)
+
+    card := &types.Card{
+        AccountNumber: account.Number,
+        Last4: pan[len(pan)-4:],
+        Status: types.CardActive,
+        DailyLimit: dailyLimit,
+        CreatedAt: now,
+        PANHash: vault.Hash(key, pan),
+    }
+    secrets := map[*string]string{
+        &card.EncryptedPAN: pan,
+        &card.EncryptedCVV: cvv,
+        &card.EncryptedExpiry: fmt.Sprintf("%02d/%d", month, year),
+    }
+    for dst, value := range secrets {
+        if *dst, err = vault.Encrypt(key, []byte(value)); err != nil {
+            return nil, err
+        }
+    }
+
+    return &types.IssuedCard{
+        Card: card,
+        PAN: pan,
+        CVV: cvv,
+        ExpiryMonth: month,
+        ExpiryYear: year,
+    }, nil
+}
+
+// handleCard changes a card's daily limit.
+func (s *APIServer) handleCard(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "PUT" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    card, err := s.cardFromPath(r)
+    if err != nil {
+        return err
+    }
+
+    req := new(types.CardLimitRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    if req.DailyLimit < 0 {
+        return fmt.Errorf("dailyLimit can't be negative")
+    }
+
+    card.DailyLimit = req.DailyLimit
+    if err := s.store.UpdateCard(r.Context(), card); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, card)
+}
+
+func (s *APIServer) handleCardAction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    card, err := s.cardFromPath(r)
+    if err != nil {
+        return err
+    }
+
+    if card.Status == types.CardBlocked {
+        return fmt.Errorf("card is blocked, contact support")
+    }
+
+    switch action := r.PathValue("action"); action {
+    case "freeze":
+        card.Status = types.CardFrozen
+    case "unfreeze":
+        card.Status = types.CardActive
+    default:
+        return fmt.Errorf("unknown card action %s", action)
+    }
+
+    if err := s.store.UpdateCard(r.Context(), card); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, card)
+}
+
+func (s *APIServer) handleHolds(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    holds, err := s.store.GetActiveHolds(r.Context(), account.Number, time.Now().UTC())
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, holds)
+}
+
+// cardFromPath loads the {cardID} card, which must belong to the
+// authenticated account.
+func (s *APIServer) cardFromPath(r *http.Request) (*types.Card, error) {
+    id, err := strconv.Atoi(r.PathValue("cardID"))
+    if err != nil {
+        return nil, fmt.Errorf("invalid card id given %s", r.PathValue("cardID"))
+    }
+
+    card, err := s.store.GetCard(r.Context(), id)
+    if err != nil {
+        return nil, err
+    }
+    if card.AccountNumber != auth.AccountFromContext(r.Context()).Number {
+        return nil, fmt.Errorf("card %d %w", id, storage.ErrNotFound)
+    }
+
+    return card, nil
+}
+
+// handleAuthorize is called by the card network, signed with its webhook
+// secret, when a card is used. An approval places a hold on the account.
+// Declines are answered with 200 and a reason, like the network expects.
+func (s *APIServer) handleAuthorize(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    body, err := s.readSigned(w, r, cardNetworkProvider)
+    if err != nil || body == nil {
+        return err
+    }
+
+    req := new(types.AuthorizationRequest)
+    if err := json.Unmarshal(body, req); err != nil {
+        return err
+    }
+    if req.Amount <= 0 {
+        return fmt.Errorf("amount must be positive")
+    }
+    if len(s.cfg.EncryptionKey) == 0 {
+        return fmt.Errorf("cards are not enabled")
+    }
+
+    resp, err := s.authorize(r, req)
+    if err != nil {
+        return err
+    }
+
+    result := "approved"
+    if !resp.Approved {
+        result = resp.DeclineReason
+    }
+    authorizationsTotal.Inc(result)
+
+    return WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *APIServer) authorize(r *http.Request, req *types.AuthorizationRequest) (*types.AuthorizationResponse, error) {
+    key := s.cfg.EncryptionKey
+    decline := func(reason string) (*types.AuthorizationResponse, error) {
+        return &types.AuthorizationResponse{DeclineReason: reason}, nil
+    }
+
+    if !cards.ValidLuhn(req.PAN) {
+        return decline("invalid_card")
+    }
+    card, err := s.store.GetCardByPANHash(r.Context(), vault.Hash(key, req.PAN))
+    if errors.Is(err, storage.ErrNotFound) {
+        return decline("invalid_card")
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    switch card.Status {
+    case types.CardFrozen:
+        return decline("card_frozen")
+    case types.CardBlocked:
+        return decline("card_blocked")
+    }
+
+    now := time.Now().UTC()
+    expiry, err := vault.Decrypt(key, card.EncryptedExpiry)
+    if err != nil {
+        return nil, err
+    }
+    if string(expiry) != fmt.Sprintf("%02d/%d", req.ExpiryMonth, req.ExpiryYear) {
+        return decline("invalid_expiry")
+    }
+    if cards.Expired(req.ExpiryMonth, req.ExpiryYear, now) {
+        return decline("card_expired")
+    }
+
+    cvv, err := vault.Decrypt(key, card.EncryptedCVV)
+    if err != nil {
+        return nil, err
+    }
+    if subtle.ConstantTimeCompare(cvv, []byte(req.CVV)) != 1 {
+        return decline("invalid_cvv")
+    }
+
+    account, err := s.store.GetAccountByNumber(r.Context(), card.AccountNumber)
+    if err != nil {
+        return nil, err
+    }
+    if req.Currency != "" && req.Currency != account.Currency {
+        return decline("currency_not_supported")
+    }
+
+    hold := &types.Hold{
+        AccountNumber: account.Number,
+        CardID: card.ID,
+        Amount: req.Amount,
+        Merchant: req.Merchant,
+        Status: types.HoldActive,
+        ExpiresAt: now.Add(holdTTL),
+        CreatedAt: now,
+    }
+    err = s.store.PlaceHold(r.Context(), hold, card.DailyLimit)
+    if errors.Is(err, storage.ErrInsufficientFunds) {
+        return decline("insufficient_funds")
+    }
+    if errors.Is(err, storage.ErrCardLimitExceeded) {
+        return decline("limit_exceeded")
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return &types.AuthorizationResponse{Approved: true, HoldID: hold.ID}, nil
+}
+
+// handleHoldAction is called by the card network, signed like
+// handleAuthorize, to capture a hold once the merchant settles the payment
+// or to release it when the payment is cancelled.
+func (s *APIServer) handleHoldAction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    body, err := s.readSigned(w, r, cardNetworkProvider)
+    if err != nil || body == nil {
+        return err
+    }
+
+    id, err := strconv.Atoi(r.PathValue("holdID"))
+    if err != nil {
+        return fmt.Errorf("invalid hold id given %s", r.PathValue("holdID"))
+    }
+    hold, err := s.store.GetHold(r.Context(), id)
+    if err != nil {
+        return err
+    }
+
+    now := time.Now().UTC()
+    switch action := r.PathValue("action"); action {
+    case "capture":
+        req := new(types.CaptureRequest)
+        if len(body) > 0 {
+            if err := json.Unmarshal(body, req); err != nil {
+                return err
+            }
+        }
+        if req.Amount == 0 {
+            req.Amount = hold.Amount
+        }
+        if req.Amount < 0 || req.Amount > hold.Amount {
+            return fmt.Errorf("amount must be between 1 and the held %d", hold.Amount)
+        }
+
+        tx := &types.Transaction{
+            Kind: types.TransactionCard,
+            FromAccount: hold.AccountNumber,
+            ToAccount: types.SettlementAccountNumber,
+            Amount: req.Amount,
+            CreatedAt: now,
+        }
+        entries := types.NewEntries(hold.AccountNumber, types.SettlementAccountNumber, req.Amount)
+        if err := s.store.CaptureHold(r.Context(), hold.ID, tx, entries); err != nil {
+            return err
+        }
+        hold.Status = types.HoldCaptured
+
+    case "release":
+        if err := s.store.ReleaseHold(r.Context(), hold.ID, now); err != nil {
+            return err
+        }
+        hold.Status = types.HoldReleased
+
+    default:
+        return fmt.Errorf("unknown hold action %q", action)
+    }
+
+    return WriteJSON(w, http.StatusOK, hold)
+}
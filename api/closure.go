@@ -0,0 +1,150 @@
+package api
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+
+    "gobank/auth"
+    "gobank/fraud"
+    "gobank/notify"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// handleCloseAccount sweeps the remaining balance to another account,
+// settles what depends on the account and closes it, all at once. An
+// account with a loan, uncaptured card payments, open disputes or
+// transfers that may still come back can't be closed until they are
+// settled, so a payout out of the bank is sent first and the account
+// closed by another request once it has settled.
+func (s *APIServer) handleCloseAccount(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.CloseAccountRequest)
+    if err := s.decodeJSON(w, r, req); err != nil && !errors.Is(err, io.EOF) {
+        return err
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    if account.ClosedAt != nil {
+        return fmt.Errorf("account %d: %w", account.Number, storage.ErrAccountClosed)
+    }
+    if req.ToAccount != 0 && req.Payout != nil {
+        return fmt.Errorf("give either toAccount or payout, not both")
+    }
+    if account.Balance > 0 && req.Payout != nil {
+        return s.payOut(w, r, account, req.Payout)
+    }
+
+    now := time.Now().UTC()
+    var sweep *types.Transaction
+    var entries []*types.LedgerEntry
+    var decision fraud.Decision
+    if account.Balance > 0 {
+        var err error
+        sweep, entries, decision, err = s.closureSweep(r, account, req.ToAccount, now)
+        if err != nil {
+            return err
+        }
+        // the account stays open until a blocked sweep is reviewed
+        if decision.Action == types.FraudBlock {
+            c, err := s.recordFraudCase(r.Context(), decision, account.Number, sweep.ToAccount, sweep.Amount, nil, nil)
+            if err != nil {
+                return err
+            }
+            return WriteJSON(w, http.StatusAccepted, c)
+        }
+    }
+
+    if err := s.store.CloseAccount(r.Context(), account.Number, sweep, entries, s.cfg.VelocityLimits, now); err != nil {
+        return err
+    }
+    if decision.Action == types.FraudReview {
+        s.recordFraudCase(r.Context(), decision, account.Number, sweep.ToAccount, sweep.Amount, nil, sweep)
+    }
+    s.notifier.Publish(notify.Event{
+        Type: notify.AccountClosed,
+        Account: account,
+        Data: map[string]any{"amount": account.Balance},
+    })
+
+    account.Balance = 0
+    account.ClosedAt = &now
+
+    return WriteJSON(w, http.StatusOK, account)
+}
+
+// closureSweep builds the transfer of the whole balance to toAccount and
+// runs it past the fraud rules like any other transfer.
+func (s *APIServer) closureSweep(r *http.Request, account *types.Account, toAccount int64, now time.Time) (*types.Transaction, []*types.LedgerEntry, fraud.Decision, error) {
+    if toAccount == 0 {
+        return nil, nil, fraud.Decision{}, fmt.Errorf("the account still holds %d, give toAccount or payout for it", account.Balance)
+    }
+    to, err := s.store.GetAccountByNumber(r.Context(), toAccount)
+    if err != nil {
+        return nil, nil, fraud.Decision{}, err
+    }
+    if to.Number == account.Number {
+        return nil, nil, fraud.Decision{}, fmt.Errorf("can't sweep the balance to the account being closed")
+    }
+    if to.ClosedAt != nil {
+        return nil, nil, fraud.Decision{}, fmt.Errorf("account %d: %w", to.Number, storage.ErrAccountClosed)
+    }
+
+    entries, _, err := s.transferEntries(account, to, account.Balance)
+    if err != nil {
+        return nil, nil, fraud.Decision{}, err
+    }
+    decision, err := s.checkFraud(r, account, to, "", nil, account.Balance)
+    if err != nil {
+        return nil, nil, fraud.Decision{}, err
+    }
+    sweep := &types.Transaction{
+        Kind: types.TransactionClosure,
+        FromAccount: account.Number,
+        ToAccount: to.Number,
+        Amount: account.Balance,
+        CreatedAt: now,
+    }
+
+    return sweep, entries, decision, nil
+}
+
+// payOut sends the whole balance to another bank ahead of the closure.
+// The pots go with the account, so their money is paid out with the rest.
+func (s *APIServer) payOut(w http.ResponseWriter, r *http.Request, account *types.Account, p *types.Payout) error {
+    req := &types.ExternalTransferRequest{
+        RoutingNumber: p.RoutingNumber,
+        ToAccount: p.ToAccount,
+        Name: p.Name,
+        Amount: account.Balance,
+    }
+    if err := validateExternalTransfer(req); err != nil {
+        return err
+    }
+
+    pots, err := s.store.GetPotsByAccount(r.Context(), account.Number)
+    if err != nil {
+        return err
+    }
+    for _, pot := range pots {
+        if err := s.store.DeletePot(r.Context(), pot.ID); err != nil {
+            return err
+        }
+    }
+
+    et, c, err := s.sendExternalTransfer(r, account, req)
+    if err != nil {
+        return err
+    }
+    if c != nil {
+        return WriteJSON(w, http.StatusAccepted, c)
+    }
+
+    return WriteJSON(w, http.StatusAccepted, et)
+}
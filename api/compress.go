@@ -0,0 +1,176 @@
+package api
+
+import (
+    "compress/gzip"
+    "compress/zlib"
+    "io"
+    "mime"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// precompressed are content types that don't get smaller when compressed
+// again.
+var precompressed = []string{
+    "application/pdf",
+    "application/zip",
+    "application/gzip",
+    "image/",
+    "audio/",
+    "video/",
+    "font/woff",
+}
+
+// withCompression gzips or deflates responses of at least minBytes for
+// clients that accept it. Smaller responses and content types in
+// precompressed are sent as they are.
+func withCompression(minBytes int) Middleware {
+    return func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+            w.Header().Add("Vary", "Accept-Encoding")
+
+            encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+            if encoding == "" || r.Method == "HEAD" || r.Header.Get("Range") != "" {
+                handlerFunc(w, r)
+                return
+            }
+
+            cw := &compressWriter{ResponseWriter: w, encoding: encoding, minBytes: minBytes, status: http.StatusOK}
+            defer cw.Close()
+
+            handlerFunc(cw, r)
+        }
+    }
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip, or returns "" when neither is accepted.
+func negotiateEncoding(accept string) string {
+    accepted := map[string]bool{}
+    for _, part := range strings.Split(accept, ",") {
+        name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        q := 1.0
+        if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            parsed, err := strconv.ParseFloat(v, 64)
+            if err != nil {
+                continue
+            }
+            q = parsed
+        }
+        accepted[strings.ToLower(name)] = q > 0
+    }
+
+    for _, encoding := range []string{"gzip", "deflate"} {
+        if accepted[encoding] {
+            return encoding
+        }
+    }
+
+    return ""
+}
+
+// compressWriter holds the response back until it has minBytes, then
+// decides whether to compress it.
+type compressWriter struct {
+    http.ResponseWriter
+    encoding string
+    minBytes int
+
+    status int
+    buf []byte
+    started bool
+    enc io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+    if cw.started {
+        return
+    }
+    cw.status = status
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+    if !cw.started {
+        cw.buf = append(cw.buf, p...)
+        if len(cw.buf) < cw.minBytes {
+            return len(p), nil
+        }
+        if err := cw.start(true); err != nil {
+            return 0, err
+        }
+        return len(p), nil
+    }
+
+    if cw.enc != nil {
+        return cw.enc.Write(p)
+    }
+    return cw.ResponseWriter.Write(p)
+}
+
+// start sends the headers and what is buffered so far, compressed when
+// compress is set and the response suits it.
+func (cw *compressWriter) start(compress bool) error {
+    cw.started = true
+
+    h := cw.Header()
+    if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+        h.Set("Content-Type", http.DetectContentType(cw.buf))
+    }
+    if compress && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) && bodyAllowed(cw.status) {
+        h.Set("Content-Encoding", cw.encoding)
+        h.Del("Content-Length")
+        if cw.encoding == "gzip" {
+            cw.enc = gzip.NewWriter(cw.ResponseWriter)
+        } else {
+            // HTTP's deflate is the zlib format, not a raw deflate stream
+            cw.enc = zlib.NewWriter(cw.ResponseWriter)
+        }
+    }
+    cw.ResponseWriter.WriteHeader(cw.status)
+
+    buf := cw.buf
+    cw.buf = nil
+    if len(buf) == 0 {
+        return nil
+    }
+    if cw.enc != nil {
+        _, err := cw.enc.Write(buf)
+        return err
+    }
+    _, err := cw.ResponseWriter.Write(buf)
+    return err
+}
+
+// Close sends a response that stayed under minBytes as it is and finishes
+// the compressed stream otherwise.
+func (cw *compressWriter) Close() error {
+    if !cw.started {
+        return cw.start(false)
+    }
+    if cw.enc != nil {
+        return cw.enc.Close()
+    }
+    return nil
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+    return cw.ResponseWriter
+}
+
+func compressible(contentType string) bool {
+    mediaType, _, err := mime.ParseMediaType(contentType)
+    if err != nil {
+        mediaType = contentType
+    }
+    for _, prefix := range precompressed {
+        if strings.HasPrefix(mediaType, prefix) {
+            return false
+        }
+    }
+    return true
+}
+
+func bodyAllowed(status int) bool {
+    return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *APIServer) handleDeadLetters(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    status := r.URL.Query().Get("status")
+    if status == "" {
+        status = types.DeadLetterPending
+    }
+
+    letters, err := s.store.GetDeadLettersByStatus(r.Context(), status)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, letters)
+}
+
+func (s *APIServer) handleDeadLetter(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    d, err := s.deadLetterFromPath(r)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, d)
+}
+
+// handleReplayDeadLetter makes one more delivery attempt. A failed replay
+// leaves the dead letter pending with the new reason.
+func (s *APIServer) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    d, err := s.deadLetterFromPath(r)
+    if err != nil {
+        return err
+    }
+    if d.Status != types.DeadLetterPending {
+        return fmt.Errorf("dead letter %d: %w", d.ID, storage.ErrAlreadyReplayed)
+    }
+
+    replay := s.notifier.Replay
+    if d.Channel == webhookChannel {
+        replay = s.replayWebhook
+    }
+    if err := replay(r.Context(), d); err != nil {
+        if err := s.store.RecordDeadLetterFailure(r.Context(), d, err.Error()); err != nil {
+            return err
+        }
+        return WriteJSON(w, http.StatusBadGateway, ApiError{Error: "replay failed: " + err.Error()})
+    }
+
+    if err := s.store.MarkDeadLetterReplayed(r.Context(), d, time.Now().UTC()); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, d)
+}
+
+func (s *APIServer) deadLetterFromPath(r *http.Request) (*types.DeadLetter, error) {
+    id, err := strconv.Atoi(r.PathValue("letterID"))
+    if err != nil {
+        return nil, fmt.Errorf("invalid dead letter id given %s", r.PathValue("letterID"))
+    }
+
+    return s.store.GetDeadLetter(r.Context(), id)
+}
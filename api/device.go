@@ -0,0 +1,229 @@
+package api
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/i18n"
+    "gobank/notify"
+    "gobank/types"
+)
+
+const (
+    stepUpTTL = 10 * time.Minute
+    stepUpMaxAttempts = 5
+)
+
+// checkDevice records the device a login comes from and warns the account
+// holder about a new device or impossible travel, a login from another
+// country than the last one sooner than anyone could have got there. When
+// step-up is on, it returns the ID of the challenge the session has to
+// confirm before it can move money, and the device is only recorded once
+// the challenge is confirmed, so logging in again doesn't make it known.
+func (s *APIServer) checkDevice(r *http.Request, account *types.Account, sess auth.Claims) (string, error) {
+    devices, err := s.store.GetDevices(r.Context(), account.Number)
+    if err != nil {
+        return "", err
+    }
+
+    fingerprint := r.Header.Get("X-Device-ID")
+    if fingerprint == "" {
+        fingerprint = r.UserAgent()
+    }
+    sum := sha256.Sum256([]byte(fingerprint))
+
+    now := time.Now().UTC()
+    device := &types.Device{
+        AccountNumber: account.Number,
+        Fingerprint: hex.EncodeToString(sum[:]),
+        UserAgent: r.UserAgent(),
+        IP: sess.IP,
+        Country: sess.Country,
+        FirstSeen: now,
+        LastSeen: now,
+    }
+
+    // the first login of an account has nothing to compare with
+    newDevice := len(devices) > 0
+    for _, d := range devices {
+        if d.Fingerprint == device.Fingerprint {
+            newDevice = false
+        }
+    }
+    travel := false
+    if len(devices) > 0 {
+        last := devices[0]
+        travel = device.Country != "" && last.Country != "" && device.Country != last.Country &&
+            now.Sub(last.LastSeen) < s.cfg.ImpossibleTravelWindow
+    }
+
+    if newDevice {
+        s.notifier.Publish(notify.Event{
+            Type: notify.NewDeviceLogin,
+            Account: account,
+            Data: map[string]any{"device": device.UserAgent, "ip": device.IP},
+        })
+    }
+    if travel {
+        s.notifier.Publish(notify.Event{
+            Type: notify.ImpossibleTravel,
+            Account: account,
+            Data: map[string]any{"country": device.Country, "lastCountry": devices[0].Country, "ip": device.IP},
+        })
+    }
+
+    if !s.cfg.StepUpOnAnomaly || !(newDevice || travel) {
+        return "", s.store.SaveDevice(r.Context(), device)
+    }
+
+    return s.startStepUp(r, account, device)
+}
+
+// startStepUp sends the account holder a code for a new challenge, which
+// trusts device, if any, once it is confirmed.
+func (s *APIServer) startStepUp(r *http.Request, account *types.Account, device *types.Device) (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    code, err := newPhoneCode()
+    if err != nil {
+        return "", err
+    }
+
+    c := &types.StepUpChallenge{
+        ID: hex.EncodeToString(b),
+        AccountNumber: account.Number,
+        CodeHash: hashPhoneCode(code),
+        ExpiresAt: time.Now().UTC().Add(stepUpTTL),
+        Device: device,
+    }
+    if err := s.store.SaveStepUpChallenge(r.Context(), c); err != nil {
+        return "", err
+    }
+
+    s.notifier.Publish(notify.Event{
+        Type: notify.TwoFactorCode,
+        Account: account,
+        Data: map[string]any{"code": code, "expiresIn": stepUpTTL.String()},
+    })
+
+    return c.ID, nil
+}
+
+// handleStepUp confirms the code of the session's challenge and returns a
+// token for the same session that can move money.
+func (s *APIServer) handleStepUp(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.StepUpRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+
+    sess := *auth.ClaimsFromContext(r.Context())
+    if sess.StepUp == "" {
+        return fmt.Errorf("no step-up verification in progress")
+    }
+    account := auth.AccountFromContext(r.Context())
+    if err := s.confirmChallenge(r, account, sess.StepUp, req.Code); err != nil {
+        return err
+    }
+
+    sess.StepUp = ""
+    token, err := auth.NewToken(jwtSecret(), sess, s.tokenTTL)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, types.LoginResponse{Token: token, Number: account.Number})
+}
+
+// confirmChallenge checks code against the account's challenge id and uses
+// the challenge up once it matches, recording its device as trusted.
+func (s *APIServer) confirmChallenge(r *http.Request, account *types.Account, id, code string) error {
+    c, err := s.store.GetStepUpChallenge(r.Context(), id)
+    if err != nil || c.AccountNumber != account.Number {
+        return fmt.Errorf("no verification in progress")
+    }
+
+    if time.Now().After(c.ExpiresAt) || c.Attempts >= stepUpMaxAttempts {
+        if err := s.store.DeleteStepUpChallenge(r.Context(), c.ID); err != nil {
+            return err
+        }
+        return fmt.Errorf("verification code expired")
+    }
+
+    if subtle.ConstantTimeCompare([]byte(hashPhoneCode(code)), []byte(c.CodeHash)) != 1 {
+        c.Attempts++
+        if err := s.store.SaveStepUpChallenge(r.Context(), c); err != nil {
+            return err
+        }
+        return fmt.Errorf("invalid verification code")
+    }
+
+    if err := s.store.DeleteStepUpChallenge(r.Context(), c.ID); err != nil {
+        return err
+    }
+    if c.Device == nil {
+        return nil
+    }
+
+    now := time.Now().UTC()
+    c.Device.FirstSeen, c.Device.LastSeen = now, now
+    return s.store.SaveDevice(r.Context(), c.Device)
+}
+
+// withStepUp turns away sessions that still have to confirm a step-up
+// challenge. It wraps money movement inside withJWTAuth.
+func withStepUp(handlerFunc http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if auth.ClaimsFromContext(r.Context()).StepUp != "" {
+            writeMessage(w, r, http.StatusForbidden, i18n.StepUpRequired)
+            return
+        }
+
+        handlerFunc(w, r)
+    }
+}
+
+func (s *APIServer) handleDevices(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    devices, err := s.store.GetDevices(r.Context(), auth.AccountFromContext(r.Context()).Number)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, devices)
+}
+
+// handleDeleteDevice forgets a device, so the next login from it counts as
+// a new device again.
+func (s *APIServer) handleDeleteDevice(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "DELETE" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := strconv.Atoi(r.PathValue("deviceID"))
+    if err != nil {
+        return fmt.Errorf("invalid device id given %s", r.PathValue("deviceID"))
+    }
+
+    if err := s.store.DeleteDevice(r.Context(), auth.AccountFromContext(r.Context()).Number, id); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, map[string]int{"deleted": id})
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/types"
+)
+
+const maxDisputeEvidence = 10
+
+// handleDisputes lists the account's disputes or opens one against a
+// transfer it sent. The money the recipient got is held until an admin
+// decides.
+func (s *APIServer) handleDisputes(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        disputes, err := s.store.GetDisputesByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, disputes)
+    }
+
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.CreateDisputeRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    if !types.ValidDisputeReason(req.Reason) {
+        return fmt.Errorf("unknown dispute reason %q", req.Reason)
+    }
+    if len(req.Evidence)+len(req.Documents) > maxDisputeEvidence {
+        return fmt.Errorf("at most %d pieces of evidence", maxDisputeEvidence)
+    }
+    if err := s.checkEvidence(r, account, req.Documents); err != nil {
+        return err
+    }
+
+    tx, err := s.store.GetTransaction(r.Context(), req.TransactionID)
+    if err != nil {
+        return err
+    }
+    if tx.FromAccount != account.Number || tx.Kind != types.TransactionTransfer || tx.Status != types.StatusCompleted {
+        return fmt.Errorf("only completed transfers sent from account %d can be disputed", account.Number)
+    }
+    now := time.Now().UTC()
+    if now.Sub(tx.CreatedAt) > s.cfg.DisputeWindow {
+        return fmt.Errorf("transfers can only be disputed within %s", s.cfg.DisputeWindow)
+    }
+
+    d := &types.Dispute{
+        TransactionID: tx.ID,
+        AccountNumber: account.Number,
+        HeldAccount: tx.ToAccount,
+        Reason: req.Reason,
+        Description: req.Description,
+        Evidence: req.Evidence,
+        Documents: req.Documents,
+        Status: types.DisputeOpen,
+        CreatedAt: now,
+    }
+    // internal accounts can't spend, so there is nothing to hold on them
+    if !types.IsInternalAccount(tx.ToAccount) {
+        entries, err := s.store.GetLedgerEntriesByTransaction(r.Context(), tx.ID)
+        if err != nil {
+            return err
+        }
+        for _, e := range entries {
+            if e.AccountNumber == tx.ToAccount {
+                d.HeldAmount += e.Amount
+            }
+        }
+    }
+
+    if err := s.store.CreateDispute(r.Context(), d); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusCreated, d)
+}
+
+func (s *APIServer) handleDispute(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    d, err := s.disputeFromPath(r)
+    if err != nil {
+        return err
+    }
+    if d.AccountNumber != auth.AccountFromContext(r.Context()).Number {
+        return fmt.Errorf("dispute %d not found", d.ID)
+    }
+
+    return WriteJSON(w, http.StatusOK, d)
+}
+
+func (s *APIServer) handleAdminDisputes(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    status := r.URL.Query().Get("status")
+    if status == "" {
+        status = types.DisputeOpen
+    }
+
+    disputes, err := s.store.GetDisputesByStatus(r.Context(), status)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, disputes)
+}
+
+// handleDisputeAction accepts or denies a dispute, with an optional
+// resolution note. Accepting posts the disputed transfer's ledger entries
+// in reverse, denying releases the hold.
+func (s *APIServer) handleDisputeAction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.ResolveDisputeRequest)
+    if err := s.decodeJSON(w, r, req); err != nil && !errors.Is(err, io.EOF) {
+        return err
+    }
+
+    d, err := s.disputeFromPath(r)
+    if err != nil {
+        return err
+    }
+    d.Resolution = req.Resolution
+
+    switch action := r.PathValue("action"); action {
+    case "accept":
+        tx, err := s.store.GetTransaction(r.Context(), d.TransactionID)
+        if err != nil {
+            return err
+        }
+        entries, err := s.store.GetLedgerEntriesByTransaction(r.Context(), tx.ID)
+        if err != nil {
+            return err
+        }
+
+        reversed := make([]*types.LedgerEntry, len(entries))
+        for i, e := range entries {
+            reversed[i] = &types.LedgerEntry{AccountNumber: e.AccountNumber, Amount: -e.Amount}
+        }
+        chargeback := &types.Transaction{
+            Kind: types.TransactionChargeback,
+            FromAccount: tx.ToAccount,
+            ToAccount: tx.FromAccount,
+            Amount: tx.Amount,
+            CreatedAt: time.Now().UTC(),
+        }
+        if err := s.store.AcceptDispute(r.Context(), d, chargeback, reversed); err != nil {
+            return err
+        }
+
+    case "deny":
+        if err := s.store.DenyDispute(r.Context(), d, time.Now().UTC()); err != nil {
+            return err
+        }
+
+    default:
+        return fmt.Errorf("unknown dispute action %q", action)
+    }
+
+    return WriteJSON(w, http.StatusOK, d)
+}
+
+func (s *APIServer) disputeFromPath(r *http.Request) (*types.Dispute, error) {
+    id, err := strconv.Atoi(r.PathValue("disputeID"))
+    if err != nil {
+        return nil, fmt.Errorf("invalid dispute id given %s", r.PathValue("disputeID"))
+    }
+
+    return s.store.GetDispute(r.Context(), id)
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "path"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/documents"
+    "gobank/i18n"
+    "gobank/types"
+)
+
+const maxFilenameLength = 255
+
+// UseDocuments replaces the blob store uploads are kept in and the scanner
+// they go through, by default a disk store under DocumentDir and no
+// scanning.
+func (s *APIServer) UseDocuments(blobs documents.BlobStore, scanner documents.Scanner) {
+    s.blobs = blobs
+    s.scanner = scanner
+}
+
+// handleDocuments lists the account's documents, optionally of one
+// purpose, or uploads one. Uploads send the file as the request body with
+// its Content-Type and name the purpose and filename in the query.
+func (s *APIServer) handleDocuments(w http.ResponseWriter, r *http.Request) error {
+    if s.cfg.DocumentSecret == "" {
+        return fmt.Errorf("documents are not enabled")
+    }
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        docs, err := s.store.GetDocumentsByAccount(r.Context(), account.Number, r.URL.Query().Get("purpose"))
+        if err != nil {
+            return err
+        }
+        for _, d := range docs {
+            s.signDocument(d)
+        }
+
+        return WriteJSON(w, http.StatusOK, docs)
+    }
+
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    purpose := r.URL.Query().Get("purpose")
+    if !types.ValidDocumentPurpose(purpose) {
+        return fmt.Errorf("unknown document purpose %q", purpose)
+    }
+    filename := path.Base(r.URL.Query().Get("filename"))
+    if filename == "." || filename == "/" {
+        filename = ""
+    }
+    if len(filename) > maxFilenameLength {
+        return fmt.Errorf("filename must be at most %d characters", maxFilenameLength)
+    }
+
+    body := http.MaxBytesReader(w, r.Body, s.cfg.DocumentMaxBytes)
+    defer body.Close()
+
+    data, err := io.ReadAll(body)
+    if err != nil {
+        return err
+    }
+    if len(data) == 0 {
+        return fmt.Errorf("document is empty")
+    }
+
+    contentType, err := documents.CheckContentType(r.Header.Get("Content-Type"), data)
+    if err != nil {
+        return err
+    }
+    if err := s.scanner.Scan(r.Context(), contentType, data); err != nil {
+        return err
+    }
+
+    key, err := documents.NewKey(account.Number)
+    if err != nil {
+        return err
+    }
+    if err := s.blobs.Put(r.Context(), key, contentType, data); err != nil {
+        return err
+    }
+
+    sum := sha256.Sum256(data)
+    d := &types.Document{
+        AccountNumber: account.Number,
+        Purpose: purpose,
+        Filename: filename,
+        ContentType: contentType,
+        Size: int64(len(data)),
+        SHA256: hex.EncodeToString(sum[:]),
+        BlobKey: key,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.store.CreateDocument(r.Context(), d); err != nil {
+        if err := s.blobs.Delete(r.Context(), key); err != nil {
+            s.logger.Printf("documents: removing orphaned blob %s: %v", key, err)
+        }
+        return err
+    }
+    s.signDocument(d)
+
+    return WriteJSON(w, http.StatusCreated, d)
+}
+
+func (s *APIServer) handleDocument(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+    if s.cfg.DocumentSecret == "" {
+        return fmt.Errorf("documents are not enabled")
+    }
+
+    d, err := s.documentFromPath(r)
+    if err != nil {
+        return err
+    }
+    if d.AccountNumber != auth.AccountFromContext(r.Context()).Number {
+        return fmt.Errorf("document %d not found", d.ID)
+    }
+    s.signDocument(d)
+
+    return WriteJSON(w, http.StatusOK, d)
+}
+
+func (s *APIServer) handleAdminDocument(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+    if s.cfg.DocumentSecret == "" {
+        return fmt.Errorf("documents are not enabled")
+    }
+
+    d, err := s.documentFromPath(r)
+    if err != nil {
+        return err
+    }
+    s.signDocument(d)
+
+    return WriteJSON(w, http.StatusOK, d)
+}
+
+// handleDownloadDocument serves a document's contents to whoever holds a
+// download link that hasn't expired, without signing in.
+func (s *APIServer) handleDownloadDocument(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+    if s.cfg.DocumentSecret == "" {
+        return fmt.Errorf("documents are not enabled")
+    }
+
+    id, err := strconv.Atoi(r.PathValue("documentID"))
+    if err != nil {
+        return fmt.Errorf("invalid document id given %s", r.PathValue("documentID"))
+    }
+    if err := documents.VerifyURL([]byte(s.cfg.DocumentSecret), id, r.URL.Query(), time.Now()); err != nil {
+        return writeMessage(w, r, http.StatusForbidden, i18n.PermissionDenied)
+    }
+
+    d, err := s.store.GetDocument(r.Context(), id)
+    if err != nil {
+        return err
+    }
+    blob, err := s.blobs.Get(r.Context(), d.BlobKey)
+    if err != nil {
+        return err
+    }
+    defer blob.Close()
+
+    w.Header().Set("Content-Type", d.ContentType)
+    w.Header().Set("Content-Length", strconv.FormatInt(d.Size, 10))
+    w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": d.Filename}))
+    w.Header().Set("X-Content-Type-Options", "nosniff")
+    w.Header().Set("Cache-Control", "private, no-store")
+    _, err = io.Copy(w, blob)
+
+    return err
+}
+
+// signDocument fills in the download link, valid for DocumentURLTTL.
+func (s *APIServer) signDocument(d *types.Document) {
+    d.DownloadURL = s.prefix + documents.DownloadURL([]byte(s.cfg.DocumentSecret), d.ID, time.Now().Add(s.cfg.DocumentURLTTL))
+}
+
+func (s *APIServer) documentFromPath(r *http.Request) (*types.Document, error) {
+    id, err := strconv.Atoi(r.PathValue("documentID"))
+    if err != nil {
+        return nil, fmt.Errorf("invalid document id given %s", r.PathValue("documentID"))
+    }
+
+    return s.store.GetDocument(r.Context(), id)
+}
+
+// checkEvidence makes sure every document backing a dispute is evidence
+// the account uploaded itself.
+func (s *APIServer) checkEvidence(r *http.Request, account *types.Account, ids []int) error {
+    for _, id := range ids {
+        d, err := s.store.GetDocument(r.Context(), id)
+        if err != nil {
+            return err
+        }
+        if d.AccountNumber != account.Number || d.Purpose != types.DocumentDisputeEvidence {
+            return fmt.Errorf("document %d is not dispute evidence of account %d", id, account.Number)
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+
+    "gobank/auth"
+    "gobank/i18n"
+    "gobank/types"
+)
+
+// withActiveAccount turns away money movement from closed and dormant
+// accounts. It runs inside withJWTAuth.
+func withActiveAccount(handlerFunc http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        account := auth.AccountFromContext(r.Context())
+        if account.ClosedAt != nil {
+            writeMessage(w, r, http.StatusForbidden, i18n.AccountClosed)
+            return
+        }
+        if account.DormantSince != nil {
+            writeMessage(w, r, http.StatusForbidden, i18n.AccountDormant)
+            return
+        }
+
+        handlerFunc(w, r)
+    }
+}
+
+// handleReactivate sends the holder of a dormant account a code to confirm
+// at /account/{id}/reactivate/verify.
+func (s *APIServer) handleReactivate(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    if account.DormantSince == nil {
+        return fmt.Errorf("account %d is not dormant", account.Number)
+    }
+
+    id, err := s.startStepUp(r, account, nil)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusAccepted, map[string]string{"challengeId": id})
+}
+
+func (s *APIServer) handleVerifyReactivate(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.ReactivateRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    if err := s.confirmChallenge(r, account, req.ChallengeID, req.Code); err != nil {
+        return err
+    }
+
+    if err := s.store.SetDormancy(r.Context(), account.Number, nil, nil); err != nil {
+        return err
+    }
+    account.DormancyNoticeAt, account.DormantSince = nil, nil
+
+    return WriteJSON(w, http.StatusOK, account)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/clearing"
+    "gobank/notify"
+    "gobank/storage"
+    "gobank/types"
+)
+
+const maxBeneficiaryNameLength = 70
+
+// UseClearing replaces the network transfers to other banks go out on, by
+// default the sandbox.
+func (s *APIServer) UseClearing(network clearing.Network) {
+    s.clearing = network
+}
+
+// handleExternalTransfer sends money to an account at another bank. The
+// money moves into the settlement account and the transfer stays pending
+// until the clearing network settles or returns it.
+func (s *APIServer) handleExternalTransfer(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.ExternalTransferRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+
+    from := auth.AccountFromContext(r.Context())
+    if grant := auth.GrantFromContext(r.Context()); grant != nil && req.Amount > grant.TransferLimit {
+        return fmt.Errorf("amount is above the %d transfer limit of your access", grant.TransferLimit)
+    }
+
+    et, c, err := s.sendExternalTransfer(r, from, req)
+    if err != nil {
+        return err
+    }
+    if c != nil {
+        return WriteJSON(w, http.StatusAccepted, c)
+    }
+    s.roundUp(r.Context(), from, et.Amount)
+
+    return WriteJSON(w, http.StatusAccepted, et)
+}
+
+// sendExternalTransfer checks req, posts it into the settlement account
+// and submits it to the clearing network. A transfer the fraud rules block
+// isn't sent but waits for review, and its case is returned instead.
+func (s *APIServer) sendExternalTransfer(r *http.Request, from *types.Account, req *types.ExternalTransferRequest) (*types.ExternalTransfer, *types.FraudCase, error) {
+    if err := validateExternalTransfer(req); err != nil {
+        return nil, nil, err
+    }
+    if err := s.checkTransferQuota(r.Context(), from.Number, req.Amount); err != nil {
+        return nil, nil, err
+    }
+
+    decision, err := s.checkFraud(r, from, nil, "", req, req.Amount)
+    if err != nil {
+        return nil, nil, err
+    }
+    if decision.Action == types.FraudBlock {
+        c, err := s.recordFraudCase(r.Context(), decision, from.Number, types.SettlementAccountNumber, req.Amount, req, nil)
+        return nil, c, err
+    }
+
+    tx, et, entries, err := s.newExternalTransfer(from, req)
+    if err != nil {
+        return nil, nil, err
+    }
+    if err := s.store.CreateExternalTransfer(r.Context(), et, tx, entries, s.cfg.VelocityLimits); err != nil {
+        return nil, nil, err
+    }
+    s.recordTransferUsage(r.Context(), from.Number, tx.Amount)
+    if decision.Action == types.FraudReview {
+        s.recordFraudCase(r.Context(), decision, from.Number, types.SettlementAccountNumber, tx.Amount, req, tx)
+    }
+
+    if err := s.submitExternalTransfer(r, et); err != nil {
+        return nil, nil, err
+    }
+
+    if et.Amount >= s.cfg.LargeWithdrawalAmount {
+        s.notifier.Publish(notify.Event{
+            Type: notify.LargeWithdrawal,
+            Account: from,
+            Data: map[string]any{"amount": et.Amount, "toAccount": et.ToAccount},
+        })
+    }
+
+    return et, nil, nil
+}
+
+// newExternalTransfer builds the pending transfer of req into the
+// settlement account.
+func (s *APIServer) newExternalTransfer(from *types.Account, req *types.ExternalTransferRequest) (*types.Transaction, *types.ExternalTransfer, []*types.LedgerEntry, error) {
+    reference, err := newClearingReference()
+    if err != nil {
+        return nil, nil, nil, err
+    }
+
+    tx := &types.Transaction{
+        Kind: types.TransactionTransfer,
+        Status: types.StatusPending,
+        FromAccount: from.Number,
+        ToAccount: types.SettlementAccountNumber,
+        Amount: req.Amount,
+        Provider: s.clearing.Name(),
+        Reference: reference,
+        CreatedAt: time.Now().UTC(),
+    }
+    et := &types.ExternalTransfer{
+        RoutingNumber: req.RoutingNumber,
+        ToAccount: req.ToAccount,
+        Name: req.Name,
+    }
+
+    return tx, et, types.NewEntries(from.Number, types.SettlementAccountNumber, req.Amount), nil
+}
+
+// submitExternalTransfer hands a posted transfer to the clearing network,
+// returning it when the network doesn't take it.
+func (s *APIServer) submitExternalTransfer(r *http.Request, et *types.ExternalTransfer) error {
+    if err := s.clearing.Submit(r.Context(), et); err != nil {
+        reason := "not accepted by the clearing network"
+        ret, entries := clearing.Return(et, time.Now().UTC())
+        if rerr := s.store.ReturnExternalTransfer(r.Context(), et.ID, reason, ret, entries); rerr != nil && !errors.Is(rerr, storage.ErrNotPending) {
+            return rerr
+        }
+        return fmt.Errorf("transfer %d %s: %w", et.ID, reason, err)
+    }
+
+    return nil
+}
+
+func (s *APIServer) handleExternalTransfers(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    transfers, err := s.store.GetExternalTransfersByAccount(r.Context(), auth.AccountFromContext(r.Context()).Number)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, transfers)
+}
+
+func (s *APIServer) handleExternalTransferByID(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := strconv.Atoi(r.PathValue("transferID"))
+    if err != nil {
+        return fmt.Errorf("invalid transfer id given %s", r.PathValue("transferID"))
+    }
+
+    et, err := s.store.GetExternalTransfer(r.Context(), id)
+    if err != nil {
+        return err
+    }
+    if et.FromAccount != auth.AccountFromContext(r.Context()).Number {
+        return fmt.Errorf("external transfer %d not found", et.ID)
+    }
+
+    return WriteJSON(w, http.StatusOK, et)
+}
+
+func validateExternalTransfer(req *types.ExternalTransferRequest) error {
+    if req.Amount <= 0 {
+        return fmt.Errorf("amount must be positive")
+    }
+    if !clearing.ValidRoutingNumber(req.RoutingNumber) {
+        return fmt.Errorf("invalid routing number %q", req.RoutingNumber)
+    }
+    if !clearing.ValidAccount(req.ToAccount) {
+        return fmt.Errorf("account number must be 4 to 17 digits")
+    }
+    if req.Name == "" || len(req.Name) > maxBeneficiaryNameLength {
+        return fmt.Errorf("name must be between 1 and %d characters", maxBeneficiaryNameLength)
+    }
+
+    return nil
+}
+
+// newClearingReference returns the reference a transfer is known by on
+// the clearing network.
+func newClearingReference() (string, error) {
+    b := make([]byte, 12)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/fraud"
+    "gobank/notify"
+    "gobank/types"
+)
+
+// checkFraud evaluates a transfer against the fraud rules before it is
+// posted. to is nil for a payee without an account here: an unclaimed
+// alias, always a new payee, or the account at another bank in external.
+func (s *APIServer) checkFraud(r *http.Request, from, to *types.Account, alias string, external *types.ExternalTransferRequest, amount int64) (fraud.Decision, error) {
+    t := fraud.Transfer{
+        From: from,
+        To: to,
+        ToAlias: alias,
+        Amount: amount,
+        IP: clientIP(r),
+        Country: s.clientCountry(r),
+        Now: time.Now().UTC(),
+    }
+    var payee int64
+    if to != nil {
+        payee = to.Number
+    }
+    if external != nil {
+        t.ToAlias = externalPayee(external.RoutingNumber, external.ToAccount)
+    }
+
+    history, err := s.store.GetFraudHistory(r.Context(), from.Number, payee, external, t.Now.Add(-s.cfg.Fraud.AmountWindow))
+    if err != nil {
+        return fraud.Decision{}, err
+    }
+    t.NewPayee = !history.PaidBefore
+    t.LastActivity = history.LastActivity
+    t.RecentAmount = history.SentSince
+
+    claims := auth.ClaimsFromContext(r.Context())
+    t.LoginIP, t.LoginCountry = claims.IP, claims.Country
+
+    return fraud.Evaluate(s.cfg.Fraud, t), nil
+}
+
+// externalPayee names an account at another bank for the fraud rules.
+func externalPayee(routingNumber, account string) string {
+    return routingNumber + "/" + account
+}
+
+// recordFraudCase saves the case of a flagged transfer, external saying
+// where it goes when it leaves the bank. Reviewed transfers were posted
+// already, so failing to record them is only logged.
+func (s *APIServer) recordFraudCase(ctx context.Context, d fraud.Decision, from, to int64, amount int64, external *types.ExternalTransferRequest, tx *types.Transaction) (*types.FraudCase, error) {
+    c := &types.FraudCase{
+        FromAccount: from,
+        ToAccount: to,
+        External: external,
+        Amount: amount,
+        Decision: d.Action,
+        Reasons: d.Reasons,
+        Status: types.FraudCasePending,
+        CreatedAt: time.Now().UTC(),
+    }
+    if tx != nil {
+        c.Status = types.FraudCasePosted
+        c.TransactionID = &tx.ID
+    }
+
+    if err := s.store.CreateFraudCase(ctx, c); err != nil {
+        if tx != nil {
+            s.logger.Printf("recording fraud review of transaction %d failed: %v", tx.ID, err)
+        }
+        return nil, err
+    }
+
+    return c, nil
+}
+
+func (s *APIServer) handleFraudCases(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    status := r.URL.Query().Get("status")
+    if status == "" {
+        status = types.FraudCasePending
+    }
+
+    cases, err := s.store.GetFraudCasesByStatus(r.Context(), status)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, cases)
+}
+
+// handleFraudCaseAction approves or rejects a blocked transfer. Approving
+// posts it as it would have been, converted at today's rate.
+func (s *APIServer) handleFraudCaseAction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := strconv.Atoi(r.PathValue("caseID"))
+    if err != nil {
+        return fmt.Errorf("invalid case id given %s", r.PathValue("caseID"))
+    }
+    c, err := s.store.GetFraudCase(r.Context(), id)
+    if err != nil {
+        return err
+    }
+
+    switch action := r.PathValue("action"); action {
+    case "approve":
+        from, err := s.store.GetAccountByNumber(r.Context(), c.FromAccount)
+        if err != nil {
+            return err
+        }
+        if c.External != nil {
+            tx, et, entries, err := s.newExternalTransfer(from, c.External)
+            if err != nil {
+                return err
+            }
+            if err := s.store.ApproveFraudCase(r.Context(), c, tx, entries, et); err != nil {
+                return err
+            }
+            if err := s.submitExternalTransfer(r, et); err != nil {
+                return err
+            }
+
+            return WriteJSON(w, http.StatusOK, c)
+        }
+        to, err := s.store.GetAccountByNumber(r.Context(), c.ToAccount)
+        if err != nil {
+            return err
+        }
+
+        entries, converted, err := s.transferEntries(from, to, c.Amount)
+        if err != nil {
+            return err
+        }
+        fee, err := s.transferFee(r.Context(), from.Number, time.Now().UTC())
+        if err != nil {
+            return err
+        }
+        entries = append(entries, fee...)
+        tx := &types.Transaction{
+            Kind: types.TransactionTransfer,
+            FromAccount: from.Number,
+            ToAccount: to.Number,
+            Amount: c.Amount,
+            CreatedAt: time.Now().UTC(),
+        }
+        if err := s.store.ApproveFraudCase(r.Context(), c, tx, entries, nil); err != nil {
+            return err
+        }
+
+        s.notifier.Publish(notify.Event{
+            Type: notify.TransferConfirmation,
+            Account: from,
+            Data: map[string]any{"amount": tx.Amount, "toAccount": tx.ToAccount},
+        })
+        s.checkAlerts(r.Context(), tx, to, converted)
+
+        return WriteJSON(w, http.StatusOK, c)
+
+    case "reject":
+        if err := s.store.RejectFraudCase(r.Context(), c, time.Now().UTC()); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, c)
+
+    default:
+        return fmt.Errorf("unknown fraud case action %q", action)
+    }
+}
+
+func clientIP(r *http.Request) string {
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
+
+func (s *APIServer) clientCountry(r *http.Request) string {
+    if s.cfg.FraudCountryHeader == "" {
+        return ""
+    }
+    return r.Header.Get(s.cfg.FraudCountryHeader)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "slices"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/storage"
+    "gobank/types"
+)
+
+var errPermissionDenied = errors.New("permission denied")
+
+type scopeCtxKey struct{}
+
+// withScope marks a route that isn't a plain read as usable through a
+// grant with scope. Grant holders can only GET routes without it. It must
+// wrap withJWTAuth.
+func withScope(scope string) Middleware {
+    return func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+            ctx := context.WithValue(r.Context(), scopeCtxKey{}, scope)
+            handlerFunc(w, r.WithContext(ctx))
+        }
+    }
+}
+
+// delegatedAccess decides if actor's holder may make r on an account they
+// don't hold, as one of its owners or through a grant. It returns the
+// grant used, if any, and errPermissionDenied when neither allows r.
+func delegatedAccess(r *http.Request, s storage.Storage, account *types.Account, actor int64) (*types.Grant, error) {
+    owner, err := s.GetAccountOwner(r.Context(), account.Number, actor)
+    if err == nil {
+        // view only owners can look but not change anything
+        if owner.Permission != types.PermissionFull && r.Method != "GET" {
+            return nil, errPermissionDenied
+        }
+        return nil, nil
+    }
+    if !errors.Is(err, storage.ErrNotFound) {
+        return nil, err
+    }
+
+    grant, err := s.GetActiveGrant(r.Context(), account.Number, actor)
+    if errors.Is(err, storage.ErrNotFound) {
+        return nil, errPermissionDenied
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    scope, _ := r.Context().Value(scopeCtxKey{}).(string)
+    if scope == "" && r.Method == "GET" {
+        scope = types.ScopeRead
+    }
+    if scope == "" || !grant.Allows(scope) {
+        return nil, errPermissionDenied
+    }
+
+    return grant, nil
+}
+
+func (s *APIServer) handleGrants(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        grants, err := s.store.GetGrantsByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, grants)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.CreateGrantRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+
+        if len(req.Scopes) == 0 {
+            return fmt.Errorf("a grant needs at least one scope")
+        }
+        for _, scope := range req.Scopes {
+            if scope != types.ScopeRead && scope != types.ScopeTransfer {
+                return fmt.Errorf("unknown scope %s", scope)
+            }
+        }
+        if slices.Contains(req.Scopes, types.ScopeTransfer) && req.TransferLimit <= 0 {
+            return fmt.Errorf("the transfer scope needs a positive transferLimit")
+        }
+        if req.GranteeNumber == account.Number {
+            return fmt.Errorf("can't grant access to the account's own holder")
+        }
+
+        grantee, err := s.store.GetAccountByNumber(r.Context(), req.GranteeNumber)
+        if err != nil {
+            return err
+        }
+
+        scopes := slices.Clone(req.Scopes)
+        slices.Sort(scopes)
+        grant := &types.Grant{
+            AccountNumber: account.Number,
+            GranteeNumber: grantee.Number,
+            Scopes: slices.Compact(scopes),
+            TransferLimit: req.TransferLimit,
+            CreatedAt: time.Now().UTC(),
+        }
+        if err := s.store.CreateGrant(r.Context(), grant); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, grant)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *APIServer) handleRevokeGrant(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "DELETE" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := strconv.Atoi(r.PathValue("grantID"))
+    if err != nil {
+        return fmt.Errorf("invalid grant id given %s", r.PathValue("grantID"))
+    }
+
+    grant, err := s.store.GetGrant(r.Context(), id)
+    if err != nil {
+        return err
+    }
+    if grant.AccountNumber != auth.AccountFromContext(r.Context()).Number {
+        return fmt.Errorf("grant %d %w", id, storage.ErrNotFound)
+    }
+
+    if err := s.store.RevokeGrant(r.Context(), id, time.Now().UTC()); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, map[string]int{"revoked": id})
+}
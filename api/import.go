@@ -0,0 +1,74 @@
+package api
+
+import (
+    "errors"
+    "fmt"
+    "mime"
+    "net/http"
+    "time"
+
+    "gobank/i18n"
+    "gobank/importer"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// handleImportAccounts migrates accounts from a CSV (text/csv) or JSON
+// body. Invalid rows are reported and skipped; the response carries the
+// activation tokens of accounts imported without a password.
+func (s *APIServer) handleImportAccounts(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    format := "json"
+    if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+        format = "csv"
+    }
+
+    body := http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes)
+    defer body.Close()
+
+    rows, err := importer.Parse(body, format)
+    if err != nil {
+        return err
+    }
+
+    res, err := importer.Import(r.Context(), s.store, s.cfg.FXRates, rows, s.cfg.ImportBatchSize, s.cfg.ActivationTTL)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, res)
+}
+
+// handleActivateAccount lets the holder of an imported account set its
+// password with the activation token they were given.
+func (s *APIServer) handleActivateAccount(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.ActivateAccountRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    if req.Password == "" {
+        return fmt.Errorf("password is required")
+    }
+
+    encpw, err := types.HashPassword(req.Password)
+    if err != nil {
+        return err
+    }
+
+    err = s.store.ActivateAccount(r.Context(), req.Number, importer.HashToken(req.Token), encpw, time.Now().UTC())
+    if errors.Is(err, storage.ErrNotFound) {
+        return writeMessage(w, r, http.StatusForbidden, i18n.InvalidCredentials)
+    }
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, map[string]string{"status": "account activated"})
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/mail"
+    "time"
+
+    "gobank/auth"
+    "gobank/i18n"
+    "gobank/importer"
+    "gobank/notify"
+    "gobank/numbering"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// handleInvitations lets a customer invite someone to open an account,
+// optionally to become an owner of theirs as well, and lists whom they
+// invited.
+func (s *APIServer) handleInvitations(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+    return s.invitations(w, r, account)
+}
+
+// withSharingChecks holds invitations that would make the invitee an
+// owner to the same checks as sharing the account directly: a session that
+// passed step-up and, for holders with signing keys, a signed request.
+func (s *APIServer) withSharingChecks(handlerFunc http.HandlerFunc) http.HandlerFunc {
+    checked := withStepUp(s.withSignature(handlerFunc))
+    return func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != "POST" {
+            handlerFunc(w, r)
+            return
+        }
+
+        body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+        if err != nil {
+            writeError(w, r, err)
+            return
+        }
+        r.Body = io.NopCloser(bytes.NewReader(body))
+
+        req := new(types.CreateInvitationRequest)
+        if err := json.Unmarshal(body, req); err == nil && req.Permission == "" {
+            handlerFunc(w, r)
+            return
+        }
+        checked(w, r)
+    }
+}
+
+// handleAdminInvitations is handleInvitations for admins, whose
+// invitations can't make anyone an owner.
+func (s *APIServer) handleAdminInvitations(w http.ResponseWriter, r *http.Request) error {
+    return s.invitations(w, r, nil)
+}
+
+// invitations lists and sends the invitations of inviter, nil for admins.
+func (s *APIServer) invitations(w http.ResponseWriter, r *http.Request, inviter *types.Account) error {
+    var invitedBy int64
+    if inviter != nil {
+        invitedBy = inviter.Number
+    }
+
+    if r.Method == "GET" {
+        invitations, err := s.store.GetAccountInvitations(r.Context(), invitedBy)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, invitations)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.CreateInvitationRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+
+        if _, err := mail.ParseAddress(req.Email); err != nil {
+            return fmt.Errorf("invalid email address")
+        }
+        if req.Permission != "" {
+            if inviter == nil {
+                return fmt.Errorf("admin invitations can't share an account")
+            }
+            if req.Permission != types.PermissionView && req.Permission != types.PermissionFull {
+                return fmt.Errorf("permission must be %s or %s", types.PermissionView, types.PermissionFull)
+            }
+        }
+
+        token, err := importer.NewToken()
+        if err != nil {
+            return err
+        }
+        now := time.Now().UTC()
+        inv := &types.AccountInvitation{
+            Email: req.Email,
+            InvitedBy: invitedBy,
+            Permission: req.Permission,
+            Status: types.InvitationPending,
+            TokenHash: importer.HashToken(token),
+            ExpiresAt: now.Add(s.cfg.InvitationTTL),
+            CreatedAt: now,
+        }
+        if err := s.store.CreateAccountInvitation(r.Context(), inv); err != nil {
+            return err
+        }
+
+        // the invitee has no account yet, so write in the inviter's locale
+        name, locale := "gobank", i18n.Match(r.Header.Get("Accept-Language"))
+        if inviter != nil {
+            name, locale = inviter.FirstName+" "+inviter.LastName, inviter.Locale
+        }
+        s.notifier.Publish(notify.Event{
+            Type: notify.AccountInvitation,
+            Account: &types.Account{Locale: locale},
+            Email: inv.Email,
+            Data: map[string]any{
+                "inviter": name,
+                "permission": inv.Permission,
+                "token": token,
+                "expiresAt": inv.ExpiresAt,
+            },
+        })
+
+        return WriteJSON(w, http.StatusOK, inv)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// handleOnboarding opens the account an invitation was sent for, with the
+// token from the invitation email.
+func (s *APIServer) handleOnboarding(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.OnboardRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    if req.FirstName == "" || req.LastName == "" {
+        return fmt.Errorf("first and last name are required")
+    }
+    if req.Password == "" {
+        return fmt.Errorf("password is required")
+    }
+    if req.Currency != "" && !s.cfg.FXRates.Supports(req.Currency) {
+        return fmt.Errorf("unsupported currency %s", req.Currency)
+    }
+    if req.Locale != "" && !i18n.Supported(req.Locale) {
+        return fmt.Errorf("unsupported locale %s", req.Locale)
+    }
+
+    account, err := types.NewAccount(req.FirstName, req.LastName, req.Password)
+    if err != nil {
+        return err
+    }
+    account.Locale = req.Locale
+    if account.Locale == "" {
+        account.Locale = i18n.Match(r.Header.Get("Accept-Language"))
+    }
+    if req.Currency != "" {
+        account.Currency = req.Currency
+    }
+
+    tokenHash, now := importer.HashToken(req.Token), time.Now().UTC()
+    var inv *types.AccountInvitation
+    err = numbering.New(s.store).CreateWith(r.Context(), account, func(ctx context.Context, acc *types.Account) error {
+        inv, err = s.store.AcceptAccountInvitation(ctx, tokenHash, acc, now)
+        return err
+    })
+    if errors.Is(err, storage.ErrNotFound) {
+        return writeMessage(w, r, http.StatusForbidden, i18n.InvalidCredentials)
+    }
+    if err != nil {
+        return err
+    }
+
+    s.notifier.Publish(notify.Event{Type: notify.AccountCreated, Account: account})
+    if inv.Permission != "" {
+        s.logger.Printf("account %d joined account %d as %s owner through invitation %d", account.Number, inv.InvitedBy, inv.Permission, inv.ID)
+    }
+
+    return WriteJSON(w, http.StatusOK, account)
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/loans"
+    "gobank/products"
+    "gobank/storage"
+    "gobank/types"
+)
+
+const maxLoanTermMonths = 360
+
+func (s *APIServer) handleLoans(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        ls, err := s.store.GetLoansByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, ls)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.LoanApplication)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+
+        if req.Amount <= 0 || req.Amount > s.cfg.LoanMaxAmount {
+            return fmt.Errorf("amount must be between 1 and %d", s.cfg.LoanMaxAmount)
+        }
+        if req.TermMonths < 1 || req.TermMonths > maxLoanTermMonths {
+            return fmt.Errorf("termMonths must be between 1 and %d", maxLoanTermMonths)
+        }
+
+        now := time.Now().UTC()
+        rate, err := products.Lookup(r.Context(), s.store, types.ProductLoanRateBPS, now, int64(s.cfg.LoanRateBPS))
+        if err != nil {
+            return err
+        }
+
+        loan := &types.Loan{
+            AccountNumber: account.Number,
+            Principal: req.Amount,
+            TermMonths: req.TermMonths,
+            RateBPS: int(rate),
+            Status: types.LoanApplied,
+            CreatedAt: now,
+        }
+        if err := s.store.CreateLoan(r.Context(), loan); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, loan)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// handleLoan shows a loan with its repayment schedule. Applications get a
+// preview of the schedule they would have if approved today.
+func (s *APIServer) handleLoan(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    loan, err := s.loanFromPath(r)
+    if err != nil {
+        return err
+    }
+    account := auth.AccountFromContext(r.Context())
+    if loan.AccountNumber != account.Number {
+        return fmt.Errorf("loan %d %w", loan.ID, storage.ErrNotFound)
+    }
+
+    schedule, err := s.store.GetLoanSchedule(r.Context(), loan.ID)
+    if err != nil {
+        return err
+    }
+    if loan.Status == types.LoanApplied {
+        schedule = loans.Schedule(loan.ID, loan.Principal, loan.TermMonths, loan.RateBPS, time.Now().UTC())
+    }
+
+    return WriteJSON(w, http.StatusOK, types.LoanDetails{Loan: loan, Schedule: schedule})
+}
+
+func (s *APIServer) handleAdminLoans(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    status := r.URL.Query().Get("status")
+    if status == "" {
+        status = types.LoanApplied
+    }
+
+    ls, err := s.store.GetLoansByStatus(r.Context(), status)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, ls)
+}
+
+// handleAdminLoanAction approves or rejects a loan application. Approving
+// disburses the principal into the account and starts the schedule.
+func (s *APIServer) handleAdminLoanAction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    loan, err := s.loanFromPath(r)
+    if err != nil {
+        return err
+    }
+
+    switch action := r.PathValue("action"); action {
+    case "approve":
+        now := time.Now().UTC()
+        tx := &types.Transaction{
+            Kind: types.TransactionLoanDisbursement,
+            FromAccount: types.LoanAccountNumber,
+            ToAccount: loan.AccountNumber,
+            Amount: loan.Principal,
+            CreatedAt: now,
+        }
+        entries := types.NewEntries(types.LoanAccountNumber, loan.AccountNumber, loan.Principal)
+        schedule := loans.Schedule(loan.ID, loan.Principal, loan.TermMonths, loan.RateBPS, now)
+
+        if err := s.store.DisburseLoan(r.Context(), loan, schedule, tx, entries); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, types.LoanDetails{Loan: loan, Schedule: schedule})
+    case "reject":
+        if err := s.store.RejectLoan(r.Context(), loan.ID); err != nil {
+            return err
+        }
+        loan.Status = types.LoanRejected
+
+        return WriteJSON(w, http.StatusOK, loan)
+    default:
+        return fmt.Errorf("unknown loan action %s", action)
+    }
+}
+
+func (s *APIServer) loanFromPath(r *http.Request) (*types.Loan, error) {
+    id, err := strconv.Atoi(r.PathValue("loanID"))
+    if err != nil {
+        return nil, fmt.Errorf("invalid loan id given %s", r.PathValue("loanID"))
+    }
+
+    return s.store.GetLoan(r.Context(), id)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "gobank/i18n"
+    "gobank/types"
+)
+
+// maintenanceExempt are the routes that take a POST without changing
+// anything, and the switch itself, so they stay up in maintenance.
+var maintenanceExempt = map[string]bool{
+    "/transfer/preview": true,
+    "/qr/decode": true,
+    "/admin/maintenance": true,
+}
+
+// maintenance is the read-only switch of one server. It isn't shared
+// between instances.
+type maintenance struct {
+    mu sync.Mutex
+    state types.Maintenance
+}
+
+func (m *maintenance) get() types.Maintenance {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    return m.state
+}
+
+func (m *maintenance) set(enabled bool) types.Maintenance {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    if enabled == m.state.Enabled {
+        return m.state
+    }
+    m.state = types.Maintenance{Enabled: enabled}
+    if enabled {
+        now := time.Now().UTC()
+        m.state.Since = &now
+    }
+
+    return m.state
+}
+
+// withMaintenance answers requests that could change anything with a 503
+// while the server is in maintenance. It runs inside the mux, so the
+// route's pattern is known.
+func (s *APIServer) withMaintenance(handlerFunc http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        switch r.Method {
+        case "GET", "HEAD", "OPTIONS":
+            handlerFunc(w, r)
+            return
+        }
+        if !s.maintenance.get().Enabled || maintenanceExempt[strings.TrimPrefix(r.Pattern, s.prefix)] {
+            handlerFunc(w, r)
+            return
+        }
+
+        w.Header().Set("Retry-After", "60")
+        writeMessage(w, r, http.StatusServiceUnavailable, i18n.Maintenance)
+    }
+}
+
+// handleMaintenance shows the maintenance mode and turns it on or off.
+func (s *APIServer) handleMaintenance(w http.ResponseWriter, r *http.Request) error {
+    if r.Method == "GET" {
+        return WriteJSON(w, http.StatusOK, s.maintenance.get())
+    }
+
+    if r.Method == "PUT" {
+        req := new(types.Maintenance)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+
+        state := s.maintenance.set(req.Enabled)
+        s.logger.Printf("maintenance mode enabled=%t", state.Enabled)
+
+        return WriteJSON(w, http.StatusOK, state)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// handleHealthz tells load balancers the server is up, and whether it only
+// serves reads.
+func (s *APIServer) handleHealthz(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    return WriteJSON(w, http.StatusOK, types.HealthResponse{Status: "ok", Maintenance: s.maintenance.get()})
+}
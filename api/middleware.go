@@ -0,0 +1,86 @@
+package api
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "log"
+    "net/http"
+    "slices"
+    "time"
+)
+
+// Middleware wraps a handler with behaviour shared by many routes. In a
+// list of middleware the first one runs outermost.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Use adds middleware that runs on every route, inside the built-in
+// request ID, logging and compression but before any route's own
+// middleware. It must be called before Serve or Handler.
+func (s *APIServer) Use(mw ...Middleware) {
+    s.middleware = append(s.middleware, mw...)
+}
+
+func chain(handlerFunc http.HandlerFunc, mw ...Middleware) http.HandlerFunc {
+    for i := len(mw) - 1; i >= 0; i-- {
+        handlerFunc = mw[i](handlerFunc)
+    }
+    return handlerFunc
+}
+
+// routes registers handlers on mux behind a group's middleware.
+type routes struct {
+    mux *http.ServeMux
+    // prefix goes in front of every pattern
+    prefix string
+    middleware []Middleware
+}
+
+// group returns routes that run mw after the middleware g already runs.
+func (g routes) group(mw ...Middleware) routes {
+    return routes{mux: g.mux, prefix: g.prefix, middleware: slices.Concat(g.middleware, mw)}
+}
+
+// handle registers handlerFunc for pattern behind the group's middleware
+// and then mw.
+func (g routes) handle(pattern string, handlerFunc http.HandlerFunc, mw ...Middleware) {
+    g.mux.HandleFunc(g.prefix+pattern, chain(handlerFunc, slices.Concat(g.middleware, mw)...))
+}
+
+const requestIDHeader = "X-Request-ID"
+
+type requestIDCtxKey struct{}
+
+// withRequestID tags the request with the caller's X-Request-ID, or a new
+// one, and echoes it in the response.
+func withRequestID(handlerFunc http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get(requestIDHeader)
+        if id == "" || len(id) > 128 {
+            b := make([]byte, 8)
+            rand.Read(b)
+            id = hex.EncodeToString(b)
+        }
+        w.Header().Set(requestIDHeader, id)
+
+        handlerFunc(w, r.WithContext(context.WithValue(r.Context(), requestIDCtxKey{}, id)))
+    }
+}
+
+func requestIDFromContext(ctx context.Context) string {
+    id, _ := ctx.Value(requestIDCtxKey{}).(string)
+    return id
+}
+
+// withLogging logs every request to logger once it is answered.
+func withLogging(logger *log.Logger) Middleware {
+    return func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+            start := time.Now()
+            rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+            handlerFunc(rec, r)
+
+            logger.Printf("%s %s %d %s request=%s", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond), requestIDFromContext(r.Context()))
+        }
+    }
+}
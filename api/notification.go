@@ -0,0 +1,185 @@
+package api
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "fmt"
+    "math/big"
+    "net/http"
+    "regexp"
+    "time"
+
+    "gobank/auth"
+    "gobank/i18n"
+    "gobank/notify"
+    "gobank/types"
+)
+
+const (
+    phoneCodeTTL = 10 * time.Minute
+    phoneCodeMaxAttempts = 5
+)
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// handleNotificationPreferences reads and replaces everything the holder
+// can choose about how the bank contacts them, including the language.
+// Fields left out of a PUT keep their current value, except events, whose
+// overrides are always replaced.
+func (s *APIServer) handleNotificationPreferences(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method != "GET" && r.Method != "PUT" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    notifications, err := s.store.GetNotificationPreferences(r.Context(), account.Number)
+    if err != nil {
+        return err
+    }
+    prefs := &types.Preferences{NotificationPreferences: *notifications, Locale: account.Locale}
+
+    if r.Method == "GET" {
+        return WriteJSON(w, http.StatusOK, prefs)
+    }
+
+    prefs.Events = nil
+    if err := s.decodeJSON(w, r, prefs); err != nil {
+        return err
+    }
+    prefs.AccountNumber = account.Number
+
+    if err := validatePreferences(account, &prefs.NotificationPreferences); err != nil {
+        return err
+    }
+    if prefs.Locale != "" && !i18n.Supported(prefs.Locale) {
+        return fmt.Errorf("unsupported locale %s", prefs.Locale)
+    }
+
+    if err := s.store.SavePreferences(r.Context(), prefs); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, prefs)
+}
+
+func validatePreferences(account *types.Account, prefs *types.NotificationPreferences) error {
+    if prefs.StatementDelivery != types.StatementByEmail && prefs.StatementDelivery != types.StatementOnline {
+        return fmt.Errorf("statement delivery must be %s or %s", types.StatementByEmail, types.StatementOnline)
+    }
+
+    sms := prefs.SMS
+    for event, channels := range prefs.Events {
+        if !notify.Known(notify.EventType(event)) {
+            return fmt.Errorf("unknown event %s", event)
+        }
+        if notify.Security(notify.EventType(event)) && !channels.Email && !channels.SMS {
+            return fmt.Errorf("%s notifications can't be switched off", event)
+        }
+        sms = sms || channels.SMS
+    }
+    if sms && !account.PhoneVerified {
+        return fmt.Errorf("verify a phone number before enabling sms notifications")
+    }
+
+    return nil
+}
+
+// handlePhone starts a phone number change by texting a code to the new
+// number. The number is only saved once the code is confirmed.
+func (s *APIServer) handlePhone(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.PhoneRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    if !e164.MatchString(req.Phone) {
+        return fmt.Errorf("phone must be in E.164 format, e.g. +14155550100")
+    }
+
+    code, err := newPhoneCode()
+    if err != nil {
+        return err
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    v := &types.PhoneVerification{
+        AccountNumber: account.Number,
+        Phone: req.Phone,
+        CodeHash: hashPhoneCode(code),
+        ExpiresAt: time.Now().UTC().Add(phoneCodeTTL),
+    }
+    if err := s.store.SavePhoneVerification(r.Context(), v); err != nil {
+        return err
+    }
+
+    s.notifier.Publish(notify.Event{
+        Type: notify.PhoneVerification,
+        Account: account,
+        Phone: req.Phone,
+        Data: map[string]any{"code": code},
+    })
+
+    return WriteJSON(w, http.StatusAccepted, map[string]string{"status": "verification code sent"})
+}
+
+func (s *APIServer) handleVerifyPhone(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    req := new(types.VerifyPhoneRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    v, err := s.store.GetPhoneVerification(r.Context(), account.Number)
+    if err != nil {
+        return fmt.Errorf("no phone verification in progress")
+    }
+
+    if time.Now().After(v.ExpiresAt) || v.Attempts >= phoneCodeMaxAttempts {
+        if err := s.store.DeletePhoneVerification(r.Context(), account.Number); err != nil {
+            return err
+        }
+        return fmt.Errorf("verification code expired, request a new one")
+    }
+
+    if subtle.ConstantTimeCompare([]byte(hashPhoneCode(req.Code)), []byte(v.CodeHash)) != 1 {
+        v.Attempts++
+        if err := s.store.SavePhoneVerification(r.Context(), v); err != nil {
+            return err
+        }
+        return fmt.Errorf("invalid verification code")
+    }
+
+    account.Phone = v.Phone
+    account.PhoneVerified = true
+    if err := s.store.UpdateAccount(r.Context(), account); err != nil {
+        return err
+    }
+    if err := s.store.DeletePhoneVerification(r.Context(), account.Number); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, account)
+}
+
+func newPhoneCode() (string, error) {
+    n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashPhoneCode(code string) string {
+    sum := sha256.Sum256([]byte(code))
+    return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/i18n"
+    "gobank/notify"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// withPrimaryOwner only lets the account's own holder through, not the
+// other owners of a joint account. It must run inside withJWTAuth.
+func withPrimaryOwner(handlerFunc http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if !isPrimaryOwner(r) {
+            writeMessage(w, r, http.StatusForbidden, i18n.PermissionDenied)
+            return
+        }
+
+        handlerFunc(w, r)
+    }
+}
+
+func isPrimaryOwner(r *http.Request) bool {
+    return auth.ActorFromContext(r.Context()) == auth.AccountFromContext(r.Context()).Number
+}
+
+func (s *APIServer) handleOwners(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        owners, err := s.store.GetAccountOwners(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, owners)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.InviteOwnerRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+
+        if req.Permission != types.PermissionView && req.Permission != types.PermissionFull {
+            return fmt.Errorf("permission must be %s or %s", types.PermissionView, types.PermissionFull)
+        }
+        if req.OwnerNumber == account.Number {
+            return fmt.Errorf("can't invite the account's own holder")
+        }
+
+        invitee, err := s.store.GetAccountByNumber(r.Context(), req.OwnerNumber)
+        if err != nil {
+            return err
+        }
+
+        inv := &types.OwnerInvitation{
+            AccountNumber: account.Number,
+            InviteeNumber: invitee.Number,
+            Permission: req.Permission,
+            Status: types.InvitationPending,
+            CreatedAt: time.Now().UTC(),
+        }
+        if err := s.store.CreateOwnerInvitation(r.Context(), inv); err != nil {
+            return err
+        }
+
+        s.notifier.Publish(notify.Event{
+            Type: notify.OwnerInvited,
+            Account: invitee,
+            Data: map[string]any{
+                "id": inv.ID,
+                "inviter": account.FirstName + " " + account.LastName,
+                "account": account.Number,
+                "permission": inv.Permission,
+            },
+        })
+
+        return WriteJSON(w, http.StatusOK, inv)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *APIServer) handleDeleteOwner(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "DELETE" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    number, err := strconv.ParseInt(r.PathValue("ownerNumber"), 10, 64)
+    if err != nil {
+        return fmt.Errorf("invalid owner number given %s", r.PathValue("ownerNumber"))
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    if err := s.store.DeleteAccountOwner(r.Context(), account.Number, number); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, map[string]int64{"deleted": number})
+}
+
+// handleOwnerInvitations lists the invitations to own other accounts that
+// this account's holder has received.
+func (s *APIServer) handleOwnerInvitations(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    invitations, err := s.store.GetOwnerInvitationsFor(r.Context(), account.Number)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, invitations)
+}
+
+func (s *APIServer) handleOwnerInvitationAction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := strconv.Atoi(r.PathValue("invitationID"))
+    if err != nil {
+        return fmt.Errorf("invalid invitation id given %s", r.PathValue("invitationID"))
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    inv, err := s.store.GetOwnerInvitation(r.Context(), id)
+    if err != nil {
+        return err
+    }
+    if inv.InviteeNumber != account.Number {
+        return fmt.Errorf("invitation %d %w", id, storage.ErrNotFound)
+    }
+
+    switch action := r.PathValue("action"); action {
+    case "accept":
+        owner, err := s.store.AcceptOwnerInvitation(r.Context(), id)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, owner)
+    case "decline":
+        if err := s.store.DeclineOwnerInvitation(r.Context(), id); err != nil {
+            return err
+        }
+        inv.Status = types.InvitationDeclined
+
+        return WriteJSON(w, http.StatusOK, inv)
+    default:
+        return fmt.Errorf("unknown invitation action %s", action)
+    }
+}
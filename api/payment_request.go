@@ -0,0 +1,231 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/i18n"
+    "gobank/notify"
+    "gobank/storage"
+    "gobank/types"
+)
+
+const (
+    paymentRequestTTL = 7 * 24 * time.Hour
+    maxMemoLength = 280
+)
+
+func (s *APIServer) handlePaymentRequests(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        prs, err := s.store.GetPaymentRequestsByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        status := r.URL.Query().Get("status")
+        filtered := []*types.PaymentRequest{}
+        for _, pr := range prs {
+            expire(pr)
+            if status == "" || pr.Status == status {
+                filtered = append(filtered, pr)
+            }
+        }
+
+        return WriteJSON(w, http.StatusOK, filtered)
+    }
+
+    if r.Method == "POST" {
+        return s.handleCreatePaymentRequest(w, r, account)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func (s *APIServer) handleCreatePaymentRequest(w http.ResponseWriter, r *http.Request, requester *types.Account) error {
+    req := new(types.CreatePaymentRequestRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+
+    if req.Amount <= 0 {
+        return fmt.Errorf("amount must be positive")
+    }
+    if len(req.Memo) > maxMemoLength {
+        return fmt.Errorf("memo must be at most %d characters", maxMemoLength)
+    }
+    if req.PayerAccount == requester.Number {
+        return fmt.Errorf("can't request money from the same account")
+    }
+
+    now := time.Now().UTC()
+    expiresAt := now.Add(paymentRequestTTL)
+    if req.ExpiresAt != nil {
+        if !req.ExpiresAt.After(now) {
+            return fmt.Errorf("expiresAt must be in the future")
+        }
+        expiresAt = req.ExpiresAt.UTC()
+    }
+
+    payer, err := s.store.GetAccountByNumber(r.Context(), req.PayerAccount)
+    if err != nil {
+        return err
+    }
+
+    pr := &types.PaymentRequest{
+        RequesterAccount: requester.Number,
+        PayerAccount: payer.Number,
+        Amount: req.Amount,
+        Memo: req.Memo,
+        Status: types.RequestPending,
+        ExpiresAt: expiresAt,
+        CreatedAt: now,
+    }
+    if err := s.store.CreatePaymentRequest(r.Context(), pr); err != nil {
+        return err
+    }
+
+    s.notifier.Publish(notify.Event{
+        Type: notify.PaymentRequested,
+        Account: payer,
+        Data: map[string]any{
+            "requester": requester.FirstName + " " + requester.LastName,
+            "amount": pr.Amount,
+            "currency": requester.Currency,
+            "memo": pr.Memo,
+            "id": pr.ID,
+        },
+    })
+
+    return WriteJSON(w, http.StatusOK, pr)
+}
+
+// handlePaymentRequestAction lets the payer accept or decline a request and
+// the requester cancel it.
+func (s *APIServer) handlePaymentRequestAction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := strconv.Atoi(r.PathValue("requestID"))
+    if err != nil {
+        return fmt.Errorf("invalid payment request id given %s", r.PathValue("requestID"))
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    pr, err := s.store.GetPaymentRequest(r.Context(), id)
+    if err != nil {
+        return err
+    }
+    if pr.RequesterAccount != account.Number && pr.PayerAccount != account.Number {
+        return fmt.Errorf("payment request %d %w", id, storage.ErrNotFound)
+    }
+
+    expire(pr)
+    if pr.Status != types.RequestPending {
+        return fmt.Errorf("payment request %d is %s: %w", id, pr.Status, storage.ErrRequestClosed)
+    }
+
+    switch action := r.PathValue("action"); {
+    case action == "accept" && account.Number == pr.PayerAccount:
+        return s.acceptPaymentRequest(w, r, account, pr)
+    case action == "decline" && account.Number == pr.PayerAccount:
+        return s.closePaymentRequest(w, r, pr, types.RequestDeclined, pr.RequesterAccount, notify.PaymentRequestDeclined)
+    case action == "cancel" && account.Number == pr.RequesterAccount:
+        return s.closePaymentRequest(w, r, pr, types.RequestCancelled, pr.PayerAccount, notify.PaymentRequestCancelled)
+    default:
+        return fmt.Errorf("can't %s this payment request", action)
+    }
+}
+
+// acceptPaymentRequest pays the request like any other transfer. Its
+// amount is in the requester's currency, so a payer in another currency is
+// charged the converted amount.
+func (s *APIServer) acceptPaymentRequest(w http.ResponseWriter, r *http.Request, payer *types.Account, pr *types.PaymentRequest) error {
+    requester, err := s.store.GetAccountByNumber(r.Context(), pr.RequesterAccount)
+    if err != nil {
+        return err
+    }
+
+    charged := pr.Amount
+    if payer.Currency != requester.Currency {
+        charged, err = s.cfg.FXRates.Convert(pr.Amount, requester.Currency, payer.Currency)
+        if err != nil {
+            return err
+        }
+    }
+
+    plan, err := s.planTransfer(r, &types.TransferRequest{ToAccount: requester.Number, Amount: charged})
+    if err != nil {
+        return err
+    }
+    // an admin approving the case couldn't settle the request, so it is
+    // refused outright
+    if plan.decision.Action == types.FraudBlock {
+        return writeMessage(w, r, http.StatusForbidden, i18n.TransferBlocked)
+    }
+    // the requester gets exactly what they asked for
+    if payer.Currency != requester.Currency {
+        plan.entries = append(types.NewFXEntries(payer.Number, requester.Number, charged, pr.Amount), plan.fee...)
+    }
+
+    tx := plan.tx
+    if err := s.store.AcceptPaymentRequest(r.Context(), pr.ID, tx, plan.entries, s.cfg.VelocityLimits); err != nil {
+        return err
+    }
+    s.recordTransferUsage(r.Context(), payer.Number, tx.Amount)
+    if plan.decision.Action == types.FraudReview {
+        s.recordFraudCase(r.Context(), plan.decision, payer.Number, requester.Number, tx.Amount, nil, tx)
+    }
+    pr.Status = types.RequestAccepted
+    pr.TransactionID = tx.ID
+
+    s.notifier.Publish(notify.Event{
+        Type: notify.TransferConfirmation,
+        Account: payer,
+        Data: map[string]any{"amount": tx.Amount, "toAccount": tx.ToAccount},
+    })
+    s.notifier.Publish(notify.Event{
+        Type: notify.PaymentRequestPaid,
+        Account: requester,
+        Data: map[string]any{"amount": pr.Amount, "currency": requester.Currency, "memo": pr.Memo, "payer": pr.PayerAccount},
+    })
+    s.checkAlerts(r.Context(), tx, requester, pr.Amount)
+
+    return WriteJSON(w, http.StatusOK, pr)
+}
+
+func (s *APIServer) closePaymentRequest(w http.ResponseWriter, r *http.Request, pr *types.PaymentRequest, status string, notifyNumber int64, event notify.EventType) error {
+    if err := s.store.ClosePaymentRequest(r.Context(), pr.ID, status); err != nil {
+        return err
+    }
+    pr.Status = status
+
+    if other, err := s.store.GetAccountByNumber(r.Context(), notifyNumber); err == nil {
+        // the amount is in the requester's currency
+        requester := auth.AccountFromContext(r.Context())
+        if other.Number == pr.RequesterAccount {
+            requester = other
+        }
+
+        s.notifier.Publish(notify.Event{
+            Type: event,
+            Account: other,
+            Data: map[string]any{"amount": pr.Amount, "currency": requester.Currency, "memo": pr.Memo, "id": pr.ID},
+        })
+    }
+
+    return WriteJSON(w, http.StatusOK, pr)
+}
+
+// expire reports pending requests past their expiry as expired. The stored
+// status is left alone since nothing can happen to them anymore.
+func expire(pr *types.PaymentRequest) {
+    if pr.Status == types.RequestPending && time.Now().After(pr.ExpiresAt) {
+        pr.Status = types.RequestExpired
+    }
+}
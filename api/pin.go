@@ -0,0 +1,136 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "regexp"
+    "strconv"
+
+    "golang.org/x/crypto/bcrypt"
+    "gobank/types"
+)
+
+const maxPINAttempts = 3
+
+var pinFormat = regexp.MustCompile(`^[0-9]{4,6}$`)
+
+// handleCardPIN sets a card's first PIN or changes it, which takes the
+// current one. Wrong current PINs count towards blocking the card.
+func (s *APIServer) handleCardPIN(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "PUT" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    card, err := s.cardFromPath(r)
+    if err != nil {
+        return err
+    }
+    if card.Status == types.CardBlocked {
+        return fmt.Errorf("card is blocked, contact support")
+    }
+
+    req := new(types.PINRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    if !pinFormat.MatchString(req.PIN) {
+        return fmt.Errorf("pin must be 4 to 6 digits")
+    }
+
+    if card.PINSet {
+        ok, _, err := s.checkPIN(r, card, req.CurrentPIN)
+        if err != nil {
+            return err
+        }
+        if !ok {
+            return fmt.Errorf("current pin is wrong")
+        }
+    }
+
+    hash, err := bcrypt.GenerateFromPassword([]byte(req.PIN), bcrypt.DefaultCost)
+    if err != nil {
+        return err
+    }
+    if err := s.store.SetCardPIN(r.Context(), card.ID, string(hash)); err != nil {
+        return err
+    }
+    card.PINSet = true
+
+    return WriteJSON(w, http.StatusOK, card)
+}
+
+func (s *APIServer) handleVerifyCardPIN(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    card, err := s.cardFromPath(r)
+    if err != nil {
+        return err
+    }
+    if !card.PINSet {
+        return fmt.Errorf("card has no pin")
+    }
+    if card.Status == types.CardBlocked {
+        return fmt.Errorf("card is blocked, contact support")
+    }
+
+    req := new(types.VerifyPINRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+
+    ok, updated, err := s.checkPIN(r, card, req.PIN)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, types.VerifyPINResponse{
+        Valid: ok,
+        AttemptsLeft: maxPINAttempts - updated.PINAttempts,
+    })
+}
+
+// checkPIN compares pin with the card's and records the attempt, which
+// blocks the card after maxPINAttempts wrong ones in a row.
+func (s *APIServer) checkPIN(r *http.Request, card *types.Card, pin string) (bool, *types.Card, error) {
+    ok := bcrypt.CompareHashAndPassword([]byte(card.PINHash), []byte(pin)) == nil
+
+    updated, err := s.store.RecordPINAttempt(r.Context(), card.ID, ok, maxPINAttempts)
+    if err != nil {
+        return false, nil, err
+    }
+
+    return ok, updated, nil
+}
+
+// handleUnblockCard lifts a PIN block. The card comes back active with its
+// failed attempts cleared and the same PIN.
+func (s *APIServer) handleUnblockCard(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := strconv.Atoi(r.PathValue("cardID"))
+    if err != nil {
+        return fmt.Errorf("invalid card id given %s", r.PathValue("cardID"))
+    }
+
+    card, err := s.store.GetCard(r.Context(), id)
+    if err != nil {
+        return err
+    }
+    if card.Status != types.CardBlocked {
+        return fmt.Errorf("card %d is not blocked", id)
+    }
+
+    card.Status = types.CardActive
+    if err := s.store.UpdateCard(r.Context(), card); err != nil {
+        return err
+    }
+    if card, err = s.store.RecordPINAttempt(r.Context(), id, true, maxPINAttempts); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, card)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/storage"
+    "gobank/types"
+)
+
+const maxPotNameLength = 100
+
+func (s *APIServer) handlePots(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        pots, err := s.store.GetPotsByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        resp := make([]types.PotResponse, 0, len(pots))
+        for _, p := range pots {
+            resp = append(resp, types.PotResponse{Pot: p, Progress: p.Progress()})
+        }
+
+        return WriteJSON(w, http.StatusOK, resp)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.PotRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+        if err := s.validatePot(r.Context(), account, 0, req); err != nil {
+            return err
+        }
+
+        pot := &types.Pot{
+            AccountNumber: account.Number,
+            Name: req.Name,
+            Target: req.Target,
+            RoundUp: req.RoundUp,
+            CreatedAt: time.Now().UTC(),
+        }
+        if err := s.store.CreatePot(r.Context(), pot); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, types.PotResponse{Pot: pot, Progress: pot.Progress()})
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// handlePot changes or deletes a pot. Deleting a pot releases its balance
+// back to the account.
+func (s *APIServer) handlePot(w http.ResponseWriter, r *http.Request) error {
+    pot, err := s.potFromPath(r)
+    if err != nil {
+        return err
+    }
+
+    if r.Method == "GET" {
+        return WriteJSON(w, http.StatusOK, types.PotResponse{Pot: pot, Progress: pot.Progress()})
+    }
+
+    if r.Method == "PUT" {
+        req := new(types.PotRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+        if err := s.validatePot(r.Context(), auth.AccountFromContext(r.Context()), pot.ID, req); err != nil {
+            return err
+        }
+
+        pot.Name = req.Name
+        pot.Target = req.Target
+        pot.RoundUp = req.RoundUp
+        if err := s.store.UpdatePot(r.Context(), pot); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, types.PotResponse{Pot: pot, Progress: pot.Progress()})
+    }
+
+    if r.Method == "DELETE" {
+        if err := s.store.DeletePot(r.Context(), pot.ID); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, map[string]int{"deleted": pot.ID})
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// handlePotAction moves money between the account's spendable balance and
+// a pot.
+func (s *APIServer) handlePotAction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    pot, err := s.potFromPath(r)
+    if err != nil {
+        return err
+    }
+
+    req := new(types.PotMoveRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    if req.Amount <= 0 {
+        return fmt.Errorf("amount must be positive")
+    }
+
+    amount := req.Amount
+    switch action := r.PathValue("action"); action {
+    case "deposit":
+    case "withdraw":
+        amount = -amount
+    default:
+        return fmt.Errorf("unknown pot action %s", action)
+    }
+
+    pot, err = s.store.MovePotMoney(r.Context(), pot.ID, amount)
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, types.PotResponse{Pot: pot, Progress: pot.Progress()})
+}
+
+// validatePot checks a pot request for the pot with id, or a new pot when
+// id is zero. Only one pot per account can collect round ups.
+func (s *APIServer) validatePot(ctx context.Context, account *types.Account, id int, req *types.PotRequest) error {
+    if req.Name == "" || len(req.Name) > maxPotNameLength {
+        return fmt.Errorf("name must be between 1 and %d characters", maxPotNameLength)
+    }
+    if req.Target <= 0 {
+        return fmt.Errorf("target must be positive")
+    }
+
+    if req.RoundUp {
+        pots, err := s.store.GetPotsByAccount(ctx, account.Number)
+        if err != nil {
+            return err
+        }
+        for _, p := range pots {
+            if p.RoundUp && p.ID != id {
+                return fmt.Errorf("round ups already go to pot %q", p.Name)
+            }
+        }
+    }
+
+    return nil
+}
+
+func (s *APIServer) potFromPath(r *http.Request) (*types.Pot, error) {
+    id, err := strconv.Atoi(r.PathValue("potID"))
+    if err != nil {
+        return nil, fmt.Errorf("invalid pot id given %s", r.PathValue("potID"))
+    }
+
+    pot, err := s.store.GetPot(r.Context(), id)
+    if err != nil {
+        return nil, err
+    }
+    if pot.AccountNumber != auth.AccountFromContext(r.Context()).Number {
+        return nil, fmt.Errorf("pot %d %w", id, storage.ErrNotFound)
+    }
+
+    return pot, nil
+}
+
+// roundUp saves the change from rounding an outgoing amount up to the next
+// whole unit into the account's round up pot, if it has one. It is best
+// effort: the payment already went through.
+func (s *APIServer) roundUp(ctx context.Context, account *types.Account, amount int64) {
+    change := (100 - amount%100) % 100
+    if change == 0 {
+        return
+    }
+
+    pots, err := s.store.GetPotsByAccount(ctx, account.Number)
+    if err != nil {
+        s.logger.Println("round up failed:", err)
+        return
+    }
+
+    for _, p := range pots {
+        if !p.RoundUp {
+            continue
+        }
+        _, err := s.store.MovePotMoney(ctx, p.ID, change)
+        if err != nil && !errors.Is(err, storage.ErrInsufficientFunds) {
+            s.logger.Println("round up failed:", err)
+        }
+        return
+    }
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "slices"
+    "strconv"
+    "time"
+
+    "gobank/types"
+)
+
+// handleProductRates lists every product rate or schedules a new one.
+func (s *APIServer) handleProductRates(w http.ResponseWriter, r *http.Request) error {
+    if r.Method == "GET" {
+        rates, err := s.store.GetProductRates(r.Context())
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, rates)
+    }
+
+    if r.Method == "POST" {
+        req := new(types.ProductRateRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+
+        now := time.Now().UTC()
+        rate := &types.ProductRate{
+            Key: req.Key,
+            Value: req.Value,
+            EffectiveFrom: now,
+            CreatedAt: now,
+        }
+        if req.EffectiveFrom != nil {
+            if err := validateEffectiveFrom(*req.EffectiveFrom, now); err != nil {
+                return err
+            }
+            rate.EffectiveFrom = req.EffectiveFrom.UTC()
+        }
+        if err := validateProductRate(rate); err != nil {
+            return err
+        }
+
+        if err := s.store.CreateProductRate(r.Context(), rate); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, rate)
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// handleProductRate changes or removes a rate that hasn't taken effect yet.
+// Rates already in effect are kept as history; schedule a new rate instead.
+func (s *APIServer) handleProductRate(w http.ResponseWriter, r *http.Request) error {
+    id, err := strconv.Atoi(r.PathValue("rateID"))
+    if err != nil {
+        return fmt.Errorf("invalid rate id given %s", r.PathValue("rateID"))
+    }
+
+    rate, err := s.store.GetProductRate(r.Context(), id)
+    if err != nil {
+        return err
+    }
+
+    switch r.Method {
+    case "GET":
+        return WriteJSON(w, http.StatusOK, rate)
+
+    case "PUT":
+        req := new(types.ProductRateRequest)
+        if err := s.decodeJSON(w, r, req); err != nil {
+            return err
+        }
+        if req.Key != "" && req.Key != rate.Key {
+            return fmt.Errorf("the key of a rate can't be changed")
+        }
+
+        now := time.Now().UTC()
+        rate.Value = req.Value
+        if req.EffectiveFrom != nil {
+            if err := validateEffectiveFrom(*req.EffectiveFrom, now); err != nil {
+                return err
+            }
+            rate.EffectiveFrom = req.EffectiveFrom.UTC()
+        }
+        if err := validateProductRate(rate); err != nil {
+            return err
+        }
+
+        if err := s.store.UpdateProductRate(r.Context(), rate, now); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, rate)
+
+    case "DELETE":
+        if err := s.store.DeleteProductRate(r.Context(), id, time.Now().UTC()); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, map[string]int{"deleted": id})
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+func validateProductRate(rate *types.ProductRate) error {
+    if !slices.Contains(types.ProductKeys, rate.Key) {
+        return fmt.Errorf("unknown product key %q, want one of %v", rate.Key, types.ProductKeys)
+    }
+    if rate.Value < 0 {
+        return fmt.Errorf("value can't be negative")
+    }
+    return nil
+}
+
+// validateEffectiveFrom keeps a new or moved rate from taking effect in the
+// past, which would change what interest and fees already came to.
+func validateEffectiveFrom(t, now time.Time) error {
+    if t.Before(now) {
+        return fmt.Errorf("effective from %s is in the past", t.UTC().Format(time.RFC3339))
+    }
+    return nil
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/qrpay"
+    "gobank/types"
+)
+
+const (
+    defaultQRTTL = 15 * time.Minute
+    maxQRTTL = 24 * time.Hour
+)
+
+// handleQR returns a signed payment code for the account. amount, memo and
+// ttl are optional query parameters.
+func (s *APIServer) handleQR(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+    if s.cfg.QRSecret == "" {
+        return fmt.Errorf("qr payments are not enabled")
+    }
+
+    q := r.URL.Query()
+
+    var amount int64
+    if v := q.Get("amount"); v != "" {
+        n, err := strconv.ParseInt(v, 10, 64)
+        if err != nil || n <= 0 {
+            return fmt.Errorf("amount must be a positive integer")
+        }
+        amount = n
+    }
+
+    memo := q.Get("memo")
+    if len(memo) > maxMemoLength {
+        return fmt.Errorf("memo must be at most %d characters", maxMemoLength)
+    }
+
+    ttl := defaultQRTTL
+    if v := q.Get("ttl"); v != "" {
+        d, err := time.ParseDuration(v)
+        if err != nil || d <= 0 || d > maxQRTTL {
+            return fmt.Errorf("ttl must be a duration up to %s", maxQRTTL)
+        }
+        ttl = d
+    }
+
+    account := auth.AccountFromContext(r.Context())
+    expiresAt := time.Now().UTC().Add(ttl).Truncate(time.Second)
+    payload, err := qrpay.Encode([]byte(s.cfg.QRSecret), qrpay.Payload{
+        Account: account.Number,
+        Amount: amount,
+        Currency: account.Currency,
+        Memo: memo,
+        ExpiresAt: expiresAt.Unix(),
+    })
+    if err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, types.QRCode{
+        Payload: payload,
+        Account: account.Number,
+        Amount: amount,
+        Currency: account.Currency,
+        Memo: memo,
+        ExpiresAt: expiresAt,
+    })
+}
+
+// handleDecodeQR validates a scanned payment code so the app can show who
+// is being paid before it calls /transfer.
+func (s *APIServer) handleDecodeQR(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+    if s.cfg.QRSecret == "" {
+        return fmt.Errorf("qr payments are not enabled")
+    }
+
+    req := new(types.DecodeQRRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+
+    p, err := qrpay.Decode([]byte(s.cfg.QRSecret), req.Payload, time.Now())
+    if err != nil {
+        return err
+    }
+
+    recipient, err := s.store.GetAccountByNumber(r.Context(), p.Account)
+    if err != nil {
+        return err
+    }
+    if recipient.Number == auth.AccountFromContext(r.Context()).Number {
+        return fmt.Errorf("can't pay your own payment code")
+    }
+
+    return WriteJSON(w, http.StatusOK, types.DecodedQR{
+        Account: recipient.Number,
+        RecipientName: recipientName(recipient),
+        Amount: p.Amount,
+        Currency: p.Currency,
+        Memo: p.Memo,
+        ExpiresAt: time.Unix(p.ExpiresAt, 0).UTC(),
+    })
+}
+
+// recipientName shows just enough of the name to confirm the payee, e.g.
+// "Alice S.".
+func recipientName(acc *types.Account) string {
+    if acc.LastName == "" {
+        return acc.FirstName
+    }
+    return acc.FirstName + " " + string([]rune(acc.LastName)[:1]) + "."
+}
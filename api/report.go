@@ -0,0 +1,188 @@
+package api
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "gobank/types"
+)
+
+const (
+    maxReportDays = 366
+    defaultReportDays = 30
+    maxLargestTransfers = 100
+)
+
+// reportCache keeps each computed report for the refresh interval, so
+// operators polling a dashboard don't each run the aggregation again.
+type reportCache struct {
+    mu sync.Mutex
+    refresh time.Duration
+    reports map[string]cachedReport
+}
+
+type cachedReport struct {
+    report any
+    expires time.Time
+}
+
+func newReportCache(refresh time.Duration) *reportCache {
+    return &reportCache{refresh: refresh, reports: map[string]cachedReport{}}
+}
+
+// get returns the cached report for key, computing it when it is missing or
+// expired, and when it expires.
+func (c *reportCache) get(key string, compute func() (any, error)) (any, time.Time, error) {
+    now := time.Now()
+
+    c.mu.Lock()
+    cached, ok := c.reports[key]
+    c.mu.Unlock()
+    if ok && now.Before(cached.expires) {
+        return cached.report, cached.expires, nil
+    }
+
+    report, err := compute()
+    if err != nil {
+        return nil, time.Time{}, err
+    }
+    cached = cachedReport{report: report, expires: now.Add(c.refresh)}
+
+    c.mu.Lock()
+    for k, old := range c.reports {
+        if now.After(old.expires) {
+            delete(c.reports, k)
+        }
+    }
+    c.reports[key] = cached
+    c.mu.Unlock()
+
+    return report, cached.expires, nil
+}
+
+func (s *APIServer) handleFlowReport(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    from, to, err := reportPeriod(r)
+    if err != nil {
+        return err
+    }
+
+    key := fmt.Sprintf("flows %s %s", from, to)
+    return s.writeReport(w, key, func() (any, error) {
+        return s.flowReport(r.Context(), from, to)
+    })
+}
+
+func (s *APIServer) flowReport(ctx context.Context, from, to time.Time) (*types.FlowReport, error) {
+    days, err := s.store.GetDailyFlows(ctx, from, to)
+    if err != nil {
+        return nil, err
+    }
+    active, err := s.store.GetActiveAccountCount(ctx, from, to)
+    if err != nil {
+        return nil, err
+    }
+
+    report := &types.FlowReport{
+        From: from,
+        To: to,
+        ActiveAccounts: active,
+        Days: days,
+        GeneratedAt: time.Now().UTC(),
+    }
+    for _, d := range days {
+        report.Deposits += d.Deposits
+        report.Withdrawals += d.Withdrawals
+    }
+    report.Net = report.Deposits - report.Withdrawals
+
+    return report, nil
+}
+
+func (s *APIServer) handleLargestTransfersReport(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    from, to, err := reportPeriod(r)
+    if err != nil {
+        return err
+    }
+    limit := 10
+    if v := r.URL.Query().Get("limit"); v != "" {
+        limit, err = strconv.Atoi(v)
+        if err != nil || limit < 1 || limit > maxLargestTransfers {
+            return fmt.Errorf("limit must be between 1 and %d", maxLargestTransfers)
+        }
+    }
+
+    key := fmt.Sprintf("largest %s %s %d", from, to, limit)
+    return s.writeReport(w, key, func() (any, error) {
+        transfers, err := s.store.GetLargestTransfers(r.Context(), from, to, limit)
+        if err != nil {
+            return nil, err
+        }
+
+        return &types.LargestTransfersReport{
+            From: from,
+            To: to,
+            Transfers: transfers,
+            GeneratedAt: time.Now().UTC(),
+        }, nil
+    })
+}
+
+// writeReport answers with the cached report and tells clients how long
+// they can keep it.
+func (s *APIServer) writeReport(w http.ResponseWriter, key string, compute func() (any, error)) error {
+    report, expires, err := s.reports.get(key, compute)
+    if err != nil {
+        return err
+    }
+
+    maxAge := int(time.Until(expires).Seconds())
+    w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", max(maxAge, 0)))
+
+    return WriteJSON(w, http.StatusOK, report)
+}
+
+// reportPeriod reads the from and to dates of a report, both included. It
+// defaults to the last 30 days up to today and returns the period as
+// [from, to).
+func reportPeriod(r *http.Request) (time.Time, time.Time, error) {
+    now := time.Now().UTC()
+    to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+    if v := r.URL.Query().Get("to"); v != "" {
+        day, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            return time.Time{}, time.Time{}, fmt.Errorf("to must be a date like 2024-06-01")
+        }
+        to = day
+    }
+    to = to.AddDate(0, 0, 1)
+
+    from := to.AddDate(0, 0, -defaultReportDays)
+    if v := r.URL.Query().Get("from"); v != "" {
+        day, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            return time.Time{}, time.Time{}, fmt.Errorf("from must be a date like 2024-06-01")
+        }
+        from = day
+    }
+
+    if !from.Before(to) {
+        return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+    }
+    if to.Sub(from) > maxReportDays*24*time.Hour {
+        return time.Time{}, time.Time{}, fmt.Errorf("a report can cover at most %d days", maxReportDays)
+    }
+
+    return from, to, nil
+}
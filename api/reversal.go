@@ -0,0 +1,89 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/notify"
+    "gobank/types"
+)
+
+// handleReverseTransaction undoes a transaction posted in error by posting
+// its entries in reverse. Each transaction can only be reversed once, and
+// not while it is disputed. Both sides are told and the outbound webhook
+// gets a transaction.reversed event.
+func (s *APIServer) handleReverseTransaction(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id, err := strconv.Atoi(r.PathValue("transactionID"))
+    if err != nil {
+        return fmt.Errorf("invalid transaction id given %s", r.PathValue("transactionID"))
+    }
+
+    req := new(types.ReverseTransactionRequest)
+    if err := s.decodeJSON(w, r, req); err != nil {
+        return err
+    }
+    if req.Reason == "" {
+        return fmt.Errorf("a reason is required")
+    }
+
+    tx, err := s.store.GetTransaction(r.Context(), id)
+    if err != nil {
+        return err
+    }
+    if tx.Status != types.StatusCompleted {
+        return fmt.Errorf("only completed transactions can be reversed")
+    }
+    if tx.Kind == types.TransactionReversal {
+        return fmt.Errorf("a reversal can't be reversed")
+    }
+
+    entries, err := s.store.GetLedgerEntriesByTransaction(r.Context(), tx.ID)
+    if err != nil {
+        return err
+    }
+    reversed := make([]*types.LedgerEntry, len(entries))
+    for i, e := range entries {
+        reversed[i] = &types.LedgerEntry{AccountNumber: e.AccountNumber, Amount: -e.Amount}
+    }
+
+    now := time.Now().UTC()
+    reversalTx := &types.Transaction{
+        Kind: types.TransactionReversal,
+        FromAccount: tx.ToAccount,
+        ToAccount: tx.FromAccount,
+        Amount: tx.Amount,
+        CreatedAt: now,
+    }
+    reversal := &types.Reversal{TransactionID: tx.ID, Reason: req.Reason, CreatedAt: now}
+    if err := s.store.ReverseTransaction(r.Context(), reversal, reversalTx, reversed); err != nil {
+        return err
+    }
+
+    // each side is told the amount in its own currency
+    amounts := map[int64]int64{}
+    for _, e := range entries {
+        if !types.IsInternalAccount(e.AccountNumber) {
+            amounts[e.AccountNumber] += e.Amount
+        }
+    }
+    for number, amount := range amounts {
+        acc, err := s.store.GetAccountByNumber(r.Context(), number)
+        if err != nil {
+            continue
+        }
+        s.notifier.Publish(notify.Event{
+            Type: notify.TransactionReversed,
+            Account: acc,
+            Data: map[string]any{"transactionId": tx.ID, "amount": max(amount, -amount), "reason": req.Reason},
+        })
+    }
+    s.publishWebhook("transaction.reversed", reversal)
+
+    return WriteJSON(w, http.StatusOK, reversal)
+}
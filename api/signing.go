@@ -0,0 +1,162 @@
+package api
+
+import (
+    "bytes"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+
+    "gobank/auth"
+    "gobank/metrics"
+    "gobank/signing"
+    "gobank/storage"
+    "gobank/types"
+    "gobank/vault"
+)
+
+var signedRequestsTotal = metrics.NewCounter("gobank_signed_requests_total", "Requests checked for a signature, by result.", "result")
+
+// withSignature makes holders with signing keys sign their requests, so a
+// stolen token alone can't move their money. Holders without keys are let
+// through. It must be inside withJWTAuth.
+func (s *APIServer) withSignature(handlerFunc http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        keys, err := s.store.GetSigningKeysByAccount(r.Context(), auth.ActorFromContext(r.Context()))
+        if err != nil {
+            writeError(w, r, err)
+            return
+        }
+        if len(keys) == 0 || s.checkSignature(w, r, keys) {
+            handlerFunc(w, r)
+        }
+    }
+}
+
+// checkSignature reports whether r is signed by one of keys, writing the
+// response itself when it isn't.
+func (s *APIServer) checkSignature(w http.ResponseWriter, r *http.Request, keys []*types.SigningKey) bool {
+    if err := s.verifySignature(w, r, keys); err != nil {
+        if errors.Is(err, signing.ErrMissingSignature) ||
+            errors.Is(err, signing.ErrInvalidSignature) ||
+            errors.Is(err, signing.ErrExpiredSignature) ||
+            errors.Is(err, storage.ErrNonceUsed) {
+            signedRequestsTotal.Inc("rejected")
+            WriteJSON(w, http.StatusUnauthorized, ApiError{Error: err.Error(), Code: "invalid_signature"})
+            return false
+        }
+        writeError(w, r, err)
+        return false
+    }
+    signedRequestsTotal.Inc("accepted")
+
+    return true
+}
+
+// verifySignature checks r against the signing key its signature names
+// and uses up the nonce. The body is read and put back for the handler.
+func (s *APIServer) verifySignature(w http.ResponseWriter, r *http.Request, keys []*types.SigningKey) error {
+    sig, err := signing.Parse(r.Header.Get(signing.Header))
+    if err != nil {
+        return err
+    }
+
+    var key *types.SigningKey
+    for _, k := range keys {
+        if k.ID == sig.KeyID {
+            key = k
+            break
+        }
+    }
+    if key == nil {
+        return signing.ErrInvalidSignature
+    }
+    secret, err := vault.Decrypt(s.cfg.EncryptionKey, key.EncryptedSecret)
+    if err != nil {
+        return err
+    }
+
+    body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+    if err != nil {
+        return err
+    }
+    r.Body = io.NopCloser(bytes.NewReader(body))
+
+    now := time.Now().UTC()
+    if err := sig.Verify(secret, r.Method, r.URL.RequestURI(), body, now, s.cfg.RequestSignatureTolerance); err != nil {
+        return err
+    }
+
+    return s.store.UseNonce(r.Context(), key.ID, sig.Nonce, now)
+}
+
+func (s *APIServer) handleSigningKeys(w http.ResponseWriter, r *http.Request) error {
+    account := auth.AccountFromContext(r.Context())
+
+    if r.Method == "GET" {
+        keys, err := s.store.GetSigningKeysByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, keys)
+    }
+
+    if r.Method == "POST" {
+        // the first key only takes a session that passed step-up, every
+        // later one a request signed with a key the holder already has
+        keys, err := s.store.GetSigningKeysByAccount(r.Context(), account.Number)
+        if err != nil {
+            return err
+        }
+        if len(keys) > 0 && !s.checkSignature(w, r, keys) {
+            return nil
+        }
+
+        id := make([]byte, 8)
+        secret := make([]byte, 32)
+        for _, b := range [][]byte{id, secret} {
+            if _, err := rand.Read(b); err != nil {
+                return err
+            }
+        }
+
+        encoded := hex.EncodeToString(secret)
+        encrypted, err := vault.Encrypt(s.cfg.EncryptionKey, []byte(encoded))
+        if err != nil {
+            return err
+        }
+
+        key := &types.SigningKey{
+            ID: "sk_" + hex.EncodeToString(id),
+            AccountNumber: account.Number,
+            EncryptedSecret: encrypted,
+            CreatedAt: time.Now().UTC(),
+        }
+        if err := s.store.CreateSigningKey(r.Context(), key); err != nil {
+            return err
+        }
+
+        return WriteJSON(w, http.StatusOK, types.CreatedSigningKey{SigningKey: key, Secret: encoded})
+    }
+
+    return fmt.Errorf("method not allowed %s", r.Method)
+}
+
+// handleDeleteSigningKey removes a key. It is a signed route itself, so a
+// stolen token can't turn signing off.
+func (s *APIServer) handleDeleteSigningKey(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "DELETE" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    id := r.PathValue("keyID")
+    if err := s.store.DeleteSigningKey(r.Context(), auth.AccountFromContext(r.Context()).Number, id); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, map[string]string{"deleted": id})
+}
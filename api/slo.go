@@ -0,0 +1,35 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "time"
+
+    "gobank/slo"
+)
+
+// withSLO records every request against t's objectives. Only server errors
+// count as failures, a transfer turned down for lack of funds was still
+// served.
+func withSLO(t *slo.Tracker) Middleware {
+    return func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+            start := time.Now()
+            rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+            handlerFunc(rec, r)
+
+            t.Record(time.Now(), rec.status < 500, time.Since(start))
+        }
+    }
+}
+
+// handleSLO shows how the transfer and login paths do against their
+// objectives.
+func (s *APIServer) handleSLO(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    now := time.Now()
+    return WriteJSON(w, http.StatusOK, []slo.Status{s.transferSLO.Status(now), s.loginSLO.Status(now)})
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+
+    "gobank/auth"
+    "gobank/fraud"
+    "gobank/notify"
+    "gobank/products"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// idempotencyKeyHeader names a transfer, so a client that got no answer
+// can send it again without it being posted twice.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+func (s *APIServer) handleTransfer(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method %s  not supported, you should use POST instead", r.Method)
+    }
+
+    transferReq := new(types.TransferRequest)
+    if err := s.decodeJSON(w, r, transferReq); err != nil {
+        return err
+    }
+
+    key := r.Header.Get(idempotencyKeyHeader)
+    if len(key) > 128 {
+        return fmt.Errorf("%s can be at most 128 characters", idempotencyKeyHeader)
+    }
+    if key != "" {
+        if err := s.replayTransfer(w, r, key, transferReq); !errors.Is(err, storage.ErrNotFound) {
+            return err
+        }
+    }
+
+    plan, err := s.planTransfer(r, transferReq)
+    if err != nil {
+        return err
+    }
+    plan.tx.IdempotencyKey = key
+    from, to := plan.from, plan.to
+    if to == nil {
+        return s.transferToUnclaimedAlias(w, r, plan)
+    }
+
+    decision := plan.decision
+    if decision.Action == types.FraudBlock {
+        c, err := s.recordFraudCase(r.Context(), decision, from.Number, to.Number, transferReq.Amount, nil, nil)
+        if err != nil {
+            return err
+        }
+        return WriteJSON(w, http.StatusAccepted, c)
+    }
+
+    tx := plan.tx
+    err = s.store.PostTransfer(r.Context(), tx, plan.entries, s.cfg.VelocityLimits)
+    if errors.Is(err, storage.ErrIdempotencyKeyUsed) {
+        // another attempt with the same key got there first
+        return s.replayTransfer(w, r, key, transferReq)
+    }
+    if err != nil {
+        return err
+    }
+    s.recordTransferUsage(r.Context(), from.Number, tx.Amount)
+    if decision.Action == types.FraudReview {
+        s.recordFraudCase(r.Context(), decision, from.Number, to.Number, tx.Amount, nil, tx)
+    }
+
+    s.notifier.Publish(notify.Event{
+        Type: notify.TransferConfirmation,
+        Account: from,
+        Data: map[string]any{"amount": tx.Amount, "toAccount": tx.ToAccount},
+    })
+
+    if tx.Amount >= s.cfg.LargeWithdrawalAmount {
+        s.notifier.Publish(notify.Event{
+            Type: notify.LargeWithdrawal,
+            Account: from,
+            Data: map[string]any{"amount": tx.Amount, "toAccount": tx.ToAccount},
+        })
+    }
+
+    s.checkAlerts(r.Context(), tx, to, plan.converted)
+    s.roundUp(r.Context(), from, tx.Amount)
+
+    return WriteJSON(w, http.StatusOK, tx)
+}
+
+// replayTransfer answers with the transfer the account already posted with
+// key, the way it was answered the first time. It fails with ErrNotFound
+// when there is none, and with ErrIdempotencyKeyUsed when the key was used
+// for a different transfer.
+func (s *APIServer) replayTransfer(w http.ResponseWriter, r *http.Request, key string, transferReq *types.TransferRequest) error {
+    from := auth.AccountFromContext(r.Context())
+    tx, err := s.store.GetTransactionByIdempotencyKey(r.Context(), from.Number, key)
+    if err != nil {
+        return err
+    }
+    // an alias can point at another account by now, only the amount is
+    // compared for those
+    if tx.Amount != transferReq.Amount || (transferReq.ToAlias == "" && tx.ToAccount != transferReq.ToAccount) {
+        return fmt.Errorf("transaction %d: %w", tx.ID, storage.ErrIdempotencyKeyUsed)
+    }
+
+    w.Header().Set("Idempotent-Replayed", "true")
+    if tx.ToAccount == types.SuspenseAccountNumber {
+        return WriteJSON(w, http.StatusAccepted, tx)
+    }
+    return WriteJSON(w, http.StatusOK, tx)
+}
+
+// handleTransferPreview answers what POST /transfer would do with the same
+// body, going through the same checks without moving any money, so clients
+// can show a confirmation screen.
+func (s *APIServer) handleTransferPreview(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    transferReq := new(types.TransferRequest)
+    if err := s.decodeJSON(w, r, transferReq); err != nil {
+        return err
+    }
+
+    plan, err := s.planTransfer(r, transferReq)
+    if err != nil {
+        return err
+    }
+
+    preview := &types.TransferPreview{
+        Outcome: types.PreviewCompleted,
+        FromAccount: plan.from.Number,
+        ToAccount: plan.tx.ToAccount,
+        ToAlias: plan.alias,
+        Amount: plan.tx.Amount,
+        Currency: plan.from.Currency,
+        ConvertedAmount: plan.converted,
+        ToCurrency: plan.from.Currency,
+        Rate: plan.rate,
+        Entries: plan.entries,
+    }
+    if plan.to != nil {
+        preview.ToCurrency = plan.to.Currency
+    }
+    for _, e := range plan.entries {
+        if e.AccountNumber == types.FeeAccountNumber {
+            preview.Fee += e.Amount
+        }
+    }
+
+    // a blocked transfer is never posted, so nothing else can fail
+    if plan.decision.Action == types.FraudBlock {
+        preview.Outcome = types.PreviewBlocked
+        return WriteJSON(w, http.StatusOK, preview)
+    }
+
+    if plan.to == nil {
+        preview.Outcome = types.PreviewPending
+    }
+    if err := s.store.PreviewTransfer(r.Context(), plan.tx, plan.entries, s.cfg.VelocityLimits); err != nil {
+        return err
+    }
+
+    return WriteJSON(w, http.StatusOK, preview)
+}
+
+// transferPlan is a transfer request that passed validation, with what
+// posting it takes. to is nil for a transfer to an unclaimed alias, which
+// goes to the suspense account until it is claimed.
+type transferPlan struct {
+    from, to *types.Account
+    alias, aliasKind string
+    tx *types.Transaction
+    // entries include fee, which charges the transfer fee
+    entries, fee []*types.LedgerEntry
+    converted int64
+    rate float64
+    decision fraud.Decision
+}
+
+func (s *APIServer) planTransfer(r *http.Request, transferReq *types.TransferRequest) (*transferPlan, error) {
+    from := auth.AccountFromContext(r.Context())
+    if from.ClosedAt != nil {
+        return nil, fmt.Errorf("account %d: %w", from.Number, storage.ErrAccountClosed)
+    }
+    if transferReq.Amount <= 0 {
+        return nil, fmt.Errorf("amount must be positive")
+    }
+    if grant := auth.GrantFromContext(r.Context()); grant != nil && transferReq.Amount > grant.TransferLimit {
+        return nil, fmt.Errorf("amount is above the %d transfer limit of your access", grant.TransferLimit)
+    }
+    if err := s.checkTransferQuota(r.Context(), from.Number, transferReq.Amount); err != nil {
+        return nil, err
+    }
+
+    plan := &transferPlan{from: from}
+    var err error
+    plan.fee, err = s.transferFee(r.Context(), from.Number, time.Now().UTC())
+    if err != nil {
+        return nil, err
+    }
+    if transferReq.ToAlias != "" {
+        plan.alias, plan.aliasKind, err = normalizeAlias(transferReq.ToAlias)
+        if err != nil {
+            return nil, err
+        }
+
+        plan.to, err = s.accountForAlias(r.Context(), plan.alias)
+        if errors.Is(err, storage.ErrNotFound) {
+            plan.tx = &types.Transaction{
+                Kind: types.TransactionTransfer,
+                Status: types.StatusPending,
+                FromAccount: from.Number,
+                ToAccount: types.SuspenseAccountNumber,
+                Amount: transferReq.Amount,
+                CreatedAt: time.Now().UTC(),
+            }
+            plan.entries = append(types.NewEntries(from.Number, types.SuspenseAccountNumber, transferReq.Amount), plan.fee...)
+            plan.converted, plan.rate = transferReq.Amount, 1
+            plan.decision, err = s.checkFraud(r, from, nil, plan.alias, nil, transferReq.Amount)
+            if err != nil {
+                return nil, err
+            }
+            return plan, nil
+        }
+        if err != nil {
+            return nil, err
+        }
+    } else {
+        plan.to, err = s.store.GetAccountByNumber(r.Context(), transferReq.ToAccount)
+        if err != nil {
+            return nil, err
+        }
+    }
+    to := plan.to
+
+    if to.Number == from.Number {
+        return nil, fmt.Errorf("can't transfer to the same account")
+    }
+    if to.ClosedAt != nil {
+        return nil, fmt.Errorf("account %d: %w", to.Number, storage.ErrAccountClosed)
+    }
+
+    plan.entries, plan.converted, err = s.transferEntries(from, to, transferReq.Amount)
+    if err != nil {
+        return nil, err
+    }
+    plan.entries = append(plan.entries, plan.fee...)
+    if plan.rate, err = s.cfg.FXRates.Rate(from.Currency, to.Currency); err != nil {
+        return nil, err
+    }
+
+    plan.decision, err = s.checkFraud(r, from, to, "", nil, transferReq.Amount)
+    if err != nil {
+        return nil, err
+    }
+
+    plan.tx = &types.Transaction{
+        Kind: types.TransactionTransfer,
+        FromAccount: from.Number,
+        ToAccount: to.Number,
+        Amount: transferReq.Amount,
+        CreatedAt: time.Now().UTC(),
+    }
+
+    return plan, nil
+}
+
+// transferFee returns the entries charging from the transfer fee in effect
+// at t, or none when transfers are free.
+func (s *APIServer) transferFee(ctx context.Context, from int64, t time.Time) ([]*types.LedgerEntry, error) {
+    fee, err := products.Lookup(ctx, s.store, types.ProductTransferFee, t, s.cfg.TransferFee)
+    if err != nil || fee <= 0 {
+        return nil, err
+    }
+
+    return types.NewEntries(from, types.FeeAccountNumber, fee), nil
+}
+
+// transferEntries moves amount, in from's currency, to another account. A
+// transfer to an account in another currency is converted and settled
+// through the FX account. It also returns the amount to receives.
+func (s *APIServer) transferEntries(from, to *types.Account, amount int64) ([]*types.LedgerEntry, int64, error) {
+    if from.Currency == to.Currency {
+        return types.NewEntries(from.Number, to.Number, amount), amount, nil
+    }
+
+    converted, err := s.cfg.FXRates.Convert(amount, from.Currency, to.Currency)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    return types.NewFXEntries(from.Number, to.Number, amount, converted), converted, nil
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "gobank/auth"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// withQuota counts the request against the account it is for and turns it
+// away once the account used up its requests for the day. A request isn't
+// refused because its usage couldn't be counted.
+func (s *APIServer) withQuota(handlerFunc http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        account := auth.AccountFromContext(r.Context())
+        now := time.Now().UTC()
+
+        err := s.store.CountRequest(r.Context(), account.Number, types.UsageDay(now), s.cfg.Quotas.RequestsPerDay)
+        if errors.Is(err, storage.ErrQuotaExceeded) {
+            // the quota starts over at midnight UTC
+            reset := types.UsageDay(now).AddDate(0, 0, 1)
+            w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+            writeError(w, r, err)
+            return
+        }
+        if err != nil {
+            s.logger.Printf("counting request of account %d: %v", account.Number, err)
+        }
+
+        handlerFunc(w, r)
+    }
+}
+
+// checkTransferQuota fails with storage.ErrQuotaExceeded when sending
+// amount would take the account over its monthly transfer volume. Transfers
+// sent at the same time can go over it by their own amount.
+func (s *APIServer) checkTransferQuota(ctx context.Context, number, amount int64) error {
+    quota := s.cfg.Quotas.TransferVolumePerMonth
+    if quota == 0 {
+        return nil
+    }
+
+    now := time.Now().UTC()
+    usage, err := s.store.GetUsage(ctx, number, types.UsageMonth(now), types.UsageDay(now).AddDate(0, 0, 1))
+    if err != nil {
+        return err
+    }
+
+    var sent int64
+    for _, u := range usage {
+        sent += u.TransferVolume
+    }
+    if sent+amount > quota {
+        return fmt.Errorf("account %d sent %d of its %d monthly transfer volume: %w", number, sent, quota, storage.ErrQuotaExceeded)
+    }
+
+    return nil
+}
+
+// recordTransferUsage adds a transfer that was sent to the account's usage.
+// Failing to is only logged, the money has moved already.
+func (s *APIServer) recordTransferUsage(ctx context.Context, number, amount int64) {
+    if err := s.store.RecordTransferUsage(ctx, number, types.UsageDay(time.Now()), amount); err != nil {
+        s.logger.Printf("recording transfer usage of account %d: %v", number, err)
+    }
+}
+
+// handleUsage reports the account's usage per day over the same period as
+// the admin reports, the last 30 days by default.
+func (s *APIServer) handleUsage(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    from, to, err := reportPeriod(r)
+    if err != nil {
+        return err
+    }
+
+    number := auth.AccountFromContext(r.Context()).Number
+    days, err := s.store.GetUsage(r.Context(), number, from, to)
+    if err != nil {
+        return err
+    }
+
+    report := &types.UsageReport{
+        AccountNumber: number,
+        From: from,
+        To: to,
+        Total: types.Usage{AccountNumber: number},
+        Days: days,
+        Quotas: s.cfg.Quotas,
+    }
+    for _, d := range days {
+        report.Total.Requests += d.Requests
+        report.Total.Transfers += d.Transfers
+        report.Total.TransferVolume += d.TransferVolume
+    }
+
+    return WriteJSON(w, http.StatusOK, report)
+}
+
+// handleAdminUsage reports every account's usage over the period, for
+// billing, busiest first.
+func (s *APIServer) handleAdminUsage(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "GET" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    from, to, err := reportPeriod(r)
+    if err != nil {
+        return err
+    }
+
+    key := fmt.Sprintf("usage %s %s", from, to)
+    return s.writeReport(w, key, func() (any, error) {
+        return s.store.GetUsageTotals(r.Context(), from, to)
+    })
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+
+    "gobank/metrics"
+    "gobank/types"
+    "gobank/webhook"
+)
+
+var webhooksTotal = metrics.NewCounter("gobank_inbound_webhooks_total", "Inbound settlement webhooks by provider and result.", "provider", "result")
+
+// handleInboundWebhook takes a signed settlement callback from a clearing
+// provider and completes or fails the matching pending transfer. Pending
+// transfers hold their money in the settlement account, so a failure
+// returns it from there to the sender.
+func (s *APIServer) handleInboundWebhook(w http.ResponseWriter, r *http.Request) error {
+    if r.Method != "POST" {
+        return fmt.Errorf("method not allowed %s", r.Method)
+    }
+
+    provider := r.PathValue("provider")
+    body, err := s.readSigned(w, r, provider)
+    if err != nil {
+        return err
+    }
+    if body == nil {
+        webhooksTotal.Inc(provider, "rejected")
+        return nil
+    }
+
+    callback := new(types.SettlementCallback)
+    if err := json.Unmarshal(body, callback); err != nil {
+        return err
+    }
+    if callback.Status != types.StatusCompleted && callback.Status != types.StatusFailed {
+        return fmt.Errorf("status must be %s or %s", types.StatusCompleted, types.StatusFailed)
+    }
+
+    tx, err := s.store.GetTransactionByReference(r.Context(), provider, callback.Reference)
+    if err != nil {
+        return err
+    }
+
+    // providers retry until they see a 2xx, so a repeated callback is fine
+    if tx.Status == callback.Status {
+        webhooksTotal.Inc(provider, "duplicate")
+        return WriteJSON(w, http.StatusOK, tx)
+    }
+
+    var reversal *types.Transaction
+    var entries []*types.LedgerEntry
+    if callback.Status == types.StatusFailed {
+        reversal = &types.Transaction{
+            Kind: types.TransactionReturn,
+            FromAccount: types.SettlementAccountNumber,
+            ToAccount: tx.FromAccount,
+            Amount: tx.Amount,
+            CreatedAt: time.Now().UTC(),
+        }
+        entries = types.NewEntries(types.SettlementAccountNumber, tx.FromAccount, tx.Amount)
+    }
+
+    if err := s.store.SettleTransaction(r.Context(), tx.ID, callback.Status, reversal, entries); err != nil {
+        return err
+    }
+    tx.Status = callback.Status
+    webhooksTotal.Inc(provider, callback.Status)
+
+    return WriteJSON(w, http.StatusOK, tx)
+}
+
+// webhookChannel is the dead letter channel of outbound webhook events.
+const webhookChannel = "webhook"
+
+// publishWebhook sends an event to the outbound webhook, if there is one,
+// in the background. Failed deliveries are retried with exponential
+// backoff, and an event that fails every attempt is kept as a dead letter
+// to be replayed.
+func (s *APIServer) publishWebhook(eventType string, data any) {
+    if s.webhooks == nil {
+        return
+    }
+
+    e := webhook.Event{Type: eventType, CreatedAt: time.Now().UTC(), Data: data}
+    if s.chaos.DropWebhook() {
+        s.logger.Printf("outbound webhook: dropped %s on purpose", eventType)
+        return
+    }
+    body, err := json.Marshal(e)
+    if err != nil {
+        s.logger.Printf("outbound webhook: encoding %s: %v", eventType, err)
+        return
+    }
+    go s.sendWebhook(eventType, body)
+}
+
+func (s *APIServer) sendWebhook(eventType string, body []byte) {
+    var err error
+    wait := s.cfg.OutboundWebhookBackoff
+    attempt := 1
+    for ; ; attempt++ {
+        ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+        err = s.webhooks.Post(ctx, body)
+        cancel()
+
+        if err == nil {
+            return
+        }
+        if attempt >= s.cfg.OutboundWebhookAttempts {
+            break
+        }
+
+        time.Sleep(wait)
+        wait *= 2
+    }
+
+    s.logger.Printf("outbound webhook: giving up on %s: %v", eventType, err)
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+
+    d := &types.DeadLetter{
+        Event: eventType,
+        Channel: webhookChannel,
+        To: s.webhooks.URL(),
+        Body: string(body),
+        Reason: err.Error(),
+        Attempts: attempt,
+        Status: types.DeadLetterPending,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.store.CreateDeadLetter(ctx, d); err != nil {
+        s.logger.Printf("outbound webhook: saving dead letter for %s: %v", eventType, err)
+    }
+}
+
+// replayWebhook makes one more attempt to send a dead lettered event to the
+// outbound webhook.
+func (s *APIServer) replayWebhook(ctx context.Context, d *types.DeadLetter) error {
+    if s.webhooks == nil {
+        return fmt.Errorf("no outbound webhook configured")
+    }
+    return s.webhooks.Post(ctx, []byte(d.Body))
+}
+
+// readSigned reads a request body signed with provider's webhook secret.
+// Unknown providers and bad signatures are answered here, in which case it
+// returns a nil body.
+func (s *APIServer) readSigned(w http.ResponseWriter, r *http.Request, provider string) ([]byte, error) {
+    secret, ok := s.cfg.WebhookSecrets[provider]
+    if !ok {
+        return nil, WriteJSON(w, http.StatusNotFound, ApiError{Error: "unknown webhook provider"})
+    }
+
+    body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+    if err != nil {
+        return nil, err
+    }
+
+    err = webhook.Verify([]byte(secret), r.Header.Get(webhook.SignatureHeader), body, time.Now(), s.cfg.WebhookTolerance)
+    if err != nil {
+        return nil, WriteJSON(w, http.StatusUnauthorized, ApiError{Error: err.Error()})
+    }
+
+    return body, nil
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    jwt "github.com/golang-jwt/jwt/v4"
+    "gobank/types"
+)
+
+// Claims are what a login token says about its holder and the login it
+// came from. The fraud rules compare transfers against IP and Country, and
+// StepUp names the challenge that has to be confirmed before the token can
+// move money.
+type Claims struct {
+    AccountID int `json:"accountId"`
+    AccountNumber int64 `json:"accountNumber"`
+    IP string `json:"ip,omitempty"`
+    Country string `json:"country,omitempty"`
+    StepUp string `json:"stepUp,omitempty"`
+    jwt.RegisteredClaims
+}
+
+// NewToken signs c with secret. The token expires after ttl, or never when
+// it is zero.
+func NewToken(secret []byte, c Claims, ttl time.Duration) (string, error) {
+    if ttl > 0 {
+        c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+    }
+
+    return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(secret)
+}
+
+// ParseToken checks the signature and expiry of token and returns its
+// claims.
+func ParseToken(secret []byte, token string) (*Claims, error) {
+    c := new(Claims)
+    _, err := jwt.ParseWithClaims(token, c, func(token *jwt.Token) (interface{}, error) {
+        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+        }
+
+        return secret, nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    if c.AccountNumber == 0 {
+        return nil, fmt.Errorf("token has no account number")
+    }
+
+    return c, nil
+}
+
+// Identity is who an authenticated request was made by and what it may do.
+type Identity struct {
+    Claims *Claims
+    // Account is the account the request is for. It is another one than
+    // the token holder's when they are one of its other owners or were
+    // granted access to it.
+    Account *types.Account
+    // Grant is the grant the request was let in with, nil when the token
+    // holder owns the account.
+    Grant *types.Grant
+}
+
+type identityCtxKey struct{}
+
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+    return context.WithValue(ctx, identityCtxKey{}, id)
+}
+
+// IdentityFromContext returns the identity of an authenticated request, or
+// nil when it wasn't authenticated.
+func IdentityFromContext(ctx context.Context) *Identity {
+    id, _ := ctx.Value(identityCtxKey{}).(*Identity)
+    return id
+}
+
+// ClaimsFromContext returns the claims of the request's token, or empty
+// claims when it wasn't authenticated.
+func ClaimsFromContext(ctx context.Context) *Claims {
+    if id := IdentityFromContext(ctx); id != nil {
+        return id.Claims
+    }
+    return &Claims{}
+}
+
+// AccountFromContext returns the account the request is for.
+func AccountFromContext(ctx context.Context) *types.Account {
+    if id := IdentityFromContext(ctx); id != nil {
+        return id.Account
+    }
+    return nil
+}
+
+// ActorFromContext returns the account number of the token holder, which
+// is another owner than the account's own holder on joint accounts.
+func ActorFromContext(ctx context.Context) int64 {
+    return ClaimsFromContext(ctx).AccountNumber
+}
+
+// GrantFromContext returns the grant the request was let in with, or nil
+// if the token holder owns the account.
+func GrantFromContext(ctx context.Context) *types.Grant {
+    if id := IdentityFromContext(ctx); id != nil {
+        return id.Grant
+    }
+    return nil
+}
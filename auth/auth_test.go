@@ -0,0 +1,58 @@
+package auth
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    jwt "github.com/golang-jwt/jwt/v4"
+    "github.com/stretchr/testify/assert"
+    "gobank/types"
+)
+
+func TestParseToken(t *testing.T) {
+    secret := []byte("s3cret")
+    token, err := NewToken(secret, Claims{AccountID: 1, AccountNumber: 42, IP: "10.0.0.1", StepUp: "c1"}, time.Hour)
+    if !assert.NoError(t, err) {
+        return
+    }
+
+    c, err := ParseToken(secret, token)
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, 1, c.AccountID)
+    assert.Equal(t, int64(42), c.AccountNumber)
+    assert.Equal(t, "10.0.0.1", c.IP)
+    assert.Equal(t, "c1", c.StepUp)
+
+    _, err = ParseToken([]byte("other"), token)
+    assert.Error(t, err)
+
+    expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+        AccountNumber: 42,
+        RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+    }).SignedString(secret)
+    _, err = ParseToken(secret, expired)
+    assert.Error(t, err)
+
+    // tokens from before the typed claims only carry the account number
+    old, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"accountNumber": 42, "ip": "10.0.0.1"}).SignedString(secret)
+    c, err = ParseToken(secret, old)
+    if assert.NoError(t, err) {
+        assert.Equal(t, int64(42), c.AccountNumber)
+    }
+}
+
+func TestIdentityFromContext(t *testing.T) {
+    ctx := context.Background()
+    assert.Nil(t, AccountFromContext(ctx))
+    assert.Equal(t, int64(0), ActorFromContext(ctx))
+
+    acc := &types.Account{Number: 7}
+    grant := &types.Grant{AccountNumber: 7, GranteeNumber: 42}
+    ctx = WithIdentity(ctx, &Identity{Claims: &Claims{AccountNumber: 42}, Account: acc, Grant: grant})
+    assert.Equal(t, acc, AccountFromContext(ctx))
+    assert.Equal(t, int64(42), ActorFromContext(ctx))
+    assert.Equal(t, grant, GrantFromContext(ctx))
+}
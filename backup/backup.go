@@ -0,0 +1,903 @@
+package backup
+
+import (
+    "archive/tar"
+    "compress/gzip"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "sort"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+// formatVersion 3 added everything besides accounts and transactions,
+// version 4 the audit log, loans, disputes, reversals, alias claims,
+// external transfers, payment requests, fraud cases, product rates,
+// documents and when accounts went dormant or were closed. Older archives
+// still restore, without those.
+const formatVersion = 4
+
+type Manifest struct {
+    Version int `json:"version"`
+    CreatedAt time.Time `json:"createdAt"`
+    Accounts int `json:"accounts"`
+    Transactions int `json:"transactions"`
+    LedgerEntries int `json:"ledgerEntries"`
+    Aliases int `json:"aliases"`
+    Cards int `json:"cards"`
+    Holds int `json:"holds"`
+    Pots int `json:"pots"`
+    Owners int `json:"owners"`
+    OwnerInvitations int `json:"ownerInvitations"`
+    AccountInvitations int `json:"accountInvitations"`
+    AlertRules int `json:"alertRules"`
+    Grants int `json:"grants"`
+    SigningKeys int `json:"signingKeys"`
+    Preferences int `json:"preferences"`
+    AuditEntries int `json:"auditEntries"`
+    Loans int `json:"loans"`
+    Disputes int `json:"disputes"`
+    Reversals int `json:"reversals"`
+    AliasClaims int `json:"aliasClaims"`
+    ExternalTransfers int `json:"externalTransfers"`
+    PaymentRequests int `json:"paymentRequests"`
+    FraudCases int `json:"fraudCases"`
+    ProductRates int `json:"productRates"`
+    Documents int `json:"documents"`
+}
+
+// accountRecord keeps the password hash and the dormancy notice, which
+// types.Account never serializes.
+type accountRecord struct {
+    FirstName string `json:"firstName"`
+    LastName string `json:"lastName"`
+    Email string `json:"email"`
+    Phone string `json:"phone"`
+    PhoneVerified bool `json:"phoneVerified"`
+    EncryptedPassword string `json:"encryptedPassword"`
+    Number int64 `json:"number"`
+    Balance int64 `json:"balance"`
+    Currency string `json:"currency"`
+    Nickname string `json:"nickname,omitempty"`
+    Locale string `json:"locale,omitempty"`
+    Metadata map[string]string `json:"metadata,omitempty"`
+    DormancyNoticeAt *time.Time `json:"dormancyNoticeAt,omitempty"`
+    DormantSince *time.Time `json:"dormantSince,omitempty"`
+    ClosedAt *time.Time `json:"closedAt,omitempty"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+// cardRecord keeps the card secrets types.Card never serializes.
+type cardRecord struct {
+    *types.Card
+    PANHash string `json:"panHash"`
+    PINHash string `json:"pinHash,omitempty"`
+    EncryptedPAN string `json:"encryptedPan"`
+    EncryptedCVV string `json:"encryptedCvv"`
+    EncryptedExpiry string `json:"encryptedExpiry"`
+}
+
+type accountInvitationRecord struct {
+    *types.AccountInvitation
+    TokenHash string `json:"tokenHash"`
+}
+
+type signingKeyRecord struct {
+    *types.SigningKey
+    EncryptedSecret string `json:"encryptedSecret"`
+}
+
+type loanRecord struct {
+    *types.Loan
+    Schedule []*types.LoanInstallment `json:"schedule"`
+}
+
+// documentRecord keeps the key of the document's blob. The blobs stay in
+// the blob store and aren't part of the backup.
+type documentRecord struct {
+    *types.Document
+    BlobKey string `json:"blobKey"`
+}
+
+// archive is the content of a backup besides its manifest. Only active
+// holds, pending invitations and pending alias claims are kept; the
+// others are history whose effects the transactions and owners already
+// carry.
+type archive struct {
+    accounts []accountRecord
+    txs []*types.Transaction
+    entries []*types.LedgerEntry
+    aliases []*types.Alias
+    cards []cardRecord
+    holds []*types.Hold
+    pots []*types.Pot
+    owners []*types.AccountOwner
+    ownerInvitations []*types.OwnerInvitation
+    accountInvitations []accountInvitationRecord
+    alertRules []*types.AlertRule
+    grants []*types.Grant
+    signingKeys []signingKeyRecord
+    preferences []*types.NotificationPreferences
+    audit []*types.AuditEntry
+    loans []loanRecord
+    disputes []*types.Dispute
+    reversals []*types.Reversal
+    aliasClaims []*types.AliasClaim
+    externalTransfers []*types.ExternalTransfer
+    paymentRequests []*types.PaymentRequest
+    fraudCases []*types.FraudCase
+    productRates []*types.ProductRate
+    documents []documentRecord
+}
+
+type file struct {
+    name string
+    v any
+}
+
+// files maps the archive's files to the slices holding them.
+func (a *archive) files() []file {
+    return []file{
+        {"accounts.json", &a.accounts},
+        {"transactions.json", &a.txs},
+        {"ledger.json", &a.entries},
+        {"aliases.json", &a.aliases},
+        {"cards.json", &a.cards},
+        {"holds.json", &a.holds},
+        {"pots.json", &a.pots},
+        {"owners.json", &a.owners},
+        {"owner_invitations.json", &a.ownerInvitations},
+        {"account_invitations.json", &a.accountInvitations},
+        {"alert_rules.json", &a.alertRules},
+        {"grants.json", &a.grants},
+        {"signing_keys.json", &a.signingKeys},
+        {"preferences.json", &a.preferences},
+        {"audit.json", &a.audit},
+        {"loans.json", &a.loans},
+        {"disputes.json", &a.disputes},
+        {"reversals.json", &a.reversals},
+        {"alias_claims.json", &a.aliasClaims},
+        {"external_transfers.json", &a.externalTransfers},
+        {"payment_requests.json", &a.paymentRequests},
+        {"fraud_cases.json", &a.fraudCases},
+        {"product_rates.json", &a.productRates},
+        {"documents.json", &a.documents},
+    }
+}
+
+func (a *archive) counts() Manifest {
+    return Manifest{
+        Accounts: len(a.accounts),
+        Transactions: len(a.txs),
+        LedgerEntries: len(a.entries),
+        Aliases: len(a.aliases),
+        Cards: len(a.cards),
+        Holds: len(a.holds),
+        Pots: len(a.pots),
+        Owners: len(a.owners),
+        OwnerInvitations: len(a.ownerInvitations),
+        AccountInvitations: len(a.accountInvitations),
+        AlertRules: len(a.alertRules),
+        Grants: len(a.grants),
+        SigningKeys: len(a.signingKeys),
+        Preferences: len(a.preferences),
+        AuditEntries: len(a.audit),
+        Loans: len(a.loans),
+        Disputes: len(a.disputes),
+        Reversals: len(a.reversals),
+        AliasClaims: len(a.aliasClaims),
+        ExternalTransfers: len(a.externalTransfers),
+        PaymentRequests: len(a.paymentRequests),
+        FraudCases: len(a.fraudCases),
+        ProductRates: len(a.productRates),
+        Documents: len(a.documents),
+    }
+}
+
+// Export writes the whole dataset of store to w as a gzipped tar archive
+// holding one JSON document per entity plus a manifest.
+func Export(ctx context.Context, store storage.Storage, w io.Writer) (*Manifest, error) {
+    a, err := collect(ctx, store)
+    if err != nil {
+        return nil, err
+    }
+
+    manifest := a.counts()
+    manifest.Version = formatVersion
+    manifest.CreatedAt = time.Now().UTC()
+
+    gz := gzip.NewWriter(w)
+    tw := tar.NewWriter(gz)
+
+    if err := writeEntry(tw, "manifest.json", manifest); err != nil {
+        return nil, err
+    }
+    for _, f := range a.files() {
+        if err := writeEntry(tw, f.name, f.v); err != nil {
+            return nil, err
+        }
+    }
+
+    if err := tw.Close(); err != nil {
+        return nil, err
+    }
+    if err := gz.Close(); err != nil {
+        return nil, err
+    }
+
+    return &manifest, nil
+}
+
+func collect(ctx context.Context, store storage.Storage) (*archive, error) {
+    accounts, err := store.GetAccounts(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    a := &archive{}
+    if a.txs, err = store.GetTransactions(ctx); err != nil {
+        return nil, err
+    }
+    if a.entries, err = store.GetLedgerEntries(ctx); err != nil {
+        return nil, err
+    }
+
+    // admins invite as account 0
+    invitations, err := store.GetAccountInvitations(ctx, 0)
+    if err != nil {
+        return nil, err
+    }
+    a.addAccountInvitations(invitations)
+
+    if a.reversals, err = store.GetReversals(ctx); err != nil {
+        return nil, err
+    }
+    if a.aliasClaims, err = store.GetPendingAliasClaims(ctx, ""); err != nil {
+        return nil, err
+    }
+    if a.productRates, err = store.GetProductRates(ctx); err != nil {
+        return nil, err
+    }
+    for _, status := range []string{types.FraudCasePending, types.FraudCasePosted, types.FraudCaseApproved, types.FraudCaseRejected} {
+        cases, err := store.GetFraudCasesByStatus(ctx, status)
+        if err != nil {
+            return nil, err
+        }
+        a.fraudCases = append(a.fraudCases, cases...)
+    }
+
+    now := time.Now().UTC()
+    paymentRequests := map[int]bool{}
+    for _, acc := range accounts {
+        a.accounts = append(a.accounts, accountRecord{
+            FirstName: acc.FirstName,
+            LastName: acc.LastName,
+            Email: acc.Email,
+            Phone: acc.Phone,
+            PhoneVerified: acc.PhoneVerified,
+            EncryptedPassword: acc.EncryptedPassword,
+            Number: acc.Number,
+            Balance: acc.Balance,
+            Currency: acc.Currency,
+            Nickname: acc.Nickname,
+            Locale: acc.Locale,
+            Metadata: acc.Metadata,
+            DormancyNoticeAt: acc.DormancyNoticeAt,
+            DormantSince: acc.DormantSince,
+            ClosedAt: acc.ClosedAt,
+            CreatedAt: acc.CreatedAt,
+        })
+
+        n := acc.Number
+        aliases, err := store.GetAliasesByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.aliases = append(a.aliases, aliases...)
+
+        cards, err := store.GetCardsByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        for _, c := range cards {
+            a.cards = append(a.cards, cardRecord{
+                Card: c,
+                PANHash: c.PANHash,
+                PINHash: c.PINHash,
+                EncryptedPAN: c.EncryptedPAN,
+                EncryptedCVV: c.EncryptedCVV,
+                EncryptedExpiry: c.EncryptedExpiry,
+            })
+        }
+
+        holds, err := store.GetActiveHolds(ctx, n, now)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.holds = append(a.holds, holds...)
+
+        pots, err := store.GetPotsByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.pots = append(a.pots, pots...)
+
+        owners, err := store.GetAccountOwners(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.owners = append(a.owners, owners...)
+
+        ownerInvitations, err := store.GetOwnerInvitationsFor(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.ownerInvitations = append(a.ownerInvitations, ownerInvitations...)
+
+        invitations, err := store.GetAccountInvitations(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.addAccountInvitations(invitations)
+
+        rules, err := store.GetAlertRules(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.alertRules = append(a.alertRules, rules...)
+
+        grants, err := store.GetGrantsByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.grants = append(a.grants, grants...)
+
+        keys, err := store.GetSigningKeysByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        for _, k := range keys {
+            a.signingKeys = append(a.signingKeys, signingKeyRecord{SigningKey: k, EncryptedSecret: k.EncryptedSecret})
+        }
+
+        prefs, err := store.GetNotificationPreferences(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.preferences = append(a.preferences, prefs)
+
+        audit, err := store.GetAuditLog(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.audit = append(a.audit, audit...)
+
+        loans, err := store.GetLoansByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        for _, l := range loans {
+            schedule, err := store.GetLoanSchedule(ctx, l.ID)
+            if err != nil {
+                return nil, fmt.Errorf("loan %d: %w", l.ID, err)
+            }
+            a.loans = append(a.loans, loanRecord{Loan: l, Schedule: schedule})
+        }
+
+        disputes, err := store.GetDisputesByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.disputes = append(a.disputes, disputes...)
+
+        transfers, err := store.GetExternalTransfersByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        a.externalTransfers = append(a.externalTransfers, transfers...)
+
+        // both the requester and the payer see a request
+        requests, err := store.GetPaymentRequestsByAccount(ctx, n)
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        for _, pr := range requests {
+            if !paymentRequests[pr.ID] {
+                paymentRequests[pr.ID] = true
+                a.paymentRequests = append(a.paymentRequests, pr)
+            }
+        }
+
+        documents, err := store.GetDocumentsByAccount(ctx, n, "")
+        if err != nil {
+            return nil, fmt.Errorf("account %d: %w", n, err)
+        }
+        for _, d := range documents {
+            a.documents = append(a.documents, documentRecord{Document: d, BlobKey: d.BlobKey})
+        }
+    }
+
+    // restored in the order they were created
+    sort.Slice(a.audit, func(i, j int) bool { return a.audit[i].ID < a.audit[j].ID })
+    sort.Slice(a.paymentRequests, func(i, j int) bool { return a.paymentRequests[i].ID < a.paymentRequests[j].ID })
+
+    return a, nil
+}
+
+func (a *archive) addAccountInvitations(invitations []*types.AccountInvitation) {
+    for _, inv := range invitations {
+        if inv.Status == types.InvitationPending {
+            a.accountInvitations = append(a.accountInvitations, accountInvitationRecord{AccountInvitation: inv, TokenHash: inv.TokenHash})
+        }
+    }
+}
+
+// Restore loads an archive written by Export into store, which must not
+// contain any accounts or transactions yet. Transactions are posted again
+// with their ledger entries so balances are rebuilt by the target store.
+// Any difference between an account's archived balance and its entries,
+// e.g. from data that predates the ledger, is posted as an opening balance
+// from the suspense account. Everything else is created again after the
+// transactions, so pots and holds are backed by the restored balances,
+// and moved to the new IDs of the transactions it refers to. When the
+// restore fails part way, everything it created is deleted again.
+func Restore(ctx context.Context, store storage.Storage, r io.Reader) (*Manifest, error) {
+    if err := ensureEmpty(ctx, store); err != nil {
+        return nil, err
+    }
+
+    gz, err := gzip.NewReader(r)
+    if err != nil {
+        return nil, err
+    }
+    defer gz.Close()
+
+    var (
+        manifest *Manifest
+        a archive
+    )
+    files := map[string]any{}
+    for _, f := range a.files() {
+        files[f.name] = f.v
+    }
+
+    tr := tar.NewReader(gz)
+    for {
+        hdr, err := tr.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+
+        v := files[hdr.Name]
+        if hdr.Name == "manifest.json" {
+            manifest = new(Manifest)
+            v = manifest
+        }
+        if v == nil {
+            continue
+        }
+        if err := json.NewDecoder(tr).Decode(v); err != nil {
+            return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+        }
+    }
+
+    if manifest == nil {
+        return nil, fmt.Errorf("archive has no manifest")
+    }
+    if manifest.Version < 2 || manifest.Version > formatVersion {
+        return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+    }
+    counts := a.counts()
+    counts.Version, counts.CreatedAt = manifest.Version, manifest.CreatedAt
+    if counts != *manifest {
+        return nil, fmt.Errorf("archive does not match its manifest")
+    }
+
+    if err := restore(ctx, store, &a); err != nil {
+        // the store was empty before, so it can be emptied again
+        if clearErr := store.ClearRestored(context.WithoutCancel(ctx)); clearErr != nil {
+            return nil, errors.Join(err, fmt.Errorf("clearing the partial restore: %w", clearErr))
+        }
+        return nil, err
+    }
+
+    return manifest, nil
+}
+
+func restore(ctx context.Context, store storage.Storage, a *archive) error {
+    txIDs, err := restoreLedger(ctx, store, a)
+    if err != nil {
+        return err
+    }
+    if err := restoreRest(ctx, store, a); err != nil {
+        return err
+    }
+    return restoreHistory(ctx, store, a, txIDs)
+}
+
+// transactionIDs maps the IDs transactions had in the archive to their
+// restored IDs.
+type transactionIDs map[int]int
+
+func (ids transactionIDs) get(id int) (int, error) {
+    restored, ok := ids[id]
+    if !ok {
+        return 0, fmt.Errorf("transaction %d is not in the archive", id)
+    }
+    return restored, nil
+}
+
+func restoreLedger(ctx context.Context, store storage.Storage, a *archive) (transactionIDs, error) {
+    byTransaction := map[int][]*types.LedgerEntry{}
+    netBalance := map[int64]int64{}
+    for _, e := range a.entries {
+        byTransaction[e.TransactionID] = append(byTransaction[e.TransactionID], e)
+        netBalance[e.AccountNumber] += e.Amount
+    }
+
+    for _, rec := range a.accounts {
+        if rec.Currency == "" {
+            rec.Currency = "USD"
+        }
+
+        acc := &types.Account{
+            FirstName: rec.FirstName,
+            LastName: rec.LastName,
+            Email: rec.Email,
+            Phone: rec.Phone,
+            PhoneVerified: rec.PhoneVerified,
+            EncryptedPassword: rec.EncryptedPassword,
+            Number: rec.Number,
+            Currency: rec.Currency,
+            Nickname: rec.Nickname,
+            Locale: rec.Locale,
+            Metadata: rec.Metadata,
+            DormancyNoticeAt: rec.DormancyNoticeAt,
+            DormantSince: rec.DormantSince,
+            ClosedAt: rec.ClosedAt,
+            CreatedAt: rec.CreatedAt,
+        }
+        if err := store.CreateAccount(ctx, acc); err != nil {
+            return nil, fmt.Errorf("account %d: %w", rec.Number, err)
+        }
+
+        if diff := rec.Balance - netBalance[rec.Number]; diff != 0 {
+            opening := &types.Transaction{
+                Kind: types.TransactionOpening,
+                FromAccount: types.SuspenseAccountNumber,
+                ToAccount: acc.Number,
+                Amount: diff,
+                CreatedAt: acc.CreatedAt,
+            }
+            entries := types.NewEntries(types.SuspenseAccountNumber, acc.Number, diff)
+            if err := store.PostTransaction(ctx, opening, entries); err != nil {
+                return nil, fmt.Errorf("account %d: opening balance: %w", rec.Number, err)
+            }
+        }
+    }
+
+    txIDs := transactionIDs{}
+    for _, tx := range a.txs {
+        archivedID := tx.ID
+        txEntries := byTransaction[archivedID]
+        for _, e := range txEntries {
+            e.ID = 0
+        }
+
+        var err error
+        if len(txEntries) == 0 {
+            err = store.CreateTransaction(ctx, tx)
+        } else {
+            err = store.PostTransaction(ctx, tx, txEntries)
+        }
+        if err != nil {
+            return nil, fmt.Errorf("transaction %d: %w", archivedID, err)
+        }
+        txIDs[archivedID] = tx.ID
+    }
+
+    return txIDs, nil
+}
+
+// restoreRest creates everything besides accounts and transactions. IDs
+// change, so holds are moved to the new IDs of their cards.
+func restoreRest(ctx context.Context, store storage.Storage, a *archive) error {
+    for _, al := range a.aliases {
+        if err := store.CreateAlias(ctx, al); err != nil {
+            return fmt.Errorf("alias %s: %w", al.Alias, err)
+        }
+    }
+
+    cardIDs := map[int]int{}
+    for _, rec := range a.cards {
+        c := rec.Card
+        archivedID := c.ID
+        c.PANHash = rec.PANHash
+        c.EncryptedPAN = rec.EncryptedPAN
+        c.EncryptedCVV = rec.EncryptedCVV
+        c.EncryptedExpiry = rec.EncryptedExpiry
+        if err := store.CreateCard(ctx, c); err != nil {
+            return fmt.Errorf("card %d: %w", archivedID, err)
+        }
+        if rec.PINHash != "" {
+            if err := store.SetCardPIN(ctx, c.ID, rec.PINHash); err != nil {
+                return fmt.Errorf("card %d: %w", archivedID, err)
+            }
+        }
+        cardIDs[archivedID] = c.ID
+    }
+
+    for _, p := range a.pots {
+        archivedID, balance := p.ID, p.Balance
+        p.Balance = 0
+        if err := store.CreatePot(ctx, p); err != nil {
+            return fmt.Errorf("pot %d: %w", archivedID, err)
+        }
+        if balance > 0 {
+            if _, err := store.MovePotMoney(ctx, p.ID, balance); err != nil {
+                return fmt.Errorf("pot %d: %w", archivedID, err)
+            }
+        }
+    }
+
+    for _, h := range a.holds {
+        archivedID := h.ID
+        h.CardID = cardIDs[h.CardID]
+        // the card's daily limit applied when the hold was placed
+        if err := store.PlaceHold(ctx, h, 0); err != nil {
+            return fmt.Errorf("hold %d: %w", archivedID, err)
+        }
+    }
+
+    // an owner is restored by accepting an invitation for it
+    for _, o := range a.owners {
+        inv := &types.OwnerInvitation{
+            AccountNumber: o.AccountNumber,
+            InviteeNumber: o.OwnerNumber,
+            Permission: o.Permission,
+            Status: types.InvitationPending,
+            CreatedAt: o.CreatedAt,
+        }
+        if err := store.CreateOwnerInvitation(ctx, inv); err != nil {
+            return fmt.Errorf("owner %d of %d: %w", o.OwnerNumber, o.AccountNumber, err)
+        }
+        if _, err := store.AcceptOwnerInvitation(ctx, inv.ID); err != nil {
+            return fmt.Errorf("owner %d of %d: %w", o.OwnerNumber, o.AccountNumber, err)
+        }
+    }
+
+    for _, inv := range a.ownerInvitations {
+        archivedID := inv.ID
+        if err := store.CreateOwnerInvitation(ctx, inv); err != nil {
+            return fmt.Errorf("owner invitation %d: %w", archivedID, err)
+        }
+    }
+
+    for _, rec := range a.accountInvitations {
+        inv := rec.AccountInvitation
+        archivedID := inv.ID
+        inv.TokenHash = rec.TokenHash
+        if err := store.CreateAccountInvitation(ctx, inv); err != nil {
+            return fmt.Errorf("account invitation %d: %w", archivedID, err)
+        }
+    }
+
+    for _, rule := range a.alertRules {
+        archivedID := rule.ID
+        if err := store.CreateAlertRule(ctx, rule); err != nil {
+            return fmt.Errorf("alert rule %d: %w", archivedID, err)
+        }
+    }
+
+    for _, g := range a.grants {
+        archivedID, revokedAt := g.ID, g.RevokedAt
+        g.RevokedAt = nil
+        if err := store.CreateGrant(ctx, g); err != nil {
+            return fmt.Errorf("grant %d: %w", archivedID, err)
+        }
+        if revokedAt != nil {
+            if err := store.RevokeGrant(ctx, g.ID, *revokedAt); err != nil {
+                return fmt.Errorf("grant %d: %w", archivedID, err)
+            }
+        }
+    }
+
+    for _, rec := range a.signingKeys {
+        k := rec.SigningKey
+        k.EncryptedSecret = rec.EncryptedSecret
+        if err := store.CreateSigningKey(ctx, k); err != nil {
+            return fmt.Errorf("signing key %s: %w", k.ID, err)
+        }
+    }
+
+    locales := map[int64]string{}
+    for _, rec := range a.accounts {
+        locales[rec.Number] = rec.Locale
+    }
+    for _, prefs := range a.preferences {
+        p := &types.Preferences{NotificationPreferences: *prefs, Locale: locales[prefs.AccountNumber]}
+        if err := store.SavePreferences(ctx, p); err != nil {
+            return fmt.Errorf("account %d: preferences: %w", prefs.AccountNumber, err)
+        }
+    }
+
+    return nil
+}
+
+// restoreHistory creates what refers to transactions, moving it to their
+// restored IDs. Nothing is posted again: the transactions already carry
+// the money it moved or holds pending.
+func restoreHistory(ctx context.Context, store storage.Storage, a *archive, txIDs transactionIDs) error {
+    for _, rate := range a.productRates {
+        archivedID := rate.ID
+        if err := store.CreateProductRate(ctx, rate); err != nil {
+            return fmt.Errorf("product rate %d: %w", archivedID, err)
+        }
+    }
+
+    for _, e := range a.audit {
+        archivedID := e.ID
+        if err := store.RecordAudit(ctx, e); err != nil {
+            return fmt.Errorf("audit entry %d: %w", archivedID, err)
+        }
+    }
+
+    documentIDs := map[int]int{}
+    for _, rec := range a.documents {
+        d := rec.Document
+        archivedID := d.ID
+        d.BlobKey = rec.BlobKey
+        if err := store.CreateDocument(ctx, d); err != nil {
+            return fmt.Errorf("document %d: %w", archivedID, err)
+        }
+        documentIDs[archivedID] = d.ID
+    }
+
+    for _, rec := range a.loans {
+        archivedID := rec.ID
+        if err := store.RestoreLoan(ctx, rec.Loan, rec.Schedule); err != nil {
+            return fmt.Errorf("loan %d: %w", archivedID, err)
+        }
+    }
+
+    for _, d := range a.disputes {
+        archivedID := d.ID
+        if err := moveDispute(d, txIDs, documentIDs); err != nil {
+            return fmt.Errorf("dispute %d: %w", archivedID, err)
+        }
+        if err := store.RestoreDispute(ctx, d); err != nil {
+            return fmt.Errorf("dispute %d: %w", archivedID, err)
+        }
+    }
+
+    for _, r := range a.reversals {
+        archivedID, reversalID := r.TransactionID, r.ReversalID
+        var err error
+        if r.TransactionID, err = txIDs.get(archivedID); err != nil {
+            return fmt.Errorf("reversal of transaction %d: %w", archivedID, err)
+        }
+        if r.ReversalID, err = txIDs.get(reversalID); err != nil {
+            return fmt.Errorf("reversal of transaction %d: %w", archivedID, err)
+        }
+        if err := store.RestoreReversal(ctx, r); err != nil {
+            return fmt.Errorf("reversal of transaction %d: %w", archivedID, err)
+        }
+    }
+
+    for _, c := range a.aliasClaims {
+        archivedID := c.TransactionID
+        var err error
+        if c.TransactionID, err = txIDs.get(archivedID); err != nil {
+            return fmt.Errorf("alias claim %d: %w", archivedID, err)
+        }
+        if err := store.RestoreAliasClaim(ctx, c); err != nil {
+            return fmt.Errorf("alias claim %d: %w", archivedID, err)
+        }
+    }
+
+    for _, et := range a.externalTransfers {
+        archivedID := et.ID
+        var err error
+        if et.ID, err = txIDs.get(archivedID); err != nil {
+            return fmt.Errorf("external transfer %d: %w", archivedID, err)
+        }
+        if err := store.RestoreExternalTransfer(ctx, et); err != nil {
+            return fmt.Errorf("external transfer %d: %w", archivedID, err)
+        }
+    }
+
+    for _, pr := range a.paymentRequests {
+        archivedID := pr.ID
+        if pr.TransactionID != 0 {
+            var err error
+            if pr.TransactionID, err = txIDs.get(pr.TransactionID); err != nil {
+                return fmt.Errorf("payment request %d: %w", archivedID, err)
+            }
+        }
+        if err := store.RestorePaymentRequest(ctx, pr); err != nil {
+            return fmt.Errorf("payment request %d: %w", archivedID, err)
+        }
+    }
+
+    for _, c := range a.fraudCases {
+        archivedID := c.ID
+        if c.TransactionID != nil {
+            txID, err := txIDs.get(*c.TransactionID)
+            if err != nil {
+                return fmt.Errorf("fraud case %d: %w", archivedID, err)
+            }
+            c.TransactionID = &txID
+        }
+        if err := store.RestoreFraudCase(ctx, c); err != nil {
+            return fmt.Errorf("fraud case %d: %w", archivedID, err)
+        }
+    }
+
+    return nil
+}
+
+// moveDispute moves d to the restored IDs of its transactions and
+// evidence documents.
+func moveDispute(d *types.Dispute, txIDs transactionIDs, documentIDs map[int]int) error {
+    var err error
+    if d.TransactionID, err = txIDs.get(d.TransactionID); err != nil {
+        return err
+    }
+    if d.ReversalID != nil {
+        reversalID, err := txIDs.get(*d.ReversalID)
+        if err != nil {
+            return err
+        }
+        d.ReversalID = &reversalID
+    }
+    for i, id := range d.Documents {
+        d.Documents[i] = documentIDs[id]
+    }
+    return nil
+}
+
+func ensureEmpty(ctx context.Context, store storage.Storage) error {
+    accounts, err := store.GetAccounts(ctx)
+    if err != nil {
+        return err
+    }
+
+    txs, err := store.GetTransactions(ctx)
+    if err != nil {
+        return err
+    }
+
+    if len(accounts) > 0 || len(txs) > 0 {
+        return fmt.Errorf("restore needs an empty store, found %d accounts and %d transactions", len(accounts), len(txs))
+    }
+
+    return nil
+}
+
+func writeEntry(tw *tar.Writer, name string, v any) error {
+    data, err := json.MarshalIndent(v, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    hdr := &tar.Header{
+        Name: name,
+        Mode: 0600,
+        Size: int64(len(data)),
+        ModTime: time.Now().UTC(),
+    }
+    if err := tw.WriteHeader(hdr); err != nil {
+        return err
+    }
+
+    _, err = tw.Write(data)
+    return err
+}
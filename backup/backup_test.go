@@ -0,0 +1,267 @@
+package backup
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestRoundTrip(t *testing.T) {
+    ctx := context.Background()
+    now := time.Now().UTC().Truncate(time.Second)
+    src := storagetest.New()
+
+    alice := &types.Account{FirstName: "alice", LastName: "a", Number: 1001, Currency: "USD", Locale: "de", CreatedAt: now}
+    alice.EncryptedPassword, _ = types.HashPassword("pw")
+    bob := &types.Account{FirstName: "bob", LastName: "b", Number: 1002, Currency: "USD", CreatedAt: now}
+    assert.Nil(t, src.CreateAccount(ctx, alice))
+    assert.Nil(t, src.CreateAccount(ctx, bob))
+
+    deposit := &types.Transaction{Kind: types.TransactionOpening, FromAccount: types.SuspenseAccountNumber, ToAccount: 1001, Amount: 10000, CreatedAt: now}
+    assert.Nil(t, src.PostTransaction(ctx, deposit, types.NewEntries(types.SuspenseAccountNumber, 1001, 10000)))
+
+    assert.Nil(t, src.CreateAlias(ctx, &types.Alias{Alias: "alice@example.com", Kind: "email", AccountNumber: 1001, CreatedAt: now}))
+
+    card := &types.Card{AccountNumber: 1001, Last4: "4242", Status: types.CardActive, DailyLimit: 5000, CreatedAt: now, PANHash: "pan-hash", EncryptedPAN: "pan"}
+    assert.Nil(t, src.CreateCard(ctx, card))
+    assert.Nil(t, src.SetCardPIN(ctx, card.ID, "pin-hash"))
+    hold := &types.Hold{AccountNumber: 1001, CardID: card.ID, Amount: 1500, Merchant: "shop", Status: types.HoldActive, ExpiresAt: now.Add(24 * time.Hour), CreatedAt: now}
+    assert.Nil(t, src.PlaceHold(ctx, hold, card.DailyLimit))
+
+    pot := &types.Pot{AccountNumber: 1001, Name: "rainy day", Target: 5000, CreatedAt: now}
+    assert.Nil(t, src.CreatePot(ctx, pot))
+    _, err := src.MovePotMoney(ctx, pot.ID, 2000)
+    assert.Nil(t, err)
+
+    accepted := &types.OwnerInvitation{AccountNumber: 1001, InviteeNumber: 1002, Permission: "view", Status: types.InvitationPending, CreatedAt: now}
+    assert.Nil(t, src.CreateOwnerInvitation(ctx, accepted))
+    _, err = src.AcceptOwnerInvitation(ctx, accepted.ID)
+    assert.Nil(t, err)
+    pending := &types.OwnerInvitation{AccountNumber: 1002, InviteeNumber: 1001, Permission: "view", Status: types.InvitationPending, CreatedAt: now}
+    assert.Nil(t, src.CreateOwnerInvitation(ctx, pending))
+
+    invitation := &types.AccountInvitation{Email: "carol@example.com", InvitedBy: 1001, Status: types.InvitationPending, TokenHash: "token-hash", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+    assert.Nil(t, src.CreateAccountInvitation(ctx, invitation))
+
+    assert.Nil(t, src.CreateAlertRule(ctx, &types.AlertRule{AccountNumber: 1001, Kind: "large_transaction", Threshold: 500, CreatedAt: now}))
+    grant := &types.Grant{AccountNumber: 1001, GranteeNumber: 1002, Scopes: []string{"read"}, CreatedAt: now}
+    assert.Nil(t, src.CreateGrant(ctx, grant))
+    assert.Nil(t, src.RevokeGrant(ctx, grant.ID, now.Add(time.Minute)))
+    assert.Nil(t, src.CreateSigningKey(ctx, &types.SigningKey{ID: "key-1", AccountNumber: 1001, EncryptedSecret: "secret", CreatedAt: now}))
+
+    prefs, err := src.GetNotificationPreferences(ctx, 1001)
+    assert.Nil(t, err)
+    prefs.SMS = true
+    prefs.MarketingOptIn = true
+    assert.Nil(t, src.SavePreferences(ctx, &types.Preferences{NotificationPreferences: *prefs, Locale: "de"}))
+
+    carol := &types.Account{FirstName: "carol", LastName: "c", Number: 1003, Currency: "USD", CreatedAt: now}
+    assert.Nil(t, src.CreateAccount(ctx, carol))
+    assert.Nil(t, src.CloseAccount(ctx, 1003, nil, nil, nil, now))
+    assert.Nil(t, src.SetDormancy(ctx, 1002, &now, nil))
+
+    disputed := &types.Transaction{Kind: types.TransactionTransfer, FromAccount: 1001, ToAccount: 1002, Amount: 1000, CreatedAt: now}
+    assert.Nil(t, src.PostTransaction(ctx, disputed, types.NewEntries(1001, 1002, 1000)))
+    dispute := &types.Dispute{TransactionID: disputed.ID, AccountNumber: 1001, HeldAccount: 1002, HeldAmount: 1000, Reason: types.DisputeOther, Status: types.DisputeOpen, CreatedAt: now}
+    assert.Nil(t, src.CreateDispute(ctx, dispute))
+
+    reversed := &types.Transaction{Kind: types.TransactionTransfer, FromAccount: 1001, ToAccount: 1002, Amount: 200, CreatedAt: now}
+    assert.Nil(t, src.PostTransaction(ctx, reversed, types.NewEntries(1001, 1002, 200)))
+    reversal := &types.Transaction{Kind: types.TransactionReversal, FromAccount: 1002, ToAccount: 1001, Amount: 200, CreatedAt: now}
+    assert.Nil(t, src.ReverseTransaction(ctx, &types.Reversal{TransactionID: reversed.ID, Reason: "mistake", CreatedAt: now}, reversal, types.NewEntries(1002, 1001, 200)))
+
+    loan := &types.Loan{AccountNumber: 1002, Principal: 5000, TermMonths: 1, RateBPS: 500, Status: types.LoanApplied, CreatedAt: now}
+    assert.Nil(t, src.CreateLoan(ctx, loan))
+    disbursement := &types.Transaction{Kind: types.TransactionLoanDisbursement, FromAccount: types.LoanAccountNumber, ToAccount: 1002, Amount: 5000, CreatedAt: now}
+    schedule := []*types.LoanInstallment{{LoanID: loan.ID, Number: 1, DueDate: now.AddDate(0, 1, 0), Principal: 5000, Interest: 21, Status: "due"}}
+    assert.Nil(t, src.DisburseLoan(ctx, loan, schedule, disbursement, types.NewEntries(types.LoanAccountNumber, 1002, 5000)))
+
+    claim := &types.AliasClaim{Alias: "dave@example.com", ExpiresAt: now.Add(24 * time.Hour)}
+    claimed := &types.Transaction{Kind: types.TransactionTransfer, Status: types.StatusPending, FromAccount: 1001, ToAccount: types.SuspenseAccountNumber, Amount: 300, CreatedAt: now}
+    assert.Nil(t, src.CreateAliasClaim(ctx, claim, claimed, types.NewEntries(1001, types.SuspenseAccountNumber, 300), nil))
+
+    for _, path := range []string{"/login", "/transfer"} {
+        assert.Nil(t, src.RecordAudit(ctx, &types.AuditEntry{AccountNumber: 1001, ActorNumber: 1001, Method: "POST", Path: path, Status: 200, CreatedAt: now}))
+    }
+
+    var buf bytes.Buffer
+    exported, err := Export(ctx, src, &buf)
+    assert.Nil(t, err)
+    assert.Equal(t, 1, exported.Holds)
+    assert.Equal(t, 1, exported.OwnerInvitations)
+    assert.Equal(t, 2, exported.AuditEntries)
+    assert.Equal(t, 1, exported.AliasClaims)
+
+    dst := storagetest.New()
+    restored, err := Restore(ctx, dst, &buf)
+    assert.Nil(t, err)
+    assert.Equal(t, exported.Accounts, restored.Accounts)
+
+    acc, err := dst.GetAccountByNumber(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Equal(t, int64(8700), acc.Balance)
+    assert.Equal(t, "de", acc.Locale)
+    assert.True(t, acc.ValidatePassword("pw"))
+
+    acc, err = dst.GetAccountByNumber(ctx, 1002)
+    assert.Nil(t, err)
+    assert.Equal(t, int64(6000), acc.Balance)
+    assert.NotNil(t, acc.DormancyNoticeAt)
+    assert.Nil(t, acc.DormantSince)
+
+    acc, err = dst.GetAccountByNumber(ctx, 1003)
+    assert.Nil(t, err)
+    if assert.NotNil(t, acc.ClosedAt) {
+        assert.True(t, now.Equal(*acc.ClosedAt))
+    }
+
+    srcEntries, _ := src.GetLedgerEntries(ctx)
+    entries, err := dst.GetLedgerEntries(ctx)
+    assert.Nil(t, err)
+    assert.Len(t, entries, len(srcEntries))
+
+    disputes, err := dst.GetDisputesByStatus(ctx, types.DisputeOpen)
+    assert.Nil(t, err)
+    if assert.Len(t, disputes, 1) {
+        assert.Equal(t, int64(1002), disputes[0].HeldAccount)
+        assert.Equal(t, int64(1000), disputes[0].HeldAmount)
+        restoredTx, err := dst.GetTransaction(ctx, disputes[0].TransactionID)
+        assert.Nil(t, err)
+        assert.Equal(t, int64(1000), restoredTx.Amount)
+    }
+
+    reversals, err := dst.GetReversals(ctx)
+    assert.Nil(t, err)
+    if assert.Len(t, reversals, 1) {
+        assert.Equal(t, "mistake", reversals[0].Reason)
+        restoredTx, err := dst.GetTransaction(ctx, reversals[0].ReversalID)
+        assert.Nil(t, err)
+        assert.Equal(t, types.TransactionReversal, restoredTx.Kind)
+    }
+
+    loans, err := dst.GetLoansByAccount(ctx, 1002)
+    assert.Nil(t, err)
+    if assert.Len(t, loans, 1) {
+        assert.Equal(t, types.LoanActive, loans[0].Status)
+        assert.Equal(t, int64(5000), loans[0].Outstanding)
+        restoredSchedule, err := dst.GetLoanSchedule(ctx, loans[0].ID)
+        assert.Nil(t, err)
+        assert.Len(t, restoredSchedule, 1)
+    }
+
+    claims, err := dst.GetPendingAliasClaims(ctx, "dave@example.com")
+    assert.Nil(t, err)
+    if assert.Len(t, claims, 1) {
+        assert.Equal(t, int64(300), claims[0].Amount)
+        assert.Equal(t, int64(1001), claims[0].FromAccount)
+    }
+
+    audit, err := dst.GetAuditLog(ctx, 1001)
+    assert.Nil(t, err)
+    if assert.Len(t, audit, 2) {
+        assert.Equal(t, "/transfer", audit[0].Path)
+    }
+
+    alias, err := dst.GetAlias(ctx, "alice@example.com")
+    assert.Nil(t, err)
+    assert.Equal(t, int64(1001), alias.AccountNumber)
+
+    cards, err := dst.GetCardsByAccount(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Len(t, cards, 1)
+    assert.Equal(t, "pan-hash", cards[0].PANHash)
+    assert.Equal(t, "pin-hash", cards[0].PINHash)
+    assert.Equal(t, int64(5000), cards[0].DailyLimit)
+
+    holds, err := dst.GetActiveHolds(ctx, 1001, now)
+    assert.Nil(t, err)
+    assert.Len(t, holds, 1)
+    assert.Equal(t, cards[0].ID, holds[0].CardID)
+    assert.Equal(t, int64(1500), holds[0].Amount)
+
+    pots, err := dst.GetPotsByAccount(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Len(t, pots, 1)
+    assert.Equal(t, int64(2000), pots[0].Balance)
+
+    owner, err := dst.GetAccountOwner(ctx, 1001, 1002)
+    assert.Nil(t, err)
+    assert.Equal(t, "view", owner.Permission)
+    invitations, err := dst.GetOwnerInvitationsFor(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Len(t, invitations, 1)
+
+    accountInvitations, err := dst.GetAccountInvitations(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Len(t, accountInvitations, 1)
+    assert.Equal(t, "token-hash", accountInvitations[0].TokenHash)
+
+    rules, err := dst.GetAlertRules(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Len(t, rules, 1)
+
+    grants, err := dst.GetGrantsByAccount(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Len(t, grants, 1)
+    assert.NotNil(t, grants[0].RevokedAt)
+
+    keys, err := dst.GetSigningKeysByAccount(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Len(t, keys, 1)
+    assert.Equal(t, "secret", keys[0].EncryptedSecret)
+
+    prefs, err = dst.GetNotificationPreferences(ctx, 1001)
+    assert.Nil(t, err)
+    assert.True(t, prefs.SMS)
+    assert.True(t, prefs.MarketingOptIn)
+}
+
+func TestRestoreNeedsAnEmptyStore(t *testing.T) {
+    ctx := context.Background()
+    src := storagetest.New()
+    assert.Nil(t, src.CreateAccount(ctx, &types.Account{FirstName: "a", LastName: "a", Number: 1001, Currency: "USD"}))
+
+    var buf bytes.Buffer
+    _, err := Export(ctx, src, &buf)
+    assert.Nil(t, err)
+
+    _, err = Restore(ctx, src, &buf)
+    assert.NotNil(t, err)
+}
+
+func TestFailedRestoreLeavesAnEmptyStore(t *testing.T) {
+    ctx := context.Background()
+    src := storagetest.New()
+    assert.Nil(t, src.CreateAccount(ctx, &types.Account{FirstName: "a", LastName: "a", Number: 1001, Currency: "USD"}))
+    deposit := &types.Transaction{Kind: types.TransactionOpening, FromAccount: types.SuspenseAccountNumber, ToAccount: 1001, Amount: 10000}
+    assert.Nil(t, src.PostTransaction(ctx, deposit, types.NewEntries(types.SuspenseAccountNumber, 1001, 10000)))
+    assert.Nil(t, src.CreateLoan(ctx, &types.Loan{AccountNumber: 1001, Principal: 5000, TermMonths: 12, Status: types.LoanApplied}))
+
+    var buf bytes.Buffer
+    _, err := Export(ctx, src, &buf)
+    assert.Nil(t, err)
+
+    dst := storagetest.New()
+    dst.FailOn("RestoreLoan", errors.New("disk full"))
+    _, err = Restore(ctx, dst, bytes.NewReader(buf.Bytes()))
+    assert.ErrorContains(t, err, "disk full")
+
+    accounts, _ := dst.GetAccounts(ctx)
+    assert.Empty(t, accounts)
+    txs, _ := dst.GetTransactions(ctx)
+    assert.Empty(t, txs)
+
+    // so it can be run again
+    dst.FailOn("RestoreLoan", nil)
+    _, err = Restore(ctx, dst, bytes.NewReader(buf.Bytes()))
+    assert.Nil(t, err)
+    acc, err := dst.GetAccountByNumber(ctx, 1001)
+    assert.Nil(t, err)
+    assert.Equal(t, int64(10000), acc.Balance)
+}
@@ -0,0 +1,92 @@
+package cards
+
+import (
+    "crypto/rand"
+    "fmt"
+    "math/big"
+    "time"
+)
+
+// BIN is the issuer prefix of every card number gobank generates.
+const BIN = "489999"
+
+const (
+    PANLength = 16
+    // Validity is how long a newly issued card is valid for.
+    Validity = 3 * 365 * 24 * time.Hour
+)
+
+// NewPAN returns a random card number under BIN with a valid Luhn check
+// digit.
+func NewPAN() (string, error) {
+    digits, err := randomDigits(PANLength - len(BIN) - 1)
+    if err != nil {
+        return "", err
+    }
+
+    partial := BIN + digits
+    return partial + string(rune('0'+checkDigit(partial))), nil
+}
+
+func NewCVV() (string, error) {
+    return randomDigits(3)
+}
+
+// Expiry returns the month and year a card issued at t expires. Cards are
+// valid until the end of that month.
+func Expiry(t time.Time) (int, int) {
+    exp := t.Add(Validity)
+    return int(exp.Month()), exp.Year()
+}
+
+// Expired reports whether a card expiring at the end of month/year has
+// expired by now.
+func Expired(month, year int, now time.Time) bool {
+    end := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+    return !now.Before(end)
+}
+
+// ValidLuhn reports whether pan is all digits and passes the Luhn check.
+func ValidLuhn(pan string) bool {
+    if len(pan) < 2 {
+        return false
+    }
+    for _, c := range pan {
+        if c < '0' || c > '9' {
+            return false
+        }
+    }
+
+    return checkDigit(pan[:len(pan)-1]) == int(pan[len(pan)-1]-'0')
+}
+
+// checkDigit computes the Luhn check digit to append to partial.
+func checkDigit(partial string) int {
+    sum := 0
+    double := true
+    for i := len(partial) - 1; i >= 0; i-- {
+        d := int(partial[i] - '0')
+        if double {
+            d *= 2
+            if d > 9 {
+                d -= 9
+            }
+        }
+        sum += d
+        double = !double
+    }
+
+    return (10 - sum%10) % 10
+}
+
+func randomDigits(n int) (string, error) {
+    s := ""
+    for i := 0; i < n; i++ {
+        d, err := rand.Int(rand.Reader, big.NewInt(10))
+        if err != nil {
+            return "", err
+        }
+        s += fmt.Sprint(d.Int64())
+    }
+    return s, nil
+}
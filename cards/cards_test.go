@@ -0,0 +1,30 @@
+package cards
+
+import (
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestNewPANPassesLuhn(t *testing.T) {
+    for i := 0; i < 100; i++ {
+        pan, err := NewPAN()
+        assert.Nil(t, err)
+        assert.Len(t, pan, PANLength)
+        assert.True(t, strings.HasPrefix(pan, BIN))
+        assert.True(t, ValidLuhn(pan), pan)
+    }
+
+    assert.True(t, ValidLuhn("4111111111111111"))
+    assert.False(t, ValidLuhn("4111111111111112"))
+    assert.False(t, ValidLuhn("41111111111x1111"))
+}
+
+func TestExpired(t *testing.T) {
+    now := time.Date(2025, time.March, 31, 23, 0, 0, 0, time.UTC)
+    assert.False(t, Expired(3, 2025, now))
+    assert.True(t, Expired(2, 2025, now))
+    assert.False(t, Expired(12, 2025, now))
+}
@@ -0,0 +1,101 @@
+package chaos
+
+import (
+    "context"
+    "fmt"
+    "math/rand/v2"
+    "time"
+
+    "gobank/metrics"
+    "gobank/storage"
+)
+
+// Faults configure fault injection for resilience testing. Nothing is
+// injected unless Enabled is set, and it never should be in production.
+// The percentages are of operations, from 0 to 100.
+type Faults struct {
+    Enabled bool
+    // LatencyPercent of storage calls are delayed by Latency.
+    LatencyPercent int
+    Latency time.Duration
+    // StorageErrorPercent of storage calls fail with ErrInjected, half of
+    // them before and half after the call was made, so a write can fail
+    // from the caller's point of view and still have happened.
+    StorageErrorPercent int
+    // WebhookDropPercent of outbound webhooks are never delivered.
+    WebhookDropPercent int
+}
+
+// ErrInjected is returned by storage calls failed on purpose. It wraps
+// storage.ErrUnavailable so they are answered and retried like a real
+// outage.
+var ErrInjected = fmt.Errorf("injected fault: %w", storage.ErrUnavailable)
+
+var faultsTotal = metrics.NewCounter("gobank_chaos_faults_total", "Faults injected on purpose, by kind.", "fault")
+
+// Injector decides which operations fail. A nil Injector injects nothing.
+type Injector struct {
+    faults Faults
+    // roll returns a number in [0, 100)
+    roll func() int
+}
+
+// New returns nil when f isn't enabled.
+func New(f Faults) *Injector {
+    if !f.Enabled {
+        return nil
+    }
+
+    return &Injector{faults: f, roll: func() int { return rand.IntN(100) }}
+}
+
+// Wrap returns store with faults injected into its calls by i, or store
+// itself when i is nil.
+func Wrap(store storage.Storage, i *Injector) storage.Storage {
+    if i == nil {
+        return store
+    }
+    return storage.Intercept(store, i.Do)
+}
+
+func (i *Injector) hit(percent int) bool {
+    return percent > 0 && i.roll() < percent
+}
+
+// Do is a storage.Interceptor.
+func (i *Injector) Do(ctx context.Context, op string, call func(context.Context) error) error {
+    if i.hit(i.faults.LatencyPercent) {
+        faultsTotal.Inc("latency")
+        t := time.NewTimer(i.faults.Latency)
+        select {
+        case <-ctx.Done():
+            t.Stop()
+            return ctx.Err()
+        case <-t.C:
+        }
+    }
+
+    if !i.hit(i.faults.StorageErrorPercent) {
+        return call(ctx)
+    }
+
+    faultsTotal.Inc("storage_error")
+    if i.roll() < 50 {
+        return fmt.Errorf("%s: %w", op, ErrInjected)
+    }
+    if err := call(ctx); err != nil {
+        return err
+    }
+    return fmt.Errorf("%s after it was made: %w", op, ErrInjected)
+}
+
+// DropWebhook reports whether an outbound webhook should be dropped
+// instead of delivered.
+func (i *Injector) DropWebhook() bool {
+    if i == nil || !i.hit(i.faults.WebhookDropPercent) {
+        return false
+    }
+
+    faultsTotal.Inc("webhook_drop")
+    return true
+}
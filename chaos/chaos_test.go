@@ -0,0 +1,63 @@
+package chaos
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestDisabledInjectsNothing(t *testing.T) {
+    i := New(Faults{StorageErrorPercent: 100, WebhookDropPercent: 100})
+    assert.Nil(t, i)
+
+    fake := storagetest.New()
+    assert.Equal(t, storage.Storage(fake), Wrap(fake, i))
+    assert.False(t, i.DropWebhook())
+}
+
+func TestStorageErrors(t *testing.T) {
+    i := New(Faults{Enabled: true, StorageErrorPercent: 100})
+    fake := storagetest.New()
+    store := Wrap(fake, i)
+    ctx := context.Background()
+
+    // failed before the call, nothing happened
+    i.roll = func() int { return 0 }
+    err := store.CreateAccount(ctx, &types.Account{Number: 1})
+    assert.ErrorIs(t, err, ErrInjected)
+    assert.True(t, storage.IsUnavailable(err))
+    _, err = fake.GetAccountByNumber(ctx, 1)
+    assert.ErrorIs(t, err, storage.ErrNotFound)
+
+    // failed after the call, the account exists anyway
+    rolls := []int{0, 99}
+    i.roll = func() int { r := rolls[0]; rolls = rolls[1:]; return r }
+    err = store.CreateAccount(ctx, &types.Account{Number: 2})
+    assert.ErrorIs(t, err, ErrInjected)
+    _, err = fake.GetAccountByNumber(ctx, 2)
+    assert.Nil(t, err)
+}
+
+func TestLatency(t *testing.T) {
+    i := New(Faults{Enabled: true, LatencyPercent: 100, Latency: time.Hour})
+    store := Wrap(storagetest.New(), i)
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    _, err := store.GetAccounts(ctx)
+    assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDropWebhook(t *testing.T) {
+    i := New(Faults{Enabled: true, WebhookDropPercent: 30})
+
+    i.roll = func() int { return 29 }
+    assert.True(t, i.DropWebhook())
+    i.roll = func() int { return 30 }
+    assert.False(t, i.DropWebhook())
+}
@@ -0,0 +1,90 @@
+package claims
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "gobank/fx"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// Pay settles every pending claim on alias into account, which has just
+// registered it. Claims are held in the sender's currency and converted if
+// the account uses another one. It returns the number of claims paid.
+func Pay(ctx context.Context, store storage.Storage, rates fx.Rates, alias string, account *types.Account) (int, error) {
+    pending, err := store.GetPendingAliasClaims(ctx, alias)
+    if err != nil {
+        return 0, err
+    }
+
+    paid := 0
+    for _, c := range pending {
+        from, err := store.GetAccountByNumber(ctx, c.FromAccount)
+        if err != nil {
+            return paid, err
+        }
+
+        entries := types.NewEntries(types.SuspenseAccountNumber, account.Number, c.Amount)
+        converted := c.Amount
+        if from.Currency != account.Currency {
+            converted, err = rates.Convert(c.Amount, from.Currency, account.Currency)
+            if err != nil {
+                return paid, err
+            }
+            entries = types.NewFXEntries(types.SuspenseAccountNumber, account.Number, c.Amount, converted)
+        }
+
+        payout := &types.Transaction{
+            Kind: types.TransactionTransfer,
+            FromAccount: types.SuspenseAccountNumber,
+            ToAccount: account.Number,
+            Amount: converted,
+            CreatedAt: time.Now().UTC(),
+        }
+        err = store.SettleTransaction(ctx, c.TransactionID, types.StatusCompleted, payout, entries)
+        if errors.Is(err, storage.ErrNotPending) {
+            // returned to the sender in the meantime
+            continue
+        }
+        if err != nil {
+            return paid, err
+        }
+        paid++
+    }
+
+    return paid, nil
+}
+
+// ReturnExpired gives the money of claims that expired before now back to
+// their senders and returns how many it returned.
+func ReturnExpired(ctx context.Context, store storage.Storage, now time.Time) (int, error) {
+    expired, err := store.GetExpiredAliasClaims(ctx, now)
+    if err != nil {
+        return 0, err
+    }
+
+    returned := 0
+    for _, c := range expired {
+        ret := &types.Transaction{
+            Kind: types.TransactionReturn,
+            FromAccount: types.SuspenseAccountNumber,
+            ToAccount: c.FromAccount,
+            Amount: c.Amount,
+            CreatedAt: now.UTC(),
+        }
+        entries := types.NewEntries(types.SuspenseAccountNumber, c.FromAccount, c.Amount)
+
+        err := store.SettleTransaction(ctx, c.TransactionID, types.StatusFailed, ret, entries)
+        if errors.Is(err, storage.ErrNotPending) {
+            continue
+        }
+        if err != nil {
+            return returned, err
+        }
+        returned++
+    }
+
+    return returned, nil
+}
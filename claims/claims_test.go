@@ -0,0 +1,50 @@
+package claims
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestReturnExpiredGivesMoneyBack(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+
+    acc, _ := types.NewAccount("a", "b", "pw")
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+    opening := &types.Transaction{Kind: types.TransactionOpening, Amount: 100, CreatedAt: time.Now()}
+    assert.Nil(t, store.PostTransaction(ctx, opening, types.NewEntries(types.SettlementAccountNumber, acc.Number, 100)))
+
+    now := time.Now().UTC()
+    for _, expires := range []time.Time{now.Add(-time.Hour), now.Add(time.Hour)} {
+        tx := &types.Transaction{
+            Kind: types.TransactionTransfer,
+            Status: types.StatusPending,
+            FromAccount: acc.Number,
+            ToAccount: types.SuspenseAccountNumber,
+            Amount: 30,
+            CreatedAt: now,
+        }
+        claim := &types.AliasClaim{Alias: "someone@example.com", ExpiresAt: expires}
+        assert.Nil(t, store.CreateAliasClaim(ctx, claim, tx, types.NewEntries(acc.Number, types.SuspenseAccountNumber, 30), nil))
+    }
+
+    n, err := ReturnExpired(ctx, store, now)
+    assert.Nil(t, err)
+    assert.Equal(t, 1, n)
+
+    // a second run finds nothing left to return
+    n, err = ReturnExpired(ctx, store, now)
+    assert.Nil(t, err)
+    assert.Equal(t, 0, n)
+
+    got, _ := store.GetAccountByNumber(ctx, acc.Number)
+    assert.Equal(t, int64(70), got.Balance)
+
+    pending, _ := store.GetPendingAliasClaims(ctx, "someone@example.com")
+    assert.Len(t, pending, 1)
+}
@@ -0,0 +1,146 @@
+package clearing
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "time"
+
+    "gobank/config"
+    "gobank/notify"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// Result is what a network reports about a transfer it was sent: pending,
+// or completed, or failed because the other bank returned it for Reason.
+type Result struct {
+    Status string
+    Reason string
+}
+
+// Network carries transfers to other banks. Transfers are handed over with
+// Submit and settle once Status reports them completed or failed. A
+// network that calls back instead can settle them through the inbound
+// webhook of its Name and report them pending until then.
+type Network interface {
+    Name() string
+    Submit(context.Context, *types.ExternalTransfer) error
+    Status(ctx context.Context, et *types.ExternalTransfer, now time.Time) (Result, error)
+}
+
+func NetworkFromConfig(cfg config.Config) (Network, error) {
+    switch cfg.ClearingNetwork {
+    case "", "sandbox":
+        return NewSandbox(cfg.ClearingSandboxDelay), nil
+    }
+
+    return nil, fmt.Errorf("unknown clearing network %q, use sandbox", cfg.ClearingNetwork)
+}
+
+// ValidRoutingNumber checks that n is nine digits with a valid ABA check
+// digit.
+func ValidRoutingNumber(n string) bool {
+    if len(n) != 9 {
+        return false
+    }
+
+    weights := [3]int{3, 7, 1}
+    sum := 0
+    for i, c := range n {
+        if c < '0' || c > '9' {
+            return false
+        }
+        sum += int(c-'0') * weights[i%3]
+    }
+
+    return sum%10 == 0
+}
+
+// ValidAccount checks that n can be an account number at another bank,
+// 4 to 17 digits.
+func ValidAccount(n string) bool {
+    if len(n) < 4 || len(n) > 17 {
+        return false
+    }
+    for _, c := range n {
+        if c < '0' || c > '9' {
+            return false
+        }
+    }
+    return true
+}
+
+// Return is the transaction giving the money of a returned transfer back
+// to its sender from the settlement account.
+func Return(et *types.ExternalTransfer, now time.Time) (*types.Transaction, []*types.LedgerEntry) {
+    ret := &types.Transaction{
+        Kind: types.TransactionReturn,
+        FromAccount: types.SettlementAccountNumber,
+        ToAccount: et.FromAccount,
+        Amount: et.Amount,
+        CreatedAt: now,
+    }
+    return ret, types.NewEntries(types.SettlementAccountNumber, et.FromAccount, et.Amount)
+}
+
+// Clear asks network about each transfer still pending on it, completing
+// the ones that cleared and returning the ones that came back, whose
+// senders are told. It returns how many transfers it settled.
+func Clear(ctx context.Context, store storage.Storage, notifier *notify.Notifier, network Network, now time.Time) (int, error) {
+    pending, err := store.GetPendingExternalTransfers(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    settled := 0
+    for _, et := range pending {
+        if et.Network != network.Name() {
+            continue
+        }
+
+        res, err := network.Status(ctx, et, now)
+        if err != nil {
+            log.Printf("clearing status of external transfer %d: %v", et.ID, err)
+            continue
+        }
+
+        switch res.Status {
+        case types.StatusCompleted:
+            err = store.SettleTransaction(ctx, et.ID, types.StatusCompleted, nil, nil)
+        case types.StatusFailed:
+            ret, entries := Return(et, now)
+            err = store.ReturnExternalTransfer(ctx, et.ID, res.Reason, ret, entries)
+            if err == nil {
+                notifyReturned(ctx, store, notifier, et, res.Reason)
+            }
+        default:
+            continue
+        }
+        if errors.Is(err, storage.ErrNotPending) {
+            // settled through the webhook or returned as stale meanwhile
+            continue
+        }
+        if err != nil {
+            return settled, err
+        }
+        settled++
+    }
+
+    return settled, nil
+}
+
+func notifyReturned(ctx context.Context, store storage.Storage, notifier *notify.Notifier, et *types.ExternalTransfer, reason string) {
+    acc, err := store.GetAccountByNumber(ctx, et.FromAccount)
+    if err != nil {
+        log.Printf("notifying return of external transfer %d: %v", et.ID, err)
+        return
+    }
+
+    notifier.Publish(notify.Event{
+        Type: notify.ExternalTransferReturned,
+        Account: acc,
+        Data: map[string]any{"amount": et.Amount, "toAccount": et.ToAccount, "name": et.Name, "reason": reason},
+    })
+}
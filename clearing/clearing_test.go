@@ -0,0 +1,69 @@
+package clearing
+
+import (
+    "context"
+    "io"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/notify"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestValidRoutingNumber(t *testing.T) {
+    assert.True(t, ValidRoutingNumber("021000021"))
+    assert.False(t, ValidRoutingNumber("021000022"))
+    assert.False(t, ValidRoutingNumber("02100002"))
+    assert.False(t, ValidRoutingNumber("02100002a"))
+}
+
+func TestClearSettlesAndReturns(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+    notifier := notify.New(notify.NewConsoleSender(io.Discard), 1)
+    defer notifier.Close()
+    network := NewSandbox(time.Minute)
+
+    acc, _ := types.NewAccount("a", "b", "pw")
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+    opening := &types.Transaction{Kind: types.TransactionOpening, Amount: 100, CreatedAt: time.Now()}
+    assert.Nil(t, store.PostTransaction(ctx, opening, types.NewEntries(types.SuspenseAccountNumber, acc.Number, 100)))
+
+    sent := time.Now().UTC()
+    send := func(to, reference string) *types.ExternalTransfer {
+        tx := &types.Transaction{
+            Kind: types.TransactionTransfer,
+            Status: types.StatusPending,
+            FromAccount: acc.Number,
+            ToAccount: types.SettlementAccountNumber,
+            Amount: 30,
+            Provider: network.Name(),
+            Reference: reference,
+            CreatedAt: sent,
+        }
+        et := &types.ExternalTransfer{RoutingNumber: "021000021", ToAccount: to, Name: "carol"}
+        assert.Nil(t, store.CreateExternalTransfer(ctx, et, tx, types.NewEntries(acc.Number, types.SettlementAccountNumber, 30), nil))
+        return et
+    }
+    cleared := send("12345678", "a")
+    returned := send("12340003", "b")
+
+    n, err := Clear(ctx, store, notifier, network, sent.Add(30*time.Second))
+    assert.Nil(t, err)
+    assert.Equal(t, 0, n)
+
+    n, err = Clear(ctx, store, notifier, network, sent.Add(time.Minute))
+    assert.Nil(t, err)
+    assert.Equal(t, 2, n)
+
+    got, _ := store.GetExternalTransfer(ctx, cleared.ID)
+    assert.Equal(t, types.StatusCompleted, got.Status)
+    got, _ = store.GetExternalTransfer(ctx, returned.ID)
+    assert.Equal(t, types.StatusFailed, got.Status)
+    assert.Equal(t, sandboxReturns["0003"], got.ReturnReason)
+
+    balance, _ := store.GetLedgerBalance(ctx, acc.Number)
+    assert.Equal(t, int64(70), balance)
+}
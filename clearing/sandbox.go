@@ -0,0 +1,52 @@
+package clearing
+
+import (
+    "context"
+    "strings"
+    "time"
+
+    "gobank/types"
+)
+
+// sandboxReturns are the account number endings the sandbox returns
+// transfers to, with the return reason, so tests and demos can see
+// returns happen.
+var sandboxReturns = map[string]string{
+    "0001": "R01 insufficient funds",
+    "0003": "R03 no account, unable to locate account",
+    "0004": "R04 invalid account number",
+}
+
+// Sandbox simulates a clearing network. It accepts every transfer and
+// settles it delay after it was sent, unless the destination account
+// number ends in 0001, 0003 or 0004, which it returns. It keeps no state,
+// so it works across restarts and instances.
+type Sandbox struct {
+    delay time.Duration
+}
+
+func NewSandbox(delay time.Duration) *Sandbox {
+    return &Sandbox{delay: delay}
+}
+
+func (s *Sandbox) Name() string {
+    return "sandbox"
+}
+
+func (s *Sandbox) Submit(ctx context.Context, et *types.ExternalTransfer) error {
+    return ctx.Err()
+}
+
+func (s *Sandbox) Status(ctx context.Context, et *types.ExternalTransfer, now time.Time) (Result, error) {
+    if now.Sub(et.CreatedAt) < s.delay {
+        return Result{Status: types.StatusPending}, nil
+    }
+
+    for suffix, reason := range sandboxReturns {
+        if strings.HasSuffix(et.ToAccount, suffix) {
+            return Result{Status: types.StatusFailed, Reason: reason}, nil
+        }
+    }
+
+    return Result{Status: types.StatusCompleted}, nil
+}
@@ -0,0 +1,274 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "gobank/signing"
+    "gobank/types"
+)
+
+type Client struct {
+    baseURL string
+    httpClient *http.Client
+    maxRetries int
+    backoff time.Duration
+    signingKeyID string
+    signingSecret []byte
+
+    mu sync.Mutex
+    token string
+    number int64
+    password string
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(hc *http.Client) Option {
+    return func(c *Client) {
+        c.httpClient = hc
+    }
+}
+
+// WithRetries sets how many times a GET or a transfer is retried after a
+// 5xx response or a network error. The wait doubles after every attempt,
+// starting at backoff. Other POSTs are never retried: the server may have
+// acted on one that failed, and only transfers carry an idempotency key
+// that lets it tell a retry from a new request.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+    return func(c *Client) {
+        c.maxRetries = maxRetries
+        c.backoff = backoff
+    }
+}
+
+// WithSigningKey signs every authenticated request with the signing key,
+// which the server requires for money movement once the account has one.
+func WithSigningKey(id, secret string) Option {
+    return func(c *Client) {
+        c.signingKeyID = id
+        c.signingSecret = []byte(secret)
+    }
+}
+
+func New(baseURL string, opts ...Option) *Client {
+    c := &Client{
+        baseURL: strings.TrimRight(baseURL, "/"),
+        httpClient: &http.Client{Timeout: 30 * time.Second},
+        maxRetries: 3,
+        backoff: 200 * time.Millisecond,
+    }
+    for _, opt := range opts {
+        opt(c)
+    }
+
+    return c
+}
+
+// Error is returned for any non 2xx response.
+type Error struct {
+    StatusCode int
+    Message string
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("gobank: %d %s", e.StatusCode, e.Message)
+}
+
+// Login authenticates and keeps the credentials so that an expired or
+// rejected token is refreshed transparently on later calls.
+func (c *Client) Login(ctx context.Context, number int64, password string) (*types.LoginResponse, error) {
+    resp := new(types.LoginResponse)
+    req := types.LoginRequest{Number: number, Password: password}
+    if err := c.do(ctx, "POST", "/login", "", req, resp, false); err != nil {
+        return nil, err
+    }
+
+    c.mu.Lock()
+    c.token = resp.Token
+    c.number = number
+    c.password = password
+    c.mu.Unlock()
+
+    return resp, nil
+}
+
+func (c *Client) CreateAccount(ctx context.Context, req types.CreateAccountRequest) (*types.Account, error) {
+    acc := new(types.Account)
+    if err := c.do(ctx, "POST", "/account", "", req, acc, false); err != nil {
+        return nil, err
+    }
+
+    return acc, nil
+}
+
+// Transfer sends every attempt with the same Idempotency-Key, so the
+// server posts the transfer at most once however often it is retried.
+func (c *Client) Transfer(ctx context.Context, req types.TransferRequest) (*types.Transaction, error) {
+    resp := new(types.Transaction)
+    if err := c.do(ctx, "POST", "/transfer", newIdempotencyKey(), req, resp, true); err != nil {
+        return nil, err
+    }
+
+    return resp, nil
+}
+
+// PreviewTransfer reports what Transfer would do with req without moving
+// any money.
+func (c *Client) PreviewTransfer(ctx context.Context, req types.TransferRequest) (*types.TransferPreview, error) {
+    resp := new(types.TransferPreview)
+    if err := c.do(ctx, "POST", "/transfer/preview", "", req, resp, true); err != nil {
+        return nil, err
+    }
+
+    return resp, nil
+}
+
+func (c *Client) ListTransactions(ctx context.Context, accountID int) ([]*types.Transaction, error) {
+    txs := []*types.Transaction{}
+    path := fmt.Sprintf("/account/%d/transactions", accountID)
+    if err := c.do(ctx, "GET", path, "", nil, &txs, true); err != nil {
+        return nil, err
+    }
+
+    return txs, nil
+}
+
+// do sends the request, with idempotencyKey in the Idempotency-Key header
+// unless it is empty. Requests with a key are retried like reads.
+func (c *Client) do(ctx context.Context, method, path, idempotencyKey string, body, out any, auth bool) error {
+    var payload []byte
+    if body != nil {
+        var err error
+        if payload, err = json.Marshal(body); err != nil {
+            return err
+        }
+    }
+
+    refreshed := false
+    wait := c.backoff
+    for attempt := 0; ; attempt++ {
+        resp, err := c.send(ctx, method, path, payload, idempotencyKey, auth)
+
+        retryable := method == "GET" || idempotencyKey != ""
+        retry := retryable && attempt < c.maxRetries && (err != nil || resp.StatusCode >= 500)
+        if retry {
+            if resp != nil {
+                resp.Body.Close()
+            }
+            if err := sleep(ctx, wait); err != nil {
+                return err
+            }
+            wait *= 2
+            continue
+        }
+        if err != nil {
+            return err
+        }
+
+        if auth && !refreshed && resp.StatusCode == http.StatusForbidden && c.canRefresh() {
+            resp.Body.Close()
+            if err := c.refresh(ctx); err != nil {
+                return err
+            }
+            refreshed = true
+            continue
+        }
+
+        return decodeResponse(resp, out)
+    }
+}
+
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, idempotencyKey string, auth bool) (*http.Response, error) {
+    req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+    if err != nil {
+        return nil, err
+    }
+
+    req.Header.Set("Content-Type", "application/json")
+    if idempotencyKey != "" {
+        req.Header.Set("Idempotency-Key", idempotencyKey)
+    }
+    if auth {
+        c.mu.Lock()
+        token := c.token
+        c.mu.Unlock()
+
+        if token != "" {
+            req.Header.Set("x-jwt-token", token)
+        }
+        // every attempt gets a new nonce, a retried one would be refused
+        if c.signingKeyID != "" {
+            req.Header.Set(signing.Header, signing.Sign(c.signingKeyID, c.signingSecret, time.Now(), signing.NewNonce(), method, path, payload))
+        }
+    }
+
+    return c.httpClient.Do(req)
+}
+
+func (c *Client) canRefresh() bool {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.password != ""
+}
+
+func (c *Client) refresh(ctx context.Context) error {
+    c.mu.Lock()
+    number, password := c.number, c.password
+    c.mu.Unlock()
+
+    _, err := c.Login(ctx, number, password)
+    return err
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        data, _ := io.ReadAll(resp.Body)
+
+        apiErr := struct {
+            Error string `json:"error"`
+        }{}
+        msg := strings.TrimSpace(string(data))
+        if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+            msg = apiErr.Error
+        }
+
+        return &Error{StatusCode: resp.StatusCode, Message: msg}
+    }
+
+    if out == nil {
+        return nil
+    }
+
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+    t := time.NewTimer(d)
+    defer t.Stop()
+
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-t.C:
+        return nil
+    }
+}
+
+func newIdempotencyKey() string {
+    b := make([]byte, 16)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/types"
+)
+
+func TestTransferRetriesWithSameIdempotencyKey(t *testing.T) {
+    keys := []string{}
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        keys = append(keys, r.Header.Get("Idempotency-Key"))
+        if len(keys) < 3 {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        json.NewEncoder(w).Encode(types.Transaction{ID: 1, ToAccount: 2, Amount: 10})
+    }))
+    defer srv.Close()
+
+    c := New(srv.URL, WithRetries(3, time.Millisecond))
+    resp, err := c.Transfer(context.Background(), types.TransferRequest{ToAccount: 2, Amount: 10})
+    assert.Nil(t, err)
+    assert.Equal(t, int64(10), resp.Amount)
+
+    assert.Len(t, keys, 3)
+    assert.NotEmpty(t, keys[0])
+    assert.Equal(t, keys[0], keys[2])
+
+    // the next transfer is a new one
+    keys = keys[:2]
+    _, err = c.Transfer(context.Background(), types.TransferRequest{ToAccount: 2, Amount: 10})
+    assert.Nil(t, err)
+    assert.NotEqual(t, keys[0], keys[2])
+}
+
+func TestPostsWithoutAKeyAreNotRetried(t *testing.T) {
+    requests := 0
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests++
+        if requests < 3 {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        w.Write([]byte(`[]`))
+    }))
+    defer srv.Close()
+
+    c := New(srv.URL, WithRetries(3, time.Millisecond))
+    txs, err := c.ListTransactions(context.Background(), 1)
+    assert.Nil(t, err)
+    assert.Empty(t, txs)
+    assert.Equal(t, 3, requests)
+
+    requests = 0
+    _, err = c.CreateAccount(context.Background(), types.CreateAccountRequest{FirstName: "a", LastName: "b", Password: "pw"})
+    apiErr := new(Error)
+    if assert.ErrorAs(t, err, &apiErr) {
+        assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+    }
+    assert.Equal(t, 1, requests)
+}
+
+func TestTokenRefreshOnForbidden(t *testing.T) {
+    logins := 0
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/login":
+            logins++
+            json.NewEncoder(w).Encode(types.LoginResponse{Number: 1, Token: "fresh"})
+        default:
+            if r.Header.Get("x-jwt-token") != "fresh" || logins < 2 {
+                w.WriteHeader(http.StatusForbidden)
+                w.Write([]byte(`{"error": "permission denied"}`))
+                return
+            }
+            w.Write([]byte(`[]`))
+        }
+    }))
+    defer srv.Close()
+
+    c := New(srv.URL)
+    _, err := c.Login(context.Background(), 1, "pw")
+    assert.Nil(t, err)
+
+    txs, err := c.ListTransactions(context.Background(), 1)
+    assert.Nil(t, err)
+    assert.Empty(t, txs)
+    assert.Equal(t, 2, logins)
+}
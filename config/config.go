@@ -0,0 +1,672 @@
+package config
+
+import (
+    "encoding/base64"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "gobank/chaos"
+    "gobank/fraud"
+    "gobank/fx"
+    "gobank/slo"
+    "gobank/types"
+    "gobank/vault"
+)
+
+type Config struct {
+    ListenAddr string
+
+    MaxBodyBytes int64
+    // Compression gzips or deflates responses of at least CompressMinBytes
+    // for clients that accept it.
+    Compression bool
+    CompressMinBytes int
+    MaxHeaderBytes int
+    ReadHeaderTimeout time.Duration
+    ReadTimeout time.Duration
+    WriteTimeout time.Duration
+    IdleTimeout time.Duration
+    // KeepAlives keeps client connections open between requests for up to
+    // IdleTimeout. TCPKeepAlive is the period of TCP keep-alive probes on
+    // accepted connections.
+    KeepAlives bool
+    TCPKeepAlive time.Duration
+    // H2C serves HTTP/2 without TLS next to HTTP/1.1, for load balancers
+    // and gateways that terminate TLS and speak HTTP/2 to the service. Only
+    // turn it on inside a private network.
+    H2C bool
+    // MaxConcurrentStreams limits the requests in flight on one HTTP/2
+    // connection.
+    MaxConcurrentStreams int
+
+    // per request deadlines, passed down to storage through the context
+    ReadRequestTimeout time.Duration
+    MoneyRequestTimeout time.Duration
+
+    // MaxInFlight is how many requests are handled at once, the last
+    // ReservedInFlight of them only for money movement and logins. Other
+    // requests wait up to AdmissionMaxWait for a slot and lists and
+    // reports don't wait at all; those that get none are answered with a
+    // 503. Zero MaxInFlight turns admission control off.
+    MaxInFlight int
+    ReservedInFlight int
+    AdmissionMaxWait time.Duration
+
+    BreakerThreshold int
+    BreakerCooldown time.Duration
+
+    // AdminToken guards the /admin endpoints, which are disabled when empty.
+    AdminToken string
+    // TokenTTL is how long a login token is valid. Tokens never expire
+    // when it is zero.
+    TokenTTL time.Duration
+    ReconcileInterval time.Duration
+    // ReportRefreshInterval is how long an operator report is served from
+    // cache before it is computed again.
+    ReportRefreshInterval time.Duration
+
+    // MailSender is one of console, smtp or ses.
+    MailSender string
+    MailFrom string
+    SMTPHost string
+    SMTPPort int
+    SMTPUsername string
+    SMTPPassword string
+    SESRegion string
+    // SMSSender is console or twilio.
+    SMSSender string
+    TwilioBaseURL string
+    TwilioAccountSID string
+    TwilioAuthToken string
+    TwilioFrom string
+    NotifyWorkers int
+    LargeWithdrawalAmount int64
+
+    // FXRates is read from a list like "EUR=1.08,GBP=1.27", each the
+    // value of one unit in USD.
+    FXRates fx.Rates
+
+    // WebhookSecrets maps an inbound webhook provider to its signing
+    // secret, read from a list like "ach=secret1,sepa=secret2". Providers
+    // without a secret are rejected.
+    WebhookSecrets map[string]string
+    WebhookTolerance time.Duration
+    // OutboundWebhookURL receives events like transaction reversals,
+    // signed with OutboundWebhookSecret. None are sent when it is empty.
+    OutboundWebhookURL string
+    OutboundWebhookSecret string
+    // OutboundWebhookAttempts is how often an event is sent before it is
+    // kept as a dead letter, waiting OutboundWebhookBackoff after the first
+    // failure and twice as long after each one after that.
+    OutboundWebhookAttempts int
+    OutboundWebhookBackoff time.Duration
+    // RequestSignatureTolerance is how far a signed request's timestamp may
+    // be from the server clock.
+    RequestSignatureTolerance time.Duration
+
+    // AliasClaimTTL is how long money sent to an unregistered alias waits
+    // to be claimed before it goes back to the sender.
+    AliasClaimTTL time.Duration
+
+    // ImportBatchSize is how many accounts a bulk import writes per
+    // transaction. ActivationTTL is how long imported accounts without a
+    // password have to redeem their activation token.
+    ImportBatchSize int
+    ActivationTTL time.Duration
+    // InvitationTTL is how long an invitation to open an account can be
+    // redeemed.
+    InvitationTTL time.Duration
+
+    // DocumentStore is disk, keeping uploaded documents under DocumentDir,
+    // or s3, keeping them in S3Bucket of an S3 compatible S3Endpoint, or
+    // of Amazon S3 in S3Region when it is empty.
+    DocumentStore string
+    DocumentDir string
+    S3Endpoint string
+    S3Region string
+    S3Bucket string
+    S3AccessKeyID string
+    S3SecretAccessKey string
+    DocumentMaxBytes int64
+    // DocumentSecret signs document download links, which stay valid for
+    // DocumentURLTTL. Documents are disabled when it is empty.
+    DocumentSecret string
+    DocumentURLTTL time.Duration
+
+    // QRSecret signs payment QR codes, which are disabled when it is empty.
+    QRSecret string
+
+    // EncryptionKey encrypts card details at rest. It is read from
+    // GOBANK_ENCRYPTION_KEY as base64 of 32 bytes; cards are disabled
+    // without it.
+    EncryptionKey []byte
+    // LoanRateBPS is the annual interest rate new loans are approved at,
+    // in basis points, unless a loan_rate_bps product rate is in effect.
+    LoanRateBPS int
+    LoanMaxAmount int64
+    // LoanLateFee is charged once on an installment still unpaid
+    // LoanGracePeriod after its due date, unless a loan_late_fee product
+    // rate is in effect.
+    LoanLateFee int64
+    LoanGracePeriod time.Duration
+    LoanCollectInterval time.Duration
+    // SavingsRateBPS is the annual interest rate paid on balances, in
+    // basis points, unless a savings_rate_bps product rate is in effect.
+    SavingsRateBPS int64
+    // TransferFee is charged to the sender of every transfer, unless a
+    // transfer_fee product rate is in effect.
+    TransferFee int64
+
+    // EndOfDaySchedule is the cron schedule, in UTC, of the end of day
+    // jobs: returning stale external transfers, expiring holds, accruing
+    // interest, taking balance snapshots, flagging dormant accounts and
+    // archiving old transactions.
+    EndOfDaySchedule string
+    // DigestSchedule is the cron schedule, in UTC, of the operations
+    // digest summarizing the previous day in DigestSections. It is emailed
+    // to DigestRecipients and dropped into DigestBucket, an S3 compatible
+    // bucket reached with the S3 settings, and not built unless either is
+    // set.
+    DigestSchedule string
+    DigestSections []string
+    DigestRecipients []string
+    DigestBucket string
+    // ExternalTransferTimeout is how long a transfer out of the bank waits
+    // for its provider to settle it before it is returned to the sender.
+    ExternalTransferTimeout time.Duration
+    // ClearingNetwork carries transfers to other banks. Only sandbox, a
+    // simulator that settles them ClearingSandboxDelay after they are
+    // sent, is built in. Pending transfers are checked on the network
+    // every ClearingInterval.
+    ClearingNetwork string
+    ClearingSandboxDelay time.Duration
+    ClearingInterval time.Duration
+    // DisputeWindow is how long after a transfer its sender can dispute it.
+    DisputeWindow time.Duration
+
+    // DormancyPeriod is how long an account can go without a transfer or
+    // sign-in before it becomes dormant and can't send money. Its holder
+    // is warned DormancyNotice before.
+    DormancyPeriod time.Duration
+    DormancyNotice time.Duration
+    // ArchiveAfterMonths is the age at which settled transactions move out
+    // of the hot tables into the archive, at the end of day.
+    ArchiveAfterMonths int
+
+    // Fraud are the thresholds transfers are checked against before they
+    // are posted.
+    Fraud fraud.Rules
+    // FraudCountryHeader names a header set by the edge proxy with the
+    // client's country, compared with the country the session started in.
+    // Only the client IP is compared when it is empty.
+    FraudCountryHeader string
+    // ImpossibleTravelWindow is how soon after a login from one country a
+    // login from another is treated as impossible travel.
+    ImpossibleTravelWindow time.Duration
+    // StepUpOnAnomaly makes sessions from a new device or impossible
+    // travel confirm a code sent to the account holder before they can
+    // move money.
+    StepUpOnAnomaly bool
+
+    // VelocityLimits cap the transfers an account sends within rolling
+    // windows, read from a list like "10m=5/500000,24h=50/2500000" of
+    // window=count/amount.
+    VelocityLimits []types.VelocityLimit
+
+    // Quotas cap the requests and transfer volume of every account, zero
+    // meaning no cap. Usage is counted either way.
+    Quotas types.Quotas
+
+    // TransferSLO and LoginSLO are the objectives of the transfer and
+    // login paths, tracked at /admin/slo.
+    TransferSLO slo.Objective
+    LoginSLO slo.Objective
+
+    // Maintenance starts the server in read-only mode, which admins can
+    // also switch at /admin/maintenance.
+    Maintenance bool
+
+    // Chaos injects latency, storage errors and dropped webhooks so
+    // integrators can test their retries against gobank. It is off unless
+    // GOBANK_CHAOS is set.
+    Chaos chaos.Faults
+}
+
+func Default() Config {
+    return Config{
+        ListenAddr: ":3000",
+        MaxBodyBytes: 1 << 20,
+        Compression: true,
+        CompressMinBytes: 1024,
+        MaxHeaderBytes: 1 << 16,
+        ReadHeaderTimeout: 5 * time.Second,
+        ReadTimeout: 15 * time.Second,
+        WriteTimeout: 15 * time.Second,
+        IdleTimeout: 60 * time.Second,
+        KeepAlives: true,
+        TCPKeepAlive: 15 * time.Second,
+        MaxConcurrentStreams: 250,
+        ReadRequestTimeout: 5 * time.Second,
+        MoneyRequestTimeout: 10 * time.Second,
+        MaxInFlight: 512,
+        ReservedInFlight: 64,
+        AdmissionMaxWait: 250 * time.Millisecond,
+        BreakerThreshold: 5,
+        BreakerCooldown: 10 * time.Second,
+        TokenTTL: 24 * time.Hour,
+        ReconcileInterval: time.Hour,
+        ReportRefreshInterval: 5 * time.Minute,
+        MailSender: "console",
+        MailFrom: "gobank <no-reply@gobank.local>",
+        SMTPPort: 587,
+        SESRegion: "us-east-1",
+        SMSSender: "console",
+        NotifyWorkers: 4,
+        LargeWithdrawalAmount: 100000,
+        FXRates: fx.DefaultRates(),
+        WebhookSecrets: map[string]string{},
+        WebhookTolerance: 5 * time.Minute,
+        OutboundWebhookAttempts: 5,
+        OutboundWebhookBackoff: time.Second,
+        RequestSignatureTolerance: 5 * time.Minute,
+        AliasClaimTTL: 14 * 24 * time.Hour,
+        ImportBatchSize: 100,
+        ActivationTTL: 30 * 24 * time.Hour,
+        InvitationTTL: 7 * 24 * time.Hour,
+        DocumentStore: "disk",
+        DocumentDir: "documents",
+        S3Region: "us-east-1",
+        DocumentMaxBytes: 10 << 20,
+        DocumentURLTTL: 15 * time.Minute,
+        LoanRateBPS: 1200,
+        LoanMaxAmount: 5000000,
+        LoanLateFee: 2500,
+        LoanGracePeriod: 5 * 24 * time.Hour,
+        LoanCollectInterval: time.Hour,
+        EndOfDaySchedule: "5 0 * * *",
+        DigestSchedule: "0 6 * * *",
+        DigestSections: types.DigestSections,
+        ExternalTransferTimeout: 72 * time.Hour,
+        ClearingNetwork: "sandbox",
+        ClearingSandboxDelay: time.Minute,
+        ClearingInterval: time.Minute,
+        DisputeWindow: 120 * 24 * time.Hour,
+        DormancyPeriod: 365 * 24 * time.Hour,
+        DormancyNotice: 30 * 24 * time.Hour,
+        ArchiveAfterMonths: 24,
+        Fraud: fraud.Rules{
+            ReviewAmount: 500000,
+            BlockAmount: 5000000,
+            NewPayeeAmount: 200000,
+            DormantAfter: 180 * 24 * time.Hour,
+        },
+        ImpossibleTravelWindow: 2 * time.Hour,
+        VelocityLimits: []types.VelocityLimit{
+            {Window: 10 * time.Minute, Count: 10, Amount: 1000000},
+            {Window: time.Hour, Count: 30, Amount: 2500000},
+            {Window: 24 * time.Hour, Count: 100, Amount: 10000000},
+        },
+        Chaos: chaos.Faults{Latency: 2 * time.Second},
+        TransferSLO: slo.Objective{SuccessBPS: 9990, Latency: time.Second, LatencyBPS: 9900},
+        LoginSLO: slo.Objective{SuccessBPS: 9990, Latency: 500 * time.Millisecond, LatencyBPS: 9900},
+    }
+}
+
+// Load returns the default config overridden by any GOBANK_* environment
+// variables that are set.
+func Load() (Config, error) {
+    cfg := Default()
+
+    if v := os.Getenv("GOBANK_LISTEN_ADDR"); v != "" {
+        cfg.ListenAddr = v
+    }
+
+    cfg.AdminToken = os.Getenv("GOBANK_ADMIN_TOKEN")
+    cfg.QRSecret = os.Getenv("GOBANK_QR_SECRET")
+    cfg.DocumentSecret = os.Getenv("GOBANK_DOCUMENT_SECRET")
+    cfg.OutboundWebhookSecret = os.Getenv("GOBANK_OUTBOUND_WEBHOOK_SECRET")
+
+    settings := map[string]*string{
+        "GOBANK_MAIL_SENDER": &cfg.MailSender,
+        "GOBANK_MAIL_FROM": &cfg.MailFrom,
+        "GOBANK_SMTP_HOST": &cfg.SMTPHost,
+        "GOBANK_SMTP_USERNAME": &cfg.SMTPUsername,
+        "GOBANK_SMTP_PASSWORD": &cfg.SMTPPassword,
+        "GOBANK_SES_REGION": &cfg.SESRegion,
+        "GOBANK_SMS_SENDER": &cfg.SMSSender,
+        "GOBANK_TWILIO_BASE_URL": &cfg.TwilioBaseURL,
+        "GOBANK_TWILIO_ACCOUNT_SID": &cfg.TwilioAccountSID,
+        "GOBANK_TWILIO_AUTH_TOKEN": &cfg.TwilioAuthToken,
+        "GOBANK_TWILIO_FROM": &cfg.TwilioFrom,
+        "GOBANK_FRAUD_COUNTRY_HEADER": &cfg.FraudCountryHeader,
+        "GOBANK_END_OF_DAY_SCHEDULE": &cfg.EndOfDaySchedule,
+        "GOBANK_DIGEST_SCHEDULE": &cfg.DigestSchedule,
+        "GOBANK_DIGEST_BUCKET": &cfg.DigestBucket,
+        "GOBANK_DOCUMENT_STORE": &cfg.DocumentStore,
+        "GOBANK_CLEARING_NETWORK": &cfg.ClearingNetwork,
+        "GOBANK_OUTBOUND_WEBHOOK_URL": &cfg.OutboundWebhookURL,
+        "GOBANK_DOCUMENT_DIR": &cfg.DocumentDir,
+        "GOBANK_S3_ENDPOINT": &cfg.S3Endpoint,
+        "GOBANK_S3_REGION": &cfg.S3Region,
+        "GOBANK_S3_BUCKET": &cfg.S3Bucket,
+        "GOBANK_S3_ACCESS_KEY_ID": &cfg.S3AccessKeyID,
+        "GOBANK_S3_SECRET_ACCESS_KEY": &cfg.S3SecretAccessKey,
+    }
+    for name, dst := range settings {
+        if v := os.Getenv(name); v != "" {
+            *dst = v
+        }
+    }
+
+    rates, err := fx.ParseRates(os.Getenv("GOBANK_FX_RATES"))
+    if err != nil {
+        return cfg, err
+    }
+    cfg.FXRates = rates
+
+    bools := map[string]*bool{
+        "GOBANK_STEP_UP_ON_ANOMALY": &cfg.StepUpOnAnomaly,
+        "GOBANK_KEEP_ALIVES": &cfg.KeepAlives,
+        "GOBANK_H2C": &cfg.H2C,
+        "GOBANK_COMPRESSION": &cfg.Compression,
+        "GOBANK_CHAOS": &cfg.Chaos.Enabled,
+        "GOBANK_MAINTENANCE": &cfg.Maintenance,
+    }
+    for name, b := range bools {
+        if err := loadBool(name, b); err != nil {
+            return cfg, err
+        }
+    }
+
+    if v := os.Getenv("GOBANK_VELOCITY_LIMITS"); v != "" {
+        limits, err := parseVelocityLimits(v)
+        if err != nil {
+            return cfg, err
+        }
+        cfg.VelocityLimits = limits
+    }
+
+    if v := os.Getenv("GOBANK_DIGEST_SECTIONS"); v != "" {
+        cfg.DigestSections = parseList(v)
+        for _, section := range cfg.DigestSections {
+            if !types.KnownDigestSection(section) {
+                return cfg, fmt.Errorf("unknown digest section %q, use %s", section, strings.Join(types.DigestSections, ", "))
+            }
+        }
+    }
+    if v := os.Getenv("GOBANK_DIGEST_RECIPIENTS"); v != "" {
+        cfg.DigestRecipients = parseList(v)
+    }
+
+    secrets, err := parsePairs("GOBANK_WEBHOOK_SECRETS")
+    if err != nil {
+        return cfg, err
+    }
+    cfg.WebhookSecrets = secrets
+
+    if v := os.Getenv("GOBANK_ENCRYPTION_KEY"); v != "" {
+        key, err := base64.StdEncoding.DecodeString(v)
+        if err != nil || len(key) != vault.KeySize {
+            return cfg, fmt.Errorf("GOBANK_ENCRYPTION_KEY must be base64 of %d bytes", vault.KeySize)
+        }
+        cfg.EncryptionKey = key
+    }
+
+    if err := loadInt64("GOBANK_MAX_BODY_BYTES", &cfg.MaxBodyBytes); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_COMPRESS_MIN_BYTES", &cfg.CompressMinBytes); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_MAX_HEADER_BYTES", &cfg.MaxHeaderBytes); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_MAX_CONCURRENT_STREAMS", &cfg.MaxConcurrentStreams); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_MAX_IN_FLIGHT", &cfg.MaxInFlight); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_RESERVED_IN_FLIGHT", &cfg.ReservedInFlight); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_DOCUMENT_MAX_BYTES", &cfg.DocumentMaxBytes); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_IMPORT_BATCH_SIZE", &cfg.ImportBatchSize); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_ARCHIVE_AFTER_MONTHS", &cfg.ArchiveAfterMonths); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_BREAKER_THRESHOLD", &cfg.BreakerThreshold); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_SMTP_PORT", &cfg.SMTPPort); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_NOTIFY_WORKERS", &cfg.NotifyWorkers); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_OUTBOUND_WEBHOOK_ATTEMPTS", &cfg.OutboundWebhookAttempts); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_LARGE_WITHDRAWAL_AMOUNT", &cfg.LargeWithdrawalAmount); err != nil {
+        return cfg, err
+    }
+    if err := loadInt("GOBANK_LOAN_RATE_BPS", &cfg.LoanRateBPS); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_LOAN_MAX_AMOUNT", &cfg.LoanMaxAmount); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_LOAN_LATE_FEE", &cfg.LoanLateFee); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_SAVINGS_RATE_BPS", &cfg.SavingsRateBPS); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_TRANSFER_FEE", &cfg.TransferFee); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_FRAUD_REVIEW_AMOUNT", &cfg.Fraud.ReviewAmount); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_FRAUD_BLOCK_AMOUNT", &cfg.Fraud.BlockAmount); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_FRAUD_NEW_PAYEE_AMOUNT", &cfg.Fraud.NewPayeeAmount); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_QUOTA_REQUESTS_PER_DAY", &cfg.Quotas.RequestsPerDay); err != nil {
+        return cfg, err
+    }
+    if err := loadInt64("GOBANK_QUOTA_TRANSFER_VOLUME_PER_MONTH", &cfg.Quotas.TransferVolumePerMonth); err != nil {
+        return cfg, err
+    }
+    objectives := map[string]*int{
+        "GOBANK_SLO_TRANSFER_SUCCESS_BPS": &cfg.TransferSLO.SuccessBPS,
+        "GOBANK_SLO_TRANSFER_LATENCY_BPS": &cfg.TransferSLO.LatencyBPS,
+        "GOBANK_SLO_LOGIN_SUCCESS_BPS": &cfg.LoginSLO.SuccessBPS,
+        "GOBANK_SLO_LOGIN_LATENCY_BPS": &cfg.LoginSLO.LatencyBPS,
+    }
+    for name, bps := range objectives {
+        if err := loadInt(name, bps); err != nil {
+            return cfg, err
+        }
+        if *bps >= 10000 {
+            return cfg, fmt.Errorf("%s must be below 10000 basis points to leave an error budget, got %d", name, *bps)
+        }
+    }
+    percents := map[string]*int{
+        "GOBANK_CHAOS_LATENCY_PERCENT": &cfg.Chaos.LatencyPercent,
+        "GOBANK_CHAOS_STORAGE_ERROR_PERCENT": &cfg.Chaos.StorageErrorPercent,
+        "GOBANK_CHAOS_WEBHOOK_DROP_PERCENT": &cfg.Chaos.WebhookDropPercent,
+    }
+    for name, p := range percents {
+        if err := loadInt(name, p); err != nil {
+            return cfg, err
+        }
+        if *p > 100 {
+            return cfg, fmt.Errorf("%s must be a percentage up to 100, got %d", name, *p)
+        }
+    }
+
+    durations := map[string]*time.Duration{
+        "GOBANK_READ_HEADER_TIMEOUT": &cfg.ReadHeaderTimeout,
+        "GOBANK_READ_TIMEOUT": &cfg.ReadTimeout,
+        "GOBANK_WRITE_TIMEOUT": &cfg.WriteTimeout,
+        "GOBANK_IDLE_TIMEOUT": &cfg.IdleTimeout,
+        "GOBANK_TCP_KEEP_ALIVE": &cfg.TCPKeepAlive,
+        "GOBANK_READ_REQUEST_TIMEOUT": &cfg.ReadRequestTimeout,
+        "GOBANK_MONEY_REQUEST_TIMEOUT": &cfg.MoneyRequestTimeout,
+        "GOBANK_ADMISSION_MAX_WAIT": &cfg.AdmissionMaxWait,
+        "GOBANK_BREAKER_COOLDOWN": &cfg.BreakerCooldown,
+        "GOBANK_TOKEN_TTL": &cfg.TokenTTL,
+        "GOBANK_RECONCILE_INTERVAL": &cfg.ReconcileInterval,
+        "GOBANK_REPORT_REFRESH_INTERVAL": &cfg.ReportRefreshInterval,
+        "GOBANK_WEBHOOK_TOLERANCE": &cfg.WebhookTolerance,
+        "GOBANK_OUTBOUND_WEBHOOK_BACKOFF": &cfg.OutboundWebhookBackoff,
+        "GOBANK_REQUEST_SIGNATURE_TOLERANCE": &cfg.RequestSignatureTolerance,
+        "GOBANK_ALIAS_CLAIM_TTL": &cfg.AliasClaimTTL,
+        "GOBANK_ACTIVATION_TTL": &cfg.ActivationTTL,
+        "GOBANK_INVITATION_TTL": &cfg.InvitationTTL,
+        "GOBANK_DOCUMENT_URL_TTL": &cfg.DocumentURLTTL,
+        "GOBANK_LOAN_GRACE_PERIOD": &cfg.LoanGracePeriod,
+        "GOBANK_LOAN_COLLECT_INTERVAL": &cfg.LoanCollectInterval,
+        "GOBANK_EXTERNAL_TRANSFER_TIMEOUT": &cfg.ExternalTransferTimeout,
+        "GOBANK_CLEARING_SANDBOX_DELAY": &cfg.ClearingSandboxDelay,
+        "GOBANK_CLEARING_INTERVAL": &cfg.ClearingInterval,
+        "GOBANK_DISPUTE_WINDOW": &cfg.DisputeWindow,
+        "GOBANK_DORMANCY_PERIOD": &cfg.DormancyPeriod,
+        "GOBANK_DORMANCY_NOTICE": &cfg.DormancyNotice,
+        "GOBANK_FRAUD_DORMANT_AFTER": &cfg.Fraud.DormantAfter,
+        "GOBANK_FRAUD_AMOUNT_WINDOW": &cfg.Fraud.AmountWindow,
+        "GOBANK_IMPOSSIBLE_TRAVEL_WINDOW": &cfg.ImpossibleTravelWindow,
+        "GOBANK_CHAOS_LATENCY": &cfg.Chaos.Latency,
+        "GOBANK_SLO_TRANSFER_LATENCY": &cfg.TransferSLO.Latency,
+        "GOBANK_SLO_LOGIN_LATENCY": &cfg.LoginSLO.Latency,
+    }
+    for name, d := range durations {
+        if err := loadDuration(name, d); err != nil {
+            return cfg, err
+        }
+    }
+
+    return cfg, nil
+}
+
+func parsePairs(name string) (map[string]string, error) {
+    pairs := map[string]string{}
+
+    v := os.Getenv(name)
+    if v == "" {
+        return pairs, nil
+    }
+
+    for _, pair := range strings.Split(v, ",") {
+        key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+        if !ok || key == "" || value == "" {
+            return nil, fmt.Errorf("%s must be a list like key=value,key2=value2", name)
+        }
+        pairs[key] = value
+    }
+
+    return pairs, nil
+}
+
+// parseList splits a comma separated list, dropping empty items.
+func parseList(v string) []string {
+    items := []string{}
+    for _, item := range strings.Split(v, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+func parseVelocityLimits(v string) ([]types.VelocityLimit, error) {
+    limits := []types.VelocityLimit{}
+
+    for _, item := range strings.Split(v, ",") {
+        window, rest, ok := strings.Cut(strings.TrimSpace(item), "=")
+        count, amount, ok2 := strings.Cut(rest, "/")
+        if !ok || !ok2 {
+            return nil, fmt.Errorf("invalid velocity limit %q, want window=count/amount", item)
+        }
+
+        var limit types.VelocityLimit
+        var err error
+        if limit.Window, err = time.ParseDuration(window); err != nil || limit.Window <= 0 {
+            return nil, fmt.Errorf("invalid velocity limit window %q", window)
+        }
+        if limit.Count, err = strconv.Atoi(count); err != nil || limit.Count < 0 {
+            return nil, fmt.Errorf("invalid velocity limit count %q", count)
+        }
+        if limit.Amount, err = strconv.ParseInt(amount, 10, 64); err != nil || limit.Amount < 0 {
+            return nil, fmt.Errorf("invalid velocity limit amount %q", amount)
+        }
+        limits = append(limits, limit)
+    }
+
+    return limits, nil
+}
+
+func loadInt64(name string, dst *int64) error {
+    v := os.Getenv(name)
+    if v == "" {
+        return nil
+    }
+
+    n, err := strconv.ParseInt(v, 10, 64)
+    if err != nil || n <= 0 {
+        return fmt.Errorf("%s must be a positive integer, got %q", name, v)
+    }
+    *dst = n
+
+    return nil
+}
+
+func loadInt(name string, dst *int) error {
+    n := int64(*dst)
+    if err := loadInt64(name, &n); err != nil {
+        return err
+    }
+    *dst = int(n)
+
+    return nil
+}
+
+func loadDuration(name string, dst *time.Duration) error {
+    v := os.Getenv(name)
+    if v == "" {
+        return nil
+    }
+
+    d, err := time.ParseDuration(v)
+    if err != nil || d <= 0 {
+        return fmt.Errorf("%s must be a positive duration like 15s, got %q", name, v)
+    }
+    *dst = d
+
+    return nil
+}
+
+func loadBool(name string, dst *bool) error {
+    v := os.Getenv(name)
+    if v == "" {
+        return nil
+    }
+
+    b, err := strconv.ParseBool(v)
+    if err != nil {
+        return fmt.Errorf("%s must be true or false, got %q", name, v)
+    }
+    *dst = b
+
+    return nil
+}
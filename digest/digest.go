@@ -0,0 +1,134 @@
+package digest
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "gobank/notify"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// Bucket is where digests are dropped as JSON, e.g. a documents.S3Store on
+// a bucket operations tooling reads from.
+type Bucket interface {
+    Put(ctx context.Context, key, contentType string, data []byte) error
+}
+
+// Build summarizes the UTC day before now in the given sections.
+func Build(ctx context.Context, store storage.Storage, sections []string, now time.Time) (*types.Digest, error) {
+    to := now.UTC().Truncate(24 * time.Hour)
+    from := to.AddDate(0, 0, -1)
+    d := &types.Digest{Day: from, GeneratedAt: now.UTC()}
+
+    for _, section := range sections {
+        var err error
+        switch section {
+        case types.DigestFlows:
+            d.Flows, err = flows(ctx, store, from, to)
+        case types.DigestReconciliation:
+            d.Reconciliation, err = reconciliation(ctx, store, from, to)
+        case types.DigestFraudReview:
+            d.FraudReview, err = fraudReview(ctx, store)
+        default:
+            err = fmt.Errorf("unknown digest section %q", section)
+        }
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return d, nil
+}
+
+func flows(ctx context.Context, store storage.Storage, from, to time.Time) (*types.DailyFlow, error) {
+    days, err := store.GetDailyFlows(ctx, from, to)
+    if err != nil {
+        return nil, err
+    }
+    if len(days) == 0 {
+        return &types.DailyFlow{Day: from}, nil
+    }
+
+    return days[0], nil
+}
+
+func reconciliation(ctx context.Context, store storage.Storage, from, to time.Time) (*types.ReconciliationSummary, error) {
+    discrepancies, err := store.GetDiscrepancies(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    s := &types.ReconciliationSummary{}
+    for _, d := range discrepancies {
+        if d.ResolvedAt != nil {
+            continue
+        }
+        s.Open++
+        s.Drift += abs(d.Difference())
+        if !d.DetectedAt.Before(from) && d.DetectedAt.Before(to) {
+            s.New++
+        }
+    }
+
+    return s, nil
+}
+
+func fraudReview(ctx context.Context, store storage.Storage) (*types.FraudReviewSummary, error) {
+    cases, err := store.GetFraudCasesByStatus(ctx, types.FraudCasePending)
+    if err != nil {
+        return nil, err
+    }
+
+    s := &types.FraudReviewSummary{Pending: len(cases)}
+    for _, c := range cases {
+        s.Amount += c.Amount
+        if s.OldestAt == nil || c.CreatedAt.Before(*s.OldestAt) {
+            created := c.CreatedAt
+            s.OldestAt = &created
+        }
+    }
+
+    return s, nil
+}
+
+// Deliver emails d to every recipient and drops it into bucket when it
+// isn't nil, as digests/<day>.json. It returns how many deliveries it
+// made, emails counting once they are queued.
+func Deliver(ctx context.Context, d *types.Digest, notifier *notify.Notifier, recipients []string, bucket Bucket) (int, error) {
+    delivered := 0
+
+    if bucket != nil {
+        data, err := json.MarshalIndent(d, "", "  ")
+        if err != nil {
+            return 0, err
+        }
+        key := fmt.Sprintf("digests/%s.json", d.Day.Format(time.DateOnly))
+        if err := bucket.Put(ctx, key, "application/json", data); err != nil {
+            return 0, err
+        }
+        delivered++
+    }
+
+    for _, to := range recipients {
+        // operators have no account, so the digest is written in English
+        notifier.Publish(notify.Event{
+            Type: notify.OperationsDigest,
+            Account: &types.Account{Locale: "en"},
+            Email: to,
+            Data: map[string]any{"digest": d},
+        })
+        delivered++
+    }
+
+    return delivered, nil
+}
+
+func abs(n int64) int64 {
+    if n < 0 {
+        return -n
+    }
+    return n
+}
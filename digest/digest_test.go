@@ -0,0 +1,61 @@
+package digest
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/notify"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+type memoryBucket map[string][]byte
+
+func (b memoryBucket) Put(ctx context.Context, key, contentType string, data []byte) error {
+    b[key] = data
+    return nil
+}
+
+func TestBuildAndDeliver(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+    now := time.Date(2026, time.March, 10, 6, 0, 0, 0, time.UTC)
+    yesterday := now.Add(-12 * time.Hour)
+
+    assert.Nil(t, store.CreateDiscrepancy(ctx, &types.Discrepancy{AccountNumber: 1, StoredBalance: 100, LedgerBalance: 150, DetectedAt: yesterday}))
+    assert.Nil(t, store.CreateDiscrepancy(ctx, &types.Discrepancy{AccountNumber: 2, StoredBalance: 20, LedgerBalance: 10, DetectedAt: yesterday.AddDate(0, 0, -3)}))
+    assert.Nil(t, store.CreateFraudCase(ctx, &types.FraudCase{FromAccount: 1, ToAccount: 2, Amount: 700, Status: types.FraudCasePending, CreatedAt: yesterday}))
+    assert.Nil(t, store.CreateFraudCase(ctx, &types.FraudCase{FromAccount: 1, ToAccount: 2, Amount: 900, Status: types.FraudCaseRejected, CreatedAt: yesterday}))
+
+    d, err := Build(ctx, store, types.DigestSections, now)
+    assert.Nil(t, err)
+    assert.Equal(t, time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC), d.Day)
+    assert.Equal(t, int64(0), d.Flows.Deposits)
+    assert.Equal(t, &types.ReconciliationSummary{Open: 2, New: 1, Drift: 60}, d.Reconciliation)
+    assert.Equal(t, 1, d.FraudReview.Pending)
+    assert.Equal(t, int64(700), d.FraudReview.Amount)
+
+    d, err = Build(ctx, store, []string{types.DigestFraudReview}, now)
+    assert.Nil(t, err)
+    assert.Nil(t, d.Flows)
+    assert.Nil(t, d.Reconciliation)
+
+    var mail bytes.Buffer
+    notifier := notify.New(notify.NewConsoleSender(&mail), 1)
+    bucket := memoryBucket{}
+    n, err := Deliver(ctx, d, notifier, []string{"ops@example.com"}, bucket)
+    notifier.Close()
+    assert.Nil(t, err)
+    assert.Equal(t, 2, n)
+
+    dropped := new(types.Digest)
+    assert.Nil(t, json.Unmarshal(bucket["digests/2026-03-09.json"], dropped))
+    assert.Equal(t, 1, dropped.FraudReview.Pending)
+    assert.Contains(t, mail.String(), "to: ops@example.com")
+    assert.Contains(t, mail.String(), "Fraud review: 1 transfers waiting for review")
+    assert.NotContains(t, mail.String(), "Reconciliation")
+}
@@ -0,0 +1,108 @@
+package documents
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "gobank/config"
+)
+
+var ErrBlobNotFound = errors.New("documents: blob not found")
+
+// BlobStore keeps document contents, addressed by the keys NewKey hands
+// out. The metadata lives in storage.
+type BlobStore interface {
+    Put(ctx context.Context, key, contentType string, data []byte) error
+    // Get fails with ErrBlobNotFound when there is nothing under key.
+    Get(ctx context.Context, key string) (io.ReadCloser, error)
+    Delete(ctx context.Context, key string) error
+}
+
+// DiskStore keeps blobs as files under dir, for development and single
+// node deployments.
+type DiskStore struct {
+    dir string
+}
+
+func NewDiskStore(dir string) *DiskStore {
+    return &DiskStore{dir: dir}
+}
+
+func (s *DiskStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+    path, err := s.path(key)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+        return err
+    }
+
+    // write aside and rename so readers never see half a file
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := io.Copy(tmp, bytes.NewReader(data)); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+
+    return os.Rename(tmp.Name(), path)
+}
+
+func (s *DiskStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    path, err := s.path(key)
+    if err != nil {
+        return nil, err
+    }
+
+    f, err := os.Open(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, fmt.Errorf("%s: %w", key, ErrBlobNotFound)
+    }
+
+    return f, err
+}
+
+func (s *DiskStore) Delete(ctx context.Context, key string) error {
+    path, err := s.path(key)
+    if err != nil {
+        return err
+    }
+
+    if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+
+    return nil
+}
+
+func (s *DiskStore) path(key string) (string, error) {
+    if key == "" || strings.Contains(key, "..") || filepath.IsAbs(key) {
+        return "", fmt.Errorf("documents: invalid blob key %q", key)
+    }
+
+    return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func BlobStoreFromConfig(cfg config.Config) (BlobStore, error) {
+    switch cfg.DocumentStore {
+    case "", "disk":
+        return NewDiskStore(cfg.DocumentDir), nil
+    case "s3":
+        return NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey), nil
+    }
+
+    return nil, fmt.Errorf("unknown document store %q, use disk or s3", cfg.DocumentStore)
+}
@@ -0,0 +1,90 @@
+package documents
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "mime"
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
+)
+
+var (
+    ErrInvalidURL = errors.New("invalid download link")
+    ErrExpiredURL = errors.New("download link expired")
+)
+
+// contentTypes are the files accepted as evidence and identity documents.
+var contentTypes = map[string]bool{
+    "application/pdf": true,
+    "image/jpeg": true,
+    "image/png": true,
+}
+
+// CheckContentType returns the media type of the declared Content-Type
+// when it is accepted and data looks like it, so a file can't pass as a
+// PDF by its header alone.
+func CheckContentType(declared string, data []byte) (string, error) {
+    mediaType, _, err := mime.ParseMediaType(declared)
+    if err != nil || !contentTypes[mediaType] {
+        return "", fmt.Errorf("unsupported content type %q, use application/pdf, image/jpeg or image/png", declared)
+    }
+
+    if detected := http.DetectContentType(data); detected != mediaType {
+        return "", fmt.Errorf("content is %s, not %s", detected, mediaType)
+    }
+
+    return mediaType, nil
+}
+
+// NewKey returns a random blob key under the account's prefix.
+func NewKey(account int64) (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+
+    return fmt.Sprintf("documents/%d/%s", account, hex.EncodeToString(b)), nil
+}
+
+// DownloadURL returns the path document id can be downloaded from without
+// signing in until expiresAt.
+func DownloadURL(secret []byte, id int, expiresAt time.Time) string {
+    expires := strconv.FormatInt(expiresAt.Unix(), 10)
+    q := url.Values{
+        "expires": {expires},
+        "signature": {sign(secret, id, expires)},
+    }
+
+    return fmt.Sprintf("/documents/%d/download?%s", id, q.Encode())
+}
+
+// VerifyURL checks the expires and signature parameters of a download
+// link for document id.
+func VerifyURL(secret []byte, id int, q url.Values, now time.Time) error {
+    expires := q.Get("expires")
+    if !hmac.Equal([]byte(q.Get("signature")), []byte(sign(secret, id, expires))) {
+        return ErrInvalidURL
+    }
+
+    unix, err := strconv.ParseInt(expires, 10, 64)
+    if err != nil {
+        return ErrInvalidURL
+    }
+    if now.Unix() > unix {
+        return ErrExpiredURL
+    }
+
+    return nil
+}
+
+func sign(secret []byte, id int, expires string) string {
+    h := hmac.New(sha256.New, secret)
+    fmt.Fprintf(h, "document:%d:%s", id, expires)
+    return hex.EncodeToString(h.Sum(nil))
+}
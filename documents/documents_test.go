@@ -0,0 +1,119 @@
+package documents
+
+import (
+    "context"
+    "errors"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+var png = []byte("\x89PNG\r\n\x1a\n0000")
+
+func TestDiskStoreRoundTrip(t *testing.T) {
+    ctx := context.Background()
+    store := NewDiskStore(t.TempDir())
+
+    assert.NoError(t, store.Put(ctx, "documents/1/abc", "image/png", png))
+
+    r, err := store.Get(ctx, "documents/1/abc")
+    if !assert.NoError(t, err) {
+        return
+    }
+    got, _ := io.ReadAll(r)
+    r.Close()
+    assert.Equal(t, png, got)
+
+    assert.NoError(t, store.Delete(ctx, "documents/1/abc"))
+    _, err = store.Get(ctx, "documents/1/abc")
+    assert.ErrorIs(t, err, ErrBlobNotFound)
+
+    assert.Error(t, store.Put(ctx, "../escape", "image/png", png))
+}
+
+func TestS3StoreSignsRequests(t *testing.T) {
+    var mu sync.Mutex
+    objects := map[string][]byte{}
+
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        auth := r.Header.Get("Authorization")
+        if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+            w.WriteHeader(http.StatusForbidden)
+            return
+        }
+
+        mu.Lock()
+        defer mu.Unlock()
+
+        switch r.Method {
+        case "PUT":
+            body, _ := io.ReadAll(r.Body)
+            if r.Header.Get("x-amz-content-sha256") != sha256Hex(body) {
+                w.WriteHeader(http.StatusBadRequest)
+                return
+            }
+            objects[r.URL.Path] = body
+        case "GET":
+            body, ok := objects[r.URL.Path]
+            if !ok {
+                w.WriteHeader(http.StatusNotFound)
+                return
+            }
+            w.Write(body)
+        case "DELETE":
+            delete(objects, r.URL.Path)
+            w.WriteHeader(http.StatusNoContent)
+        }
+    }))
+    defer srv.Close()
+
+    ctx := context.Background()
+    store := NewS3Store(srv.URL, "eu-west-1", "bucket", "key", "secret")
+
+    assert.NoError(t, store.Put(ctx, "documents/1/abc", "image/png", png))
+    assert.Contains(t, objects, "/bucket/documents/1/abc")
+
+    r, err := store.Get(ctx, "documents/1/abc")
+    if !assert.NoError(t, err) {
+        return
+    }
+    got, _ := io.ReadAll(r)
+    r.Close()
+    assert.Equal(t, png, got)
+
+    assert.NoError(t, store.Delete(ctx, "documents/1/abc"))
+    _, err = store.Get(ctx, "documents/1/abc")
+    assert.True(t, errors.Is(err, ErrBlobNotFound))
+}
+
+func TestCheckContentType(t *testing.T) {
+    got, err := CheckContentType("image/png", png)
+    assert.NoError(t, err)
+    assert.Equal(t, "image/png", got)
+
+    _, err = CheckContentType("application/pdf", png)
+    assert.Error(t, err)
+
+    _, err = CheckContentType("text/html", []byte("<html></html>"))
+    assert.Error(t, err)
+}
+
+func TestDownloadURL(t *testing.T) {
+    secret := []byte("secret")
+    now := time.Now()
+
+    link, _ := url.Parse(DownloadURL(secret, 7, now.Add(time.Minute)))
+    assert.Equal(t, "/documents/7/download", link.Path)
+    assert.NoError(t, VerifyURL(secret, 7, link.Query(), now))
+
+    assert.ErrorIs(t, VerifyURL(secret, 8, link.Query(), now), ErrInvalidURL)
+    assert.ErrorIs(t, VerifyURL([]byte("other"), 7, link.Query(), now), ErrInvalidURL)
+    assert.ErrorIs(t, VerifyURL(secret, 7, link.Query(), now.Add(2*time.Minute)), ErrExpiredURL)
+}
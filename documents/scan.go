@@ -0,0 +1,23 @@
+package documents
+
+import (
+    "context"
+    "errors"
+)
+
+var ErrInfected = errors.New("documents: file failed the virus scan")
+
+// Scanner is the hook uploads go through before they are stored. An
+// implementation returns ErrInfected, wrapped with whatever it found, to
+// reject a file; any other error fails the upload without judging it.
+type Scanner interface {
+    Scan(ctx context.Context, contentType string, data []byte) error
+}
+
+// NopScanner accepts every file, for deployments that scan elsewhere or
+// not at all.
+type NopScanner struct{}
+
+func (NopScanner) Scan(ctx context.Context, contentType string, data []byte) error {
+    return nil
+}
@@ -0,0 +1,64 @@
+package dormancy
+
+import (
+    "context"
+    "time"
+
+    "gobank/notify"
+    "gobank/storage"
+)
+
+// Sweep warns the holders of accounts that have been inactive for period
+// minus notice, flags accounts inactive for the whole period as dormant and
+// forgets the warning of accounts that were used again in time. Dormant
+// accounts stay dormant until their holder reactivates them. It returns how
+// many accounts were warned or flagged.
+func Sweep(ctx context.Context, store storage.Storage, notifier *notify.Notifier, now time.Time, period, notice time.Duration) (int, error) {
+    accounts, err := store.GetAccounts(ctx)
+    if err != nil {
+        return 0, err
+    }
+    activity, err := store.GetLastActivity(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    changed := 0
+    for _, acc := range accounts {
+        if acc.DormantSince != nil || acc.ClosedAt != nil {
+            continue
+        }
+
+        last, ok := activity[acc.Number]
+        if !ok {
+            last = acc.CreatedAt
+        }
+        idle := now.Sub(last)
+        warned := acc.DormancyNoticeAt != nil && acc.DormancyNoticeAt.After(last)
+
+        switch {
+        case idle >= period:
+            if err := store.SetDormancy(ctx, acc.Number, acc.DormancyNoticeAt, &now); err != nil {
+                return changed, err
+            }
+            notifier.Publish(notify.Event{Type: notify.AccountDormant, Account: acc})
+            changed++
+        case idle >= period-notice && !warned:
+            if err := store.SetDormancy(ctx, acc.Number, &now, nil); err != nil {
+                return changed, err
+            }
+            notifier.Publish(notify.Event{
+                Type: notify.DormancyWarning,
+                Account: acc,
+                Data: map[string]any{"dormantOn": last.Add(period)},
+            })
+            changed++
+        case idle < period-notice && acc.DormancyNoticeAt != nil:
+            if err := store.SetDormancy(ctx, acc.Number, nil, nil); err != nil {
+                return changed, err
+            }
+        }
+    }
+
+    return changed, nil
+}
@@ -0,0 +1,53 @@
+package dormancy
+
+import (
+    "context"
+    "io"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/notify"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestSweepWarnsThenFlags(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+    notifier := notify.New(notify.NewConsoleSender(io.Discard), 1)
+    defer notifier.Close()
+
+    opened := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+    acc := &types.Account{FirstName: "alice", LastName: "a", Number: 1, CreatedAt: opened}
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+
+    period, notice := 100*24*time.Hour, 10*24*time.Hour
+    sweep := func(now time.Time) *types.Account {
+        _, err := Sweep(ctx, store, notifier, now, period, notice)
+        assert.Nil(t, err)
+        acc, _ := store.GetAccountByNumber(ctx, 1)
+        return acc
+    }
+
+    acc = sweep(opened.Add(50 * 24 * time.Hour))
+    assert.Nil(t, acc.DormancyNoticeAt)
+
+    warnedAt := opened.Add(91 * 24 * time.Hour)
+    acc = sweep(warnedAt)
+    assert.Equal(t, warnedAt, *acc.DormancyNoticeAt)
+    assert.Nil(t, acc.DormantSince)
+
+    // a sign-in in time puts the clock back
+    store.SaveDevice(ctx, &types.Device{AccountNumber: 1, Fingerprint: "x", FirstSeen: warnedAt, LastSeen: warnedAt.Add(time.Hour)})
+    acc = sweep(warnedAt.Add(24 * time.Hour))
+    assert.Nil(t, acc.DormancyNoticeAt)
+
+    acc = sweep(warnedAt.Add(101 * 24 * time.Hour))
+    assert.NotNil(t, acc.DormantSince)
+
+    // dormant accounts stay dormant until they are reactivated
+    store.SaveDevice(ctx, &types.Device{AccountNumber: 1, Fingerprint: "x", LastSeen: warnedAt.Add(102 * 24 * time.Hour)})
+    acc = sweep(warnedAt.Add(103 * 24 * time.Hour))
+    assert.NotNil(t, acc.DormantSince)
+}
@@ -0,0 +1,175 @@
+package fixtures
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    "gopkg.in/yaml.v3"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// baseTime is used for any fixture entry without an explicit timestamp so
+// that two loads of the same file produce the same dataset.
+var baseTime = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type File struct {
+    Accounts []Account `json:"accounts" yaml:"accounts"`
+    Transactions []Transaction `json:"transactions" yaml:"transactions"`
+}
+
+type Account struct {
+    FirstName string `json:"firstName" yaml:"firstName"`
+    LastName string `json:"lastName" yaml:"lastName"`
+    Email string `json:"email" yaml:"email"`
+    Password string `json:"password" yaml:"password"`
+    Number int64 `json:"number" yaml:"number"`
+    Balance int64 `json:"balance" yaml:"balance"`
+    Currency string `json:"currency" yaml:"currency"`
+    CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+}
+
+// Account balances are opening balances, posted from the suspense account.
+// Transactions are then posted in file order as transfers, so the final
+// balances include them.
+type Transaction struct {
+    FromAccount int64 `json:"fromAccount" yaml:"fromAccount"`
+    ToAccount int64 `json:"toAccount" yaml:"toAccount"`
+    Amount int64 `json:"amount" yaml:"amount"`
+    CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+}
+
+func Load(path string) (*File, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    switch filepath.Ext(path) {
+    case ".json":
+        return Parse(data, "json")
+    case ".yaml", ".yml":
+        return Parse(data, "yaml")
+    }
+
+    return nil, fmt.Errorf("unsupported fixtures file %s, use .json or .yaml", path)
+}
+
+func Parse(data []byte, format string) (*File, error) {
+    f := new(File)
+
+    var err error
+    switch format {
+    case "json":
+        err = json.Unmarshal(data, f)
+    case "yaml":
+        err = yaml.Unmarshal(data, f)
+    default:
+        return nil, fmt.Errorf("unsupported fixtures format %s", format)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if err := f.validate(); err != nil {
+        return nil, err
+    }
+
+    return f, nil
+}
+
+func (f *File) validate() error {
+    numbers := map[int64]bool{}
+    currencies := map[int64]string{}
+    for i, acc := range f.Accounts {
+        if acc.Number == 0 {
+            return fmt.Errorf("account %d: number is required", i)
+        }
+        if acc.Balance < 0 {
+            return fmt.Errorf("account %d: balance can't be negative", i)
+        }
+        if numbers[acc.Number] {
+            return fmt.Errorf("account %d: duplicate number %d", i, acc.Number)
+        }
+        numbers[acc.Number] = true
+        currencies[acc.Number] = acc.Currency
+    }
+
+    for i, tx := range f.Transactions {
+        if !numbers[tx.FromAccount] || !numbers[tx.ToAccount] {
+            return fmt.Errorf("transaction %d: unknown account", i)
+        }
+        if tx.Amount <= 0 {
+            return fmt.Errorf("transaction %d: amount must be positive", i)
+        }
+        if currencies[tx.FromAccount] != currencies[tx.ToAccount] {
+            return fmt.Errorf("transaction %d: accounts must share a currency", i)
+        }
+    }
+
+    return nil
+}
+
+func Seed(ctx context.Context, store storage.Storage, f *File) error {
+    for i, a := range f.Accounts {
+        acc, err := types.NewAccount(a.FirstName, a.LastName, a.Password)
+        if err != nil {
+            return err
+        }
+
+        acc.Number = a.Number
+        acc.Email = a.Email
+        if a.Currency != "" {
+            acc.Currency = a.Currency
+        }
+        acc.CreatedAt = timestamp(a.CreatedAt, i)
+
+        if err := store.CreateAccount(ctx, acc); err != nil {
+            return fmt.Errorf("account %d: %w", a.Number, err)
+        }
+
+        if a.Balance == 0 {
+            continue
+        }
+
+        tx := &types.Transaction{
+            Kind: types.TransactionOpening,
+            FromAccount: types.SuspenseAccountNumber,
+            ToAccount: acc.Number,
+            Amount: a.Balance,
+            CreatedAt: acc.CreatedAt,
+        }
+        entries := types.NewEntries(types.SuspenseAccountNumber, acc.Number, a.Balance)
+        if err := store.PostTransaction(ctx, tx, entries); err != nil {
+            return fmt.Errorf("account %d: opening balance: %w", a.Number, err)
+        }
+    }
+
+    for i, t := range f.Transactions {
+        tx := &types.Transaction{
+            Kind: types.TransactionTransfer,
+            FromAccount: t.FromAccount,
+            ToAccount: t.ToAccount,
+            Amount: t.Amount,
+            CreatedAt: timestamp(t.CreatedAt, len(f.Accounts)+i),
+        }
+
+        entries := types.NewEntries(t.FromAccount, t.ToAccount, t.Amount)
+        if err := store.PostTransaction(ctx, tx, entries); err != nil {
+            return fmt.Errorf("transaction %d: %w", i, err)
+        }
+    }
+
+    return nil
+}
+
+func timestamp(t time.Time, i int) time.Time {
+    if t.IsZero() {
+        return baseTime.Add(time.Duration(i) * time.Minute)
+    }
+    return t.UTC()
+}
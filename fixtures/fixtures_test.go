@@ -0,0 +1,51 @@
+package fixtures
+
+import (
+    "context"
+    "testing"
+    "time"
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestLoadYAML(t *testing.T) {
+    f, err := Load("testdata/demo.yaml")
+    assert.Nil(t, err)
+
+    assert.Len(t, f.Accounts, 2)
+    assert.Len(t, f.Transactions, 2)
+    assert.Equal(t, int64(250000), f.Accounts[0].Balance)
+    assert.Equal(t, time.Date(2024, time.February, 1, 9, 30, 0, 0, time.UTC), f.Accounts[1].CreatedAt)
+}
+
+func TestParseRejectsUnknownAccount(t *testing.T) {
+    data := []byte(`{
+        "accounts": [{"firstName": "a", "lastName": "b", "password": "c", "number": 1}],
+        "transactions": [{"fromAccount": 1, "toAccount": 2, "amount": 10}]
+    }`)
+
+    _, err := Parse(data, "json")
+    assert.NotNil(t, err)
+}
+
+func TestSeedPostsOpeningBalancesAndTransfers(t *testing.T) {
+    f, err := Load("testdata/demo.yaml")
+    assert.Nil(t, err)
+
+    store := storagetest.New()
+    ctx := context.Background()
+    assert.Nil(t, Seed(ctx, store, f))
+
+    alice, err := store.GetAccountByNumber(ctx, 1000001)
+    assert.Nil(t, err)
+    assert.Equal(t, int64(250000-5000+1500), alice.Balance)
+
+    balance, err := store.GetLedgerBalance(ctx, 1000001)
+    assert.Nil(t, err)
+    assert.Equal(t, alice.Balance, balance)
+
+    suspense, err := store.GetLedgerBalance(ctx, types.SuspenseAccountNumber)
+    assert.Nil(t, err)
+    assert.Equal(t, int64(-262000), suspense)
+}
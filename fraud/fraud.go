@@ -0,0 +1,115 @@
+package fraud
+
+import (
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+// Rules are the thresholds transfers are checked against. A zero amount or
+// duration turns its rule off.
+type Rules struct {
+    // ReviewAmount flags transfers of at least this much for review and
+    // BlockAmount holds them for an admin.
+    ReviewAmount int64
+    BlockAmount int64
+    // NewPayeeAmount blocks transfers of at least this much to an account
+    // the sender never paid before.
+    NewPayeeAmount int64
+    // AmountWindow makes the amount thresholds apply to the transfer and
+    // what From sent within this long before it, so splitting a transfer
+    // doesn't get under them.
+    AmountWindow time.Duration
+    // DormantAfter flags transfers from an account with no transactions
+    // for this long.
+    DormantAfter time.Duration
+}
+
+// Transfer is what the rules know about a transfer about to be posted.
+// The login fields describe where the sender's session started.
+type Transfer struct {
+    From *types.Account
+    // To is nil for a transfer to an alias nobody registered yet or to an
+    // account at another bank, ToAlias names the payee then.
+    To *types.Account
+    ToAlias string
+    Amount int64
+    // RecentAmount is what From sent within the rules' AmountWindow before
+    // this transfer.
+    RecentAmount int64
+    // NewPayee is set when From never sent money to To.
+    NewPayee bool
+    // LastActivity is the time of From's latest transaction, zero when it
+    // has none.
+    LastActivity time.Time
+    IP string
+    LoginIP string
+    Country string
+    LoginCountry string
+    Now time.Time
+}
+
+type Decision struct {
+    Action string `json:"action"`
+    Reasons []string `json:"reasons"`
+}
+
+// Evaluate runs every rule against t. The decision is the most severe any
+// rule reached, and two or more reasons for review make it a block.
+func Evaluate(rules Rules, t Transfer) Decision {
+    d := Decision{Action: types.FraudAllow, Reasons: []string{}}
+    reviews := 0
+
+    flag := func(action, reason string) {
+        d.Reasons = append(d.Reasons, reason)
+        if action == types.FraudReview {
+            reviews++
+        }
+        if action == types.FraudBlock || reviews > 1 {
+            d.Action = types.FraudBlock
+        } else if d.Action == types.FraudAllow {
+            d.Action = types.FraudReview
+        }
+    }
+
+    sent, amount := t.Amount, fmt.Sprintf("amount %d", t.Amount)
+    if rules.AmountWindow > 0 && t.RecentAmount > 0 {
+        sent += t.RecentAmount
+        amount = fmt.Sprintf("amount %d with %d sent within %s", t.Amount, t.RecentAmount, rules.AmountWindow)
+    }
+    if rules.BlockAmount > 0 && sent >= rules.BlockAmount {
+        flag(types.FraudBlock, fmt.Sprintf("%s is at or above the block threshold of %d", amount, rules.BlockAmount))
+    } else if rules.ReviewAmount > 0 && sent >= rules.ReviewAmount {
+        flag(types.FraudReview, fmt.Sprintf("%s is at or above the review threshold of %d", amount, rules.ReviewAmount))
+    }
+
+    if rules.NewPayeeAmount > 0 && t.NewPayee && t.Amount >= rules.NewPayeeAmount {
+        flag(types.FraudBlock, fmt.Sprintf("amount %d to new payee %s", t.Amount, t.payee()))
+    }
+
+    if t.Country != "" && t.LoginCountry != "" && t.Country != t.LoginCountry {
+        flag(types.FraudReview, fmt.Sprintf("request from %s but the session started in %s", t.Country, t.LoginCountry))
+    } else if t.IP != "" && t.LoginIP != "" && t.IP != t.LoginIP {
+        flag(types.FraudReview, fmt.Sprintf("request from %s but the session started from %s", t.IP, t.LoginIP))
+    }
+
+    if rules.DormantAfter > 0 {
+        since := t.LastActivity
+        if since.IsZero() {
+            since = t.From.CreatedAt
+        }
+        if t.Now.Sub(since) >= rules.DormantAfter {
+            flag(types.FraudReview, fmt.Sprintf("account inactive since %s", since.Format(time.DateOnly)))
+        }
+    }
+
+    return d
+}
+
+func (t Transfer) payee() string {
+    if t.To == nil {
+        return t.ToAlias
+    }
+    return fmt.Sprint(t.To.Number)
+}
@@ -0,0 +1,63 @@
+package fraud
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/types"
+)
+
+func TestEvaluate(t *testing.T) {
+    now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+    rules := Rules{ReviewAmount: 1000, BlockAmount: 10000, NewPayeeAmount: 500, DormantAfter: 90 * 24 * time.Hour}
+    from := &types.Account{Number: 1, CreatedAt: now.AddDate(-1, 0, 0)}
+    to := &types.Account{Number: 2}
+    base := Transfer{From: from, To: to, LastActivity: now.AddDate(0, 0, -1), IP: "10.0.0.1", LoginIP: "10.0.0.1", Now: now}
+
+    small := base
+    small.Amount = 100
+    assert.Equal(t, types.FraudAllow, Evaluate(rules, small).Action)
+
+    large := base
+    large.Amount = 2000
+    assert.Equal(t, types.FraudReview, Evaluate(rules, large).Action)
+
+    huge := base
+    huge.Amount = 10000
+    assert.Equal(t, types.FraudBlock, Evaluate(rules, huge).Action)
+
+    newPayee := base
+    newPayee.Amount = 600
+    newPayee.NewPayee = true
+    assert.Equal(t, types.FraudBlock, Evaluate(rules, newPayee).Action)
+
+    unclaimed := newPayee
+    unclaimed.To, unclaimed.ToAlias = nil, "bob@example.com"
+    d := Evaluate(rules, unclaimed)
+    assert.Equal(t, types.FraudBlock, d.Action)
+    assert.Equal(t, "amount 600 to new payee bob@example.com", d.Reasons[0])
+
+    // one reason for review is a review, two are a block
+    moved := small
+    moved.IP = "10.0.0.2"
+    assert.Equal(t, types.FraudReview, Evaluate(rules, moved).Action)
+
+    moved.LastActivity = now.AddDate(0, -6, 0)
+    d = Evaluate(rules, moved)
+    assert.Equal(t, types.FraudBlock, d.Action)
+    assert.Len(t, d.Reasons, 2)
+
+    // within the amount window earlier transfers count toward the thresholds
+    split := small
+    split.RecentAmount = 9900
+    assert.Equal(t, types.FraudAllow, Evaluate(rules, split).Action)
+    windowed := rules
+    windowed.AmountWindow = 24 * time.Hour
+    d = Evaluate(windowed, split)
+    assert.Equal(t, types.FraudBlock, d.Action)
+    assert.Equal(t, "amount 100 with 9900 sent within 24h0m0s is at or above the block threshold of 10000", d.Reasons[0])
+
+    // a disabled rule never fires
+    assert.Equal(t, types.FraudAllow, Evaluate(Rules{}, huge).Action)
+}
@@ -0,0 +1,135 @@
+package fx
+
+import (
+    "fmt"
+    "math/big"
+    "strconv"
+    "strings"
+)
+
+const BaseCurrency = "USD"
+
+// RateScale is the fixed point of rates: a rate of RateScale is 1, so rates
+// have up to 8 decimals and money is never converted through a float.
+const RateScale = 100_000_000
+
+// Rates holds the value of one unit of each currency in BaseCurrency, in
+// units of 1/RateScale.
+type Rates map[string]int64
+
+func DefaultRates() Rates {
+    return Rates{BaseCurrency: RateScale}
+}
+
+// ParseRates reads a list like "EUR=1.08,GBP=1.27" on top of the base
+// currency.
+func ParseRates(s string) (Rates, error) {
+    rates := DefaultRates()
+    if s == "" {
+        return rates, nil
+    }
+
+    for _, pair := range strings.Split(s, ",") {
+        currency, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+        if !ok {
+            return nil, fmt.Errorf("invalid fx rate %q, want CUR=rate", pair)
+        }
+
+        rate, err := ParseRate(value)
+        if err != nil {
+            return nil, fmt.Errorf("invalid fx rate %q: %w", pair, err)
+        }
+        rates[strings.ToUpper(currency)] = rate
+    }
+
+    return rates, nil
+}
+
+// ParseRate reads a positive decimal like "1.08" exactly, in units of
+// 1/RateScale.
+func ParseRate(s string) (int64, error) {
+    whole, frac, _ := strings.Cut(s, ".")
+    if whole == "" && frac == "" {
+        return 0, fmt.Errorf("empty rate")
+    }
+    if len(frac) > 8 {
+        return 0, fmt.Errorf("more than 8 decimals")
+    }
+
+    var rate int64
+    if whole != "" {
+        n, err := strconv.ParseUint(whole, 10, 63)
+        if err != nil || n > (1<<63-1)/RateScale {
+            return 0, fmt.Errorf("not a decimal number")
+        }
+        rate = int64(n) * RateScale
+    }
+    if frac != "" {
+        n, err := strconv.ParseUint(frac+strings.Repeat("0", 8-len(frac)), 10, 63)
+        if err != nil {
+            return 0, fmt.Errorf("not a decimal number")
+        }
+        rate += int64(n)
+    }
+    if rate <= 0 {
+        return 0, fmt.Errorf("rate must be positive")
+    }
+
+    return rate, nil
+}
+
+func (r Rates) Supports(currency string) bool {
+    _, ok := r[currency]
+    return ok
+}
+
+// Rate returns how many units of to one unit of from buys. It is only for
+// showing the rate; Convert works on the exact rates.
+func (r Rates) Rate(from, to string) (float64, error) {
+    fromRate, toRate, err := r.pair(from, to)
+    if err != nil {
+        return 0, err
+    }
+
+    return float64(fromRate) / float64(toRate), nil
+}
+
+// Convert converts amount from one currency to another, rounding to the
+// nearest minor unit and halves away from zero.
+func (r Rates) Convert(amount int64, from, to string) (int64, error) {
+    if from == to {
+        return amount, nil
+    }
+
+    fromRate, toRate, err := r.pair(from, to)
+    if err != nil {
+        return 0, err
+    }
+
+    // amount * fromRate / toRate, which can overflow int64 before the
+    // division
+    num := new(big.Int).Mul(big.NewInt(amount), big.NewInt(fromRate))
+    den := big.NewInt(toRate)
+    q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+    if m.Abs(m).Lsh(m, 1).Cmp(den) >= 0 {
+        q.Add(q, big.NewInt(int64(num.Sign())))
+    }
+    if !q.IsInt64() {
+        return 0, fmt.Errorf("converting %d %s to %s overflows", amount, from, to)
+    }
+
+    return q.Int64(), nil
+}
+
+func (r Rates) pair(from, to string) (int64, int64, error) {
+    fromRate, ok := r[from]
+    if !ok {
+        return 0, 0, fmt.Errorf("unsupported currency %s", from)
+    }
+    toRate, ok := r[to]
+    if !ok {
+        return 0, 0, fmt.Errorf("unsupported currency %s", to)
+    }
+
+    return fromRate, toRate, nil
+}
@@ -0,0 +1,50 @@
+package fx
+
+import (
+    "math"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestParseRates(t *testing.T) {
+    rates, err := ParseRates("EUR=1.08, gbp=1.27,JPY=0.00667123")
+    assert.NoError(t, err)
+    assert.Equal(t, Rates{"USD": 100000000, "EUR": 108000000, "GBP": 127000000, "JPY": 667123}, rates)
+
+    for _, s := range []string{"EUR", "EUR=", "EUR=0", "EUR=-1.08", "EUR=1.0x", "EUR=1.123456789", "EUR=1e3", "EUR=99999999999"} {
+        _, err := ParseRates(s)
+        assert.Error(t, err, s)
+    }
+}
+
+func TestConvert(t *testing.T) {
+    rates, _ := ParseRates("EUR=1.08,XYZ=1.005")
+
+    converted, err := rates.Convert(1000, "EUR", "USD")
+    assert.NoError(t, err)
+    assert.Equal(t, int64(1080), converted)
+
+    converted, err = rates.Convert(1080, "USD", "EUR")
+    assert.NoError(t, err)
+    assert.Equal(t, int64(1000), converted)
+
+    // 100.5 exactly, which 100 * 1.005 as a float64 falls just short of
+    converted, _ = rates.Convert(100, "XYZ", "USD")
+    assert.Equal(t, int64(101), converted)
+    converted, _ = rates.Convert(-100, "XYZ", "USD")
+    assert.Equal(t, int64(-101), converted)
+    // 99.5024..., rounded down
+    converted, _ = rates.Convert(100, "USD", "XYZ")
+    assert.Equal(t, int64(100), converted)
+
+    converted, _ = rates.Convert(1<<62, "USD", "USD")
+    assert.Equal(t, int64(1<<62), converted)
+    _, err = rates.Convert(math.MaxInt64, "EUR", "USD")
+    assert.Error(t, err)
+    _, err = rates.Convert(100, "EUR", "CHF")
+    assert.Error(t, err)
+
+    rate, _ := rates.Rate("EUR", "USD")
+    assert.InDelta(t, 1.08, rate, 1e-9)
+}
@@ -1,15 +1,17 @@
 module gobank
 
-go 1.18
+go 1.24
+
+require (
+	github.com/golang-jwt/jwt/v4 v4.5.0
+	github.com/lib/pq v1.10.9
+	github.com/stretchr/testify v1.8.2
+	golang.org/x/crypto v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/golang-jwt/jwt/v4 v4.5.0 // indirect
-	github.com/gorilla/mux v1.8.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.0 // indirect
-	github.com/stretchr/testify v1.8.2 // indirect
-	golang.org/x/crypto v0.8.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
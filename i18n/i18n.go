@@ -0,0 +1,162 @@
+package i18n
+
+import (
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const DefaultLocale = "en-US"
+
+// format is how a locale writes numbers, amounts and dates.
+type format struct {
+    decimal string
+    group string
+    // symbolAfter puts the currency symbol after the amount, e.g. 1.234,56 €,
+    // separated by a no-break space.
+    symbolAfter bool
+    date string
+}
+
+var locales = map[string]format{
+    "en-US": {decimal: ".", group: ",", date: "01/02/2006"},
+    "en-GB": {decimal: ".", group: ",", date: "02/01/2006"},
+    "de-DE": {decimal: ",", group: ".", symbolAfter: true, date: "02.01.2006"},
+    "es-ES": {decimal: ",", group: ".", symbolAfter: true, date: "02/01/2006"},
+    "fr-FR": {decimal: ",", group: "\u202f", symbolAfter: true, date: "02/01/2006"},
+}
+
+// languages maps a language to the locale used when its region isn't
+// supported.
+var languages = map[string]string{
+    "en": "en-US",
+    "de": "de-DE",
+    "es": "es-ES",
+    "fr": "fr-FR",
+}
+
+var symbols = map[string]string{
+    "USD": "$",
+    "EUR": "€",
+    "GBP": "£",
+}
+
+func Supported(locale string) bool {
+    _, ok := locales[locale]
+    return ok
+}
+
+// Match picks the supported locale that fits an Accept-Language header
+// best, e.g. "de-AT,de;q=0.9,en;q=0.5" gives de-DE. A bare language
+// matches the first supported locale for it. It falls back to
+// DefaultLocale.
+func Match(acceptLanguage string) string {
+    type tag struct {
+        name string
+        q float64
+    }
+
+    var tags []tag
+    for _, part := range strings.Split(acceptLanguage, ",") {
+        name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+        if name == "" || name == "*" {
+            continue
+        }
+        q := 1.0
+        if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+            parsed, err := strconv.ParseFloat(v, 64)
+            if err != nil {
+                continue
+            }
+            q = parsed
+        }
+        if q > 0 {
+            tags = append(tags, tag{name, q})
+        }
+    }
+    sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+    for _, t := range tags {
+        if locale := canonical(t.name); locale != "" {
+            return locale
+        }
+    }
+
+    return DefaultLocale
+}
+
+// canonical returns the supported locale for a language tag, or "".
+func canonical(name string) string {
+    lang, region, _ := strings.Cut(strings.ReplaceAll(name, "_", "-"), "-")
+    lang = strings.ToLower(lang)
+
+    if region != "" {
+        if locale := lang + "-" + strings.ToUpper(region); Supported(locale) {
+            return locale
+        }
+    }
+
+    // the language alone, e.g. de-AT or de gives de-DE
+    return languages[lang]
+}
+
+func lookup(locale string) format {
+    if f, ok := locales[locale]; ok {
+        return f
+    }
+    return locales[DefaultLocale]
+}
+
+// FormatNumber writes n with the locale's thousands separator.
+func FormatNumber(locale string, n int64) string {
+    return group(lookup(locale), n)
+}
+
+// FormatMoney writes an amount in minor units with its currency, e.g.
+// $1,234.56 in en-US or 1.234,56 € in de-DE. Currencies without a known
+// symbol use their code, and an empty currency writes the number alone.
+func FormatMoney(locale string, amount int64, currency string) string {
+    f := lookup(locale)
+
+    sign := ""
+    if amount < 0 {
+        sign = "-"
+        amount = -amount
+    }
+    number := group(f, amount/100) + f.decimal + strconv.FormatInt(100+amount%100, 10)[1:]
+
+    symbol, ok := symbols[currency]
+    switch {
+    case currency == "":
+        return sign + number
+    case !ok:
+        return sign + number + " " + currency
+    case f.symbolAfter:
+        return sign + number + "\u00a0" + symbol
+    default:
+        return sign + symbol + number
+    }
+}
+
+func FormatDate(locale string, t time.Time) string {
+    return t.Format(lookup(locale).date)
+}
+
+func group(f format, n int64) string {
+    digits := strconv.FormatInt(n, 10)
+    sign := ""
+    if n < 0 {
+        sign, digits = "-", digits[1:]
+    }
+
+    var b strings.Builder
+    for i, d := range digits {
+        if i > 0 && (len(digits)-i)%3 == 0 {
+            b.WriteString(f.group)
+        }
+        b.WriteRune(d)
+    }
+
+    return sign + b.String()
+}
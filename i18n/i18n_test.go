@@ -0,0 +1,42 @@
+package i18n
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestFormatMoney(t *testing.T) {
+    assert.Equal(t, "$1,234.56", FormatMoney("en-US", 123456, "USD"))
+    assert.Equal(t, "1.234,56\u00a0€", FormatMoney("de-DE", 123456, "EUR"))
+    assert.Equal(t, "-£0.05", FormatMoney("en-GB", -5, "GBP"))
+    assert.Equal(t, "1\u202f000\u202f000,00 CHF", FormatMoney("fr-FR", 100000000, "CHF"))
+    assert.Equal(t, "$12.00", FormatMoney("xx-XX", 1200, "USD"))
+}
+
+func TestFormatDate(t *testing.T) {
+    d := time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)
+    assert.Equal(t, "03/07/2024", FormatDate("en-US", d))
+    assert.Equal(t, "07.03.2024", FormatDate("de-DE", d))
+}
+
+func TestMatch(t *testing.T) {
+    assert.Equal(t, "de-DE", Match("de-AT,de;q=0.9,en;q=0.5"))
+    assert.Equal(t, "en-GB", Match("en-gb"))
+    assert.Equal(t, "en-US", Match("en"))
+    assert.Equal(t, "fr-FR", Match("it;q=0.9, fr;q=0.8"))
+    assert.Equal(t, DefaultLocale, Match(""))
+    assert.Equal(t, DefaultLocale, Match("ja"))
+}
+
+func TestMessage(t *testing.T) {
+    assert.Equal(t, "Nicht genügend Guthaben", Message("de-DE", InsufficientFunds))
+    assert.Equal(t, "Insufficient funds", Message("en-GB", InsufficientFunds))
+    assert.Equal(t, "Insufficient funds", Message("ja-JP", InsufficientFunds))
+    assert.Equal(t, "some_code", Message("de-DE", "some_code"))
+
+    for code, texts := range messages {
+        assert.NotEmpty(t, texts["en"], code)
+    }
+}
@@ -0,0 +1,122 @@
+package i18n
+
+import (
+    "strings"
+)
+
+// Message codes are stable, so clients can act on them whatever language
+// the text comes in.
+const (
+    AccountClosed = "account_closed"
+    AccountDormant = "account_dormant"
+    InvalidCredentials = "invalid_credentials"
+    InsufficientFunds = "insufficient_funds"
+    LimitExceeded = "limit_exceeded"
+    Overloaded = "overloaded"
+    Maintenance = "maintenance"
+    QuotaExceeded = "quota_exceeded"
+    PermissionDenied = "permission_denied"
+    StepUpRequired = "step_up_required"
+    StorageUnavailable = "storage_unavailable"
+    Timeout = "timeout"
+    TransferBlocked = "transfer_blocked"
+)
+
+// messages holds the text for each code by language. Every code has an
+// English text.
+var messages = map[string]map[string]string{
+    AccountClosed: {
+        "en": "This account is closed",
+        "de": "Dieses Konto ist geschlossen",
+        "es": "Esta cuenta está cerrada",
+        "fr": "Ce compte est clôturé",
+    },
+    AccountDormant: {
+        "en": "This account is dormant, reactivate it at /account/{id}/reactivate first",
+        "de": "Dieses Konto ist inaktiv, reaktiviere es zuerst unter /account/{id}/reactivate",
+        "es": "Esta cuenta está inactiva, reactívala primero en /account/{id}/reactivate",
+        "fr": "Ce compte est dormant, réactivez-le d'abord sur /account/{id}/reactivate",
+    },
+    InvalidCredentials: {
+        "en": "Either number or password is incorrect",
+        "de": "Kontonummer oder Passwort ist falsch",
+        "es": "El número o la contraseña son incorrectos",
+        "fr": "Le numéro ou le mot de passe est incorrect",
+    },
+    InsufficientFunds: {
+        "en": "Insufficient funds",
+        "de": "Nicht genügend Guthaben",
+        "es": "Fondos insuficientes",
+        "fr": "Fonds insuffisants",
+    },
+    LimitExceeded: {
+        "en": "Too many transfers in a short time, try again later",
+        "de": "Zu viele Überweisungen in kurzer Zeit, bitte später erneut versuchen",
+        "es": "Demasiadas transferencias en poco tiempo, inténtalo más tarde",
+        "fr": "Trop de virements en peu de temps, réessayez plus tard",
+    },
+    QuotaExceeded: {
+        "en": "Usage quota exceeded",
+        "de": "Nutzungskontingent überschritten",
+        "es": "Cuota de uso superada",
+        "fr": "Quota d'utilisation dépassé",
+    },
+    Maintenance: {
+        "en": "The bank is in maintenance, only reads are possible right now",
+        "de": "Die Bank wird gewartet, gerade ist nur Lesen möglich",
+        "es": "El banco está en mantenimiento, ahora solo se pueden hacer consultas",
+        "fr": "La banque est en maintenance, seule la consultation est possible",
+    },
+    Overloaded: {
+        "en": "Too many requests right now, try again shortly",
+        "de": "Gerade zu viele Anfragen, bitte gleich erneut versuchen",
+        "es": "Demasiadas solicitudes en este momento, inténtalo de nuevo en breve",
+        "fr": "Trop de requêtes en ce moment, réessayez dans un instant",
+    },
+    PermissionDenied: {
+        "en": "Permission denied",
+        "de": "Zugriff verweigert",
+        "es": "Permiso denegado",
+        "fr": "Accès refusé",
+    },
+    StepUpRequired: {
+        "en": "Confirm the code sent to you at /login/step-up first",
+        "de": "Bestätige zuerst den Code, den wir dir geschickt haben, unter /login/step-up",
+        "es": "Confirma primero el código que te enviamos en /login/step-up",
+        "fr": "Confirmez d'abord le code qui vous a été envoyé sur /login/step-up",
+    },
+    StorageUnavailable: {
+        "en": "Service temporarily unavailable",
+        "de": "Dienst vorübergehend nicht verfügbar",
+        "es": "Servicio no disponible temporalmente",
+        "fr": "Service temporairement indisponible",
+    },
+    Timeout: {
+        "en": "Request timed out",
+        "de": "Zeitüberschreitung der Anfrage",
+        "es": "La solicitud ha caducado",
+        "fr": "La requête a expiré",
+    },
+    TransferBlocked: {
+        "en": "This transfer was blocked by our fraud checks",
+        "de": "Diese Überweisung wurde von unserer Betrugsprüfung blockiert",
+        "es": "Esta transferencia fue bloqueada por nuestros controles de fraude",
+        "fr": "Ce virement a été bloqué par nos contrôles anti-fraude",
+    },
+}
+
+// Message returns the text for code in locale's language, or in English
+// when there is no translation. Unknown codes come back as they are.
+func Message(locale, code string) string {
+    texts, ok := messages[code]
+    if !ok {
+        return code
+    }
+
+    lang, _, _ := strings.Cut(locale, "-")
+    if text, ok := texts[lang]; ok {
+        return text
+    }
+
+    return texts["en"]
+}
@@ -0,0 +1,350 @@
+package importer
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/csv"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/mail"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "gobank/fx"
+    "gobank/i18n"
+    "gobank/numbering"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// Row is one account to import. The columns of a CSV file are named by its
+// header row with the JSON field names, e.g. firstName,lastName,balance.
+type Row struct {
+    FirstName string `json:"firstName"`
+    LastName string `json:"lastName"`
+    Email string `json:"email"`
+    // Password is hashed on import. Rows without one get an activation
+    // token instead.
+    Password string `json:"password"`
+    // Number keeps the account number from the old system. A new one is
+    // drawn when it is zero.
+    Number int64 `json:"number"`
+    Balance int64 `json:"balance"`
+    Currency string `json:"currency"`
+    Locale string `json:"locale"`
+
+    // err is why the row couldn't be read, reported instead of importing it
+    err error
+}
+
+// Result reports every row of an import, numbered from 1, as either
+// imported or failed.
+type Result struct {
+    Imported []Imported `json:"imported"`
+    Errors []RowError `json:"errors"`
+}
+
+type Imported struct {
+    Row int `json:"row"`
+    Number int64 `json:"number"`
+    // ActivationToken has to reach the account holder, who redeems it at
+    // /login/activate to choose a password. It is only shown here.
+    ActivationToken string `json:"activationToken,omitempty"`
+}
+
+type RowError struct {
+    Row int `json:"row"`
+    Error string `json:"error"`
+}
+
+var columns = map[string]func(r *Row, v string) error{
+    "firstname": func(r *Row, v string) error { r.FirstName = v; return nil },
+    "lastname": func(r *Row, v string) error { r.LastName = v; return nil },
+    "email": func(r *Row, v string) error { r.Email = v; return nil },
+    "password": func(r *Row, v string) error { r.Password = v; return nil },
+    "currency": func(r *Row, v string) error { r.Currency = v; return nil },
+    "locale": func(r *Row, v string) error { r.Locale = v; return nil },
+    "number": func(r *Row, v string) error { return parseInt("number", v, &r.Number) },
+    "balance": func(r *Row, v string) error { return parseInt("balance", v, &r.Balance) },
+}
+
+func parseInt(name, v string, dst *int64) error {
+    if v == "" {
+        return nil
+    }
+
+    n, err := strconv.ParseInt(v, 10, 64)
+    if err != nil {
+        return fmt.Errorf("%s must be a whole number, got %q", name, v)
+    }
+    *dst = n
+
+    return nil
+}
+
+func Load(path string) ([]Row, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    switch filepath.Ext(path) {
+    case ".csv":
+        return Parse(f, "csv")
+    case ".json":
+        return Parse(f, "json")
+    }
+
+    return nil, fmt.Errorf("unsupported import file %s, use .csv or .json", path)
+}
+
+// Parse reads rows from a CSV file with a header row or a JSON array. A
+// field that can't be read only fails its own row, when it is imported.
+func Parse(r io.Reader, format string) ([]Row, error) {
+    switch format {
+    case "csv":
+        return parseCSV(r)
+    case "json":
+        rows := []Row{}
+        if err := json.NewDecoder(r).Decode(&rows); err != nil {
+            return nil, err
+        }
+        return rows, nil
+    }
+
+    return nil, fmt.Errorf("unsupported import format %s", format)
+}
+
+func parseCSV(r io.Reader) ([]Row, error) {
+    cr := csv.NewReader(r)
+    cr.TrimLeadingSpace = true
+
+    header, err := cr.Read()
+    if err != nil {
+        return nil, fmt.Errorf("reading header: %w", err)
+    }
+
+    setters := make([]func(*Row, string) error, len(header))
+    for i, name := range header {
+        set, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+        if !ok {
+            return nil, fmt.Errorf("unknown column %q", name)
+        }
+        setters[i] = set
+    }
+
+    rows := []Row{}
+    for {
+        record, err := cr.Read()
+        if err == io.EOF {
+            return rows, nil
+        }
+        if err != nil {
+            return nil, err
+        }
+
+        var row Row
+        for i, v := range record {
+            if err := setters[i](&row, strings.TrimSpace(v)); err != nil && row.err == nil {
+                row.err = err
+            }
+        }
+        rows = append(rows, row)
+    }
+}
+
+// Import validates the rows and imports the valid ones batchSize at a
+// time, each batch in one transaction. A batch that fails is reported
+// against each of its rows and the import goes on with the next one, but
+// one that lost a number to another account is imported row by row, with
+// the drawn numbers drawn again. Activation tokens expire after
+// activationTTL.
+func Import(ctx context.Context, store storage.Storage, rates fx.Rates, rows []Row, batchSize int, activationTTL time.Duration) (*Result, error) {
+    res := &Result{Imported: []Imported{}, Errors: []RowError{}}
+    now := time.Now().UTC()
+
+    type pending struct {
+        row int
+        imp *types.AccountImport
+        token string
+    }
+    var batch []pending
+
+    flush := func() error {
+        if len(batch) == 0 {
+            return nil
+        }
+
+        imports := make([]*types.AccountImport, len(batch))
+        for i, p := range batch {
+            imports[i] = p.imp
+        }
+
+        err := store.ImportAccounts(ctx, imports)
+        for _, p := range batch {
+            rowErr := err
+            if errors.Is(err, storage.ErrNumberTaken) {
+                rowErr = importOne(ctx, store, p.imp, rows[p.row-1].Number == 0)
+            }
+            if rowErr != nil {
+                res.Errors = append(res.Errors, RowError{Row: p.row, Error: rowErr.Error()})
+                continue
+            }
+            res.Imported = append(res.Imported, Imported{Row: p.row, Number: p.imp.Account.Number, ActivationToken: p.token})
+        }
+        batch = batch[:0]
+
+        return ctx.Err()
+    }
+
+    seen := map[int64]bool{}
+    for i, row := range rows {
+        imp, token, err := prepare(ctx, store, rates, row, seen, now.Add(activationTTL))
+        if err != nil {
+            if ctx.Err() != nil {
+                return res, ctx.Err()
+            }
+            res.Errors = append(res.Errors, RowError{Row: i + 1, Error: err.Error()})
+            continue
+        }
+        seen[imp.Account.Number] = true
+
+        batch = append(batch, pending{row: i + 1, imp: imp, token: token})
+        if len(batch) >= batchSize {
+            if err := flush(); err != nil {
+                return res, err
+            }
+        }
+    }
+
+    return res, flush()
+}
+
+// prepare checks the row and turns it into an account, hashing its
+// password or creating its activation.
+func prepare(ctx context.Context, store storage.Storage, rates fx.Rates, row Row, seen map[int64]bool, expiresAt time.Time) (*types.AccountImport, string, error) {
+    if row.err != nil {
+        return nil, "", row.err
+    }
+    if row.FirstName == "" || row.LastName == "" {
+        return nil, "", fmt.Errorf("first and last name are required")
+    }
+    if row.Email != "" {
+        if _, err := mail.ParseAddress(row.Email); err != nil {
+            return nil, "", fmt.Errorf("invalid email address %q", row.Email)
+        }
+    }
+    if row.Currency == "" {
+        row.Currency = fx.BaseCurrency
+    }
+    row.Currency = strings.ToUpper(row.Currency)
+    if !rates.Supports(row.Currency) {
+        return nil, "", fmt.Errorf("unsupported currency %s", row.Currency)
+    }
+    if row.Locale != "" && !i18n.Supported(row.Locale) {
+        return nil, "", fmt.Errorf("unsupported locale %s", row.Locale)
+    }
+    if row.Balance < 0 {
+        return nil, "", fmt.Errorf("balance can't be negative")
+    }
+    if row.Number < 0 {
+        return nil, "", fmt.Errorf("number can't be negative")
+    }
+
+    number := row.Number
+    if number == 0 {
+        var err error
+        if number, err = types.NewAccountNumber(); err != nil {
+            return nil, "", err
+        }
+    } else if err := checkNumber(ctx, store, number, seen); err != nil {
+        return nil, "", err
+    }
+
+    acc := &types.Account{
+        FirstName: row.FirstName,
+        LastName: row.LastName,
+        Email: row.Email,
+        Number: number,
+        Currency: row.Currency,
+        Locale: row.Locale,
+        CreatedAt: time.Now().UTC(),
+    }
+    imp := &types.AccountImport{Account: acc, Balance: row.Balance}
+
+    if row.Password != "" {
+        var err error
+        acc.EncryptedPassword, err = types.HashPassword(row.Password)
+        return imp, "", err
+    }
+
+    token, err := NewToken()
+    if err != nil {
+        return nil, "", err
+    }
+    imp.Activation = &types.AccountActivation{
+        AccountNumber: number,
+        TokenHash: HashToken(token),
+        ExpiresAt: expiresAt,
+    }
+
+    return imp, token, nil
+}
+
+// checkNumber reports a number given in the file that is in use already,
+// before its batch fails on it.
+func checkNumber(ctx context.Context, store storage.Storage, number int64, seen map[int64]bool) error {
+    taken := seen[number]
+    if !taken {
+        _, err := store.GetAccountByNumber(ctx, number)
+        if err != nil && !errors.Is(err, storage.ErrNotFound) {
+            return err
+        }
+        taken = err == nil
+    }
+    if taken {
+        return fmt.Errorf("account number %d is already in use", number)
+    }
+
+    return nil
+}
+
+// importOne imports a single account, drawing its number again until it
+// gets a free one when drawn is set.
+func importOne(ctx context.Context, store storage.Storage, imp *types.AccountImport, drawn bool) error {
+    create := func(ctx context.Context, acc *types.Account) error {
+        if imp.Activation != nil {
+            imp.Activation.AccountNumber = acc.Number
+        }
+        return store.ImportAccounts(ctx, []*types.AccountImport{imp})
+    }
+    if !drawn {
+        return create(ctx, imp.Account)
+    }
+
+    return numbering.New(store).CreateWith(ctx, imp.Account, create)
+}
+
+// NewToken returns a random activation token.
+func NewToken() (string, error) {
+    b := make([]byte, 32)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hash an activation token is stored and looked up
+// by.
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/fx"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+const sample = `firstName,lastName,email,password,number,balance,currency
+alice,a,alice@example.com,pw,1000001,25000,USD
+bob,b,,,1000002,0,
+carol,c,not-an-email,pw,1000003,0,USD
+dave,d,,pw,1000001,0,USD
+erin,e,,pw,abc,0,USD
+`
+
+func TestImportReportsRowErrors(t *testing.T) {
+    rows, err := Parse(strings.NewReader(sample), "csv")
+    assert.Nil(t, err)
+    assert.Len(t, rows, 5)
+
+    store := storagetest.New()
+    ctx := context.Background()
+    res, err := Import(ctx, store, fx.DefaultRates(), rows, 100, time.Hour)
+    assert.Nil(t, err)
+
+    assert.Len(t, res.Imported, 2)
+    assert.Equal(t, []int{3, 4, 5}, []int{res.Errors[0].Row, res.Errors[1].Row, res.Errors[2].Row})
+    assert.Contains(t, res.Errors[1].Error, "already in use")
+
+    alice, err := store.GetAccountByNumber(ctx, 1000001)
+    assert.Nil(t, err)
+    assert.Equal(t, int64(25000), alice.Balance)
+    assert.True(t, alice.ValidatePassword("pw"))
+    assert.Equal(t, "", res.Imported[0].ActivationToken)
+
+    // bob has no password until he redeems his token
+    bob, err := store.GetAccountByNumber(ctx, 1000002)
+    assert.Nil(t, err)
+    assert.False(t, bob.ValidatePassword(""))
+    token := res.Imported[1].ActivationToken
+    assert.NotEmpty(t, token)
+
+    encpw, _ := types.HashPassword("new-pw")
+    assert.NotNil(t, store.ActivateAccount(ctx, bob.Number, HashToken("wrong"), encpw, time.Now()))
+    assert.Nil(t, store.ActivateAccount(ctx, bob.Number, HashToken(token), encpw, time.Now()))
+    bob, _ = store.GetAccountByNumber(ctx, 1000002)
+    assert.True(t, bob.ValidatePassword("new-pw"))
+}
+
+func TestImportReportsFailedBatches(t *testing.T) {
+    rows := []Row{
+        {FirstName: "a", LastName: "a", Password: "pw", Number: 1},
+        {FirstName: "b", LastName: "b", Password: "pw", Number: 2},
+        {FirstName: "c", LastName: "c", Password: "pw", Number: 3},
+    }
+
+    store := storagetest.New()
+    store.FailOn("ImportAccounts", errors.New("boom"))
+
+    res, err := Import(context.Background(), store, fx.DefaultRates(), rows, 2, time.Hour)
+    assert.Nil(t, err)
+    assert.Len(t, res.Imported, 0)
+    assert.Len(t, res.Errors, 3)
+}
+
+// racingStore creates an account under the first number of every batch
+// just before the batch is imported, like a concurrent sign-up would.
+type racingStore struct {
+    *storagetest.Store
+    raced bool
+}
+
+func (s *racingStore) ImportAccounts(ctx context.Context, imports []*types.AccountImport) error {
+    if !s.raced {
+        s.raced = true
+        s.CreateAccount(ctx, &types.Account{FirstName: "x", LastName: "y", Number: imports[0].Account.Number})
+    }
+    return s.Store.ImportAccounts(ctx, imports)
+}
+
+func TestImportDrawsAgainWhenANumberIsTaken(t *testing.T) {
+    rows := []Row{
+        {FirstName: "a", LastName: "a"},
+        {FirstName: "b", LastName: "b", Password: "pw", Number: 2},
+    }
+
+    store := &racingStore{Store: storagetest.New()}
+    ctx := context.Background()
+    res, err := Import(ctx, store, fx.DefaultRates(), rows, 100, time.Hour)
+    assert.Nil(t, err)
+    assert.Empty(t, res.Errors)
+    if !assert.Len(t, res.Imported, 2) {
+        return
+    }
+
+    accounts, _ := store.GetAccounts(ctx)
+    assert.Len(t, accounts, 3)
+    a, err := store.GetAccountByNumber(ctx, res.Imported[0].Number)
+    assert.Nil(t, err)
+    assert.Equal(t, "a", a.FirstName)
+
+    // the activation moved to the new number
+    encpw, _ := types.HashPassword("pw")
+    assert.Nil(t, store.ActivateAccount(ctx, a.Number, HashToken(res.Imported[0].ActivationToken), encpw, time.Now()))
+}
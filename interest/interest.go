@@ -0,0 +1,73 @@
+package interest
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/products"
+    "gobank/snapshot"
+    "gobank/storage"
+    "gobank/types"
+)
+
+// Provider marks interest payments, whose Reference makes sure an account
+// is paid for a day only once.
+const Provider = "interest"
+
+// Accrue pays every account a day of interest on its balance at the end
+// of day, at the savings rate in effect that day or fallbackBPS when no
+// product rate sets it. Fractions of a minor unit are dropped. It returns
+// how many accounts were paid.
+func Accrue(ctx context.Context, store storage.Storage, day time.Time, fallbackBPS int64) (int, error) {
+    day = snapshot.Day(day)
+
+    rate, err := products.Lookup(ctx, store, types.ProductSavingsRateBPS, day, fallbackBPS)
+    if err != nil || rate <= 0 {
+        return 0, err
+    }
+
+    accounts, err := store.GetAccounts(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    paid := 0
+    for _, acc := range accounts {
+        ref := fmt.Sprintf("%d/%s", acc.Number, day.Format("2006-01-02"))
+        _, err := store.GetTransactionByReference(ctx, Provider, ref)
+        if err == nil {
+            continue
+        }
+        if !errors.Is(err, storage.ErrNotFound) {
+            return paid, err
+        }
+
+        balance, err := snapshot.BalanceAt(ctx, store, acc.Number, day)
+        if err != nil {
+            return paid, err
+        }
+        amount := balance * rate / 10000 / 365
+        if amount <= 0 {
+            continue
+        }
+
+        tx := &types.Transaction{
+            Kind: types.TransactionInterest,
+            FromAccount: types.InterestAccountNumber,
+            ToAccount: acc.Number,
+            Amount: amount,
+            Provider: Provider,
+            Reference: ref,
+            CreatedAt: time.Now().UTC(),
+        }
+        entries := types.NewEntries(types.InterestAccountNumber, acc.Number, amount)
+        if err := store.PostTransaction(ctx, tx, entries); err != nil {
+            return paid, err
+        }
+        paid++
+    }
+
+    return paid, nil
+}
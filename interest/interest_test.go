@@ -0,0 +1,38 @@
+package interest
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestAccruePaysOncePerDay(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+
+    acc, _ := types.NewAccount("a", "b", "pw")
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+    day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+    opening := &types.Transaction{Kind: types.TransactionOpening, Amount: 3650000, CreatedAt: day.Add(time.Hour)}
+    assert.Nil(t, store.PostTransaction(ctx, opening, types.NewEntries(types.SettlementAccountNumber, acc.Number, 3650000)))
+
+    // no rate, no interest
+    n, err := Accrue(ctx, store, day, 0)
+    assert.Nil(t, err)
+    assert.Equal(t, 0, n)
+
+    // 1% a year on 36,500.00 is 1.00 a day
+    n, err = Accrue(ctx, store, day, 100)
+    assert.Nil(t, err)
+    assert.Equal(t, 1, n)
+    n, err = Accrue(ctx, store, day, 100)
+    assert.Nil(t, err)
+    assert.Equal(t, 0, n)
+
+    got, _ := store.GetAccountByNumber(ctx, acc.Number)
+    assert.Equal(t, int64(3650100), got.Balance)
+}
@@ -0,0 +1,180 @@
+package jobs
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+// LockName is the advisory lock the leader holds. Only the instance holding
+// it runs jobs, the others stand by and take over when it goes away.
+const LockName = "gobank-jobs"
+
+// Func runs a job for now and returns how many items it processed.
+type Func func(ctx context.Context, now time.Time) (int, error)
+
+type job struct {
+    name string
+    spec string
+    schedule Schedule
+    run Func
+    next time.Time
+}
+
+// Scheduler runs jobs on their schedules on the leader instance and records
+// the outcome of every run.
+type Scheduler struct {
+    store storage.JobStorage
+    jobs []*job
+    leading bool
+}
+
+func New(store storage.JobStorage) *Scheduler {
+    return &Scheduler{store: store}
+}
+
+// Add registers run under name with a schedule Parse accepts. Jobs due at
+// the same time run in the order they were added.
+func (s *Scheduler) Add(name, spec string, run Func) error {
+    schedule, err := Parse(spec)
+    if err != nil {
+        return fmt.Errorf("job %s: %w", name, err)
+    }
+
+    s.jobs = append(s.jobs, &job{name: name, spec: spec, schedule: schedule, run: run})
+    return nil
+}
+
+// Start plans the first run of every job after now. The leader replaces
+// the plan with the recorded one when it takes over, see resume.
+func (s *Scheduler) Start(now time.Time) {
+    for _, j := range s.jobs {
+        j.next = j.schedule.Next(now)
+    }
+}
+
+// Tick runs the jobs due at now, one after the other, if this instance is
+// the leader. It returns how many jobs ran. A failed job is recorded and
+// retried at its next scheduled time, and a job whose run can't be recorded
+// doesn't keep the others from running.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) (int, error) {
+    leader, err := s.store.TryLeaderLock(ctx, LockName)
+    if err != nil {
+        return 0, err
+    }
+    if leader && !s.leading {
+        if err := s.resume(ctx); err != nil {
+            return 0, err
+        }
+    }
+    s.leading = leader
+
+    ran := 0
+    var errs []error
+    for _, j := range s.jobs {
+        if j.next.After(now) {
+            continue
+        }
+        j.next = j.schedule.Next(now)
+        if !leader {
+            continue
+        }
+
+        if err := s.runJob(ctx, j, now); err != nil {
+            errs = append(errs, fmt.Errorf("job %s: %w", j.name, err))
+            continue
+        }
+        ran++
+    }
+
+    return ran, errors.Join(errs...)
+}
+
+// resume picks up the plan of the previous leader, or of this instance
+// before it restarted, from the recorded job statuses. A job is due at the
+// run recorded after its last run, so a run that fell in the gap happens on
+// this tick instead of being skipped, and a run that never finished happens
+// again.
+func (s *Scheduler) resume(ctx context.Context) error {
+    statuses, err := s.store.GetJobStatuses(ctx)
+    if err != nil {
+        return err
+    }
+
+    recorded := map[string]*types.JobStatus{}
+    for _, status := range statuses {
+        recorded[status.Name] = status
+    }
+
+    for _, j := range s.jobs {
+        status, ok := recorded[j.name]
+        if !ok || status.Schedule != j.spec {
+            continue
+        }
+
+        next := status.NextRun
+        if status.Status == types.JobRunning && status.LastStarted != nil {
+            next = *status.LastStarted
+        }
+        if next.Before(j.next) {
+            j.next = next
+        }
+    }
+
+    return nil
+}
+
+func (s *Scheduler) runJob(ctx context.Context, j *job, now time.Time) error {
+    started := time.Now().UTC()
+    status := &types.JobStatus{
+        Name: j.name,
+        Schedule: j.spec,
+        Status: types.JobRunning,
+        LastStarted: &started,
+        NextRun: j.next,
+    }
+    if err := s.store.SaveJobStatus(ctx, status); err != nil {
+        return err
+    }
+
+    n, err := j.run(ctx, now)
+
+    finished := time.Now().UTC()
+    status.LastFinished = &finished
+    status.LastCount = n
+    status.Status = types.JobSucceeded
+    if err != nil {
+        status.Status = types.JobFailed
+        status.LastError = err.Error()
+        log.Printf("job %s failed: %v", j.name, err)
+    } else if n > 0 {
+        log.Printf("job %s processed %d", j.name, n)
+    }
+
+    return s.store.SaveJobStatus(ctx, status)
+}
+
+// Run ticks every minute until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+    s.Start(time.Now())
+
+    ticker := time.NewTicker(time.Minute)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+
+        if _, err := s.Tick(ctx, time.Now()); err != nil {
+            log.Println("running scheduled jobs failed:", err)
+        }
+    }
+}
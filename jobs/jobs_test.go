@@ -0,0 +1,132 @@
+package jobs
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestParseNext(t *testing.T) {
+    from := time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC) // a Saturday
+
+    cases := []struct {
+        spec string
+        want time.Time
+    }{
+        {"5 0 * * *", time.Date(2024, 6, 2, 0, 5, 0, 0, time.UTC)},
+        {"*/15 * * * *", time.Date(2024, 6, 1, 10, 45, 0, 0, time.UTC)},
+        {"0 9 * * 1-5", time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)},
+        {"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+        {"@hourly", time.Date(2024, 6, 1, 11, 0, 0, 0, time.UTC)},
+        {"@every 10m", time.Date(2024, 6, 1, 10, 40, 0, 0, time.UTC)},
+    }
+    for _, c := range cases {
+        schedule, err := Parse(c.spec)
+        assert.Nil(t, err, c.spec)
+        assert.Equal(t, c.want, schedule.Next(from), c.spec)
+    }
+
+    for _, spec := range []string{"", "* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *", "@every soon"} {
+        _, err := Parse(spec)
+        assert.NotNil(t, err, spec)
+    }
+}
+
+func TestTickRunsDueJobsOnLeader(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+    start := time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC)
+
+    runs := []string{}
+    s := New(store)
+    assert.Nil(t, s.Add("first", "5 0 * * *", func(ctx context.Context, now time.Time) (int, error) {
+        runs = append(runs, "first")
+        return 3, nil
+    }))
+    assert.Nil(t, s.Add("second", "5 0 * * *", func(ctx context.Context, now time.Time) (int, error) {
+        runs = append(runs, "second")
+        return 0, errors.New("boom")
+    }))
+    s.Start(start)
+
+    n, err := s.Tick(ctx, start.Add(time.Hour))
+    assert.Nil(t, err)
+    assert.Equal(t, 0, n)
+
+    // another instance is the leader on the first night
+    store.LockOut(true)
+    n, err = s.Tick(ctx, start.Add(65*time.Minute))
+    assert.Nil(t, err)
+    assert.Equal(t, 0, n)
+
+    store.LockOut(false)
+    n, err = s.Tick(ctx, start.Add(25*time.Hour+5*time.Minute))
+    assert.Nil(t, err)
+    assert.Equal(t, 2, n)
+    assert.Equal(t, []string{"first", "second"}, runs)
+
+    statuses, _ := store.GetJobStatuses(ctx)
+    assert.Len(t, statuses, 2)
+    assert.Equal(t, types.JobSucceeded, statuses[0].Status)
+    assert.Equal(t, 3, statuses[0].LastCount)
+    assert.Equal(t, types.JobFailed, statuses[1].Status)
+    assert.Equal(t, "boom", statuses[1].LastError)
+    assert.Equal(t, time.Date(2024, 6, 4, 0, 5, 0, 0, time.UTC), statuses[1].NextRun)
+}
+
+func TestTickCatchesUpOnRunsMissedAcrossARestart(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+    start := time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC)
+
+    runs := 0
+    settle := func(ctx context.Context, now time.Time) (int, error) {
+        runs++
+        return 0, nil
+    }
+
+    s := New(store)
+    assert.Nil(t, s.Add("settle", "5 0 * * *", settle))
+    s.Start(start)
+    n, err := s.Tick(ctx, start.Add(65*time.Minute))
+    assert.Nil(t, err)
+    assert.Equal(t, 1, n)
+
+    // the process is down over the next cutoff and comes back after it
+    restarted := start.Add(25*time.Hour + 30*time.Minute)
+    s = New(store)
+    assert.Nil(t, s.Add("settle", "5 0 * * *", settle))
+    s.Start(restarted)
+    n, err = s.Tick(ctx, restarted.Add(time.Minute))
+    assert.Nil(t, err)
+    assert.Equal(t, 1, n)
+    assert.Equal(t, 2, runs)
+
+    statuses, _ := store.GetJobStatuses(ctx)
+    assert.Equal(t, time.Date(2024, 6, 4, 0, 5, 0, 0, time.UTC), statuses[0].NextRun)
+}
+
+func TestTickKeepsGoingAfterAJobFails(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+    start := time.Date(2024, 6, 1, 23, 0, 0, 0, time.UTC)
+
+    s := New(store)
+    for _, name := range []string{"first", "second"} {
+        assert.Nil(t, s.Add(name, "5 0 * * *", func(ctx context.Context, now time.Time) (int, error) {
+            return 0, nil
+        }))
+    }
+    s.Start(start)
+
+    store.FailOn("SaveJobStatus", errors.New("db down"))
+    n, err := s.Tick(ctx, start.Add(65*time.Minute))
+    assert.Equal(t, 0, n)
+    assert.ErrorContains(t, err, "job first: db down")
+    assert.ErrorContains(t, err, "job second: db down")
+}
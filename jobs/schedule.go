@@ -0,0 +1,142 @@
+package jobs
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+    // Next returns the first run time after t.
+    Next(t time.Time) time.Time
+}
+
+// every runs a job at a fixed interval.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+    return t.Add(time.Duration(e))
+}
+
+// cron runs a job at the minutes matching all five fields of a cron
+// expression, in UTC. Day of month and day of week both have to match.
+type cron struct {
+    minute, hour, dom, month, dow []bool
+}
+
+func (c *cron) Next(t time.Time) time.Time {
+    t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+
+    // four years cover every valid combination, leap days included
+    end := t.AddDate(4, 0, 0)
+    for t.Before(end) {
+        if !c.month[t.Month()] || !c.dom[t.Day()] || !c.dow[t.Weekday()] {
+            t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+            continue
+        }
+        if !c.hour[t.Hour()] {
+            t = t.Truncate(time.Hour).Add(time.Hour)
+            continue
+        }
+        if !c.minute[t.Minute()] {
+            t = t.Add(time.Minute)
+            continue
+        }
+        return t
+    }
+
+    return time.Time{}
+}
+
+// Parse reads a schedule: a five field cron expression like "5 0 * * *"
+// for 00:05 UTC every day, "@daily", "@hourly", or "@every 10m". Cron
+// fields take *, numbers, ranges like 1-5, steps like */15 and lists of
+// those separated by commas.
+func Parse(spec string) (Schedule, error) {
+    spec = strings.TrimSpace(spec)
+
+    switch spec {
+    case "@daily":
+        spec = "0 0 * * *"
+    case "@hourly":
+        spec = "0 * * * *"
+    }
+
+    if d, ok := strings.CutPrefix(spec, "@every "); ok {
+        interval, err := time.ParseDuration(strings.TrimSpace(d))
+        if err != nil || interval <= 0 {
+            return nil, fmt.Errorf("invalid interval in schedule %q", spec)
+        }
+        return every(interval), nil
+    }
+
+    fields := strings.Fields(spec)
+    if len(fields) != 5 {
+        return nil, fmt.Errorf("schedule %q must have 5 fields: minute hour day month weekday", spec)
+    }
+
+    c := new(cron)
+    ranges := []struct {
+        dst *[]bool
+        min, max int
+    }{
+        {&c.minute, 0, 59},
+        {&c.hour, 0, 23},
+        {&c.dom, 1, 31},
+        {&c.month, 1, 12},
+        {&c.dow, 0, 6},
+    }
+    for i, r := range ranges {
+        set, err := parseField(fields[i], r.min, r.max)
+        if err != nil {
+            return nil, fmt.Errorf("schedule %q: %w", spec, err)
+        }
+        *r.dst = set
+    }
+
+    return c, nil
+}
+
+func parseField(field string, min, max int) ([]bool, error) {
+    set := make([]bool, max+1)
+
+    for _, part := range strings.Split(field, ",") {
+        expr, stepText, hasStep := strings.Cut(part, "/")
+        step := 1
+        if hasStep {
+            n, err := strconv.Atoi(stepText)
+            if err != nil || n < 1 {
+                return nil, fmt.Errorf("invalid step in %q", part)
+            }
+            step = n
+        }
+
+        lo, hi := min, max
+        if expr != "*" {
+            from, to, isRange := strings.Cut(expr, "-")
+            var err error
+            if lo, err = strconv.Atoi(from); err != nil {
+                return nil, fmt.Errorf("invalid value %q", part)
+            }
+            hi = lo
+            if isRange {
+                if hi, err = strconv.Atoi(to); err != nil {
+                    return nil, fmt.Errorf("invalid value %q", part)
+                }
+            } else if hasStep {
+                hi = max
+            }
+        }
+        if lo < min || hi > max || lo > hi {
+            return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+        }
+
+        for v := lo; v <= hi; v += step {
+            set[v] = true
+        }
+    }
+
+    return set, nil
+}
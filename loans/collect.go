@@ -0,0 +1,117 @@
+package loans
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "gobank/storage"
+    "gobank/products"
+    "gobank/types"
+)
+
+// Collect takes payment for every installment due by now from the
+// borrower's balance, as much as the available balance transfers can spend
+// covers. An installment still not paid off grace after its due date is
+// marked late and charged the late fee in effect at now once, lateFee when
+// no product rate sets it. Payments go to the late fee, then interest, then
+// principal. It returns the number of payments taken.
+func Collect(ctx context.Context, store storage.Storage, now time.Time, lateFee int64, grace time.Duration) (int, error) {
+    due, err := store.GetDueInstallments(ctx, now)
+    if err != nil {
+        return 0, err
+    }
+
+    lateFee, err = products.Lookup(ctx, store, types.ProductLoanLateFee, now, lateFee)
+    if err != nil {
+        return 0, err
+    }
+
+    collected := 0
+    for _, inst := range due {
+        loan, err := store.GetLoan(ctx, inst.LoanID)
+        if err != nil {
+            return collected, err
+        }
+        account, err := store.GetAccountByNumber(ctx, loan.AccountNumber)
+        if err != nil {
+            return collected, err
+        }
+
+        // pots, card holds and disputed money can't be taken
+        balances, err := store.GetBalances(ctx, account.Number, now.UTC())
+        if err != nil {
+            return collected, err
+        }
+        spendable := balances.Available
+
+        changed := false
+        if inst.Status != types.InstallmentLate && now.After(inst.DueDate.Add(grace)) {
+            inst.Status = types.InstallmentLate
+            inst.LateFee += lateFee
+            changed = true
+        }
+
+        pay := min(spendable, inst.Remaining())
+        if pay <= 0 {
+            if changed {
+                if err := store.ApplyLoanPayment(ctx, inst, 0, nil, nil); err != nil {
+                    return collected, err
+                }
+            }
+            continue
+        }
+
+        fee, interest, principal := allocate(inst, pay)
+        inst.LateFeePaid += fee
+        inst.InterestPaid += interest
+        inst.PrincipalPaid += principal
+        switch {
+        case inst.Remaining() == 0:
+            inst.Status = types.InstallmentPaid
+        case inst.Status != types.InstallmentLate:
+            inst.Status = types.InstallmentPartial
+        }
+
+        tx := &types.Transaction{
+            Kind: types.TransactionLoanRepayment,
+            FromAccount: account.Number,
+            ToAccount: types.LoanAccountNumber,
+            Amount: pay,
+            CreatedAt: now.UTC(),
+        }
+        entries := []*types.LedgerEntry{{AccountNumber: account.Number, Amount: -pay}}
+        for _, e := range []*types.LedgerEntry{
+            {AccountNumber: types.FeeAccountNumber, Amount: fee},
+            {AccountNumber: types.InterestAccountNumber, Amount: interest},
+            {AccountNumber: types.LoanAccountNumber, Amount: principal},
+        } {
+            if e.Amount != 0 {
+                entries = append(entries, e)
+            }
+        }
+
+        err = store.ApplyLoanPayment(ctx, inst, principal, tx, entries)
+        if errors.Is(err, storage.ErrInsufficientFunds) {
+            // the balance moved since we read it, try again next run
+            continue
+        }
+        if err != nil {
+            return collected, err
+        }
+        collected++
+    }
+
+    return collected, nil
+}
+
+// allocate splits pay between what is left of the installment's late fee,
+// interest and principal, in that order.
+func allocate(inst *types.LoanInstallment, pay int64) (fee, interest, principal int64) {
+    fee = min(pay, inst.LateFee-inst.LateFeePaid)
+    pay -= fee
+    interest = min(pay, inst.Interest-inst.InterestPaid)
+    pay -= interest
+    principal = min(pay, inst.Principal-inst.PrincipalPaid)
+    return fee, interest, principal
+}
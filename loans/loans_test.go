@@ -0,0 +1,126 @@
+package loans
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestScheduleRepaysPrincipal(t *testing.T) {
+    start := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+    for _, rate := range []int{0, 1200} {
+        schedule := Schedule(1, 100000, 12, rate, start)
+        assert.Len(t, schedule, 12)
+
+        var principal, interest int64
+        for _, inst := range schedule {
+            principal += inst.Principal
+            interest += inst.Interest
+        }
+        assert.Equal(t, int64(100000), principal)
+        if rate == 0 {
+            assert.Equal(t, int64(0), interest)
+        } else {
+            assert.Equal(t, int64(1000), schedule[0].Interest)
+            assert.Greater(t, interest, int64(0))
+        }
+        assert.Equal(t, start.AddDate(0, 1, 0), schedule[0].DueDate)
+    }
+}
+
+func TestCollectPartialAndLatePayments(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+
+    acc, _ := types.NewAccount("a", "b", "pw")
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+
+    start := time.Now().UTC().AddDate(0, -1, -10)
+    loan := &types.Loan{AccountNumber: acc.Number, Principal: 2000, TermMonths: 2, Status: types.LoanApplied}
+    assert.Nil(t, store.CreateLoan(ctx, loan))
+    disbursement := &types.Transaction{Kind: types.TransactionLoanDisbursement, Amount: 2000, CreatedAt: start}
+    entries := types.NewEntries(types.LoanAccountNumber, acc.Number, 2000)
+    assert.Nil(t, store.DisburseLoan(ctx, loan, Schedule(loan.ID, 2000, 2, 0, start), disbursement, entries))
+
+    // spend all but 400 of the loan
+    spend := &types.Transaction{Kind: types.TransactionTransfer, Amount: 1600, CreatedAt: start}
+    assert.Nil(t, store.PostTransaction(ctx, spend, types.NewEntries(acc.Number, types.SettlementAccountNumber, 1600)))
+
+    // the first installment is 10 days overdue: it gets the late fee and
+    // the 400 covers the fee and part of the principal
+    now := time.Now().UTC()
+    n, err := Collect(ctx, store, now, 100, 5*24*time.Hour)
+    assert.Nil(t, err)
+    assert.Equal(t, 1, n)
+
+    schedule, _ := store.GetLoanSchedule(ctx, loan.ID)
+    assert.Equal(t, types.InstallmentLate, schedule[0].Status)
+    assert.Equal(t, int64(100), schedule[0].LateFeePaid)
+    assert.Equal(t, int64(300), schedule[0].PrincipalPaid)
+    assert.Equal(t, int64(700), schedule[0].Remaining())
+
+    got, _ := store.GetLoan(ctx, loan.ID)
+    assert.Equal(t, int64(1700), got.Outstanding)
+
+    // nothing to take and the fee isn't charged twice
+    n, err = Collect(ctx, store, now, 100, 5*24*time.Hour)
+    assert.Nil(t, err)
+    assert.Equal(t, 0, n)
+    schedule, _ = store.GetLoanSchedule(ctx, loan.ID)
+    assert.Equal(t, int64(100), schedule[0].LateFee)
+
+    top := &types.Transaction{Kind: types.TransactionOpening, Amount: 5000, CreatedAt: now}
+    assert.Nil(t, store.PostTransaction(ctx, top, types.NewEntries(types.SettlementAccountNumber, acc.Number, 5000)))
+
+    // by then the second installment is overdue too and pays its own fee
+    n, err = Collect(ctx, store, now.AddDate(0, 1, 0), 100, 5*24*time.Hour)
+    assert.Nil(t, err)
+    assert.Equal(t, 2, n)
+
+    got, _ = store.GetLoan(ctx, loan.ID)
+    assert.Equal(t, types.LoanPaidOff, got.Status)
+    assert.Equal(t, int64(0), got.Outstanding)
+
+    balance, _ := store.GetAccountByNumber(ctx, acc.Number)
+    assert.Equal(t, int64(5000-700-1100), balance.Balance)
+}
+
+func TestCollectLeavesHeldMoney(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+
+    acc, _ := types.NewAccount("a", "b", "pw")
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+
+    start := time.Now().UTC().AddDate(0, -1, 0)
+    loan := &types.Loan{AccountNumber: acc.Number, Principal: 2000, TermMonths: 2, Status: types.LoanApplied}
+    assert.Nil(t, store.CreateLoan(ctx, loan))
+    disbursement := &types.Transaction{Kind: types.TransactionLoanDisbursement, Amount: 2000, CreatedAt: start}
+    entries := types.NewEntries(types.LoanAccountNumber, acc.Number, 2000)
+    assert.Nil(t, store.DisburseLoan(ctx, loan, Schedule(loan.ID, 2000, 2, 0, start), disbursement, entries))
+
+    spend := &types.Transaction{Kind: types.TransactionTransfer, Amount: 1600, CreatedAt: start}
+    assert.Nil(t, store.PostTransaction(ctx, spend, types.NewEntries(acc.Number, types.SettlementAccountNumber, 1600)))
+
+    now := time.Now().UTC()
+    card := &types.Card{AccountNumber: acc.Number, Status: types.CardActive, CreatedAt: now}
+    assert.Nil(t, store.CreateCard(ctx, card))
+    hold := &types.Hold{AccountNumber: acc.Number, CardID: card.ID, Amount: 150, Status: types.HoldActive, ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+    assert.Nil(t, store.PlaceHold(ctx, hold, 0))
+
+    // only the 250 outside the card hold is taken
+    n, err := Collect(ctx, store, now, 100, 5*24*time.Hour)
+    assert.Nil(t, err)
+    assert.Equal(t, 1, n)
+
+    schedule, _ := store.GetLoanSchedule(ctx, loan.ID)
+    assert.Equal(t, int64(250), schedule[0].PrincipalPaid)
+
+    balances, _ := store.GetBalances(ctx, acc.Number, now)
+    assert.Equal(t, int64(0), balances.Available)
+}
@@ -0,0 +1,44 @@
+package loans
+
+import (
+    "math"
+    "time"
+
+    "gobank/types"
+)
+
+// Schedule amortizes principal over termMonths equal monthly payments at
+// rateBPS a year, the first due a month after start. Payments are rounded
+// to the minor unit and the last installment takes the rounding remainder,
+// so the principals always add up to principal.
+func Schedule(loanID int, principal int64, termMonths, rateBPS int, start time.Time) []*types.LoanInstallment {
+    monthly := float64(rateBPS) / 10000 / 12
+
+    payment := float64(principal) / float64(termMonths)
+    if monthly > 0 {
+        payment = float64(principal) * monthly / (1 - math.Pow(1+monthly, -float64(termMonths)))
+    }
+    rounded := int64(math.Round(payment))
+
+    schedule := make([]*types.LoanInstallment, 0, termMonths)
+    balance := principal
+    for n := 1; n <= termMonths; n++ {
+        interest := int64(math.Round(float64(balance) * monthly))
+        part := rounded - interest
+        if n == termMonths || part > balance {
+            part = balance
+        }
+        balance -= part
+
+        schedule = append(schedule, &types.LoanInstallment{
+            LoanID: loanID,
+            Number: n,
+            DueDate: start.AddDate(0, n, 0),
+            Principal: part,
+            Interest: interest,
+            Status: types.InstallmentDue,
+        })
+    }
+
+    return schedule
+}
@@ -1,12 +1,35 @@
 package main
 
 import (
+    "context"
+    "encoding/json"
     "fmt"
 	"flag"
 	"log"
+    "os"
+    "time"
     "gobank/storage"
     "gobank/api"
     "gobank/types"
+    "gobank/fixtures"
+    "gobank/importer"
+    "gobank/numbering"
+    "gobank/backup"
+    "gobank/config"
+    "gobank/storage/breaker"
+    "gobank/chaos"
+    "gobank/claims"
+    "gobank/clearing"
+    "gobank/digest"
+    "gobank/documents"
+    "gobank/dormancy"
+    "gobank/loans"
+    "gobank/interest"
+    "gobank/jobs"
+    "gobank/settlement"
+    "gobank/reconcile"
+    "gobank/snapshot"
+    "gobank/notify"
 )
 
 func seedAccount(store storage.Storage, firstName, lastName, pw string) *types.Account {
@@ -15,7 +38,7 @@ func seedAccount(store storage.Storage, firstName, lastName, pw string) *types.A
         log.Fatal(err)
     }
 
-    if err := store.CreateAccount(acc); err != nil {
+    if err := numbering.New(store).Create(context.Background(), acc); err != nil {
        log.Fatal(err) 
     }
 
@@ -28,8 +51,142 @@ func seedAccounts(s storage.Storage) {
     seedAccount(s, "lolname", "lollastname", "hunter999")
 }
 
+func runBackup(store storage.Storage, path string) {
+    f, err := os.Create(path)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer f.Close()
+
+    manifest, err := backup.Export(context.Background(), store, f)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    fmt.Printf("backed up %d accounts and %d transactions to %s\n", manifest.Accounts, manifest.Transactions, path)
+}
+
+func runRestore(store storage.Storage, path string) {
+    f, err := os.Open(path)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer f.Close()
+
+    manifest, err := backup.Restore(context.Background(), store, f)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    fmt.Printf("restored %d accounts and %d transactions from %s\n", manifest.Accounts, manifest.Transactions, path)
+}
+
+// runImport imports accounts from a .csv or .json file and prints the
+// result, including the activation tokens to send out, as JSON.
+func runImport(cfg config.Config, store storage.Storage, path string) {
+    rows, err := importer.Load(path)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    res, err := importer.Import(context.Background(), store, cfg.FXRates, rows, cfg.ImportBatchSize, cfg.ActivationTTL)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(res); err != nil {
+        log.Fatal(err)
+    }
+
+    fmt.Fprintf(os.Stderr, "imported %d accounts from %s, %d rows failed\n", len(res.Imported), path, len(res.Errors))
+}
+
+// scheduleJobs sets up the background jobs. Only the instance holding the
+// jobs leader lock runs them.
+func scheduleJobs(cfg config.Config, store storage.Storage, notifier *notify.Notifier, network clearing.Network) (*jobs.Scheduler, error) {
+    scheduler := jobs.New(store)
+    every := func(d time.Duration) string { return "@every " + d.String() }
+
+    add := []struct {
+        name string
+        spec string
+        run jobs.Func
+    }{
+        {"reconcile", every(cfg.ReconcileInterval), func(ctx context.Context, now time.Time) (int, error) {
+            report, err := reconcile.New(store).RunOnce(ctx)
+            if err != nil {
+                return 0, err
+            }
+            return report.AccountsChecked, nil
+        }},
+        {"clear-external-transfers", every(cfg.ClearingInterval), func(ctx context.Context, now time.Time) (int, error) {
+            return clearing.Clear(ctx, store, notifier, network, now)
+        }},
+        {"return-alias-claims", every(time.Hour), func(ctx context.Context, now time.Time) (int, error) {
+            return claims.ReturnExpired(ctx, store, now)
+        }},
+        {"collect-loans", every(cfg.LoanCollectInterval), func(ctx context.Context, now time.Time) (int, error) {
+            return loans.Collect(ctx, store, now, cfg.LoanLateFee, cfg.LoanGracePeriod)
+        }},
+        // a nonce is only needed while its signature could still pass
+        {"prune-request-nonces", every(time.Hour), func(ctx context.Context, now time.Time) (int, error) {
+            return store.DeleteNoncesBefore(ctx, now.Add(-2*cfg.RequestSignatureTolerance))
+        }},
+        // end of day, in this order
+        {"return-stale-transfers", cfg.EndOfDaySchedule, func(ctx context.Context, now time.Time) (int, error) {
+            return settlement.ReturnStale(ctx, store, now.Add(-cfg.ExternalTransferTimeout))
+        }},
+        {"expire-holds", cfg.EndOfDaySchedule, func(ctx context.Context, now time.Time) (int, error) {
+            return store.ExpireHolds(ctx, now)
+        }},
+        {"accrue-interest", cfg.EndOfDaySchedule, func(ctx context.Context, now time.Time) (int, error) {
+            return interest.Accrue(ctx, store, now.AddDate(0, 0, -1), cfg.SavingsRateBPS)
+        }},
+        {"balance-snapshots", cfg.EndOfDaySchedule, func(ctx context.Context, now time.Time) (int, error) {
+            return snapshot.Take(ctx, store, now.AddDate(0, 0, -1))
+        }},
+        {"flag-dormant-accounts", cfg.EndOfDaySchedule, func(ctx context.Context, now time.Time) (int, error) {
+            return dormancy.Sweep(ctx, store, notifier, now, cfg.DormancyPeriod, cfg.DormancyNotice)
+        }},
+        {"archive-transactions", cfg.EndOfDaySchedule, func(ctx context.Context, now time.Time) (int, error) {
+            return store.ArchiveTransactionsBefore(ctx, now.AddDate(0, -cfg.ArchiveAfterMonths, 0))
+        }},
+    }
+    for _, j := range add {
+        if err := scheduler.Add(j.name, j.spec, j.run); err != nil {
+            return nil, err
+        }
+    }
+
+    // the digest runs after end of day, when yesterday is settled
+    if len(cfg.DigestRecipients) > 0 || cfg.DigestBucket != "" {
+        var bucket digest.Bucket
+        if cfg.DigestBucket != "" {
+            bucket = documents.NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.DigestBucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey)
+        }
+        err := scheduler.Add("operations-digest", cfg.DigestSchedule, func(ctx context.Context, now time.Time) (int, error) {
+            d, err := digest.Build(ctx, store, cfg.DigestSections, now)
+            if err != nil {
+                return 0, err
+            }
+            return digest.Deliver(ctx, d, notifier, cfg.DigestRecipients, bucket)
+        })
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return scheduler, nil
+}
+
 func main()  {
     seed := flag.Bool("seed", false, "seed the db")
+    fixturesPath := flag.String("fixtures", "", "seed the db from a fixtures file (.json or .yaml)")
+    backupPath := flag.String("backup", "", "write a backup archive of the db to this file and exit")
+    restorePath := flag.String("restore", "", "restore a backup archive into an empty db and exit")
+    importPath := flag.String("import", "", "import accounts from a .csv or .json file and exit")
     flag.Parse()
 
     store, err := storage.NewPostgresStore()
@@ -41,13 +198,87 @@ func main()  {
         log.Fatal(err)
     }
 
+    if *backupPath != "" {
+        runBackup(store, *backupPath)
+        return
+    }
+
+    if *restorePath != "" {
+        runRestore(store, *restorePath)
+        return
+    }
+
     if *seed {
         fmt.Println("seeding the database")
         seedAccounts(store)
     }
 
+    if *fixturesPath != "" {
+        f, err := fixtures.Load(*fixturesPath)
+        if err != nil {
+            log.Fatal(err)
+        }
+
+        fmt.Println("loading fixtures from", *fixturesPath)
+        if err := fixtures.Seed(context.Background(), store, f); err != nil {
+            log.Fatal(err)
+        }
+    }
+
+
+    cfg, err := config.Load()
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    if *importPath != "" {
+        runImport(cfg, store, *importPath)
+        return
+    }
+
+    injector := chaos.New(cfg.Chaos)
+    if injector != nil {
+        log.Printf("fault injection is on: %+v", cfg.Chaos)
+    }
+    // injected faults go through the breaker like real ones
+    guarded := breaker.Wrap(chaos.Wrap(store, injector), breaker.New(cfg.BreakerThreshold, cfg.BreakerCooldown))
+
+    sender, err := notify.SenderFromConfig(cfg, os.Stdout)
+    if err != nil {
+        log.Fatal(err)
+    }
+    smsSender, err := notify.SMSSenderFromConfig(cfg, os.Stdout)
+    if err != nil {
+        log.Fatal(err)
+    }
+    notifier := notify.New(
+        sender,
+        cfg.NotifyWorkers,
+        notify.WithSMS(smsSender),
+        notify.WithPreferences(guarded),
+        notify.WithDeadLetters(guarded),
+    )
+    defer notifier.Close()
+
+    network, err := clearing.NetworkFromConfig(cfg)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    scheduler, err := scheduleJobs(cfg, guarded, notifier, network)
+    if err != nil {
+        log.Fatal(err)
+    }
+    go scheduler.Run(context.Background())
+
+    blobs, err := documents.BlobStoreFromConfig(cfg)
+    if err != nil {
+        log.Fatal(err)
+    }
 
-    server := api.NewApiServer(":3000", store)
+    server := api.NewApiServer(cfg, guarded, notifier, api.WithChaos(injector))
+    server.UseDocuments(blobs, documents.NopScanner{})
+    server.UseClearing(network)
     if  err := server.Run(); err != nil {
         log.Fatal(err)
     }
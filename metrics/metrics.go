@@ -0,0 +1,155 @@
+package metrics
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// Registry holds metrics and renders them in the Prometheus text format.
+type Registry struct {
+    mu sync.Mutex
+    metrics []*metric
+}
+
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+    return new(Registry)
+}
+
+type metric struct {
+    name string
+    help string
+    kind string
+    labels []string
+
+    mu sync.Mutex
+    values map[string]float64
+}
+
+type Counter struct {
+    m *metric
+}
+
+type Gauge struct {
+    m *metric
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+    return Default.NewCounter(name, help, labels...)
+}
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+    return Default.NewGauge(name, help, labels...)
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+    return &Counter{m: r.register(name, help, "counter", labels)}
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+    return &Gauge{m: r.register(name, help, "gauge", labels)}
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+    c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(v float64, labelValues ...string) {
+    c.m.update(labelValues, func(old float64) float64 { return old + v })
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+    g.m.update(labelValues, func(float64) float64 { return v })
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+    g.m.update(labelValues, func(old float64) float64 { return old + v })
+}
+
+func (r *Registry) register(name, help, kind string, labels []string) *metric {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    for _, m := range r.metrics {
+        if m.name == name {
+            return m
+        }
+    }
+
+    m := &metric{
+        name: name,
+        help: help,
+        kind: kind,
+        labels: labels,
+        values: map[string]float64{},
+    }
+    r.metrics = append(r.metrics, m)
+
+    return m
+}
+
+func (m *metric) update(labelValues []string, f func(float64) float64) {
+    if len(labelValues) != len(m.labels) {
+        panic(fmt.Sprintf("metric %s: want %d label values, got %d", m.name, len(m.labels), len(labelValues)))
+    }
+
+    key := m.labelString(labelValues)
+
+    m.mu.Lock()
+    m.values[key] = f(m.values[key])
+    m.mu.Unlock()
+}
+
+func (m *metric) labelString(values []string) string {
+    if len(values) == 0 {
+        return ""
+    }
+
+    pairs := make([]string, len(values))
+    for i, v := range values {
+        v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+        pairs[i] = fmt.Sprintf(`%s="%s"`, m.labels[i], v)
+    }
+
+    return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (r *Registry) WriteText(sb *strings.Builder) {
+    r.mu.Lock()
+    metrics := append([]*metric{}, r.metrics...)
+    r.mu.Unlock()
+
+    for _, m := range metrics {
+        fmt.Fprintf(sb, "# HELP %s %s\n", m.name, m.help)
+        fmt.Fprintf(sb, "# TYPE %s %s\n", m.name, m.kind)
+
+        m.mu.Lock()
+        keys := make([]string, 0, len(m.values))
+        for k := range m.values {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+        for _, k := range keys {
+            fmt.Fprintf(sb, "%s%s %g\n", m.name, k, m.values[k])
+        }
+        m.mu.Unlock()
+    }
+}
+
+func (r *Registry) Handler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+        sb := new(strings.Builder)
+        r.WriteText(sb)
+
+        w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+        w.Write([]byte(sb.String()))
+    })
+}
+
+func Handler() http.Handler {
+    return Default.Handler()
+}
@@ -0,0 +1,281 @@
+package notify
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "sync"
+    "time"
+
+    "gobank/metrics"
+    "gobank/types"
+)
+
+var (
+    sentTotal = metrics.NewCounter("gobank_notifications_sent_total", "Notifications delivered.", "event", "channel")
+    failedTotal = metrics.NewCounter("gobank_notifications_failed_total", "Notifications dropped after all delivery attempts.", "event", "channel")
+    deadLetteredTotal = metrics.NewCounter("gobank_notifications_dead_lettered_total", "Failed notifications kept in the dead-letter table for replay.", "event", "channel")
+)
+
+type Event struct {
+    Type EventType
+    Account *types.Account
+    Data map[string]any
+    // Phone sends the SMS to this number regardless of the account's
+    // preferences, e.g. to verify a number before it is saved.
+    Phone string
+    // Email does the same for an email address. Only the overridden
+    // channel is used when either is set.
+    Email string
+    // Marketing events only reach accounts that opted in to marketing.
+    Marketing bool
+}
+
+type PreferenceLookup interface {
+    GetNotificationPreferences(context.Context, int64) (*types.NotificationPreferences, error)
+}
+
+// DeadLetterStore keeps notifications that failed every delivery attempt.
+type DeadLetterStore interface {
+    CreateDeadLetter(context.Context, *types.DeadLetter) error
+}
+
+type Option func(*Notifier)
+
+func WithSMS(sender SMSSender) Option {
+    return func(n *Notifier) {
+        n.sms = sender
+    }
+}
+
+// WithPreferences makes delivery follow each account's channel
+// preferences. Without it only email is used.
+func WithPreferences(prefs PreferenceLookup) Option {
+    return func(n *Notifier) {
+        n.prefs = prefs
+    }
+}
+
+// WithDeadLetters keeps notifications that exhaust their retries in store
+// so they can be inspected and replayed. Without it they are only logged.
+func WithDeadLetters(store DeadLetterStore) Option {
+    return func(n *Notifier) {
+        n.deadLetters = store
+    }
+}
+
+// Notifier renders published events into messages and delivers them in the
+// background, retrying failed deliveries with exponential backoff.
+type Notifier struct {
+    email Sender
+    sms SMSSender
+    prefs PreferenceLookup
+    deadLetters DeadLetterStore
+    queue chan Event
+    maxAttempts int
+    backoff time.Duration
+
+    wg sync.WaitGroup
+    // mu guards closed, so Publish never sends on the closed queue
+    mu sync.Mutex
+    closed bool
+}
+
+func New(email Sender, workers int, opts ...Option) *Notifier {
+    n := &Notifier{
+        email: email,
+        queue: make(chan Event, 1024),
+        maxAttempts: 5,
+        backoff: time.Second,
+    }
+    for _, opt := range opts {
+        opt(n)
+    }
+
+    for i := 0; i < workers; i++ {
+        n.wg.Add(1)
+        go n.work()
+    }
+
+    return n
+}
+
+// Publish queues e for delivery without blocking. Events are dropped when
+// the queue is full or the notifier is closed.
+func (n *Notifier) Publish(e Event) {
+    if e.Account == nil {
+        return
+    }
+
+    n.mu.Lock()
+    defer n.mu.Unlock()
+
+    if n.closed {
+        failedTotal.Inc(string(e.Type), "any")
+        log.Printf("notifier closed, dropping %s for account %d", e.Type, e.Account.Number)
+        return
+    }
+
+    select {
+    case n.queue <- e:
+    default:
+        failedTotal.Inc(string(e.Type), "any")
+        log.Printf("notification queue full, dropping %s for account %d", e.Type, e.Account.Number)
+    }
+}
+
+// Close stops accepting events and waits for queued ones to be delivered.
+func (n *Notifier) Close() {
+    n.mu.Lock()
+    if !n.closed {
+        n.closed = true
+        close(n.queue)
+    }
+    n.mu.Unlock()
+
+    n.wg.Wait()
+}
+
+func (n *Notifier) work() {
+    defer n.wg.Done()
+
+    for e := range n.queue {
+        n.deliver(e)
+    }
+}
+
+func (n *Notifier) deliver(e Event) {
+    prefs := n.preferences(e.Account.Number)
+    if e.Marketing && !prefs.MarketingOptIn {
+        return
+    }
+
+    channels := prefs.ChannelsFor(string(e.Type))
+    if e.Type == StatementReady && prefs.StatementDelivery == types.StatementOnline {
+        channels.Email = false
+    }
+
+    to := e.Email
+    if to == "" && e.Phone == "" && channels.Email {
+        to = e.Account.Email
+    }
+    if to != "" {
+        msg, ok, err := renderEmail(e)
+        if err != nil {
+            log.Println("notification:", err)
+        }
+        if ok {
+            msg.To = to
+            n.retry(e, "email", msg)
+        }
+    }
+
+    phone := e.Phone
+    if phone == "" && e.Email == "" && channels.SMS && e.Account.PhoneVerified {
+        phone = e.Account.Phone
+    }
+    if phone != "" && n.sms != nil {
+        text, ok, err := renderSMS(e)
+        if err != nil {
+            log.Println("notification:", err)
+        }
+        if ok {
+            n.retry(e, "sms", Message{To: phone, Body: text})
+        }
+    }
+}
+
+func (n *Notifier) preferences(number int64) *types.NotificationPreferences {
+    if n.prefs == nil {
+        return types.DefaultNotificationPreferences(number)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+
+    prefs, err := n.prefs.GetNotificationPreferences(ctx, number)
+    if err != nil {
+        log.Printf("notification preferences for account %d: %v", number, err)
+        return types.DefaultNotificationPreferences(number)
+    }
+
+    return prefs
+}
+
+func (n *Notifier) retry(e Event, channel string, msg Message) {
+    var err error
+    wait := n.backoff
+    attempt := 1
+    for ; ; attempt++ {
+        ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+        err = n.send(ctx, channel, msg)
+        cancel()
+
+        if err == nil {
+            sentTotal.Inc(string(e.Type), channel)
+            return
+        }
+        if attempt == n.maxAttempts {
+            break
+        }
+
+        time.Sleep(wait)
+        wait *= 2
+    }
+
+    failedTotal.Inc(string(e.Type), channel)
+    log.Printf("giving up on %s %s notification for account %d: %v", e.Type, channel, e.Account.Number, err)
+    n.deadLetter(&types.DeadLetter{
+        Event: string(e.Type),
+        Channel: channel,
+        AccountNumber: e.Account.Number,
+        To: msg.To,
+        Subject: msg.Subject,
+        Body: msg.Body,
+        Reason: err.Error(),
+        Attempts: attempt,
+        Status: types.DeadLetterPending,
+        CreatedAt: time.Now().UTC(),
+    })
+}
+
+func (n *Notifier) deadLetter(d *types.DeadLetter) {
+    if n.deadLetters == nil {
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+
+    if err := n.deadLetters.CreateDeadLetter(ctx, d); err != nil {
+        log.Printf("saving dead letter for %s %s notification to account %d: %v", d.Event, d.Channel, d.AccountNumber, err)
+        return
+    }
+    deadLetteredTotal.Inc(d.Event, d.Channel)
+}
+
+// Replay makes one more attempt to deliver a dead letter, on the channel
+// and to the recipient it was meant for.
+func (n *Notifier) Replay(ctx context.Context, d *types.DeadLetter) error {
+    err := n.send(ctx, d.Channel, Message{To: d.To, Subject: d.Subject, Body: d.Body})
+    if err != nil {
+        return err
+    }
+    sentTotal.Inc(d.Event, d.Channel)
+
+    return nil
+}
+
+func (n *Notifier) send(ctx context.Context, channel string, msg Message) error {
+    switch channel {
+    case "email":
+        return n.email.Send(ctx, msg)
+    case "sms":
+        if n.sms == nil {
+            return fmt.Errorf("no sms sender configured")
+        }
+        return n.sms.SendSMS(ctx, msg.To, msg.Body)
+    }
+
+    return fmt.Errorf("unknown notification channel %q", channel)
+}
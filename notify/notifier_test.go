@@ -0,0 +1,161 @@
+package notify
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/types"
+)
+
+type flakySender struct {
+    mu sync.Mutex
+    failures int
+    sent []Message
+}
+
+func (s *flakySender) Send(ctx context.Context, msg Message) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.failures > 0 {
+        s.failures--
+        return errors.New("smtp: try again later")
+    }
+    s.sent = append(s.sent, msg)
+    return nil
+}
+
+func TestNotifierRetriesDelivery(t *testing.T) {
+    sender := &flakySender{failures: 2}
+    n := New(sender, 1)
+    n.backoff = time.Millisecond
+
+    acc := &types.Account{FirstName: "Ada", Number: 42, Email: "ada@example.com", Currency: "EUR", Locale: "de-DE"}
+    n.Publish(Event{Type: LargeWithdrawal, Account: acc, Data: map[string]any{"amount": 5000, "toAccount": 7}})
+    n.Publish(Event{Type: AccountCreated, Account: &types.Account{Number: 1}})
+    n.Close()
+
+    assert.Len(t, sender.sent, 1)
+    assert.Equal(t, "ada@example.com", sender.sent[0].To)
+    assert.Equal(t, "Large withdrawal from account 42", sender.sent[0].Subject)
+    assert.Contains(t, sender.sent[0].Body, "50,00\u00a0€ was sent from your account 42 to account 7")
+}
+
+func TestNotifierDropsEventsPublishedAfterClose(t *testing.T) {
+    sender := &flakySender{}
+    n := New(sender, 1)
+    n.Close()
+
+    assert.NotPanics(t, func() {
+        n.Publish(Event{Type: AccountCreated, Account: &types.Account{Number: 1, Email: "a@example.com"}})
+    })
+    n.Close()
+
+    assert.Empty(t, sender.sent)
+}
+
+type smsRecorder struct {
+    mu sync.Mutex
+    to []string
+}
+
+func (s *smsRecorder) SendSMS(ctx context.Context, to, body string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.to = append(s.to, to)
+    return nil
+}
+
+type staticPrefs types.NotificationPreferences
+
+func (p staticPrefs) GetNotificationPreferences(ctx context.Context, number int64) (*types.NotificationPreferences, error) {
+    prefs := types.NotificationPreferences(p)
+    return &prefs, nil
+}
+
+func TestNotifierFollowsChannelPreferences(t *testing.T) {
+    email := &flakySender{}
+    sms := &smsRecorder{}
+    n := New(email, 1, WithSMS(sms), WithPreferences(staticPrefs{Email: false, SMS: true}))
+
+    verified := &types.Account{Number: 1, Email: "a@example.com", Phone: "+14155550100", PhoneVerified: true}
+    unverified := &types.Account{Number: 2, Email: "b@example.com", Phone: "+14155550101"}
+
+    n.Publish(Event{Type: TransferConfirmation, Account: verified, Data: map[string]any{"amount": 1, "toAccount": 2}})
+    n.Publish(Event{Type: TransferConfirmation, Account: unverified, Data: map[string]any{"amount": 1, "toAccount": 1}})
+    n.Publish(Event{Type: PhoneVerification, Account: unverified, Phone: "+14155550102", Data: map[string]any{"code": "123456"}})
+    n.Close()
+
+    assert.Empty(t, email.sent)
+    assert.Equal(t, []string{"+14155550100", "+14155550102"}, sms.to)
+}
+
+func TestNotifierFollowsEventPreferences(t *testing.T) {
+    email := &flakySender{}
+    sms := &smsRecorder{}
+    n := New(email, 1, WithSMS(sms), WithPreferences(staticPrefs{
+        Email: true,
+        Events: map[string]types.Channels{string(TransferConfirmation): {SMS: true}},
+        StatementDelivery: types.StatementOnline,
+    }))
+
+    acc := &types.Account{Number: 1, Email: "a@example.com", Phone: "+14155550100", PhoneVerified: true}
+    n.Publish(Event{Type: TransferConfirmation, Account: acc, Data: map[string]any{"amount": 1, "toAccount": 2}})
+    n.Publish(Event{Type: StatementReady, Account: acc, Data: map[string]any{"period": "2026-09"}})
+    n.Publish(Event{Type: LargeWithdrawal, Account: acc, Data: map[string]any{"amount": 5000, "toAccount": 7}, Marketing: true})
+    n.Publish(Event{Type: LargeWithdrawal, Account: acc, Data: map[string]any{"amount": 5000, "toAccount": 7}})
+    n.Close()
+
+    assert.Equal(t, []string{"+14155550100"}, sms.to)
+    if assert.Len(t, email.sent, 1) {
+        assert.Equal(t, "a@example.com", email.sent[0].To)
+    }
+}
+
+type deadLetters struct {
+    mu sync.Mutex
+    letters []*types.DeadLetter
+}
+
+func (d *deadLetters) CreateDeadLetter(ctx context.Context, l *types.DeadLetter) error {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+
+    d.letters = append(d.letters, l)
+    return nil
+}
+
+func TestNotifierDeadLettersFailedDelivery(t *testing.T) {
+    sender := &flakySender{failures: 3}
+    store := &deadLetters{}
+    n := New(sender, 1, WithDeadLetters(store))
+    n.backoff = time.Millisecond
+    n.maxAttempts = 2
+
+    acc := &types.Account{Number: 42, Email: "ada@example.com"}
+    n.Publish(Event{Type: LargeWithdrawal, Account: acc, Data: map[string]any{"amount": 5000, "toAccount": 7}})
+    n.Close()
+
+    assert.Empty(t, sender.sent)
+    if assert.Len(t, store.letters, 1) {
+        d := store.letters[0]
+        assert.Equal(t, string(LargeWithdrawal), d.Event)
+        assert.Equal(t, "email", d.Channel)
+        assert.Equal(t, "ada@example.com", d.To)
+        assert.Equal(t, 2, d.Attempts)
+        assert.Equal(t, "smtp: try again later", d.Reason)
+        assert.Equal(t, types.DeadLetterPending, d.Status)
+
+        assert.Error(t, n.Replay(context.Background(), d))
+        assert.NoError(t, n.Replay(context.Background(), d))
+        if assert.Len(t, sender.sent, 1) {
+            assert.Equal(t, d.Subject, sender.sent[0].Subject)
+            assert.Equal(t, d.Body, sender.sent[0].Body)
+        }
+    }
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net"
+    "net/smtp"
+    "strconv"
+    "strings"
+    "sync"
+
+    "gobank/config"
+)
+
+type Message struct {
+    To string
+    Subject string
+    Body string
+}
+
+type Sender interface {
+    Send(context.Context, Message) error
+}
+
+// ConsoleSender writes messages to w instead of delivering them, for local
+// development.
+type ConsoleSender struct {
+    mu sync.Mutex
+    w io.Writer
+}
+
+func NewConsoleSender(w io.Writer) *ConsoleSender {
+    return &ConsoleSender{w: w}
+}
+
+func (s *ConsoleSender) Send(ctx context.Context, msg Message) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    _, err := fmt.Fprintf(s.w, "to: %s\nsubject: %s\n\n%s\n\n", msg.To, msg.Subject, msg.Body)
+    return err
+}
+
+type SMTPSender struct {
+    addr string
+    from string
+    auth smtp.Auth
+}
+
+func NewSMTPSender(host string, port int, username, password, from string) *SMTPSender {
+    var auth smtp.Auth
+    if username != "" {
+        auth = smtp.PlainAuth("", username, password, host)
+    }
+
+    return &SMTPSender{
+        addr: net.JoinHostPort(host, strconv.Itoa(port)),
+        from: from,
+        auth: auth,
+    }
+}
+
+// NewSESSender delivers through the Amazon SES SMTP interface of region
+// using SES SMTP credentials.
+func NewSESSender(region, username, password, from string) *SMTPSender {
+    host := fmt.Sprintf("email-smtp.%s.amazonaws.com", region)
+    return NewSMTPSender(host, 587, username, password, from)
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    body := strings.Join([]string{
+        "From: " + s.from,
+        "To: " + msg.To,
+        "Subject: " + msg.Subject,
+        "MIME-Version: 1.0",
+        "Content-Type: text/plain; charset=utf-8",
+        "",
+        msg.Body,
+    }, "\r\n")
+
+    return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, []byte(body))
+}
+
+func SenderFromConfig(cfg config.Config, console io.Writer) (Sender, error) {
+    switch cfg.MailSender {
+    case "", "console":
+        return NewConsoleSender(console), nil
+    case "smtp":
+        return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+    case "ses":
+        return NewSESSender(cfg.SESRegion, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+    }
+
+    return nil, fmt.Errorf("unknown mail sender %q, use console, smtp or ses", cfg.MailSender)
+}
+
+func SMSSenderFromConfig(cfg config.Config, console io.Writer) (SMSSender, error) {
+    switch cfg.SMSSender {
+    case "", "console":
+        return NewConsoleSMSSender(console), nil
+    case "twilio":
+        return NewTwilioSender(cfg.TwilioBaseURL, cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioFrom), nil
+    }
+
+    return nil, fmt.Errorf("unknown sms sender %q, use console or twilio", cfg.SMSSender)
+}
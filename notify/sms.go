@@ -0,0 +1,86 @@
+package notify
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+type SMSSender interface {
+    SendSMS(ctx context.Context, to, body string) error
+}
+
+type ConsoleSMSSender struct {
+    mu sync.Mutex
+    w io.Writer
+}
+
+func NewConsoleSMSSender(w io.Writer) *ConsoleSMSSender {
+    return &ConsoleSMSSender{w: w}
+}
+
+func (s *ConsoleSMSSender) SendSMS(ctx context.Context, to, body string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    _, err := fmt.Fprintf(s.w, "sms to: %s\n%s\n\n", to, body)
+    return err
+}
+
+// TwilioSender sends through the Twilio Messages API, or any service
+// compatible with it when baseURL points elsewhere.
+type TwilioSender struct {
+    baseURL string
+    accountSID string
+    authToken string
+    from string
+    client *http.Client
+}
+
+func NewTwilioSender(baseURL, accountSID, authToken, from string) *TwilioSender {
+    if baseURL == "" {
+        baseURL = "https://api.twilio.com"
+    }
+
+    return &TwilioSender{
+        baseURL: strings.TrimRight(baseURL, "/"),
+        accountSID: accountSID,
+        authToken: authToken,
+        from: from,
+        client: &http.Client{Timeout: 15 * time.Second},
+    }
+}
+
+func (s *TwilioSender) SendSMS(ctx context.Context, to, body string) error {
+    form := url.Values{
+        "To": {to},
+        "From": {s.from},
+        "Body": {body},
+    }
+
+    endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.baseURL, s.accountSID)
+    req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+    if err != nil {
+        return err
+    }
+    req.SetBasicAuth(s.accountSID, s.authToken)
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("twilio: %d %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+    }
+
+    return nil
+}
@@ -0,0 +1,326 @@
+package notify
+
+import (
+    "bytes"
+    "fmt"
+    "text/template"
+    "time"
+
+    "gobank/i18n"
+)
+
+type EventType string
+
+const (
+    AccountCreated EventType = "account_created"
+    LargeWithdrawal EventType = "large_withdrawal"
+    NewDeviceLogin EventType = "new_device_login"
+    StatementReady EventType = "statement_ready"
+    TransferConfirmation EventType = "transfer_confirmation"
+    TwoFactorCode EventType = "two_factor_code"
+    PhoneVerification EventType = "phone_verification"
+    AlertTriggered EventType = "alert_triggered"
+    AliasVerification EventType = "alias_verification"
+    AliasInvite EventType = "alias_invite"
+    PaymentRequested EventType = "payment_requested"
+    PaymentRequestPaid EventType = "payment_request_paid"
+    PaymentRequestDeclined EventType = "payment_request_declined"
+    PaymentRequestCancelled EventType = "payment_request_cancelled"
+    OwnerInvited EventType = "owner_invited"
+    AccountInvitation EventType = "account_invitation"
+    ImpossibleTravel EventType = "impossible_travel"
+    DormancyWarning EventType = "dormancy_warning"
+    AccountDormant EventType = "account_dormant"
+    AccountClosed EventType = "account_closed"
+    ExternalTransferReturned EventType = "external_transfer_returned"
+    TransactionReversed EventType = "transaction_reversed"
+    OperationsDigest EventType = "operations_digest"
+)
+
+// Known reports whether t is an event type notifications are sent for.
+func Known(t EventType) bool {
+    _, ok := templates[t]
+    return ok
+}
+
+// Security reports whether t warns about something the account holder
+// may not have done. These can't be switched off completely.
+func Security(t EventType) bool {
+    switch t {
+    case LargeWithdrawal, NewDeviceLogin, ImpossibleTravel, AccountClosed:
+        return true
+    }
+    return false
+}
+
+// messageTemplate holds the email subject and body and the SMS text of an
+// event. An event is only sent on the channels it has a template for.
+type messageTemplate struct {
+    subject *template.Template
+    body *template.Template
+    sms *template.Template
+}
+
+func parse(name, text string) *template.Template {
+    if text == "" {
+        return nil
+    }
+    return template.Must(template.New(name).Parse(text))
+}
+
+func mustTemplate(subject, body, sms string) messageTemplate {
+    return messageTemplate{
+        subject: parse("subject", subject),
+        body: parse("body", body),
+        sms: parse("sms", sms),
+    }
+}
+
+var templates = map[EventType]messageTemplate{
+    AccountCreated: mustTemplate(
+        "Welcome to gobank",
+        `Hi {{.Account.FirstName}},
+
+your account {{.Account.Number}} is ready to use.
+`,
+        ""),
+    LargeWithdrawal: mustTemplate(
+        "Large withdrawal from account {{.Account.Number}}",
+        `Hi {{.Account.FirstName}},
+
+{{.Money .Data.amount .Account.Currency}} was sent from your account {{.Account.Number}} to account {{.Data.toAccount}}.
+If this wasn't you, contact us immediately.
+`,
+        "gobank: {{.Money .Data.amount .Account.Currency}} was sent from account {{.Account.Number}}. Not you? Contact us immediately."),
+    NewDeviceLogin: mustTemplate(
+        "New sign-in to your gobank account",
+        `Hi {{.Account.FirstName}},
+
+your account {{.Account.Number}} was signed in to from a new device ({{.Data.device}}, {{.Data.ip}}).
+If this wasn't you, change your password.
+`,
+        "gobank: new sign-in to account {{.Account.Number}} from {{.Data.ip}}. Not you? Change your password."),
+    ImpossibleTravel: mustTemplate(
+        "Unusual sign-in to your gobank account",
+        `Hi {{.Account.FirstName}},
+
+your account {{.Account.Number}} was signed in to from {{.Data.country}} ({{.Data.ip}}), shortly after a sign-in from {{.Data.lastCountry}}.
+If this wasn't you, change your password.
+`,
+        "gobank: unusual sign-in to account {{.Account.Number}} from {{.Data.country}}. Not you? Change your password."),
+    DormancyWarning: mustTemplate(
+        "Your gobank account is about to become dormant",
+        `Hi {{.Account.FirstName}},
+
+your account {{.Account.Number}} hasn't been used in a long time. Unless you sign in or make a transfer before {{.Date .Data.dormantOn}}, it becomes dormant and can't send money until you reactivate it.
+`,
+        "gobank: account {{.Account.Number}} becomes dormant on {{.Date .Data.dormantOn}} unless you sign in."),
+    AccountDormant: mustTemplate(
+        "Your gobank account is dormant",
+        `Hi {{.Account.FirstName}},
+
+your account {{.Account.Number}} is now dormant because it hasn't been used in a long time. Your money is safe. Sign in and reactivate the account to send money again.
+`,
+        ""),
+    AccountClosed: mustTemplate(
+        "Your gobank account is closed",
+        `Hi {{.Account.FirstName}},
+
+your account {{.Account.Number}} is now closed.{{if .Data.amount}} Its remaining balance of {{.Money .Data.amount .Account.Currency}} is on its way to where you chose.{{end}} Not you? Contact us immediately.
+`,
+        "gobank: account {{.Account.Number}} is closed. Not you? Contact us immediately."),
+    ExternalTransferReturned: mustTemplate(
+        "Your transfer came back",
+        `Hi {{.Account.FirstName}},
+
+your transfer of {{.Money .Data.amount .Account.Currency}} to {{.Data.name}}, account {{.Data.toAccount}}, was returned by their bank: {{.Data.reason}}. The money is back in your account.
+`,
+        "gobank: your transfer of {{.Money .Data.amount .Account.Currency}} to {{.Data.name}} was returned: {{.Data.reason}}."),
+    TransactionReversed: mustTemplate(
+        "A transaction on your account was reversed",
+        `Hi {{.Account.FirstName}},
+
+transaction {{.Data.transactionId}} of {{.Money .Data.amount .Account.Currency}} on your account {{.Account.Number}} was made in error and has been reversed: {{.Data.reason}}.
+`,
+        "gobank: transaction {{.Data.transactionId}} of {{.Money .Data.amount .Account.Currency}} was reversed: {{.Data.reason}}."),
+    StatementReady: mustTemplate(
+        "Your statement is ready",
+        `Hi {{.Account.FirstName}},
+
+your statement for {{.Data.period}} is ready.
+`,
+        ""),
+    TransferConfirmation: mustTemplate(
+        "",
+        "",
+        "gobank: you sent {{.Money .Data.amount .Account.Currency}} to account {{.Data.toAccount}}."),
+    TwoFactorCode: mustTemplate(
+        "Your gobank security code",
+        `Hi {{.Account.FirstName}},
+
+your security code is {{.Data.code}}. It expires in {{.Data.expiresIn}}.
+`,
+        "gobank: your security code is {{.Data.code}}"),
+    PhoneVerification: mustTemplate(
+        "",
+        "",
+        "gobank: your phone verification code is {{.Data.code}}"),
+    AlertTriggered: mustTemplate(
+        "Account alert for {{.Account.Number}}",
+        `Hi {{.Account.FirstName}},
+
+{{.Data.description}}.
+`,
+        "gobank: {{.Data.description}}."),
+    AliasVerification: mustTemplate(
+        "Confirm your email for gobank payments",
+        `Hi {{.Account.FirstName}},
+
+enter the code {{.Data.code}} to receive payments to {{.Data.alias}} in account {{.Account.Number}}.
+`,
+        ""),
+    AliasInvite: mustTemplate(
+        "You've been sent money on gobank",
+        `Hi,
+
+{{.Data.sender}} sent you {{.Money .Data.amount .Data.currency}}.
+Open a gobank account and add {{.Data.alias}} to it before {{.Date .Data.expiresAt}} to claim it.
+`,
+        "gobank: {{.Data.sender}} sent you {{.Money .Data.amount .Data.currency}}. Open an account and add this number by {{.Date .Data.expiresAt}} to claim it."),
+    PaymentRequested: mustTemplate(
+        "{{.Data.requester}} requested a payment",
+        `Hi {{.Account.FirstName}},
+
+{{.Data.requester}} requested {{.Money .Data.amount .Data.currency}} from your account {{.Account.Number}}.
+{{if .Data.memo}}Memo: {{.Data.memo}}
+{{end}}Accept or decline request {{.Data.id}} in the app.
+`,
+        "gobank: {{.Data.requester}} requested {{.Money .Data.amount .Data.currency}}. Open the app to accept or decline."),
+    PaymentRequestPaid: mustTemplate(
+        "Your payment request was paid",
+        `Hi {{.Account.FirstName}},
+
+account {{.Data.payer}} paid your request for {{.Money .Data.amount .Data.currency}}.
+`,
+        "gobank: account {{.Data.payer}} paid your request for {{.Money .Data.amount .Data.currency}}."),
+    PaymentRequestDeclined: mustTemplate(
+        "Your payment request was declined",
+        `Hi {{.Account.FirstName}},
+
+your request {{.Data.id}} for {{.Money .Data.amount .Data.currency}} was declined.
+`,
+        ""),
+    PaymentRequestCancelled: mustTemplate(
+        "A payment request was cancelled",
+        `Hi {{.Account.FirstName}},
+
+payment request {{.Data.id}} for {{.Money .Data.amount .Data.currency}} was cancelled and no longer needs paying.
+`,
+        ""),
+    OwnerInvited: mustTemplate(
+        "{{.Data.inviter}} invited you to share an account",
+        `Hi {{.Account.FirstName}},
+
+{{.Data.inviter}} invited you to become an owner of account {{.Data.account}} with {{.Data.permission}} access.
+Accept or decline invitation {{.Data.id}} in the app.
+`,
+        ""),
+    AccountInvitation: mustTemplate(
+        "{{.Data.inviter}} invited you to gobank",
+        `Hi,
+
+{{.Data.inviter}} invited you to open a gobank account{{if .Data.permission}} and share theirs with {{.Data.permission}} access{{end}}.
+Sign up with the code {{.Data.token}} before {{.Date .Data.expiresAt}}.
+`,
+        ""),
+    OperationsDigest: mustTemplate(
+        "gobank digest for {{.Date .Data.digest.Day}}",
+        `Good morning,
+
+here is how {{.Date .Data.digest.Day}} went.
+{{with .Data.digest.Flows}}
+Flows: {{.Deposits}} deposited, {{.Withdrawals}} withdrawn, {{.Net}} net, {{.ActiveAccounts}} active accounts.
+{{end}}{{with .Data.digest.Reconciliation}}
+Reconciliation: {{.Open}} open discrepancies, {{.New}} new, drifting by {{.Drift}} in total.
+{{end}}{{with .Data.digest.FraudReview}}
+Fraud review: {{.Pending}} transfers waiting for review, {{.Amount}} in total{{with .OldestAt}}, the oldest since {{$.Date .}}{{end}}.
+{{end}}`,
+        ""),
+}
+
+// Money writes an amount in minor units the way the account holder's
+// locale does, e.g. {{.Money .Data.amount .Account.Currency}}.
+func (e Event) Money(amount any, currency string) string {
+    var minor int64
+    switch v := amount.(type) {
+    case int64:
+        minor = v
+    case int:
+        minor = int64(v)
+    case float64:
+        minor = int64(v)
+    default:
+        return fmt.Sprint(amount)
+    }
+    return i18n.FormatMoney(e.Account.Locale, minor, currency)
+}
+
+// Date writes a time as a date in the account holder's locale.
+func (e Event) Date(t any) string {
+    switch v := t.(type) {
+    case time.Time:
+        return i18n.FormatDate(e.Account.Locale, v)
+    case *time.Time:
+        if v != nil {
+            return i18n.FormatDate(e.Account.Locale, *v)
+        }
+    }
+    return fmt.Sprint(t)
+}
+
+func execute(tmpl *template.Template, e Event) (string, error) {
+    buf := new(bytes.Buffer)
+    if err := tmpl.Execute(buf, e); err != nil {
+        return "", err
+    }
+    return buf.String(), nil
+}
+
+func renderEmail(e Event) (Message, bool, error) {
+    tmpl, ok := templates[e.Type]
+    if !ok {
+        return Message{}, false, fmt.Errorf("no template for event %s", e.Type)
+    }
+    if tmpl.body == nil {
+        return Message{}, false, nil
+    }
+
+    subject, err := execute(tmpl.subject, e)
+    if err != nil {
+        return Message{}, false, err
+    }
+
+    body, err := execute(tmpl.body, e)
+    if err != nil {
+        return Message{}, false, err
+    }
+
+    return Message{
+        To: e.Account.Email,
+        Subject: subject,
+        Body: body,
+    }, true, nil
+}
+
+func renderSMS(e Event) (string, bool, error) {
+    tmpl, ok := templates[e.Type]
+    if !ok {
+        return "", false, fmt.Errorf("no template for event %s", e.Type)
+    }
+    if tmpl.sms == nil {
+        return "", false, nil
+    }
+
+    text, err := execute(tmpl.sms, e)
+    return text, err == nil, err
+}
@@ -0,0 +1,53 @@
+package numbering
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+// attempts is how many numbers are drawn for one account before giving
+// up. With the accounts of a whole bank spread over MaxAccountNumber,
+// running out means something is wrong rather than unlucky.
+const attempts = 10
+
+// Allocator creates accounts under numbers nobody else has. The store's
+// uniqueness check decides who gets a number, so concurrent creates,
+// also from other instances, can't end up sharing one.
+type Allocator struct {
+    store storage.Storage
+    draw func() (int64, error)
+}
+
+func New(store storage.Storage) *Allocator {
+    return &Allocator{store: store, draw: types.NewAccountNumber}
+}
+
+// Create draws a number for acc and stores it, drawing again whenever the
+// number turns out to be taken.
+func (a *Allocator) Create(ctx context.Context, acc *types.Account) error {
+    return a.CreateWith(ctx, acc, a.store.CreateAccount)
+}
+
+// CreateWith is Create for accounts stored by create along with something
+// else. create must fail with storage.ErrNumberTaken, changing nothing,
+// when the number is in use.
+func (a *Allocator) CreateWith(ctx context.Context, acc *types.Account, create func(context.Context, *types.Account) error) error {
+    for attempt := 0; attempt < attempts; attempt++ {
+        number, err := a.draw()
+        if err != nil {
+            return err
+        }
+        acc.Number = number
+
+        err = create(ctx, acc)
+        if !errors.Is(err, storage.ErrNumberTaken) {
+            return err
+        }
+    }
+
+    return fmt.Errorf("no free account number after %d attempts", attempts)
+}
@@ -0,0 +1,56 @@
+package numbering
+
+import (
+    "context"
+    "math/rand"
+    "sync"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestCreateNeverSharesNumbers(t *testing.T) {
+    store := storagetest.New()
+    a := New(store)
+    // a tiny range makes concurrent creates collide all the time
+    a.draw = func() (int64, error) { return int64(rand.Intn(400)) + 1, nil }
+
+    var wg sync.WaitGroup
+    errs := make(chan error, 200)
+    for i := 0; i < 200; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            errs <- a.Create(context.Background(), &types.Account{FirstName: "a", LastName: "b"})
+        }()
+    }
+    wg.Wait()
+    close(errs)
+
+    created := 0
+    for err := range errs {
+        if err == nil {
+            created++
+        }
+    }
+
+    accounts, _ := store.GetAccounts(context.Background())
+    assert.Len(t, accounts, created)
+    seen := map[int64]bool{}
+    for _, acc := range accounts {
+        assert.False(t, seen[acc.Number], "number %d given out twice", acc.Number)
+        seen[acc.Number] = true
+    }
+    assert.Greater(t, created, 150)
+}
+
+func TestCreateGivesUpWhenEveryNumberIsTaken(t *testing.T) {
+    store := storagetest.New()
+    a := New(store)
+    a.draw = func() (int64, error) { return 7, nil }
+
+    assert.NoError(t, a.Create(context.Background(), &types.Account{}))
+    assert.Error(t, a.Create(context.Background(), &types.Account{}))
+}
@@ -0,0 +1,23 @@
+package products
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "gobank/storage"
+)
+
+// Lookup returns the value of a product setting in effect at t, or
+// fallback, normally the configured default, when no rate is in effect.
+func Lookup(ctx context.Context, store storage.ProductStorage, key string, t time.Time, fallback int64) (int64, error) {
+    rate, err := store.GetEffectiveProductRate(ctx, key, t)
+    if errors.Is(err, storage.ErrNotFound) {
+        return fallback, nil
+    }
+    if err != nil {
+        return 0, err
+    }
+
+    return rate.Value, nil
+}
@@ -0,0 +1,77 @@
+package qrpay
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "strings"
+    "time"
+)
+
+// prefix versions the format so scanners can tell gobank codes apart from
+// other QR content.
+const prefix = "GOBANK1"
+
+var (
+    ErrInvalidPayload = errors.New("invalid payment code")
+    ErrExpiredPayload = errors.New("payment code expired")
+)
+
+// Payload is what a payment QR code carries. Amount and Memo are optional;
+// an empty amount lets the payer choose it.
+type Payload struct {
+    Account int64 `json:"a"`
+    Amount int64 `json:"m,omitempty"`
+    Currency string `json:"c"`
+    Memo string `json:"d,omitempty"`
+    ExpiresAt int64 `json:"e"`
+}
+
+// Encode signs p into a compact string of the form
+// GOBANK1.<base64url json>.<base64url hmac-sha256>.
+func Encode(secret []byte, p Payload) (string, error) {
+    body, err := json.Marshal(p)
+    if err != nil {
+        return "", err
+    }
+
+    data := prefix + "." + base64.RawURLEncoding.EncodeToString(body)
+    return data + "." + base64.RawURLEncoding.EncodeToString(sign(secret, data)), nil
+}
+
+// Decode verifies s and returns its payload unless it has expired by now.
+func Decode(secret []byte, s string, now time.Time) (*Payload, error) {
+    i := strings.LastIndex(s, ".")
+    if i < 0 || !strings.HasPrefix(s, prefix+".") {
+        return nil, ErrInvalidPayload
+    }
+    data, sig := s[:i], s[i+1:]
+
+    got, err := base64.RawURLEncoding.DecodeString(sig)
+    if err != nil || !hmac.Equal(got, sign(secret, data)) {
+        return nil, ErrInvalidPayload
+    }
+
+    body, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(data, prefix+"."))
+    if err != nil {
+        return nil, ErrInvalidPayload
+    }
+
+    p := new(Payload)
+    if err := json.Unmarshal(body, p); err != nil {
+        return nil, ErrInvalidPayload
+    }
+    if now.Unix() > p.ExpiresAt {
+        return nil, ErrExpiredPayload
+    }
+
+    return p, nil
+}
+
+func sign(secret []byte, data string) []byte {
+    h := hmac.New(sha256.New, secret)
+    h.Write([]byte(data))
+    return h.Sum(nil)
+}
@@ -0,0 +1,30 @@
+package qrpay
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecode(t *testing.T) {
+    secret := []byte("secret")
+    now := time.Now()
+    p := Payload{Account: 1234, Amount: 500, Currency: "USD", Memo: "coffee", ExpiresAt: now.Add(time.Minute).Unix()}
+
+    code, err := Encode(secret, p)
+    assert.Nil(t, err)
+
+    got, err := Decode(secret, code, now)
+    assert.Nil(t, err)
+    assert.Equal(t, p, *got)
+
+    _, err = Decode([]byte("other"), code, now)
+    assert.ErrorIs(t, err, ErrInvalidPayload)
+
+    _, err = Decode(secret, code[:len(code)-2], now)
+    assert.ErrorIs(t, err, ErrInvalidPayload)
+
+    _, err = Decode(secret, code, now.Add(2*time.Minute))
+    assert.ErrorIs(t, err, ErrExpiredPayload)
+}
@@ -0,0 +1,142 @@
+package reconcile
+
+import (
+    "context"
+    "log"
+    "time"
+
+    "gobank/metrics"
+    "gobank/storage"
+    "gobank/types"
+)
+
+var (
+    runsTotal = metrics.NewCounter("gobank_reconciliation_runs_total", "Completed reconciliation runs.")
+    driftAccounts = metrics.NewGauge("gobank_reconciliation_drift_accounts", "Accounts whose stored balance differs from their ledger entries.")
+    driftAmount = metrics.NewGauge("gobank_reconciliation_drift_amount", "Sum of absolute balance differences found by the last run.")
+)
+
+type Report struct {
+    RunAt time.Time `json:"runAt"`
+    AccountsChecked int `json:"accountsChecked"`
+    Discrepancies []*types.Discrepancy `json:"discrepancies"`
+}
+
+// Reconciler compares every account's stored balance with the sum of its
+// ledger entries. New differences are flagged in storage, and flagged
+// differences that have disappeared are marked resolved.
+type Reconciler struct {
+    store storage.Storage
+}
+
+func New(store storage.Storage) *Reconciler {
+    return &Reconciler{store: store}
+}
+
+func (r *Reconciler) RunOnce(ctx context.Context) (*Report, error) {
+    accounts, err := r.store.GetAccounts(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    balances, err := r.store.GetLedgerBalances(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    flagged, err := r.store.GetDiscrepancies(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    open := map[int64]*types.Discrepancy{}
+    for _, d := range flagged {
+        if d.ResolvedAt == nil {
+            open[d.AccountNumber] = d
+        }
+    }
+
+    now := time.Now().UTC()
+    report := &Report{
+        RunAt: now,
+        AccountsChecked: len(accounts),
+        Discrepancies: []*types.Discrepancy{},
+    }
+
+    var amount int64
+    for _, acc := range accounts {
+        ledger := balances[acc.Number]
+        existing := open[acc.Number]
+
+        if acc.Balance != ledger {
+            // accounts and balances are read separately, so a transfer in
+            // between shows up as drift; look again before flagging it
+            if acc, ledger, err = r.recheck(ctx, acc.Number); err != nil {
+                return nil, err
+            }
+        }
+
+        if acc.Balance == ledger {
+            if existing != nil {
+                if err := r.store.ResolveDiscrepancy(ctx, existing.ID, now); err != nil {
+                    return nil, err
+                }
+            }
+            continue
+        }
+
+        d := existing
+        if d == nil || d.StoredBalance != acc.Balance || d.LedgerBalance != ledger {
+            if existing != nil {
+                if err := r.store.ResolveDiscrepancy(ctx, existing.ID, now); err != nil {
+                    return nil, err
+                }
+            }
+
+            d = &types.Discrepancy{
+                AccountNumber: acc.Number,
+                StoredBalance: acc.Balance,
+                LedgerBalance: ledger,
+                DetectedAt: now,
+            }
+            if err := r.store.CreateDiscrepancy(ctx, d); err != nil {
+                return nil, err
+            }
+        }
+
+        report.Discrepancies = append(report.Discrepancies, d)
+        amount += abs(d.Difference())
+    }
+
+    runsTotal.Inc()
+    driftAccounts.Set(float64(len(report.Discrepancies)))
+    driftAmount.Set(float64(amount))
+
+    if len(report.Discrepancies) > 0 {
+        log.Printf("ALERT reconciliation: %d accounts drifted from the ledger by %d in total", len(report.Discrepancies), amount)
+    }
+
+    return report, nil
+}
+
+func (r *Reconciler) recheck(ctx context.Context, number int64) (*types.Account, int64, error) {
+    acc, err := r.store.GetAccountByNumber(ctx, number)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    ledger, err := r.store.GetLedgerBalance(ctx, number)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    return acc, ledger, nil
+}
+
+
+func abs(n int64) int64 {
+    if n < 0 {
+        return -n
+    }
+    return n
+}
@@ -0,0 +1,48 @@
+package reconcile
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestRunOnceFlagsAndResolvesDrift(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+
+    acc, _ := types.NewAccount("a", "b", "pw")
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+
+    tx := &types.Transaction{Kind: types.TransactionOpening, Amount: 100, CreatedAt: time.Now()}
+    assert.Nil(t, store.PostTransaction(ctx, tx, types.NewEntries(types.SuspenseAccountNumber, acc.Number, 100)))
+
+    r := New(store)
+    report, err := r.RunOnce(ctx)
+    assert.Nil(t, err)
+    assert.Empty(t, report.Discrepancies)
+
+    store.SetBalance(acc.Number, 150)
+
+    report, err = r.RunOnce(ctx)
+    assert.Nil(t, err)
+    assert.Len(t, report.Discrepancies, 1)
+    assert.Equal(t, int64(50), report.Discrepancies[0].Difference())
+
+    // the same drift is not flagged twice
+    _, err = r.RunOnce(ctx)
+    assert.Nil(t, err)
+    flagged, _ := store.GetDiscrepancies(ctx)
+    assert.Len(t, flagged, 1)
+
+    store.SetBalance(acc.Number, 100)
+
+    report, err = r.RunOnce(ctx)
+    assert.Nil(t, err)
+    assert.Empty(t, report.Discrepancies)
+    flagged, _ = store.GetDiscrepancies(ctx)
+    assert.NotNil(t, flagged[0].ResolvedAt)
+}
@@ -0,0 +1,45 @@
+package settlement
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+// ReturnStale fails the transfers out of the bank that their provider
+// still hasn't settled by before and gives the money back to the senders.
+// A provider that confirms one of them later is answered with a conflict
+// and shows up in reconciliation. It returns how many it returned.
+func ReturnStale(ctx context.Context, store storage.Storage, before time.Time) (int, error) {
+    stale, err := store.GetPendingExternalTransactions(ctx, before)
+    if err != nil {
+        return 0, err
+    }
+
+    returned := 0
+    for _, tx := range stale {
+        ret := &types.Transaction{
+            Kind: types.TransactionReturn,
+            FromAccount: types.SettlementAccountNumber,
+            ToAccount: tx.FromAccount,
+            Amount: tx.Amount,
+            CreatedAt: time.Now().UTC(),
+        }
+        entries := types.NewEntries(types.SettlementAccountNumber, tx.FromAccount, tx.Amount)
+
+        err := store.SettleTransaction(ctx, tx.ID, types.StatusFailed, ret, entries)
+        if errors.Is(err, storage.ErrNotPending) {
+            // settled by its provider in the meantime
+            continue
+        }
+        if err != nil {
+            return returned, err
+        }
+        returned++
+    }
+
+    return returned, nil
+}
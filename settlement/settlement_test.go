@@ -0,0 +1,46 @@
+package settlement
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestReturnStaleGivesMoneyBack(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+
+    acc, _ := types.NewAccount("a", "b", "pw")
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+    opening := &types.Transaction{Kind: types.TransactionOpening, Amount: 100, CreatedAt: time.Now()}
+    assert.Nil(t, store.PostTransaction(ctx, opening, types.NewEntries(types.SettlementAccountNumber, acc.Number, 100)))
+
+    now := time.Now().UTC()
+    for i, created := range []time.Time{now.Add(-4 * 24 * time.Hour), now} {
+        tx := &types.Transaction{
+            Kind: types.TransactionTransfer,
+            Status: types.StatusPending,
+            FromAccount: acc.Number,
+            ToAccount: types.SettlementAccountNumber,
+            Amount: 30,
+            Provider: "ach",
+            Reference: []string{"old", "new"}[i],
+            CreatedAt: created,
+        }
+        assert.Nil(t, store.PostTransaction(ctx, tx, types.NewEntries(acc.Number, types.SettlementAccountNumber, 30)))
+    }
+
+    n, err := ReturnStale(ctx, store, now.Add(-72*time.Hour))
+    assert.Nil(t, err)
+    assert.Equal(t, 1, n)
+
+    got, _ := store.GetAccountByNumber(ctx, acc.Number)
+    assert.Equal(t, int64(70), got.Balance)
+
+    old, _ := store.GetTransactionByReference(ctx, "ach", "old")
+    assert.Equal(t, types.StatusFailed, old.Status)
+}
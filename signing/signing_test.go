@@ -0,0 +1,33 @@
+package signing
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+    secret := []byte("s3cret")
+    body := []byte(`{"toAccount":7,"amount":100}`)
+    now := time.Now()
+    header := Sign("key1", secret, now, "n1", "POST", "/transfer", body)
+
+    sig, err := Parse(header)
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, "key1", sig.KeyID)
+    assert.Equal(t, "n1", sig.Nonce)
+
+    assert.NoError(t, sig.Verify(secret, "POST", "/transfer", body, now, time.Minute))
+    assert.ErrorIs(t, sig.Verify([]byte("other"), "POST", "/transfer", body, now, time.Minute), ErrInvalidSignature)
+    assert.ErrorIs(t, sig.Verify(secret, "POST", "/account/1/transfer", body, now, time.Minute), ErrInvalidSignature)
+    assert.ErrorIs(t, sig.Verify(secret, "POST", "/transfer", []byte(`{}`), now, time.Minute), ErrInvalidSignature)
+    assert.ErrorIs(t, sig.Verify(secret, "POST", "/transfer", body, now.Add(2*time.Minute), time.Minute), ErrExpiredSignature)
+
+    _, err = Parse("")
+    assert.ErrorIs(t, err, ErrMissingSignature)
+    _, err = Parse("garbage")
+    assert.ErrorIs(t, err, ErrInvalidSignature)
+}
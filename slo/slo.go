@@ -0,0 +1,221 @@
+package slo
+
+import (
+    "sync"
+    "time"
+
+    "gobank/metrics"
+)
+
+// Objective is what a path of the API promises, with shares of requests
+// in basis points. Server errors count against SuccessBPS, answers slower
+// than Latency against LatencyBPS.
+type Objective struct {
+    SuccessBPS int
+    Latency time.Duration
+    LatencyBPS int
+}
+
+// States of an objective, from its burn rates as in the multiwindow alerts
+// of the SRE workbook: critical spends 2% of a 30 day budget within an
+// hour, warning 5% within six hours.
+const (
+    StateOK = "ok"
+    StateWarning = "warning"
+    StateCritical = "critical"
+)
+
+const (
+    criticalBurnRate = 14.4
+    warningBurnRate = 6
+)
+
+// windows are the burn rate windows. The last one is the longest and the
+// one the error budget is reported over.
+var windows = []struct {
+    name string
+    d time.Duration
+}{
+    {"5m", 5 * time.Minute},
+    {"30m", 30 * time.Minute},
+    {"1h", time.Hour},
+    {"6h", 6 * time.Hour},
+}
+
+const buckets = 6 * 60
+
+var (
+    requestsTotal = metrics.NewCounter("gobank_slo_requests_total", "Requests on paths with an SLO.", "slo")
+    errorsTotal = metrics.NewCounter("gobank_slo_errors_total", "Requests on paths with an SLO that failed with a server error.", "slo")
+    slowTotal = metrics.NewCounter("gobank_slo_slow_total", "Requests on paths with an SLO answered slower than its latency objective.", "slo")
+    burnRate = metrics.NewGauge("gobank_slo_burn_rate", "How fast the error budget is spent, 1 spending exactly all of it.", "slo", "objective", "window")
+    budgetRemaining = metrics.NewGauge("gobank_slo_error_budget_remaining", "Share of the error budget left over the last 6h.", "slo", "objective")
+)
+
+// bucket counts the requests of one minute.
+type bucket struct {
+    minute int64
+    total, errors, slow int64
+}
+
+// Tracker keeps the last six hours of one path's requests a minute at a
+// time.
+type Tracker struct {
+    name string
+    objective Objective
+
+    mu sync.Mutex
+    buckets [buckets]bucket
+    published time.Time
+}
+
+func New(name string, o Objective) *Tracker {
+    return &Tracker{name: name, objective: o}
+}
+
+func (t *Tracker) Name() string {
+    return t.name
+}
+
+// Record counts a request answered at at, after d, that failed with a
+// server error unless ok.
+func (t *Tracker) Record(at time.Time, ok bool, d time.Duration) {
+    slow := d > t.objective.Latency
+    requestsTotal.Inc(t.name)
+    if !ok {
+        errorsTotal.Inc(t.name)
+    }
+    if slow {
+        slowTotal.Inc(t.name)
+    }
+
+    t.mu.Lock()
+    minute := at.Unix() / 60
+    b := &t.buckets[minute%buckets]
+    if b.minute != minute {
+        *b = bucket{minute: minute}
+    }
+    b.total++
+    if !ok {
+        b.errors++
+    }
+    if slow {
+        b.slow++
+    }
+    // the gauges are refreshed at most every few seconds
+    publish := at.Sub(t.published) >= 10*time.Second
+    if publish {
+        t.published = at
+    }
+    t.mu.Unlock()
+
+    if publish {
+        t.Status(at)
+    }
+}
+
+// sum adds up the buckets within d before now.
+func (t *Tracker) sum(now time.Time, d time.Duration) bucket {
+    minute := now.Unix() / 60
+    from := minute - int64(d/time.Minute)
+
+    var s bucket
+    for _, b := range t.buckets {
+        if b.minute > from && b.minute <= minute {
+            s.total += b.total
+            s.errors += b.errors
+            s.slow += b.slow
+        }
+    }
+    return s
+}
+
+// Status is how a path does against its objectives.
+type Status struct {
+    Name string `json:"name"`
+    State string `json:"state"`
+    Objectives []ObjectiveStatus `json:"objectives"`
+}
+
+type ObjectiveStatus struct {
+    // Objective is success or latency.
+    Objective string `json:"objective"`
+    Target float64 `json:"target"`
+    // Requests and Ratio, the share of them that met the objective, are
+    // over the last 6h.
+    Requests int64 `json:"requests"`
+    Ratio float64 `json:"ratio"`
+    BurnRates map[string]float64 `json:"burnRates"`
+    // BudgetRemaining is the share of the error budget left over the last
+    // 6h. It is negative once the objective was missed.
+    BudgetRemaining float64 `json:"budgetRemaining"`
+    State string `json:"state"`
+}
+
+// Status computes the objectives' burn rates at now and publishes them as
+// gauges.
+func (t *Tracker) Status(now time.Time) Status {
+    t.mu.Lock()
+    sums := make([]bucket, len(windows))
+    for i, w := range windows {
+        sums[i] = t.sum(now, w.d)
+    }
+    t.mu.Unlock()
+
+    st := Status{Name: t.name, State: StateOK}
+    objectives := []struct {
+        name string
+        bps int
+        bad func(bucket) int64
+    }{
+        {"success", t.objective.SuccessBPS, func(b bucket) int64 { return b.errors }},
+        {"latency", t.objective.LatencyBPS, func(b bucket) int64 { return b.slow }},
+    }
+    for _, o := range objectives {
+        obj := ObjectiveStatus{
+            Objective: o.name,
+            Target: float64(o.bps) / 10000,
+            BurnRates: map[string]float64{},
+        }
+        budget := 1 - obj.Target
+
+        rates := make([]float64, len(windows))
+        for i, w := range windows {
+            if sums[i].total > 0 && budget > 0 {
+                rates[i] = float64(o.bad(sums[i])) / float64(sums[i].total) / budget
+            }
+            obj.BurnRates[w.name] = rates[i]
+            burnRate.Set(rates[i], t.name, o.name, w.name)
+        }
+
+        long := sums[len(sums)-1]
+        obj.Requests = long.total
+        obj.Ratio = 1
+        if long.total > 0 {
+            obj.Ratio = 1 - float64(o.bad(long))/float64(long.total)
+        }
+        obj.BudgetRemaining = 1 - rates[len(rates)-1]
+        budgetRemaining.Set(obj.BudgetRemaining, t.name, o.name)
+
+        // rates are 5m, 30m, 1h, 6h
+        obj.State = StateOK
+        switch {
+        case rates[2] > criticalBurnRate && rates[0] > criticalBurnRate:
+            obj.State = StateCritical
+        case rates[3] > warningBurnRate && rates[1] > warningBurnRate:
+            obj.State = StateWarning
+        }
+        st.State = worse(st.State, obj.State)
+        st.Objectives = append(st.Objectives, obj)
+    }
+
+    return st
+}
+
+func worse(a, b string) string {
+    rank := map[string]int{StateOK: 0, StateWarning: 1, StateCritical: 2}
+    if rank[b] > rank[a] {
+        return b
+    }
+    return a
+}
@@ -0,0 +1,68 @@
+package slo
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+var objective = Objective{SuccessBPS: 9990, Latency: time.Second, LatencyBPS: 9900}
+
+func TestHealthyPath(t *testing.T) {
+    tr := New("transfer", objective)
+    now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    for i := 0; i < 600; i++ {
+        tr.Record(now.Add(-time.Duration(i)*time.Minute/2), true, 100*time.Millisecond)
+    }
+
+    st := tr.Status(now)
+    assert.Equal(t, StateOK, st.State)
+    assert.Equal(t, "success", st.Objectives[0].Objective)
+    assert.Equal(t, 0.999, st.Objectives[0].Target)
+    assert.Equal(t, int64(600), st.Objectives[0].Requests)
+    assert.Equal(t, 1.0, st.Objectives[0].Ratio)
+    assert.Equal(t, 1.0, st.Objectives[0].BudgetRemaining)
+}
+
+func TestFastBurnIsCritical(t *testing.T) {
+    tr := New("transfer", objective)
+    now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    for i := 0; i < 100; i++ {
+        // 2% failing spends the budget 20 times too fast
+        tr.Record(now.Add(-time.Duration(i)*time.Second), i%50 != 0, 100*time.Millisecond)
+    }
+
+    st := tr.Status(now)
+    assert.Equal(t, StateCritical, st.State)
+    assert.Equal(t, StateCritical, st.Objectives[0].State)
+    assert.InDelta(t, 20, st.Objectives[0].BurnRates["5m"], 0.001)
+    assert.Equal(t, StateOK, st.Objectives[1].State)
+}
+
+func TestSlowBurnIsWarning(t *testing.T) {
+    tr := New("login", objective)
+    now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    // 10% slow over the last 20 minutes, 10 times the 1% budget
+    for i := 0; i < 200; i++ {
+        d := 100 * time.Millisecond
+        if i%10 == 0 {
+            d = 2 * time.Second
+        }
+        tr.Record(now.Add(-time.Duration(i)*6*time.Second), true, d)
+    }
+
+    st := tr.Status(now)
+    assert.Equal(t, StateWarning, st.State)
+    assert.Equal(t, StateWarning, st.Objectives[1].State)
+}
+
+func TestOldRequestsAgeOut(t *testing.T) {
+    tr := New("transfer", objective)
+    now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    tr.Record(now, false, time.Second)
+
+    st := tr.Status(now.Add(7 * time.Hour))
+    assert.Equal(t, int64(0), st.Objectives[0].Requests)
+    assert.Equal(t, StateOK, st.State)
+}
@@ -0,0 +1,70 @@
+package snapshot
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+// Day truncates t to midnight UTC.
+func Day(t time.Time) time.Time {
+    y, m, d := t.UTC().Date()
+    return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// BalanceAt returns the balance of the account at the end of day, starting
+// from the nearest earlier snapshot and adding the ledger entries after it.
+func BalanceAt(ctx context.Context, store storage.Storage, number int64, day time.Time) (int64, error) {
+    end := Day(day).AddDate(0, 0, 1)
+
+    var (
+        base int64
+        from time.Time
+    )
+    snap, err := store.GetBalanceSnapshotBefore(ctx, number, end)
+    switch {
+    case err == nil:
+        base = snap.Balance
+        from = snap.Day.AddDate(0, 0, 1)
+    case !errors.Is(err, storage.ErrNotFound):
+        return 0, err
+    }
+
+    sum, err := store.GetLedgerSumBetween(ctx, number, from, end)
+    if err != nil {
+        return 0, err
+    }
+
+    return base + sum, nil
+}
+
+// Take records the end of day balance of every account and returns how
+// many snapshots were saved.
+func Take(ctx context.Context, store storage.Storage, day time.Time) (int, error) {
+    accounts, err := store.GetAccounts(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    day = Day(day)
+    for _, acc := range accounts {
+        balance, err := BalanceAt(ctx, store, acc.Number, day)
+        if err != nil {
+            return 0, err
+        }
+
+        snap := &types.BalanceSnapshot{
+            AccountNumber: acc.Number,
+            Day: day,
+            Balance: balance,
+        }
+        if err := store.SaveBalanceSnapshot(ctx, snap); err != nil {
+            return 0, err
+        }
+    }
+
+    return len(accounts), nil
+}
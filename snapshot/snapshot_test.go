@@ -0,0 +1,45 @@
+package snapshot
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage/storagetest"
+    "gobank/types"
+)
+
+func TestBalanceAtUsesSnapshotAndLaterEntries(t *testing.T) {
+    ctx := context.Background()
+    store := storagetest.New()
+
+    acc, _ := types.NewAccount("a", "b", "pw")
+    assert.Nil(t, store.CreateAccount(ctx, acc))
+
+    deposit := func(amount int64, at time.Time) {
+        tx := &types.Transaction{Kind: types.TransactionOpening, Amount: amount, CreatedAt: at}
+        assert.Nil(t, store.PostTransaction(ctx, tx, types.NewEntries(types.SettlementAccountNumber, acc.Number, amount)))
+    }
+
+    day1 := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+    deposit(100, day1.Add(10*time.Hour))
+    deposit(50, day1.Add(30*time.Hour))
+    deposit(25, day1.Add(60*time.Hour))
+
+    n, err := Take(ctx, store, day1)
+    assert.Nil(t, err)
+    assert.Equal(t, 1, n)
+
+    cases := map[time.Time]int64{
+        day1.AddDate(0, 0, -1): 0,
+        day1: 100,
+        day1.AddDate(0, 0, 1): 150,
+        day1.AddDate(0, 0, 5): 175,
+    }
+    for day, want := range cases {
+        got, err := BalanceAt(ctx, store, acc.Number, day)
+        assert.Nil(t, err)
+        assert.Equal(t, want, got, day.Format("2006-01-02"))
+    }
+}
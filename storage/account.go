@@ -1,21 +1,42 @@
 package storage
 
 import (
+    "context"
     "database/sql"
+    "encoding/json"
+    "errors"
     "gobank/types"
     "fmt"
+
+    "github.com/lib/pq"
 )
 
+// ErrNumberTaken means another account already has the number.
+var ErrNumberTaken = errors.New("account number is already taken")
+
+const accountColumns = `
+    id, first_name, last_name, number, balance, encrypted_password, created_at,
+    coalesce(email, ''), coalesce(phone, ''), coalesce(phone_verified, false),
+    currency, nickname, metadata, locale, dormancy_notice_at, dormant_since, closed_at
+`
+
 type AccountStorage interface {
-    CreateAccount(*types.Account) error
-    DeleteAccount(int) error
-    UpdateAccount(*types.Account) error
-    GetAccounts() ([]*types.Account, error)
-    GetAccountByID(int) (*types.Account, error)
-    GetAccountByNumber(int64) (*types.Account, error)
+    // CreateAccount fails with ErrNumberTaken when the account's number is
+    // in use, also when a concurrent call took it first.
+    CreateAccount(context.Context, *types.Account) error
+    DeleteAccount(context.Context, int) error
+    UpdateAccount(context.Context, *types.Account) error
+    GetAccounts(context.Context) ([]*types.Account, error)
+    GetAccountByID(context.Context, int) (*types.Account, error)
+    GetAccountByNumber(context.Context, int64) (*types.Account, error)
 }
 
-func (s *PostgresStore) CreateAccount(acc *types.Account) error  {
+func (s *PostgresStore) CreateAccount(ctx context.Context, acc *types.Account) error  {
+    metadata, err := marshalMetadata(acc.Metadata)
+    if err != nil {
+        return err
+    }
+
     query := `
          insert into account 
          (
@@ -24,11 +45,23 @@ func (s *PostgresStore) CreateAccount(acc *types.Account) error  {
              number,
              balance,
              encrypted_password,
-             created_at
+             created_at,
+             email,
+             phone,
+             phone_verified,
+             currency,
+             nickname,
+             metadata,
+             locale,
+             dormancy_notice_at,
+             dormant_since,
+             closed_at
          )
-         values ($1, $2, $3, $4, $5, $6)
+         values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+         returning id
     `
-    _, err := s.db.Query(
+    err = s.db.QueryRowContext(
+        ctx,
         query,
         acc.FirstName,
         acc.LastName,
@@ -36,61 +69,114 @@ func (s *PostgresStore) CreateAccount(acc *types.Account) error  {
         acc.Balance,
         acc.EncryptedPassword,
         acc.CreatedAt,
-    )
+        acc.Email,
+        acc.Phone,
+        acc.PhoneVerified,
+        acc.Currency,
+        acc.Nickname,
+        metadata,
+        acc.Locale,
+        acc.DormancyNoticeAt,
+        acc.DormantSince,
+        acc.ClosedAt,
+    ).Scan(&acc.ID)
+    if err = numberTaken(err); errors.Is(err, ErrNumberTaken) {
+        return fmt.Errorf("account %d: %w", acc.Number, err)
+    }
+
+    return err
+}
+
+// numberTaken turns the unique violation of an account insert into
+// ErrNumberTaken and passes other errors through.
+func numberTaken(err error) error {
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "account_number_key" {
+        return ErrNumberTaken
+    }
+
+    return err
+}
+
+// UpdateAccount saves the profile fields of the account. The balance is
+// owned by the ledger and never written here.
+func (s *PostgresStore) UpdateAccount(ctx context.Context, acc *types.Account) error  {
+    metadata, err := marshalMetadata(acc.Metadata)
     if err != nil {
         return err
     }
 
-    return nil
-}
+    res, err := s.db.ExecContext(ctx, `
+        update account set
+            first_name = $1,
+            last_name = $2,
+            email = $3,
+            phone = $4,
+            phone_verified = $5,
+            nickname = $6,
+            metadata = $7,
+            locale = $8
+        where id = $9
+    `, acc.FirstName, acc.LastName, acc.Email, acc.Phone, acc.PhoneVerified, acc.Nickname, metadata, acc.Locale, acc.ID)
+    if err != nil {
+        return err
+    }
+
+    if n, _ := res.RowsAffected(); n == 0 {
+        return fmt.Errorf("account %d %w", acc.ID, ErrNotFound)
+    }
 
-func (s *PostgresStore) UpdateAccount(*types.Account) error  {
     return nil
 }
 
-func (s *PostgresStore) DeleteAccount(id int) error  {
-    _, err := s.db.Query(`
+func (s *PostgresStore) DeleteAccount(ctx context.Context, id int) error  {
+    _, err := s.db.ExecContext(ctx, `
         delete from account where id = $1
     `, id)
 
     return err
 }
 
-func (s *PostgresStore) GetAccountByNumber(number int64) (*types.Account, error) {
-    rows, err := s.db.Query(`
-        select * from account where number = $1
+func (s *PostgresStore) GetAccountByNumber(ctx context.Context, number int64) (*types.Account, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+accountColumns+` from account where number = $1
     `, number)
 
     if err != nil {
         return nil, err
     }
+    defer rows.Close()
 
     for rows.Next() {
         return scanIntoAccount(rows)
     }
 
-    return nil, fmt.Errorf("account %d not found", number)
+    return nil, fmt.Errorf("account %d %w", number, ErrNotFound)
 }
 
-func (s *PostgresStore) GetAccountByID(id int ) (*types.Account, error)  {
-    rows, err := s.db.Query(`
-        select * from account where id = $1
+func (s *PostgresStore) GetAccountByID(ctx context.Context, id int ) (*types.Account, error)  {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+accountColumns+` from account where id = $1
     `, id)
     if err != nil {
         return nil, err
     }
+    defer rows.Close()
+
     for rows.Next() {
         return scanIntoAccount(rows)
     }
 
-    return nil, fmt.Errorf("account %d not found", id)
+    return nil, fmt.Errorf("account %d %w", id, ErrNotFound)
 }
 
-func (s *PostgresStore) GetAccounts() ([]*types.Account, error)  {
-    rows, err := s.db.Query("select * from account")
+func (s *PostgresStore) GetAccounts(ctx context.Context) ([]*types.Account, error)  {
+    rows, err := s.db.QueryContext(ctx, "select "+accountColumns+" from account")
     if err != nil {
         return nil, err
     }
+    defer rows.Close()
+
     accounts := []*types.Account{}
     for rows.Next() {
         account, err := scanIntoAccount(rows)
@@ -100,11 +186,12 @@ func (s *PostgresStore) GetAccounts() ([]*types.Account, error)  {
         accounts = append(accounts, account)
     }
 
-    return accounts, nil
+    return accounts, rows.Err()
 }
 
 func scanIntoAccount(rows *sql.Rows) (*types.Account, error) {
     account := new(types.Account)
+    var metadata []byte
     err := rows.Scan(
         &account.ID,
         &account.FirstName,
@@ -113,9 +200,36 @@ func scanIntoAccount(rows *sql.Rows) (*types.Account, error) {
         &account.Balance,
         &account.EncryptedPassword,
         &account.CreatedAt,
+        &account.Email,
+        &account.Phone,
+        &account.PhoneVerified,
+        &account.Currency,
+        &account.Nickname,
+        &metadata,
+        &account.Locale,
+        &account.DormancyNoticeAt,
+        &account.DormantSince,
+        &account.ClosedAt,
     )
-    
-    return account, err
+    if err != nil {
+        return nil, err
+    }
+
+    if err := json.Unmarshal(metadata, &account.Metadata); err != nil {
+        return nil, err
+    }
+    if len(account.Metadata) == 0 {
+        account.Metadata = nil
+    }
+
+    return account, nil
+}
+
+func marshalMetadata(metadata map[string]string) ([]byte, error) {
+    if metadata == nil {
+        return []byte("{}"), nil
+    }
+    return json.Marshal(metadata)
 }
 
 
@@ -0,0 +1,79 @@
+package storage
+
+import (
+    "context"
+    "fmt"
+
+    "gobank/types"
+)
+
+type AlertStorage interface {
+    CreateAlertRule(context.Context, *types.AlertRule) error
+    GetAlertRules(context.Context, int64) ([]*types.AlertRule, error)
+    // DeleteAlertRule removes the rule only if it belongs to the account.
+    DeleteAlertRule(context.Context, int64, int) error
+}
+
+func (s *PostgresStore) CreateAlertTable() error {
+    query := `create table if not exists alert_rule (
+        id serial primary key,
+        account_number bigint not null,
+        kind varchar(32) not null,
+        threshold bigint not null default 0,
+        created_at timestamp not null
+    )`
+
+    _, err := s.db.Exec(query)
+    return err
+}
+
+func (s *PostgresStore) CreateAlertRule(ctx context.Context, rule *types.AlertRule) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into alert_rule (account_number, kind, threshold, created_at)
+        values ($1, $2, $3, $4)
+        returning id
+    `, rule.AccountNumber, rule.Kind, rule.Threshold, rule.CreatedAt).Scan(&rule.ID)
+}
+
+func (s *PostgresStore) GetAlertRules(ctx context.Context, number int64) ([]*types.AlertRule, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, account_number, kind, threshold, created_at
+        from alert_rule where account_number = $1 order by id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    rules := []*types.AlertRule{}
+    for rows.Next() {
+        rule := new(types.AlertRule)
+        if err := rows.Scan(
+            &rule.ID,
+            &rule.AccountNumber,
+            &rule.Kind,
+            &rule.Threshold,
+            &rule.CreatedAt,
+        ); err != nil {
+            return nil, err
+        }
+        rules = append(rules, rule)
+    }
+
+    return rules, rows.Err()
+}
+
+func (s *PostgresStore) DeleteAlertRule(ctx context.Context, number int64, id int) error {
+    res, err := s.db.ExecContext(ctx, `
+        delete from alert_rule where id = $1 and account_number = $2
+    `, id, number)
+    if err != nil {
+        return err
+    }
+
+    if n, _ := res.RowsAffected(); n == 0 {
+        return fmt.Errorf("alert rule %d %w", id, ErrNotFound)
+    }
+
+    return nil
+}
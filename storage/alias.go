@@ -0,0 +1,249 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/lib/pq"
+    "gobank/types"
+)
+
+var ErrAliasTaken = errors.New("alias already registered")
+
+type AliasStorage interface {
+    // CreateAlias fails with ErrAliasTaken if any account has the alias.
+    CreateAlias(context.Context, *types.Alias) error
+    GetAlias(context.Context, string) (*types.Alias, error)
+    GetAliasesByAccount(context.Context, int64) ([]*types.Alias, error)
+    DeleteAlias(ctx context.Context, number int64, alias string) error
+
+    SaveAliasVerification(context.Context, *types.AliasVerification) error
+    GetAliasVerification(ctx context.Context, number int64, alias string) (*types.AliasVerification, error)
+    DeleteAliasVerification(ctx context.Context, number int64, alias string) error
+
+    // CreateAliasClaim posts the pending transaction holding the money and
+    // records the claim in one database transaction. It is a transfer, so
+    // it fails with ErrVelocityExceeded like PostTransfer.
+    CreateAliasClaim(ctx context.Context, c *types.AliasClaim, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error
+    // GetPendingAliasClaims returns the pending claims on alias, or on any
+    // alias when it is empty.
+    GetPendingAliasClaims(context.Context, string) ([]*types.AliasClaim, error)
+    // GetExpiredAliasClaims returns pending claims that expired before t.
+    GetExpiredAliasClaims(context.Context, time.Time) ([]*types.AliasClaim, error)
+}
+
+func (s *PostgresStore) CreateAliasTables() error {
+    queries := []string{
+        `create table if not exists alias (
+            alias varchar(320) primary key,
+            kind varchar(16) not null,
+            account_number bigint not null,
+            created_at timestamp not null
+        )`,
+        `create index if not exists alias_account_number_idx on alias (account_number)`,
+        `create table if not exists alias_verification (
+            account_number bigint not null,
+            alias varchar(320) not null,
+            code_hash varchar(64) not null,
+            attempts integer not null default 0,
+            expires_at timestamp not null,
+            primary key (account_number, alias)
+        )`,
+        `create table if not exists alias_claim (
+            transaction_id integer primary key references transaction(id),
+            alias varchar(320) not null,
+            expires_at timestamp not null
+        )`,
+        `create index if not exists alias_claim_alias_idx on alias_claim (alias)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateAlias(ctx context.Context, a *types.Alias) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into alias (alias, kind, account_number, created_at)
+        values ($1, $2, $3, $4)
+    `, a.Alias, a.Kind, a.AccountNumber, a.CreatedAt)
+
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+        return fmt.Errorf("%s: %w", a.Alias, ErrAliasTaken)
+    }
+
+    return err
+}
+
+func (s *PostgresStore) GetAlias(ctx context.Context, alias string) (*types.Alias, error) {
+    a := new(types.Alias)
+    err := s.db.QueryRowContext(ctx, `
+        select alias, kind, account_number, created_at from alias where alias = $1
+    `, alias).Scan(&a.Alias, &a.Kind, &a.AccountNumber, &a.CreatedAt)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("alias %s %w", alias, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return a, nil
+}
+
+func (s *PostgresStore) GetAliasesByAccount(ctx context.Context, number int64) ([]*types.Alias, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select alias, kind, account_number, created_at
+        from alias where account_number = $1 order by created_at
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    aliases := []*types.Alias{}
+    for rows.Next() {
+        a := new(types.Alias)
+        if err := rows.Scan(&a.Alias, &a.Kind, &a.AccountNumber, &a.CreatedAt); err != nil {
+            return nil, err
+        }
+        aliases = append(aliases, a)
+    }
+
+    return aliases, rows.Err()
+}
+
+func (s *PostgresStore) DeleteAlias(ctx context.Context, number int64, alias string) error {
+    res, err := s.db.ExecContext(ctx, `
+        delete from alias where account_number = $1 and alias = $2
+    `, number, alias)
+    if err != nil {
+        return err
+    }
+
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("alias %s %w", alias, ErrNotFound)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) SaveAliasVerification(ctx context.Context, v *types.AliasVerification) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into alias_verification (account_number, alias, code_hash, attempts, expires_at)
+        values ($1, $2, $3, $4, $5)
+        on conflict (account_number, alias) do update set
+            code_hash = excluded.code_hash,
+            attempts = excluded.attempts,
+            expires_at = excluded.expires_at
+    `, v.AccountNumber, v.Alias, v.CodeHash, v.Attempts, v.ExpiresAt)
+
+    return err
+}
+
+func (s *PostgresStore) GetAliasVerification(ctx context.Context, number int64, alias string) (*types.AliasVerification, error) {
+    v := &types.AliasVerification{AccountNumber: number, Alias: alias}
+    err := s.db.QueryRowContext(ctx, `
+        select code_hash, attempts, expires_at
+        from alias_verification where account_number = $1 and alias = $2
+    `, number, alias).Scan(&v.CodeHash, &v.Attempts, &v.ExpiresAt)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("alias verification for %s %w", alias, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return v, nil
+}
+
+func (s *PostgresStore) DeleteAliasVerification(ctx context.Context, number int64, alias string) error {
+    _, err := s.db.ExecContext(ctx, `
+        delete from alias_verification where account_number = $1 and alias = $2
+    `, number, alias)
+
+    return err
+}
+
+func (s *PostgresStore) CreateAliasClaim(ctx context.Context, c *types.AliasClaim, t *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+    if err := checkVelocity(ctx, dbtx, t, limits); err != nil {
+        return err
+    }
+
+    c.TransactionID = t.ID
+    c.FromAccount = t.FromAccount
+    c.Amount = t.Amount
+    _, err = dbtx.ExecContext(ctx, `
+        insert into alias_claim (transaction_id, alias, expires_at)
+        values ($1, $2, $3)
+    `, c.TransactionID, c.Alias, c.ExpiresAt)
+    if err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) GetPendingAliasClaims(ctx context.Context, alias string) ([]*types.AliasClaim, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select c.transaction_id, c.alias, t.from_account, t.amount, c.expires_at
+        from alias_claim c join transaction t on t.id = c.transaction_id
+        where ($1 = '' or c.alias = $1) and t.status = $2
+        order by c.transaction_id
+    `, alias, types.StatusPending)
+    if err != nil {
+        return nil, err
+    }
+    return scanAliasClaims(rows)
+}
+
+func (s *PostgresStore) GetExpiredAliasClaims(ctx context.Context, t time.Time) ([]*types.AliasClaim, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select c.transaction_id, c.alias, t.from_account, t.amount, c.expires_at
+        from alias_claim c join transaction t on t.id = c.transaction_id
+        where c.expires_at < $1 and t.status = $2
+        order by c.transaction_id
+    `, t, types.StatusPending)
+    if err != nil {
+        return nil, err
+    }
+    return scanAliasClaims(rows)
+}
+
+func scanAliasClaims(rows *sql.Rows) ([]*types.AliasClaim, error) {
+    defer rows.Close()
+
+    claims := []*types.AliasClaim{}
+    for rows.Next() {
+        c := new(types.AliasClaim)
+        if err := rows.Scan(&c.TransactionID, &c.Alias, &c.FromAccount, &c.Amount, &c.ExpiresAt); err != nil {
+            return nil, err
+        }
+        claims = append(claims, c)
+    }
+
+    return claims, rows.Err()
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+    "context"
+    "time"
+
+    "github.com/lib/pq"
+    "gobank/types"
+)
+
+// archiveBatchSize is how many transactions are moved per database
+// transaction, so archiving never holds locks on the hot tables for long.
+const archiveBatchSize = 1000
+
+// Reads of old data go through these views, which put the archive tables
+// back together with the hot ones. Any column added to transaction or
+// ledger_entry has to be added to its archive table and view as well.
+const (
+    allTransactions = "all_transactions"
+    allLedgerEntries = "all_ledger_entries"
+)
+
+type ArchiveStorage interface {
+    // ArchiveTransactionsBefore moves the settled transactions created
+    // before t, with their ledger entries, from the hot tables to the
+    // archive tables and returns how many it moved. Balances, statements
+    // and reports read both, so nothing changes for them. Transactions a
+    // payment request or alias claim points to stay where they are.
+    ArchiveTransactionsBefore(context.Context, time.Time) (int, error)
+}
+
+func (s *PostgresStore) CreateArchiveTables() error {
+    queries := []string{
+        `create table if not exists transaction_archive (
+            id integer primary key,
+            kind varchar(32),
+            status varchar(16),
+            from_account bigint,
+            to_account bigint,
+            amount bigint,
+            provider varchar(32),
+            reference varchar(128),
+            created_at timestamp
+        )`,
+        `alter table transaction_archive add column if not exists idempotency_key varchar(128)`,
+        `create index if not exists transaction_archive_from_idx on transaction_archive (from_account, created_at)`,
+        `create index if not exists transaction_archive_to_idx on transaction_archive (to_account, created_at)`,
+        `create index if not exists transaction_archive_provider_reference_idx on transaction_archive (provider, reference) where reference is not null`,
+        `create table if not exists ledger_entry_archive (
+            id integer primary key,
+            transaction_id integer not null,
+            account_number bigint not null,
+            amount bigint not null,
+            created_at timestamp
+        )`,
+        `create index if not exists ledger_entry_archive_account_idx on ledger_entry_archive (account_number, created_at)`,
+        `create or replace view ` + allTransactions + ` as
+            select id, kind, status, from_account, to_account, amount, provider, reference, created_at, idempotency_key from transaction
+            union all
+            select id, kind, status, from_account, to_account, amount, provider, reference, created_at, idempotency_key from transaction_archive`,
+        `create or replace view ` + allLedgerEntries + ` as
+            select id, transaction_id, account_number, amount, created_at from ledger_entry
+            union all
+            select id, transaction_id, account_number, amount, created_at from ledger_entry_archive`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) ArchiveTransactionsBefore(ctx context.Context, t time.Time) (int, error) {
+    moved := 0
+    for {
+        n, err := s.archiveBatch(ctx, t)
+        moved += n
+        if err != nil || n < archiveBatchSize {
+            return moved, err
+        }
+    }
+}
+
+func (s *PostgresStore) archiveBatch(ctx context.Context, before time.Time) (int, error) {
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer dbtx.Rollback()
+
+    rows, err := dbtx.QueryContext(ctx, `
+        select id from transaction t
+        where created_at < $1 and coalesce(status, $2) <> $3
+            and not exists (select 1 from alias_claim c where c.transaction_id = t.id)
+            and not exists (select 1 from payment_request r where r.transaction_id = t.id)
+        order by id
+        limit $4
+        for update skip locked
+    `, before, types.StatusCompleted, types.StatusPending, archiveBatchSize)
+    if err != nil {
+        return 0, err
+    }
+
+    ids := []int64{}
+    for rows.Next() {
+        var id int64
+        if err := rows.Scan(&id); err != nil {
+            rows.Close()
+            return 0, err
+        }
+        ids = append(ids, id)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return 0, err
+    }
+    if len(ids) == 0 {
+        return 0, nil
+    }
+
+    // entries first, they reference the transactions
+    queries := []string{
+        `insert into ledger_entry_archive (id, transaction_id, account_number, amount, created_at)
+            select id, transaction_id, account_number, amount, created_at from ledger_entry where transaction_id = any($1)`,
+        `delete from ledger_entry where transaction_id = any($1)`,
+        `insert into transaction_archive (id, kind, status, from_account, to_account, amount, provider, reference, created_at, idempotency_key)
+            select id, kind, status, from_account, to_account, amount, provider, reference, created_at, idempotency_key from transaction where id = any($1)`,
+        `delete from transaction where id = any($1)`,
+    }
+    for _, query := range queries {
+        if _, err := dbtx.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+            return 0, err
+        }
+    }
+
+    return len(ids), dbtx.Commit()
+}
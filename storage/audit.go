@@ -0,0 +1,63 @@
+package storage
+
+import (
+    "context"
+
+    "gobank/types"
+)
+
+type AuditStorage interface {
+    RecordAudit(context.Context, *types.AuditEntry) error
+    // GetAuditLog returns an account's audit entries, newest first.
+    GetAuditLog(context.Context, int64) ([]*types.AuditEntry, error)
+}
+
+func (s *PostgresStore) CreateAuditTable() error {
+    query := `create table if not exists audit_log (
+        id serial primary key,
+        account_number bigint not null,
+        actor_number bigint not null,
+        method varchar(8) not null,
+        path text not null,
+        status integer not null,
+        created_at timestamp not null
+    )`
+
+    if _, err := s.db.Exec(query); err != nil {
+        return err
+    }
+
+    _, err := s.db.Exec(`create index if not exists audit_log_account_number_idx on audit_log (account_number, created_at)`)
+    return err
+}
+
+func (s *PostgresStore) RecordAudit(ctx context.Context, e *types.AuditEntry) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into audit_log (account_number, actor_number, method, path, status, created_at)
+        values ($1, $2, $3, $4, $5, $6)
+        returning id
+    `, e.AccountNumber, e.ActorNumber, e.Method, e.Path, e.Status, e.CreatedAt).Scan(&e.ID)
+}
+
+func (s *PostgresStore) GetAuditLog(ctx context.Context, number int64) ([]*types.AuditEntry, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, account_number, actor_number, method, path, status, created_at
+        from audit_log where account_number = $1
+        order by created_at desc, id desc
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    entries := []*types.AuditEntry{}
+    for rows.Next() {
+        e := new(types.AuditEntry)
+        if err := rows.Scan(&e.ID, &e.AccountNumber, &e.ActorNumber, &e.Method, &e.Path, &e.Status, &e.CreatedAt); err != nil {
+            return nil, err
+        }
+        entries = append(entries, e)
+    }
+
+    return entries, rows.Err()
+}
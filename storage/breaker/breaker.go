@@ -0,0 +1,145 @@
+package breaker
+
+import (
+    "context"
+    "fmt"
+    "sync"
+    "time"
+
+    "gobank/metrics"
+    "gobank/storage"
+)
+
+type State int
+
+const (
+    Closed State = iota
+    HalfOpen
+    Open
+)
+
+func (s State) String() string {
+    switch s {
+    case Closed:
+        return "closed"
+    case HalfOpen:
+        return "half-open"
+    }
+    return "open"
+}
+
+// ErrOpen is returned without touching the backend while the breaker is
+// open. It wraps storage.ErrUnavailable.
+var ErrOpen = fmt.Errorf("circuit breaker is open: %w", storage.ErrUnavailable)
+
+var (
+    stateGauge = metrics.NewGauge("gobank_storage_breaker_state", "Storage circuit breaker state (0 closed, 1 half-open, 2 open).")
+    tripsTotal = metrics.NewCounter("gobank_storage_breaker_trips_total", "Times the storage circuit breaker opened.")
+    rejectedTotal = metrics.NewCounter("gobank_storage_breaker_rejected_total", "Storage calls rejected while the breaker was open.")
+)
+
+// Breaker opens after threshold consecutive calls failed with an error
+// storage.IsUnavailable reports as an outage. After cooldown one call is
+// let through as a probe: success closes the breaker, failure reopens it.
+// Calls that began before the last state change don't count, so a slow call
+// that started while the backend was healthy can't close an open breaker.
+type Breaker struct {
+    threshold int
+    cooldown time.Duration
+    now func() time.Time
+
+    mu sync.Mutex
+    state State
+    failures int
+    openedAt time.Time
+    // generation counts state changes
+    generation uint64
+}
+
+func New(threshold int, cooldown time.Duration) *Breaker {
+    b := &Breaker{
+        threshold: threshold,
+        cooldown: cooldown,
+        now: time.Now,
+    }
+    stateGauge.Set(float64(Closed))
+
+    return b
+}
+
+// Wrap returns store with every call guarded by b.
+func Wrap(store storage.Storage, b *Breaker) storage.Storage {
+    return storage.Intercept(store, b.Do)
+}
+
+func (b *Breaker) State() State {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    return b.state
+}
+
+func (b *Breaker) Do(ctx context.Context, op string, call func(context.Context) error) error {
+    generation, ok := b.allow()
+    if !ok {
+        rejectedTotal.Inc()
+        return ErrOpen
+    }
+
+    err := call(ctx)
+    b.record(generation, storage.IsUnavailable(err))
+
+    return err
+}
+
+// allow reports whether a call may go ahead and the generation it runs in.
+func (b *Breaker) allow() (uint64, bool) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    switch b.state {
+    case Open:
+        if b.now().Sub(b.openedAt) < b.cooldown {
+            return 0, false
+        }
+        b.setState(HalfOpen)
+        return b.generation, true
+    case HalfOpen:
+        // a probe is already in flight
+        return 0, false
+    }
+
+    return b.generation, true
+}
+
+func (b *Breaker) record(generation uint64, failed bool) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if generation != b.generation {
+        return
+    }
+
+    if !failed {
+        b.failures = 0
+        b.setState(Closed)
+        return
+    }
+
+    b.failures++
+    if b.state == HalfOpen || b.failures >= b.threshold {
+        if b.state != Open {
+            tripsTotal.Inc()
+        }
+        b.openedAt = b.now()
+        b.setState(Open)
+    }
+}
+
+func (b *Breaker) setState(s State) {
+    if s != b.state {
+        b.generation++
+    }
+    b.state = s
+    stateGauge.Set(float64(s))
+}
@@ -0,0 +1,83 @@
+package breaker
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "gobank/storage"
+    "gobank/storage/storagetest"
+)
+
+func TestBreakerTripsAndRecovers(t *testing.T) {
+    now := time.Now()
+    b := New(2, time.Minute)
+    b.now = func() time.Time { return now }
+
+    fake := storagetest.New()
+    store := Wrap(fake, b)
+    ctx := context.Background()
+
+    fake.FailOn("GetAccounts", context.DeadlineExceeded)
+    for i := 0; i < 2; i++ {
+        _, err := store.GetAccounts(ctx)
+        assert.ErrorIs(t, err, context.DeadlineExceeded)
+    }
+    assert.Equal(t, Open, b.State())
+
+    _, err := store.GetAccounts(ctx)
+    assert.ErrorIs(t, err, ErrOpen)
+    assert.ErrorIs(t, err, storage.ErrUnavailable)
+
+    fake.FailOn("GetAccounts", nil)
+    now = now.Add(time.Minute)
+
+    _, err = store.GetAccounts(ctx)
+    assert.Nil(t, err)
+    assert.Equal(t, Closed, b.State())
+}
+
+func TestBreakerIgnoresNotFound(t *testing.T) {
+    b := New(1, time.Minute)
+    store := Wrap(storagetest.New(), b)
+
+    _, err := store.GetAccountByID(context.Background(), 42)
+    assert.True(t, errors.Is(err, storage.ErrNotFound))
+    assert.Equal(t, Closed, b.State())
+}
+
+func TestBreakerIgnoresCallsFromBeforeAStateChange(t *testing.T) {
+    now := time.Now()
+    b := New(2, time.Minute)
+    b.now = func() time.Time { return now }
+    ctx := context.Background()
+
+    started := make(chan struct{})
+    release := make(chan struct{})
+    done := make(chan error)
+    go func() {
+        done <- b.Do(ctx, "GetAccounts", func(context.Context) error {
+            close(started)
+            <-release
+            return nil
+        })
+    }()
+    <-started
+
+    failing := func(context.Context) error { return context.DeadlineExceeded }
+    for i := 0; i < 2; i++ {
+        b.Do(ctx, "GetAccounts", failing)
+    }
+    assert.Equal(t, Open, b.State())
+
+    // the slow call began while the breaker was closed
+    close(release)
+    assert.Nil(t, <-done)
+    assert.Equal(t, Open, b.State())
+
+    now = now.Add(time.Minute)
+    assert.Nil(t, b.Do(ctx, "GetAccounts", func(context.Context) error { return nil }))
+    assert.Equal(t, Closed, b.State())
+}
@@ -0,0 +1,412 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+var (
+    ErrCardLimitExceeded = errors.New("card daily limit exceeded")
+    ErrHoldClosed = errors.New("hold is no longer active")
+)
+
+type CardStorage interface {
+    CreateCard(context.Context, *types.Card) error
+    GetCard(context.Context, int) (*types.Card, error)
+    GetCardByPANHash(context.Context, string) (*types.Card, error)
+    GetCardsByAccount(context.Context, int64) ([]*types.Card, error)
+    // UpdateCard saves the status and daily limit.
+    UpdateCard(context.Context, *types.Card) error
+    // SetCardPIN saves a PIN hash and clears the failed attempts.
+    SetCardPIN(ctx context.Context, id int, hash string) error
+    // RecordPINAttempt clears the failed attempts after a correct PIN, or
+    // counts a wrong one and blocks the card once maxAttempts are used up.
+    // It returns the card as updated.
+    RecordPINAttempt(ctx context.Context, id int, correct bool, maxAttempts int) (*types.Card, error)
+
+    // PlaceHold reserves the hold's amount on its account. It fails with
+    // ErrInsufficientFunds when the balance minus active holds doesn't
+    // cover it and with ErrCardLimitExceeded when the card's holds for the
+    // UTC day would go over dailyLimit (0 for none).
+    PlaceHold(ctx context.Context, hold *types.Hold, dailyLimit int64) error
+    GetHold(context.Context, int) (*types.Hold, error)
+    // GetActiveHolds returns the account's holds that are neither released
+    // nor expired at t.
+    GetActiveHolds(ctx context.Context, number int64, t time.Time) ([]*types.Hold, error)
+    // CaptureHold marks the hold captured and posts tx, the card payment,
+    // in one database transaction, so the money isn't held and spent at
+    // once. ReleaseHold gives the held amount back. Both fail with
+    // ErrHoldClosed unless the hold is active and unexpired at tx's or at
+    // time.
+    CaptureHold(ctx context.Context, id int, tx *types.Transaction, entries []*types.LedgerEntry) error
+    ReleaseHold(ctx context.Context, id int, at time.Time) error
+    // ExpireHolds marks the active holds that expired by t and returns
+    // how many there were.
+    ExpireHolds(ctx context.Context, t time.Time) (int, error)
+}
+
+const cardColumns = `id, account_number, last4, status, daily_limit, created_at, pan_hash, pan_encrypted, cvv_encrypted, expiry_encrypted, coalesce(pin_hash, ''), pin_attempts`
+
+func (s *PostgresStore) CreateCardTables() error {
+    queries := []string{
+        `create table if not exists card (
+            id serial primary key,
+            account_number bigint not null,
+            last4 varchar(4) not null,
+            status varchar(16) not null,
+            daily_limit bigint not null default 0,
+            created_at timestamp not null,
+            pan_hash varchar(64) not null unique,
+            pan_encrypted text not null,
+            cvv_encrypted text not null,
+            expiry_encrypted text not null
+        )`,
+        `create index if not exists card_account_number_idx on card (account_number)`,
+        `alter table card add column if not exists pin_hash varchar(72)`,
+        `alter table card add column if not exists pin_attempts integer not null default 0`,
+        `create table if not exists card_hold (
+            id serial primary key,
+            account_number bigint not null,
+            card_id integer not null references card(id),
+            amount bigint not null check (amount > 0),
+            merchant varchar(128) not null default '',
+            status varchar(16) not null,
+            expires_at timestamp not null,
+            created_at timestamp not null
+        )`,
+        `create index if not exists card_hold_account_number_idx on card_hold (account_number, status)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateCard(ctx context.Context, c *types.Card) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into card
+        (account_number, last4, status, daily_limit, created_at, pan_hash, pan_encrypted, cvv_encrypted, expiry_encrypted)
+        values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        returning id
+    `,
+        c.AccountNumber,
+        c.Last4,
+        c.Status,
+        c.DailyLimit,
+        c.CreatedAt,
+        c.PANHash,
+        c.EncryptedPAN,
+        c.EncryptedCVV,
+        c.EncryptedExpiry,
+    ).Scan(&c.ID)
+}
+
+func (s *PostgresStore) GetCard(ctx context.Context, id int) (*types.Card, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+cardColumns+` from card where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+    return firstCard(rows, fmt.Sprintf("card %d", id))
+}
+
+func (s *PostgresStore) GetCardByPANHash(ctx context.Context, hash string) (*types.Card, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+cardColumns+` from card where pan_hash = $1`, hash)
+    if err != nil {
+        return nil, err
+    }
+    return firstCard(rows, "card")
+}
+
+func (s *PostgresStore) GetCardsByAccount(ctx context.Context, number int64) ([]*types.Card, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+cardColumns+` from card where account_number = $1 order by id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanCards(rows)
+}
+
+func (s *PostgresStore) UpdateCard(ctx context.Context, c *types.Card) error {
+    res, err := s.db.ExecContext(ctx, `
+        update card set status = $1, daily_limit = $2 where id = $3
+    `, c.Status, c.DailyLimit, c.ID)
+    if err != nil {
+        return err
+    }
+
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("card %d %w", c.ID, ErrNotFound)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) SetCardPIN(ctx context.Context, id int, hash string) error {
+    res, err := s.db.ExecContext(ctx, `
+        update card set pin_hash = $1, pin_attempts = 0 where id = $2
+    `, hash, id)
+    if err != nil {
+        return err
+    }
+
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("card %d %w", id, ErrNotFound)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) RecordPINAttempt(ctx context.Context, id int, correct bool, maxAttempts int) (*types.Card, error) {
+    query := `update card set pin_attempts = 0 where id = $1`
+    args := []any{id}
+    if !correct {
+        query = `
+            update card set
+                pin_attempts = pin_attempts + 1,
+                status = case when pin_attempts + 1 >= $2 then $3 else status end
+            where id = $1`
+        args = append(args, maxAttempts, types.CardBlocked)
+    }
+
+    if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+        return nil, err
+    }
+
+    return s.GetCard(ctx, id)
+}
+
+func (s *PostgresStore) PlaceHold(ctx context.Context, h *types.Hold, dailyLimit int64) error {
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    // the account row lock serializes holds and postings on the account
+    var balance int64
+    err = dbtx.QueryRowContext(ctx, `
+        select balance from account where number = $1 for update
+    `, h.AccountNumber).Scan(&balance)
+    if err == sql.ErrNoRows {
+        return fmt.Errorf("account %d %w", h.AccountNumber, ErrNotFound)
+    }
+    if err != nil {
+        return err
+    }
+
+    held, err := heldTotal(ctx, dbtx, h.AccountNumber, h.CreatedAt)
+    if err != nil {
+        return err
+    }
+    saved, err := potTotal(ctx, dbtx, h.AccountNumber)
+    if err != nil {
+        return err
+    }
+    disputed, err := disputedTotal(ctx, dbtx, h.AccountNumber)
+    if err != nil {
+        return err
+    }
+    if balance-saved-disputed-held < h.Amount {
+        return fmt.Errorf("account %d: %w", h.AccountNumber, ErrInsufficientFunds)
+    }
+
+    if dailyLimit > 0 {
+        day := h.CreatedAt.UTC().Truncate(24 * time.Hour)
+        var spent int64
+        err = dbtx.QueryRowContext(ctx, `
+            select coalesce(sum(amount), 0) from card_hold
+            where card_id = $1 and status <> $2 and created_at >= $3
+        `, h.CardID, types.HoldReleased, day).Scan(&spent)
+        if err != nil {
+            return err
+        }
+        if spent+h.Amount > dailyLimit {
+            return fmt.Errorf("card %d: %w", h.CardID, ErrCardLimitExceeded)
+        }
+    }
+
+    err = dbtx.QueryRowContext(ctx, `
+        insert into card_hold
+        (account_number, card_id, amount, merchant, status, expires_at, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7)
+        returning id
+    `, h.AccountNumber, h.CardID, h.Amount, h.Merchant, h.Status, h.ExpiresAt, h.CreatedAt).Scan(&h.ID)
+    if err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+const holdColumns = `id, account_number, card_id, amount, merchant, status, expires_at, created_at`
+
+func (s *PostgresStore) GetHold(ctx context.Context, id int) (*types.Hold, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+holdColumns+` from card_hold where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    holds, err := scanHolds(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(holds) == 0 {
+        return nil, fmt.Errorf("hold %d %w", id, ErrNotFound)
+    }
+
+    return holds[0], nil
+}
+
+func (s *PostgresStore) GetActiveHolds(ctx context.Context, number int64, t time.Time) ([]*types.Hold, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+holdColumns+` from card_hold
+        where account_number = $1 and status = $2 and expires_at > $3
+        order by id
+    `, number, types.HoldActive, t)
+    if err != nil {
+        return nil, err
+    }
+    return scanHolds(rows)
+}
+
+func (s *PostgresStore) CaptureHold(ctx context.Context, id int, t *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := closeHold(ctx, dbtx, id, types.HoldCaptured, t.CreatedAt); err != nil {
+        return err
+    }
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) ReleaseHold(ctx context.Context, id int, at time.Time) error {
+    return closeHold(ctx, s.db, id, types.HoldReleased, at)
+}
+
+// closeHold runs on the database or inside a database transaction.
+func closeHold(ctx context.Context, db interface {
+    ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, id int, status string, at time.Time) error {
+    res, err := db.ExecContext(ctx, `
+        update card_hold set status = $1 where id = $2 and status = $3 and expires_at > $4
+    `, status, id, types.HoldActive, at)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("hold %d: %w", id, ErrHoldClosed)
+    }
+
+    return nil
+}
+
+// heldTotal is how much of an account's balance active card holds reserve
+// at t.
+func heldTotal(ctx context.Context, dbtx *sql.Tx, number int64, t time.Time) (int64, error) {
+    var total int64
+    err := dbtx.QueryRowContext(ctx, `
+        select coalesce(sum(amount), 0) from card_hold
+        where account_number = $1 and status = $2 and expires_at > $3
+    `, number, types.HoldActive, t).Scan(&total)
+    return total, err
+}
+
+func scanHolds(rows *sql.Rows) ([]*types.Hold, error) {
+    defer rows.Close()
+
+    holds := []*types.Hold{}
+    for rows.Next() {
+        h := new(types.Hold)
+        if err := rows.Scan(&h.ID, &h.AccountNumber, &h.CardID, &h.Amount, &h.Merchant, &h.Status, &h.ExpiresAt, &h.CreatedAt); err != nil {
+            return nil, err
+        }
+        holds = append(holds, h)
+    }
+
+    return holds, rows.Err()
+}
+
+func (s *PostgresStore) ExpireHolds(ctx context.Context, t time.Time) (int, error) {
+    res, err := s.db.ExecContext(ctx, `
+        update card_hold set status = $1 where status = $2 and expires_at <= $3
+    `, types.HoldExpired, types.HoldActive, t)
+    if err != nil {
+        return 0, err
+    }
+
+    n, err := res.RowsAffected()
+    return int(n), err
+}
+
+func firstCard(rows *sql.Rows, what string) (*types.Card, error) {
+    cards, err := scanCards(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(cards) == 0 {
+        return nil, fmt.Errorf("%s %w", what, ErrNotFound)
+    }
+
+    return cards[0], nil
+}
+
+func scanCards(rows *sql.Rows) ([]*types.Card, error) {
+    defer rows.Close()
+
+    cards := []*types.Card{}
+    for rows.Next() {
+        c := new(types.Card)
+        if err := rows.Scan(
+            &c.ID,
+            &c.AccountNumber,
+            &c.Last4,
+            &c.Status,
+            &c.DailyLimit,
+            &c.CreatedAt,
+            &c.PANHash,
+            &c.EncryptedPAN,
+            &c.EncryptedCVV,
+            &c.EncryptedExpiry,
+            &c.PINHash,
+            &c.PINAttempts,
+        ); err != nil {
+            return nil, err
+        }
+        c.PINSet = c.PINHash != ""
+        cards = append(cards, c)
+    }
+
+    return cards, rows.Err()
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+var (
+    ErrAccountClosed = errors.New("account is closed")
+    // ErrClosureBlocked means the account still owes or is owed something
+    // that has to be settled before it can be closed.
+    ErrClosureBlocked = errors.New("account can't be closed yet")
+)
+
+type ClosureStorage interface {
+    // CloseAccount closes the account in one database transaction: it
+    // empties the pots, posts sweep (nil when there is no balance), which
+    // has to leave the balance at exactly zero, cancels pending payment
+    // requests and owner invitations, revokes grants, blocks the cards
+    // and sets ClosedAt. sweep is held to limits like PostTransfer. It
+    // fails with ErrAccountClosed when the account is already closed and
+    // with ErrClosureBlocked while it has a loan, card holds that may
+    // still be captured, open disputes, transfers out of the bank that
+    // haven't settled or transfers to aliases that haven't been claimed.
+    CloseAccount(ctx context.Context, number int64, sweep *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit, at time.Time) error
+}
+
+func (s *PostgresStore) CloseAccount(ctx context.Context, number int64, sweep *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit, at time.Time) error {
+    if sweep != nil {
+        if err := types.ValidateEntries(entries); err != nil {
+            return err
+        }
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    var closedAt sql.NullTime
+    err = dbtx.QueryRowContext(ctx, `select closed_at from account where number = $1 for update`, number).Scan(&closedAt)
+    if err == sql.ErrNoRows {
+        return fmt.Errorf("account %d %w", number, ErrNotFound)
+    }
+    if err != nil {
+        return err
+    }
+    if closedAt.Valid {
+        return fmt.Errorf("account %d: %w", number, ErrAccountClosed)
+    }
+
+    if err := closureBlocker(ctx, dbtx, number, at); err != nil {
+        return err
+    }
+
+    // money in pots is part of the balance, it is swept with the rest
+    if _, err := dbtx.ExecContext(ctx, `delete from pot where account_number = $1`, number); err != nil {
+        return err
+    }
+    if sweep != nil {
+        if err := postTransaction(ctx, dbtx, sweep, entries); err != nil {
+            return err
+        }
+        if err := checkVelocity(ctx, dbtx, sweep, limits); err != nil {
+            return err
+        }
+    }
+
+    var balance int64
+    if err := dbtx.QueryRowContext(ctx, `select balance from account where number = $1`, number).Scan(&balance); err != nil {
+        return err
+    }
+    if balance != 0 {
+        return fmt.Errorf("account %d would keep a balance of %d: %w", number, balance, ErrClosureBlocked)
+    }
+
+    statements := []struct {
+        query string
+        args []any
+    }{
+        {`update payment_request set status = $1 where status = $2 and (requester_account = $3 or payer_account = $3)`,
+            []any{types.RequestCancelled, types.RequestPending, number}},
+        {`update owner_invitation set status = $1 where status = $2 and (account_number = $3 or invitee_number = $3)`,
+            []any{types.InvitationDeclined, types.InvitationPending, number}},
+        {`update access_grant set revoked_at = $1 where revoked_at is null and (account_number = $2 or grantee_number = $2)`,
+            []any{at, number}},
+        {`update card set status = $1 where account_number = $2`,
+            []any{types.CardBlocked, number}},
+        {`update account set closed_at = $1 where number = $2`,
+            []any{at, number}},
+    }
+    for _, st := range statements {
+        if _, err := dbtx.ExecContext(ctx, st.query, st.args...); err != nil {
+            return err
+        }
+    }
+
+    return dbtx.Commit()
+}
+
+// closureBlocker returns ErrClosureBlocked, saying why, when the account
+// has something that would outlive it.
+func closureBlocker(ctx context.Context, dbtx *sql.Tx, number int64, at time.Time) error {
+    checks := []struct {
+        reason string
+        query string
+        args []any
+    }{
+        {"a loan that isn't paid off",
+            `select count(*) from loan where account_number = $1 and status in ($2, $3)`,
+            []any{number, types.LoanApplied, types.LoanActive}},
+        {"card payments that aren't captured yet",
+            `select count(*) from card_hold where account_number = $1 and status = $2 and expires_at > $3`,
+            []any{number, types.HoldActive, at}},
+        {"open disputes",
+            `select count(*) from dispute where (account_number = $1 or held_account = $1) and status = $2`,
+            []any{number, types.DisputeOpen}},
+        // a transfer that comes back would credit the closed account
+        {"transfers to other banks that haven't settled",
+            `select count(*) from transaction where from_account = $1 and to_account = $2 and status = $3`,
+            []any{number, types.SettlementAccountNumber, types.StatusPending}},
+        // as would a claim that expires and is refunded
+        {"transfers to aliases that haven't been claimed",
+            `select count(*) from alias_claim c join transaction t on t.id = c.transaction_id where t.from_account = $1 and t.status = $2`,
+            []any{number, types.StatusPending}},
+    }
+    for _, c := range checks {
+        var n int
+        if err := dbtx.QueryRowContext(ctx, c.query, c.args...).Scan(&n); err != nil {
+            return err
+        }
+        if n > 0 {
+            return fmt.Errorf("account %d has %s: %w", number, c.reason, ErrClosureBlocked)
+        }
+    }
+
+    return nil
+}
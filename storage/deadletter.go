@@ -0,0 +1,145 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+var ErrAlreadyReplayed = errors.New("dead letter was already replayed")
+
+type DeadLetterStorage interface {
+    CreateDeadLetter(context.Context, *types.DeadLetter) error
+    GetDeadLetter(context.Context, int) (*types.DeadLetter, error)
+    GetDeadLettersByStatus(context.Context, string) ([]*types.DeadLetter, error)
+    // RecordDeadLetterFailure counts a failed replay and keeps its reason.
+    RecordDeadLetterFailure(ctx context.Context, d *types.DeadLetter, reason string) error
+    // MarkDeadLetterReplayed fails with ErrAlreadyReplayed unless the dead
+    // letter is still pending.
+    MarkDeadLetterReplayed(ctx context.Context, d *types.DeadLetter, at time.Time) error
+}
+
+const deadLetterColumns = `id, event, channel, account_number, recipient, subject, body, reason, attempts, status, created_at, replayed_at`
+
+func (s *PostgresStore) CreateDeadLetterTable() error {
+    queries := []string{
+        `create table if not exists dead_letter (
+            id serial primary key,
+            event varchar(64) not null,
+            channel varchar(16) not null,
+            account_number bigint not null,
+            recipient varchar(256) not null,
+            subject text not null default '',
+            body text not null,
+            reason text not null,
+            attempts integer not null,
+            status varchar(16) not null,
+            created_at timestamp not null,
+            replayed_at timestamp
+        )`,
+        `create index if not exists dead_letter_status_idx on dead_letter (status, created_at)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateDeadLetter(ctx context.Context, d *types.DeadLetter) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into dead_letter (event, channel, account_number, recipient, subject, body, reason, attempts, status, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        returning id
+    `, d.Event, d.Channel, d.AccountNumber, d.To, d.Subject, d.Body, d.Reason, d.Attempts, d.Status, d.CreatedAt).Scan(&d.ID)
+}
+
+func (s *PostgresStore) GetDeadLetter(ctx context.Context, id int) (*types.DeadLetter, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+deadLetterColumns+` from dead_letter where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    letters, err := scanDeadLetters(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(letters) == 0 {
+        return nil, fmt.Errorf("dead letter %d %w", id, ErrNotFound)
+    }
+
+    return letters[0], nil
+}
+
+func (s *PostgresStore) GetDeadLettersByStatus(ctx context.Context, status string) ([]*types.DeadLetter, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+deadLetterColumns+` from dead_letter where status = $1 order by created_at, id
+    `, status)
+    if err != nil {
+        return nil, err
+    }
+    return scanDeadLetters(rows)
+}
+
+func (s *PostgresStore) RecordDeadLetterFailure(ctx context.Context, d *types.DeadLetter, reason string) error {
+    err := s.db.QueryRowContext(ctx, `
+        update dead_letter set reason = $1, attempts = attempts + 1
+        where id = $2
+        returning attempts
+    `, reason, d.ID).Scan(&d.Attempts)
+    if err == sql.ErrNoRows {
+        return fmt.Errorf("dead letter %d %w", d.ID, ErrNotFound)
+    }
+    if err != nil {
+        return err
+    }
+    d.Reason = reason
+
+    return nil
+}
+
+func (s *PostgresStore) MarkDeadLetterReplayed(ctx context.Context, d *types.DeadLetter, at time.Time) error {
+    res, err := s.db.ExecContext(ctx, `
+        update dead_letter set status = $1, replayed_at = $2
+        where id = $3 and status = $4
+    `, types.DeadLetterReplayed, at, d.ID, types.DeadLetterPending)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("dead letter %d: %w", d.ID, ErrAlreadyReplayed)
+    }
+    d.Status = types.DeadLetterReplayed
+    d.ReplayedAt = &at
+
+    return nil
+}
+
+func scanDeadLetters(rows *sql.Rows) ([]*types.DeadLetter, error) {
+    defer rows.Close()
+
+    letters := []*types.DeadLetter{}
+    for rows.Next() {
+        d := new(types.DeadLetter)
+        var replayedAt sql.NullTime
+        if err := rows.Scan(&d.ID, &d.Event, &d.Channel, &d.AccountNumber, &d.To, &d.Subject, &d.Body, &d.Reason, &d.Attempts, &d.Status, &d.CreatedAt, &replayedAt); err != nil {
+            return nil, err
+        }
+        if replayedAt.Valid {
+            d.ReplayedAt = &replayedAt.Time
+        }
+        letters = append(letters, d)
+    }
+
+    return letters, rows.Err()
+}
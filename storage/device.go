@@ -0,0 +1,162 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+
+    "gobank/types"
+)
+
+type DeviceStorage interface {
+    // GetDevices returns the account's devices, most recently seen first.
+    GetDevices(context.Context, int64) ([]*types.Device, error)
+    // SaveDevice adds a device or updates the one with the same account
+    // and fingerprint, keeping its ID and FirstSeen.
+    SaveDevice(context.Context, *types.Device) error
+    DeleteDevice(ctx context.Context, number int64, id int) error
+    SaveStepUpChallenge(context.Context, *types.StepUpChallenge) error
+    GetStepUpChallenge(context.Context, string) (*types.StepUpChallenge, error)
+    DeleteStepUpChallenge(context.Context, string) error
+}
+
+func (s *PostgresStore) CreateDeviceTables() error {
+    queries := []string{
+        `create table if not exists device (
+            id serial primary key,
+            account_number bigint not null,
+            fingerprint varchar(64) not null,
+            user_agent text not null,
+            ip varchar(64) not null,
+            country varchar(8) not null,
+            first_seen timestamp not null,
+            last_seen timestamp not null,
+            unique (account_number, fingerprint)
+        )`,
+        `create table if not exists step_up_challenge (
+            id varchar(64) primary key,
+            account_number bigint not null,
+            code_hash varchar(64) not null,
+            attempts integer not null default 0,
+            expires_at timestamp not null
+        )`,
+        `alter table step_up_challenge add column if not exists device_fingerprint varchar(64)`,
+        `alter table step_up_challenge add column if not exists device_user_agent text`,
+        `alter table step_up_challenge add column if not exists device_ip varchar(64)`,
+        `alter table step_up_challenge add column if not exists device_country varchar(8)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) GetDevices(ctx context.Context, number int64) ([]*types.Device, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, account_number, fingerprint, user_agent, ip, country, first_seen, last_seen
+        from device where account_number = $1
+        order by last_seen desc
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    devices := []*types.Device{}
+    for rows.Next() {
+        d := new(types.Device)
+        if err := rows.Scan(&d.ID, &d.AccountNumber, &d.Fingerprint, &d.UserAgent, &d.IP, &d.Country, &d.FirstSeen, &d.LastSeen); err != nil {
+            return nil, err
+        }
+        devices = append(devices, d)
+    }
+
+    return devices, rows.Err()
+}
+
+func (s *PostgresStore) SaveDevice(ctx context.Context, d *types.Device) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into device (account_number, fingerprint, user_agent, ip, country, first_seen, last_seen)
+        values ($1, $2, $3, $4, $5, $6, $7)
+        on conflict (account_number, fingerprint) do update set
+            user_agent = excluded.user_agent,
+            ip = excluded.ip,
+            country = excluded.country,
+            last_seen = excluded.last_seen
+        returning id, first_seen
+    `, d.AccountNumber, d.Fingerprint, d.UserAgent, d.IP, d.Country, d.FirstSeen, d.LastSeen).Scan(&d.ID, &d.FirstSeen)
+}
+
+func (s *PostgresStore) DeleteDevice(ctx context.Context, number int64, id int) error {
+    res, err := s.db.ExecContext(ctx, `delete from device where id = $1 and account_number = $2`, id, number)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("device %d %w", id, ErrNotFound)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) SaveStepUpChallenge(ctx context.Context, c *types.StepUpChallenge) error {
+    var fingerprint, userAgent, ip, country sql.NullString
+    if d := c.Device; d != nil {
+        fingerprint = sql.NullString{String: d.Fingerprint, Valid: true}
+        userAgent = sql.NullString{String: d.UserAgent, Valid: true}
+        ip = sql.NullString{String: d.IP, Valid: true}
+        country = sql.NullString{String: d.Country, Valid: true}
+    }
+
+    _, err := s.db.ExecContext(ctx, `
+        insert into step_up_challenge
+        (id, account_number, code_hash, attempts, expires_at, device_fingerprint, device_user_agent, device_ip, device_country)
+        values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        on conflict (id) do update set
+            code_hash = excluded.code_hash,
+            attempts = excluded.attempts,
+            expires_at = excluded.expires_at
+    `, c.ID, c.AccountNumber, c.CodeHash, c.Attempts, c.ExpiresAt, fingerprint, userAgent, ip, country)
+
+    return err
+}
+
+func (s *PostgresStore) GetStepUpChallenge(ctx context.Context, id string) (*types.StepUpChallenge, error) {
+    c := &types.StepUpChallenge{ID: id}
+    var fingerprint, userAgent, ip, country sql.NullString
+    err := s.db.QueryRowContext(ctx, `
+        select account_number, code_hash, attempts, expires_at,
+            device_fingerprint, device_user_agent, device_ip, device_country
+        from step_up_challenge where id = $1
+    `, id).Scan(&c.AccountNumber, &c.CodeHash, &c.Attempts, &c.ExpiresAt, &fingerprint, &userAgent, &ip, &country)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("step-up challenge %s %w", id, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if fingerprint.Valid {
+        c.Device = &types.Device{
+            AccountNumber: c.AccountNumber,
+            Fingerprint: fingerprint.String,
+            UserAgent: userAgent.String,
+            IP: ip.String,
+            Country: country.String,
+        }
+    }
+
+    return c, nil
+}
+
+func (s *PostgresStore) DeleteStepUpChallenge(ctx context.Context, id string) error {
+    _, err := s.db.ExecContext(ctx, `delete from step_up_challenge where id = $1`, id)
+    return err
+}
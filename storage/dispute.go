@@ -0,0 +1,281 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/lib/pq"
+    "gobank/types"
+)
+
+var (
+    ErrAlreadyDisputed = errors.New("transaction is already disputed")
+    ErrDisputeClosed = errors.New("dispute is no longer open")
+)
+
+type DisputeStorage interface {
+    // CreateDispute fails with ErrAlreadyDisputed when the transaction has
+    // a dispute that wasn't denied, and with ErrAlreadyReversed when it was
+    // reversed.
+    CreateDispute(context.Context, *types.Dispute) error
+    GetDispute(context.Context, int) (*types.Dispute, error)
+    GetDisputesByAccount(context.Context, int64) ([]*types.Dispute, error)
+    GetDisputesByStatus(context.Context, string) ([]*types.Dispute, error)
+    // AcceptDispute and DenyDispute fail with ErrDisputeClosed unless the
+    // dispute is still open. Accepting posts the reversal and closes the
+    // dispute in one database transaction, releasing the hold first so
+    // the reversal can take the held money. It fails with
+    // ErrAlreadyReversed when the transfer was reversed by hand.
+    AcceptDispute(ctx context.Context, d *types.Dispute, reversal *types.Transaction, entries []*types.LedgerEntry) error
+    DenyDispute(ctx context.Context, d *types.Dispute, at time.Time) error
+}
+
+const disputeColumns = `id, transaction_id, account_number, held_account, held_amount, reason, description, evidence, documents, status, resolution, reversal_id, created_at, resolved_at`
+
+func (s *PostgresStore) CreateDisputeTable() error {
+    queries := []string{
+        `create table if not exists dispute (
+            id serial primary key,
+            transaction_id integer not null,
+            account_number bigint not null,
+            held_account bigint not null,
+            held_amount bigint not null,
+            reason varchar(32) not null,
+            description text not null default '',
+            evidence jsonb not null default '[]',
+            status varchar(16) not null,
+            resolution text not null default '',
+            reversal_id integer,
+            created_at timestamp not null,
+            resolved_at timestamp
+        )`,
+        `alter table dispute add column if not exists documents jsonb not null default '[]'`,
+        `create unique index if not exists dispute_transaction_idx on dispute (transaction_id) where status <> '` + types.DisputeDenied + `'`,
+        `create index if not exists dispute_account_idx on dispute (account_number, created_at)`,
+        `create index if not exists dispute_held_idx on dispute (held_account) where status = '` + types.DisputeOpen + `'`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateDispute(ctx context.Context, d *types.Dispute) error {
+    evidence, err := json.Marshal(d.Evidence)
+    if err != nil {
+        return err
+    }
+    documents, err := json.Marshal(d.Documents)
+    if err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := lockTransaction(ctx, dbtx, d.TransactionID); err != nil {
+        return err
+    }
+
+    var reversed bool
+    err = dbtx.QueryRowContext(ctx, `
+        select exists (select 1 from transaction_reversal where transaction_id = $1)
+    `, d.TransactionID).Scan(&reversed)
+    if err != nil {
+        return err
+    }
+    if reversed {
+        return fmt.Errorf("transaction %d: %w", d.TransactionID, ErrAlreadyReversed)
+    }
+
+    err = dbtx.QueryRowContext(ctx, `
+        insert into dispute
+        (transaction_id, account_number, held_account, held_amount, reason, description, evidence, documents, status, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        returning id
+    `, d.TransactionID, d.AccountNumber, d.HeldAccount, d.HeldAmount, d.Reason, d.Description, evidence, documents, d.Status, d.CreatedAt).Scan(&d.ID)
+
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+        return fmt.Errorf("transaction %d: %w", d.TransactionID, ErrAlreadyDisputed)
+    }
+    if err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) GetDispute(ctx context.Context, id int) (*types.Dispute, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+disputeColumns+` from dispute where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    disputes, err := scanDisputes(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(disputes) == 0 {
+        return nil, fmt.Errorf("dispute %d %w", id, ErrNotFound)
+    }
+
+    return disputes[0], nil
+}
+
+func (s *PostgresStore) GetDisputesByAccount(ctx context.Context, number int64) ([]*types.Dispute, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+disputeColumns+` from dispute where account_number = $1 order by created_at, id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanDisputes(rows)
+}
+
+func (s *PostgresStore) GetDisputesByStatus(ctx context.Context, status string) ([]*types.Dispute, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+disputeColumns+` from dispute where status = $1 order by created_at, id
+    `, status)
+    if err != nil {
+        return nil, err
+    }
+    return scanDisputes(rows)
+}
+
+func (s *PostgresStore) AcceptDispute(ctx context.Context, d *types.Dispute, t *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := closeDispute(ctx, dbtx, d.ID, types.DisputeAccepted, d.Resolution, t.CreatedAt); err != nil {
+        return err
+    }
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+    if _, err := dbtx.ExecContext(ctx, `update dispute set reversal_id = $1 where id = $2`, t.ID, d.ID); err != nil {
+        return err
+    }
+    err = linkReversal(ctx, dbtx, &types.Reversal{
+        TransactionID: d.TransactionID,
+        ReversalID: t.ID,
+        Reason: fmt.Sprintf("dispute %d accepted", d.ID),
+        CreatedAt: t.CreatedAt,
+    })
+    if err != nil {
+        return err
+    }
+
+    if err := dbtx.Commit(); err != nil {
+        return err
+    }
+    d.Status = types.DisputeAccepted
+    d.ReversalID = &t.ID
+    d.ResolvedAt = &t.CreatedAt
+
+    return nil
+}
+
+func (s *PostgresStore) DenyDispute(ctx context.Context, d *types.Dispute, at time.Time) error {
+    if err := closeDispute(ctx, s.db, d.ID, types.DisputeDenied, d.Resolution, at); err != nil {
+        return err
+    }
+    d.Status = types.DisputeDenied
+    d.ResolvedAt = &at
+
+    return nil
+}
+
+// closeDispute runs on the database or inside a database transaction.
+func closeDispute(ctx context.Context, db interface {
+    ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, id int, status, resolution string, at time.Time) error {
+    res, err := db.ExecContext(ctx, `
+        update dispute set status = $1, resolution = $2, resolved_at = $3
+        where id = $4 and status = $5
+    `, status, resolution, at, id, types.DisputeOpen)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("dispute %d: %w", id, ErrDisputeClosed)
+    }
+
+    return nil
+}
+
+// disputedTotal is how much of an account's balance open disputes hold.
+func disputedTotal(ctx context.Context, dbtx *sql.Tx, number int64) (int64, error) {
+    var total int64
+    err := dbtx.QueryRowContext(ctx, `
+        select coalesce(sum(held_amount), 0) from dispute where held_account = $1 and status = $2
+    `, number, types.DisputeOpen).Scan(&total)
+    return total, err
+}
+
+func scanDisputes(rows *sql.Rows) ([]*types.Dispute, error) {
+    defer rows.Close()
+
+    disputes := []*types.Dispute{}
+    for rows.Next() {
+        d := new(types.Dispute)
+        var evidence, documents []byte
+        var reversalID sql.NullInt64
+        var resolvedAt sql.NullTime
+        if err := rows.Scan(
+            &d.ID,
+            &d.TransactionID,
+            &d.AccountNumber,
+            &d.HeldAccount,
+            &d.HeldAmount,
+            &d.Reason,
+            &d.Description,
+            &evidence,
+            &documents,
+            &d.Status,
+            &d.Resolution,
+            &reversalID,
+            &d.CreatedAt,
+            &resolvedAt,
+        ); err != nil {
+            return nil, err
+        }
+        if err := json.Unmarshal(evidence, &d.Evidence); err != nil {
+            return nil, err
+        }
+        if err := json.Unmarshal(documents, &d.Documents); err != nil {
+            return nil, err
+        }
+        if reversalID.Valid {
+            id := int(reversalID.Int64)
+            d.ReversalID = &id
+        }
+        if resolvedAt.Valid {
+            d.ResolvedAt = &resolvedAt.Time
+        }
+        disputes = append(disputes, d)
+    }
+
+    return disputes, rows.Err()
+}
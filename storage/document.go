@@ -0,0 +1,106 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+
+    "gobank/types"
+)
+
+type DocumentStorage interface {
+    CreateDocument(context.Context, *types.Document) error
+    GetDocument(context.Context, int) (*types.Document, error)
+    // GetDocumentsByAccount returns the account's documents for purpose,
+    // or all of them when purpose is empty.
+    GetDocumentsByAccount(ctx context.Context, number int64, purpose string) ([]*types.Document, error)
+}
+
+const documentColumns = `id, account_number, purpose, filename, content_type, size, sha256, blob_key, created_at`
+
+func (s *PostgresStore) CreateDocumentTable() error {
+    queries := []string{
+        `create table if not exists document (
+            id serial primary key,
+            account_number bigint not null,
+            purpose varchar(32) not null,
+            filename varchar(255) not null default '',
+            content_type varchar(64) not null,
+            size bigint not null,
+            sha256 varchar(64) not null,
+            blob_key varchar(255) not null unique,
+            created_at timestamp not null
+        )`,
+        `create index if not exists document_account_idx on document (account_number, created_at)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateDocument(ctx context.Context, d *types.Document) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into document
+        (account_number, purpose, filename, content_type, size, sha256, blob_key, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8)
+        returning id
+    `, d.AccountNumber, d.Purpose, d.Filename, d.ContentType, d.Size, d.SHA256, d.BlobKey, d.CreatedAt).Scan(&d.ID)
+}
+
+func (s *PostgresStore) GetDocument(ctx context.Context, id int) (*types.Document, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+documentColumns+` from document where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    documents, err := scanDocuments(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(documents) == 0 {
+        return nil, fmt.Errorf("document %d %w", id, ErrNotFound)
+    }
+
+    return documents[0], nil
+}
+
+func (s *PostgresStore) GetDocumentsByAccount(ctx context.Context, number int64, purpose string) ([]*types.Document, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+documentColumns+` from document
+        where account_number = $1 and ($2 = '' or purpose = $2)
+        order by created_at, id
+    `, number, purpose)
+    if err != nil {
+        return nil, err
+    }
+    return scanDocuments(rows)
+}
+
+func scanDocuments(rows *sql.Rows) ([]*types.Document, error) {
+    defer rows.Close()
+
+    documents := []*types.Document{}
+    for rows.Next() {
+        d := new(types.Document)
+        if err := rows.Scan(
+            &d.ID,
+            &d.AccountNumber,
+            &d.Purpose,
+            &d.Filename,
+            &d.ContentType,
+            &d.Size,
+            &d.SHA256,
+            &d.BlobKey,
+            &d.CreatedAt,
+        ); err != nil {
+            return nil, err
+        }
+        documents = append(documents, d)
+    }
+
+    return documents, rows.Err()
+}
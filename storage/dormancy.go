@@ -0,0 +1,61 @@
+package storage
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+type DormancyStorage interface {
+    // GetLastActivity returns when each account last sent a transfer or
+    // signed in, or when it was opened if it has done neither.
+    GetLastActivity(context.Context) (map[int64]time.Time, error)
+    // SetDormancy saves when the account's holder was warned and when it
+    // became dormant. A nil time clears it.
+    SetDormancy(ctx context.Context, number int64, noticeAt, dormantSince *time.Time) error
+}
+
+func (s *PostgresStore) GetLastActivity(ctx context.Context) (map[int64]time.Time, error) {
+    // greatest skips the nulls of accounts without transfers or devices
+    rows, err := s.db.QueryContext(ctx, `
+        select a.number, greatest(
+            a.created_at,
+            (select max(t.created_at) from `+allTransactions+` t where t.from_account = a.number and t.kind = $1),
+            (select max(d.last_seen) from device d where d.account_number = a.number)
+        )
+        from account a
+    `, types.TransactionTransfer)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    activity := map[int64]time.Time{}
+    for rows.Next() {
+        var number int64
+        var last time.Time
+        if err := rows.Scan(&number, &last); err != nil {
+            return nil, err
+        }
+        activity[number] = last
+    }
+
+    return activity, rows.Err()
+}
+
+func (s *PostgresStore) SetDormancy(ctx context.Context, number int64, noticeAt, dormantSince *time.Time) error {
+    res, err := s.db.ExecContext(ctx, `
+        update account set dormancy_notice_at = $1, dormant_since = $2 where number = $3
+    `, noticeAt, dormantSince, number)
+    if err != nil {
+        return err
+    }
+
+    if n, _ := res.RowsAffected(); n == 0 {
+        return fmt.Errorf("account %d %w", number, ErrNotFound)
+    }
+
+    return nil
+}
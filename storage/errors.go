@@ -0,0 +1,48 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "net"
+
+    "github.com/lib/pq"
+)
+
+var (
+    ErrNotFound = errors.New("not found")
+    ErrUnavailable = errors.New("storage unavailable")
+)
+
+// IsUnavailable reports whether err means the backend itself is failing
+// (timeouts, dropped connections, an overloaded or restarting server) as
+// opposed to a query that legitimately failed, like a missing row.
+func IsUnavailable(err error) bool {
+    if err == nil {
+        return false
+    }
+
+    if errors.Is(err, ErrUnavailable) ||
+        errors.Is(err, context.DeadlineExceeded) ||
+        errors.Is(err, driver.ErrBadConn) ||
+        errors.Is(err, sql.ErrConnDone) {
+        return true
+    }
+
+    var netErr net.Error
+    if errors.As(err, &netErr) {
+        return true
+    }
+
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) {
+        switch pqErr.Code.Class() {
+        // connection exception, insufficient resources, operator intervention
+        case "08", "53", "57":
+            return true
+        }
+    }
+
+    return false
+}
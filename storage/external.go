@@ -0,0 +1,190 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+
+    "gobank/types"
+)
+
+type ExternalTransferStorage interface {
+    // CreateExternalTransfer posts tx, the pending transfer into the
+    // settlement account, like PostTransfer and records where it goes in
+    // the same database transaction. et takes the ID of tx.
+    CreateExternalTransfer(ctx context.Context, et *types.ExternalTransfer, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error
+    GetExternalTransfer(context.Context, int) (*types.ExternalTransfer, error)
+    GetExternalTransfersByAccount(context.Context, int64) ([]*types.ExternalTransfer, error)
+    // GetPendingExternalTransfers returns the transfers that are still
+    // clearing, oldest first.
+    GetPendingExternalTransfers(context.Context) ([]*types.ExternalTransfer, error)
+    // ReturnExternalTransfer fails a pending transfer with reason and posts
+    // reversal like SettleTransaction, failing with ErrNotPending if it was
+    // already settled.
+    ReturnExternalTransfer(ctx context.Context, id int, reason string, reversal *types.Transaction, entries []*types.LedgerEntry) error
+}
+
+// The status, amount and reference of an external transfer are those of
+// its transaction. There is no foreign key, settled transactions move to
+// the archive.
+const externalTransferSelect = `
+    select e.transaction_id, t.from_account, e.routing_number, e.to_account, e.name, t.amount, t.status, t.provider, t.reference, e.return_reason, t.created_at
+    from external_transfer e join ` + allTransactions + ` t on t.id = e.transaction_id`
+
+func (s *PostgresStore) CreateExternalTransferTable() error {
+    queries := []string{
+        `create table if not exists external_transfer (
+            transaction_id integer primary key,
+            routing_number varchar(9) not null,
+            to_account varchar(17) not null,
+            name varchar(70) not null,
+            return_reason text not null default ''
+        )`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateExternalTransfer(ctx context.Context, et *types.ExternalTransfer, t *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+    if err := checkVelocity(ctx, dbtx, t, limits); err != nil {
+        return err
+    }
+    if err := insertExternalTransfer(ctx, dbtx, et, t); err != nil {
+        return err
+    }
+
+    if err := dbtx.Commit(); err != nil {
+        return err
+    }
+    fillExternalTransfer(et, t)
+
+    return nil
+}
+
+// insertExternalTransfer records where t, already posted in dbtx, goes.
+func insertExternalTransfer(ctx context.Context, dbtx *sql.Tx, et *types.ExternalTransfer, t *types.Transaction) error {
+    _, err := dbtx.ExecContext(ctx, `
+        insert into external_transfer (transaction_id, routing_number, to_account, name)
+        values ($1, $2, $3, $4)
+    `, t.ID, et.RoutingNumber, et.ToAccount, et.Name)
+    return err
+}
+
+// fillExternalTransfer copies the fields et shares with its transaction.
+func fillExternalTransfer(et *types.ExternalTransfer, t *types.Transaction) {
+    et.ID = t.ID
+    et.FromAccount = t.FromAccount
+    et.Amount = t.Amount
+    et.Status = t.Status
+    et.Network = t.Provider
+    et.Reference = t.Reference
+    et.CreatedAt = t.CreatedAt
+}
+
+func (s *PostgresStore) GetExternalTransfer(ctx context.Context, id int) (*types.ExternalTransfer, error) {
+    rows, err := s.db.QueryContext(ctx, externalTransferSelect+` where e.transaction_id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    transfers, err := scanExternalTransfers(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(transfers) == 0 {
+        return nil, fmt.Errorf("external transfer %d %w", id, ErrNotFound)
+    }
+
+    return transfers[0], nil
+}
+
+func (s *PostgresStore) GetExternalTransfersByAccount(ctx context.Context, number int64) ([]*types.ExternalTransfer, error) {
+    rows, err := s.db.QueryContext(ctx, externalTransferSelect+`
+        where t.from_account = $1 order by t.created_at, t.id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanExternalTransfers(rows)
+}
+
+func (s *PostgresStore) GetPendingExternalTransfers(ctx context.Context) ([]*types.ExternalTransfer, error) {
+    rows, err := s.db.QueryContext(ctx, externalTransferSelect+`
+        where t.status = $1 order by t.created_at, t.id
+    `, types.StatusPending)
+    if err != nil {
+        return nil, err
+    }
+    return scanExternalTransfers(rows)
+}
+
+func (s *PostgresStore) ReturnExternalTransfer(ctx context.Context, id int, reason string, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := settleTransaction(ctx, dbtx, id, types.StatusFailed, reversal, entries); err != nil {
+        return err
+    }
+    if _, err := dbtx.ExecContext(ctx, `
+        update external_transfer set return_reason = $1 where transaction_id = $2
+    `, reason, id); err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func scanExternalTransfers(rows *sql.Rows) ([]*types.ExternalTransfer, error) {
+    defer rows.Close()
+
+    transfers := []*types.ExternalTransfer{}
+    for rows.Next() {
+        et := new(types.ExternalTransfer)
+        var network, reference sql.NullString
+        if err := rows.Scan(
+            &et.ID,
+            &et.FromAccount,
+            &et.RoutingNumber,
+            &et.ToAccount,
+            &et.Name,
+            &et.Amount,
+            &et.Status,
+            &network,
+            &reference,
+            &et.ReturnReason,
+            &et.CreatedAt,
+        ); err != nil {
+            return nil, err
+        }
+        et.Network = network.String
+        et.Reference = reference.String
+        transfers = append(transfers, et)
+    }
+
+    return transfers, rows.Err()
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+var ErrCaseClosed = errors.New("fraud case is no longer pending")
+
+type FraudStorage interface {
+    CreateFraudCase(context.Context, *types.FraudCase) error
+    GetFraudCase(context.Context, int) (*types.FraudCase, error)
+    GetFraudCasesByStatus(context.Context, string) ([]*types.FraudCase, error)
+    // ApproveFraudCase and RejectFraudCase fail with ErrCaseClosed unless
+    // the case is still pending. Approving posts the held transfer and
+    // closes the case in one database transaction, recording et like
+    // CreateExternalTransfer for a transfer out of the bank.
+    ApproveFraudCase(ctx context.Context, c *types.FraudCase, tx *types.Transaction, entries []*types.LedgerEntry, et *types.ExternalTransfer) error
+    RejectFraudCase(ctx context.Context, c *types.FraudCase, at time.Time) error
+    // GetFraudHistory looks up what the fraud rules know of from: whether
+    // it paid to, or the account at another bank named by external when to
+    // is zero, and what it sent after since.
+    GetFraudHistory(ctx context.Context, from, to int64, external *types.ExternalTransferRequest, since time.Time) (*types.FraudHistory, error)
+}
+
+const fraudCaseColumns = `id, from_account, to_account, external, amount, decision, reasons, status, transaction_id, created_at, decided_at`
+
+func (s *PostgresStore) CreateFraudCaseTable() error {
+    queries := []string{
+        `create table if not exists fraud_case (
+            id serial primary key,
+            from_account bigint not null,
+            to_account bigint not null,
+            amount bigint not null,
+            decision varchar(16) not null,
+            reasons jsonb not null,
+            status varchar(16) not null,
+            transaction_id integer,
+            created_at timestamp not null,
+            decided_at timestamp
+        )`,
+        `create index if not exists fraud_case_status_idx on fraud_case (status, created_at)`,
+        `alter table fraud_case add column if not exists external jsonb`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateFraudCase(ctx context.Context, c *types.FraudCase) error {
+    reasons, err := json.Marshal(c.Reasons)
+    if err != nil {
+        return err
+    }
+    var external []byte
+    if c.External != nil {
+        if external, err = json.Marshal(c.External); err != nil {
+            return err
+        }
+    }
+
+    return s.db.QueryRowContext(ctx, `
+        insert into fraud_case (from_account, to_account, external, amount, decision, reasons, status, transaction_id, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        returning id
+    `, c.FromAccount, c.ToAccount, external, c.Amount, c.Decision, reasons, c.Status, c.TransactionID, c.CreatedAt).Scan(&c.ID)
+}
+
+func (s *PostgresStore) GetFraudCase(ctx context.Context, id int) (*types.FraudCase, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+fraudCaseColumns+` from fraud_case where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    cases, err := scanFraudCases(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(cases) == 0 {
+        return nil, fmt.Errorf("fraud case %d %w", id, ErrNotFound)
+    }
+
+    return cases[0], nil
+}
+
+func (s *PostgresStore) GetFraudCasesByStatus(ctx context.Context, status string) ([]*types.FraudCase, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+fraudCaseColumns+` from fraud_case where status = $1 order by created_at, id
+    `, status)
+    if err != nil {
+        return nil, err
+    }
+    return scanFraudCases(rows)
+}
+
+func (s *PostgresStore) ApproveFraudCase(ctx context.Context, c *types.FraudCase, t *types.Transaction, entries []*types.LedgerEntry, et *types.ExternalTransfer) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+    if et != nil {
+        if err := insertExternalTransfer(ctx, dbtx, et, t); err != nil {
+            return err
+        }
+    }
+    if err := closeFraudCase(ctx, dbtx, c.ID, types.FraudCaseApproved, &t.ID, t.CreatedAt); err != nil {
+        return err
+    }
+
+    if err := dbtx.Commit(); err != nil {
+        return err
+    }
+    if et != nil {
+        fillExternalTransfer(et, t)
+    }
+    c.Status = types.FraudCaseApproved
+    c.TransactionID = &t.ID
+    c.DecidedAt = &t.CreatedAt
+
+    return nil
+}
+
+func (s *PostgresStore) RejectFraudCase(ctx context.Context, c *types.FraudCase, at time.Time) error {
+    if err := closeFraudCase(ctx, s.db, c.ID, types.FraudCaseRejected, nil, at); err != nil {
+        return err
+    }
+    c.Status = types.FraudCaseRejected
+    c.DecidedAt = &at
+
+    return nil
+}
+
+func (s *PostgresStore) GetFraudHistory(ctx context.Context, from, to int64, external *types.ExternalTransferRequest, since time.Time) (*types.FraudHistory, error) {
+    h := new(types.FraudHistory)
+
+    var err error
+    switch {
+    case external != nil:
+        err = s.db.QueryRowContext(ctx, `
+            select exists (
+                select 1 from external_transfer e join `+allTransactions+` t on t.id = e.transaction_id
+                where t.from_account = $1 and e.routing_number = $2 and e.to_account = $3 and t.status <> $4
+            )
+        `, from, external.RoutingNumber, external.ToAccount, types.StatusFailed).Scan(&h.PaidBefore)
+    case to != 0:
+        err = s.db.QueryRowContext(ctx, `
+            select exists (select 1 from `+allTransactions+` where from_account = $1 and to_account = $2)
+        `, from, to).Scan(&h.PaidBefore)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var last sql.NullTime
+    err = s.db.QueryRowContext(ctx, `
+        select max(created_at) from `+allTransactions+` where from_account = $1 or to_account = $1
+    `, from).Scan(&last)
+    if err != nil {
+        return nil, err
+    }
+    h.LastActivity = last.Time
+
+    // Like checkVelocity, recent transfers are never archived yet.
+    err = s.db.QueryRowContext(ctx, `
+        select coalesce(sum(amount), 0) from transaction
+        where from_account = $1 and kind in ($2, $3) and status <> $4 and created_at > $5
+    `, from, types.TransactionTransfer, types.TransactionClosure, types.StatusFailed, since).Scan(&h.SentSince)
+    if err != nil {
+        return nil, err
+    }
+
+    return h, nil
+}
+
+// closeFraudCase runs on the database or inside a database transaction.
+func closeFraudCase(ctx context.Context, db interface {
+    ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, id int, status string, txID *int, at time.Time) error {
+    res, err := db.ExecContext(ctx, `
+        update fraud_case set status = $1, transaction_id = $2, decided_at = $3
+        where id = $4 and status = $5
+    `, status, txID, at, id, types.FraudCasePending)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("fraud case %d: %w", id, ErrCaseClosed)
+    }
+
+    return nil
+}
+
+func scanFraudCases(rows *sql.Rows) ([]*types.FraudCase, error) {
+    defer rows.Close()
+
+    cases := []*types.FraudCase{}
+    for rows.Next() {
+        c := new(types.FraudCase)
+        var reasons, external []byte
+        var txID sql.NullInt64
+        var decidedAt sql.NullTime
+        if err := rows.Scan(&c.ID, &c.FromAccount, &c.ToAccount, &external, &c.Amount, &c.Decision, &reasons, &c.Status, &txID, &c.CreatedAt, &decidedAt); err != nil {
+            return nil, err
+        }
+        if err := json.Unmarshal(reasons, &c.Reasons); err != nil {
+            return nil, err
+        }
+        if external != nil {
+            c.External = new(types.ExternalTransferRequest)
+            if err := json.Unmarshal(external, c.External); err != nil {
+                return nil, err
+            }
+        }
+        if txID.Valid {
+            id := int(txID.Int64)
+            c.TransactionID = &id
+        }
+        if decidedAt.Valid {
+            c.DecidedAt = &decidedAt.Time
+        }
+        cases = append(cases, c)
+    }
+
+    return cases, rows.Err()
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "strings"
+    "time"
+
+    "gobank/types"
+)
+
+type GrantStorage interface {
+    // CreateGrant revokes any grant the grantee already has on the
+    // account, so there is at most one active grant per pair.
+    CreateGrant(context.Context, *types.Grant) error
+    GetGrant(context.Context, int) (*types.Grant, error)
+    GetGrantsByAccount(context.Context, int64) ([]*types.Grant, error)
+    GetActiveGrant(ctx context.Context, account, grantee int64) (*types.Grant, error)
+    RevokeGrant(ctx context.Context, id int, t time.Time) error
+}
+
+const grantColumns = `id, account_number, grantee_number, scopes, transfer_limit, created_at, revoked_at`
+
+func (s *PostgresStore) CreateGrantTable() error {
+    query := `create table if not exists access_grant (
+        id serial primary key,
+        account_number bigint not null,
+        grantee_number bigint not null,
+        scopes text not null,
+        transfer_limit bigint not null default 0,
+        created_at timestamp not null,
+        revoked_at timestamp
+    )`
+
+    if _, err := s.db.Exec(query); err != nil {
+        return err
+    }
+
+    _, err := s.db.Exec(`create index if not exists access_grant_pair_idx on access_grant (account_number, grantee_number)`)
+    return err
+}
+
+func (s *PostgresStore) CreateGrant(ctx context.Context, g *types.Grant) error {
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    _, err = dbtx.ExecContext(ctx, `
+        update access_grant set revoked_at = $1
+        where account_number = $2 and grantee_number = $3 and revoked_at is null
+    `, g.CreatedAt, g.AccountNumber, g.GranteeNumber)
+    if err != nil {
+        return err
+    }
+
+    err = dbtx.QueryRowContext(ctx, `
+        insert into access_grant (account_number, grantee_number, scopes, transfer_limit, created_at)
+        values ($1, $2, $3, $4, $5)
+        returning id
+    `, g.AccountNumber, g.GranteeNumber, strings.Join(g.Scopes, ","), g.TransferLimit, g.CreatedAt).Scan(&g.ID)
+    if err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) GetGrant(ctx context.Context, id int) (*types.Grant, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+grantColumns+` from access_grant where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    grants, err := scanGrants(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(grants) == 0 {
+        return nil, fmt.Errorf("grant %d %w", id, ErrNotFound)
+    }
+
+    return grants[0], nil
+}
+
+func (s *PostgresStore) GetGrantsByAccount(ctx context.Context, number int64) ([]*types.Grant, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+grantColumns+` from access_grant where account_number = $1 order by id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanGrants(rows)
+}
+
+func (s *PostgresStore) GetActiveGrant(ctx context.Context, account, grantee int64) (*types.Grant, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+grantColumns+` from access_grant
+        where account_number = $1 and grantee_number = $2 and revoked_at is null
+    `, account, grantee)
+    if err != nil {
+        return nil, err
+    }
+
+    grants, err := scanGrants(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(grants) == 0 {
+        return nil, fmt.Errorf("grant for %d on account %d %w", grantee, account, ErrNotFound)
+    }
+
+    return grants[0], nil
+}
+
+func (s *PostgresStore) RevokeGrant(ctx context.Context, id int, t time.Time) error {
+    _, err := s.db.ExecContext(ctx, `
+        update access_grant set revoked_at = $1 where id = $2 and revoked_at is null
+    `, t, id)
+    return err
+}
+
+func scanGrants(rows *sql.Rows) ([]*types.Grant, error) {
+    defer rows.Close()
+
+    grants := []*types.Grant{}
+    for rows.Next() {
+        g := new(types.Grant)
+        var scopes string
+        var revokedAt sql.NullTime
+        if err := rows.Scan(&g.ID, &g.AccountNumber, &g.GranteeNumber, &scopes, &g.TransferLimit, &g.CreatedAt, &revokedAt); err != nil {
+            return nil, err
+        }
+        g.Scopes = strings.Split(scopes, ",")
+        if revokedAt.Valid {
+            g.RevokedAt = &revokedAt.Time
+        }
+        grants = append(grants, g)
+    }
+
+    return grants, rows.Err()
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+type ImportStorage interface {
+    // ImportAccounts creates the accounts with their opening balances and
+    // activations. Either all of them are imported or none are.
+    ImportAccounts(context.Context, []*types.AccountImport) error
+    // ActivateAccount sets the password of an imported account and uses up
+    // its activation. It fails with ErrNotFound unless the account has an
+    // activation with tokenHash that is still valid at now.
+    ActivateAccount(ctx context.Context, number int64, tokenHash, encryptedPassword string, now time.Time) error
+}
+
+func (s *PostgresStore) CreateActivationTable() error {
+    _, err := s.db.Exec(`create table if not exists account_activation (
+        account_number bigint primary key,
+        token_hash varchar(64) not null,
+        expires_at timestamp not null
+    )`)
+    return err
+}
+
+func (s *PostgresStore) ImportAccounts(ctx context.Context, imports []*types.AccountImport) error {
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    for _, imp := range imports {
+        if err := importAccount(ctx, dbtx, imp); err != nil {
+            return fmt.Errorf("account %d: %w", imp.Account.Number, err)
+        }
+    }
+
+    return dbtx.Commit()
+}
+
+func importAccount(ctx context.Context, dbtx *sql.Tx, imp *types.AccountImport) error {
+    acc := imp.Account
+    metadata, err := marshalMetadata(acc.Metadata)
+    if err != nil {
+        return err
+    }
+
+    err = dbtx.QueryRowContext(ctx, `
+        insert into account
+        (first_name, last_name, number, balance, encrypted_password, created_at, email, currency, metadata, locale)
+        values ($1, $2, $3, 0, $4, $5, $6, $7, $8, $9)
+        returning id
+    `, acc.FirstName, acc.LastName, acc.Number, acc.EncryptedPassword, acc.CreatedAt, acc.Email, acc.Currency, metadata, acc.Locale).Scan(&acc.ID)
+    if err != nil {
+        return numberTaken(err)
+    }
+
+    if imp.Balance > 0 {
+        tx := &types.Transaction{
+            Kind: types.TransactionOpening,
+            FromAccount: types.SuspenseAccountNumber,
+            ToAccount: acc.Number,
+            Amount: imp.Balance,
+            CreatedAt: acc.CreatedAt,
+        }
+        if err := postTransaction(ctx, dbtx, tx, types.NewEntries(types.SuspenseAccountNumber, acc.Number, imp.Balance)); err != nil {
+            return err
+        }
+        acc.Balance = imp.Balance
+    }
+
+    if a := imp.Activation; a != nil {
+        _, err := dbtx.ExecContext(ctx, `
+            insert into account_activation (account_number, token_hash, expires_at)
+            values ($1, $2, $3)
+            on conflict (account_number) do update set token_hash = $2, expires_at = $3
+        `, a.AccountNumber, a.TokenHash, a.ExpiresAt)
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) ActivateAccount(ctx context.Context, number int64, tokenHash, encryptedPassword string, now time.Time) error {
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    res, err := dbtx.ExecContext(ctx, `
+        delete from account_activation
+        where account_number = $1 and token_hash = $2 and expires_at > $3
+    `, number, tokenHash, now)
+    if err != nil {
+        return err
+    }
+    if n, _ := res.RowsAffected(); n == 0 {
+        return fmt.Errorf("activation for account %d %w", number, ErrNotFound)
+    }
+
+    if _, err := dbtx.ExecContext(ctx, `
+        update account set encrypted_password = $1 where number = $2
+    `, encryptedPassword, number); err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
@@ -0,0 +1,1196 @@
+package storage
+
+import (
+    "context"
+    "time"
+
+    "gobank/types"
+)
+
+// Interceptor runs around every call made through a store returned by
+// Intercept. op is the Storage method name and call performs the real call
+// with the given context.
+type Interceptor func(ctx context.Context, op string, call func(context.Context) error) error
+
+// Intercept decorates s so that every Storage method goes through i. It is
+// the extension point for cross cutting behaviour like circuit breaking.
+func Intercept(s Storage, i Interceptor) Storage {
+    return &interceptedStore{next: s, intercept: i}
+}
+
+type interceptedStore struct {
+    next Storage
+    intercept Interceptor
+}
+
+func (s *interceptedStore) CreateAccount(ctx context.Context, acc *types.Account) error {
+    return s.intercept(ctx, "CreateAccount", func(ctx context.Context) error {
+        return s.next.CreateAccount(ctx, acc)
+    })
+}
+
+func (s *interceptedStore) DeleteAccount(ctx context.Context, id int) error {
+    return s.intercept(ctx, "DeleteAccount", func(ctx context.Context) error {
+        return s.next.DeleteAccount(ctx, id)
+    })
+}
+
+func (s *interceptedStore) UpdateAccount(ctx context.Context, acc *types.Account) error {
+    return s.intercept(ctx, "UpdateAccount", func(ctx context.Context) error {
+        return s.next.UpdateAccount(ctx, acc)
+    })
+}
+
+func (s *interceptedStore) GetAccounts(ctx context.Context) (accounts []*types.Account, err error) {
+    err = s.intercept(ctx, "GetAccounts", func(ctx context.Context) error {
+        accounts, err = s.next.GetAccounts(ctx)
+        return err
+    })
+    return accounts, err
+}
+
+func (s *interceptedStore) GetAccountByID(ctx context.Context, id int) (acc *types.Account, err error) {
+    err = s.intercept(ctx, "GetAccountByID", func(ctx context.Context) error {
+        acc, err = s.next.GetAccountByID(ctx, id)
+        return err
+    })
+    return acc, err
+}
+
+func (s *interceptedStore) GetAccountByNumber(ctx context.Context, number int64) (acc *types.Account, err error) {
+    err = s.intercept(ctx, "GetAccountByNumber", func(ctx context.Context) error {
+        acc, err = s.next.GetAccountByNumber(ctx, number)
+        return err
+    })
+    return acc, err
+}
+
+func (s *interceptedStore) CreateTransaction(ctx context.Context, tx *types.Transaction) error {
+    return s.intercept(ctx, "CreateTransaction", func(ctx context.Context) error {
+        return s.next.CreateTransaction(ctx, tx)
+    })
+}
+
+func (s *interceptedStore) GetTransactions(ctx context.Context) (txs []*types.Transaction, err error) {
+    err = s.intercept(ctx, "GetTransactions", func(ctx context.Context) error {
+        txs, err = s.next.GetTransactions(ctx)
+        return err
+    })
+    return txs, err
+}
+
+func (s *interceptedStore) GetTransaction(ctx context.Context, id int) (tx *types.Transaction, err error) {
+    err = s.intercept(ctx, "GetTransaction", func(ctx context.Context) error {
+        tx, err = s.next.GetTransaction(ctx, id)
+        return err
+    })
+    return tx, err
+}
+
+func (s *interceptedStore) GetTransactionsByAccount(ctx context.Context, number int64) (txs []*types.Transaction, err error) {
+    err = s.intercept(ctx, "GetTransactionsByAccount", func(ctx context.Context) error {
+        txs, err = s.next.GetTransactionsByAccount(ctx, number)
+        return err
+    })
+    return txs, err
+}
+
+func (s *interceptedStore) GetTransactionByReference(ctx context.Context, provider, reference string) (tx *types.Transaction, err error) {
+    err = s.intercept(ctx, "GetTransactionByReference", func(ctx context.Context) error {
+        tx, err = s.next.GetTransactionByReference(ctx, provider, reference)
+        return err
+    })
+    return tx, err
+}
+
+func (s *interceptedStore) GetTransactionByIdempotencyKey(ctx context.Context, from int64, key string) (tx *types.Transaction, err error) {
+    err = s.intercept(ctx, "GetTransactionByIdempotencyKey", func(ctx context.Context) error {
+        tx, err = s.next.GetTransactionByIdempotencyKey(ctx, from, key)
+        return err
+    })
+    return tx, err
+}
+
+func (s *interceptedStore) SettleTransaction(ctx context.Context, id int, status string, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    return s.intercept(ctx, "SettleTransaction", func(ctx context.Context) error {
+        return s.next.SettleTransaction(ctx, id, status, reversal, entries)
+    })
+}
+
+func (s *interceptedStore) PostTransaction(ctx context.Context, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    return s.intercept(ctx, "PostTransaction", func(ctx context.Context) error {
+        return s.next.PostTransaction(ctx, tx, entries)
+    })
+}
+
+func (s *interceptedStore) PostTransfer(ctx context.Context, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    return s.intercept(ctx, "PostTransfer", func(ctx context.Context) error {
+        return s.next.PostTransfer(ctx, tx, entries, limits)
+    })
+}
+
+func (s *interceptedStore) PreviewTransfer(ctx context.Context, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    return s.intercept(ctx, "PreviewTransfer", func(ctx context.Context) error {
+        return s.next.PreviewTransfer(ctx, tx, entries, limits)
+    })
+}
+
+func (s *interceptedStore) GetLedgerEntries(ctx context.Context) (entries []*types.LedgerEntry, err error) {
+    err = s.intercept(ctx, "GetLedgerEntries", func(ctx context.Context) error {
+        entries, err = s.next.GetLedgerEntries(ctx)
+        return err
+    })
+    return entries, err
+}
+
+func (s *interceptedStore) GetLedgerEntriesByAccount(ctx context.Context, number int64) (entries []*types.LedgerEntry, err error) {
+    err = s.intercept(ctx, "GetLedgerEntriesByAccount", func(ctx context.Context) error {
+        entries, err = s.next.GetLedgerEntriesByAccount(ctx, number)
+        return err
+    })
+    return entries, err
+}
+
+func (s *interceptedStore) GetLedgerEntriesByTransaction(ctx context.Context, id int) (entries []*types.LedgerEntry, err error) {
+    err = s.intercept(ctx, "GetLedgerEntriesByTransaction", func(ctx context.Context) error {
+        entries, err = s.next.GetLedgerEntriesByTransaction(ctx, id)
+        return err
+    })
+    return entries, err
+}
+
+func (s *interceptedStore) GetBalances(ctx context.Context, number int64, t time.Time) (b *types.Balances, err error) {
+    err = s.intercept(ctx, "GetBalances", func(ctx context.Context) error {
+        b, err = s.next.GetBalances(ctx, number, t)
+        return err
+    })
+    return b, err
+}
+
+func (s *interceptedStore) GetLedgerBalance(ctx context.Context, number int64) (balance int64, err error) {
+    err = s.intercept(ctx, "GetLedgerBalance", func(ctx context.Context) error {
+        balance, err = s.next.GetLedgerBalance(ctx, number)
+        return err
+    })
+    return balance, err
+}
+
+func (s *interceptedStore) GetLedgerBalances(ctx context.Context) (balances map[int64]int64, err error) {
+    err = s.intercept(ctx, "GetLedgerBalances", func(ctx context.Context) error {
+        balances, err = s.next.GetLedgerBalances(ctx)
+        return err
+    })
+    return balances, err
+}
+
+func (s *interceptedStore) CreateDiscrepancy(ctx context.Context, d *types.Discrepancy) error {
+    return s.intercept(ctx, "CreateDiscrepancy", func(ctx context.Context) error {
+        return s.next.CreateDiscrepancy(ctx, d)
+    })
+}
+
+func (s *interceptedStore) ResolveDiscrepancy(ctx context.Context, id int, at time.Time) error {
+    return s.intercept(ctx, "ResolveDiscrepancy", func(ctx context.Context) error {
+        return s.next.ResolveDiscrepancy(ctx, id, at)
+    })
+}
+
+func (s *interceptedStore) GetDiscrepancies(ctx context.Context) (discrepancies []*types.Discrepancy, err error) {
+    err = s.intercept(ctx, "GetDiscrepancies", func(ctx context.Context) error {
+        discrepancies, err = s.next.GetDiscrepancies(ctx)
+        return err
+    })
+    return discrepancies, err
+}
+
+func (s *interceptedStore) GetLedgerSumBetween(ctx context.Context, number int64, from, to time.Time) (sum int64, err error) {
+    err = s.intercept(ctx, "GetLedgerSumBetween", func(ctx context.Context) error {
+        sum, err = s.next.GetLedgerSumBetween(ctx, number, from, to)
+        return err
+    })
+    return sum, err
+}
+
+func (s *interceptedStore) SaveBalanceSnapshot(ctx context.Context, snap *types.BalanceSnapshot) error {
+    return s.intercept(ctx, "SaveBalanceSnapshot", func(ctx context.Context) error {
+        return s.next.SaveBalanceSnapshot(ctx, snap)
+    })
+}
+
+func (s *interceptedStore) GetBalanceSnapshotBefore(ctx context.Context, number int64, before time.Time) (snap *types.BalanceSnapshot, err error) {
+    err = s.intercept(ctx, "GetBalanceSnapshotBefore", func(ctx context.Context) error {
+        snap, err = s.next.GetBalanceSnapshotBefore(ctx, number, before)
+        return err
+    })
+    return snap, err
+}
+
+func (s *interceptedStore) GetNotificationPreferences(ctx context.Context, number int64) (prefs *types.NotificationPreferences, err error) {
+    err = s.intercept(ctx, "GetNotificationPreferences", func(ctx context.Context) error {
+        prefs, err = s.next.GetNotificationPreferences(ctx, number)
+        return err
+    })
+    return prefs, err
+}
+
+func (s *interceptedStore) SavePreferences(ctx context.Context, prefs *types.Preferences) error {
+    return s.intercept(ctx, "SavePreferences", func(ctx context.Context) error {
+        return s.next.SavePreferences(ctx, prefs)
+    })
+}
+
+func (s *interceptedStore) SavePhoneVerification(ctx context.Context, v *types.PhoneVerification) error {
+    return s.intercept(ctx, "SavePhoneVerification", func(ctx context.Context) error {
+        return s.next.SavePhoneVerification(ctx, v)
+    })
+}
+
+func (s *interceptedStore) GetPhoneVerification(ctx context.Context, number int64) (v *types.PhoneVerification, err error) {
+    err = s.intercept(ctx, "GetPhoneVerification", func(ctx context.Context) error {
+        v, err = s.next.GetPhoneVerification(ctx, number)
+        return err
+    })
+    return v, err
+}
+
+func (s *interceptedStore) DeletePhoneVerification(ctx context.Context, number int64) error {
+    return s.intercept(ctx, "DeletePhoneVerification", func(ctx context.Context) error {
+        return s.next.DeletePhoneVerification(ctx, number)
+    })
+}
+
+func (s *interceptedStore) CreateAlertRule(ctx context.Context, rule *types.AlertRule) error {
+    return s.intercept(ctx, "CreateAlertRule", func(ctx context.Context) error {
+        return s.next.CreateAlertRule(ctx, rule)
+    })
+}
+
+func (s *interceptedStore) GetAlertRules(ctx context.Context, number int64) (rules []*types.AlertRule, err error) {
+    err = s.intercept(ctx, "GetAlertRules", func(ctx context.Context) error {
+        rules, err = s.next.GetAlertRules(ctx, number)
+        return err
+    })
+    return rules, err
+}
+
+func (s *interceptedStore) DeleteAlertRule(ctx context.Context, number int64, id int) error {
+    return s.intercept(ctx, "DeleteAlertRule", func(ctx context.Context) error {
+        return s.next.DeleteAlertRule(ctx, number, id)
+    })
+}
+
+func (s *interceptedStore) CreateAlias(ctx context.Context, a *types.Alias) error {
+    return s.intercept(ctx, "CreateAlias", func(ctx context.Context) error {
+        return s.next.CreateAlias(ctx, a)
+    })
+}
+
+func (s *interceptedStore) GetAlias(ctx context.Context, alias string) (a *types.Alias, err error) {
+    err = s.intercept(ctx, "GetAlias", func(ctx context.Context) error {
+        a, err = s.next.GetAlias(ctx, alias)
+        return err
+    })
+    return a, err
+}
+
+func (s *interceptedStore) GetAliasesByAccount(ctx context.Context, number int64) (aliases []*types.Alias, err error) {
+    err = s.intercept(ctx, "GetAliasesByAccount", func(ctx context.Context) error {
+        aliases, err = s.next.GetAliasesByAccount(ctx, number)
+        return err
+    })
+    return aliases, err
+}
+
+func (s *interceptedStore) DeleteAlias(ctx context.Context, number int64, alias string) error {
+    return s.intercept(ctx, "DeleteAlias", func(ctx context.Context) error {
+        return s.next.DeleteAlias(ctx, number, alias)
+    })
+}
+
+func (s *interceptedStore) SaveAliasVerification(ctx context.Context, v *types.AliasVerification) error {
+    return s.intercept(ctx, "SaveAliasVerification", func(ctx context.Context) error {
+        return s.next.SaveAliasVerification(ctx, v)
+    })
+}
+
+func (s *interceptedStore) GetAliasVerification(ctx context.Context, number int64, alias string) (v *types.AliasVerification, err error) {
+    err = s.intercept(ctx, "GetAliasVerification", func(ctx context.Context) error {
+        v, err = s.next.GetAliasVerification(ctx, number, alias)
+        return err
+    })
+    return v, err
+}
+
+func (s *interceptedStore) DeleteAliasVerification(ctx context.Context, number int64, alias string) error {
+    return s.intercept(ctx, "DeleteAliasVerification", func(ctx context.Context) error {
+        return s.next.DeleteAliasVerification(ctx, number, alias)
+    })
+}
+
+func (s *interceptedStore) CreateAliasClaim(ctx context.Context, c *types.AliasClaim, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    return s.intercept(ctx, "CreateAliasClaim", func(ctx context.Context) error {
+        return s.next.CreateAliasClaim(ctx, c, tx, entries, limits)
+    })
+}
+
+func (s *interceptedStore) GetPendingAliasClaims(ctx context.Context, alias string) (claims []*types.AliasClaim, err error) {
+    err = s.intercept(ctx, "GetPendingAliasClaims", func(ctx context.Context) error {
+        claims, err = s.next.GetPendingAliasClaims(ctx, alias)
+        return err
+    })
+    return claims, err
+}
+
+func (s *interceptedStore) GetExpiredAliasClaims(ctx context.Context, t time.Time) (claims []*types.AliasClaim, err error) {
+    err = s.intercept(ctx, "GetExpiredAliasClaims", func(ctx context.Context) error {
+        claims, err = s.next.GetExpiredAliasClaims(ctx, t)
+        return err
+    })
+    return claims, err
+}
+
+func (s *interceptedStore) CreatePaymentRequest(ctx context.Context, pr *types.PaymentRequest) error {
+    return s.intercept(ctx, "CreatePaymentRequest", func(ctx context.Context) error {
+        return s.next.CreatePaymentRequest(ctx, pr)
+    })
+}
+
+func (s *interceptedStore) GetPaymentRequest(ctx context.Context, id int) (pr *types.PaymentRequest, err error) {
+    err = s.intercept(ctx, "GetPaymentRequest", func(ctx context.Context) error {
+        pr, err = s.next.GetPaymentRequest(ctx, id)
+        return err
+    })
+    return pr, err
+}
+
+func (s *interceptedStore) GetPaymentRequestsByAccount(ctx context.Context, number int64) (prs []*types.PaymentRequest, err error) {
+    err = s.intercept(ctx, "GetPaymentRequestsByAccount", func(ctx context.Context) error {
+        prs, err = s.next.GetPaymentRequestsByAccount(ctx, number)
+        return err
+    })
+    return prs, err
+}
+
+func (s *interceptedStore) AcceptPaymentRequest(ctx context.Context, id int, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    return s.intercept(ctx, "AcceptPaymentRequest", func(ctx context.Context) error {
+        return s.next.AcceptPaymentRequest(ctx, id, tx, entries, limits)
+    })
+}
+
+func (s *interceptedStore) ClosePaymentRequest(ctx context.Context, id int, status string) error {
+    return s.intercept(ctx, "ClosePaymentRequest", func(ctx context.Context) error {
+        return s.next.ClosePaymentRequest(ctx, id, status)
+    })
+}
+
+func (s *interceptedStore) CreateCard(ctx context.Context, c *types.Card) error {
+    return s.intercept(ctx, "CreateCard", func(ctx context.Context) error {
+        return s.next.CreateCard(ctx, c)
+    })
+}
+
+func (s *interceptedStore) GetCard(ctx context.Context, id int) (c *types.Card, err error) {
+    err = s.intercept(ctx, "GetCard", func(ctx context.Context) error {
+        c, err = s.next.GetCard(ctx, id)
+        return err
+    })
+    return c, err
+}
+
+func (s *interceptedStore) GetCardByPANHash(ctx context.Context, hash string) (c *types.Card, err error) {
+    err = s.intercept(ctx, "GetCardByPANHash", func(ctx context.Context) error {
+        c, err = s.next.GetCardByPANHash(ctx, hash)
+        return err
+    })
+    return c, err
+}
+
+func (s *interceptedStore) GetCardsByAccount(ctx context.Context, number int64) (cards []*types.Card, err error) {
+    err = s.intercept(ctx, "GetCardsByAccount", func(ctx context.Context) error {
+        cards, err = s.next.GetCardsByAccount(ctx, number)
+        return err
+    })
+    return cards, err
+}
+
+func (s *interceptedStore) UpdateCard(ctx context.Context, c *types.Card) error {
+    return s.intercept(ctx, "UpdateCard", func(ctx context.Context) error {
+        return s.next.UpdateCard(ctx, c)
+    })
+}
+
+func (s *interceptedStore) PlaceHold(ctx context.Context, h *types.Hold, dailyLimit int64) error {
+    return s.intercept(ctx, "PlaceHold", func(ctx context.Context) error {
+        return s.next.PlaceHold(ctx, h, dailyLimit)
+    })
+}
+
+func (s *interceptedStore) GetHold(ctx context.Context, id int) (h *types.Hold, err error) {
+    err = s.intercept(ctx, "GetHold", func(ctx context.Context) error {
+        h, err = s.next.GetHold(ctx, id)
+        return err
+    })
+    return h, err
+}
+
+func (s *interceptedStore) CaptureHold(ctx context.Context, id int, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    return s.intercept(ctx, "CaptureHold", func(ctx context.Context) error {
+        return s.next.CaptureHold(ctx, id, tx, entries)
+    })
+}
+
+func (s *interceptedStore) ReleaseHold(ctx context.Context, id int, at time.Time) error {
+    return s.intercept(ctx, "ReleaseHold", func(ctx context.Context) error {
+        return s.next.ReleaseHold(ctx, id, at)
+    })
+}
+
+func (s *interceptedStore) GetActiveHolds(ctx context.Context, number int64, t time.Time) (holds []*types.Hold, err error) {
+    err = s.intercept(ctx, "GetActiveHolds", func(ctx context.Context) error {
+        holds, err = s.next.GetActiveHolds(ctx, number, t)
+        return err
+    })
+    return holds, err
+}
+
+func (s *interceptedStore) SetCardPIN(ctx context.Context, id int, hash string) error {
+    return s.intercept(ctx, "SetCardPIN", func(ctx context.Context) error {
+        return s.next.SetCardPIN(ctx, id, hash)
+    })
+}
+
+func (s *interceptedStore) RecordPINAttempt(ctx context.Context, id int, correct bool, maxAttempts int) (c *types.Card, err error) {
+    err = s.intercept(ctx, "RecordPINAttempt", func(ctx context.Context) error {
+        c, err = s.next.RecordPINAttempt(ctx, id, correct, maxAttempts)
+        return err
+    })
+    return c, err
+}
+
+func (s *interceptedStore) CreateLoan(ctx context.Context, l *types.Loan) error {
+    return s.intercept(ctx, "CreateLoan", func(ctx context.Context) error {
+        return s.next.CreateLoan(ctx, l)
+    })
+}
+
+func (s *interceptedStore) GetLoan(ctx context.Context, id int) (l *types.Loan, err error) {
+    err = s.intercept(ctx, "GetLoan", func(ctx context.Context) error {
+        l, err = s.next.GetLoan(ctx, id)
+        return err
+    })
+    return l, err
+}
+
+func (s *interceptedStore) GetLoansByAccount(ctx context.Context, number int64) (loans []*types.Loan, err error) {
+    err = s.intercept(ctx, "GetLoansByAccount", func(ctx context.Context) error {
+        loans, err = s.next.GetLoansByAccount(ctx, number)
+        return err
+    })
+    return loans, err
+}
+
+func (s *interceptedStore) GetLoansByStatus(ctx context.Context, status string) (loans []*types.Loan, err error) {
+    err = s.intercept(ctx, "GetLoansByStatus", func(ctx context.Context) error {
+        loans, err = s.next.GetLoansByStatus(ctx, status)
+        return err
+    })
+    return loans, err
+}
+
+func (s *interceptedStore) RejectLoan(ctx context.Context, id int) error {
+    return s.intercept(ctx, "RejectLoan", func(ctx context.Context) error {
+        return s.next.RejectLoan(ctx, id)
+    })
+}
+
+func (s *interceptedStore) DisburseLoan(ctx context.Context, l *types.Loan, schedule []*types.LoanInstallment, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    return s.intercept(ctx, "DisburseLoan", func(ctx context.Context) error {
+        return s.next.DisburseLoan(ctx, l, schedule, tx, entries)
+    })
+}
+
+func (s *interceptedStore) GetLoanSchedule(ctx context.Context, loanID int) (schedule []*types.LoanInstallment, err error) {
+    err = s.intercept(ctx, "GetLoanSchedule", func(ctx context.Context) error {
+        schedule, err = s.next.GetLoanSchedule(ctx, loanID)
+        return err
+    })
+    return schedule, err
+}
+
+func (s *interceptedStore) GetDueInstallments(ctx context.Context, t time.Time) (due []*types.LoanInstallment, err error) {
+    err = s.intercept(ctx, "GetDueInstallments", func(ctx context.Context) error {
+        due, err = s.next.GetDueInstallments(ctx, t)
+        return err
+    })
+    return due, err
+}
+
+func (s *interceptedStore) ApplyLoanPayment(ctx context.Context, inst *types.LoanInstallment, principal int64, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    return s.intercept(ctx, "ApplyLoanPayment", func(ctx context.Context) error {
+        return s.next.ApplyLoanPayment(ctx, inst, principal, tx, entries)
+    })
+}
+
+func (s *interceptedStore) CreatePot(ctx context.Context, p *types.Pot) error {
+    return s.intercept(ctx, "CreatePot", func(ctx context.Context) error {
+        return s.next.CreatePot(ctx, p)
+    })
+}
+
+func (s *interceptedStore) GetPot(ctx context.Context, id int) (p *types.Pot, err error) {
+    err = s.intercept(ctx, "GetPot", func(ctx context.Context) error {
+        p, err = s.next.GetPot(ctx, id)
+        return err
+    })
+    return p, err
+}
+
+func (s *interceptedStore) GetPotsByAccount(ctx context.Context, number int64) (pots []*types.Pot, err error) {
+    err = s.intercept(ctx, "GetPotsByAccount", func(ctx context.Context) error {
+        pots, err = s.next.GetPotsByAccount(ctx, number)
+        return err
+    })
+    return pots, err
+}
+
+func (s *interceptedStore) UpdatePot(ctx context.Context, p *types.Pot) error {
+    return s.intercept(ctx, "UpdatePot", func(ctx context.Context) error {
+        return s.next.UpdatePot(ctx, p)
+    })
+}
+
+func (s *interceptedStore) DeletePot(ctx context.Context, id int) error {
+    return s.intercept(ctx, "DeletePot", func(ctx context.Context) error {
+        return s.next.DeletePot(ctx, id)
+    })
+}
+
+func (s *interceptedStore) MovePotMoney(ctx context.Context, id int, amount int64) (p *types.Pot, err error) {
+    err = s.intercept(ctx, "MovePotMoney", func(ctx context.Context) error {
+        p, err = s.next.MovePotMoney(ctx, id, amount)
+        return err
+    })
+    return p, err
+}
+
+func (s *interceptedStore) CreateOwnerInvitation(ctx context.Context, inv *types.OwnerInvitation) error {
+    return s.intercept(ctx, "CreateOwnerInvitation", func(ctx context.Context) error {
+        return s.next.CreateOwnerInvitation(ctx, inv)
+    })
+}
+
+func (s *interceptedStore) GetOwnerInvitation(ctx context.Context, id int) (inv *types.OwnerInvitation, err error) {
+    err = s.intercept(ctx, "GetOwnerInvitation", func(ctx context.Context) error {
+        inv, err = s.next.GetOwnerInvitation(ctx, id)
+        return err
+    })
+    return inv, err
+}
+
+func (s *interceptedStore) GetOwnerInvitationsFor(ctx context.Context, number int64) (invitations []*types.OwnerInvitation, err error) {
+    err = s.intercept(ctx, "GetOwnerInvitationsFor", func(ctx context.Context) error {
+        invitations, err = s.next.GetOwnerInvitationsFor(ctx, number)
+        return err
+    })
+    return invitations, err
+}
+
+func (s *interceptedStore) AcceptOwnerInvitation(ctx context.Context, id int) (o *types.AccountOwner, err error) {
+    err = s.intercept(ctx, "AcceptOwnerInvitation", func(ctx context.Context) error {
+        o, err = s.next.AcceptOwnerInvitation(ctx, id)
+        return err
+    })
+    return o, err
+}
+
+func (s *interceptedStore) DeclineOwnerInvitation(ctx context.Context, id int) error {
+    return s.intercept(ctx, "DeclineOwnerInvitation", func(ctx context.Context) error {
+        return s.next.DeclineOwnerInvitation(ctx, id)
+    })
+}
+
+func (s *interceptedStore) GetAccountOwners(ctx context.Context, number int64) (owners []*types.AccountOwner, err error) {
+    err = s.intercept(ctx, "GetAccountOwners", func(ctx context.Context) error {
+        owners, err = s.next.GetAccountOwners(ctx, number)
+        return err
+    })
+    return owners, err
+}
+
+func (s *interceptedStore) GetAccountOwner(ctx context.Context, account, owner int64) (o *types.AccountOwner, err error) {
+    err = s.intercept(ctx, "GetAccountOwner", func(ctx context.Context) error {
+        o, err = s.next.GetAccountOwner(ctx, account, owner)
+        return err
+    })
+    return o, err
+}
+
+func (s *interceptedStore) DeleteAccountOwner(ctx context.Context, account, owner int64) error {
+    return s.intercept(ctx, "DeleteAccountOwner", func(ctx context.Context) error {
+        return s.next.DeleteAccountOwner(ctx, account, owner)
+    })
+}
+
+func (s *interceptedStore) RecordAudit(ctx context.Context, e *types.AuditEntry) error {
+    return s.intercept(ctx, "RecordAudit", func(ctx context.Context) error {
+        return s.next.RecordAudit(ctx, e)
+    })
+}
+
+func (s *interceptedStore) GetAuditLog(ctx context.Context, number int64) (entries []*types.AuditEntry, err error) {
+    err = s.intercept(ctx, "GetAuditLog", func(ctx context.Context) error {
+        entries, err = s.next.GetAuditLog(ctx, number)
+        return err
+    })
+    return entries, err
+}
+
+func (s *interceptedStore) CreateGrant(ctx context.Context, g *types.Grant) error {
+    return s.intercept(ctx, "CreateGrant", func(ctx context.Context) error {
+        return s.next.CreateGrant(ctx, g)
+    })
+}
+
+func (s *interceptedStore) GetGrant(ctx context.Context, id int) (g *types.Grant, err error) {
+    err = s.intercept(ctx, "GetGrant", func(ctx context.Context) error {
+        g, err = s.next.GetGrant(ctx, id)
+        return err
+    })
+    return g, err
+}
+
+func (s *interceptedStore) GetGrantsByAccount(ctx context.Context, number int64) (grants []*types.Grant, err error) {
+    err = s.intercept(ctx, "GetGrantsByAccount", func(ctx context.Context) error {
+        grants, err = s.next.GetGrantsByAccount(ctx, number)
+        return err
+    })
+    return grants, err
+}
+
+func (s *interceptedStore) GetActiveGrant(ctx context.Context, account, grantee int64) (g *types.Grant, err error) {
+    err = s.intercept(ctx, "GetActiveGrant", func(ctx context.Context) error {
+        g, err = s.next.GetActiveGrant(ctx, account, grantee)
+        return err
+    })
+    return g, err
+}
+
+func (s *interceptedStore) RevokeGrant(ctx context.Context, id int, t time.Time) error {
+    return s.intercept(ctx, "RevokeGrant", func(ctx context.Context) error {
+        return s.next.RevokeGrant(ctx, id, t)
+    })
+}
+
+func (s *interceptedStore) CreateProductRate(ctx context.Context, rate *types.ProductRate) error {
+    return s.intercept(ctx, "CreateProductRate", func(ctx context.Context) error {
+        return s.next.CreateProductRate(ctx, rate)
+    })
+}
+
+func (s *interceptedStore) GetProductRate(ctx context.Context, id int) (rate *types.ProductRate, err error) {
+    err = s.intercept(ctx, "GetProductRate", func(ctx context.Context) error {
+        rate, err = s.next.GetProductRate(ctx, id)
+        return err
+    })
+    return rate, err
+}
+
+func (s *interceptedStore) GetProductRates(ctx context.Context) (rates []*types.ProductRate, err error) {
+    err = s.intercept(ctx, "GetProductRates", func(ctx context.Context) error {
+        rates, err = s.next.GetProductRates(ctx)
+        return err
+    })
+    return rates, err
+}
+
+func (s *interceptedStore) GetEffectiveProductRate(ctx context.Context, key string, t time.Time) (rate *types.ProductRate, err error) {
+    err = s.intercept(ctx, "GetEffectiveProductRate", func(ctx context.Context) error {
+        rate, err = s.next.GetEffectiveProductRate(ctx, key, t)
+        return err
+    })
+    return rate, err
+}
+
+func (s *interceptedStore) UpdateProductRate(ctx context.Context, rate *types.ProductRate, now time.Time) error {
+    return s.intercept(ctx, "UpdateProductRate", func(ctx context.Context) error {
+        return s.next.UpdateProductRate(ctx, rate, now)
+    })
+}
+
+func (s *interceptedStore) DeleteProductRate(ctx context.Context, id int, now time.Time) error {
+    return s.intercept(ctx, "DeleteProductRate", func(ctx context.Context) error {
+        return s.next.DeleteProductRate(ctx, id, now)
+    })
+}
+
+func (s *interceptedStore) CreateFraudCase(ctx context.Context, c *types.FraudCase) error {
+    return s.intercept(ctx, "CreateFraudCase", func(ctx context.Context) error {
+        return s.next.CreateFraudCase(ctx, c)
+    })
+}
+
+func (s *interceptedStore) GetFraudCase(ctx context.Context, id int) (c *types.FraudCase, err error) {
+    err = s.intercept(ctx, "GetFraudCase", func(ctx context.Context) error {
+        c, err = s.next.GetFraudCase(ctx, id)
+        return err
+    })
+    return c, err
+}
+
+func (s *interceptedStore) GetFraudCasesByStatus(ctx context.Context, status string) (cases []*types.FraudCase, err error) {
+    err = s.intercept(ctx, "GetFraudCasesByStatus", func(ctx context.Context) error {
+        cases, err = s.next.GetFraudCasesByStatus(ctx, status)
+        return err
+    })
+    return cases, err
+}
+
+func (s *interceptedStore) ApproveFraudCase(ctx context.Context, c *types.FraudCase, tx *types.Transaction, entries []*types.LedgerEntry, et *types.ExternalTransfer) error {
+    return s.intercept(ctx, "ApproveFraudCase", func(ctx context.Context) error {
+        return s.next.ApproveFraudCase(ctx, c, tx, entries, et)
+    })
+}
+
+func (s *interceptedStore) RejectFraudCase(ctx context.Context, c *types.FraudCase, at time.Time) error {
+    return s.intercept(ctx, "RejectFraudCase", func(ctx context.Context) error {
+        return s.next.RejectFraudCase(ctx, c, at)
+    })
+}
+
+func (s *interceptedStore) GetFraudHistory(ctx context.Context, from, to int64, external *types.ExternalTransferRequest, since time.Time) (h *types.FraudHistory, err error) {
+    err = s.intercept(ctx, "GetFraudHistory", func(ctx context.Context) error {
+        h, err = s.next.GetFraudHistory(ctx, from, to, external, since)
+        return err
+    })
+    return h, err
+}
+
+func (s *interceptedStore) GetDevices(ctx context.Context, number int64) (devices []*types.Device, err error) {
+    err = s.intercept(ctx, "GetDevices", func(ctx context.Context) error {
+        devices, err = s.next.GetDevices(ctx, number)
+        return err
+    })
+    return devices, err
+}
+
+func (s *interceptedStore) SaveDevice(ctx context.Context, d *types.Device) error {
+    return s.intercept(ctx, "SaveDevice", func(ctx context.Context) error {
+        return s.next.SaveDevice(ctx, d)
+    })
+}
+
+func (s *interceptedStore) DeleteDevice(ctx context.Context, number int64, id int) error {
+    return s.intercept(ctx, "DeleteDevice", func(ctx context.Context) error {
+        return s.next.DeleteDevice(ctx, number, id)
+    })
+}
+
+func (s *interceptedStore) SaveStepUpChallenge(ctx context.Context, c *types.StepUpChallenge) error {
+    return s.intercept(ctx, "SaveStepUpChallenge", func(ctx context.Context) error {
+        return s.next.SaveStepUpChallenge(ctx, c)
+    })
+}
+
+func (s *interceptedStore) GetStepUpChallenge(ctx context.Context, id string) (c *types.StepUpChallenge, err error) {
+    err = s.intercept(ctx, "GetStepUpChallenge", func(ctx context.Context) error {
+        c, err = s.next.GetStepUpChallenge(ctx, id)
+        return err
+    })
+    return c, err
+}
+
+func (s *interceptedStore) DeleteStepUpChallenge(ctx context.Context, id string) error {
+    return s.intercept(ctx, "DeleteStepUpChallenge", func(ctx context.Context) error {
+        return s.next.DeleteStepUpChallenge(ctx, id)
+    })
+}
+
+func (s *interceptedStore) GetDailyFlows(ctx context.Context, from, to time.Time) (flows []*types.DailyFlow, err error) {
+    err = s.intercept(ctx, "GetDailyFlows", func(ctx context.Context) error {
+        flows, err = s.next.GetDailyFlows(ctx, from, to)
+        return err
+    })
+    return flows, err
+}
+
+func (s *interceptedStore) GetActiveAccountCount(ctx context.Context, from, to time.Time) (n int, err error) {
+    err = s.intercept(ctx, "GetActiveAccountCount", func(ctx context.Context) error {
+        n, err = s.next.GetActiveAccountCount(ctx, from, to)
+        return err
+    })
+    return n, err
+}
+
+func (s *interceptedStore) GetLargestTransfers(ctx context.Context, from, to time.Time, limit int) (txs []*types.Transaction, err error) {
+    err = s.intercept(ctx, "GetLargestTransfers", func(ctx context.Context) error {
+        txs, err = s.next.GetLargestTransfers(ctx, from, to, limit)
+        return err
+    })
+    return txs, err
+}
+
+func (s *interceptedStore) TryLeaderLock(ctx context.Context, name string) (held bool, err error) {
+    err = s.intercept(ctx, "TryLeaderLock", func(ctx context.Context) error {
+        held, err = s.next.TryLeaderLock(ctx, name)
+        return err
+    })
+    return held, err
+}
+
+func (s *interceptedStore) SaveJobStatus(ctx context.Context, j *types.JobStatus) error {
+    return s.intercept(ctx, "SaveJobStatus", func(ctx context.Context) error {
+        return s.next.SaveJobStatus(ctx, j)
+    })
+}
+
+func (s *interceptedStore) GetJobStatuses(ctx context.Context) (statuses []*types.JobStatus, err error) {
+    err = s.intercept(ctx, "GetJobStatuses", func(ctx context.Context) error {
+        statuses, err = s.next.GetJobStatuses(ctx)
+        return err
+    })
+    return statuses, err
+}
+
+func (s *interceptedStore) ExpireHolds(ctx context.Context, t time.Time) (n int, err error) {
+    err = s.intercept(ctx, "ExpireHolds", func(ctx context.Context) error {
+        n, err = s.next.ExpireHolds(ctx, t)
+        return err
+    })
+    return n, err
+}
+
+func (s *interceptedStore) GetPendingExternalTransactions(ctx context.Context, before time.Time) (txs []*types.Transaction, err error) {
+    err = s.intercept(ctx, "GetPendingExternalTransactions", func(ctx context.Context) error {
+        txs, err = s.next.GetPendingExternalTransactions(ctx, before)
+        return err
+    })
+    return txs, err
+}
+
+func (s *interceptedStore) CreateDeadLetter(ctx context.Context, d *types.DeadLetter) error {
+    return s.intercept(ctx, "CreateDeadLetter", func(ctx context.Context) error {
+        return s.next.CreateDeadLetter(ctx, d)
+    })
+}
+
+func (s *interceptedStore) GetDeadLetter(ctx context.Context, id int) (d *types.DeadLetter, err error) {
+    err = s.intercept(ctx, "GetDeadLetter", func(ctx context.Context) error {
+        d, err = s.next.GetDeadLetter(ctx, id)
+        return err
+    })
+    return d, err
+}
+
+func (s *interceptedStore) GetDeadLettersByStatus(ctx context.Context, status string) (letters []*types.DeadLetter, err error) {
+    err = s.intercept(ctx, "GetDeadLettersByStatus", func(ctx context.Context) error {
+        letters, err = s.next.GetDeadLettersByStatus(ctx, status)
+        return err
+    })
+    return letters, err
+}
+
+func (s *interceptedStore) RecordDeadLetterFailure(ctx context.Context, d *types.DeadLetter, reason string) error {
+    return s.intercept(ctx, "RecordDeadLetterFailure", func(ctx context.Context) error {
+        return s.next.RecordDeadLetterFailure(ctx, d, reason)
+    })
+}
+
+func (s *interceptedStore) MarkDeadLetterReplayed(ctx context.Context, d *types.DeadLetter, at time.Time) error {
+    return s.intercept(ctx, "MarkDeadLetterReplayed", func(ctx context.Context) error {
+        return s.next.MarkDeadLetterReplayed(ctx, d, at)
+    })
+}
+
+func (s *interceptedStore) CreateSigningKey(ctx context.Context, k *types.SigningKey) error {
+    return s.intercept(ctx, "CreateSigningKey", func(ctx context.Context) error {
+        return s.next.CreateSigningKey(ctx, k)
+    })
+}
+
+func (s *interceptedStore) GetSigningKeysByAccount(ctx context.Context, number int64) (keys []*types.SigningKey, err error) {
+    err = s.intercept(ctx, "GetSigningKeysByAccount", func(ctx context.Context) error {
+        keys, err = s.next.GetSigningKeysByAccount(ctx, number)
+        return err
+    })
+    return keys, err
+}
+
+func (s *interceptedStore) DeleteSigningKey(ctx context.Context, number int64, id string) error {
+    return s.intercept(ctx, "DeleteSigningKey", func(ctx context.Context) error {
+        return s.next.DeleteSigningKey(ctx, number, id)
+    })
+}
+
+func (s *interceptedStore) UseNonce(ctx context.Context, keyID, nonce string, at time.Time) error {
+    return s.intercept(ctx, "UseNonce", func(ctx context.Context) error {
+        return s.next.UseNonce(ctx, keyID, nonce, at)
+    })
+}
+
+func (s *interceptedStore) DeleteNoncesBefore(ctx context.Context, t time.Time) (n int, err error) {
+    err = s.intercept(ctx, "DeleteNoncesBefore", func(ctx context.Context) error {
+        n, err = s.next.DeleteNoncesBefore(ctx, t)
+        return err
+    })
+    return n, err
+}
+
+func (s *interceptedStore) ImportAccounts(ctx context.Context, imports []*types.AccountImport) error {
+    return s.intercept(ctx, "ImportAccounts", func(ctx context.Context) error {
+        return s.next.ImportAccounts(ctx, imports)
+    })
+}
+
+func (s *interceptedStore) ActivateAccount(ctx context.Context, number int64, tokenHash, encryptedPassword string, now time.Time) error {
+    return s.intercept(ctx, "ActivateAccount", func(ctx context.Context) error {
+        return s.next.ActivateAccount(ctx, number, tokenHash, encryptedPassword, now)
+    })
+}
+
+func (s *interceptedStore) ArchiveTransactionsBefore(ctx context.Context, t time.Time) (n int, err error) {
+    err = s.intercept(ctx, "ArchiveTransactionsBefore", func(ctx context.Context) error {
+        n, err = s.next.ArchiveTransactionsBefore(ctx, t)
+        return err
+    })
+    return n, err
+}
+
+func (s *interceptedStore) GetLastActivity(ctx context.Context) (activity map[int64]time.Time, err error) {
+    err = s.intercept(ctx, "GetLastActivity", func(ctx context.Context) error {
+        activity, err = s.next.GetLastActivity(ctx)
+        return err
+    })
+    return activity, err
+}
+
+func (s *interceptedStore) SetDormancy(ctx context.Context, number int64, noticeAt, dormantSince *time.Time) error {
+    return s.intercept(ctx, "SetDormancy", func(ctx context.Context) error {
+        return s.next.SetDormancy(ctx, number, noticeAt, dormantSince)
+    })
+}
+
+func (s *interceptedStore) CreateDispute(ctx context.Context, d *types.Dispute) error {
+    return s.intercept(ctx, "CreateDispute", func(ctx context.Context) error {
+        return s.next.CreateDispute(ctx, d)
+    })
+}
+
+func (s *interceptedStore) GetDispute(ctx context.Context, id int) (d *types.Dispute, err error) {
+    err = s.intercept(ctx, "GetDispute", func(ctx context.Context) error {
+        d, err = s.next.GetDispute(ctx, id)
+        return err
+    })
+    return d, err
+}
+
+func (s *interceptedStore) GetDisputesByAccount(ctx context.Context, number int64) (disputes []*types.Dispute, err error) {
+    err = s.intercept(ctx, "GetDisputesByAccount", func(ctx context.Context) error {
+        disputes, err = s.next.GetDisputesByAccount(ctx, number)
+        return err
+    })
+    return disputes, err
+}
+
+func (s *interceptedStore) GetDisputesByStatus(ctx context.Context, status string) (disputes []*types.Dispute, err error) {
+    err = s.intercept(ctx, "GetDisputesByStatus", func(ctx context.Context) error {
+        disputes, err = s.next.GetDisputesByStatus(ctx, status)
+        return err
+    })
+    return disputes, err
+}
+
+func (s *interceptedStore) AcceptDispute(ctx context.Context, d *types.Dispute, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    return s.intercept(ctx, "AcceptDispute", func(ctx context.Context) error {
+        return s.next.AcceptDispute(ctx, d, reversal, entries)
+    })
+}
+
+func (s *interceptedStore) DenyDispute(ctx context.Context, d *types.Dispute, at time.Time) error {
+    return s.intercept(ctx, "DenyDispute", func(ctx context.Context) error {
+        return s.next.DenyDispute(ctx, d, at)
+    })
+}
+
+func (s *interceptedStore) CreateDocument(ctx context.Context, d *types.Document) error {
+    return s.intercept(ctx, "CreateDocument", func(ctx context.Context) error {
+        return s.next.CreateDocument(ctx, d)
+    })
+}
+
+func (s *interceptedStore) GetDocument(ctx context.Context, id int) (d *types.Document, err error) {
+    err = s.intercept(ctx, "GetDocument", func(ctx context.Context) error {
+        d, err = s.next.GetDocument(ctx, id)
+        return err
+    })
+    return d, err
+}
+
+func (s *interceptedStore) GetDocumentsByAccount(ctx context.Context, number int64, purpose string) (documents []*types.Document, err error) {
+    err = s.intercept(ctx, "GetDocumentsByAccount", func(ctx context.Context) error {
+        documents, err = s.next.GetDocumentsByAccount(ctx, number, purpose)
+        return err
+    })
+    return documents, err
+}
+
+func (s *interceptedStore) CloseAccount(ctx context.Context, number int64, sweep *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit, at time.Time) error {
+    return s.intercept(ctx, "CloseAccount", func(ctx context.Context) error {
+        return s.next.CloseAccount(ctx, number, sweep, entries, limits, at)
+    })
+}
+
+func (s *interceptedStore) CreateExternalTransfer(ctx context.Context, et *types.ExternalTransfer, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    return s.intercept(ctx, "CreateExternalTransfer", func(ctx context.Context) error {
+        return s.next.CreateExternalTransfer(ctx, et, tx, entries, limits)
+    })
+}
+
+func (s *interceptedStore) GetExternalTransfer(ctx context.Context, id int) (et *types.ExternalTransfer, err error) {
+    err = s.intercept(ctx, "GetExternalTransfer", func(ctx context.Context) error {
+        et, err = s.next.GetExternalTransfer(ctx, id)
+        return err
+    })
+    return et, err
+}
+
+func (s *interceptedStore) GetExternalTransfersByAccount(ctx context.Context, number int64) (transfers []*types.ExternalTransfer, err error) {
+    err = s.intercept(ctx, "GetExternalTransfersByAccount", func(ctx context.Context) error {
+        transfers, err = s.next.GetExternalTransfersByAccount(ctx, number)
+        return err
+    })
+    return transfers, err
+}
+
+func (s *interceptedStore) GetPendingExternalTransfers(ctx context.Context) (transfers []*types.ExternalTransfer, err error) {
+    err = s.intercept(ctx, "GetPendingExternalTransfers", func(ctx context.Context) error {
+        transfers, err = s.next.GetPendingExternalTransfers(ctx)
+        return err
+    })
+    return transfers, err
+}
+
+func (s *interceptedStore) ReturnExternalTransfer(ctx context.Context, id int, reason string, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    return s.intercept(ctx, "ReturnExternalTransfer", func(ctx context.Context) error {
+        return s.next.ReturnExternalTransfer(ctx, id, reason, reversal, entries)
+    })
+}
+
+func (s *interceptedStore) ReverseTransaction(ctx context.Context, r *types.Reversal, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    return s.intercept(ctx, "ReverseTransaction", func(ctx context.Context) error {
+        return s.next.ReverseTransaction(ctx, r, tx, entries)
+    })
+}
+
+func (s *interceptedStore) GetReversal(ctx context.Context, id int) (r *types.Reversal, err error) {
+    err = s.intercept(ctx, "GetReversal", func(ctx context.Context) error {
+        r, err = s.next.GetReversal(ctx, id)
+        return err
+    })
+    return r, err
+}
+
+func (s *interceptedStore) GetReversals(ctx context.Context) (reversals []*types.Reversal, err error) {
+    err = s.intercept(ctx, "GetReversals", func(ctx context.Context) error {
+        reversals, err = s.next.GetReversals(ctx)
+        return err
+    })
+    return reversals, err
+}
+
+func (s *interceptedStore) CountRequest(ctx context.Context, number int64, day time.Time, limit int64) error {
+    return s.intercept(ctx, "CountRequest", func(ctx context.Context) error {
+        return s.next.CountRequest(ctx, number, day, limit)
+    })
+}
+
+func (s *interceptedStore) RecordTransferUsage(ctx context.Context, number int64, day time.Time, amount int64) error {
+    return s.intercept(ctx, "RecordTransferUsage", func(ctx context.Context) error {
+        return s.next.RecordTransferUsage(ctx, number, day, amount)
+    })
+}
+
+func (s *interceptedStore) GetUsage(ctx context.Context, number int64, from, to time.Time) (usage []*types.Usage, err error) {
+    err = s.intercept(ctx, "GetUsage", func(ctx context.Context) error {
+        usage, err = s.next.GetUsage(ctx, number, from, to)
+        return err
+    })
+    return usage, err
+}
+
+func (s *interceptedStore) GetUsageTotals(ctx context.Context, from, to time.Time) (totals []*types.Usage, err error) {
+    err = s.intercept(ctx, "GetUsageTotals", func(ctx context.Context) error {
+        totals, err = s.next.GetUsageTotals(ctx, from, to)
+        return err
+    })
+    return totals, err
+}
+
+func (s *interceptedStore) CreateAccountInvitation(ctx context.Context, inv *types.AccountInvitation) error {
+    return s.intercept(ctx, "CreateAccountInvitation", func(ctx context.Context) error {
+        return s.next.CreateAccountInvitation(ctx, inv)
+    })
+}
+
+func (s *interceptedStore) GetAccountInvitations(ctx context.Context, invitedBy int64) (invitations []*types.AccountInvitation, err error) {
+    err = s.intercept(ctx, "GetAccountInvitations", func(ctx context.Context) error {
+        invitations, err = s.next.GetAccountInvitations(ctx, invitedBy)
+        return err
+    })
+    return invitations, err
+}
+
+func (s *interceptedStore) AcceptAccountInvitation(ctx context.Context, tokenHash string, acc *types.Account, now time.Time) (inv *types.AccountInvitation, err error) {
+    err = s.intercept(ctx, "AcceptAccountInvitation", func(ctx context.Context) error {
+        inv, err = s.next.AcceptAccountInvitation(ctx, tokenHash, acc, now)
+        return err
+    })
+    return inv, err
+}
+
+func (s *interceptedStore) RestoreLoan(ctx context.Context, l *types.Loan, schedule []*types.LoanInstallment) error {
+    return s.intercept(ctx, "RestoreLoan", func(ctx context.Context) error {
+        return s.next.RestoreLoan(ctx, l, schedule)
+    })
+}
+
+func (s *interceptedStore) RestoreDispute(ctx context.Context, d *types.Dispute) error {
+    return s.intercept(ctx, "RestoreDispute", func(ctx context.Context) error {
+        return s.next.RestoreDispute(ctx, d)
+    })
+}
+
+func (s *interceptedStore) RestoreAliasClaim(ctx context.Context, c *types.AliasClaim) error {
+    return s.intercept(ctx, "RestoreAliasClaim", func(ctx context.Context) error {
+        return s.next.RestoreAliasClaim(ctx, c)
+    })
+}
+
+func (s *interceptedStore) RestoreExternalTransfer(ctx context.Context, et *types.ExternalTransfer) error {
+    return s.intercept(ctx, "RestoreExternalTransfer", func(ctx context.Context) error {
+        return s.next.RestoreExternalTransfer(ctx, et)
+    })
+}
+
+func (s *interceptedStore) RestorePaymentRequest(ctx context.Context, pr *types.PaymentRequest) error {
+    return s.intercept(ctx, "RestorePaymentRequest", func(ctx context.Context) error {
+        return s.next.RestorePaymentRequest(ctx, pr)
+    })
+}
+
+func (s *interceptedStore) RestoreFraudCase(ctx context.Context, c *types.FraudCase) error {
+    return s.intercept(ctx, "RestoreFraudCase", func(ctx context.Context) error {
+        return s.next.RestoreFraudCase(ctx, c)
+    })
+}
+
+func (s *interceptedStore) RestoreReversal(ctx context.Context, r *types.Reversal) error {
+    return s.intercept(ctx, "RestoreReversal", func(ctx context.Context) error {
+        return s.next.RestoreReversal(ctx, r)
+    })
+}
+
+func (s *interceptedStore) ClearRestored(ctx context.Context) error {
+    return s.intercept(ctx, "ClearRestored", func(ctx context.Context) error {
+        return s.next.ClearRestored(ctx)
+    })
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+type InvitationStorage interface {
+    CreateAccountInvitation(context.Context, *types.AccountInvitation) error
+    // GetAccountInvitations returns the invitations sent by an account, or
+    // by admins for 0, newest first.
+    GetAccountInvitations(context.Context, int64) ([]*types.AccountInvitation, error)
+    // AcceptAccountInvitation creates acc, under the invitation's email
+    // address, links it as an owner when the invitation says so and uses
+    // up the invitation, all in one database transaction. It fails with
+    // ErrNotFound unless there is a pending invitation with tokenHash that
+    // is still valid at now, with ErrAccountClosed when it would make acc
+    // an owner of a closed account, and with ErrNumberTaken, changing
+    // nothing, when acc's number is in use.
+    AcceptAccountInvitation(ctx context.Context, tokenHash string, acc *types.Account, now time.Time) (*types.AccountInvitation, error)
+}
+
+const invitationColumns = `id, email, invited_by, permission, status, account_number, token_hash, expires_at, created_at, accepted_at`
+
+func (s *PostgresStore) CreateAccountInvitationTable() error {
+    queries := []string{
+        `create table if not exists account_invitation (
+            id serial primary key,
+            email varchar(256) not null,
+            invited_by bigint not null default 0,
+            permission varchar(8) not null default '',
+            status varchar(16) not null,
+            account_number bigint not null default 0,
+            token_hash varchar(64) not null unique,
+            expires_at timestamp not null,
+            created_at timestamp not null,
+            accepted_at timestamp
+        )`,
+        `create index if not exists account_invitation_invited_by_idx on account_invitation (invited_by, created_at)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateAccountInvitation(ctx context.Context, inv *types.AccountInvitation) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into account_invitation (email, invited_by, permission, status, token_hash, expires_at, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7)
+        returning id
+    `, inv.Email, inv.InvitedBy, inv.Permission, inv.Status, inv.TokenHash, inv.ExpiresAt, inv.CreatedAt).Scan(&inv.ID)
+}
+
+func (s *PostgresStore) GetAccountInvitations(ctx context.Context, invitedBy int64) ([]*types.AccountInvitation, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+invitationColumns+` from account_invitation where invited_by = $1 order by created_at desc, id desc
+    `, invitedBy)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    invitations := []*types.AccountInvitation{}
+    for rows.Next() {
+        inv, err := scanInvitation(rows)
+        if err != nil {
+            return nil, err
+        }
+        invitations = append(invitations, inv)
+    }
+
+    return invitations, rows.Err()
+}
+
+func (s *PostgresStore) AcceptAccountInvitation(ctx context.Context, tokenHash string, acc *types.Account, now time.Time) (*types.AccountInvitation, error) {
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer dbtx.Rollback()
+
+    inv, err := scanInvitation(dbtx.QueryRowContext(ctx, `
+        select `+invitationColumns+` from account_invitation
+        where token_hash = $1 and status = $2 and expires_at > $3
+        for update
+    `, tokenHash, types.InvitationPending, now))
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("invitation %w", ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if inv.Permission != "" && inv.InvitedBy != 0 {
+        var closedAt sql.NullTime
+        err := dbtx.QueryRowContext(ctx, `select closed_at from account where number = $1 for share`, inv.InvitedBy).Scan(&closedAt)
+        if err != nil && err != sql.ErrNoRows {
+            return nil, err
+        }
+        if err == sql.ErrNoRows || closedAt.Valid {
+            return nil, fmt.Errorf("account %d: %w", inv.InvitedBy, ErrAccountClosed)
+        }
+    }
+
+    acc.Email = inv.Email
+    if err := importAccount(ctx, dbtx, &types.AccountImport{Account: acc}); err != nil {
+        return nil, err
+    }
+
+    if inv.Permission != "" && inv.InvitedBy != 0 {
+        if _, err := dbtx.ExecContext(ctx, `
+            insert into account_owner (account_number, owner_number, permission, created_at)
+            values ($1, $2, $3, $4)
+        `, inv.InvitedBy, acc.Number, inv.Permission, now); err != nil {
+            return nil, err
+        }
+    }
+
+    if _, err := dbtx.ExecContext(ctx, `
+        update account_invitation set status = $1, account_number = $2, accepted_at = $3 where id = $4
+    `, types.InvitationAccepted, acc.Number, now, inv.ID); err != nil {
+        return nil, err
+    }
+
+    if err := dbtx.Commit(); err != nil {
+        return nil, err
+    }
+    inv.Status = types.InvitationAccepted
+    inv.AccountNumber = acc.Number
+    inv.AcceptedAt = &now
+
+    return inv, nil
+}
+
+func scanInvitation(row interface{ Scan(...any) error }) (*types.AccountInvitation, error) {
+    inv := new(types.AccountInvitation)
+    var acceptedAt sql.NullTime
+    err := row.Scan(
+        &inv.ID,
+        &inv.Email,
+        &inv.InvitedBy,
+        &inv.Permission,
+        &inv.Status,
+        &inv.AccountNumber,
+        &inv.TokenHash,
+        &inv.ExpiresAt,
+        &inv.CreatedAt,
+        &acceptedAt,
+    )
+    if err != nil {
+        return nil, err
+    }
+    if acceptedAt.Valid {
+        inv.AcceptedAt = &acceptedAt.Time
+    }
+
+    return inv, nil
+}
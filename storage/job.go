@@ -0,0 +1,116 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+
+    "gobank/types"
+)
+
+type JobStorage interface {
+    // TryLeaderLock takes the named lock, or confirms it still holds it,
+    // and reports whether this instance is the leader. Only one instance
+    // holds a lock at a time, until it exits or loses its connection.
+    TryLeaderLock(ctx context.Context, name string) (bool, error)
+    SaveJobStatus(context.Context, *types.JobStatus) error
+    GetJobStatuses(context.Context) ([]*types.JobStatus, error)
+}
+
+func (s *PostgresStore) CreateJobStatusTable() error {
+    query := `create table if not exists job_status (
+        name varchar(64) primary key,
+        schedule varchar(64) not null,
+        status varchar(16) not null,
+        last_started timestamp,
+        last_finished timestamp,
+        last_count integer not null default 0,
+        last_error text not null default '',
+        next_run timestamp not null
+    )`
+
+    _, err := s.db.Exec(query)
+    return err
+}
+
+// TryLeaderLock holds a session advisory lock on a connection kept out of
+// the pool, so the lock lives as long as that connection does.
+func (s *PostgresStore) TryLeaderLock(ctx context.Context, name string) (bool, error) {
+    s.locksMu.Lock()
+    defer s.locksMu.Unlock()
+
+    if conn, ok := s.locks[name]; ok {
+        if err := conn.PingContext(ctx); err == nil {
+            return true, nil
+        }
+        conn.Close()
+        delete(s.locks, name)
+    }
+
+    conn, err := s.db.Conn(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    var held bool
+    if err := conn.QueryRowContext(ctx, `select pg_try_advisory_lock(hashtext($1))`, name).Scan(&held); err != nil {
+        conn.Close()
+        return false, err
+    }
+    if !held {
+        conn.Close()
+        return false, nil
+    }
+
+    if s.locks == nil {
+        s.locks = map[string]*sql.Conn{}
+    }
+    s.locks[name] = conn
+
+    return true, nil
+}
+
+func (s *PostgresStore) SaveJobStatus(ctx context.Context, j *types.JobStatus) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into job_status (name, schedule, status, last_started, last_finished, last_count, last_error, next_run)
+        values ($1, $2, $3, $4, $5, $6, $7, $8)
+        on conflict (name) do update set
+            schedule = excluded.schedule,
+            status = excluded.status,
+            last_started = excluded.last_started,
+            last_finished = excluded.last_finished,
+            last_count = excluded.last_count,
+            last_error = excluded.last_error,
+            next_run = excluded.next_run
+    `, j.Name, j.Schedule, j.Status, j.LastStarted, j.LastFinished, j.LastCount, j.LastError, j.NextRun)
+
+    return err
+}
+
+func (s *PostgresStore) GetJobStatuses(ctx context.Context) ([]*types.JobStatus, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select name, schedule, status, last_started, last_finished, last_count, last_error, next_run
+        from job_status order by name
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    statuses := []*types.JobStatus{}
+    for rows.Next() {
+        j := new(types.JobStatus)
+        var started, finished sql.NullTime
+        if err := rows.Scan(&j.Name, &j.Schedule, &j.Status, &started, &finished, &j.LastCount, &j.LastError, &j.NextRun); err != nil {
+            return nil, err
+        }
+        if started.Valid {
+            j.LastStarted = &started.Time
+        }
+        if finished.Valid {
+            j.LastFinished = &finished.Time
+        }
+        statuses = append(statuses, j)
+    }
+
+    return statuses, rows.Err()
+}
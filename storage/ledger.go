@@ -0,0 +1,410 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/lib/pq"
+    "gobank/types"
+)
+
+var (
+    ErrInsufficientFunds = errors.New("insufficient funds")
+    ErrNotPending = errors.New("transaction is not pending")
+    ErrVelocityExceeded = errors.New("too many transfers in a short time")
+)
+
+type LedgerStorage interface {
+    // PostTransaction records tx together with its balanced ledger entries
+    // in one database transaction and updates the affected account
+    // balances. It fails with ErrInsufficientFunds when a customer account
+    // would be debited below what it has set aside in pots.
+    PostTransaction(context.Context, *types.Transaction, []*types.LedgerEntry) error
+    // PostTransfer posts a customer transfer like PostTransaction, failing
+    // with ErrVelocityExceeded when the sender's transfers within any of
+    // the limits' windows, this one included, go over its count or amount.
+    PostTransfer(ctx context.Context, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error
+    // PreviewTransfer fails like PostTransfer would, but leaves tx, its
+    // entries and the ledger untouched.
+    PreviewTransfer(ctx context.Context, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error
+    // SettleTransaction moves a pending transaction to status, failing with
+    // ErrNotPending if it was already settled. A reversal, when given, is
+    // posted in the same database transaction so a failed transfer is
+    // returned exactly once.
+    SettleTransaction(ctx context.Context, id int, status string, reversal *types.Transaction, entries []*types.LedgerEntry) error
+    GetLedgerEntries(context.Context) ([]*types.LedgerEntry, error)
+    GetLedgerEntriesByAccount(context.Context, int64) ([]*types.LedgerEntry, error)
+    GetLedgerEntriesByTransaction(context.Context, int) ([]*types.LedgerEntry, error)
+    GetLedgerBalance(context.Context, int64) (int64, error)
+    // GetBalances breaks down the balance of an account at t, which
+    // decides the card holds that are still active.
+    GetBalances(ctx context.Context, number int64, t time.Time) (*types.Balances, error)
+    // GetLedgerBalances sums the entries of every account that has any.
+    GetLedgerBalances(context.Context) (map[int64]int64, error)
+    // GetLedgerSumBetween sums an account's entries created in [from, to).
+    GetLedgerSumBetween(context.Context, int64, time.Time, time.Time) (int64, error)
+}
+
+func (s *PostgresStore) CreateLedgerTable() error {
+    query := `create table if not exists ledger_entry (
+        id serial primary key,
+        transaction_id integer not null references transaction(id),
+        account_number bigint not null,
+        amount bigint not null check (amount <> 0),
+        created_at timestamp
+    )`
+
+    if _, err := s.db.Exec(query); err != nil {
+        return err
+    }
+
+    _, err := s.db.Exec(`create index if not exists ledger_entry_account_number_idx on ledger_entry (account_number)`)
+    return err
+}
+
+func (s *PostgresStore) PostTransaction(ctx context.Context, t *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) PostTransfer(ctx context.Context, t *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    // the sender's row stays locked from here to commit, so concurrent
+    // transfers are counted one after the other
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+    if err := checkVelocity(ctx, dbtx, t, limits); err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) PreviewTransfer(ctx context.Context, t *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    // never committed, posting copies keeps the rolled back ids away from
+    // the caller
+    defer dbtx.Rollback()
+
+    c := *t
+    copies := make([]*types.LedgerEntry, len(entries))
+    for i, e := range entries {
+        e := *e
+        copies[i] = &e
+    }
+    if err := postTransaction(ctx, dbtx, &c, copies); err != nil {
+        return err
+    }
+
+    return checkVelocity(ctx, dbtx, &c, limits)
+}
+
+// checkVelocity fails with ErrVelocityExceeded when t, already posted in
+// dbtx, takes its sender over one of limits.
+func checkVelocity(ctx context.Context, dbtx *sql.Tx, t *types.Transaction, limits []types.VelocityLimit) error {
+    for _, limit := range limits {
+        var count int
+        var sum int64
+        err := dbtx.QueryRowContext(ctx, `
+            select count(*), coalesce(sum(amount), 0) from transaction
+            where from_account = $1 and kind in ($2, $3) and status <> $4 and created_at > $5
+        `, t.FromAccount, types.TransactionTransfer, types.TransactionClosure, types.StatusFailed, t.CreatedAt.Add(-limit.Window)).Scan(&count, &sum)
+        if err != nil {
+            return err
+        }
+        if velocityExceeded(limit, count, sum) {
+            return fmt.Errorf("account %d over %d transfers or %d within %s: %w", t.FromAccount, limit.Count, limit.Amount, limit.Window, ErrVelocityExceeded)
+        }
+    }
+
+    return nil
+}
+
+// velocityExceeded reports whether count transfers adding up to sum go over
+// limit.
+func velocityExceeded(limit types.VelocityLimit, count int, sum int64) bool {
+    return (limit.Count > 0 && count > limit.Count) || (limit.Amount > 0 && sum > limit.Amount)
+}
+
+func (s *PostgresStore) SettleTransaction(ctx context.Context, id int, status string, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    if reversal != nil {
+        if err := types.ValidateEntries(entries); err != nil {
+            return err
+        }
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := settleTransaction(ctx, dbtx, id, status, reversal, entries); err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func settleTransaction(ctx context.Context, dbtx *sql.Tx, id int, status string, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    res, err := dbtx.ExecContext(ctx, `
+        update transaction set status = $1 where id = $2 and status = $3
+    `, status, id, types.StatusPending)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("transaction %d: %w", id, ErrNotPending)
+    }
+
+    if reversal != nil {
+        return postTransaction(ctx, dbtx, reversal, entries)
+    }
+
+    return nil
+}
+
+func postTransaction(ctx context.Context, dbtx *sql.Tx, t *types.Transaction, entries []*types.LedgerEntry) error {
+    deltas := map[int64]int64{}
+    for _, e := range entries {
+        if !types.IsInternalAccount(e.AccountNumber) {
+            deltas[e.AccountNumber] += e.Amount
+        }
+    }
+
+    // lock in a stable order so concurrent postings can't deadlock
+    numbers := make([]int64, 0, len(deltas))
+    for n := range deltas {
+        numbers = append(numbers, n)
+    }
+    sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+    for _, n := range numbers {
+        var balance int64
+        err := dbtx.QueryRowContext(ctx, `
+            select balance from account where number = $1 for update
+        `, n).Scan(&balance)
+        if err == sql.ErrNoRows {
+            return fmt.Errorf("account %d %w", n, ErrNotFound)
+        }
+        if err != nil {
+            return err
+        }
+
+        if deltas[n] < 0 {
+            // money in pots is set aside and money under dispute or card
+            // holds is held, none of it can be spent
+            saved, err := potTotal(ctx, dbtx, n)
+            if err != nil {
+                return err
+            }
+            disputed, err := disputedTotal(ctx, dbtx, n)
+            if err != nil {
+                return err
+            }
+            held, err := heldTotal(ctx, dbtx, n, t.CreatedAt)
+            if err != nil {
+                return err
+            }
+            if balance-saved-disputed-held+deltas[n] < 0 {
+                return fmt.Errorf("account %d: %w", n, ErrInsufficientFunds)
+            }
+        }
+    }
+
+    if t.Status == "" {
+        t.Status = types.StatusCompleted
+    }
+    err := dbtx.QueryRowContext(ctx, `
+        insert into transaction
+        (kind, status, from_account, to_account, amount, provider, reference, idempotency_key, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        returning id
+    `, t.Kind, t.Status, t.FromAccount, t.ToAccount, t.Amount, nullString(t.Provider), nullString(t.Reference), nullString(t.IdempotencyKey), t.CreatedAt).Scan(&t.ID)
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) && pqErr.Constraint == idempotencyKeyIndex {
+        return fmt.Errorf("transaction from account %d: %w", t.FromAccount, ErrIdempotencyKeyUsed)
+    }
+    if err != nil {
+        return err
+    }
+
+    for _, e := range entries {
+        e.TransactionID = t.ID
+        e.CreatedAt = t.CreatedAt
+
+        err := dbtx.QueryRowContext(ctx, `
+            insert into ledger_entry
+            (transaction_id, account_number, amount, created_at)
+            values ($1, $2, $3, $4)
+            returning id
+        `, e.TransactionID, e.AccountNumber, e.Amount, e.CreatedAt).Scan(&e.ID)
+        if err != nil {
+            return err
+        }
+    }
+
+    for _, n := range numbers {
+        _, err := dbtx.ExecContext(ctx, `
+            update account set balance = balance + $1 where number = $2
+        `, deltas[n], n)
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) GetLedgerEntries(ctx context.Context) ([]*types.LedgerEntry, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, transaction_id, account_number, amount, created_at
+        from `+allLedgerEntries+` order by id
+    `)
+    if err != nil {
+        return nil, err
+    }
+    return scanLedgerEntries(rows)
+}
+
+func (s *PostgresStore) GetLedgerEntriesByAccount(ctx context.Context, number int64) ([]*types.LedgerEntry, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, transaction_id, account_number, amount, created_at
+        from `+allLedgerEntries+` where account_number = $1 order by id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanLedgerEntries(rows)
+}
+
+func (s *PostgresStore) GetLedgerEntriesByTransaction(ctx context.Context, id int) ([]*types.LedgerEntry, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, transaction_id, account_number, amount, created_at
+        from `+allLedgerEntries+` where transaction_id = $1 order by id
+    `, id)
+    if err != nil {
+        return nil, err
+    }
+    return scanLedgerEntries(rows)
+}
+
+func (s *PostgresStore) GetLedgerBalance(ctx context.Context, number int64) (int64, error) {
+    var balance int64
+    err := s.db.QueryRowContext(ctx, `
+        select coalesce(sum(amount), 0) from `+allLedgerEntries+` where account_number = $1
+    `, number).Scan(&balance)
+
+    return balance, err
+}
+
+func (s *PostgresStore) GetBalances(ctx context.Context, number int64, t time.Time) (*types.Balances, error) {
+    var balance, pending, holds, disputed, saved int64
+    err := s.db.QueryRowContext(ctx, `
+        select
+            a.balance,
+            (select coalesce(sum(amount), 0) from transaction where from_account = a.number and status = $2),
+            (select coalesce(sum(amount), 0) from card_hold where account_number = a.number and status = $3 and expires_at > $4),
+            (select coalesce(sum(held_amount), 0) from dispute where held_account = a.number and status = $5),
+            (select coalesce(sum(balance), 0) from pot where account_number = a.number)
+        from account a where a.number = $1
+    `, number, types.StatusPending, types.HoldActive, t, types.DisputeOpen).Scan(&balance, &pending, &holds, &disputed, &saved)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("account %d %w", number, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return types.NewBalances(balance, pending, holds+disputed, saved), nil
+}
+
+func (s *PostgresStore) GetLedgerBalances(ctx context.Context) (map[int64]int64, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select account_number, sum(amount) from `+allLedgerEntries+` group by account_number
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    balances := map[int64]int64{}
+    for rows.Next() {
+        var number, balance int64
+        if err := rows.Scan(&number, &balance); err != nil {
+            return nil, err
+        }
+        balances[number] = balance
+    }
+
+    return balances, rows.Err()
+}
+
+func (s *PostgresStore) GetLedgerSumBetween(ctx context.Context, number int64, from, to time.Time) (int64, error) {
+    var sum int64
+    err := s.db.QueryRowContext(ctx, `
+        select coalesce(sum(amount), 0) from `+allLedgerEntries+`
+        where account_number = $1 and created_at >= $2 and created_at < $3
+    `, number, from, to).Scan(&sum)
+
+    return sum, err
+}
+
+func scanLedgerEntries(rows *sql.Rows) ([]*types.LedgerEntry, error) {
+    defer rows.Close()
+
+    entries := []*types.LedgerEntry{}
+    for rows.Next() {
+        e := new(types.LedgerEntry)
+        if err := rows.Scan(
+            &e.ID,
+            &e.TransactionID,
+            &e.AccountNumber,
+            &e.Amount,
+            &e.CreatedAt,
+        ); err != nil {
+            return nil, err
+        }
+        entries = append(entries, e)
+    }
+
+    return entries, rows.Err()
+}
@@ -0,0 +1,318 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+var ErrLoanState = errors.New("loan is not in a state that allows this")
+
+type LoanStorage interface {
+    CreateLoan(context.Context, *types.Loan) error
+    GetLoan(context.Context, int) (*types.Loan, error)
+    GetLoansByAccount(context.Context, int64) ([]*types.Loan, error)
+    GetLoansByStatus(context.Context, string) ([]*types.Loan, error)
+    // RejectLoan and DisburseLoan fail with ErrLoanState unless the loan is
+    // still an application.
+    RejectLoan(context.Context, int) error
+    // DisburseLoan activates the loan, saves its schedule and posts the
+    // disbursement in one database transaction.
+    DisburseLoan(ctx context.Context, loan *types.Loan, schedule []*types.LoanInstallment, tx *types.Transaction, entries []*types.LedgerEntry) error
+    GetLoanSchedule(context.Context, int) ([]*types.LoanInstallment, error)
+    // GetDueInstallments returns unpaid installments of active loans due
+    // at or before t, oldest first.
+    GetDueInstallments(context.Context, time.Time) ([]*types.LoanInstallment, error)
+    // ApplyLoanPayment saves the installment and takes principal off the
+    // loan's outstanding balance, posting tx if it isn't nil, in one
+    // database transaction. The loan is paid off when nothing is left.
+    ApplyLoanPayment(ctx context.Context, inst *types.LoanInstallment, principal int64, tx *types.Transaction, entries []*types.LedgerEntry) error
+}
+
+const (
+    loanColumns = `id, account_number, principal, term_months, rate_bps, status, outstanding, created_at, disbursed_at`
+    installmentColumns = `loan_id, number, due_date, principal, interest, late_fee, principal_paid, interest_paid, late_fee_paid, status`
+)
+
+func (s *PostgresStore) CreateLoanTables() error {
+    queries := []string{
+        `create table if not exists loan (
+            id serial primary key,
+            account_number bigint not null,
+            principal bigint not null check (principal > 0),
+            term_months integer not null,
+            rate_bps integer not null,
+            status varchar(16) not null,
+            outstanding bigint not null default 0,
+            created_at timestamp not null,
+            disbursed_at timestamp
+        )`,
+        `create index if not exists loan_account_number_idx on loan (account_number)`,
+        `create table if not exists loan_installment (
+            loan_id integer not null references loan(id),
+            number integer not null,
+            due_date timestamp not null,
+            principal bigint not null,
+            interest bigint not null,
+            late_fee bigint not null default 0,
+            principal_paid bigint not null default 0,
+            interest_paid bigint not null default 0,
+            late_fee_paid bigint not null default 0,
+            status varchar(16) not null,
+            primary key (loan_id, number)
+        )`,
+        `create index if not exists loan_installment_due_idx on loan_installment (status, due_date)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateLoan(ctx context.Context, l *types.Loan) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into loan
+        (account_number, principal, term_months, rate_bps, status, outstanding, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7)
+        returning id
+    `, l.AccountNumber, l.Principal, l.TermMonths, l.RateBPS, l.Status, l.Outstanding, l.CreatedAt).Scan(&l.ID)
+}
+
+func (s *PostgresStore) GetLoan(ctx context.Context, id int) (*types.Loan, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+loanColumns+` from loan where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    loans, err := scanLoans(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(loans) == 0 {
+        return nil, fmt.Errorf("loan %d %w", id, ErrNotFound)
+    }
+
+    return loans[0], nil
+}
+
+func (s *PostgresStore) GetLoansByAccount(ctx context.Context, number int64) ([]*types.Loan, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+loanColumns+` from loan where account_number = $1 order by id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanLoans(rows)
+}
+
+func (s *PostgresStore) GetLoansByStatus(ctx context.Context, status string) ([]*types.Loan, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+loanColumns+` from loan where status = $1 order by id
+    `, status)
+    if err != nil {
+        return nil, err
+    }
+    return scanLoans(rows)
+}
+
+func (s *PostgresStore) RejectLoan(ctx context.Context, id int) error {
+    res, err := s.db.ExecContext(ctx, `
+        update loan set status = $1 where id = $2 and status = $3
+    `, types.LoanRejected, id, types.LoanApplied)
+    if err != nil {
+        return err
+    }
+
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("loan %d: %w", id, ErrLoanState)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) DisburseLoan(ctx context.Context, l *types.Loan, schedule []*types.LoanInstallment, t *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    res, err := dbtx.ExecContext(ctx, `
+        update loan set status = $1, outstanding = $2, disbursed_at = $3
+        where id = $4 and status = $5
+    `, types.LoanActive, l.Principal, t.CreatedAt, l.ID, types.LoanApplied)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("loan %d: %w", l.ID, ErrLoanState)
+    }
+
+    for _, inst := range schedule {
+        _, err := dbtx.ExecContext(ctx, `
+            insert into loan_installment (loan_id, number, due_date, principal, interest, status)
+            values ($1, $2, $3, $4, $5, $6)
+        `, inst.LoanID, inst.Number, inst.DueDate, inst.Principal, inst.Interest, inst.Status)
+        if err != nil {
+            return err
+        }
+    }
+
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+
+    if err := dbtx.Commit(); err != nil {
+        return err
+    }
+    l.Status = types.LoanActive
+    l.Outstanding = l.Principal
+    l.DisbursedAt = &t.CreatedAt
+
+    return nil
+}
+
+func (s *PostgresStore) GetLoanSchedule(ctx context.Context, loanID int) ([]*types.LoanInstallment, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+installmentColumns+` from loan_installment where loan_id = $1 order by number
+    `, loanID)
+    if err != nil {
+        return nil, err
+    }
+    return scanInstallments(rows)
+}
+
+func (s *PostgresStore) GetDueInstallments(ctx context.Context, t time.Time) ([]*types.LoanInstallment, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select i.loan_id, i.number, i.due_date, i.principal, i.interest, i.late_fee,
+            i.principal_paid, i.interest_paid, i.late_fee_paid, i.status
+        from loan_installment i join loan l on l.id = i.loan_id
+        where l.status = $1 and i.status <> $2 and i.due_date <= $3
+        order by i.due_date, i.loan_id, i.number
+    `, types.LoanActive, types.InstallmentPaid, t)
+    if err != nil {
+        return nil, err
+    }
+    return scanInstallments(rows)
+}
+
+func (s *PostgresStore) ApplyLoanPayment(ctx context.Context, inst *types.LoanInstallment, principal int64, t *types.Transaction, entries []*types.LedgerEntry) error {
+    if t != nil {
+        if err := types.ValidateEntries(entries); err != nil {
+            return err
+        }
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if t != nil {
+        if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+            return err
+        }
+    }
+
+    _, err = dbtx.ExecContext(ctx, `
+        update loan_installment set
+            late_fee = $1,
+            principal_paid = $2,
+            interest_paid = $3,
+            late_fee_paid = $4,
+            status = $5
+        where loan_id = $6 and number = $7
+    `, inst.LateFee, inst.PrincipalPaid, inst.InterestPaid, inst.LateFeePaid, inst.Status, inst.LoanID, inst.Number)
+    if err != nil {
+        return err
+    }
+
+    if principal != 0 {
+        _, err = dbtx.ExecContext(ctx, `
+            update loan set
+                outstanding = outstanding - $1,
+                status = case when outstanding - $1 <= 0 then $2 else status end
+            where id = $3
+        `, principal, types.LoanPaidOff, inst.LoanID)
+        if err != nil {
+            return err
+        }
+    }
+
+    return dbtx.Commit()
+}
+
+func scanLoans(rows *sql.Rows) ([]*types.Loan, error) {
+    defer rows.Close()
+
+    loans := []*types.Loan{}
+    for rows.Next() {
+        l := new(types.Loan)
+        var disbursedAt sql.NullTime
+        if err := rows.Scan(
+            &l.ID,
+            &l.AccountNumber,
+            &l.Principal,
+            &l.TermMonths,
+            &l.RateBPS,
+            &l.Status,
+            &l.Outstanding,
+            &l.CreatedAt,
+            &disbursedAt,
+        ); err != nil {
+            return nil, err
+        }
+        if disbursedAt.Valid {
+            l.DisbursedAt = &disbursedAt.Time
+        }
+        loans = append(loans, l)
+    }
+
+    return loans, rows.Err()
+}
+
+func scanInstallments(rows *sql.Rows) ([]*types.LoanInstallment, error) {
+    defer rows.Close()
+
+    installments := []*types.LoanInstallment{}
+    for rows.Next() {
+        i := new(types.LoanInstallment)
+        if err := rows.Scan(
+            &i.LoanID,
+            &i.Number,
+            &i.DueDate,
+            &i.Principal,
+            &i.Interest,
+            &i.LateFee,
+            &i.PrincipalPaid,
+            &i.InterestPaid,
+            &i.LateFeePaid,
+            &i.Status,
+        ); err != nil {
+            return nil, err
+        }
+        installments = append(installments, i)
+    }
+
+    return installments, rows.Err()
+}
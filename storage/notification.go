@@ -0,0 +1,156 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "fmt"
+
+    "gobank/types"
+)
+
+type NotificationStorage interface {
+    // GetNotificationPreferences returns the defaults for accounts that
+    // never saved any.
+    GetNotificationPreferences(context.Context, int64) (*types.NotificationPreferences, error)
+    // SavePreferences saves the notification preferences and the account's
+    // locale together.
+    SavePreferences(context.Context, *types.Preferences) error
+
+    SavePhoneVerification(context.Context, *types.PhoneVerification) error
+    GetPhoneVerification(context.Context, int64) (*types.PhoneVerification, error)
+    DeletePhoneVerification(context.Context, int64) error
+}
+
+func (s *PostgresStore) CreateNotificationTables() error {
+    queries := []string{
+        `create table if not exists notification_preference (
+            account_number bigint primary key,
+            email boolean not null,
+            sms boolean not null
+        )`,
+        `alter table notification_preference add column if not exists events jsonb not null default '{}'`,
+        `alter table notification_preference add column if not exists statement_delivery varchar(16) not null default '` + types.StatementByEmail + `'`,
+        `alter table notification_preference add column if not exists marketing_opt_in boolean not null default false`,
+        `create table if not exists phone_verification (
+            account_number bigint primary key,
+            phone varchar(32) not null,
+            code_hash varchar(64) not null,
+            attempts integer not null default 0,
+            expires_at timestamp not null
+        )`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) GetNotificationPreferences(ctx context.Context, number int64) (*types.NotificationPreferences, error) {
+    prefs := &types.NotificationPreferences{AccountNumber: number}
+    var events []byte
+    err := s.db.QueryRowContext(ctx, `
+        select email, sms, events, statement_delivery, marketing_opt_in
+        from notification_preference where account_number = $1
+    `, number).Scan(&prefs.Email, &prefs.SMS, &events, &prefs.StatementDelivery, &prefs.MarketingOptIn)
+    if err == sql.ErrNoRows {
+        return types.DefaultNotificationPreferences(number), nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if err := json.Unmarshal(events, &prefs.Events); err != nil {
+        return nil, err
+    }
+    if len(prefs.Events) == 0 {
+        prefs.Events = nil
+    }
+
+    return prefs, nil
+}
+
+func (s *PostgresStore) SavePreferences(ctx context.Context, prefs *types.Preferences) error {
+    events := []byte("{}")
+    if len(prefs.Events) > 0 {
+        var err error
+        if events, err = json.Marshal(prefs.Events); err != nil {
+            return err
+        }
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    res, err := dbtx.ExecContext(ctx, `update account set locale = $1 where number = $2`, prefs.Locale, prefs.AccountNumber)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("account %d %w", prefs.AccountNumber, ErrNotFound)
+    }
+
+    _, err = dbtx.ExecContext(ctx, `
+        insert into notification_preference (account_number, email, sms, events, statement_delivery, marketing_opt_in)
+        values ($1, $2, $3, $4, $5, $6)
+        on conflict (account_number) do update set
+            email = excluded.email,
+            sms = excluded.sms,
+            events = excluded.events,
+            statement_delivery = excluded.statement_delivery,
+            marketing_opt_in = excluded.marketing_opt_in
+    `, prefs.AccountNumber, prefs.Email, prefs.SMS, events, prefs.StatementDelivery, prefs.MarketingOptIn)
+    if err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) SavePhoneVerification(ctx context.Context, v *types.PhoneVerification) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into phone_verification (account_number, phone, code_hash, attempts, expires_at)
+        values ($1, $2, $3, $4, $5)
+        on conflict (account_number) do update set
+            phone = excluded.phone,
+            code_hash = excluded.code_hash,
+            attempts = excluded.attempts,
+            expires_at = excluded.expires_at
+    `, v.AccountNumber, v.Phone, v.CodeHash, v.Attempts, v.ExpiresAt)
+
+    return err
+}
+
+func (s *PostgresStore) GetPhoneVerification(ctx context.Context, number int64) (*types.PhoneVerification, error) {
+    v := &types.PhoneVerification{AccountNumber: number}
+    err := s.db.QueryRowContext(ctx, `
+        select phone, code_hash, attempts, expires_at
+        from phone_verification where account_number = $1
+    `, number).Scan(&v.Phone, &v.CodeHash, &v.Attempts, &v.ExpiresAt)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("phone verification for account %d %w", number, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return v, nil
+}
+
+func (s *PostgresStore) DeletePhoneVerification(ctx context.Context, number int64) error {
+    _, err := s.db.ExecContext(ctx, `
+        delete from phone_verification where account_number = $1
+    `, number)
+
+    return err
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+
+    "gobank/types"
+)
+
+var ErrInvitationClosed = errors.New("invitation is no longer pending")
+
+type OwnerStorage interface {
+    CreateOwnerInvitation(context.Context, *types.OwnerInvitation) error
+    GetOwnerInvitation(context.Context, int) (*types.OwnerInvitation, error)
+    // GetOwnerInvitationsFor returns the pending invitations sent to an
+    // account.
+    GetOwnerInvitationsFor(context.Context, int64) ([]*types.OwnerInvitation, error)
+    // AcceptOwnerInvitation adds the invitee as an owner and marks the
+    // invitation accepted in one database transaction. Both fail with
+    // ErrInvitationClosed unless the invitation is still pending.
+    AcceptOwnerInvitation(context.Context, int) (*types.AccountOwner, error)
+    DeclineOwnerInvitation(context.Context, int) error
+    GetAccountOwners(context.Context, int64) ([]*types.AccountOwner, error)
+    GetAccountOwner(ctx context.Context, account, owner int64) (*types.AccountOwner, error)
+    DeleteAccountOwner(ctx context.Context, account, owner int64) error
+}
+
+func (s *PostgresStore) CreateOwnerTables() error {
+    queries := []string{
+        `create table if not exists account_owner (
+            account_number bigint not null,
+            owner_number bigint not null,
+            permission varchar(8) not null,
+            created_at timestamp not null,
+            primary key (account_number, owner_number)
+        )`,
+        `create table if not exists owner_invitation (
+            id serial primary key,
+            account_number bigint not null,
+            invitee_number bigint not null,
+            permission varchar(8) not null,
+            status varchar(16) not null,
+            created_at timestamp not null
+        )`,
+        `create index if not exists owner_invitation_invitee_idx on owner_invitation (invitee_number, status)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateOwnerInvitation(ctx context.Context, inv *types.OwnerInvitation) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into owner_invitation (account_number, invitee_number, permission, status, created_at)
+        values ($1, $2, $3, $4, $5)
+        returning id
+    `, inv.AccountNumber, inv.InviteeNumber, inv.Permission, inv.Status, inv.CreatedAt).Scan(&inv.ID)
+}
+
+func (s *PostgresStore) GetOwnerInvitation(ctx context.Context, id int) (*types.OwnerInvitation, error) {
+    inv := new(types.OwnerInvitation)
+    err := s.db.QueryRowContext(ctx, `
+        select id, account_number, invitee_number, permission, status, created_at
+        from owner_invitation where id = $1
+    `, id).Scan(&inv.ID, &inv.AccountNumber, &inv.InviteeNumber, &inv.Permission, &inv.Status, &inv.CreatedAt)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("invitation %d %w", id, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return inv, nil
+}
+
+func (s *PostgresStore) GetOwnerInvitationsFor(ctx context.Context, number int64) ([]*types.OwnerInvitation, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, account_number, invitee_number, permission, status, created_at
+        from owner_invitation where invitee_number = $1 and status = $2
+        order by id
+    `, number, types.InvitationPending)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    invitations := []*types.OwnerInvitation{}
+    for rows.Next() {
+        inv := new(types.OwnerInvitation)
+        if err := rows.Scan(&inv.ID, &inv.AccountNumber, &inv.InviteeNumber, &inv.Permission, &inv.Status, &inv.CreatedAt); err != nil {
+            return nil, err
+        }
+        invitations = append(invitations, inv)
+    }
+
+    return invitations, rows.Err()
+}
+
+func (s *PostgresStore) AcceptOwnerInvitation(ctx context.Context, id int) (*types.AccountOwner, error) {
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer dbtx.Rollback()
+
+    owner := new(types.AccountOwner)
+    err = dbtx.QueryRowContext(ctx, `
+        update owner_invitation set status = $1 where id = $2 and status = $3
+        returning account_number, invitee_number, permission
+    `, types.InvitationAccepted, id, types.InvitationPending).Scan(&owner.AccountNumber, &owner.OwnerNumber, &owner.Permission)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("invitation %d: %w", id, ErrInvitationClosed)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    // accepting a second invitation to the same account changes the
+    // permission instead of failing
+    err = dbtx.QueryRowContext(ctx, `
+        insert into account_owner (account_number, owner_number, permission, created_at)
+        values ($1, $2, $3, now())
+        on conflict (account_number, owner_number) do update set permission = excluded.permission
+        returning created_at
+    `, owner.AccountNumber, owner.OwnerNumber, owner.Permission).Scan(&owner.CreatedAt)
+    if err != nil {
+        return nil, err
+    }
+
+    return owner, dbtx.Commit()
+}
+
+func (s *PostgresStore) DeclineOwnerInvitation(ctx context.Context, id int) error {
+    res, err := s.db.ExecContext(ctx, `
+        update owner_invitation set status = $1 where id = $2 and status = $3
+    `, types.InvitationDeclined, id, types.InvitationPending)
+    if err != nil {
+        return err
+    }
+
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("invitation %d: %w", id, ErrInvitationClosed)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) GetAccountOwners(ctx context.Context, number int64) ([]*types.AccountOwner, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select account_number, owner_number, permission, created_at
+        from account_owner where account_number = $1
+        order by created_at
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    owners := []*types.AccountOwner{}
+    for rows.Next() {
+        o := new(types.AccountOwner)
+        if err := rows.Scan(&o.AccountNumber, &o.OwnerNumber, &o.Permission, &o.CreatedAt); err != nil {
+            return nil, err
+        }
+        owners = append(owners, o)
+    }
+
+    return owners, rows.Err()
+}
+
+func (s *PostgresStore) GetAccountOwner(ctx context.Context, account, owner int64) (*types.AccountOwner, error) {
+    o := new(types.AccountOwner)
+    err := s.db.QueryRowContext(ctx, `
+        select account_number, owner_number, permission, created_at
+        from account_owner where account_number = $1 and owner_number = $2
+    `, account, owner).Scan(&o.AccountNumber, &o.OwnerNumber, &o.Permission, &o.CreatedAt)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("owner %d of account %d %w", owner, account, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return o, nil
+}
+
+func (s *PostgresStore) DeleteAccountOwner(ctx context.Context, account, owner int64) error {
+    _, err := s.db.ExecContext(ctx, `
+        delete from account_owner where account_number = $1 and owner_number = $2
+    `, account, owner)
+    return err
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+
+    "gobank/types"
+)
+
+var ErrRequestClosed = errors.New("payment request is no longer pending")
+
+type PaymentRequestStorage interface {
+    CreatePaymentRequest(context.Context, *types.PaymentRequest) error
+    GetPaymentRequest(context.Context, int) (*types.PaymentRequest, error)
+    // GetPaymentRequestsByAccount returns requests the account sent or
+    // received, newest first.
+    GetPaymentRequestsByAccount(context.Context, int64) ([]*types.PaymentRequest, error)
+    // AcceptPaymentRequest posts the payment, held to limits like
+    // PostTransfer, and marks the request accepted in one database
+    // transaction. Both fail with ErrRequestClosed unless the request is
+    // still pending.
+    AcceptPaymentRequest(ctx context.Context, id int, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error
+    ClosePaymentRequest(ctx context.Context, id int, status string) error
+}
+
+const paymentRequestColumns = `id, requester_account, payer_account, amount, memo, status, coalesce(transaction_id, 0), expires_at, created_at`
+
+func (s *PostgresStore) CreatePaymentRequestTable() error {
+    queries := []string{
+        `create table if not exists payment_request (
+            id serial primary key,
+            requester_account bigint not null,
+            payer_account bigint not null,
+            amount bigint not null check (amount > 0),
+            memo varchar(280) not null default '',
+            status varchar(16) not null,
+            transaction_id integer references transaction(id),
+            expires_at timestamp not null,
+            created_at timestamp not null
+        )`,
+        `create index if not exists payment_request_requester_idx on payment_request (requester_account)`,
+        `create index if not exists payment_request_payer_idx on payment_request (payer_account)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreatePaymentRequest(ctx context.Context, pr *types.PaymentRequest) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into payment_request
+        (requester_account, payer_account, amount, memo, status, expires_at, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7)
+        returning id
+    `, pr.RequesterAccount, pr.PayerAccount, pr.Amount, pr.Memo, pr.Status, pr.ExpiresAt, pr.CreatedAt).Scan(&pr.ID)
+}
+
+func (s *PostgresStore) GetPaymentRequest(ctx context.Context, id int) (*types.PaymentRequest, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+paymentRequestColumns+` from payment_request where id = $1
+    `, id)
+    if err != nil {
+        return nil, err
+    }
+
+    prs, err := scanPaymentRequests(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(prs) == 0 {
+        return nil, fmt.Errorf("payment request %d %w", id, ErrNotFound)
+    }
+
+    return prs[0], nil
+}
+
+func (s *PostgresStore) GetPaymentRequestsByAccount(ctx context.Context, number int64) ([]*types.PaymentRequest, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+paymentRequestColumns+`
+        from payment_request
+        where requester_account = $1 or payer_account = $1
+        order by created_at desc, id desc
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanPaymentRequests(rows)
+}
+
+func (s *PostgresStore) AcceptPaymentRequest(ctx context.Context, id int, t *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    var status string
+    err = dbtx.QueryRowContext(ctx, `
+        select status from payment_request where id = $1 for update
+    `, id).Scan(&status)
+    if err == sql.ErrNoRows {
+        return fmt.Errorf("payment request %d %w", id, ErrNotFound)
+    }
+    if err != nil {
+        return err
+    }
+    if status != types.RequestPending {
+        return fmt.Errorf("payment request %d: %w", id, ErrRequestClosed)
+    }
+
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+    if err := checkVelocity(ctx, dbtx, t, limits); err != nil {
+        return err
+    }
+
+    _, err = dbtx.ExecContext(ctx, `
+        update payment_request set status = $1, transaction_id = $2 where id = $3
+    `, types.RequestAccepted, t.ID, id)
+    if err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) ClosePaymentRequest(ctx context.Context, id int, status string) error {
+    res, err := s.db.ExecContext(ctx, `
+        update payment_request set status = $1 where id = $2 and status = $3
+    `, status, id, types.RequestPending)
+    if err != nil {
+        return err
+    }
+
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("payment request %d: %w", id, ErrRequestClosed)
+    }
+
+    return nil
+}
+
+func scanPaymentRequests(rows *sql.Rows) ([]*types.PaymentRequest, error) {
+    defer rows.Close()
+
+    prs := []*types.PaymentRequest{}
+    for rows.Next() {
+        pr := new(types.PaymentRequest)
+        if err := rows.Scan(
+            &pr.ID,
+            &pr.RequesterAccount,
+            &pr.PayerAccount,
+            &pr.Amount,
+            &pr.Memo,
+            &pr.Status,
+            &pr.TransactionID,
+            &pr.ExpiresAt,
+            &pr.CreatedAt,
+        ); err != nil {
+            return nil, err
+        }
+        prs = append(prs, pr)
+    }
+
+    return prs, rows.Err()
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+type PotStorage interface {
+    CreatePot(context.Context, *types.Pot) error
+    GetPot(context.Context, int) (*types.Pot, error)
+    GetPotsByAccount(context.Context, int64) ([]*types.Pot, error)
+    // UpdatePot saves the name, target and round up setting. The balance
+    // only changes through MovePotMoney.
+    UpdatePot(context.Context, *types.Pot) error
+    // DeletePot removes the pot, which releases its balance back to the
+    // account.
+    DeletePot(context.Context, int) error
+    // MovePotMoney moves amount from the account's spendable balance into
+    // the pot, or out of it when amount is negative. It fails with
+    // ErrInsufficientFunds when either side doesn't have enough.
+    MovePotMoney(ctx context.Context, id int, amount int64) (*types.Pot, error)
+}
+
+func (s *PostgresStore) CreatePotTable() error {
+    query := `create table if not exists pot (
+        id serial primary key,
+        account_number bigint not null,
+        name varchar(100) not null,
+        target bigint not null,
+        balance bigint not null default 0 check (balance >= 0),
+        round_up boolean not null default false,
+        created_at timestamp not null
+    )`
+
+    if _, err := s.db.Exec(query); err != nil {
+        return err
+    }
+
+    _, err := s.db.Exec(`create index if not exists pot_account_number_idx on pot (account_number)`)
+    return err
+}
+
+func (s *PostgresStore) CreatePot(ctx context.Context, p *types.Pot) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into pot (account_number, name, target, round_up, created_at)
+        values ($1, $2, $3, $4, $5)
+        returning id
+    `, p.AccountNumber, p.Name, p.Target, p.RoundUp, p.CreatedAt).Scan(&p.ID)
+}
+
+func (s *PostgresStore) GetPot(ctx context.Context, id int) (*types.Pot, error) {
+    p := new(types.Pot)
+    err := s.db.QueryRowContext(ctx, `
+        select id, account_number, name, target, balance, round_up, created_at
+        from pot where id = $1
+    `, id).Scan(&p.ID, &p.AccountNumber, &p.Name, &p.Target, &p.Balance, &p.RoundUp, &p.CreatedAt)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("pot %d %w", id, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return p, nil
+}
+
+func (s *PostgresStore) GetPotsByAccount(ctx context.Context, number int64) ([]*types.Pot, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, account_number, name, target, balance, round_up, created_at
+        from pot where account_number = $1 order by id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    pots := []*types.Pot{}
+    for rows.Next() {
+        p := new(types.Pot)
+        if err := rows.Scan(&p.ID, &p.AccountNumber, &p.Name, &p.Target, &p.Balance, &p.RoundUp, &p.CreatedAt); err != nil {
+            return nil, err
+        }
+        pots = append(pots, p)
+    }
+
+    return pots, rows.Err()
+}
+
+func (s *PostgresStore) UpdatePot(ctx context.Context, p *types.Pot) error {
+    res, err := s.db.ExecContext(ctx, `
+        update pot set name = $1, target = $2, round_up = $3 where id = $4
+    `, p.Name, p.Target, p.RoundUp, p.ID)
+    if err != nil {
+        return err
+    }
+
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("pot %d %w", p.ID, ErrNotFound)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) DeletePot(ctx context.Context, id int) error {
+    _, err := s.db.ExecContext(ctx, `delete from pot where id = $1`, id)
+    return err
+}
+
+func (s *PostgresStore) MovePotMoney(ctx context.Context, id int, amount int64) (*types.Pot, error) {
+    p, err := s.GetPot(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer dbtx.Rollback()
+
+    // the account row lock serializes moves with postings and holds
+    var balance int64
+    err = dbtx.QueryRowContext(ctx, `
+        select balance from account where number = $1 for update
+    `, p.AccountNumber).Scan(&balance)
+    if err != nil {
+        return nil, err
+    }
+
+    if amount > 0 {
+        saved, err := potTotal(ctx, dbtx, p.AccountNumber)
+        if err != nil {
+            return nil, err
+        }
+        var held int64
+        err = dbtx.QueryRowContext(ctx, `
+            select coalesce(sum(amount), 0) from card_hold
+            where account_number = $1 and status = $2 and expires_at > $3
+        `, p.AccountNumber, types.HoldActive, time.Now().UTC()).Scan(&held)
+        if err != nil {
+            return nil, err
+        }
+        disputed, err := disputedTotal(ctx, dbtx, p.AccountNumber)
+        if err != nil {
+            return nil, err
+        }
+        if balance-saved-disputed-held < amount {
+            return nil, fmt.Errorf("account %d: %w", p.AccountNumber, ErrInsufficientFunds)
+        }
+    }
+
+    err = dbtx.QueryRowContext(ctx, `
+        update pot set balance = balance + $1 where id = $2 and balance + $1 >= 0
+        returning balance
+    `, amount, id).Scan(&p.Balance)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("pot %d: %w", id, ErrInsufficientFunds)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return p, dbtx.Commit()
+}
+
+// potTotal is how much of an account's balance is set aside in pots.
+func potTotal(ctx context.Context, dbtx *sql.Tx, number int64) (int64, error) {
+    var total int64
+    err := dbtx.QueryRowContext(ctx, `
+        select coalesce(sum(balance), 0) from pot where account_number = $1
+    `, number).Scan(&total)
+    return total, err
+}
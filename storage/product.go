@@ -0,0 +1,152 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+var ErrRateInEffect = errors.New("rate is already in effect")
+
+type ProductStorage interface {
+    CreateProductRate(context.Context, *types.ProductRate) error
+    GetProductRate(context.Context, int) (*types.ProductRate, error)
+    // GetProductRates returns every rate ordered by key and effective date.
+    GetProductRates(context.Context) ([]*types.ProductRate, error)
+    // GetEffectiveProductRate returns the rate for key in effect at t.
+    GetEffectiveProductRate(ctx context.Context, key string, t time.Time) (*types.ProductRate, error)
+    // UpdateProductRate and DeleteProductRate fail with ErrRateInEffect
+    // once the rate took effect at now, so history can't be rewritten.
+    UpdateProductRate(ctx context.Context, rate *types.ProductRate, now time.Time) error
+    DeleteProductRate(ctx context.Context, id int, now time.Time) error
+}
+
+const productRateColumns = `id, key, value, effective_from, created_at`
+
+func (s *PostgresStore) CreateProductRateTable() error {
+    query := `create table if not exists product_rate (
+        id serial primary key,
+        key varchar(64) not null,
+        value bigint not null,
+        effective_from timestamp not null,
+        created_at timestamp not null,
+        unique (key, effective_from)
+    )`
+
+    _, err := s.db.Exec(query)
+    return err
+}
+
+func (s *PostgresStore) CreateProductRate(ctx context.Context, rate *types.ProductRate) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into product_rate (key, value, effective_from, created_at)
+        values ($1, $2, $3, $4)
+        returning id
+    `, rate.Key, rate.Value, rate.EffectiveFrom, rate.CreatedAt).Scan(&rate.ID)
+}
+
+func (s *PostgresStore) GetProductRate(ctx context.Context, id int) (*types.ProductRate, error) {
+    rows, err := s.db.QueryContext(ctx, `select `+productRateColumns+` from product_rate where id = $1`, id)
+    if err != nil {
+        return nil, err
+    }
+
+    rates, err := scanProductRates(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(rates) == 0 {
+        return nil, fmt.Errorf("product rate %d %w", id, ErrNotFound)
+    }
+
+    return rates[0], nil
+}
+
+func (s *PostgresStore) GetProductRates(ctx context.Context) ([]*types.ProductRate, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+productRateColumns+` from product_rate order by key, effective_from
+    `)
+    if err != nil {
+        return nil, err
+    }
+    return scanProductRates(rows)
+}
+
+func (s *PostgresStore) GetEffectiveProductRate(ctx context.Context, key string, t time.Time) (*types.ProductRate, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+productRateColumns+` from product_rate
+        where key = $1 and effective_from <= $2
+        order by effective_from desc
+        limit 1
+    `, key, t)
+    if err != nil {
+        return nil, err
+    }
+
+    rates, err := scanProductRates(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(rates) == 0 {
+        return nil, fmt.Errorf("product rate %s %w", key, ErrNotFound)
+    }
+
+    return rates[0], nil
+}
+
+func (s *PostgresStore) UpdateProductRate(ctx context.Context, rate *types.ProductRate, now time.Time) error {
+    res, err := s.db.ExecContext(ctx, `
+        update product_rate set value = $1, effective_from = $2
+        where id = $3 and effective_from > $4
+    `, rate.Value, rate.EffectiveFrom, rate.ID, now)
+    if err != nil {
+        return err
+    }
+    return s.futureRateChanged(ctx, res, rate.ID)
+}
+
+func (s *PostgresStore) DeleteProductRate(ctx context.Context, id int, now time.Time) error {
+    res, err := s.db.ExecContext(ctx, `
+        delete from product_rate where id = $1 and effective_from > $2
+    `, id, now)
+    if err != nil {
+        return err
+    }
+    return s.futureRateChanged(ctx, res, id)
+}
+
+// futureRateChanged tells apart a rate that doesn't exist from one that is
+// already in effect when an update or delete matched nothing.
+func (s *PostgresStore) futureRateChanged(ctx context.Context, res sql.Result, id int) error {
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n > 0 {
+        return nil
+    }
+
+    if _, err := s.GetProductRate(ctx, id); err != nil {
+        return err
+    }
+    return fmt.Errorf("product rate %d: %w", id, ErrRateInEffect)
+}
+
+func scanProductRates(rows *sql.Rows) ([]*types.ProductRate, error) {
+    defer rows.Close()
+
+    rates := []*types.ProductRate{}
+    for rows.Next() {
+        r := new(types.ProductRate)
+        if err := rows.Scan(&r.ID, &r.Key, &r.Value, &r.EffectiveFrom, &r.CreatedAt); err != nil {
+            return nil, err
+        }
+        rates = append(rates, r)
+    }
+
+    return rates, rows.Err()
+}
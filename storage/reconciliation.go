@@ -0,0 +1,74 @@
+package storage
+
+import (
+    "context"
+    "time"
+
+    "gobank/types"
+)
+
+type ReconciliationStorage interface {
+    CreateDiscrepancy(context.Context, *types.Discrepancy) error
+    ResolveDiscrepancy(context.Context, int, time.Time) error
+    GetDiscrepancies(context.Context) ([]*types.Discrepancy, error)
+}
+
+func (s *PostgresStore) CreateReconciliationTable() error {
+    query := `create table if not exists reconciliation (
+        id serial primary key,
+        account_number bigint not null,
+        stored_balance bigint not null,
+        ledger_balance bigint not null,
+        detected_at timestamp not null,
+        resolved_at timestamp
+    )`
+
+    _, err := s.db.Exec(query)
+    return err
+}
+
+func (s *PostgresStore) CreateDiscrepancy(ctx context.Context, d *types.Discrepancy) error {
+    return s.db.QueryRowContext(ctx, `
+        insert into reconciliation
+        (account_number, stored_balance, ledger_balance, detected_at)
+        values ($1, $2, $3, $4)
+        returning id
+    `, d.AccountNumber, d.StoredBalance, d.LedgerBalance, d.DetectedAt).Scan(&d.ID)
+}
+
+func (s *PostgresStore) ResolveDiscrepancy(ctx context.Context, id int, at time.Time) error {
+    _, err := s.db.ExecContext(ctx, `
+        update reconciliation set resolved_at = $1 where id = $2
+    `, at, id)
+
+    return err
+}
+
+func (s *PostgresStore) GetDiscrepancies(ctx context.Context) ([]*types.Discrepancy, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, account_number, stored_balance, ledger_balance, detected_at, resolved_at
+        from reconciliation order by id
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    discrepancies := []*types.Discrepancy{}
+    for rows.Next() {
+        d := new(types.Discrepancy)
+        if err := rows.Scan(
+            &d.ID,
+            &d.AccountNumber,
+            &d.StoredBalance,
+            &d.LedgerBalance,
+            &d.DetectedAt,
+            &d.ResolvedAt,
+        ); err != nil {
+            return nil, err
+        }
+        discrepancies = append(discrepancies, d)
+    }
+
+    return discrepancies, rows.Err()
+}
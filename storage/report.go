@@ -0,0 +1,75 @@
+package storage
+
+import (
+    "context"
+    "time"
+
+    "gobank/types"
+)
+
+// ReportStorage aggregates in the database for the operator reports. All
+// periods are [from, to).
+type ReportStorage interface {
+    // GetDailyFlows returns a row for every UTC day with ledger entries.
+    GetDailyFlows(ctx context.Context, from, to time.Time) ([]*types.DailyFlow, error)
+    GetActiveAccountCount(ctx context.Context, from, to time.Time) (int, error)
+    // GetLargestTransfers returns up to limit transfers that didn't fail,
+    // largest first.
+    GetLargestTransfers(ctx context.Context, from, to time.Time, limit int) ([]*types.Transaction, error)
+}
+
+func (s *PostgresStore) GetDailyFlows(ctx context.Context, from, to time.Time) ([]*types.DailyFlow, error) {
+    // money comes in when the settlement account is debited and leaves
+    // when it is credited
+    rows, err := s.db.QueryContext(ctx, `
+        select
+            date_trunc('day', created_at) as day,
+            coalesce(-sum(amount) filter (where account_number = $1 and amount < 0), 0),
+            coalesce(sum(amount) filter (where account_number = $1 and amount > 0), 0),
+            count(distinct account_number) filter (where account_number > 0)
+        from `+allLedgerEntries+`
+        where created_at >= $2 and created_at < $3
+        group by day
+        order by day
+    `, types.SettlementAccountNumber, from, to)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    flows := []*types.DailyFlow{}
+    for rows.Next() {
+        f := new(types.DailyFlow)
+        if err := rows.Scan(&f.Day, &f.Deposits, &f.Withdrawals, &f.ActiveAccounts); err != nil {
+            return nil, err
+        }
+        f.Net = f.Deposits - f.Withdrawals
+        flows = append(flows, f)
+    }
+
+    return flows, rows.Err()
+}
+
+func (s *PostgresStore) GetActiveAccountCount(ctx context.Context, from, to time.Time) (int, error) {
+    var n int
+    err := s.db.QueryRowContext(ctx, `
+        select count(distinct account_number) from `+allLedgerEntries+`
+        where account_number > 0 and created_at >= $1 and created_at < $2
+    `, from, to).Scan(&n)
+
+    return n, err
+}
+
+func (s *PostgresStore) GetLargestTransfers(ctx context.Context, from, to time.Time, limit int) ([]*types.Transaction, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+transactionColumns+`
+        from `+allTransactions+`
+        where kind = $1 and status <> $2 and created_at >= $3 and created_at < $4
+        order by amount desc, id
+        limit $5
+    `, types.TransactionTransfer, types.StatusFailed, from, to, limit)
+    if err != nil {
+        return nil, err
+    }
+    return scanTransactions(rows)
+}
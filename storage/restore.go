@@ -0,0 +1,169 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "strings"
+
+    "gobank/types"
+)
+
+// RestoreStorage saves what a backup holds as it was. Transactions are
+// restored before these, so nothing here posts money or checks the
+// state the methods that created the records would check, and IDs of
+// transactions must already be the restored ones.
+type RestoreStorage interface {
+    // RestoreLoan creates the loan with its schedule, moving the schedule
+    // to the loan's new ID.
+    RestoreLoan(ctx context.Context, l *types.Loan, schedule []*types.LoanInstallment) error
+    RestoreDispute(context.Context, *types.Dispute) error
+    RestoreAliasClaim(context.Context, *types.AliasClaim) error
+    // RestoreExternalTransfer records where transaction et.ID went; the
+    // rest of et comes from the transaction.
+    RestoreExternalTransfer(context.Context, *types.ExternalTransfer) error
+    RestorePaymentRequest(context.Context, *types.PaymentRequest) error
+    RestoreFraudCase(context.Context, *types.FraudCase) error
+    RestoreReversal(context.Context, *types.Reversal) error
+    // ClearRestored deletes everything a restore creates, so a restore
+    // that failed part way leaves an empty store behind.
+    ClearRestored(context.Context) error
+}
+
+// restoredTables are the tables a restore writes to.
+var restoredTables = []string{
+    "account",
+    "transaction",
+    "ledger_entry",
+    "alias",
+    "alias_claim",
+    "card",
+    "card_hold",
+    "pot",
+    "account_owner",
+    "owner_invitation",
+    "account_invitation",
+    "alert_rule",
+    "access_grant",
+    "signing_key",
+    "notification_preference",
+    "audit_log",
+    "loan",
+    "loan_installment",
+    "dispute",
+    "document",
+    "external_transfer",
+    "payment_request",
+    "fraud_case",
+    "product_rate",
+    "transaction_reversal",
+}
+
+func (s *PostgresStore) RestoreLoan(ctx context.Context, l *types.Loan, schedule []*types.LoanInstallment) error {
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    err = dbtx.QueryRowContext(ctx, `
+        insert into loan
+        (account_number, principal, term_months, rate_bps, status, outstanding, created_at, disbursed_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8)
+        returning id
+    `, l.AccountNumber, l.Principal, l.TermMonths, l.RateBPS, l.Status, l.Outstanding, l.CreatedAt, l.DisbursedAt).Scan(&l.ID)
+    if err != nil {
+        return err
+    }
+
+    for _, inst := range schedule {
+        inst.LoanID = l.ID
+        _, err := dbtx.ExecContext(ctx, `
+            insert into loan_installment
+            (loan_id, number, due_date, principal, interest, late_fee, principal_paid, interest_paid, late_fee_paid, status)
+            values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        `, inst.LoanID, inst.Number, inst.DueDate, inst.Principal, inst.Interest, inst.LateFee, inst.PrincipalPaid, inst.InterestPaid, inst.LateFeePaid, inst.Status)
+        if err != nil {
+            return err
+        }
+    }
+
+    return dbtx.Commit()
+}
+
+func (s *PostgresStore) RestoreDispute(ctx context.Context, d *types.Dispute) error {
+    evidence, err := json.Marshal(d.Evidence)
+    if err != nil {
+        return err
+    }
+    documents, err := json.Marshal(d.Documents)
+    if err != nil {
+        return err
+    }
+
+    return s.db.QueryRowContext(ctx, `
+        insert into dispute
+        (transaction_id, account_number, held_account, held_amount, reason, description, evidence, documents, status, resolution, reversal_id, created_at, resolved_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        returning id
+    `, d.TransactionID, d.AccountNumber, d.HeldAccount, d.HeldAmount, d.Reason, d.Description, evidence, documents, d.Status, d.Resolution, d.ReversalID, d.CreatedAt, d.ResolvedAt).Scan(&d.ID)
+}
+
+func (s *PostgresStore) RestoreAliasClaim(ctx context.Context, c *types.AliasClaim) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into alias_claim (transaction_id, alias, expires_at)
+        values ($1, $2, $3)
+    `, c.TransactionID, c.Alias, c.ExpiresAt)
+    return err
+}
+
+func (s *PostgresStore) RestoreExternalTransfer(ctx context.Context, et *types.ExternalTransfer) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into external_transfer (transaction_id, routing_number, to_account, name, return_reason)
+        values ($1, $2, $3, $4, $5)
+    `, et.ID, et.RoutingNumber, et.ToAccount, et.Name, et.ReturnReason)
+    return err
+}
+
+func (s *PostgresStore) RestorePaymentRequest(ctx context.Context, pr *types.PaymentRequest) error {
+    txID := sql.NullInt64{Int64: int64(pr.TransactionID), Valid: pr.TransactionID != 0}
+
+    return s.db.QueryRowContext(ctx, `
+        insert into payment_request
+        (requester_account, payer_account, amount, memo, status, transaction_id, expires_at, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8)
+        returning id
+    `, pr.RequesterAccount, pr.PayerAccount, pr.Amount, pr.Memo, pr.Status, txID, pr.ExpiresAt, pr.CreatedAt).Scan(&pr.ID)
+}
+
+func (s *PostgresStore) RestoreFraudCase(ctx context.Context, c *types.FraudCase) error {
+    reasons, err := json.Marshal(c.Reasons)
+    if err != nil {
+        return err
+    }
+    var external []byte
+    if c.External != nil {
+        if external, err = json.Marshal(c.External); err != nil {
+            return err
+        }
+    }
+
+    return s.db.QueryRowContext(ctx, `
+        insert into fraud_case (from_account, to_account, external, amount, decision, reasons, status, transaction_id, created_at, decided_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        returning id
+    `, c.FromAccount, c.ToAccount, external, c.Amount, c.Decision, reasons, c.Status, c.TransactionID, c.CreatedAt, c.DecidedAt).Scan(&c.ID)
+}
+
+func (s *PostgresStore) RestoreReversal(ctx context.Context, r *types.Reversal) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into transaction_reversal (transaction_id, reversal_id, reason, created_at)
+        values ($1, $2, $3, $4)
+    `, r.TransactionID, r.ReversalID, r.Reason, r.CreatedAt)
+    return err
+}
+
+func (s *PostgresStore) ClearRestored(ctx context.Context) error {
+    _, err := s.db.ExecContext(ctx, `truncate table `+strings.Join(restoredTables, ", ")+` restart identity`)
+    return err
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+
+    "github.com/lib/pq"
+    "gobank/types"
+)
+
+var ErrAlreadyReversed = errors.New("transaction is already reversed")
+
+type ReversalStorage interface {
+    // ReverseTransaction posts tx, which undoes transaction r.TransactionID
+    // with its entries in reverse, and records r in the same database
+    // transaction. It fails with ErrAlreadyReversed when the transaction
+    // was reversed before, by hand or by an accepted dispute, and with
+    // ErrAlreadyDisputed while a dispute about it is open.
+    ReverseTransaction(ctx context.Context, r *types.Reversal, tx *types.Transaction, entries []*types.LedgerEntry) error
+    // GetReversal returns the reversal of a transaction, failing with
+    // ErrNotFound when it wasn't reversed.
+    GetReversal(context.Context, int) (*types.Reversal, error)
+    GetReversals(context.Context) ([]*types.Reversal, error)
+}
+
+func (s *PostgresStore) CreateReversalTable() error {
+    queries := []string{
+        `create table if not exists transaction_reversal (
+            transaction_id integer primary key,
+            reversal_id integer not null,
+            reason text not null default '',
+            created_at timestamp not null
+        )`,
+        // disputes accepted before reversals were recorded
+        `insert into transaction_reversal (transaction_id, reversal_id, reason, created_at)
+            select transaction_id, reversal_id, 'dispute ' || id || ' accepted', resolved_at from dispute
+            where status = '` + types.DisputeAccepted + `' and reversal_id is not null
+            on conflict do nothing`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) ReverseTransaction(ctx context.Context, r *types.Reversal, t *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    dbtx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer dbtx.Rollback()
+
+    if err := lockTransaction(ctx, dbtx, r.TransactionID); err != nil {
+        return err
+    }
+
+    var open int
+    err = dbtx.QueryRowContext(ctx, `
+        select count(*) from dispute where transaction_id = $1 and status = $2
+    `, r.TransactionID, types.DisputeOpen).Scan(&open)
+    if err != nil {
+        return err
+    }
+    if open > 0 {
+        return fmt.Errorf("transaction %d: %w", r.TransactionID, ErrAlreadyDisputed)
+    }
+
+    if err := postTransaction(ctx, dbtx, t, entries); err != nil {
+        return err
+    }
+    r.ReversalID = t.ID
+    if err := linkReversal(ctx, dbtx, r); err != nil {
+        return err
+    }
+
+    return dbtx.Commit()
+}
+
+// lockTransaction locks the row of transaction id until dbtx ends, so a
+// reversal and a dispute of the same transaction can't both go through.
+// Archived transactions have no row to lock, they are too old to dispute.
+func lockTransaction(ctx context.Context, dbtx *sql.Tx, id int) error {
+    _, err := dbtx.ExecContext(ctx, `select id from transaction where id = $1 for update`, id)
+    return err
+}
+
+// linkReversal records r, failing with ErrAlreadyReversed when its
+// transaction already has a reversal.
+func linkReversal(ctx context.Context, dbtx *sql.Tx, r *types.Reversal) error {
+    _, err := dbtx.ExecContext(ctx, `
+        insert into transaction_reversal (transaction_id, reversal_id, reason, created_at)
+        values ($1, $2, $3, $4)
+    `, r.TransactionID, r.ReversalID, r.Reason, r.CreatedAt)
+
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+        return fmt.Errorf("transaction %d: %w", r.TransactionID, ErrAlreadyReversed)
+    }
+
+    return err
+}
+
+func (s *PostgresStore) GetReversal(ctx context.Context, id int) (*types.Reversal, error) {
+    r := &types.Reversal{TransactionID: id}
+    err := s.db.QueryRowContext(ctx, `
+        select reversal_id, reason, created_at from transaction_reversal where transaction_id = $1
+    `, id).Scan(&r.ReversalID, &r.Reason, &r.CreatedAt)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("reversal of transaction %d %w", id, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return r, nil
+}
+
+func (s *PostgresStore) GetReversals(ctx context.Context) ([]*types.Reversal, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select transaction_id, reversal_id, reason, created_at from transaction_reversal order by transaction_id
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    reversals := []*types.Reversal{}
+    for rows.Next() {
+        r := new(types.Reversal)
+        if err := rows.Scan(&r.TransactionID, &r.ReversalID, &r.Reason, &r.CreatedAt); err != nil {
+            return nil, err
+        }
+        reversals = append(reversals, r)
+    }
+
+    return reversals, rows.Err()
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+var ErrNonceUsed = errors.New("request nonce was already used")
+
+type SigningKeyStorage interface {
+    CreateSigningKey(context.Context, *types.SigningKey) error
+    GetSigningKeysByAccount(context.Context, int64) ([]*types.SigningKey, error)
+    DeleteSigningKey(ctx context.Context, number int64, id string) error
+    // UseNonce fails with ErrNonceUsed when the key has already signed a
+    // request with nonce.
+    UseNonce(ctx context.Context, keyID, nonce string, at time.Time) error
+    DeleteNoncesBefore(context.Context, time.Time) (int, error)
+}
+
+func (s *PostgresStore) CreateSigningKeyTables() error {
+    queries := []string{
+        `create table if not exists signing_key (
+            id varchar(64) primary key,
+            account_number bigint not null,
+            encrypted_secret text not null,
+            created_at timestamp not null
+        )`,
+        `create index if not exists signing_key_account_idx on signing_key (account_number)`,
+        `create table if not exists request_nonce (
+            key_id varchar(64) not null,
+            nonce varchar(64) not null,
+            used_at timestamp not null,
+            primary key (key_id, nonce)
+        )`,
+        `create index if not exists request_nonce_used_at_idx on request_nonce (used_at)`,
+    }
+    for _, query := range queries {
+        if _, err := s.db.Exec(query); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateSigningKey(ctx context.Context, k *types.SigningKey) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into signing_key (id, account_number, encrypted_secret, created_at)
+        values ($1, $2, $3, $4)
+    `, k.ID, k.AccountNumber, k.EncryptedSecret, k.CreatedAt)
+    return err
+}
+
+func (s *PostgresStore) GetSigningKeysByAccount(ctx context.Context, number int64) ([]*types.SigningKey, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select id, account_number, encrypted_secret, created_at
+        from signing_key where account_number = $1 order by created_at, id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanSigningKeys(rows)
+}
+
+func (s *PostgresStore) DeleteSigningKey(ctx context.Context, number int64, id string) error {
+    res, err := s.db.ExecContext(ctx, `delete from signing_key where id = $1 and account_number = $2`, id, number)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("signing key %s %w", id, ErrNotFound)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) UseNonce(ctx context.Context, keyID, nonce string, at time.Time) error {
+    res, err := s.db.ExecContext(ctx, `
+        insert into request_nonce (key_id, nonce, used_at) values ($1, $2, $3)
+        on conflict do nothing
+    `, keyID, nonce, at)
+    if err != nil {
+        return err
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if n == 0 {
+        return fmt.Errorf("nonce %s: %w", nonce, ErrNonceUsed)
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) DeleteNoncesBefore(ctx context.Context, t time.Time) (int, error) {
+    res, err := s.db.ExecContext(ctx, `delete from request_nonce where used_at < $1`, t)
+    if err != nil {
+        return 0, err
+    }
+    n, err := res.RowsAffected()
+    return int(n), err
+}
+
+func scanSigningKeys(rows *sql.Rows) ([]*types.SigningKey, error) {
+    defer rows.Close()
+
+    keys := []*types.SigningKey{}
+    for rows.Next() {
+        k := new(types.SigningKey)
+        if err := rows.Scan(&k.ID, &k.AccountNumber, &k.EncryptedSecret, &k.CreatedAt); err != nil {
+            return nil, err
+        }
+        keys = append(keys, k)
+    }
+
+    return keys, rows.Err()
+}
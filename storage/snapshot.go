@@ -0,0 +1,59 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+type SnapshotStorage interface {
+    // SaveBalanceSnapshot inserts the snapshot or replaces the one already
+    // taken for that account and day.
+    SaveBalanceSnapshot(context.Context, *types.BalanceSnapshot) error
+    // GetBalanceSnapshotBefore returns the latest snapshot whose day is
+    // before the given time, or ErrNotFound.
+    GetBalanceSnapshotBefore(context.Context, int64, time.Time) (*types.BalanceSnapshot, error)
+}
+
+func (s *PostgresStore) CreateSnapshotTable() error {
+    query := `create table if not exists balance_snapshot (
+        account_number bigint not null,
+        day date not null,
+        balance bigint not null,
+        primary key (account_number, day)
+    )`
+
+    _, err := s.db.Exec(query)
+    return err
+}
+
+func (s *PostgresStore) SaveBalanceSnapshot(ctx context.Context, snap *types.BalanceSnapshot) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into balance_snapshot (account_number, day, balance)
+        values ($1, $2, $3)
+        on conflict (account_number, day) do update set balance = excluded.balance
+    `, snap.AccountNumber, snap.Day, snap.Balance)
+
+    return err
+}
+
+func (s *PostgresStore) GetBalanceSnapshotBefore(ctx context.Context, number int64, before time.Time) (*types.BalanceSnapshot, error) {
+    snap := &types.BalanceSnapshot{AccountNumber: number}
+    err := s.db.QueryRowContext(ctx, `
+        select day, balance from balance_snapshot
+        where account_number = $1 and day < $2
+        order by day desc limit 1
+    `, number, before).Scan(&snap.Day, &snap.Balance)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("snapshot for account %d %w", number, ErrNotFound)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    snap.Day = snap.Day.UTC()
+    return snap, nil
+}
@@ -2,15 +2,52 @@ package storage
 
 import (
     "database/sql"
+    "sync"
     _ "github.com/lib/pq"
 )
 
 type Storage interface {
     AccountStorage
+    TransactionStorage
+    LedgerStorage
+    ReconciliationStorage
+    SnapshotStorage
+    NotificationStorage
+    AlertStorage
+    AliasStorage
+    PaymentRequestStorage
+    CardStorage
+    LoanStorage
+    PotStorage
+    OwnerStorage
+    AuditStorage
+    GrantStorage
+    ProductStorage
+    FraudStorage
+    DeviceStorage
+    ReportStorage
+    JobStorage
+    DeadLetterStorage
+    SigningKeyStorage
+    ImportStorage
+    ArchiveStorage
+    DormancyStorage
+    DisputeStorage
+    DocumentStorage
+    ClosureStorage
+    ExternalTransferStorage
+    ReversalStorage
+    UsageStorage
+    InvitationStorage
+    RestoreStorage
 }
 
 type PostgresStore struct {
     db *sql.DB
+
+    // locks are the connections holding leader locks
+    locksMu sync.Mutex
+    locks map[string]*sql.Conn
 }
 
 func NewPostgresStore() (*PostgresStore, error) {
@@ -31,7 +68,44 @@ func NewPostgresStore() (*PostgresStore, error) {
 }
 
 func (s *PostgresStore) Init() error {
-    return s.CreateAccountTable()
+    tables := []func() error{
+        s.CreateAccountTable,
+        s.CreateTransactionTable,
+        s.CreateLedgerTable,
+        s.CreateReconciliationTable,
+        s.CreateSnapshotTable,
+        s.CreateNotificationTables,
+        s.CreateAlertTable,
+        s.CreateAliasTables,
+        s.CreatePaymentRequestTable,
+        s.CreateCardTables,
+        s.CreateLoanTables,
+        s.CreatePotTable,
+        s.CreateOwnerTables,
+        s.CreateAuditTable,
+        s.CreateGrantTable,
+        s.CreateProductRateTable,
+        s.CreateFraudCaseTable,
+        s.CreateDeviceTables,
+        s.CreateJobStatusTable,
+        s.CreateDeadLetterTable,
+        s.CreateSigningKeyTables,
+        s.CreateActivationTable,
+        s.CreateArchiveTables,
+        s.CreateDisputeTable,
+        s.CreateDocumentTable,
+        s.CreateExternalTransferTable,
+        s.CreateReversalTable,
+        s.CreateUsageTable,
+        s.CreateAccountInvitationTable,
+    }
+    for _, create := range tables {
+        if err := create(); err != nil {
+            return err
+        }
+    }
+
+    return nil
 }
 
 func (s *PostgresStore) CreateAccountTable() error {
@@ -40,12 +114,36 @@ func (s *PostgresStore) CreateAccountTable() error {
         first_name varchar(50),
         last_name varchar(50),
         number serial,
-        balance serial,
+        balance bigint not null default 0,
         encrypted_password varchar(256),
         created_at timestamp
     )`
 
-    _, err := s.db.Exec(query)
-    return err
+    if _, err := s.db.Exec(query); err != nil {
+        return err
+    }
+
+    alters := []string{
+        `alter table account add column if not exists email varchar(256)`,
+        `alter table account add column if not exists phone varchar(32)`,
+        `alter table account add column if not exists phone_verified boolean not null default false`,
+        `alter table account add column if not exists currency varchar(3) not null default 'USD'`,
+        `alter table account add column if not exists nickname varchar(64) not null default ''`,
+        `alter table account add column if not exists metadata jsonb not null default '{}'`,
+        `alter table account add column if not exists locale varchar(16) not null default ''`,
+        `alter table account add column if not exists dormancy_notice_at timestamp`,
+        `alter table account add column if not exists dormant_since timestamp`,
+        `alter table account add column if not exists closed_at timestamp`,
+        // fails while the table still holds duplicate numbers, which have
+        // to be renumbered by hand first
+        `create unique index if not exists account_number_key on account (number)`,
+    }
+    for _, alter := range alters {
+        if _, err := s.db.Exec(alter); err != nil {
+            return err
+        }
+    }
+
+    return nil
 }
 
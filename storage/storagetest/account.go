@@ -0,0 +1,147 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "maps"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateAccount(ctx context.Context, acc *types.Account) error {
+    if err := s.call(ctx, "CreateAccount"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.accountByNumber(acc.Number) != nil {
+        return fmt.Errorf("account %d: %w", acc.Number, storage.ErrNumberTaken)
+    }
+
+    s.lastAccountID++
+    acc.ID = s.lastAccountID
+    s.accounts = append(s.accounts, copyAccount(acc))
+
+    return nil
+}
+
+func (s *Store) UpdateAccount(ctx context.Context, acc *types.Account) error {
+    if err := s.call(ctx, "UpdateAccount"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, a := range s.accounts {
+        if a.ID == acc.ID {
+            c := copyAccount(acc)
+            c.Balance = a.Balance
+            c.DormancyNoticeAt, c.DormantSince = a.DormancyNoticeAt, a.DormantSince
+            c.ClosedAt = a.ClosedAt
+            s.accounts[i] = c
+            return nil
+        }
+    }
+
+    return fmt.Errorf("account %d %w", acc.ID, storage.ErrNotFound)
+}
+
+func (s *Store) DeleteAccount(ctx context.Context, id int) error {
+    if err := s.call(ctx, "DeleteAccount"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, a := range s.accounts {
+        if a.ID == id {
+            s.accounts = append(s.accounts[:i], s.accounts[i+1:]...)
+            break
+        }
+    }
+
+    return nil
+}
+
+func (s *Store) GetAccounts(ctx context.Context) ([]*types.Account, error) {
+    if err := s.call(ctx, "GetAccounts"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    accounts := []*types.Account{}
+    for _, a := range s.accounts {
+        accounts = append(accounts, copyAccount(a))
+    }
+
+    return accounts, nil
+}
+
+func (s *Store) GetAccountByID(ctx context.Context, id int) (*types.Account, error) {
+    if err := s.call(ctx, "GetAccountByID"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, a := range s.accounts {
+        if a.ID == id {
+            return copyAccount(a), nil
+        }
+    }
+
+    return nil, fmt.Errorf("account %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) GetAccountByNumber(ctx context.Context, number int64) (*types.Account, error) {
+    if err := s.call(ctx, "GetAccountByNumber"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, a := range s.accounts {
+        if a.Number == number {
+            return copyAccount(a), nil
+        }
+    }
+
+    return nil, fmt.Errorf("account %d %w", number, storage.ErrNotFound)
+}
+
+func copyAccount(acc *types.Account) *types.Account {
+    c := *acc
+    c.Metadata = maps.Clone(acc.Metadata)
+    return &c
+}
+
+// SetBalance overwrites the stored balance without posting to the ledger,
+// e.g. to simulate drift.
+func (s *Store) SetBalance(number int64, balance int64) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if acc := s.accountByNumber(number); acc != nil {
+        acc.Balance = balance
+    }
+}
+
+// accountByNumber returns the stored account itself, not a copy. The caller
+// must hold mu.
+func (s *Store) accountByNumber(number int64) *types.Account {
+    for _, a := range s.accounts {
+        if a.Number == number {
+            return a
+        }
+    }
+    return nil
+}
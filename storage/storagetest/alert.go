@@ -0,0 +1,62 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateAlertRule(ctx context.Context, rule *types.AlertRule) error {
+    if err := s.call(ctx, "CreateAlertRule"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastAlertRuleID++
+    rule.ID = s.lastAlertRuleID
+    c := *rule
+    s.alertRules = append(s.alertRules, &c)
+
+    return nil
+}
+
+func (s *Store) GetAlertRules(ctx context.Context, number int64) ([]*types.AlertRule, error) {
+    if err := s.call(ctx, "GetAlertRules"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    rules := []*types.AlertRule{}
+    for _, rule := range s.alertRules {
+        if rule.AccountNumber == number {
+            c := *rule
+            rules = append(rules, &c)
+        }
+    }
+
+    return rules, nil
+}
+
+func (s *Store) DeleteAlertRule(ctx context.Context, number int64, id int) error {
+    if err := s.call(ctx, "DeleteAlertRule"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, rule := range s.alertRules {
+        if rule.ID == id && rule.AccountNumber == number {
+            s.alertRules = append(s.alertRules[:i], s.alertRules[i+1:]...)
+            return nil
+        }
+    }
+
+    return fmt.Errorf("alert rule %d %w", id, storage.ErrNotFound)
+}
@@ -0,0 +1,196 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateAlias(ctx context.Context, a *types.Alias) error {
+    if err := s.call(ctx, "CreateAlias"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.aliases[a.Alias]; ok {
+        return fmt.Errorf("%s: %w", a.Alias, storage.ErrAliasTaken)
+    }
+    c := *a
+    s.aliases[a.Alias] = &c
+
+    return nil
+}
+
+func (s *Store) GetAlias(ctx context.Context, alias string) (*types.Alias, error) {
+    if err := s.call(ctx, "GetAlias"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    a, ok := s.aliases[alias]
+    if !ok {
+        return nil, fmt.Errorf("alias %s %w", alias, storage.ErrNotFound)
+    }
+    c := *a
+
+    return &c, nil
+}
+
+func (s *Store) GetAliasesByAccount(ctx context.Context, number int64) ([]*types.Alias, error) {
+    if err := s.call(ctx, "GetAliasesByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    aliases := []*types.Alias{}
+    for _, a := range s.aliases {
+        if a.AccountNumber == number {
+            c := *a
+            aliases = append(aliases, &c)
+        }
+    }
+    sort.Slice(aliases, func(i, j int) bool { return aliases[i].CreatedAt.Before(aliases[j].CreatedAt) })
+
+    return aliases, nil
+}
+
+func (s *Store) DeleteAlias(ctx context.Context, number int64, alias string) error {
+    if err := s.call(ctx, "DeleteAlias"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    a, ok := s.aliases[alias]
+    if !ok || a.AccountNumber != number {
+        return fmt.Errorf("alias %s %w", alias, storage.ErrNotFound)
+    }
+    delete(s.aliases, alias)
+
+    return nil
+}
+
+func aliasVerificationKey(number int64, alias string) string {
+    return fmt.Sprintf("%d/%s", number, alias)
+}
+
+func (s *Store) SaveAliasVerification(ctx context.Context, v *types.AliasVerification) error {
+    if err := s.call(ctx, "SaveAliasVerification"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    c := *v
+    s.aliasVerifications[aliasVerificationKey(v.AccountNumber, v.Alias)] = &c
+
+    return nil
+}
+
+func (s *Store) GetAliasVerification(ctx context.Context, number int64, alias string) (*types.AliasVerification, error) {
+    if err := s.call(ctx, "GetAliasVerification"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    v, ok := s.aliasVerifications[aliasVerificationKey(number, alias)]
+    if !ok {
+        return nil, fmt.Errorf("alias verification for %s %w", alias, storage.ErrNotFound)
+    }
+    c := *v
+
+    return &c, nil
+}
+
+func (s *Store) DeleteAliasVerification(ctx context.Context, number int64, alias string) error {
+    if err := s.call(ctx, "DeleteAliasVerification"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    delete(s.aliasVerifications, aliasVerificationKey(number, alias))
+
+    return nil
+}
+
+func (s *Store) CreateAliasClaim(ctx context.Context, c *types.AliasClaim, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := s.call(ctx, "CreateAliasClaim"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err := s.checkVelocity(tx, limits); err != nil {
+        return err
+    }
+    if err := s.post(tx, entries); err != nil {
+        return err
+    }
+
+    c.TransactionID = tx.ID
+    c.FromAccount = tx.FromAccount
+    c.Amount = tx.Amount
+    cc := *c
+    s.aliasClaims = append(s.aliasClaims, &cc)
+
+    return nil
+}
+
+func (s *Store) GetPendingAliasClaims(ctx context.Context, alias string) ([]*types.AliasClaim, error) {
+    if err := s.call(ctx, "GetPendingAliasClaims"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.pendingAliasClaims(func(c *types.AliasClaim) bool { return alias == "" || c.Alias == alias }), nil
+}
+
+func (s *Store) GetExpiredAliasClaims(ctx context.Context, t time.Time) ([]*types.AliasClaim, error) {
+    if err := s.call(ctx, "GetExpiredAliasClaims"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.pendingAliasClaims(func(c *types.AliasClaim) bool { return c.ExpiresAt.Before(t) }), nil
+}
+
+func (s *Store) pendingAliasClaims(match func(*types.AliasClaim) bool) []*types.AliasClaim {
+    claims := []*types.AliasClaim{}
+    for _, c := range s.aliasClaims {
+        if !match(c) {
+            continue
+        }
+        for _, tx := range s.transactions {
+            if tx.ID == c.TransactionID && tx.Status == types.StatusPending {
+                cc := *c
+                claims = append(claims, &cc)
+            }
+        }
+    }
+
+    return claims
+}
@@ -0,0 +1,47 @@
+package storagetest
+
+import (
+    "context"
+    "time"
+
+    "gobank/types"
+)
+
+// ArchiveTransactionsBefore only marks the transactions as archived, reads
+// keep returning them just like they do from the Postgres views.
+func (s *Store) ArchiveTransactionsBefore(ctx context.Context, t time.Time) (int, error) {
+    if err := s.call(ctx, "ArchiveTransactionsBefore"); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    referenced := map[int]bool{}
+    for _, c := range s.aliasClaims {
+        referenced[c.TransactionID] = true
+    }
+    for _, pr := range s.paymentRequests {
+        referenced[pr.TransactionID] = true
+    }
+
+    moved := 0
+    for _, tx := range s.transactions {
+        if s.archived[tx.ID] || referenced[tx.ID] || tx.Status == types.StatusPending || !tx.CreatedAt.Before(t) {
+            continue
+        }
+        s.archived[tx.ID] = true
+        moved++
+    }
+
+    return moved, nil
+}
+
+// Archived reports whether ArchiveTransactionsBefore moved transaction id
+// out of the hot tables.
+func (s *Store) Archived(id int) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.archived[id]
+}
@@ -0,0 +1,42 @@
+package storagetest
+
+import (
+    "context"
+
+    "gobank/types"
+)
+
+func (s *Store) RecordAudit(ctx context.Context, e *types.AuditEntry) error {
+    if err := s.call(ctx, "RecordAudit"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastAuditID++
+    e.ID = s.lastAuditID
+    c := *e
+    s.audit = append(s.audit, &c)
+
+    return nil
+}
+
+func (s *Store) GetAuditLog(ctx context.Context, number int64) ([]*types.AuditEntry, error) {
+    if err := s.call(ctx, "GetAuditLog"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    entries := []*types.AuditEntry{}
+    for i := len(s.audit) - 1; i >= 0; i-- {
+        if s.audit[i].AccountNumber == number {
+            c := *s.audit[i]
+            entries = append(entries, &c)
+        }
+    }
+
+    return entries, nil
+}
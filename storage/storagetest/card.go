@@ -0,0 +1,308 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateCard(ctx context.Context, c *types.Card) error {
+    if err := s.call(ctx, "CreateCard"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastCardID++
+    c.ID = s.lastCardID
+    cc := *c
+    s.cards = append(s.cards, &cc)
+
+    return nil
+}
+
+func (s *Store) GetCard(ctx context.Context, id int) (*types.Card, error) {
+    if err := s.call(ctx, "GetCard"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, c := range s.cards {
+        if c.ID == id {
+            cc := *c
+            return &cc, nil
+        }
+    }
+
+    return nil, fmt.Errorf("card %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) GetCardByPANHash(ctx context.Context, hash string) (*types.Card, error) {
+    if err := s.call(ctx, "GetCardByPANHash"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, c := range s.cards {
+        if c.PANHash == hash {
+            cc := *c
+            return &cc, nil
+        }
+    }
+
+    return nil, fmt.Errorf("card %w", storage.ErrNotFound)
+}
+
+func (s *Store) GetCardsByAccount(ctx context.Context, number int64) ([]*types.Card, error) {
+    if err := s.call(ctx, "GetCardsByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    cards := []*types.Card{}
+    for _, c := range s.cards {
+        if c.AccountNumber == number {
+            cc := *c
+            cards = append(cards, &cc)
+        }
+    }
+
+    return cards, nil
+}
+
+func (s *Store) UpdateCard(ctx context.Context, c *types.Card) error {
+    if err := s.call(ctx, "UpdateCard"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, existing := range s.cards {
+        if existing.ID == c.ID {
+            existing.Status = c.Status
+            existing.DailyLimit = c.DailyLimit
+            return nil
+        }
+    }
+
+    return fmt.Errorf("card %d %w", c.ID, storage.ErrNotFound)
+}
+
+func (s *Store) SetCardPIN(ctx context.Context, id int, hash string) error {
+    if err := s.call(ctx, "SetCardPIN"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    c := s.card(id)
+    if c == nil {
+        return fmt.Errorf("card %d %w", id, storage.ErrNotFound)
+    }
+    c.PINHash = hash
+    c.PINSet = true
+    c.PINAttempts = 0
+
+    return nil
+}
+
+func (s *Store) RecordPINAttempt(ctx context.Context, id int, correct bool, maxAttempts int) (*types.Card, error) {
+    if err := s.call(ctx, "RecordPINAttempt"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    c := s.card(id)
+    if c == nil {
+        return nil, fmt.Errorf("card %d %w", id, storage.ErrNotFound)
+    }
+
+    if correct {
+        c.PINAttempts = 0
+    } else {
+        c.PINAttempts++
+        if c.PINAttempts >= maxAttempts {
+            c.Status = types.CardBlocked
+        }
+    }
+    cc := *c
+
+    return &cc, nil
+}
+
+func (s *Store) card(id int) *types.Card {
+    for _, c := range s.cards {
+        if c.ID == id {
+            return c
+        }
+    }
+    return nil
+}
+
+func (s *Store) PlaceHold(ctx context.Context, h *types.Hold, dailyLimit int64) error {
+    if err := s.call(ctx, "PlaceHold"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    acc := s.accountByNumber(h.AccountNumber)
+    if acc == nil {
+        return fmt.Errorf("account %d %w", h.AccountNumber, storage.ErrNotFound)
+    }
+
+    var spent int64
+    day := h.CreatedAt.UTC().Truncate(24 * time.Hour)
+    for _, existing := range s.holds {
+        if existing.CardID == h.CardID && existing.Status != types.HoldReleased && !existing.CreatedAt.Before(day) {
+            spent += existing.Amount
+        }
+    }
+
+    if acc.Balance-s.potTotal(h.AccountNumber)-s.disputedTotal(h.AccountNumber)-s.heldTotal(h.AccountNumber, h.CreatedAt) < h.Amount {
+        return fmt.Errorf("account %d: %w", h.AccountNumber, storage.ErrInsufficientFunds)
+    }
+    if dailyLimit > 0 && spent+h.Amount > dailyLimit {
+        return fmt.Errorf("card %d: %w", h.CardID, storage.ErrCardLimitExceeded)
+    }
+
+    s.lastHoldID++
+    h.ID = s.lastHoldID
+    c := *h
+    s.holds = append(s.holds, &c)
+
+    return nil
+}
+
+func (s *Store) GetHold(ctx context.Context, id int) (*types.Hold, error) {
+    if err := s.call(ctx, "GetHold"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, h := range s.holds {
+        if h.ID == id {
+            c := *h
+            return &c, nil
+        }
+    }
+
+    return nil, fmt.Errorf("hold %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) CaptureHold(ctx context.Context, id int, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := s.call(ctx, "CaptureHold"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    h, err := s.activeHold(id, tx.CreatedAt)
+    if err != nil {
+        return err
+    }
+    h.Status = types.HoldCaptured
+    if err := s.post(tx, entries); err != nil {
+        h.Status = types.HoldActive
+        return err
+    }
+
+    return nil
+}
+
+func (s *Store) ReleaseHold(ctx context.Context, id int, at time.Time) error {
+    if err := s.call(ctx, "ReleaseHold"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    h, err := s.activeHold(id, at)
+    if err != nil {
+        return err
+    }
+    h.Status = types.HoldReleased
+
+    return nil
+}
+
+func (s *Store) activeHold(id int, at time.Time) (*types.Hold, error) {
+    for _, h := range s.holds {
+        if h.ID == id && h.Status == types.HoldActive && h.ExpiresAt.After(at) {
+            return h, nil
+        }
+    }
+    return nil, fmt.Errorf("hold %d: %w", id, storage.ErrHoldClosed)
+}
+
+// heldTotal is how much of an account's balance active card holds reserve
+// at t.
+func (s *Store) heldTotal(number int64, t time.Time) int64 {
+    var total int64
+    for _, h := range s.holds {
+        if h.AccountNumber == number && h.Status == types.HoldActive && h.ExpiresAt.After(t) {
+            total += h.Amount
+        }
+    }
+    return total
+}
+
+func (s *Store) GetActiveHolds(ctx context.Context, number int64, t time.Time) ([]*types.Hold, error) {
+    if err := s.call(ctx, "GetActiveHolds"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    holds := []*types.Hold{}
+    for _, h := range s.holds {
+        if h.AccountNumber == number && h.Status == types.HoldActive && h.ExpiresAt.After(t) {
+            c := *h
+            holds = append(holds, &c)
+        }
+    }
+
+    return holds, nil
+}
+
+func (s *Store) ExpireHolds(ctx context.Context, t time.Time) (int, error) {
+    if err := s.call(ctx, "ExpireHolds"); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    n := 0
+    for _, h := range s.holds {
+        if h.Status == types.HoldActive && !h.ExpiresAt.After(t) {
+            h.Status = types.HoldExpired
+            n++
+        }
+    }
+
+    return n, nil
+}
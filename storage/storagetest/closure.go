@@ -0,0 +1,120 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "slices"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CloseAccount(ctx context.Context, number int64, sweep *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit, at time.Time) error {
+    if err := s.call(ctx, "CloseAccount"); err != nil {
+        return err
+    }
+    if sweep != nil {
+        if err := types.ValidateEntries(entries); err != nil {
+            return err
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    acc := s.accountByNumber(number)
+    if acc == nil {
+        return fmt.Errorf("account %d %w", number, storage.ErrNotFound)
+    }
+    if acc.ClosedAt != nil {
+        return fmt.Errorf("account %d: %w", number, storage.ErrAccountClosed)
+    }
+    if err := s.closureBlocker(number, at); err != nil {
+        return err
+    }
+
+    remaining := acc.Balance
+    if sweep != nil {
+        for _, e := range entries {
+            if e.AccountNumber == number {
+                remaining += e.Amount
+            }
+        }
+    }
+    if remaining != 0 {
+        return fmt.Errorf("account %d would keep a balance of %d: %w", number, remaining, storage.ErrClosureBlocked)
+    }
+    if sweep != nil {
+        if err := s.checkVelocity(sweep, limits); err != nil {
+            return err
+        }
+    }
+
+    // money in pots is part of the balance, it is swept with the rest
+    pots := s.pots
+    s.pots = slices.DeleteFunc(slices.Clone(s.pots), func(p *types.Pot) bool { return p.AccountNumber == number })
+    if sweep != nil {
+        if err := s.post(sweep, entries); err != nil {
+            s.pots = pots
+            return err
+        }
+    }
+
+    for _, pr := range s.paymentRequests {
+        if pr.Status == types.RequestPending && (pr.RequesterAccount == number || pr.PayerAccount == number) {
+            pr.Status = types.RequestCancelled
+        }
+    }
+    for _, inv := range s.invitations {
+        if inv.Status == types.InvitationPending && (inv.AccountNumber == number || inv.InviteeNumber == number) {
+            inv.Status = types.InvitationDeclined
+        }
+    }
+    for _, g := range s.grants {
+        if g.RevokedAt == nil && (g.AccountNumber == number || g.GranteeNumber == number) {
+            revokedAt := at
+            g.RevokedAt = &revokedAt
+        }
+    }
+    for _, c := range s.cards {
+        if c.AccountNumber == number {
+            c.Status = types.CardBlocked
+        }
+    }
+    acc.ClosedAt = &at
+
+    return nil
+}
+
+func (s *Store) closureBlocker(number int64, at time.Time) error {
+    reason := ""
+    for _, l := range s.loans {
+        if l.AccountNumber == number && (l.Status == types.LoanApplied || l.Status == types.LoanActive) {
+            reason = "a loan that isn't paid off"
+        }
+    }
+    for _, h := range s.holds {
+        if h.AccountNumber == number && h.Status == types.HoldActive && h.ExpiresAt.After(at) {
+            reason = "card payments that aren't captured yet"
+        }
+    }
+    for _, d := range s.disputes {
+        if (d.AccountNumber == number || d.HeldAccount == number) && d.Status == types.DisputeOpen {
+            reason = "open disputes"
+        }
+    }
+    for _, tx := range s.transactions {
+        if tx.FromAccount == number && tx.ToAccount == types.SettlementAccountNumber && tx.Status == types.StatusPending {
+            reason = "transfers to other banks that haven't settled"
+        }
+    }
+    if len(s.pendingAliasClaims(func(c *types.AliasClaim) bool { return c.FromAccount == number })) > 0 {
+        reason = "transfers to aliases that haven't been claimed"
+    }
+    if reason != "" {
+        return fmt.Errorf("account %d has %s: %w", number, reason, storage.ErrClosureBlocked)
+    }
+
+    return nil
+}
@@ -0,0 +1,118 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateDeadLetter(ctx context.Context, d *types.DeadLetter) error {
+    if err := s.call(ctx, "CreateDeadLetter"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastDeadLetterID++
+    d.ID = s.lastDeadLetterID
+    s.deadLetters = append(s.deadLetters, copyDeadLetter(d))
+
+    return nil
+}
+
+func (s *Store) GetDeadLetter(ctx context.Context, id int) (*types.DeadLetter, error) {
+    if err := s.call(ctx, "GetDeadLetter"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    d := s.deadLetter(id)
+    if d == nil {
+        return nil, fmt.Errorf("dead letter %d %w", id, storage.ErrNotFound)
+    }
+
+    return copyDeadLetter(d), nil
+}
+
+func (s *Store) GetDeadLettersByStatus(ctx context.Context, status string) ([]*types.DeadLetter, error) {
+    if err := s.call(ctx, "GetDeadLettersByStatus"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    letters := []*types.DeadLetter{}
+    for _, d := range s.deadLetters {
+        if d.Status == status {
+            letters = append(letters, copyDeadLetter(d))
+        }
+    }
+
+    return letters, nil
+}
+
+func (s *Store) RecordDeadLetterFailure(ctx context.Context, d *types.DeadLetter, reason string) error {
+    if err := s.call(ctx, "RecordDeadLetterFailure"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored := s.deadLetter(d.ID)
+    if stored == nil {
+        return fmt.Errorf("dead letter %d %w", d.ID, storage.ErrNotFound)
+    }
+    stored.Reason = reason
+    stored.Attempts++
+    *d = *copyDeadLetter(stored)
+
+    return nil
+}
+
+func (s *Store) MarkDeadLetterReplayed(ctx context.Context, d *types.DeadLetter, at time.Time) error {
+    if err := s.call(ctx, "MarkDeadLetterReplayed"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored := s.deadLetter(d.ID)
+    if stored == nil {
+        return fmt.Errorf("dead letter %d %w", d.ID, storage.ErrNotFound)
+    }
+    if stored.Status != types.DeadLetterPending {
+        return fmt.Errorf("dead letter %d: %w", d.ID, storage.ErrAlreadyReplayed)
+    }
+    stored.Status = types.DeadLetterReplayed
+    stored.ReplayedAt = &at
+    *d = *copyDeadLetter(stored)
+
+    return nil
+}
+
+func (s *Store) deadLetter(id int) *types.DeadLetter {
+    for _, d := range s.deadLetters {
+        if d.ID == id {
+            return d
+        }
+    }
+    return nil
+}
+
+func copyDeadLetter(d *types.DeadLetter) *types.DeadLetter {
+    cp := *d
+    if d.ReplayedAt != nil {
+        at := *d.ReplayedAt
+        cp.ReplayedAt = &at
+    }
+    return &cp
+}
@@ -0,0 +1,130 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "sort"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) GetDevices(ctx context.Context, number int64) ([]*types.Device, error) {
+    if err := s.call(ctx, "GetDevices"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    devices := []*types.Device{}
+    for _, d := range s.devices {
+        if d.AccountNumber == number {
+            c := *d
+            devices = append(devices, &c)
+        }
+    }
+    sort.SliceStable(devices, func(i, j int) bool {
+        return devices[i].LastSeen.After(devices[j].LastSeen)
+    })
+
+    return devices, nil
+}
+
+func (s *Store) SaveDevice(ctx context.Context, d *types.Device) error {
+    if err := s.call(ctx, "SaveDevice"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, existing := range s.devices {
+        if existing.AccountNumber == d.AccountNumber && existing.Fingerprint == d.Fingerprint {
+            existing.UserAgent = d.UserAgent
+            existing.IP = d.IP
+            existing.Country = d.Country
+            existing.LastSeen = d.LastSeen
+            d.ID = existing.ID
+            d.FirstSeen = existing.FirstSeen
+            return nil
+        }
+    }
+
+    s.lastDeviceID++
+    d.ID = s.lastDeviceID
+    c := *d
+    s.devices = append(s.devices, &c)
+
+    return nil
+}
+
+func (s *Store) DeleteDevice(ctx context.Context, number int64, id int) error {
+    if err := s.call(ctx, "DeleteDevice"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, d := range s.devices {
+        if d.ID == id && d.AccountNumber == number {
+            s.devices = append(s.devices[:i], s.devices[i+1:]...)
+            return nil
+        }
+    }
+
+    return fmt.Errorf("device %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) SaveStepUpChallenge(ctx context.Context, c *types.StepUpChallenge) error {
+    if err := s.call(ctx, "SaveStepUpChallenge"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    cp := *c
+    if c.Device != nil {
+        d := *c.Device
+        cp.Device = &d
+    }
+    s.stepUps[c.ID] = &cp
+
+    return nil
+}
+
+func (s *Store) GetStepUpChallenge(ctx context.Context, id string) (*types.StepUpChallenge, error) {
+    if err := s.call(ctx, "GetStepUpChallenge"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    c, ok := s.stepUps[id]
+    if !ok {
+        return nil, fmt.Errorf("step-up challenge %s %w", id, storage.ErrNotFound)
+    }
+    cp := *c
+    if c.Device != nil {
+        d := *c.Device
+        cp.Device = &d
+    }
+
+    return &cp, nil
+}
+
+func (s *Store) DeleteStepUpChallenge(ctx context.Context, id string) error {
+    if err := s.call(ctx, "DeleteStepUpChallenge"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    delete(s.stepUps, id)
+
+    return nil
+}
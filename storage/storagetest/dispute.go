@@ -0,0 +1,183 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "slices"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateDispute(ctx context.Context, d *types.Dispute) error {
+    if err := s.call(ctx, "CreateDispute"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.reversals[d.TransactionID] != nil {
+        return fmt.Errorf("transaction %d: %w", d.TransactionID, storage.ErrAlreadyReversed)
+    }
+    for _, existing := range s.disputes {
+        if existing.TransactionID == d.TransactionID && existing.Status != types.DisputeDenied {
+            return fmt.Errorf("transaction %d: %w", d.TransactionID, storage.ErrAlreadyDisputed)
+        }
+    }
+
+    s.lastDisputeID++
+    d.ID = s.lastDisputeID
+    s.disputes = append(s.disputes, copyDispute(d))
+
+    return nil
+}
+
+func (s *Store) GetDispute(ctx context.Context, id int) (*types.Dispute, error) {
+    if err := s.call(ctx, "GetDispute"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if d := s.dispute(id); d != nil {
+        return copyDispute(d), nil
+    }
+
+    return nil, fmt.Errorf("dispute %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) GetDisputesByAccount(ctx context.Context, number int64) ([]*types.Dispute, error) {
+    if err := s.call(ctx, "GetDisputesByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    disputes := []*types.Dispute{}
+    for _, d := range s.disputes {
+        if d.AccountNumber == number {
+            disputes = append(disputes, copyDispute(d))
+        }
+    }
+
+    return disputes, nil
+}
+
+func (s *Store) GetDisputesByStatus(ctx context.Context, status string) ([]*types.Dispute, error) {
+    if err := s.call(ctx, "GetDisputesByStatus"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    disputes := []*types.Dispute{}
+    for _, d := range s.disputes {
+        if d.Status == status {
+            disputes = append(disputes, copyDispute(d))
+        }
+    }
+
+    return disputes, nil
+}
+
+func (s *Store) AcceptDispute(ctx context.Context, d *types.Dispute, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := s.call(ctx, "AcceptDispute"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored, err := s.openDispute(d.ID)
+    if err != nil {
+        return err
+    }
+    if s.reversals[stored.TransactionID] != nil {
+        return fmt.Errorf("transaction %d: %w", stored.TransactionID, storage.ErrAlreadyReversed)
+    }
+
+    // the hold is released before the reversal takes the money
+    stored.Status = types.DisputeAccepted
+    if err := s.post(tx, entries); err != nil {
+        stored.Status = types.DisputeOpen
+        return err
+    }
+
+    txID, resolvedAt := tx.ID, tx.CreatedAt
+    s.reversals[stored.TransactionID] = &types.Reversal{
+        TransactionID: stored.TransactionID,
+        ReversalID: txID,
+        Reason: fmt.Sprintf("dispute %d accepted", stored.ID),
+        CreatedAt: resolvedAt,
+    }
+    stored.Resolution = d.Resolution
+    stored.ReversalID = &txID
+    stored.ResolvedAt = &resolvedAt
+    *d = *copyDispute(stored)
+
+    return nil
+}
+
+func (s *Store) DenyDispute(ctx context.Context, d *types.Dispute, at time.Time) error {
+    if err := s.call(ctx, "DenyDispute"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored, err := s.openDispute(d.ID)
+    if err != nil {
+        return err
+    }
+
+    stored.Status = types.DisputeDenied
+    stored.Resolution = d.Resolution
+    stored.ResolvedAt = &at
+    *d = *copyDispute(stored)
+
+    return nil
+}
+
+func (s *Store) dispute(id int) *types.Dispute {
+    for _, d := range s.disputes {
+        if d.ID == id {
+            return d
+        }
+    }
+    return nil
+}
+
+func (s *Store) openDispute(id int) (*types.Dispute, error) {
+    d := s.dispute(id)
+    if d == nil || d.Status != types.DisputeOpen {
+        return nil, fmt.Errorf("dispute %d: %w", id, storage.ErrDisputeClosed)
+    }
+    return d, nil
+}
+
+// disputedTotal is how much of an account's balance open disputes hold.
+func (s *Store) disputedTotal(number int64) int64 {
+    var total int64
+    for _, d := range s.disputes {
+        if d.HeldAccount == number && d.Status == types.DisputeOpen {
+            total += d.HeldAmount
+        }
+    }
+    return total
+}
+
+func copyDispute(d *types.Dispute) *types.Dispute {
+    cp := *d
+    cp.Evidence = slices.Clone(d.Evidence)
+    cp.Documents = slices.Clone(d.Documents)
+    return &cp
+}
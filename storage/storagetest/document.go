@@ -0,0 +1,62 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateDocument(ctx context.Context, d *types.Document) error {
+    if err := s.call(ctx, "CreateDocument"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastDocumentID++
+    d.ID = s.lastDocumentID
+    cp := *d
+    s.documents = append(s.documents, &cp)
+
+    return nil
+}
+
+func (s *Store) GetDocument(ctx context.Context, id int) (*types.Document, error) {
+    if err := s.call(ctx, "GetDocument"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, d := range s.documents {
+        if d.ID == id {
+            cp := *d
+            return &cp, nil
+        }
+    }
+
+    return nil, fmt.Errorf("document %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) GetDocumentsByAccount(ctx context.Context, number int64, purpose string) ([]*types.Document, error) {
+    if err := s.call(ctx, "GetDocumentsByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    documents := []*types.Document{}
+    for _, d := range s.documents {
+        if d.AccountNumber == number && (purpose == "" || d.Purpose == purpose) {
+            cp := *d
+            documents = append(documents, &cp)
+        }
+    }
+
+    return documents, nil
+}
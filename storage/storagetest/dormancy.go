@@ -0,0 +1,65 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) GetLastActivity(ctx context.Context) (map[int64]time.Time, error) {
+    if err := s.call(ctx, "GetLastActivity"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    activity := map[int64]time.Time{}
+    for _, a := range s.accounts {
+        activity[a.Number] = a.CreatedAt
+    }
+    later := func(number int64, t time.Time) {
+        if last, ok := activity[number]; ok && t.After(last) {
+            activity[number] = t
+        }
+    }
+    for _, tx := range s.transactions {
+        if tx.Kind == types.TransactionTransfer {
+            later(tx.FromAccount, tx.CreatedAt)
+        }
+    }
+    for _, d := range s.devices {
+        later(d.AccountNumber, d.LastSeen)
+    }
+
+    return activity, nil
+}
+
+func (s *Store) SetDormancy(ctx context.Context, number int64, noticeAt, dormantSince *time.Time) error {
+    if err := s.call(ctx, "SetDormancy"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    acc := s.accountByNumber(number)
+    if acc == nil {
+        return fmt.Errorf("account %d %w", number, storage.ErrNotFound)
+    }
+    acc.DormancyNoticeAt = copyTime(noticeAt)
+    acc.DormantSince = copyTime(dormantSince)
+
+    return nil
+}
+
+func copyTime(t *time.Time) *time.Time {
+    if t == nil {
+        return nil
+    }
+    c := *t
+    return &c
+}
@@ -0,0 +1,127 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateExternalTransfer(ctx context.Context, et *types.ExternalTransfer, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := s.call(ctx, "CreateExternalTransfer"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err := s.checkVelocity(tx, limits); err != nil {
+        return err
+    }
+    if err := s.post(tx, entries); err != nil {
+        return err
+    }
+
+    et.ID = tx.ID
+    cp := *et
+    s.externalTransfers = append(s.externalTransfers, &cp)
+    *et = *s.externalTransfer(&cp)
+
+    return nil
+}
+
+func (s *Store) GetExternalTransfer(ctx context.Context, id int) (*types.ExternalTransfer, error) {
+    if err := s.call(ctx, "GetExternalTransfer"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, et := range s.externalTransfers {
+        if et.ID == id {
+            return s.externalTransfer(et), nil
+        }
+    }
+
+    return nil, fmt.Errorf("external transfer %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) GetExternalTransfersByAccount(ctx context.Context, number int64) ([]*types.ExternalTransfer, error) {
+    if err := s.call(ctx, "GetExternalTransfersByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    transfers := []*types.ExternalTransfer{}
+    for _, et := range s.externalTransfers {
+        if c := s.externalTransfer(et); c.FromAccount == number {
+            transfers = append(transfers, c)
+        }
+    }
+
+    return transfers, nil
+}
+
+func (s *Store) GetPendingExternalTransfers(ctx context.Context) ([]*types.ExternalTransfer, error) {
+    if err := s.call(ctx, "GetPendingExternalTransfers"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    transfers := []*types.ExternalTransfer{}
+    for _, et := range s.externalTransfers {
+        if c := s.externalTransfer(et); c.Status == types.StatusPending {
+            transfers = append(transfers, c)
+        }
+    }
+
+    return transfers, nil
+}
+
+func (s *Store) ReturnExternalTransfer(ctx context.Context, id int, reason string, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := s.call(ctx, "ReturnExternalTransfer"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err := s.settle(id, types.StatusFailed, reversal, entries); err != nil {
+        return err
+    }
+    for _, et := range s.externalTransfers {
+        if et.ID == id {
+            et.ReturnReason = reason
+        }
+    }
+
+    return nil
+}
+
+// externalTransfer returns a copy of et filled in from its transaction.
+func (s *Store) externalTransfer(et *types.ExternalTransfer) *types.ExternalTransfer {
+    c := *et
+    for _, tx := range s.transactions {
+        if tx.ID == et.ID {
+            c.FromAccount = tx.FromAccount
+            c.Amount = tx.Amount
+            c.Status = tx.Status
+            c.Network = tx.Provider
+            c.Reference = tx.Reference
+            c.CreatedAt = tx.CreatedAt
+        }
+    }
+    return &c
+}
@@ -0,0 +1,179 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "slices"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateFraudCase(ctx context.Context, c *types.FraudCase) error {
+    if err := s.call(ctx, "CreateFraudCase"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastFraudCaseID++
+    c.ID = s.lastFraudCaseID
+    s.fraudCases = append(s.fraudCases, copyFraudCase(c))
+
+    return nil
+}
+
+func (s *Store) GetFraudCase(ctx context.Context, id int) (*types.FraudCase, error) {
+    if err := s.call(ctx, "GetFraudCase"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    c := s.fraudCase(id)
+    if c == nil {
+        return nil, fmt.Errorf("fraud case %d %w", id, storage.ErrNotFound)
+    }
+
+    return copyFraudCase(c), nil
+}
+
+func (s *Store) GetFraudCasesByStatus(ctx context.Context, status string) ([]*types.FraudCase, error) {
+    if err := s.call(ctx, "GetFraudCasesByStatus"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    cases := []*types.FraudCase{}
+    for _, c := range s.fraudCases {
+        if c.Status == status {
+            cases = append(cases, copyFraudCase(c))
+        }
+    }
+
+    return cases, nil
+}
+
+func (s *Store) ApproveFraudCase(ctx context.Context, c *types.FraudCase, tx *types.Transaction, entries []*types.LedgerEntry, et *types.ExternalTransfer) error {
+    if err := s.call(ctx, "ApproveFraudCase"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored, err := s.pendingFraudCase(c.ID)
+    if err != nil {
+        return err
+    }
+    if err := s.post(tx, entries); err != nil {
+        return err
+    }
+    if et != nil {
+        et.ID = tx.ID
+        cp := *et
+        s.externalTransfers = append(s.externalTransfers, &cp)
+        *et = *s.externalTransfer(&cp)
+    }
+
+    txID, decidedAt := tx.ID, tx.CreatedAt
+    stored.Status = types.FraudCaseApproved
+    stored.TransactionID = &txID
+    stored.DecidedAt = &decidedAt
+    *c = *copyFraudCase(stored)
+
+    return nil
+}
+
+func (s *Store) RejectFraudCase(ctx context.Context, c *types.FraudCase, at time.Time) error {
+    if err := s.call(ctx, "RejectFraudCase"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored, err := s.pendingFraudCase(c.ID)
+    if err != nil {
+        return err
+    }
+
+    stored.Status = types.FraudCaseRejected
+    stored.DecidedAt = &at
+    *c = *copyFraudCase(stored)
+
+    return nil
+}
+
+func (s *Store) GetFraudHistory(ctx context.Context, from, to int64, external *types.ExternalTransferRequest, since time.Time) (*types.FraudHistory, error) {
+    if err := s.call(ctx, "GetFraudHistory"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    h := new(types.FraudHistory)
+    for _, t := range s.transactions {
+        if t.FromAccount != from && t.ToAccount != from {
+            continue
+        }
+        if t.CreatedAt.After(h.LastActivity) {
+            h.LastActivity = t.CreatedAt
+        }
+        if t.FromAccount != from {
+            continue
+        }
+        if to != 0 && t.ToAccount == to {
+            h.PaidBefore = true
+        }
+        if (t.Kind == types.TransactionTransfer || t.Kind == types.TransactionClosure) && t.Status != types.StatusFailed && t.CreatedAt.After(since) {
+            h.SentSince += t.Amount
+        }
+    }
+    if external != nil {
+        for _, et := range s.externalTransfers {
+            c := s.externalTransfer(et)
+            if c.FromAccount == from && c.RoutingNumber == external.RoutingNumber && c.ToAccount == external.ToAccount && c.Status != types.StatusFailed {
+                h.PaidBefore = true
+            }
+        }
+    }
+
+    return h, nil
+}
+
+func (s *Store) fraudCase(id int) *types.FraudCase {
+    for _, c := range s.fraudCases {
+        if c.ID == id {
+            return c
+        }
+    }
+    return nil
+}
+
+func (s *Store) pendingFraudCase(id int) (*types.FraudCase, error) {
+    c := s.fraudCase(id)
+    if c == nil || c.Status != types.FraudCasePending {
+        return nil, fmt.Errorf("fraud case %d: %w", id, storage.ErrCaseClosed)
+    }
+    return c, nil
+}
+
+func copyFraudCase(c *types.FraudCase) *types.FraudCase {
+    cp := *c
+    cp.Reasons = slices.Clone(c.Reasons)
+    if c.External != nil {
+        external := *c.External
+        cp.External = &external
+    }
+    return &cp
+}
@@ -0,0 +1,112 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "slices"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateGrant(ctx context.Context, g *types.Grant) error {
+    if err := s.call(ctx, "CreateGrant"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, existing := range s.grants {
+        if existing.AccountNumber == g.AccountNumber && existing.GranteeNumber == g.GranteeNumber && existing.RevokedAt == nil {
+            revokedAt := g.CreatedAt
+            existing.RevokedAt = &revokedAt
+        }
+    }
+
+    s.lastGrantID++
+    g.ID = s.lastGrantID
+    s.grants = append(s.grants, copyGrant(g))
+
+    return nil
+}
+
+func (s *Store) GetGrant(ctx context.Context, id int) (*types.Grant, error) {
+    if err := s.call(ctx, "GetGrant"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, g := range s.grants {
+        if g.ID == id {
+            return copyGrant(g), nil
+        }
+    }
+
+    return nil, fmt.Errorf("grant %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) GetGrantsByAccount(ctx context.Context, number int64) ([]*types.Grant, error) {
+    if err := s.call(ctx, "GetGrantsByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    grants := []*types.Grant{}
+    for _, g := range s.grants {
+        if g.AccountNumber == number {
+            grants = append(grants, copyGrant(g))
+        }
+    }
+
+    return grants, nil
+}
+
+func (s *Store) GetActiveGrant(ctx context.Context, account, grantee int64) (*types.Grant, error) {
+    if err := s.call(ctx, "GetActiveGrant"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, g := range s.grants {
+        if g.AccountNumber == account && g.GranteeNumber == grantee && g.RevokedAt == nil {
+            return copyGrant(g), nil
+        }
+    }
+
+    return nil, fmt.Errorf("grant for %d on account %d %w", grantee, account, storage.ErrNotFound)
+}
+
+func (s *Store) RevokeGrant(ctx context.Context, id int, t time.Time) error {
+    if err := s.call(ctx, "RevokeGrant"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, g := range s.grants {
+        if g.ID == id && g.RevokedAt == nil {
+            g.RevokedAt = &t
+        }
+    }
+
+    return nil
+}
+
+func copyGrant(g *types.Grant) *types.Grant {
+    c := *g
+    c.Scopes = slices.Clone(g.Scopes)
+    if g.RevokedAt != nil {
+        revokedAt := *g.RevokedAt
+        c.RevokedAt = &revokedAt
+    }
+    return &c
+}
@@ -0,0 +1,76 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) ImportAccounts(ctx context.Context, imports []*types.AccountImport) error {
+    if err := s.call(ctx, "ImportAccounts"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    // all or nothing, like the database transaction
+    seen := map[int64]bool{}
+    for _, imp := range imports {
+        n := imp.Account.Number
+        if seen[n] || s.accountByNumber(n) != nil {
+            return fmt.Errorf("account %d: %w", n, storage.ErrNumberTaken)
+        }
+        seen[n] = true
+    }
+
+    for _, imp := range imports {
+        s.lastAccountID++
+        imp.Account.ID = s.lastAccountID
+        s.accounts = append(s.accounts, copyAccount(imp.Account))
+
+        // an opening balance from suspense can't fail once the account exists
+        if imp.Balance > 0 {
+            tx := &types.Transaction{
+                Kind: types.TransactionOpening,
+                FromAccount: types.SuspenseAccountNumber,
+                ToAccount: imp.Account.Number,
+                Amount: imp.Balance,
+                CreatedAt: imp.Account.CreatedAt,
+            }
+            s.post(tx, types.NewEntries(types.SuspenseAccountNumber, imp.Account.Number, imp.Balance))
+            imp.Account.Balance = imp.Balance
+        }
+
+        if imp.Activation != nil {
+            cp := *imp.Activation
+            s.activations[cp.AccountNumber] = &cp
+        }
+    }
+
+    return nil
+}
+
+func (s *Store) ActivateAccount(ctx context.Context, number int64, tokenHash, encryptedPassword string, now time.Time) error {
+    if err := s.call(ctx, "ActivateAccount"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    a, ok := s.activations[number]
+    if !ok || a.TokenHash != tokenHash || !a.ExpiresAt.After(now) {
+        return fmt.Errorf("activation for account %d %w", number, storage.ErrNotFound)
+    }
+    delete(s.activations, number)
+
+    if acc := s.accountByNumber(number); acc != nil {
+        acc.EncryptedPassword = encryptedPassword
+    }
+
+    return nil
+}
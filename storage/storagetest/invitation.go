@@ -0,0 +1,95 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateAccountInvitation(ctx context.Context, inv *types.AccountInvitation) error {
+    if err := s.call(ctx, "CreateAccountInvitation"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastAccountInvitationID++
+    inv.ID = s.lastAccountInvitationID
+    c := *inv
+    s.accountInvitations = append(s.accountInvitations, &c)
+
+    return nil
+}
+
+func (s *Store) GetAccountInvitations(ctx context.Context, invitedBy int64) ([]*types.AccountInvitation, error) {
+    if err := s.call(ctx, "GetAccountInvitations"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    invitations := []*types.AccountInvitation{}
+    for _, inv := range s.accountInvitations {
+        if inv.InvitedBy == invitedBy {
+            c := *inv
+            invitations = append(invitations, &c)
+        }
+    }
+    sort.Slice(invitations, func(i, j int) bool { return invitations[i].ID > invitations[j].ID })
+
+    return invitations, nil
+}
+
+func (s *Store) AcceptAccountInvitation(ctx context.Context, tokenHash string, acc *types.Account, now time.Time) (*types.AccountInvitation, error) {
+    if err := s.call(ctx, "AcceptAccountInvitation"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var inv *types.AccountInvitation
+    for _, i := range s.accountInvitations {
+        if i.TokenHash == tokenHash && i.Status == types.InvitationPending && i.ExpiresAt.After(now) {
+            inv = i
+        }
+    }
+    if inv == nil {
+        return nil, fmt.Errorf("invitation %w", storage.ErrNotFound)
+    }
+    if inv.Permission != "" && inv.InvitedBy != 0 {
+        if inviter := s.accountByNumber(inv.InvitedBy); inviter == nil || inviter.ClosedAt != nil {
+            return nil, fmt.Errorf("account %d: %w", inv.InvitedBy, storage.ErrAccountClosed)
+        }
+    }
+    if s.accountByNumber(acc.Number) != nil {
+        return nil, fmt.Errorf("account %d: %w", acc.Number, storage.ErrNumberTaken)
+    }
+
+    acc.Email = inv.Email
+    s.lastAccountID++
+    acc.ID = s.lastAccountID
+    s.accounts = append(s.accounts, copyAccount(acc))
+
+    if inv.Permission != "" && inv.InvitedBy != 0 {
+        s.owners = append(s.owners, &types.AccountOwner{
+            AccountNumber: inv.InvitedBy,
+            OwnerNumber: acc.Number,
+            Permission: inv.Permission,
+            CreatedAt: now,
+        })
+    }
+
+    inv.Status = types.InvitationAccepted
+    inv.AccountNumber = acc.Number
+    inv.AcceptedAt = &now
+    c := *inv
+
+    return &c, nil
+}
@@ -0,0 +1,60 @@
+package storagetest
+
+import (
+    "context"
+    "sort"
+
+    "gobank/types"
+)
+
+// LockOut makes TryLeaderLock report that another instance holds every
+// leader lock, until it is called again with false.
+func (s *Store) LockOut(lockedOut bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lockedOut = lockedOut
+}
+
+func (s *Store) TryLeaderLock(ctx context.Context, name string) (bool, error) {
+    if err := s.call(ctx, "TryLeaderLock"); err != nil {
+        return false, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return !s.lockedOut, nil
+}
+
+func (s *Store) SaveJobStatus(ctx context.Context, j *types.JobStatus) error {
+    if err := s.call(ctx, "SaveJobStatus"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    c := *j
+    s.jobStatuses[j.Name] = &c
+
+    return nil
+}
+
+func (s *Store) GetJobStatuses(ctx context.Context) ([]*types.JobStatus, error) {
+    if err := s.call(ctx, "GetJobStatuses"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    statuses := []*types.JobStatus{}
+    for _, j := range s.jobStatuses {
+        c := *j
+        statuses = append(statuses, &c)
+    }
+    sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+    return statuses, nil
+}
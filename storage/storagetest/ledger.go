@@ -0,0 +1,315 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) PostTransaction(ctx context.Context, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := s.call(ctx, "PostTransaction"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.post(tx, entries)
+}
+
+func (s *Store) PostTransfer(ctx context.Context, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := s.call(ctx, "PostTransfer"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err := s.checkVelocity(tx, limits); err != nil {
+        return err
+    }
+
+    return s.post(tx, entries)
+}
+
+func (s *Store) PreviewTransfer(ctx context.Context, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := s.call(ctx, "PreviewTransfer"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err := s.checkVelocity(tx, limits); err != nil {
+        return err
+    }
+    _, err := s.checkFunds(entries, tx.CreatedAt)
+
+    return err
+}
+
+// checkVelocity does the velocity check of PostTransfer with s.mu held,
+// before tx is posted.
+func (s *Store) checkVelocity(tx *types.Transaction, limits []types.VelocityLimit) error {
+    for _, limit := range limits {
+        count, sum := 1, tx.Amount
+        since := tx.CreatedAt.Add(-limit.Window)
+        for _, t := range s.transactions {
+            if t.FromAccount == tx.FromAccount && (t.Kind == types.TransactionTransfer || t.Kind == types.TransactionClosure) && t.Status != types.StatusFailed && t.CreatedAt.After(since) {
+                count++
+                sum += t.Amount
+            }
+        }
+        if (limit.Count > 0 && count > limit.Count) || (limit.Amount > 0 && sum > limit.Amount) {
+            return fmt.Errorf("account %d over %d transfers or %d within %s: %w", tx.FromAccount, limit.Count, limit.Amount, limit.Window, storage.ErrVelocityExceeded)
+        }
+    }
+
+    return nil
+}
+
+func (s *Store) SettleTransaction(ctx context.Context, id int, status string, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := s.call(ctx, "SettleTransaction"); err != nil {
+        return err
+    }
+    if reversal != nil {
+        if err := types.ValidateEntries(entries); err != nil {
+            return err
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.settle(id, status, reversal, entries)
+}
+
+// settle does the work of SettleTransaction with s.mu held.
+func (s *Store) settle(id int, status string, reversal *types.Transaction, entries []*types.LedgerEntry) error {
+    var pending *types.Transaction
+    for _, tx := range s.transactions {
+        if tx.ID == id && tx.Status == types.StatusPending {
+            pending = tx
+        }
+    }
+    if pending == nil {
+        return fmt.Errorf("transaction %d: %w", id, storage.ErrNotPending)
+    }
+
+    if reversal != nil {
+        if err := s.post(reversal, entries); err != nil {
+            return err
+        }
+    }
+    pending.Status = status
+
+    return nil
+}
+
+// post does the work of PostTransaction with s.mu held.
+func (s *Store) post(tx *types.Transaction, entries []*types.LedgerEntry) error {
+    deltas, err := s.checkFunds(entries, tx.CreatedAt)
+    if err != nil {
+        return err
+    }
+    if tx.IdempotencyKey != "" {
+        for _, existing := range s.transactions {
+            if existing.FromAccount == tx.FromAccount && existing.IdempotencyKey == tx.IdempotencyKey {
+                return fmt.Errorf("transaction from account %d: %w", tx.FromAccount, storage.ErrIdempotencyKeyUsed)
+            }
+        }
+    }
+
+    if tx.Status == "" {
+        tx.Status = types.StatusCompleted
+    }
+    s.lastTransactionID++
+    tx.ID = s.lastTransactionID
+    c := *tx
+    s.transactions = append(s.transactions, &c)
+
+    for _, e := range entries {
+        s.lastEntryID++
+        e.ID = s.lastEntryID
+        e.TransactionID = tx.ID
+        e.CreatedAt = tx.CreatedAt
+        c := *e
+        s.entries = append(s.entries, &c)
+    }
+
+    for n, delta := range deltas {
+        s.accountByNumber(n).Balance += delta
+    }
+
+    return nil
+}
+
+// checkFunds returns how entries change each customer account, failing
+// like posting them would when an account is missing or can't pay.
+func (s *Store) checkFunds(entries []*types.LedgerEntry, at time.Time) (map[int64]int64, error) {
+    deltas := map[int64]int64{}
+    for _, e := range entries {
+        if !types.IsInternalAccount(e.AccountNumber) {
+            deltas[e.AccountNumber] += e.Amount
+        }
+    }
+
+    for n, delta := range deltas {
+        acc := s.accountByNumber(n)
+        if acc == nil {
+            return nil, fmt.Errorf("account %d %w", n, storage.ErrNotFound)
+        }
+        if delta < 0 && acc.Balance-s.potTotal(n)-s.disputedTotal(n)-s.heldTotal(n, at)+delta < 0 {
+            return nil, fmt.Errorf("account %d: %w", n, storage.ErrInsufficientFunds)
+        }
+    }
+
+    return deltas, nil
+}
+
+func (s *Store) GetLedgerEntries(ctx context.Context) ([]*types.LedgerEntry, error) {
+    if err := s.call(ctx, "GetLedgerEntries"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    entries := []*types.LedgerEntry{}
+    for _, e := range s.entries {
+        c := *e
+        entries = append(entries, &c)
+    }
+
+    return entries, nil
+}
+
+func (s *Store) GetLedgerEntriesByAccount(ctx context.Context, number int64) ([]*types.LedgerEntry, error) {
+    if err := s.call(ctx, "GetLedgerEntriesByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    entries := []*types.LedgerEntry{}
+    for _, e := range s.entries {
+        if e.AccountNumber == number {
+            c := *e
+            entries = append(entries, &c)
+        }
+    }
+
+    return entries, nil
+}
+
+func (s *Store) GetLedgerEntriesByTransaction(ctx context.Context, id int) ([]*types.LedgerEntry, error) {
+    if err := s.call(ctx, "GetLedgerEntriesByTransaction"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    entries := []*types.LedgerEntry{}
+    for _, e := range s.entries {
+        if e.TransactionID == id {
+            c := *e
+            entries = append(entries, &c)
+        }
+    }
+
+    return entries, nil
+}
+
+func (s *Store) GetBalances(ctx context.Context, number int64, t time.Time) (*types.Balances, error) {
+    if err := s.call(ctx, "GetBalances"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    acc := s.accountByNumber(number)
+    if acc == nil {
+        return nil, fmt.Errorf("account %d %w", number, storage.ErrNotFound)
+    }
+
+    var pending, held int64
+    for _, tx := range s.transactions {
+        if tx.FromAccount == number && tx.Status == types.StatusPending {
+            pending += tx.Amount
+        }
+    }
+    for _, h := range s.holds {
+        if h.AccountNumber == number && h.Status == types.HoldActive && h.ExpiresAt.After(t) {
+            held += h.Amount
+        }
+    }
+
+    return types.NewBalances(acc.Balance, pending, held+s.disputedTotal(number), s.potTotal(number)), nil
+}
+
+func (s *Store) GetLedgerBalance(ctx context.Context, number int64) (int64, error) {
+    if err := s.call(ctx, "GetLedgerBalance"); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var balance int64
+    for _, e := range s.entries {
+        if e.AccountNumber == number {
+            balance += e.Amount
+        }
+    }
+
+    return balance, nil
+}
+
+func (s *Store) GetLedgerBalances(ctx context.Context) (map[int64]int64, error) {
+    if err := s.call(ctx, "GetLedgerBalances"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    balances := map[int64]int64{}
+    for _, e := range s.entries {
+        balances[e.AccountNumber] += e.Amount
+    }
+
+    return balances, nil
+}
+
+func (s *Store) GetLedgerSumBetween(ctx context.Context, number int64, from, to time.Time) (int64, error) {
+    if err := s.call(ctx, "GetLedgerSumBetween"); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var sum int64
+    for _, e := range s.entries {
+        if e.AccountNumber == number && !e.CreatedAt.Before(from) && e.CreatedAt.Before(to) {
+            sum += e.Amount
+        }
+    }
+
+    return sum, nil
+}
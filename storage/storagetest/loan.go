@@ -0,0 +1,209 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateLoan(ctx context.Context, l *types.Loan) error {
+    if err := s.call(ctx, "CreateLoan"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastLoanID++
+    l.ID = s.lastLoanID
+    c := *l
+    s.loans = append(s.loans, &c)
+
+    return nil
+}
+
+func (s *Store) GetLoan(ctx context.Context, id int) (*types.Loan, error) {
+    if err := s.call(ctx, "GetLoan"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    l := s.loan(id)
+    if l == nil {
+        return nil, fmt.Errorf("loan %d %w", id, storage.ErrNotFound)
+    }
+    c := *l
+
+    return &c, nil
+}
+
+func (s *Store) GetLoansByAccount(ctx context.Context, number int64) ([]*types.Loan, error) {
+    if err := s.call(ctx, "GetLoansByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.filterLoans(func(l *types.Loan) bool { return l.AccountNumber == number }), nil
+}
+
+func (s *Store) GetLoansByStatus(ctx context.Context, status string) ([]*types.Loan, error) {
+    if err := s.call(ctx, "GetLoansByStatus"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.filterLoans(func(l *types.Loan) bool { return l.Status == status }), nil
+}
+
+func (s *Store) RejectLoan(ctx context.Context, id int) error {
+    if err := s.call(ctx, "RejectLoan"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    l := s.loan(id)
+    if l == nil || l.Status != types.LoanApplied {
+        return fmt.Errorf("loan %d: %w", id, storage.ErrLoanState)
+    }
+    l.Status = types.LoanRejected
+
+    return nil
+}
+
+func (s *Store) DisburseLoan(ctx context.Context, l *types.Loan, schedule []*types.LoanInstallment, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := s.call(ctx, "DisburseLoan"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored := s.loan(l.ID)
+    if stored == nil || stored.Status != types.LoanApplied {
+        return fmt.Errorf("loan %d: %w", l.ID, storage.ErrLoanState)
+    }
+    if err := s.post(tx, entries); err != nil {
+        return err
+    }
+
+    for _, inst := range schedule {
+        c := *inst
+        s.installments = append(s.installments, &c)
+    }
+    disbursedAt := tx.CreatedAt
+    stored.Status = types.LoanActive
+    stored.Outstanding = stored.Principal
+    stored.DisbursedAt = &disbursedAt
+    *l = *stored
+
+    return nil
+}
+
+func (s *Store) GetLoanSchedule(ctx context.Context, loanID int) ([]*types.LoanInstallment, error) {
+    if err := s.call(ctx, "GetLoanSchedule"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    schedule := []*types.LoanInstallment{}
+    for _, inst := range s.installments {
+        if inst.LoanID == loanID {
+            c := *inst
+            schedule = append(schedule, &c)
+        }
+    }
+
+    return schedule, nil
+}
+
+func (s *Store) GetDueInstallments(ctx context.Context, t time.Time) ([]*types.LoanInstallment, error) {
+    if err := s.call(ctx, "GetDueInstallments"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    due := []*types.LoanInstallment{}
+    for _, inst := range s.installments {
+        l := s.loan(inst.LoanID)
+        if l.Status == types.LoanActive && inst.Status != types.InstallmentPaid && !inst.DueDate.After(t) {
+            c := *inst
+            due = append(due, &c)
+        }
+    }
+    sort.SliceStable(due, func(i, j int) bool { return due[i].DueDate.Before(due[j].DueDate) })
+
+    return due, nil
+}
+
+func (s *Store) ApplyLoanPayment(ctx context.Context, inst *types.LoanInstallment, principal int64, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := s.call(ctx, "ApplyLoanPayment"); err != nil {
+        return err
+    }
+    if tx != nil {
+        if err := types.ValidateEntries(entries); err != nil {
+            return err
+        }
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if tx != nil {
+        if err := s.post(tx, entries); err != nil {
+            return err
+        }
+    }
+
+    for _, stored := range s.installments {
+        if stored.LoanID == inst.LoanID && stored.Number == inst.Number {
+            *stored = *inst
+        }
+    }
+
+    l := s.loan(inst.LoanID)
+    l.Outstanding -= principal
+    if l.Outstanding <= 0 {
+        l.Status = types.LoanPaidOff
+    }
+
+    return nil
+}
+
+func (s *Store) loan(id int) *types.Loan {
+    for _, l := range s.loans {
+        if l.ID == id {
+            return l
+        }
+    }
+    return nil
+}
+
+func (s *Store) filterLoans(match func(*types.Loan) bool) []*types.Loan {
+    loans := []*types.Loan{}
+    for _, l := range s.loans {
+        if match(l) {
+            c := *l
+            loans = append(loans, &c)
+        }
+    }
+    return loans
+}
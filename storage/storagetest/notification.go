@@ -0,0 +1,94 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "maps"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) GetNotificationPreferences(ctx context.Context, number int64) (*types.NotificationPreferences, error) {
+    if err := s.call(ctx, "GetNotificationPreferences"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    prefs, ok := s.preferences[number]
+    if !ok {
+        return types.DefaultNotificationPreferences(number), nil
+    }
+
+    return copyPreferences(prefs), nil
+}
+
+func (s *Store) SavePreferences(ctx context.Context, prefs *types.Preferences) error {
+    if err := s.call(ctx, "SavePreferences"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    a := s.accountByNumber(prefs.AccountNumber)
+    if a == nil {
+        return fmt.Errorf("account %d %w", prefs.AccountNumber, storage.ErrNotFound)
+    }
+    a.Locale = prefs.Locale
+    s.preferences[prefs.AccountNumber] = copyPreferences(&prefs.NotificationPreferences)
+
+    return nil
+}
+
+func copyPreferences(prefs *types.NotificationPreferences) *types.NotificationPreferences {
+    c := *prefs
+    c.Events = maps.Clone(prefs.Events)
+    return &c
+}
+
+func (s *Store) SavePhoneVerification(ctx context.Context, v *types.PhoneVerification) error {
+    if err := s.call(ctx, "SavePhoneVerification"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    c := *v
+    s.phoneVerifications[v.AccountNumber] = &c
+
+    return nil
+}
+
+func (s *Store) GetPhoneVerification(ctx context.Context, number int64) (*types.PhoneVerification, error) {
+    if err := s.call(ctx, "GetPhoneVerification"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    v, ok := s.phoneVerifications[number]
+    if !ok {
+        return nil, fmt.Errorf("phone verification for account %d %w", number, storage.ErrNotFound)
+    }
+
+    c := *v
+    return &c, nil
+}
+
+func (s *Store) DeletePhoneVerification(ctx context.Context, number int64) error {
+    if err := s.call(ctx, "DeletePhoneVerification"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    delete(s.phoneVerifications, number)
+
+    return nil
+}
@@ -0,0 +1,182 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateOwnerInvitation(ctx context.Context, inv *types.OwnerInvitation) error {
+    if err := s.call(ctx, "CreateOwnerInvitation"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastInvitationID++
+    inv.ID = s.lastInvitationID
+    c := *inv
+    s.invitations = append(s.invitations, &c)
+
+    return nil
+}
+
+func (s *Store) GetOwnerInvitation(ctx context.Context, id int) (*types.OwnerInvitation, error) {
+    if err := s.call(ctx, "GetOwnerInvitation"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    inv := s.invitation(id)
+    if inv == nil {
+        return nil, fmt.Errorf("invitation %d %w", id, storage.ErrNotFound)
+    }
+    c := *inv
+
+    return &c, nil
+}
+
+func (s *Store) GetOwnerInvitationsFor(ctx context.Context, number int64) ([]*types.OwnerInvitation, error) {
+    if err := s.call(ctx, "GetOwnerInvitationsFor"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    invitations := []*types.OwnerInvitation{}
+    for _, inv := range s.invitations {
+        if inv.InviteeNumber == number && inv.Status == types.InvitationPending {
+            c := *inv
+            invitations = append(invitations, &c)
+        }
+    }
+
+    return invitations, nil
+}
+
+func (s *Store) AcceptOwnerInvitation(ctx context.Context, id int) (*types.AccountOwner, error) {
+    if err := s.call(ctx, "AcceptOwnerInvitation"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    inv := s.invitation(id)
+    if inv == nil || inv.Status != types.InvitationPending {
+        return nil, fmt.Errorf("invitation %d: %w", id, storage.ErrInvitationClosed)
+    }
+    inv.Status = types.InvitationAccepted
+
+    owner := &types.AccountOwner{
+        AccountNumber: inv.AccountNumber,
+        OwnerNumber: inv.InviteeNumber,
+        Permission: inv.Permission,
+        CreatedAt: time.Now().UTC(),
+    }
+    if existing := s.owner(inv.AccountNumber, inv.InviteeNumber); existing != nil {
+        existing.Permission = inv.Permission
+        owner.CreatedAt = existing.CreatedAt
+    } else {
+        c := *owner
+        s.owners = append(s.owners, &c)
+    }
+
+    return owner, nil
+}
+
+func (s *Store) DeclineOwnerInvitation(ctx context.Context, id int) error {
+    if err := s.call(ctx, "DeclineOwnerInvitation"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    inv := s.invitation(id)
+    if inv == nil || inv.Status != types.InvitationPending {
+        return fmt.Errorf("invitation %d: %w", id, storage.ErrInvitationClosed)
+    }
+    inv.Status = types.InvitationDeclined
+
+    return nil
+}
+
+func (s *Store) GetAccountOwners(ctx context.Context, number int64) ([]*types.AccountOwner, error) {
+    if err := s.call(ctx, "GetAccountOwners"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    owners := []*types.AccountOwner{}
+    for _, o := range s.owners {
+        if o.AccountNumber == number {
+            c := *o
+            owners = append(owners, &c)
+        }
+    }
+
+    return owners, nil
+}
+
+func (s *Store) GetAccountOwner(ctx context.Context, account, owner int64) (*types.AccountOwner, error) {
+    if err := s.call(ctx, "GetAccountOwner"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    o := s.owner(account, owner)
+    if o == nil {
+        return nil, fmt.Errorf("owner %d of account %d %w", owner, account, storage.ErrNotFound)
+    }
+    c := *o
+
+    return &c, nil
+}
+
+func (s *Store) DeleteAccountOwner(ctx context.Context, account, owner int64) error {
+    if err := s.call(ctx, "DeleteAccountOwner"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, o := range s.owners {
+        if o.AccountNumber == account && o.OwnerNumber == owner {
+            s.owners = append(s.owners[:i], s.owners[i+1:]...)
+            break
+        }
+    }
+
+    return nil
+}
+
+func (s *Store) invitation(id int) *types.OwnerInvitation {
+    for _, inv := range s.invitations {
+        if inv.ID == id {
+            return inv
+        }
+    }
+    return nil
+}
+
+func (s *Store) owner(account, owner int64) *types.AccountOwner {
+    for _, o := range s.owners {
+        if o.AccountNumber == account && o.OwnerNumber == owner {
+            return o
+        }
+    }
+    return nil
+}
@@ -0,0 +1,119 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreatePaymentRequest(ctx context.Context, pr *types.PaymentRequest) error {
+    if err := s.call(ctx, "CreatePaymentRequest"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastPaymentRequestID++
+    pr.ID = s.lastPaymentRequestID
+    c := *pr
+    s.paymentRequests = append(s.paymentRequests, &c)
+
+    return nil
+}
+
+func (s *Store) GetPaymentRequest(ctx context.Context, id int) (*types.PaymentRequest, error) {
+    if err := s.call(ctx, "GetPaymentRequest"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    pr := s.paymentRequest(id)
+    if pr == nil {
+        return nil, fmt.Errorf("payment request %d %w", id, storage.ErrNotFound)
+    }
+    c := *pr
+
+    return &c, nil
+}
+
+func (s *Store) GetPaymentRequestsByAccount(ctx context.Context, number int64) ([]*types.PaymentRequest, error) {
+    if err := s.call(ctx, "GetPaymentRequestsByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    prs := []*types.PaymentRequest{}
+    for i := len(s.paymentRequests) - 1; i >= 0; i-- {
+        pr := s.paymentRequests[i]
+        if pr.RequesterAccount == number || pr.PayerAccount == number {
+            c := *pr
+            prs = append(prs, &c)
+        }
+    }
+
+    return prs, nil
+}
+
+func (s *Store) AcceptPaymentRequest(ctx context.Context, id int, tx *types.Transaction, entries []*types.LedgerEntry, limits []types.VelocityLimit) error {
+    if err := s.call(ctx, "AcceptPaymentRequest"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    pr := s.paymentRequest(id)
+    if pr == nil {
+        return fmt.Errorf("payment request %d %w", id, storage.ErrNotFound)
+    }
+    if pr.Status != types.RequestPending {
+        return fmt.Errorf("payment request %d: %w", id, storage.ErrRequestClosed)
+    }
+
+    if err := s.checkVelocity(tx, limits); err != nil {
+        return err
+    }
+    if err := s.post(tx, entries); err != nil {
+        return err
+    }
+    pr.Status = types.RequestAccepted
+    pr.TransactionID = tx.ID
+
+    return nil
+}
+
+func (s *Store) ClosePaymentRequest(ctx context.Context, id int, status string) error {
+    if err := s.call(ctx, "ClosePaymentRequest"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    pr := s.paymentRequest(id)
+    if pr == nil || pr.Status != types.RequestPending {
+        return fmt.Errorf("payment request %d: %w", id, storage.ErrRequestClosed)
+    }
+    pr.Status = status
+
+    return nil
+}
+
+func (s *Store) paymentRequest(id int) *types.PaymentRequest {
+    for _, pr := range s.paymentRequests {
+        if pr.ID == id {
+            return pr
+        }
+    }
+    return nil
+}
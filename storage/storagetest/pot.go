@@ -0,0 +1,154 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreatePot(ctx context.Context, p *types.Pot) error {
+    if err := s.call(ctx, "CreatePot"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastPotID++
+    p.ID = s.lastPotID
+    c := *p
+    s.pots = append(s.pots, &c)
+
+    return nil
+}
+
+func (s *Store) GetPot(ctx context.Context, id int) (*types.Pot, error) {
+    if err := s.call(ctx, "GetPot"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    p := s.pot(id)
+    if p == nil {
+        return nil, fmt.Errorf("pot %d %w", id, storage.ErrNotFound)
+    }
+    c := *p
+
+    return &c, nil
+}
+
+func (s *Store) GetPotsByAccount(ctx context.Context, number int64) ([]*types.Pot, error) {
+    if err := s.call(ctx, "GetPotsByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    pots := []*types.Pot{}
+    for _, p := range s.pots {
+        if p.AccountNumber == number {
+            c := *p
+            pots = append(pots, &c)
+        }
+    }
+
+    return pots, nil
+}
+
+func (s *Store) UpdatePot(ctx context.Context, p *types.Pot) error {
+    if err := s.call(ctx, "UpdatePot"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored := s.pot(p.ID)
+    if stored == nil {
+        return fmt.Errorf("pot %d %w", p.ID, storage.ErrNotFound)
+    }
+    stored.Name = p.Name
+    stored.Target = p.Target
+    stored.RoundUp = p.RoundUp
+
+    return nil
+}
+
+func (s *Store) DeletePot(ctx context.Context, id int) error {
+    if err := s.call(ctx, "DeletePot"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, p := range s.pots {
+        if p.ID == id {
+            s.pots = append(s.pots[:i], s.pots[i+1:]...)
+            break
+        }
+    }
+
+    return nil
+}
+
+func (s *Store) MovePotMoney(ctx context.Context, id int, amount int64) (*types.Pot, error) {
+    if err := s.call(ctx, "MovePotMoney"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    p := s.pot(id)
+    if p == nil {
+        return nil, fmt.Errorf("pot %d %w", id, storage.ErrNotFound)
+    }
+
+    if amount > 0 {
+        acc := s.accountByNumber(p.AccountNumber)
+        var held int64
+        now := time.Now().UTC()
+        for _, h := range s.holds {
+            if h.AccountNumber == p.AccountNumber && h.Status == types.HoldActive && h.ExpiresAt.After(now) {
+                held += h.Amount
+            }
+        }
+        if acc.Balance-s.potTotal(p.AccountNumber)-s.disputedTotal(p.AccountNumber)-held < amount {
+            return nil, fmt.Errorf("account %d: %w", p.AccountNumber, storage.ErrInsufficientFunds)
+        }
+    }
+    if p.Balance+amount < 0 {
+        return nil, fmt.Errorf("pot %d: %w", id, storage.ErrInsufficientFunds)
+    }
+    p.Balance += amount
+    c := *p
+
+    return &c, nil
+}
+
+func (s *Store) pot(id int) *types.Pot {
+    for _, p := range s.pots {
+        if p.ID == id {
+            return p
+        }
+    }
+    return nil
+}
+
+// potTotal is how much of an account's balance is set aside in pots.
+func (s *Store) potTotal(number int64) int64 {
+    var total int64
+    for _, p := range s.pots {
+        if p.AccountNumber == number {
+            total += p.Balance
+        }
+    }
+    return total
+}
@@ -0,0 +1,151 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateProductRate(ctx context.Context, rate *types.ProductRate) error {
+    if err := s.call(ctx, "CreateProductRate"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastProductRateID++
+    rate.ID = s.lastProductRateID
+    c := *rate
+    s.productRates = append(s.productRates, &c)
+
+    return nil
+}
+
+func (s *Store) GetProductRate(ctx context.Context, id int) (*types.ProductRate, error) {
+    if err := s.call(ctx, "GetProductRate"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    rate := s.productRate(id)
+    if rate == nil {
+        return nil, fmt.Errorf("product rate %d %w", id, storage.ErrNotFound)
+    }
+    c := *rate
+
+    return &c, nil
+}
+
+func (s *Store) GetProductRates(ctx context.Context) ([]*types.ProductRate, error) {
+    if err := s.call(ctx, "GetProductRates"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    rates := []*types.ProductRate{}
+    for _, rate := range s.productRates {
+        c := *rate
+        rates = append(rates, &c)
+    }
+    sort.Slice(rates, func(i, j int) bool {
+        if rates[i].Key != rates[j].Key {
+            return rates[i].Key < rates[j].Key
+        }
+        return rates[i].EffectiveFrom.Before(rates[j].EffectiveFrom)
+    })
+
+    return rates, nil
+}
+
+func (s *Store) GetEffectiveProductRate(ctx context.Context, key string, t time.Time) (*types.ProductRate, error) {
+    if err := s.call(ctx, "GetEffectiveProductRate"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var effective *types.ProductRate
+    for _, rate := range s.productRates {
+        if rate.Key != key || rate.EffectiveFrom.After(t) {
+            continue
+        }
+        if effective == nil || rate.EffectiveFrom.After(effective.EffectiveFrom) {
+            effective = rate
+        }
+    }
+    if effective == nil {
+        return nil, fmt.Errorf("product rate %s %w", key, storage.ErrNotFound)
+    }
+    c := *effective
+
+    return &c, nil
+}
+
+func (s *Store) UpdateProductRate(ctx context.Context, rate *types.ProductRate, now time.Time) error {
+    if err := s.call(ctx, "UpdateProductRate"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored, err := s.futureProductRate(rate.ID, now)
+    if err != nil {
+        return err
+    }
+    stored.Value = rate.Value
+    stored.EffectiveFrom = rate.EffectiveFrom
+
+    return nil
+}
+
+func (s *Store) DeleteProductRate(ctx context.Context, id int, now time.Time) error {
+    if err := s.call(ctx, "DeleteProductRate"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, err := s.futureProductRate(id, now); err != nil {
+        return err
+    }
+    for i, rate := range s.productRates {
+        if rate.ID == id {
+            s.productRates = append(s.productRates[:i], s.productRates[i+1:]...)
+            break
+        }
+    }
+
+    return nil
+}
+
+func (s *Store) productRate(id int) *types.ProductRate {
+    for _, rate := range s.productRates {
+        if rate.ID == id {
+            return rate
+        }
+    }
+    return nil
+}
+
+func (s *Store) futureProductRate(id int, now time.Time) (*types.ProductRate, error) {
+    rate := s.productRate(id)
+    if rate == nil {
+        return nil, fmt.Errorf("product rate %d %w", id, storage.ErrNotFound)
+    }
+    if !rate.EffectiveFrom.After(now) {
+        return nil, fmt.Errorf("product rate %d: %w", id, storage.ErrRateInEffect)
+    }
+    return rate, nil
+}
@@ -0,0 +1,57 @@
+package storagetest
+
+import (
+    "context"
+    "time"
+
+    "gobank/types"
+)
+
+func (s *Store) CreateDiscrepancy(ctx context.Context, d *types.Discrepancy) error {
+    if err := s.call(ctx, "CreateDiscrepancy"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    d.ID = len(s.discrepancies) + 1
+    c := *d
+    s.discrepancies = append(s.discrepancies, &c)
+
+    return nil
+}
+
+func (s *Store) ResolveDiscrepancy(ctx context.Context, id int, at time.Time) error {
+    if err := s.call(ctx, "ResolveDiscrepancy"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, d := range s.discrepancies {
+        if d.ID == id {
+            d.ResolvedAt = &at
+        }
+    }
+
+    return nil
+}
+
+func (s *Store) GetDiscrepancies(ctx context.Context) ([]*types.Discrepancy, error) {
+    if err := s.call(ctx, "GetDiscrepancies"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    discrepancies := []*types.Discrepancy{}
+    for _, d := range s.discrepancies {
+        c := *d
+        discrepancies = append(discrepancies, &c)
+    }
+
+    return discrepancies, nil
+}
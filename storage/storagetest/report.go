@@ -0,0 +1,93 @@
+package storagetest
+
+import (
+    "context"
+    "sort"
+    "time"
+
+    "gobank/types"
+)
+
+func (s *Store) GetDailyFlows(ctx context.Context, from, to time.Time) ([]*types.DailyFlow, error) {
+    if err := s.call(ctx, "GetDailyFlows"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    byDay := map[time.Time]*types.DailyFlow{}
+    active := map[time.Time]map[int64]bool{}
+    for _, e := range s.entries {
+        if e.CreatedAt.Before(from) || !e.CreatedAt.Before(to) {
+            continue
+        }
+
+        day := e.CreatedAt.UTC().Truncate(24 * time.Hour)
+        f, ok := byDay[day]
+        if !ok {
+            f = &types.DailyFlow{Day: day}
+            byDay[day] = f
+            active[day] = map[int64]bool{}
+        }
+        switch {
+        case e.AccountNumber == types.SettlementAccountNumber && e.Amount < 0:
+            f.Deposits -= e.Amount
+        case e.AccountNumber == types.SettlementAccountNumber:
+            f.Withdrawals += e.Amount
+        case !types.IsInternalAccount(e.AccountNumber):
+            active[day][e.AccountNumber] = true
+        }
+    }
+
+    flows := []*types.DailyFlow{}
+    for day, f := range byDay {
+        f.Net = f.Deposits - f.Withdrawals
+        f.ActiveAccounts = len(active[day])
+        flows = append(flows, f)
+    }
+    sort.Slice(flows, func(i, j int) bool { return flows[i].Day.Before(flows[j].Day) })
+
+    return flows, nil
+}
+
+func (s *Store) GetActiveAccountCount(ctx context.Context, from, to time.Time) (int, error) {
+    if err := s.call(ctx, "GetActiveAccountCount"); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    active := map[int64]bool{}
+    for _, e := range s.entries {
+        if !types.IsInternalAccount(e.AccountNumber) && !e.CreatedAt.Before(from) && e.CreatedAt.Before(to) {
+            active[e.AccountNumber] = true
+        }
+    }
+
+    return len(active), nil
+}
+
+func (s *Store) GetLargestTransfers(ctx context.Context, from, to time.Time, limit int) ([]*types.Transaction, error) {
+    if err := s.call(ctx, "GetLargestTransfers"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    txs := []*types.Transaction{}
+    for _, tx := range s.transactions {
+        if tx.Kind == types.TransactionTransfer && tx.Status != types.StatusFailed && !tx.CreatedAt.Before(from) && tx.CreatedAt.Before(to) {
+            c := *tx
+            txs = append(txs, &c)
+        }
+    }
+    sort.SliceStable(txs, func(i, j int) bool { return txs[i].Amount > txs[j].Amount })
+    if len(txs) > limit {
+        txs = txs[:limit]
+    }
+
+    return txs, nil
+}
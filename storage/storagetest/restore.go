@@ -0,0 +1,155 @@
+package storagetest
+
+import (
+    "context"
+
+    "gobank/types"
+)
+
+func (s *Store) RestoreLoan(ctx context.Context, l *types.Loan, schedule []*types.LoanInstallment) error {
+    if err := s.call(ctx, "RestoreLoan"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastLoanID++
+    l.ID = s.lastLoanID
+    c := *l
+    c.DisbursedAt = copyTime(l.DisbursedAt)
+    s.loans = append(s.loans, &c)
+
+    for _, inst := range schedule {
+        inst.LoanID = l.ID
+        c := *inst
+        s.installments = append(s.installments, &c)
+    }
+
+    return nil
+}
+
+func (s *Store) RestoreDispute(ctx context.Context, d *types.Dispute) error {
+    if err := s.call(ctx, "RestoreDispute"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastDisputeID++
+    d.ID = s.lastDisputeID
+    s.disputes = append(s.disputes, copyDispute(d))
+
+    return nil
+}
+
+func (s *Store) RestoreAliasClaim(ctx context.Context, c *types.AliasClaim) error {
+    if err := s.call(ctx, "RestoreAliasClaim"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    cc := *c
+    s.aliasClaims = append(s.aliasClaims, &cc)
+
+    return nil
+}
+
+func (s *Store) RestoreExternalTransfer(ctx context.Context, et *types.ExternalTransfer) error {
+    if err := s.call(ctx, "RestoreExternalTransfer"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    cp := *et
+    s.externalTransfers = append(s.externalTransfers, &cp)
+
+    return nil
+}
+
+func (s *Store) RestorePaymentRequest(ctx context.Context, pr *types.PaymentRequest) error {
+    if err := s.call(ctx, "RestorePaymentRequest"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastPaymentRequestID++
+    pr.ID = s.lastPaymentRequestID
+    c := *pr
+    s.paymentRequests = append(s.paymentRequests, &c)
+
+    return nil
+}
+
+func (s *Store) RestoreFraudCase(ctx context.Context, c *types.FraudCase) error {
+    if err := s.call(ctx, "RestoreFraudCase"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.lastFraudCaseID++
+    c.ID = s.lastFraudCaseID
+    s.fraudCases = append(s.fraudCases, copyFraudCase(c))
+
+    return nil
+}
+
+func (s *Store) RestoreReversal(ctx context.Context, r *types.Reversal) error {
+    if err := s.call(ctx, "RestoreReversal"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    cp := *r
+    s.reversals[r.TransactionID] = &cp
+
+    return nil
+}
+
+func (s *Store) ClearRestored(ctx context.Context) error {
+    if err := s.call(ctx, "ClearRestored"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.accounts = nil
+    s.transactions = nil
+    s.entries = nil
+    s.aliases = map[string]*types.Alias{}
+    s.aliasClaims = nil
+    s.cards = nil
+    s.holds = nil
+    s.pots = nil
+    s.owners = nil
+    s.invitations = nil
+    s.accountInvitations = nil
+    s.alertRules = nil
+    s.grants = nil
+    s.signingKeys = nil
+    s.preferences = map[int64]*types.NotificationPreferences{}
+    s.audit = nil
+    s.loans = nil
+    s.installments = nil
+    s.disputes = nil
+    s.documents = nil
+    s.externalTransfers = nil
+    s.paymentRequests = nil
+    s.fraudCases = nil
+    s.productRates = nil
+    s.reversals = map[int]*types.Reversal{}
+
+    return nil
+}
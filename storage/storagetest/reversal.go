@@ -0,0 +1,75 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "sort"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) ReverseTransaction(ctx context.Context, r *types.Reversal, tx *types.Transaction, entries []*types.LedgerEntry) error {
+    if err := s.call(ctx, "ReverseTransaction"); err != nil {
+        return err
+    }
+    if err := types.ValidateEntries(entries); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, d := range s.disputes {
+        if d.TransactionID == r.TransactionID && d.Status == types.DisputeOpen {
+            return fmt.Errorf("transaction %d: %w", r.TransactionID, storage.ErrAlreadyDisputed)
+        }
+    }
+    if s.reversals[r.TransactionID] != nil {
+        return fmt.Errorf("transaction %d: %w", r.TransactionID, storage.ErrAlreadyReversed)
+    }
+
+    if err := s.post(tx, entries); err != nil {
+        return err
+    }
+    r.ReversalID = tx.ID
+    cp := *r
+    s.reversals[r.TransactionID] = &cp
+
+    return nil
+}
+
+func (s *Store) GetReversal(ctx context.Context, id int) (*types.Reversal, error) {
+    if err := s.call(ctx, "GetReversal"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    r, ok := s.reversals[id]
+    if !ok {
+        return nil, fmt.Errorf("reversal of transaction %d %w", id, storage.ErrNotFound)
+    }
+    cp := *r
+
+    return &cp, nil
+}
+
+func (s *Store) GetReversals(ctx context.Context) ([]*types.Reversal, error) {
+    if err := s.call(ctx, "GetReversals"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    reversals := []*types.Reversal{}
+    for _, r := range s.reversals {
+        cp := *r
+        reversals = append(reversals, &cp)
+    }
+    sort.Slice(reversals, func(i, j int) bool { return reversals[i].TransactionID < reversals[j].TransactionID })
+
+    return reversals, nil
+}
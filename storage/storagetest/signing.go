@@ -0,0 +1,97 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateSigningKey(ctx context.Context, k *types.SigningKey) error {
+    if err := s.call(ctx, "CreateSigningKey"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    cp := *k
+    s.signingKeys = append(s.signingKeys, &cp)
+
+    return nil
+}
+
+func (s *Store) GetSigningKeysByAccount(ctx context.Context, number int64) ([]*types.SigningKey, error) {
+    if err := s.call(ctx, "GetSigningKeysByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    keys := []*types.SigningKey{}
+    for _, k := range s.signingKeys {
+        if k.AccountNumber == number {
+            cp := *k
+            keys = append(keys, &cp)
+        }
+    }
+
+    return keys, nil
+}
+
+func (s *Store) DeleteSigningKey(ctx context.Context, number int64, id string) error {
+    if err := s.call(ctx, "DeleteSigningKey"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for i, k := range s.signingKeys {
+        if k.ID == id && k.AccountNumber == number {
+            s.signingKeys = append(s.signingKeys[:i], s.signingKeys[i+1:]...)
+            return nil
+        }
+    }
+
+    return fmt.Errorf("signing key %s %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) UseNonce(ctx context.Context, keyID, nonce string, at time.Time) error {
+    if err := s.call(ctx, "UseNonce"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    key := keyID + "/" + nonce
+    if _, ok := s.nonces[key]; ok {
+        return fmt.Errorf("nonce %s: %w", nonce, storage.ErrNonceUsed)
+    }
+    s.nonces[key] = at
+
+    return nil
+}
+
+func (s *Store) DeleteNoncesBefore(ctx context.Context, t time.Time) (int, error) {
+    if err := s.call(ctx, "DeleteNoncesBefore"); err != nil {
+        return 0, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    n := 0
+    for key, at := range s.nonces {
+        if at.Before(t) {
+            delete(s.nonces, key)
+            n++
+        }
+    }
+
+    return n, nil
+}
@@ -0,0 +1,56 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) SaveBalanceSnapshot(ctx context.Context, snap *types.BalanceSnapshot) error {
+    if err := s.call(ctx, "SaveBalanceSnapshot"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, existing := range s.snapshots {
+        if existing.AccountNumber == snap.AccountNumber && existing.Day.Equal(snap.Day) {
+            existing.Balance = snap.Balance
+            return nil
+        }
+    }
+
+    c := *snap
+    s.snapshots = append(s.snapshots, &c)
+
+    return nil
+}
+
+func (s *Store) GetBalanceSnapshotBefore(ctx context.Context, number int64, before time.Time) (*types.BalanceSnapshot, error) {
+    if err := s.call(ctx, "GetBalanceSnapshotBefore"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var latest *types.BalanceSnapshot
+    for _, snap := range s.snapshots {
+        if snap.AccountNumber != number || !snap.Day.Before(before) {
+            continue
+        }
+        if latest == nil || snap.Day.After(latest.Day) {
+            latest = snap
+        }
+    }
+    if latest == nil {
+        return nil, fmt.Errorf("snapshot for account %d %w", number, storage.ErrNotFound)
+    }
+
+    c := *latest
+    return &c, nil
+}
@@ -0,0 +1,140 @@
+package storagetest
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+var _ storage.Storage = (*Store)(nil)
+
+// Store is an in-memory storage.Storage for tests. Every method can be made
+// to fail with FailOn and slowed down with SetLatency.
+type Store struct {
+    mu sync.Mutex
+    accounts []*types.Account
+    transactions []*types.Transaction
+    entries []*types.LedgerEntry
+    discrepancies []*types.Discrepancy
+    snapshots []*types.BalanceSnapshot
+    preferences map[int64]*types.NotificationPreferences
+    phoneVerifications map[int64]*types.PhoneVerification
+    alertRules []*types.AlertRule
+    aliases map[string]*types.Alias
+    aliasVerifications map[string]*types.AliasVerification
+    aliasClaims []*types.AliasClaim
+    paymentRequests []*types.PaymentRequest
+    cards []*types.Card
+    holds []*types.Hold
+    loans []*types.Loan
+    installments []*types.LoanInstallment
+    pots []*types.Pot
+    owners []*types.AccountOwner
+    invitations []*types.OwnerInvitation
+    audit []*types.AuditEntry
+    grants []*types.Grant
+    productRates []*types.ProductRate
+    fraudCases []*types.FraudCase
+    devices []*types.Device
+    stepUps map[string]*types.StepUpChallenge
+    jobStatuses map[string]*types.JobStatus
+    deadLetters []*types.DeadLetter
+    signingKeys []*types.SigningKey
+    nonces map[string]time.Time
+    activations map[int64]*types.AccountActivation
+    archived map[int]bool
+    disputes []*types.Dispute
+    documents []*types.Document
+    externalTransfers []*types.ExternalTransfer
+    reversals map[int]*types.Reversal
+    usage []*types.Usage
+    accountInvitations []*types.AccountInvitation
+    lockedOut bool
+    lastAccountID int
+    lastTransactionID int
+    lastEntryID int
+    lastAlertRuleID int
+    lastPaymentRequestID int
+    lastCardID int
+    lastHoldID int
+    lastLoanID int
+    lastPotID int
+    lastInvitationID int
+    lastAuditID int
+    lastGrantID int
+    lastProductRateID int
+    lastFraudCaseID int
+    lastDeviceID int
+    lastDeadLetterID int
+    lastDisputeID int
+    lastDocumentID int
+    lastAccountInvitationID int
+
+    errs map[string]error
+    latency time.Duration
+}
+
+func New() *Store {
+    return &Store{
+        preferences: map[int64]*types.NotificationPreferences{},
+        reversals: map[int]*types.Reversal{},
+        phoneVerifications: map[int64]*types.PhoneVerification{},
+        aliases: map[string]*types.Alias{},
+        aliasVerifications: map[string]*types.AliasVerification{},
+        stepUps: map[string]*types.StepUpChallenge{},
+        jobStatuses: map[string]*types.JobStatus{},
+        nonces: map[string]time.Time{},
+        activations: map[int64]*types.AccountActivation{},
+        archived: map[int]bool{},
+        errs: map[string]error{},
+    }
+}
+
+// FailOn makes every call to the named method return err. Passing a nil err
+// removes the failure again.
+func (s *Store) FailOn(method string, err error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err == nil {
+        delete(s.errs, method)
+        return
+    }
+    s.errs[method] = err
+}
+
+func (s *Store) SetLatency(d time.Duration) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.latency = d
+}
+
+// call applies the configured latency and failure for method, giving up
+// early when ctx is done. It must be called without holding mu.
+func (s *Store) call(ctx context.Context, method string) error {
+    s.mu.Lock()
+    latency := s.latency
+    err := s.errs[method]
+    s.mu.Unlock()
+
+    if latency > 0 {
+        t := time.NewTimer(latency)
+        defer t.Stop()
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-t.C:
+        }
+    }
+
+    if err != nil {
+        return err
+    }
+
+    return ctx.Err()
+}
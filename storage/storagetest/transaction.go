@@ -0,0 +1,138 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+func (s *Store) CreateTransaction(ctx context.Context, tx *types.Transaction) error {
+    if err := s.call(ctx, "CreateTransaction"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if tx.Status == "" {
+        tx.Status = types.StatusCompleted
+    }
+    s.lastTransactionID++
+    tx.ID = s.lastTransactionID
+    c := *tx
+    s.transactions = append(s.transactions, &c)
+
+    return nil
+}
+
+func (s *Store) GetTransactions(ctx context.Context) ([]*types.Transaction, error) {
+    if err := s.call(ctx, "GetTransactions"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    txs := []*types.Transaction{}
+    for _, tx := range s.transactions {
+        c := *tx
+        txs = append(txs, &c)
+    }
+
+    return txs, nil
+}
+
+func (s *Store) GetTransaction(ctx context.Context, id int) (*types.Transaction, error) {
+    if err := s.call(ctx, "GetTransaction"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, tx := range s.transactions {
+        if tx.ID == id {
+            c := *tx
+            return &c, nil
+        }
+    }
+
+    return nil, fmt.Errorf("transaction %d %w", id, storage.ErrNotFound)
+}
+
+func (s *Store) GetTransactionsByAccount(ctx context.Context, number int64) ([]*types.Transaction, error) {
+    if err := s.call(ctx, "GetTransactionsByAccount"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    txs := []*types.Transaction{}
+    for _, tx := range s.transactions {
+        if tx.FromAccount == number || tx.ToAccount == number {
+            c := *tx
+            txs = append(txs, &c)
+        }
+    }
+
+    return txs, nil
+}
+
+func (s *Store) GetTransactionByReference(ctx context.Context, provider, reference string) (*types.Transaction, error) {
+    if err := s.call(ctx, "GetTransactionByReference"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, tx := range s.transactions {
+        if tx.Provider == provider && tx.Reference == reference {
+            c := *tx
+            return &c, nil
+        }
+    }
+
+    return nil, fmt.Errorf("transaction %s/%s %w", provider, reference, storage.ErrNotFound)
+}
+
+func (s *Store) GetTransactionByIdempotencyKey(ctx context.Context, from int64, key string) (*types.Transaction, error) {
+    if err := s.call(ctx, "GetTransactionByIdempotencyKey"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, tx := range s.transactions {
+        if tx.FromAccount == from && tx.IdempotencyKey == key {
+            c := *tx
+            return &c, nil
+        }
+    }
+
+    return nil, fmt.Errorf("transaction with idempotency key %s %w", key, storage.ErrNotFound)
+}
+
+func (s *Store) GetPendingExternalTransactions(ctx context.Context, before time.Time) ([]*types.Transaction, error) {
+    if err := s.call(ctx, "GetPendingExternalTransactions"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    txs := []*types.Transaction{}
+    for _, tx := range s.transactions {
+        if tx.Status == types.StatusPending && tx.ToAccount == types.SettlementAccountNumber && tx.Provider != "" && tx.CreatedAt.Before(before) {
+            c := *tx
+            txs = append(txs, &c)
+        }
+    }
+
+    return txs, nil
+}
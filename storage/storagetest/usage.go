@@ -0,0 +1,111 @@
+package storagetest
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+
+    "gobank/storage"
+    "gobank/types"
+)
+
+// usageFor returns the usage row of number on day, adding it if there is
+// none yet. It must be called holding mu.
+func (s *Store) usageFor(number int64, day time.Time) *types.Usage {
+    for _, u := range s.usage {
+        if u.AccountNumber == number && u.Day.Equal(day) {
+            return u
+        }
+    }
+
+    u := &types.Usage{AccountNumber: number, Day: day}
+    s.usage = append(s.usage, u)
+    return u
+}
+
+func (s *Store) CountRequest(ctx context.Context, number int64, day time.Time, limit int64) error {
+    if err := s.call(ctx, "CountRequest"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    u := s.usageFor(number, day)
+    if limit > 0 && u.Requests >= limit {
+        return fmt.Errorf("account %d made %d requests today: %w", number, limit, storage.ErrQuotaExceeded)
+    }
+    u.Requests++
+
+    return nil
+}
+
+func (s *Store) RecordTransferUsage(ctx context.Context, number int64, day time.Time, amount int64) error {
+    if err := s.call(ctx, "RecordTransferUsage"); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    u := s.usageFor(number, day)
+    u.Transfers++
+    u.TransferVolume += amount
+
+    return nil
+}
+
+func (s *Store) GetUsage(ctx context.Context, number int64, from, to time.Time) ([]*types.Usage, error) {
+    if err := s.call(ctx, "GetUsage"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    usage := []*types.Usage{}
+    for _, u := range s.usage {
+        if u.AccountNumber == number && !u.Day.Before(from) && u.Day.Before(to) {
+            c := *u
+            usage = append(usage, &c)
+        }
+    }
+    sort.Slice(usage, func(i, j int) bool { return usage[i].Day.Before(usage[j].Day) })
+
+    return usage, nil
+}
+
+func (s *Store) GetUsageTotals(ctx context.Context, from, to time.Time) ([]*types.Usage, error) {
+    if err := s.call(ctx, "GetUsageTotals"); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    byAccount := map[int64]*types.Usage{}
+    totals := []*types.Usage{}
+    for _, u := range s.usage {
+        if u.Day.Before(from) || !u.Day.Before(to) {
+            continue
+        }
+        t, ok := byAccount[u.AccountNumber]
+        if !ok {
+            t = &types.Usage{AccountNumber: u.AccountNumber}
+            byAccount[u.AccountNumber] = t
+            totals = append(totals, t)
+        }
+        t.Requests += u.Requests
+        t.Transfers += u.Transfers
+        t.TransferVolume += u.TransferVolume
+    }
+    sort.Slice(totals, func(i, j int) bool {
+        if totals[i].Requests != totals[j].Requests {
+            return totals[i].Requests > totals[j].Requests
+        }
+        return totals[i].AccountNumber < totals[j].AccountNumber
+    })
+
+    return totals, nil
+}
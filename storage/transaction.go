@@ -0,0 +1,220 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+// ErrIdempotencyKeyUsed is returned when posting a transaction with an
+// idempotency key its sender already posted another one with.
+var ErrIdempotencyKeyUsed = errors.New("idempotency key was already used")
+
+const idempotencyKeyIndex = "transaction_idempotency_key_idx"
+
+type TransactionStorage interface {
+    CreateTransaction(context.Context, *types.Transaction) error
+    GetTransactions(context.Context) ([]*types.Transaction, error)
+    GetTransaction(context.Context, int) (*types.Transaction, error)
+    GetTransactionsByAccount(context.Context, int64) ([]*types.Transaction, error)
+    GetTransactionByReference(ctx context.Context, provider, reference string) (*types.Transaction, error)
+    // GetTransactionByIdempotencyKey returns the transaction the account
+    // sent with key, failing with ErrNotFound when there is none.
+    GetTransactionByIdempotencyKey(ctx context.Context, from int64, key string) (*types.Transaction, error)
+    // GetPendingExternalTransactions returns the transfers out of the bank
+    // created before t that their provider hasn't settled yet.
+    GetPendingExternalTransactions(ctx context.Context, before time.Time) ([]*types.Transaction, error)
+}
+
+const transactionColumns = `id, coalesce(kind, ''), coalesce(status, 'completed'), from_account, to_account, amount, coalesce(provider, ''), coalesce(reference, ''), created_at`
+
+func (s *PostgresStore) CreateTransactionTable() error {
+    query := `create table if not exists transaction (
+        id serial primary key,
+        from_account bigint,
+        to_account bigint,
+        amount bigint,
+        created_at timestamp
+    )`
+
+    if _, err := s.db.Exec(query); err != nil {
+        return err
+    }
+
+    alters := []string{
+        `alter table transaction add column if not exists kind varchar(32)`,
+        `alter table transaction add column if not exists status varchar(16)`,
+        `alter table transaction add column if not exists provider varchar(32)`,
+        `alter table transaction add column if not exists reference varchar(128)`,
+        `create unique index if not exists transaction_provider_reference_idx on transaction (provider, reference) where reference is not null`,
+        `alter table transaction add column if not exists idempotency_key varchar(128)`,
+        `create unique index if not exists ` + idempotencyKeyIndex + ` on transaction (from_account, idempotency_key) where idempotency_key is not null`,
+    }
+    for _, q := range alters {
+        if _, err := s.db.Exec(q); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (s *PostgresStore) CreateTransaction(ctx context.Context, tx *types.Transaction) error {
+    query := `
+        insert into transaction
+        (kind, status, from_account, to_account, amount, provider, reference, created_at)
+        values ($1, $2, $3, $4, $5, $6, $7, $8)
+        returning id
+    `
+    if tx.Status == "" {
+        tx.Status = types.StatusCompleted
+    }
+    return s.db.QueryRowContext(
+        ctx,
+        query,
+        tx.Kind,
+        tx.Status,
+        tx.FromAccount,
+        tx.ToAccount,
+        tx.Amount,
+        nullString(tx.Provider),
+        nullString(tx.Reference),
+        tx.CreatedAt,
+    ).Scan(&tx.ID)
+}
+
+func (s *PostgresStore) GetTransactions(ctx context.Context) ([]*types.Transaction, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+transactionColumns+`
+        from `+allTransactions+` order by id
+    `)
+    if err != nil {
+        return nil, err
+    }
+    return scanTransactions(rows)
+}
+
+func (s *PostgresStore) GetTransaction(ctx context.Context, id int) (*types.Transaction, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+transactionColumns+`
+        from `+allTransactions+` where id = $1
+    `, id)
+    if err != nil {
+        return nil, err
+    }
+
+    txs, err := scanTransactions(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(txs) == 0 {
+        return nil, fmt.Errorf("transaction %d %w", id, ErrNotFound)
+    }
+
+    return txs[0], nil
+}
+
+func (s *PostgresStore) GetTransactionsByAccount(ctx context.Context, number int64) ([]*types.Transaction, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+transactionColumns+`
+        from `+allTransactions+`
+        where from_account = $1 or to_account = $1
+        order by created_at, id
+    `, number)
+    if err != nil {
+        return nil, err
+    }
+    return scanTransactions(rows)
+}
+
+func (s *PostgresStore) GetTransactionByReference(ctx context.Context, provider, reference string) (*types.Transaction, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+transactionColumns+`
+        from `+allTransactions+`
+        where provider = $1 and reference = $2
+    `, provider, reference)
+    if err != nil {
+        return nil, err
+    }
+
+    txs, err := scanTransactions(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(txs) == 0 {
+        return nil, fmt.Errorf("transaction %s/%s %w", provider, reference, ErrNotFound)
+    }
+
+    return txs[0], nil
+}
+
+func (s *PostgresStore) GetTransactionByIdempotencyKey(ctx context.Context, from int64, key string) (*types.Transaction, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+transactionColumns+`
+        from `+allTransactions+`
+        where from_account = $1 and idempotency_key = $2
+    `, from, key)
+    if err != nil {
+        return nil, err
+    }
+
+    txs, err := scanTransactions(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(txs) == 0 {
+        return nil, fmt.Errorf("transaction with idempotency key %s %w", key, ErrNotFound)
+    }
+
+    return txs[0], nil
+}
+
+// GetPendingExternalTransactions only reads the hot table, archival never
+// moves pending transactions out of it.
+func (s *PostgresStore) GetPendingExternalTransactions(ctx context.Context, before time.Time) ([]*types.Transaction, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select `+transactionColumns+`
+        from transaction
+        where status = $1 and to_account = $2 and provider is not null and created_at < $3
+        order by created_at, id
+    `, types.StatusPending, types.SettlementAccountNumber, before)
+    if err != nil {
+        return nil, err
+    }
+    return scanTransactions(rows)
+}
+
+// nullString stores empty optional columns as null so they stay out of
+// unique indexes.
+func nullString(s string) sql.NullString {
+    return sql.NullString{String: s, Valid: s != ""}
+}
+
+func scanTransactions(rows *sql.Rows) ([]*types.Transaction, error) {
+    defer rows.Close()
+
+    txs := []*types.Transaction{}
+    for rows.Next() {
+        tx := new(types.Transaction)
+        if err := rows.Scan(
+            &tx.ID,
+            &tx.Kind,
+            &tx.Status,
+            &tx.FromAccount,
+            &tx.ToAccount,
+            &tx.Amount,
+            &tx.Provider,
+            &tx.Reference,
+            &tx.CreatedAt,
+        ); err != nil {
+            return nil, err
+        }
+        txs = append(txs, tx)
+    }
+
+    return txs, rows.Err()
+}
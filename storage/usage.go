@@ -0,0 +1,115 @@
+package storage
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "gobank/types"
+)
+
+var ErrQuotaExceeded = errors.New("usage quota exceeded")
+
+type UsageStorage interface {
+    // CountRequest counts a request of the account on day. It fails with
+    // ErrQuotaExceeded, without counting it, when the account already made
+    // limit requests that day. A zero limit isn't checked.
+    CountRequest(ctx context.Context, number int64, day time.Time, limit int64) error
+    RecordTransferUsage(ctx context.Context, number int64, day time.Time, amount int64) error
+    // GetUsage returns the account's usage per day from from up to to.
+    GetUsage(ctx context.Context, number int64, from, to time.Time) ([]*types.Usage, error)
+    // GetUsageTotals returns the usage of every account that used the API
+    // from from up to to, summed up, busiest first.
+    GetUsageTotals(ctx context.Context, from, to time.Time) ([]*types.Usage, error)
+}
+
+func (s *PostgresStore) CreateUsageTable() error {
+    query := `create table if not exists usage (
+        account_number bigint not null,
+        day date not null,
+        requests bigint not null default 0,
+        transfers bigint not null default 0,
+        transfer_volume bigint not null default 0,
+        primary key (account_number, day)
+    )`
+
+    _, err := s.db.Exec(query)
+    return err
+}
+
+func (s *PostgresStore) CountRequest(ctx context.Context, number int64, day time.Time, limit int64) error {
+    // the update is skipped, returning no row, once the limit is reached
+    var requests int64
+    err := s.db.QueryRowContext(ctx, `
+        insert into usage (account_number, day, requests) values ($1, $2, 1)
+        on conflict (account_number, day) do update set requests = usage.requests + 1
+        where $3 = 0 or usage.requests < $3
+        returning requests
+    `, number, day, limit).Scan(&requests)
+    if errors.Is(err, sql.ErrNoRows) {
+        return fmt.Errorf("account %d made %d requests today: %w", number, limit, ErrQuotaExceeded)
+    }
+
+    return err
+}
+
+func (s *PostgresStore) RecordTransferUsage(ctx context.Context, number int64, day time.Time, amount int64) error {
+    _, err := s.db.ExecContext(ctx, `
+        insert into usage (account_number, day, transfers, transfer_volume) values ($1, $2, 1, $3)
+        on conflict (account_number, day) do update set
+            transfers = usage.transfers + 1,
+            transfer_volume = usage.transfer_volume + excluded.transfer_volume
+    `, number, day, amount)
+
+    return err
+}
+
+func (s *PostgresStore) GetUsage(ctx context.Context, number int64, from, to time.Time) ([]*types.Usage, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select account_number, day, requests, transfers, transfer_volume from usage
+        where account_number = $1 and day >= $2 and day < $3
+        order by day
+    `, number, from, to)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    usage := []*types.Usage{}
+    for rows.Next() {
+        u := new(types.Usage)
+        if err := rows.Scan(&u.AccountNumber, &u.Day, &u.Requests, &u.Transfers, &u.TransferVolume); err != nil {
+            return nil, err
+        }
+        u.Day = u.Day.UTC()
+        usage = append(usage, u)
+    }
+
+    return usage, rows.Err()
+}
+
+func (s *PostgresStore) GetUsageTotals(ctx context.Context, from, to time.Time) ([]*types.Usage, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        select account_number, sum(requests), sum(transfers), sum(transfer_volume) from usage
+        where day >= $1 and day < $2
+        group by account_number
+        order by sum(requests) desc, account_number
+    `, from, to)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    totals := []*types.Usage{}
+    for rows.Next() {
+        u := new(types.Usage)
+        if err := rows.Scan(&u.AccountNumber, &u.Requests, &u.Transfers, &u.TransferVolume); err != nil {
+            return nil, err
+        }
+        totals = append(totals, u)
+    }
+
+    return totals, rows.Err()
+}
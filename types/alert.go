@@ -0,0 +1,26 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    AlertBalanceBelow = "balance_below"
+    AlertTransactionAbove = "transaction_above"
+    AlertForeignCurrency = "foreign_currency"
+)
+
+// AlertRule notifies the account owner when a money movement matches it.
+// Threshold is unused for foreign currency rules.
+type AlertRule struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    Kind string `json:"kind"`
+    Threshold int64 `json:"threshold"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateAlertRuleRequest struct {
+    Kind string `json:"kind"`
+    Threshold int64 `json:"threshold"`
+}
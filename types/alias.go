@@ -0,0 +1,49 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    AliasEmail = "email"
+    AliasPhone = "phone"
+)
+
+// Alias lets other customers pay an account by a verified email address or
+// phone number instead of its account number.
+type Alias struct {
+    Alias string `json:"alias"`
+    Kind string `json:"kind"`
+    AccountNumber int64 `json:"accountNumber"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+type AliasRequest struct {
+    Alias string `json:"alias"`
+}
+
+type VerifyAliasRequest struct {
+    Alias string `json:"alias"`
+    Code string `json:"code"`
+}
+
+// AliasVerification is a pending email alias, confirmed with a code sent to
+// that address. Only the hash of the code is stored.
+type AliasVerification struct {
+    AccountNumber int64
+    Alias string
+    CodeHash string
+    Attempts int
+    ExpiresAt time.Time
+}
+
+// AliasClaim is money sent to an alias nobody has registered yet. It is
+// held in the suspense account by a pending transaction until the alias is
+// registered, or returned to the sender once it expires.
+type AliasClaim struct {
+    TransactionID int `json:"transactionId"`
+    Alias string `json:"alias"`
+    FromAccount int64 `json:"fromAccount"`
+    Amount int64 `json:"amount"`
+    ExpiresAt time.Time `json:"expiresAt"`
+}
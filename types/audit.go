@@ -0,0 +1,17 @@
+package types
+
+import (
+    "time"
+)
+
+// AuditEntry records a change made to an account and which owner's login
+// made it.
+type AuditEntry struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    ActorNumber int64 `json:"actorNumber"`
+    Method string `json:"method"`
+    Path string `json:"path"`
+    Status int `json:"status"`
+    CreatedAt time.Time `json:"createdAt"`
+}
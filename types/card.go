@@ -0,0 +1,112 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    CardActive = "active"
+    CardFrozen = "frozen"
+    // CardBlocked is set after too many wrong PINs and only lifted by an
+    // admin.
+    CardBlocked = "blocked"
+)
+
+// Card is a virtual card on an account. The number, CVV and expiry are only
+// stored encrypted and shown once, when the card is issued.
+type Card struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    Last4 string `json:"last4"`
+    Status string `json:"status"`
+    // DailyLimit caps what the card can authorize per UTC day; 0 means no
+    // limit beyond the account balance.
+    DailyLimit int64 `json:"dailyLimit"`
+    PINSet bool `json:"pinSet"`
+    CreatedAt time.Time `json:"createdAt"`
+
+    PANHash string `json:"-"`
+    PINHash string `json:"-"`
+    PINAttempts int `json:"-"`
+    EncryptedPAN string `json:"-"`
+    EncryptedCVV string `json:"-"`
+    EncryptedExpiry string `json:"-"`
+}
+
+type IssueCardRequest struct {
+    DailyLimit int64 `json:"dailyLimit"`
+}
+
+// IssuedCard is the only response that carries the full card details.
+type IssuedCard struct {
+    *Card
+    PAN string `json:"pan"`
+    CVV string `json:"cvv"`
+    ExpiryMonth int `json:"expiryMonth"`
+    ExpiryYear int `json:"expiryYear"`
+}
+
+// PINRequest sets a card's PIN. CurrentPIN is required to change one that
+// is already set.
+type PINRequest struct {
+    PIN string `json:"pin"`
+    CurrentPIN string `json:"currentPin,omitempty"`
+}
+
+type VerifyPINRequest struct {
+    PIN string `json:"pin"`
+}
+
+type VerifyPINResponse struct {
+    Valid bool `json:"valid"`
+    AttemptsLeft int `json:"attemptsLeft"`
+}
+
+type CardLimitRequest struct {
+    DailyLimit int64 `json:"dailyLimit"`
+}
+
+const (
+    HoldActive = "active"
+    HoldReleased = "released"
+    // HoldCaptured is a hold the card network turned into a payment.
+    HoldCaptured = "captured"
+    // HoldExpired is a hold the end of day job found past its ExpiresAt.
+    HoldExpired = "expired"
+)
+
+// Hold reserves Amount of an account's balance for a card authorization
+// until it is released or ExpiresAt passes.
+type Hold struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    CardID int `json:"cardId"`
+    Amount int64 `json:"amount"`
+    Merchant string `json:"merchant"`
+    Status string `json:"status"`
+    ExpiresAt time.Time `json:"expiresAt"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+// CaptureRequest is sent by the card network to settle a hold. Amount may
+// be less than the hold's, zero meaning all of it.
+type CaptureRequest struct {
+    Amount int64 `json:"amount"`
+}
+
+// AuthorizationRequest is sent by the card network when a card is used.
+type AuthorizationRequest struct {
+    PAN string `json:"pan"`
+    CVV string `json:"cvv"`
+    ExpiryMonth int `json:"expiryMonth"`
+    ExpiryYear int `json:"expiryYear"`
+    Amount int64 `json:"amount"`
+    Currency string `json:"currency"`
+    Merchant string `json:"merchant"`
+}
+
+type AuthorizationResponse struct {
+    Approved bool `json:"approved"`
+    HoldID int `json:"holdId,omitempty"`
+    DeclineReason string `json:"declineReason,omitempty"`
+}
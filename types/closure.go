@@ -0,0 +1,17 @@
+package types
+
+// CloseAccountRequest names where the remaining balance goes: another
+// account by ToAccount, or out of the bank by Payout. Neither is needed
+// when the balance is zero.
+type CloseAccountRequest struct {
+    ToAccount int64 `json:"toAccount"`
+    Payout *Payout `json:"payout"`
+}
+
+// Payout pays the balance out as a transfer to an account at another bank.
+// The account can only be closed once the transfer settles.
+type Payout struct {
+    RoutingNumber string `json:"routingNumber"`
+    ToAccount string `json:"toAccount"`
+    Name string `json:"name"`
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    DeadLetterPending = "pending"
+    DeadLetterReplayed = "replayed"
+)
+
+// DeadLetter is a notification or outbound webhook event that failed every
+// delivery attempt. It keeps the rendered message, or the encoded event on
+// the webhook channel, so a replay sends exactly what would have been sent.
+type DeadLetter struct {
+    ID int `json:"id"`
+    Event string `json:"event"`
+    Channel string `json:"channel"`
+    AccountNumber int64 `json:"accountNumber"`
+    To string `json:"to"`
+    Subject string `json:"subject,omitempty"`
+    Body string `json:"body"`
+    // Reason is the error from the last delivery or replay attempt.
+    Reason string `json:"reason"`
+    Attempts int `json:"attempts"`
+    Status string `json:"status"`
+    CreatedAt time.Time `json:"createdAt"`
+    ReplayedAt *time.Time `json:"replayedAt,omitempty"`
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+    "time"
+)
+
+// Device is a browser or app an account has logged in from, told apart by
+// the hash of its fingerprint.
+type Device struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    Fingerprint string `json:"-"`
+    UserAgent string `json:"userAgent"`
+    IP string `json:"ip"`
+    Country string `json:"country,omitempty"`
+    FirstSeen time.Time `json:"firstSeen"`
+    LastSeen time.Time `json:"lastSeen"`
+}
+
+// StepUpChallenge holds back money movement in a session that logged in
+// from a new device or an unlikely place until the code sent to the
+// account holder is confirmed. Only the hash of the code is stored.
+type StepUpChallenge struct {
+    ID string
+    AccountNumber int64
+    CodeHash string
+    Attempts int
+    ExpiresAt time.Time
+    // Device is the device of the login that started the challenge. It is
+    // only trusted once the code is confirmed. Nil when the challenge
+    // didn't come from a login.
+    Device *Device
+}
+
+type StepUpRequest struct {
+    Code string `json:"code"`
+}
+
+// ReactivateRequest confirms the code sent to reactivate a dormant account.
+type ReactivateRequest struct {
+    ChallengeID string `json:"challengeId"`
+    Code string `json:"code"`
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+    "time"
+)
+
+// Sections of the operations digest.
+const (
+    DigestFlows = "flows"
+    DigestReconciliation = "reconciliation"
+    DigestFraudReview = "fraud_review"
+)
+
+var DigestSections = []string{DigestFlows, DigestReconciliation, DigestFraudReview}
+
+func KnownDigestSection(s string) bool {
+    for _, known := range DigestSections {
+        if s == known {
+            return true
+        }
+    }
+    return false
+}
+
+// Digest is the morning summary sent to operations about Day, a UTC day.
+// Sections that weren't asked for are nil.
+type Digest struct {
+    Day time.Time `json:"day"`
+    Flows *DailyFlow `json:"flows,omitempty"`
+    Reconciliation *ReconciliationSummary `json:"reconciliation,omitempty"`
+    FraudReview *FraudReviewSummary `json:"fraudReview,omitempty"`
+    GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// ReconciliationSummary counts the unresolved discrepancies, New of them
+// found on the digest's day, and Drift is the sum of their absolute
+// differences.
+type ReconciliationSummary struct {
+    Open int `json:"open"`
+    New int `json:"new"`
+    Drift int64 `json:"drift"`
+}
+
+// FraudReviewSummary is the queue of transfers held for review.
+type FraudReviewSummary struct {
+    Pending int `json:"pending"`
+    Amount int64 `json:"amount"`
+    OldestAt *time.Time `json:"oldestAt,omitempty"`
+}
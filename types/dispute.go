@@ -0,0 +1,64 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    DisputeOpen = "open"
+    DisputeAccepted = "accepted"
+    DisputeDenied = "denied"
+)
+
+// Dispute reason codes.
+const (
+    DisputeUnauthorized = "unauthorized"
+    DisputeNotReceived = "not_received"
+    DisputeDuplicate = "duplicate"
+    DisputeIncorrectAmount = "incorrect_amount"
+    DisputeOther = "other"
+)
+
+func ValidDisputeReason(reason string) bool {
+    switch reason {
+    case DisputeUnauthorized, DisputeNotReceived, DisputeDuplicate, DisputeIncorrectAmount, DisputeOther:
+        return true
+    }
+    return false
+}
+
+// Dispute is a customer's claim against a transfer they sent. While it is
+// open, HeldAmount of what HeldAccount received is held and can't be spent.
+// Accepting it posts the transfer's entries in reverse as ReversalID.
+type Dispute struct {
+    ID int `json:"id"`
+    TransactionID int `json:"transactionId"`
+    AccountNumber int64 `json:"accountNumber"`
+    HeldAccount int64 `json:"heldAccount"`
+    HeldAmount int64 `json:"heldAmount"`
+    Reason string `json:"reason"`
+    Description string `json:"description,omitempty"`
+    // Evidence refers to documents kept elsewhere, e.g. receipts.
+    Evidence []string `json:"evidence,omitempty"`
+    // Documents are the ids of dispute_evidence documents uploaded by the
+    // account.
+    Documents []int `json:"documents,omitempty"`
+    Status string `json:"status"`
+    // Resolution is the admin's note on the decision.
+    Resolution string `json:"resolution,omitempty"`
+    ReversalID *int `json:"reversalId,omitempty"`
+    CreatedAt time.Time `json:"createdAt"`
+    ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+type CreateDisputeRequest struct {
+    TransactionID int `json:"transactionId"`
+    Reason string `json:"reason"`
+    Description string `json:"description"`
+    Evidence []string `json:"evidence"`
+    Documents []int `json:"documents"`
+}
+
+type ResolveDisputeRequest struct {
+    Resolution string `json:"resolution"`
+}
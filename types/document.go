@@ -0,0 +1,37 @@
+package types
+
+import (
+    "time"
+)
+
+// Document purposes.
+const (
+    DocumentDisputeEvidence = "dispute_evidence"
+    DocumentKYC = "kyc"
+)
+
+func ValidDocumentPurpose(purpose string) bool {
+    switch purpose {
+    case DocumentDisputeEvidence, DocumentKYC:
+        return true
+    }
+    return false
+}
+
+// Document is a file an account holder uploaded, e.g. a receipt backing a
+// dispute or an identity document. Its contents live in the blob store
+// under BlobKey.
+type Document struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    Purpose string `json:"purpose"`
+    Filename string `json:"filename"`
+    ContentType string `json:"contentType"`
+    Size int64 `json:"size"`
+    SHA256 string `json:"sha256"`
+    BlobKey string `json:"-"`
+    CreatedAt time.Time `json:"createdAt"`
+    // DownloadURL is signed each time the document is served and only
+    // works for a while.
+    DownloadURL string `json:"downloadUrl,omitempty"`
+}
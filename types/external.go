@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+// ExternalTransferRequest sends money to an account at another bank,
+// named by its routing number.
+type ExternalTransferRequest struct {
+    RoutingNumber string `json:"routingNumber"`
+    ToAccount string `json:"toAccount"`
+    Name string `json:"name"`
+    Amount int64 `json:"amount"`
+}
+
+// ExternalTransfer is a transfer to another bank. It is a pending
+// transaction into the settlement account, sharing its ID, until the
+// clearing Network it went out on settles it or returns it with
+// ReturnReason.
+type ExternalTransfer struct {
+    ID int `json:"id"`
+    FromAccount int64 `json:"fromAccount"`
+    RoutingNumber string `json:"routingNumber"`
+    ToAccount string `json:"toAccount"`
+    Name string `json:"name"`
+    Amount int64 `json:"amount"`
+    Status string `json:"status"`
+    Network string `json:"network"`
+    Reference string `json:"reference"`
+    ReturnReason string `json:"returnReason,omitempty"`
+    CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+    "time"
+)
+
+// Fraud decisions, from least to most severe.
+const (
+    FraudAllow = "allow"
+    FraudReview = "review"
+    FraudBlock = "block"
+)
+
+const (
+    // FraudCasePending is a blocked transfer waiting for an admin.
+    FraudCasePending = "pending"
+    // FraudCasePosted is a transfer flagged for review that went through.
+    FraudCasePosted = "posted"
+    FraudCaseApproved = "approved"
+    FraudCaseRejected = "rejected"
+)
+
+// FraudCase records a transfer the fraud rules flagged. Blocked transfers
+// aren't posted until an admin approves the case; TransactionID is set once
+// the transfer is posted. A transfer out of the bank goes to the settlement
+// account and keeps where it is going in External.
+type FraudCase struct {
+    ID int `json:"id"`
+    FromAccount int64 `json:"fromAccount"`
+    ToAccount int64 `json:"toAccount"`
+    External *ExternalTransferRequest `json:"external,omitempty"`
+    Amount int64 `json:"amount"`
+    Decision string `json:"decision"`
+    Reasons []string `json:"reasons"`
+    Status string `json:"status"`
+    TransactionID *int `json:"transactionId,omitempty"`
+    CreatedAt time.Time `json:"createdAt"`
+    DecidedAt *time.Time `json:"decidedAt,omitempty"`
+}
+
+// FraudHistory is what the fraud rules need of a sender's earlier
+// transactions, so checking a transfer never reads all of them.
+type FraudHistory struct {
+    // PaidBefore is set when the sender sent money to the payee before.
+    PaidBefore bool `json:"paidBefore"`
+    // LastActivity is the time of the sender's latest transaction, zero
+    // when there is none.
+    LastActivity time.Time `json:"lastActivity"`
+    // SentSince adds up the transfers the sender made after the time asked
+    // for.
+    SentSince int64 `json:"sentSince"`
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+    "slices"
+    "time"
+)
+
+const (
+    ScopeRead = "read"
+    ScopeTransfer = "transfer"
+)
+
+// Grant gives GranteeNumber's holder limited access to AccountNumber, e.g.
+// for an accountant or a family member. With the transfer scope each
+// transfer can be at most TransferLimit.
+type Grant struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    GranteeNumber int64 `json:"granteeNumber"`
+    Scopes []string `json:"scopes"`
+    TransferLimit int64 `json:"transferLimit,omitempty"`
+    CreatedAt time.Time `json:"createdAt"`
+    RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+func (g *Grant) Allows(scope string) bool {
+    return slices.Contains(g.Scopes, scope)
+}
+
+type CreateGrantRequest struct {
+    GranteeNumber int64 `json:"granteeNumber"`
+    Scopes []string `json:"scopes"`
+    TransferLimit int64 `json:"transferLimit"`
+}
@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+// Maintenance is the read-only mode of a server. While it is on, requests
+// that change anything are turned away and reads are still served.
+type Maintenance struct {
+    Enabled bool `json:"enabled"`
+    Since *time.Time `json:"since,omitempty"`
+}
+
+type HealthResponse struct {
+    Status string `json:"status"`
+    Maintenance Maintenance `json:"maintenance"`
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+    "time"
+)
+
+// AccountImport is one account migrated from another system. Balance is
+// posted as its opening balance from the suspense account. Accounts
+// imported without a password carry an Activation instead and can't log in
+// until it is redeemed.
+type AccountImport struct {
+    Account *Account
+    Balance int64
+    Activation *AccountActivation
+}
+
+// AccountActivation lets the holder of an imported account choose its
+// password. Only the hash of the token is stored.
+type AccountActivation struct {
+    AccountNumber int64 `json:"accountNumber"`
+    TokenHash string `json:"-"`
+    ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type ActivateAccountRequest struct {
+    Number int64 `json:"number"`
+    Token string `json:"token"`
+    Password string `json:"password"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+    "time"
+)
+
+// AccountInvitation invites someone without an account to open one, sent
+// by an admin or a customer. Only the hash of its token is stored, the
+// token itself reaches the invitee by email.
+type AccountInvitation struct {
+    ID int `json:"id"`
+    Email string `json:"email"`
+    // InvitedBy is the account of the customer who sent it, 0 for admins.
+    InvitedBy int64 `json:"invitedBy,omitempty"`
+    // Permission, when set, makes the invitee an owner of the InvitedBy
+    // account with it once they onboard.
+    Permission string `json:"permission,omitempty"`
+    Status string `json:"status"`
+    // AccountNumber is the account opened with the invitation.
+    AccountNumber int64 `json:"accountNumber,omitempty"`
+    TokenHash string `json:"-"`
+    ExpiresAt time.Time `json:"expiresAt"`
+    CreatedAt time.Time `json:"createdAt"`
+    AcceptedAt *time.Time `json:"acceptedAt,omitempty"`
+}
+
+type CreateInvitationRequest struct {
+    Email string `json:"email"`
+    Permission string `json:"permission"`
+}
+
+// OnboardRequest opens the account an invitation was sent for. The email
+// address is the one the invitation went to.
+type OnboardRequest struct {
+    Token string `json:"token"`
+    FirstName string `json:"firstName"`
+    LastName string `json:"lastName"`
+    Password string `json:"password"`
+    Currency string `json:"currency"`
+    Locale string `json:"locale"`
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    JobRunning = "running"
+    JobSucceeded = "succeeded"
+    JobFailed = "failed"
+)
+
+// JobStatus is the outcome of the last run of a scheduled job. Count is
+// how many items the run processed, e.g. snapshots taken.
+type JobStatus struct {
+    Name string `json:"name"`
+    Schedule string `json:"schedule"`
+    Status string `json:"status"`
+    LastStarted *time.Time `json:"lastStarted,omitempty"`
+    LastFinished *time.Time `json:"lastFinished,omitempty"`
+    LastCount int `json:"lastCount"`
+    LastError string `json:"lastError,omitempty"`
+    NextRun time.Time `json:"nextRun"`
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+    "fmt"
+    "time"
+)
+
+// Internal ledger accounts use negative numbers so they can never collide
+// with customer account numbers. They only exist as ledger entries.
+const (
+    FeeAccountNumber int64 = -1
+    InterestAccountNumber int64 = -2
+    SuspenseAccountNumber int64 = -3
+    SettlementAccountNumber int64 = -4
+    FXAccountNumber int64 = -5
+    // LoanAccountNumber holds the principal customers owe on their loans.
+    LoanAccountNumber int64 = -6
+)
+
+func IsInternalAccount(number int64) bool {
+    return number < 0
+}
+
+// LedgerEntry is one side of a posting. A positive amount credits the
+// account, a negative amount debits it. Amounts are in the currency of the
+// account they are posted to.
+type LedgerEntry struct {
+    ID int `json:"id"`
+    TransactionID int `json:"transactionId"`
+    AccountNumber int64 `json:"accountNumber"`
+    Amount int64 `json:"amount"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+// ValidateEntries checks the double-entry invariant: at least one debit and
+// one credit, no zero amounts, and everything sums to zero.
+func ValidateEntries(entries []*LedgerEntry) error {
+    if len(entries) < 2 {
+        return fmt.Errorf("a posting needs at least two entries")
+    }
+
+    var sum int64
+    for _, e := range entries {
+        if e.Amount == 0 {
+            return fmt.Errorf("ledger entry for account %d has a zero amount", e.AccountNumber)
+        }
+        sum += e.Amount
+    }
+    if sum != 0 {
+        return fmt.Errorf("ledger entries are unbalanced by %d", sum)
+    }
+
+    return nil
+}
+
+// NewFXEntries moves amount from one account to another account in a
+// different currency, which receives converted. The FX account takes both
+// legs so the posting stays balanced.
+func NewFXEntries(from, to int64, amount, converted int64) []*LedgerEntry {
+    return []*LedgerEntry{
+        {AccountNumber: from, Amount: -amount},
+        {AccountNumber: FXAccountNumber, Amount: amount},
+        {AccountNumber: FXAccountNumber, Amount: -converted},
+        {AccountNumber: to, Amount: converted},
+    }
+}
+
+// NewEntries moves amount from one account to another.
+func NewEntries(from, to int64, amount int64) []*LedgerEntry {
+    return []*LedgerEntry{
+        {AccountNumber: from, Amount: -amount},
+        {AccountNumber: to, Amount: amount},
+    }
+}
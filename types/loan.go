@@ -0,0 +1,64 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    LoanApplied = "applied"
+    LoanRejected = "rejected"
+    LoanActive = "active"
+    LoanPaidOff = "paid_off"
+)
+
+// Loan is money lent to an account and paid back in monthly installments.
+// Outstanding is the principal still owed.
+type Loan struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    Principal int64 `json:"principal"`
+    TermMonths int `json:"termMonths"`
+    // RateBPS is the annual interest rate in basis points.
+    RateBPS int `json:"rateBps"`
+    Status string `json:"status"`
+    Outstanding int64 `json:"outstanding"`
+    CreatedAt time.Time `json:"createdAt"`
+    DisbursedAt *time.Time `json:"disbursedAt,omitempty"`
+}
+
+type LoanApplication struct {
+    Amount int64 `json:"amount"`
+    TermMonths int `json:"termMonths"`
+}
+
+const (
+    InstallmentDue = "due"
+    InstallmentPartial = "partial"
+    InstallmentLate = "late"
+    InstallmentPaid = "paid"
+)
+
+// LoanInstallment is one scheduled repayment. Payments go to the late fee
+// first, then interest, then principal.
+type LoanInstallment struct {
+    LoanID int `json:"loanId"`
+    Number int `json:"number"`
+    DueDate time.Time `json:"dueDate"`
+    Principal int64 `json:"principal"`
+    Interest int64 `json:"interest"`
+    LateFee int64 `json:"lateFee"`
+    PrincipalPaid int64 `json:"principalPaid"`
+    InterestPaid int64 `json:"interestPaid"`
+    LateFeePaid int64 `json:"lateFeePaid"`
+    Status string `json:"status"`
+}
+
+func (i *LoanInstallment) Remaining() int64 {
+    return i.Principal + i.Interest + i.LateFee - i.PrincipalPaid - i.InterestPaid - i.LateFeePaid
+}
+
+// LoanDetails is a loan with its repayment schedule.
+type LoanDetails struct {
+    *Loan
+    Schedule []*LoanInstallment `json:"schedule"`
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+    "time"
+)
+
+// Statement delivery preferences.
+const (
+    // StatementByEmail announces each new statement by email.
+    StatementByEmail = "email"
+    // StatementOnline only makes statements available in the app.
+    StatementOnline = "online"
+)
+
+// NotificationPreferences selects how an account is contacted: the channels
+// it is notified on, per event type where Events says so, how statements
+// reach it and whether it gets marketing. SMS only works once the account
+// has a verified phone number.
+type NotificationPreferences struct {
+    AccountNumber int64 `json:"accountNumber"`
+    Email bool `json:"email"`
+    SMS bool `json:"sms"`
+    // Events overrides Email and SMS for single event types, keyed by the
+    // event type, e.g. "transfer_confirmation".
+    Events map[string]Channels `json:"events,omitempty"`
+    StatementDelivery string `json:"statementDelivery"`
+    MarketingOptIn bool `json:"marketingOptIn"`
+}
+
+type Channels struct {
+    Email bool `json:"email"`
+    SMS bool `json:"sms"`
+}
+
+func DefaultNotificationPreferences(number int64) *NotificationPreferences {
+    return &NotificationPreferences{
+        AccountNumber: number,
+        Email: true,
+        StatementDelivery: StatementByEmail,
+    }
+}
+
+// ChannelsFor returns the channels event is sent on.
+func (p *NotificationPreferences) ChannelsFor(event string) Channels {
+    if c, ok := p.Events[event]; ok {
+        return c
+    }
+    return Channels{Email: p.Email, SMS: p.SMS}
+}
+
+// Preferences is everything an account holder can choose about how the
+// bank deals with them: the notification preferences and the language,
+// which is the account's locale.
+type Preferences struct {
+    NotificationPreferences
+    Locale string `json:"locale,omitempty"`
+}
+
+// PhoneVerification is a pending phone number change, confirmed with a code
+// sent to that number. Only the hash of the code is stored.
+type PhoneVerification struct {
+    AccountNumber int64
+    Phone string
+    CodeHash string
+    Attempts int
+    ExpiresAt time.Time
+}
+
+type PhoneRequest struct {
+    Phone string `json:"phone"`
+}
+
+type VerifyPhoneRequest struct {
+    Code string `json:"code"`
+}
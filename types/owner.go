@@ -0,0 +1,41 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    PermissionView = "view"
+    PermissionFull = "full"
+)
+
+// AccountOwner lets the holder of OwnerNumber operate AccountNumber with
+// their own login. The account's own holder is always its primary owner
+// and has no row.
+type AccountOwner struct {
+    AccountNumber int64 `json:"accountNumber"`
+    OwnerNumber int64 `json:"ownerNumber"`
+    Permission string `json:"permission"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+const (
+    InvitationPending = "pending"
+    InvitationAccepted = "accepted"
+    InvitationDeclined = "declined"
+)
+
+// OwnerInvitation asks InviteeNumber to become an owner of AccountNumber.
+type OwnerInvitation struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    InviteeNumber int64 `json:"inviteeNumber"`
+    Permission string `json:"permission"`
+    Status string `json:"status"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+type InviteOwnerRequest struct {
+    OwnerNumber int64 `json:"ownerNumber"`
+    Permission string `json:"permission"`
+}
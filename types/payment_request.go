@@ -0,0 +1,37 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    RequestPending = "pending"
+    RequestAccepted = "accepted"
+    RequestDeclined = "declined"
+    RequestCancelled = "cancelled"
+    // RequestExpired is never stored; pending requests read as expired
+    // once ExpiresAt has passed.
+    RequestExpired = "expired"
+)
+
+// PaymentRequest asks PayerAccount to pay Amount, in the requester's
+// currency, to RequesterAccount. TransactionID is set once it is accepted.
+type PaymentRequest struct {
+    ID int `json:"id"`
+    RequesterAccount int64 `json:"requesterAccount"`
+    PayerAccount int64 `json:"payerAccount"`
+    Amount int64 `json:"amount"`
+    Memo string `json:"memo"`
+    Status string `json:"status"`
+    TransactionID int `json:"transactionId,omitempty"`
+    ExpiresAt time.Time `json:"expiresAt"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreatePaymentRequestRequest struct {
+    PayerAccount int64 `json:"payerAccount"`
+    Amount int64 `json:"amount"`
+    Memo string `json:"memo"`
+    // ExpiresAt defaults to a week from now.
+    ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
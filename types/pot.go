@@ -0,0 +1,44 @@
+package types
+
+import (
+    "time"
+)
+
+// Pot is a savings goal inside an account. Its balance is part of the
+// account balance but set aside, so it can't be spent until it is moved
+// back out.
+type Pot struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    Name string `json:"name"`
+    Target int64 `json:"target"`
+    Balance int64 `json:"balance"`
+    // RoundUp saves the change when outgoing transfers are rounded up to
+    // the next whole unit.
+    RoundUp bool `json:"roundUp"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+// Progress is how far the pot is towards its target, in percent.
+func (p *Pot) Progress() int {
+    if p.Target <= 0 || p.Balance >= p.Target {
+        return 100
+    }
+    return int(p.Balance * 100 / p.Target)
+}
+
+type PotRequest struct {
+    Name string `json:"name"`
+    Target int64 `json:"target"`
+    RoundUp bool `json:"roundUp"`
+}
+
+type PotMoveRequest struct {
+    Amount int64 `json:"amount"`
+}
+
+// PotResponse is a pot with its progress towards the target.
+type PotResponse struct {
+    *Pot
+    Progress int `json:"progress"`
+}
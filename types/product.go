@@ -0,0 +1,39 @@
+package types
+
+import (
+    "time"
+)
+
+// Product settings that can be changed at runtime through product rates.
+const (
+    // ProductLoanRateBPS is the annual interest rate of new loans in basis
+    // points.
+    ProductLoanRateBPS = "loan_rate_bps"
+    // ProductLoanLateFee is charged on overdue loan installments.
+    ProductLoanLateFee = "loan_late_fee"
+    // ProductSavingsRateBPS is the annual interest rate paid on balances
+    // in basis points, accrued daily.
+    ProductSavingsRateBPS = "savings_rate_bps"
+    // ProductTransferFee is charged to the sender of every transfer between
+    // accounts, in the sender's currency.
+    ProductTransferFee = "transfer_fee"
+)
+
+var ProductKeys = []string{ProductLoanRateBPS, ProductLoanLateFee, ProductSavingsRateBPS, ProductTransferFee}
+
+// ProductRate sets a product setting to Value from EffectiveFrom until the
+// next rate for the same key takes effect.
+type ProductRate struct {
+    ID int `json:"id"`
+    Key string `json:"key"`
+    Value int64 `json:"value"`
+    EffectiveFrom time.Time `json:"effectiveFrom"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+type ProductRateRequest struct {
+    Key string `json:"key"`
+    Value int64 `json:"value"`
+    // EffectiveFrom defaults to now and can't be in the past.
+    EffectiveFrom *time.Time `json:"effectiveFrom,omitempty"`
+}
@@ -0,0 +1,31 @@
+package types
+
+import (
+    "time"
+)
+
+// QRCode is a signed payment code for an account, to be rendered as a QR
+// code by the client.
+type QRCode struct {
+    Payload string `json:"payload"`
+    Account int64 `json:"account"`
+    Amount int64 `json:"amount,omitempty"`
+    Currency string `json:"currency"`
+    Memo string `json:"memo,omitempty"`
+    ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type DecodeQRRequest struct {
+    Payload string `json:"payload"`
+}
+
+// DecodedQR is a validated payment code with enough about the recipient
+// for the payer to confirm before transferring.
+type DecodedQR struct {
+    Account int64 `json:"account"`
+    RecipientName string `json:"recipientName"`
+    Amount int64 `json:"amount,omitempty"`
+    Currency string `json:"currency"`
+    Memo string `json:"memo,omitempty"`
+    ExpiresAt time.Time `json:"expiresAt"`
+}
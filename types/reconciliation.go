@@ -0,0 +1,20 @@
+package types
+
+import (
+    "time"
+)
+
+// Discrepancy records an account whose stored balance doesn't match the sum
+// of its ledger entries.
+type Discrepancy struct {
+    ID int `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    StoredBalance int64 `json:"storedBalance"`
+    LedgerBalance int64 `json:"ledgerBalance"`
+    DetectedAt time.Time `json:"detectedAt"`
+    ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+func (d *Discrepancy) Difference() int64 {
+    return d.StoredBalance - d.LedgerBalance
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+    "time"
+)
+
+// DailyFlow is the money that came into and left the bank on Day through
+// the settlement account, and how many customer accounts had any ledger
+// entry that day.
+type DailyFlow struct {
+    Day time.Time `json:"day"`
+    Deposits int64 `json:"deposits"`
+    Withdrawals int64 `json:"withdrawals"`
+    Net int64 `json:"net"`
+    ActiveAccounts int `json:"activeAccounts"`
+}
+
+// FlowReport sums the daily flows in [From, To). ActiveAccounts counts each
+// account once over the whole period.
+type FlowReport struct {
+    From time.Time `json:"from"`
+    To time.Time `json:"to"`
+    Deposits int64 `json:"deposits"`
+    Withdrawals int64 `json:"withdrawals"`
+    Net int64 `json:"net"`
+    ActiveAccounts int `json:"activeAccounts"`
+    Days []*DailyFlow `json:"days"`
+    GeneratedAt time.Time `json:"generatedAt"`
+}
+
+type LargestTransfersReport struct {
+    From time.Time `json:"from"`
+    To time.Time `json:"to"`
+    Transfers []*Transaction `json:"transfers"`
+    GeneratedAt time.Time `json:"generatedAt"`
+}
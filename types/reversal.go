@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// Reversal links a transaction to the TransactionReversal that posted its
+// entries in reverse. Accepted disputes are recorded as reversals too, so a
+// transaction is only ever undone once.
+type Reversal struct {
+    TransactionID int `json:"transactionId"`
+    ReversalID int `json:"reversalId"`
+    Reason string `json:"reason"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+type ReverseTransactionRequest struct {
+    Reason string `json:"reason"`
+}
@@ -0,0 +1,21 @@
+package types
+
+import (
+    "time"
+)
+
+// SigningKey lets its holder sign money movements. Once a holder has one,
+// their transfers must be signed with it; see package signing. The secret
+// is only shown when the key is created and is stored encrypted.
+type SigningKey struct {
+    ID string `json:"id"`
+    AccountNumber int64 `json:"accountNumber"`
+    EncryptedSecret string `json:"-"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreatedSigningKey is the only response that carries the secret.
+type CreatedSigningKey struct {
+    *SigningKey
+    Secret string `json:"secret"`
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+    "time"
+)
+
+// BalanceSnapshot is an account balance at the end of Day (UTC).
+type BalanceSnapshot struct {
+    AccountNumber int64 `json:"accountNumber"`
+    Day time.Time `json:"day"`
+    Balance int64 `json:"balance"`
+}
+
+// BalanceResponse is an account's balance now, broken down in Balances, or
+// At the end of a past day.
+type BalanceResponse struct {
+    AccountNumber int64 `json:"accountNumber"`
+    Balance int64 `json:"balance"`
+    Currency string `json:"currency"`
+    *Balances
+    At *time.Time `json:"at,omitempty"`
+}
+
+// Balances breaks down what an account has. Outgoing transfers leave the
+// balance when they are sent, Booked counts the ones still Pending as not
+// gone yet. Available is what can be spent: Booked minus Pending, what
+// card holds and open disputes have Held, and what is Saved in pots.
+type Balances struct {
+    Booked int64 `json:"booked"`
+    Available int64 `json:"available"`
+    Pending int64 `json:"pending"`
+    Held int64 `json:"held"`
+    Saved int64 `json:"saved"`
+}
+
+func NewBalances(balance, pending, held, saved int64) *Balances {
+    return &Balances{
+        Booked: balance + pending,
+        Available: balance - held - saved,
+        Pending: pending,
+        Held: held,
+        Saved: saved,
+    }
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+    "time"
+)
+
+const (
+    TransactionTransfer = "transfer"
+    TransactionOpening = "opening"
+    // TransactionReturn gives back the money of an external transfer that
+    // failed to settle.
+    TransactionReturn = "return"
+    TransactionLoanDisbursement = "loan_disbursement"
+    TransactionLoanRepayment = "loan_repayment"
+    TransactionInterest = "interest"
+    // TransactionChargeback reverses a transfer whose dispute was accepted.
+    TransactionChargeback = "chargeback"
+    // TransactionClosure sweeps the balance of an account being closed.
+    TransactionClosure = "closure"
+    // TransactionReversal undoes an erroneous transaction.
+    TransactionReversal = "reversal"
+    // TransactionCard is a captured card payment, paid into the settlement
+    // account.
+    TransactionCard = "card"
+)
+
+const (
+    StatusCompleted = "completed"
+    StatusPending = "pending"
+    StatusFailed = "failed"
+)
+
+// Transaction is a posted money movement. Transfers to outside the bank
+// stay pending until the provider named in Provider confirms Reference.
+// Interest payments use them to be posted only once per account and day.
+type Transaction struct {
+    ID int `json:"id"`
+    Kind string `json:"kind"`
+    Status string `json:"status"`
+    FromAccount int64 `json:"fromAccount"`
+    ToAccount int64 `json:"toAccount"`
+    Amount int64 `json:"amount"`
+    Provider string `json:"provider,omitempty"`
+    Reference string `json:"reference,omitempty"`
+    // IdempotencyKey is the Idempotency-Key the sender posted the transfer
+    // with. A sender can't post two transactions with the same key.
+    IdempotencyKey string `json:"-"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+// SettlementCallback is the body of an inbound webhook from a clearing
+// provider reporting the outcome of a pending transfer.
+type SettlementCallback struct {
+    Reference string `json:"reference"`
+    Status string `json:"status"`
+    Reason string `json:"reason,omitempty"`
+}
@@ -1,11 +1,15 @@
 package types
 
 import (
-	"math/rand"
+	"crypto/rand"
+	"math/big"
 	"time"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// MaxAccountNumber bounds the account numbers NewAccountNumber draws.
+const MaxAccountNumber = 10000000
+
 type LoginRequest struct {
     Number int64  `json:"number"`
     Password string  `json:"password"`
@@ -14,26 +18,89 @@ type LoginRequest struct {
 type LoginResponse struct {
     Number int64 `json:"number"`
     Token string `json:"token"`
+    // StepUpRequired is set when the token can't move money until the
+    // code sent to the account holder is confirmed at /login/step-up.
+    StepUpRequired bool `json:"stepUpRequired,omitempty"`
 }
 
 type TransferRequest struct {
     ToAccount int64 `json:"toAccount"` 
+    // ToAlias pays the account registered for an email or phone alias
+    // and is used instead of ToAccount when set.
+    ToAlias string `json:"toAlias,omitempty"`
     Amount int64 `json:"amount"` 
 }
 
+const (
+    PreviewCompleted = "completed"
+    // PreviewPending is a transfer to an alias nobody has claimed yet.
+    PreviewPending = "pending"
+    // PreviewBlocked is a transfer the fraud rules would hold for review.
+    PreviewBlocked = "blocked"
+)
+
+// TransferPreview is what a transfer would do, worked out with every check
+// it would go through but without posting it. Amount is taken from the
+// sender in Currency, ConvertedAmount reaches the recipient in ToCurrency.
+type TransferPreview struct {
+    Outcome string `json:"outcome"`
+    FromAccount int64 `json:"fromAccount"`
+    ToAccount int64 `json:"toAccount"`
+    ToAlias string `json:"toAlias,omitempty"`
+    Amount int64 `json:"amount"`
+    Currency string `json:"currency"`
+    ConvertedAmount int64 `json:"convertedAmount"`
+    ToCurrency string `json:"toCurrency"`
+    Rate float64 `json:"rate"`
+    // Fee is what the fee account would be credited.
+    Fee int64 `json:"fee"`
+    Entries []*LedgerEntry `json:"entries"`
+}
+
 type CreateAccountRequest struct {
     FirstName string `json:"firstName"`
     LastName string `json:"lastName"`
+    Email string `json:"email"`
+    Currency string `json:"currency"`
+    // Locale defaults to the best match for the Accept-Language header.
+    Locale string `json:"locale"`
     Password string `json:"password"`
 }
 
+// UpdateAccountRequest is a PATCH: fields left out are unchanged, and a
+// metadata key set to null is removed.
+type UpdateAccountRequest struct {
+    Nickname *string `json:"nickname"`
+    Locale *string `json:"locale"`
+    Metadata map[string]*string `json:"metadata"`
+}
+
 type Account struct {
     ID int `json:"id"`
     FirstName string `json:"fistName"`
     LastName string `json:"lastName"`
+    Email string `json:"email"`
+    Phone string `json:"phone,omitempty"`
+    PhoneVerified bool `json:"phoneVerified"`
     EncryptedPassword string `json:"-"`
     Number int64 `json:"number"`
     Balance int64 `json:"balance"`
+    Currency string `json:"currency"`
+    Nickname string `json:"nickname,omitempty"`
+    // Locale, e.g. de-DE, decides how amounts and dates are written to the
+    // account holder.
+    Locale string `json:"locale,omitempty"`
+    // Metadata holds the integrator's own references, e.g. a CRM id.
+    Metadata map[string]string `json:"metadata,omitempty"`
+    // DormantSince is set once the account has gone without activity for
+    // too long. It can't send money until its holder reactivates it.
+    DormantSince *time.Time `json:"dormantSince,omitempty"`
+    // DormancyNoticeAt is when the holder was warned that the account is
+    // about to become dormant.
+    DormancyNoticeAt *time.Time `json:"-"`
+    // ClosedAt is set once the holder closed the account. A closed account
+    // has no balance and can't send or receive money.
+    ClosedAt *time.Time `json:"closedAt,omitempty"`
     CreatedAt time.Time  `json:"createdAt"`
 }
 
@@ -41,9 +108,30 @@ func (acc *Account) ValidatePassword(pw string) bool {
     return bcrypt.CompareHashAndPassword([]byte(acc.EncryptedPassword), []byte(pw)) == nil
 }
 
+// HashPassword returns the bcrypt hash stored as EncryptedPassword.
+func HashPassword(password string) (string, error) {
+    encpw, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    return string(encpw), err
+}
+
+// NewAccountNumber draws a random account number between 1 and
+// MaxAccountNumber. It can be in use already; storing the account is what
+// makes sure it isn't.
+func NewAccountNumber() (int64, error) {
+    n, err := rand.Int(rand.Reader, big.NewInt(MaxAccountNumber))
+    if err != nil {
+        return 0, err
+    }
+    return n.Int64() + 1, nil
+}
+
 func  NewAccount(firstName, lastName, password string) (*Account, error)  {
-    encpw, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost) 
+    encpw, err := HashPassword(password)
 
+    if err != nil {
+        return nil, err
+    }
+    number, err := NewAccountNumber()
     if err != nil {
         return nil, err
     }
@@ -51,8 +139,9 @@ func  NewAccount(firstName, lastName, password string) (*Account, error)  {
     return &Account {
         FirstName: firstName,
         LastName: lastName,
-        Number: int64(rand.Intn(10000000)),
-        EncryptedPassword: string(encpw),
+        Number: number,
+        Currency: "USD",
+        EncryptedPassword: encpw,
         CreatedAt: time.Now().UTC(),
     }, nil
 }
@@ -0,0 +1,43 @@
+package types
+
+import "time"
+
+// Quotas cap how much of the API one account may use. Zero means no cap.
+type Quotas struct {
+    RequestsPerDay int64 `json:"requestsPerDay"`
+    // TransferVolumePerMonth caps what the account sends per calendar
+    // month (UTC), in its own currency.
+    TransferVolumePerMonth int64 `json:"transferVolumePerMonth"`
+}
+
+// Usage is what an account used of the API on one day, or over a period
+// in reports.
+type Usage struct {
+    AccountNumber int64 `json:"accountNumber"`
+    Day time.Time `json:"day,omitempty"`
+    Requests int64 `json:"requests"`
+    Transfers int64 `json:"transfers"`
+    TransferVolume int64 `json:"transferVolume"`
+}
+
+// UsageReport is an account's usage from From up to To, day by day.
+type UsageReport struct {
+    AccountNumber int64 `json:"accountNumber"`
+    From time.Time `json:"from"`
+    To time.Time `json:"to"`
+    Total Usage `json:"total"`
+    Days []*Usage `json:"days"`
+    Quotas Quotas `json:"quotas"`
+}
+
+// UsageDay truncates t to the UTC day its usage is counted on.
+func UsageDay(t time.Time) time.Time {
+    y, m, d := t.UTC().Date()
+    return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// UsageMonth is the first day of the UTC month of t.
+func UsageMonth(t time.Time) time.Time {
+    y, m, _ := t.UTC().Date()
+    return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
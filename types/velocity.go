@@ -0,0 +1,14 @@
+package types
+
+import (
+    "time"
+)
+
+// VelocityLimit caps how many transfers an account sends, and how much they
+// add up to, within any rolling Window. A zero Count or Amount isn't
+// checked.
+type VelocityLimit struct {
+    Window time.Duration `json:"window"`
+    Count int `json:"count"`
+    Amount int64 `json:"amount"`
+}
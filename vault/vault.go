@@ -0,0 +1,74 @@
+package vault
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "fmt"
+)
+
+const KeySize = 32
+
+var ErrDecrypt = errors.New("vault: can't decrypt value")
+
+// Encrypt seals plaintext with AES-256-GCM under key and returns the nonce
+// and ciphertext as base64.
+func Encrypt(key, plaintext []byte) (string, error) {
+    gcm, err := newGCM(key)
+    if err != nil {
+        return "", err
+    }
+
+    nonce := make([]byte, gcm.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return "", err
+    }
+
+    return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+func Decrypt(key []byte, sealed string) ([]byte, error) {
+    gcm, err := newGCM(key)
+    if err != nil {
+        return nil, err
+    }
+
+    data, err := base64.StdEncoding.DecodeString(sealed)
+    if err != nil || len(data) < gcm.NonceSize() {
+        return nil, ErrDecrypt
+    }
+
+    plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+    if err != nil {
+        return nil, ErrDecrypt
+    }
+
+    return plaintext, nil
+}
+
+// Hash returns a keyed hash of value, for looking up encrypted values
+// without decrypting every row.
+func Hash(key []byte, value string) string {
+    h := hmac.New(sha256.New, key)
+    h.Write([]byte("vault-lookup:"))
+    h.Write([]byte(value))
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+    if len(key) != KeySize {
+        return nil, fmt.Errorf("vault: key must be %d bytes, got %d", KeySize, len(key))
+    }
+
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+
+    return cipher.NewGCM(block)
+}
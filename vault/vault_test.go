@@ -0,0 +1,26 @@
+package vault
+
+import (
+    "bytes"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+    key := bytes.Repeat([]byte{1}, KeySize)
+
+    sealed, err := Encrypt(key, []byte("4111111111111111"))
+    assert.Nil(t, err)
+    assert.NotContains(t, sealed, "4111")
+
+    got, err := Decrypt(key, sealed)
+    assert.Nil(t, err)
+    assert.Equal(t, "4111111111111111", string(got))
+
+    _, err = Decrypt(bytes.Repeat([]byte{2}, KeySize), sealed)
+    assert.ErrorIs(t, err, ErrDecrypt)
+
+    _, err = Encrypt([]byte("short"), nil)
+    assert.Error(t, err)
+}
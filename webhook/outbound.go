@@ -0,0 +1,76 @@
+package webhook
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// Event is the body of an outbound webhook.
+type Event struct {
+    Type string `json:"type"`
+    CreatedAt time.Time `json:"createdAt"`
+    Data any `json:"data"`
+}
+
+// Client posts events to one endpoint, signed in SignatureHeader the same
+// way inbound webhooks are, so receivers can check them with Verify.
+type Client struct {
+    url string
+    secret []byte
+    client *http.Client
+}
+
+func NewClient(url string, secret []byte) *Client {
+    return &Client{
+        url: url,
+        secret: secret,
+        client: &http.Client{Timeout: 15 * time.Second},
+    }
+}
+
+// URL is the endpoint events are posted to.
+func (c *Client) URL() string {
+    return c.url
+}
+
+// Send posts e and fails unless the endpoint answers with a 2xx.
+func (c *Client) Send(ctx context.Context, e Event) error {
+    body, err := json.Marshal(e)
+    if err != nil {
+        return err
+    }
+
+    if err := c.Post(ctx, body); err != nil {
+        return fmt.Errorf("webhook %s: %w", e.Type, err)
+    }
+
+    return nil
+}
+
+// Post sends an event Send encoded earlier, signed anew, so one that failed
+// can be sent again as it was.
+func (c *Client) Post(ctx context.Context, body []byte) error {
+    req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set(SignatureHeader, Sign(c.secret, time.Now(), body))
+
+    resp, err := c.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return errors.New(resp.Status)
+    }
+
+    return nil
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// SignatureHeader carries "t=<unix seconds>,v1=<hex hmac>", where the HMAC
+// is SHA-256 over "<t>.<body>" keyed with the provider's shared secret.
+// Signing the timestamp lets receivers reject replayed requests.
+const SignatureHeader = "X-Webhook-Signature"
+
+var (
+    ErrInvalidSignature = errors.New("invalid webhook signature")
+    ErrExpiredSignature = errors.New("webhook signature timestamp outside tolerance")
+)
+
+func Sign(secret []byte, t time.Time, body []byte) string {
+    ts := strconv.FormatInt(t.Unix(), 10)
+    return fmt.Sprintf("t=%s,v1=%s", ts, mac(secret, ts, body))
+}
+
+// Verify checks header against body and rejects signatures made more than
+// tolerance away from now.
+func Verify(secret []byte, header string, body []byte, now time.Time, tolerance time.Duration) error {
+    var ts, sig string
+    for _, part := range strings.Split(header, ",") {
+        k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+        switch k {
+        case "t":
+            ts = v
+        case "v1":
+            sig = v
+        }
+    }
+    if ts == "" || sig == "" {
+        return ErrInvalidSignature
+    }
+
+    unix, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil {
+        return ErrInvalidSignature
+    }
+
+    got, err := hex.DecodeString(sig)
+    if err != nil || !hmac.Equal(got, macBytes(secret, ts, body)) {
+        return ErrInvalidSignature
+    }
+
+    if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+        return ErrExpiredSignature
+    }
+
+    return nil
+}
+
+func mac(secret []byte, ts string, body []byte) string {
+    return hex.EncodeToString(macBytes(secret, ts, body))
+}
+
+func macBytes(secret []byte, ts string, body []byte) []byte {
+    h := hmac.New(sha256.New, secret)
+    h.Write([]byte(ts))
+    h.Write([]byte("."))
+    h.Write(body)
+    return h.Sum(nil)
+}
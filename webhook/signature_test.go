@@ -0,0 +1,45 @@
+package webhook
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+    secret := []byte("s3cret")
+    body := []byte(`{"reference":"abc","status":"completed"}`)
+    now := time.Now()
+    header := Sign(secret, now, body)
+
+    assert.NoError(t, Verify(secret, header, body, now, time.Minute))
+    assert.ErrorIs(t, Verify([]byte("other"), header, body, now, time.Minute), ErrInvalidSignature)
+    assert.ErrorIs(t, Verify(secret, header, []byte(`{}`), now, time.Minute), ErrInvalidSignature)
+    assert.ErrorIs(t, Verify(secret, header, body, now.Add(2*time.Minute), time.Minute), ErrExpiredSignature)
+    assert.ErrorIs(t, Verify(secret, "garbage", body, now, time.Minute), ErrInvalidSignature)
+}
+
+func TestClientSignsEvents(t *testing.T) {
+    secret := []byte("s3cret")
+    var got Event
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        if Verify(secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute) != nil {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        json.Unmarshal(body, &got)
+    }))
+    defer srv.Close()
+
+    e := Event{Type: "transaction.reversed", CreatedAt: time.Now().UTC(), Data: map[string]int{"transactionId": 7}}
+    assert.NoError(t, NewClient(srv.URL, secret).Send(context.Background(), e))
+    assert.Equal(t, "transaction.reversed", got.Type)
+    assert.Error(t, NewClient(srv.URL, []byte("other")).Send(context.Background(), e))
+}